@@ -14,7 +14,7 @@ ROOT_FOLDER_SERVER_LOCATION="/schej.it"
 # Build server locally
 echo "Building server..."
 cd server
-GOOS="linux" GOARCH="amd64" go build -buildvcs=false
+GOOS="linux" GOARCH="amd64" go build -buildvcs=false -ldflags "-X schej.it/server/services/telemetry.Version=$(git describe --tags --always)"
 
 # Transfer build to server
 echo "Transferring build to server..."
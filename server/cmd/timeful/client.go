@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Calls the Timeful API as the owner of an API key
+type client struct {
+	baseUrl    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func newClient(baseUrl string, apiKey string) *client {
+	return &client{
+		baseUrl:    strings.TrimSuffix(baseUrl, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Error returned by the API, e.g. {"error": "event-not-found"}
+type apiError struct {
+	Status  int
+	Message string
+}
+
+func (e *apiError) Error() string {
+	if len(e.Message) == 0 {
+		return fmt.Sprintf("request failed with status %d", e.Status)
+	}
+	return fmt.Sprintf("%s (status %d)", e.Message, e.Status)
+}
+
+// Sends the request with the payload as JSON, if any, and returns the
+// response body. Responses that aren't 2xx are returned as an *apiError
+func (cl *client) request(method string, path string, payload interface{}) ([]byte, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, cl.baseUrl+"/api"+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+cl.apiKey)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := cl.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		apiErr := &apiError{Status: res.StatusCode}
+		var errorBody struct {
+			Error interface{} `json:"error"`
+		}
+		if json.Unmarshal(data, &errorBody) == nil && errorBody.Error != nil {
+			apiErr.Message = fmt.Sprint(errorBody.Error)
+		}
+		return nil, apiErr
+	}
+	return data, nil
+}
+
+// Sends the request and decodes the JSON response into out, if not nil
+func (cl *client) requestJSON(method string, path string, payload interface{}, out interface{}) error {
+	data, err := cl.request(method, path, payload)
+	if err != nil {
+		return err
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
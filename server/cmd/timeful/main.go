@@ -0,0 +1,302 @@
+// Command timeful is a minimal client for the Timeful API, for power users and
+// scripts. It authenticates with an API key (see POST /api/user/api-keys)
+// given with --key or the TIMEFUL_API_KEY environment variable.
+//
+// Usage:
+//
+//	timeful [--url URL] [--key KEY] <command> [flags]
+//
+// Commands:
+//
+//	create     Creates an event on the given dates
+//	responses  Lists the responses of an event
+//	export     Exports the responses of an event as csv or xlsx
+//	finalize   Finalizes an event at the given time, or its best time
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const defaultUrl = "https://timeful.app"
+
+// Layout of the times passed to finalize
+const timeLayout = "2006-01-02T15:04"
+
+func main() {
+	flag.Usage = usage
+	baseUrl := flag.String("url", getEnv("TIMEFUL_URL", defaultUrl), "Timeful instance to use, or TIMEFUL_URL")
+	apiKey := flag.String("key", os.Getenv("TIMEFUL_API_KEY"), "API key, or TIMEFUL_API_KEY")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	if len(*apiKey) == 0 {
+		fail(errors.New("an API key is required: pass --key or set TIMEFUL_API_KEY"))
+	}
+	cl := newClient(*baseUrl, *apiKey)
+
+	commands := map[string]func(*client, []string) error{
+		"create":    createEvent,
+		"responses": listResponses,
+		"export":    exportResponses,
+		"finalize":  finalizeEvent,
+	}
+	command, ok := commands[flag.Arg(0)]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if err := command(cl, flag.Args()[1:]); err != nil {
+		fail(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: timeful [--url URL] [--key KEY] <command> [flags]
+
+Commands:
+  create     Creates an event on the given dates
+  responses  Lists the responses of an event
+  export     Exports the responses of an event as csv or xlsx
+  finalize   Finalizes an event at the given time, or its best time
+
+Run "timeful <command> -h" for the flags of a command.
+
+Global flags:
+`)
+	flag.PrintDefaults()
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "timeful:", err)
+	os.Exit(1)
+}
+
+func getEnv(name string, fallback string) string {
+	if value := os.Getenv(name); len(value) > 0 {
+		return value
+	}
+	return fallback
+}
+
+// Splits a comma separated list, dropping empty values
+func splitList(list string) []string {
+	values := make([]string, 0)
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); len(value) > 0 {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Returns the start of the event on each of the dates (e.g. "2026-10-20"), and
+// the length of each day in hours, for the daily start and end times (e.g.
+// "09:00" and "17:00") in the given location
+func getEventDates(dates []string, start string, end string, loc *time.Location) ([]time.Time, float64, error) {
+	startTime, err := time.Parse("15:04", start)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid start time %q, expected HH:MM", start)
+	}
+	endTime, err := time.Parse("15:04", end)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid end time %q, expected HH:MM", end)
+	}
+	duration := endTime.Sub(startTime).Hours()
+	if duration <= 0 {
+		return nil, 0, errors.New("the end time must be after the start time")
+	}
+	if len(dates) == 0 {
+		return nil, 0, errors.New("at least one date is required")
+	}
+
+	starts := make([]time.Time, 0)
+	for _, date := range dates {
+		day, err := time.ParseInLocation("2006-01-02", date, loc)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
+		}
+		starts = append(starts, time.Date(day.Year(), day.Month(), day.Day(), startTime.Hour(), startTime.Minute(), 0, 0, loc).UTC())
+	}
+	return starts, duration, nil
+}
+
+func createEvent(cl *client, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	name := fs.String("name", "", "Name of the event")
+	dates := fs.String("dates", "", "Comma separated dates, e.g. 2026-10-20,2026-10-21")
+	start := fs.String("start", "09:00", "Earliest time of each day")
+	end := fs.String("end", "17:00", "Latest time of each day")
+	timezone := fs.String("timezone", "Local", "Timezone of the dates and times, e.g. America/New_York")
+	remindees := fs.String("remindees", "", "Comma separated emails to invite and remind")
+	fs.Parse(args)
+
+	if len(*name) == 0 {
+		return errors.New("--name is required")
+	}
+	loc, err := time.LoadLocation(*timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q", *timezone)
+	}
+	starts, duration, err := getEventDates(splitList(*dates), *start, *end, loc)
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{
+		"name":      *name,
+		"type":      "specific_dates",
+		"dates":     starts,
+		"duration":  duration,
+		"remindees": splitList(*remindees),
+	}
+	if *timezone != "Local" {
+		payload["timezone"] = *timezone
+	}
+	var created struct {
+		EventId string `json:"eventId"`
+	}
+	if err := cl.requestJSON("POST", "/events", payload, &created); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s/e/%s\n", cl.baseUrl, created.EventId)
+	return nil
+}
+
+type response struct {
+	Name         string      `json:"name"`
+	Email        string      `json:"email"`
+	SignUpBlocks []string    `json:"signUpBlocks"`
+	Availability []time.Time `json:"availability"`
+	IfNeeded     []time.Time `json:"ifNeeded"`
+}
+
+func listResponses(cl *client, args []string) error {
+	fs := flag.NewFlagSet("responses", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print the responses as JSON")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: timeful responses [--json] <eventId>")
+	}
+
+	path := fmt.Sprintf("/events/%s/responses/export", url.PathEscape(fs.Arg(0)))
+	if *asJSON {
+		data, err := cl.request("GET", path, nil)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(append(data, '\n'))
+		return err
+	}
+
+	var responses []response
+	if err := cl.requestJSON("GET", path, nil, &responses); err != nil {
+		return err
+	}
+	writeResponses(os.Stdout, responses)
+	return nil
+}
+
+func writeResponses(w io.Writer, responses []response) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tEMAIL\tAVAILABLE\tIF NEEDED\tSIGN UP BLOCKS")
+	for _, r := range responses {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\n", r.Name, r.Email, len(r.Availability), len(r.IfNeeded), strings.Join(r.SignUpBlocks, ", "))
+	}
+	tw.Flush()
+}
+
+func exportResponses(cl *client, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "csv", "csv or xlsx")
+	sheet := fs.String("sheet", "responses", "For csv exports, responses or summary (the best times)")
+	output := fs.String("o", "", "File to write to, defaults to stdout")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: timeful export [--format csv|xlsx] [--sheet responses|summary] [-o file] <eventId>")
+	}
+	if *format != "csv" && *format != "xlsx" {
+		return errors.New("--format must be csv or xlsx")
+	}
+	if *format == "xlsx" && len(*output) == 0 {
+		return errors.New("-o is required for xlsx exports")
+	}
+
+	query := url.Values{"format": {*format}, "sheet": {*sheet}}
+	data, err := cl.request("GET", fmt.Sprintf("/events/%s/responses/export?%s", url.PathEscape(fs.Arg(0)), query.Encode()), nil)
+	if err != nil {
+		return err
+	}
+
+	if len(*output) == 0 {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*output, data, 0644)
+}
+
+func finalizeEvent(cl *client, args []string) error {
+	fs := flag.NewFlagSet("finalize", flag.ExitOnError)
+	best := fs.Bool("best", false, "Finalize at the event's best time")
+	start := fs.String("start", "", "Start of the scheduled time, e.g. 2026-10-20T10:00")
+	end := fs.String("end", "", "End of the scheduled time, e.g. 2026-10-20T11:00")
+	timezone := fs.String("timezone", "Local", "Timezone of the start and end")
+	force := fs.Bool("force", false, "Finalize even if the time conflicts with your calendar")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: timeful finalize (--best | --start TIME --end TIME) [--timezone TZ] [--force] <eventId>")
+	}
+	eventId := url.PathEscape(fs.Arg(0))
+
+	loc, err := time.LoadLocation(*timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q", *timezone)
+	}
+	var startDate, endDate time.Time
+	if *best {
+		var summary struct {
+			TopSlots []struct {
+				Start time.Time `json:"start"`
+				End   time.Time `json:"end"`
+			} `json:"topSlots"`
+		}
+		if err := cl.requestJSON("GET", fmt.Sprintf("/events/%s/summary", eventId), nil, &summary); err != nil {
+			return err
+		}
+		if len(summary.TopSlots) == 0 {
+			return errors.New("nobody is available at any time yet")
+		}
+		startDate, endDate = summary.TopSlots[0].Start, summary.TopSlots[0].End
+	} else {
+		if startDate, err = time.ParseInLocation(timeLayout, *start, loc); err != nil {
+			return fmt.Errorf("invalid --start %q, expected YYYY-MM-DDTHH:MM", *start)
+		}
+		if endDate, err = time.ParseInLocation(timeLayout, *end, loc); err != nil {
+			return fmt.Errorf("invalid --end %q, expected YYYY-MM-DDTHH:MM", *end)
+		}
+	}
+
+	payload := map[string]interface{}{
+		"startDate":       startDate.UTC(),
+		"endDate":         endDate.UTC(),
+		"ignoreConflicts": *force,
+	}
+	if err := cl.requestJSON("POST", fmt.Sprintf("/events/%s/finalize", eventId), payload, nil); err != nil {
+		return err
+	}
+
+	fmt.Printf("Finalized for %s to %s\n", startDate.In(loc).Format("Mon Jan 2 2006 15:04"), endDate.In(loc).Format("15:04 MST"))
+	return nil
+}
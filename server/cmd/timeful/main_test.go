@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetEventDates(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+	starts, duration, err := getEventDates([]string{"2026-10-31", "2026-11-02"}, "09:30", "17:00", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if duration != 7.5 {
+		t.Errorf("got duration %v, want 7.5", duration)
+	}
+	// Daylight saving time ends in between
+	want := []time.Time{time.Date(2026, 10, 31, 13, 30, 0, 0, time.UTC), time.Date(2026, 11, 2, 14, 30, 0, 0, time.UTC)}
+	for i := range want {
+		if !starts[i].Equal(want[i]) {
+			t.Errorf("got start %v, want %v", starts[i], want[i])
+		}
+	}
+
+	for _, args := range [][]string{{"2026-10-31", "17:00", "09:00"}, {"10/31/2026", "09:00", "17:00"}, {"2026-10-31", "9am", "17:00"}} {
+		if _, _, err := getEventDates([]string{args[0]}, args[1], args[2], loc); err == nil {
+			t.Errorf("expected an error for %v", args)
+		}
+	}
+	if _, _, err := getEventDates(splitList(" , "), "09:00", "17:00", loc); err == nil {
+		t.Errorf("expected an error without dates")
+	}
+}
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tf_key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid-api-key"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/api/events/abc/responses/export":
+			w.Write([]byte(`[{"name":"Ana","email":"ana@example.com","availability":["2026-10-20T13:00:00Z","2026-10-20T13:15:00Z"]}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"event-not-found"}`))
+		}
+	}))
+	defer server.Close()
+
+	var responses []response
+	if err := newClient(server.URL+"/", "tf_key").requestJSON("GET", "/events/abc/responses/export", nil, &responses); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	writeResponses(&out, responses)
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 || !strings.Contains(lines[1], "ana@example.com") || !strings.Contains(lines[1], "2") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	err := newClient(server.URL, "tf_key").requestJSON("GET", "/events/missing/summary", nil, nil)
+	if apiErr, ok := err.(*apiError); !ok || apiErr.Status != http.StatusNotFound || apiErr.Message != "event-not-found" {
+		t.Errorf("got %v, want a not found error", err)
+	}
+	if _, err := newClient(server.URL, "tf_wrong").request("GET", "/events/abc/responses/export", nil); err == nil || !strings.Contains(err.Error(), "invalid-api-key") {
+		t.Errorf("got %v, want an invalid key error", err)
+	}
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Inserts the merge, replacing the user's other pending merges
+func InsertAccountMerge(merge *models.AccountMerge) {
+	if merge.Id.IsZero() {
+		merge.Id = primitive.NewObjectID()
+	}
+	if _, err := AccountMergesCollection.DeleteMany(context.Background(), bson.M{"intoUserId": merge.IntoUserId}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	if _, err := AccountMergesCollection.InsertOne(context.Background(), merge); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Returns the user's pending merge with the given id, or nil if it doesn't exist
+func GetAccountMerge(intoUserId primitive.ObjectID, mergeId string) *models.AccountMerge {
+	objectId, err := primitive.ObjectIDFromHex(mergeId)
+	if err != nil {
+		return nil
+	}
+
+	var merge models.AccountMerge
+	err = AccountMergesCollection.FindOne(context.Background(), bson.M{"_id": objectId, "intoUserId": intoUserId}).Decode(&merge)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &merge
+}
+
+// Counts a wrong code entered for the merge
+func IncrementAccountMergeAttempts(mergeId primitive.ObjectID) {
+	_, err := AccountMergesCollection.UpdateByID(context.Background(), mergeId, bson.M{"$inc": bson.M{"attempts": 1}})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+func DeleteAccountMerge(mergeId primitive.ObjectID) {
+	if _, err := AccountMergesCollection.DeleteOne(context.Background(), bson.M{"_id": mergeId}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+func InsertActivity(activity *models.Activity) {
+	if activity.Id.IsZero() {
+		activity.Id = primitive.NewObjectID()
+	}
+	_, err := ActivitiesCollection.InsertOne(context.Background(), activity)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Returns the activity of the given event, most recent first. If before is
+// set, only activity older than it is returned, for paging through the feed
+func GetEventActivities(eventId primitive.ObjectID, before *primitive.DateTime, limit int64) []models.Activity {
+	filter := bson.M{"eventId": eventId}
+	if before != nil {
+		filter["createdAt"] = bson.M{"$lt": *before}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).SetLimit(limit)
+	cursor, err := ActivitiesCollection.Find(context.Background(), filter, opts)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	activities := make([]models.Activity, 0)
+	if err := cursor.All(context.Background(), &activities); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return activities
+}
+
+func DeleteEventActivities(eventId primitive.ObjectID) {
+	_, err := ActivitiesCollection.DeleteMany(context.Background(), bson.M{"eventId": eventId})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Returns the activity of the given events, oldest first
+func GetActivitiesByEventIds(eventIds []primitive.ObjectID) []models.Activity {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := ActivitiesCollection.Find(context.Background(), bson.M{"eventId": bson.M{"$in": eventIds}}, opts)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	activities := make([]models.Activity, 0)
+	if err := cursor.All(context.Background(), &activities); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return activities
+}
+
+// Returns the ids of the events with the most recent activity since the given
+// time, most recently active first
+func GetRecentlyActiveEventIds(since time.Time, limit int64) []primitive.ObjectID {
+	cursor, err := ActivitiesCollection.Aggregate(context.Background(), mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"createdAt": bson.M{"$gte": primitive.NewDateTimeFromTime(since)}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$eventId", "lastActiveAt": bson.M{"$max": "$createdAt"}}}},
+		{{Key: "$sort", Value: bson.M{"lastActiveAt": -1}}},
+		{{Key: "$limit", Value: limit}},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	var results []struct {
+		Id primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(context.Background(), &results); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	eventIds := make([]primitive.ObjectID, 0)
+	for _, result := range results {
+		eventIds = append(eventIds, result.Id)
+	}
+	return eventIds
+}
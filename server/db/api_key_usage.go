@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Counts a request made with the API key to the endpoint on the given day
+func IncrementApiKeyUsage(apiKey *models.ApiKey, day time.Time, method string, route string, isError bool, rateLimited bool) {
+	inc := bson.M{"requests": 1}
+	if isError {
+		inc["errors"] = 1
+	}
+	if rateLimited {
+		inc["rateLimited"] = 1
+	}
+
+	_, err := ApiKeyUsageCollection.UpdateOne(context.Background(), bson.M{
+		"apiKeyId": apiKey.Id,
+		"day":      primitive.NewDateTimeFromTime(day),
+		"method":   method,
+		"route":    route,
+	}, bson.M{
+		"$inc":         inc,
+		"$setOnInsert": bson.M{"ownerId": apiKey.OwnerId},
+	}, options.Update().SetUpsert(true))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Returns the usage of the API key on the days from since onwards
+func GetApiKeyUsage(apiKeyId primitive.ObjectID, since time.Time) []models.ApiKeyUsage {
+	cursor, err := ApiKeyUsageCollection.Find(context.Background(), bson.M{
+		"apiKeyId": apiKeyId,
+		"day":      bson.M{"$gte": primitive.NewDateTimeFromTime(since)},
+	}, options.Find().SetSort(bson.M{"day": 1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	usage := make([]models.ApiKeyUsage, 0)
+	if err := cursor.All(context.Background(), &usage); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return usage
+}
+
+// Deletes the usage of days before the given one
+func DeleteApiKeyUsageBefore(day time.Time) {
+	if _, err := ApiKeyUsageCollection.DeleteMany(context.Background(), bson.M{"day": bson.M{"$lt": primitive.NewDateTimeFromTime(day)}}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+func DeleteApiKeyUsage(apiKeyId primitive.ObjectID) {
+	if _, err := ApiKeyUsageCollection.DeleteMany(context.Background(), bson.M{"apiKeyId": apiKeyId}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
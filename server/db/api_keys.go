@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the API key with the given hash, or nil if it doesn't exist
+func GetApiKeyByHash(keyHash string) *models.ApiKey {
+	var apiKey models.ApiKey
+	err := ApiKeysCollection.FindOne(context.Background(), bson.M{"keyHash": keyHash}).Decode(&apiKey)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &apiKey
+}
+
+// Returns the API keys of the given user, oldest first
+func GetApiKeysByOwner(ownerId primitive.ObjectID) []models.ApiKey {
+	cursor, err := ApiKeysCollection.Find(context.Background(), bson.M{"ownerId": ownerId}, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	apiKeys := make([]models.ApiKey, 0)
+	if err := cursor.All(context.Background(), &apiKeys); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return apiKeys
+}
+
+func InsertApiKey(apiKey *models.ApiKey) {
+	if apiKey.Id.IsZero() {
+		apiKey.Id = primitive.NewObjectID()
+	}
+	_, err := ApiKeysCollection.InsertOne(context.Background(), apiKey)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Deletes the user's API key with the given id, returning whether it existed
+func DeleteApiKey(ownerId primitive.ObjectID, apiKeyId string) bool {
+	objectId, err := primitive.ObjectIDFromHex(apiKeyId)
+	if err != nil {
+		return false
+	}
+
+	result, err := ApiKeysCollection.DeleteOne(context.Background(), bson.M{"_id": objectId, "ownerId": ownerId})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return result.DeletedCount > 0
+}
+
+func SetApiKeyUsed(apiKeyId primitive.ObjectID, now time.Time) {
+	_, err := ApiKeysCollection.UpdateByID(context.Background(), apiKeyId, bson.M{"$set": bson.M{"lastUsedAt": primitive.NewDateTimeFromTime(now)}})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
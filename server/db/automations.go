@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the automation rule with the given id, or nil if it doesn't exist
+func GetAutomationRuleById(ruleId string) *models.AutomationRule {
+	objectId, err := primitive.ObjectIDFromHex(ruleId)
+	if err != nil {
+		return nil
+	}
+
+	var rule models.AutomationRule
+	err = AutomationRulesCollection.FindOne(context.Background(), bson.M{"_id": objectId}).Decode(&rule)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &rule
+}
+
+// Returns the automation rules of the given event, oldest first
+func GetEventAutomationRules(eventId primitive.ObjectID) []models.AutomationRule {
+	cursor, err := AutomationRulesCollection.Find(context.Background(), bson.M{"eventId": eventId}, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	rules := make([]models.AutomationRule, 0)
+	if err := cursor.All(context.Background(), &rules); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return rules
+}
+
+// Returns the enabled automation rules that haven't fired yet
+func GetArmedAutomationRules() []models.AutomationRule {
+	cursor, err := AutomationRulesCollection.Find(context.Background(), bson.M{
+		"enabled": true,
+		"firedAt": bson.M{"$exists": false},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	rules := make([]models.AutomationRule, 0)
+	if err := cursor.All(context.Background(), &rules); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return rules
+}
+
+func InsertAutomationRule(rule *models.AutomationRule) {
+	if rule.Id.IsZero() {
+		rule.Id = primitive.NewObjectID()
+	}
+	_, err := AutomationRulesCollection.InsertOne(context.Background(), rule)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+func UpdateAutomationRule(rule *models.AutomationRule) {
+	_, err := AutomationRulesCollection.ReplaceOne(context.Background(), bson.M{"_id": rule.Id}, rule)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Marks the rule as fired if it hasn't fired yet, returning whether it was
+// marked, so that only one server runs its action
+func ClaimAutomationRule(ruleId primitive.ObjectID, now time.Time) bool {
+	result, err := AutomationRulesCollection.UpdateOne(context.Background(), bson.M{
+		"_id":     ruleId,
+		"enabled": true,
+		"firedAt": bson.M{"$exists": false},
+	}, bson.M{
+		"$set": bson.M{"firedAt": primitive.NewDateTimeFromTime(now)},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return result.ModifiedCount > 0
+}
+
+// Deletes the automation rule along with its runs
+func DeleteAutomationRule(ruleId primitive.ObjectID) {
+	if _, err := AutomationRulesCollection.DeleteOne(context.Background(), bson.M{"_id": ruleId}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	if _, err := AutomationRunsCollection.DeleteMany(context.Background(), bson.M{"ruleId": ruleId}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Returns the most recent runs of the rule, most recent first
+func GetAutomationRuns(ruleId primitive.ObjectID, limit int64) []models.AutomationRun {
+	cursor, err := AutomationRunsCollection.Find(context.Background(), bson.M{"ruleId": ruleId}, options.Find().SetSort(bson.M{"ranAt": -1}).SetLimit(limit))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	runs := make([]models.AutomationRun, 0)
+	if err := cursor.All(context.Background(), &runs); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return runs
+}
+
+func InsertAutomationRun(run *models.AutomationRun) {
+	if run.Id.IsZero() {
+		run.Id = primitive.NewObjectID()
+	}
+	_, err := AutomationRunsCollection.InsertOne(context.Background(), run)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the broadcast with the given id, or nil if it doesn't exist
+func GetBroadcastById(broadcastId string) *models.Broadcast {
+	objectId, err := primitive.ObjectIDFromHex(broadcastId)
+	if err != nil {
+		return nil
+	}
+
+	var broadcast models.Broadcast
+	err = BroadcastsCollection.FindOne(context.Background(), bson.M{"_id": objectId}).Decode(&broadcast)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &broadcast
+}
+
+// Returns the broadcasts of the given event, most recently scheduled first
+func GetEventBroadcasts(eventId primitive.ObjectID) []models.Broadcast {
+	cursor, err := BroadcastsCollection.Find(context.Background(), bson.M{"eventId": eventId}, options.Find().SetSort(bson.M{"sendAt": -1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	broadcasts := make([]models.Broadcast, 0)
+	if err := cursor.All(context.Background(), &broadcasts); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return broadcasts
+}
+
+func InsertBroadcast(broadcast *models.Broadcast) {
+	if broadcast.Id.IsZero() {
+		broadcast.Id = primitive.NewObjectID()
+	}
+	_, err := BroadcastsCollection.InsertOne(context.Background(), broadcast)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+func UpdateBroadcast(broadcast *models.Broadcast) {
+	_, err := BroadcastsCollection.ReplaceOne(context.Background(), bson.M{"_id": broadcast.Id}, broadcast)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Sets the status of the broadcast if it currently has the given status,
+// returning whether it was updated
+func TransitionBroadcastStatus(broadcastId primitive.ObjectID, from models.BroadcastStatus, to models.BroadcastStatus) bool {
+	result, err := BroadcastsCollection.UpdateOne(context.Background(), bson.M{"_id": broadcastId, "status": from}, bson.M{
+		"$set": bson.M{"status": to},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return result.ModifiedCount > 0
+}
+
+// Claims a scheduled broadcast that is due by marking it as sending, returning
+// nil if there are none
+func ClaimDueBroadcast(now time.Time) *models.Broadcast {
+	var broadcast models.Broadcast
+	err := BroadcastsCollection.FindOneAndUpdate(context.Background(), bson.M{
+		"status": models.BROADCAST_SCHEDULED,
+		"sendAt": bson.M{"$lte": primitive.NewDateTimeFromTime(now)},
+	}, bson.M{
+		"$set": bson.M{"status": models.BROADCAST_SENDING},
+	}, options.FindOneAndUpdate().SetSort(bson.M{"sendAt": 1}).SetReturnDocument(options.After)).Decode(&broadcast)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &broadcast
+}
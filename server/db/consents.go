@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the consents given for the given event, most recent first
+func GetEventConsents(eventId primitive.ObjectID) []models.Consent {
+	cursor, err := ConsentsCollection.Find(context.Background(), bson.M{"eventId": eventId}, options.Find().SetSort(bson.M{"agreedAt": -1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	consents := make([]models.Consent, 0)
+	if err := cursor.All(context.Background(), &consents); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return consents
+}
+
+// Returns whether the respondent has agreed to the document with the given hash
+func HasConsented(eventId primitive.ObjectID, userId string, documentHash string) bool {
+	count, err := ConsentsCollection.CountDocuments(context.Background(), bson.M{
+		"eventId":      eventId,
+		"userId":       userId,
+		"documentHash": documentHash,
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return count > 0
+}
+
+func InsertConsent(consent *models.Consent) {
+	if consent.Id.IsZero() {
+		consent.Id = primitive.NewObjectID()
+	}
+	_, err := ConsentsCollection.InsertOne(context.Background(), consent)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
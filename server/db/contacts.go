@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the user's contacts whose email or name starts with the query, most
+// frequently invited first
+func SearchContacts(ownerId primitive.ObjectID, query string, limit int64) []models.Contact {
+	filter := bson.M{"ownerId": ownerId}
+	if len(query) > 0 {
+		prefix := primitive.Regex{Pattern: "^" + regexp.QuoteMeta(query), Options: "i"}
+		filter["$or"] = bson.A{bson.M{"email": prefix}, bson.M{"name": prefix}}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "numInvites", Value: -1}, {Key: "lastInvitedAt", Value: -1}}).SetLimit(limit)
+	cursor, err := ContactsCollection.Find(context.Background(), filter, opts)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	contacts := make([]models.Contact, 0)
+	if err := cursor.All(context.Background(), &contacts); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return contacts
+}
+
+// Adds the emails to the user's contacts, or bumps their invite count if they
+// already are contacts
+func RecordInvitees(ownerId primitive.ObjectID, emails []string, invitedAt time.Time) {
+	if len(emails) == 0 {
+		return
+	}
+
+	writes := make([]mongo.WriteModel, 0)
+	for _, email := range emails {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"ownerId": ownerId, "email": email}).
+			SetUpdate(bson.M{
+				"$inc": bson.M{"numInvites": 1},
+				"$set": bson.M{"lastInvitedAt": primitive.NewDateTimeFromTime(invitedAt)},
+			}).
+			SetUpsert(true))
+	}
+	if _, err := ContactsCollection.BulkWrite(context.Background(), writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Adds the contact to the user's contacts, or updates its name if it already is one
+func UpsertContact(ownerId primitive.ObjectID, email string, name string) *models.Contact {
+	var contact models.Contact
+	err := ContactsCollection.FindOneAndUpdate(context.Background(),
+		bson.M{"ownerId": ownerId, "email": email},
+		bson.M{"$set": bson.M{"name": name}, "$setOnInsert": bson.M{"numInvites": 0}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&contact)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return &contact
+}
+
+func DeleteContact(ownerId primitive.ObjectID, contactId primitive.ObjectID) bool {
+	result, err := ContactsCollection.DeleteOne(context.Background(), bson.M{"_id": contactId, "ownerId": ownerId})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return result.DeletedCount > 0
+}
+
+// Returns the user's own groups and the groups shared with the given organizations
+func GetContactGroups(ownerId primitive.ObjectID, orgIds []primitive.ObjectID) []models.ContactGroup {
+	cursor, err := ContactGroupsCollection.Find(context.Background(), getContactGroupsFilter(ownerId, orgIds), options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	groups := make([]models.ContactGroup, 0)
+	if err := cursor.All(context.Background(), &groups); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return groups
+}
+
+// Returns the groups with the given ids, out of the ones returned by GetContactGroups
+func GetContactGroupsByIds(ownerId primitive.ObjectID, orgIds []primitive.ObjectID, groupIds []primitive.ObjectID) []models.ContactGroup {
+	filter := getContactGroupsFilter(ownerId, orgIds)
+	filter["_id"] = bson.M{"$in": groupIds}
+	cursor, err := ContactGroupsCollection.Find(context.Background(), filter)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	groups := make([]models.ContactGroup, 0)
+	if err := cursor.All(context.Background(), &groups); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return groups
+}
+
+func getContactGroupsFilter(ownerId primitive.ObjectID, orgIds []primitive.ObjectID) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{"ownerId": ownerId, "organizationId": bson.M{"$exists": false}},
+		bson.M{"organizationId": bson.M{"$in": orgIds}},
+	}}
+}
+
+func GetContactGroupById(groupId string) *models.ContactGroup {
+	groupIdObj, err := primitive.ObjectIDFromHex(groupId)
+	if err != nil {
+		return nil
+	}
+
+	var group models.ContactGroup
+	if err := ContactGroupsCollection.FindOne(context.Background(), bson.M{"_id": groupIdObj}).Decode(&group); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &group
+}
+
+func InsertContactGroup(group *models.ContactGroup) {
+	if group.Id.IsZero() {
+		group.Id = primitive.NewObjectID()
+	}
+	if _, err := ContactGroupsCollection.InsertOne(context.Background(), group); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+func UpdateContactGroup(group *models.ContactGroup) {
+	if _, err := ContactGroupsCollection.ReplaceOne(context.Background(), bson.M{"_id": group.Id}, group); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+func DeleteContactGroup(group *models.ContactGroup) {
+	if _, err := ContactGroupsCollection.DeleteOne(context.Background(), bson.M{"_id": group.Id}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Adds the emails to the group, skipping the ones that already are members
+func AddContactGroupMembers(groupId primitive.ObjectID, emails []string) {
+	_, err := ContactGroupsCollection.UpdateByID(context.Background(), groupId, bson.M{"$addToSet": bson.M{"emails": bson.M{"$each": emails}}})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+func RemoveContactGroupMember(groupId primitive.ObjectID, email string) {
+	_, err := ContactGroupsCollection.UpdateByID(context.Background(), groupId, bson.M{"$pull": bson.M{"emails": email}})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
@@ -0,0 +1,183 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Everything stored about the person with an email address, whether they
+// signed up or only responded as a guest
+type DataSubjectRecords struct {
+	User                *models.User                `json:"user"`
+	EventResponses      []models.EventResponse      `json:"eventResponses"`
+	OwnedEvents         []models.Event              `json:"ownedEvents"`
+	InvitedEvents       []models.Event              `json:"invitedEvents"`
+	Attendees           []models.Attendee           `json:"attendees"`
+	Contacts            []models.Contact            `json:"contacts"`
+	ContactGroups       []models.ContactGroup       `json:"contactGroups"`
+	Consents            []models.Consent            `json:"consents"`
+	Payments            []models.Payment            `json:"payments"`
+	Folders             []models.Folder             `json:"folders"`
+	NotificationOptOuts []models.NotificationOptOut `json:"notificationOptOuts"`
+	EmailSuppressions   []models.EmailSuppression   `json:"emailSuppressions"`
+	LtiIdentities       []models.LtiIdentity        `json:"ltiIdentities"`
+}
+
+func findAll[T any](collection *mongo.Collection, filter bson.M) []T {
+	cursor, err := collection.Find(context.Background(), filter)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	results := make([]T, 0)
+	if err := cursor.All(context.Background(), &results); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return results
+}
+
+// Returns the filter matching documents of the data subject, by their email
+// and, if they have an account, by their user id in the given field
+func dataSubjectFilter(email string, user *models.User, emailField string, userIdField string, userId interface{}) bson.M {
+	if user == nil {
+		return bson.M{emailField: email}
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{emailField: email},
+		bson.M{userIdField: userId},
+	}}
+}
+
+// Returns every record stored about the person with the given email
+func FindDataSubjectRecords(email string) *DataSubjectRecords {
+	user := GetUserByEmail(email)
+	records := &DataSubjectRecords{User: user}
+
+	var userHex string
+	var userId primitive.ObjectID
+	if user != nil {
+		userHex = user.Id.Hex()
+		userId = user.Id
+	}
+
+	records.EventResponses = findAll[models.EventResponse](EventResponsesCollection, dataSubjectFilter(email, user, "response.email", "userId", userHex))
+	records.InvitedEvents = findAll[models.Event](EventsCollection, bson.M{"$or": bson.A{
+		bson.M{"remindees.email": email},
+		bson.M{"authorizedEmails": email},
+	}})
+	records.Attendees = findAll[models.Attendee](AttendeesCollection, bson.M{"email": email})
+	records.Contacts = findAll[models.Contact](ContactsCollection, dataSubjectFilter(email, user, "email", "ownerId", userId))
+	records.ContactGroups = findAll[models.ContactGroup](ContactGroupsCollection, dataSubjectFilter(email, user, "emails", "ownerId", userId))
+	records.Consents = findAll[models.Consent](ConsentsCollection, dataSubjectFilter(email, user, "email", "userId", userHex))
+	records.Payments = findAll[models.Payment](PaymentsCollection, dataSubjectFilter(email, user, "email", "userId", userHex))
+	records.NotificationOptOuts = findAll[models.NotificationOptOut](NotificationOptOutsCollection, bson.M{"email": email})
+	records.EmailSuppressions = findAll[models.EmailSuppression](EmailSuppressionsCollection, bson.M{"email": email})
+	records.LtiIdentities = findAll[models.LtiIdentity](LtiIdentitiesCollection, bson.M{"email": email})
+
+	records.OwnedEvents = make([]models.Event, 0)
+	records.Folders = make([]models.Folder, 0)
+	if user != nil {
+		records.OwnedEvents = findAll[models.Event](EventsCollection, bson.M{"ownerId": user.Id})
+		records.Folders = findAll[models.Folder](FoldersCollection, bson.M{"userId": user.Id})
+	}
+
+	return records
+}
+
+// Erases the records of the data subject. Events they own are deleted along
+// with everything attached to them, payments are anonymized rather than
+// deleted since they're needed for accounting, and notification opt outs and
+// email suppressions are kept so the address isn't contacted again. Returns how many documents were
+// deleted and anonymized in each collection
+func EraseDataSubjectRecords(email string, records *DataSubjectRecords) (map[string]int, map[string]int) {
+	deleted := make(map[string]int)
+	anonymized := make(map[string]int)
+	deleteMany := func(collection *mongo.Collection, filter bson.M) {
+		result, err := collection.DeleteMany(context.Background(), filter)
+		if err != nil {
+			logger.StdErr.Panicln(err)
+		}
+		deleted[collection.Name()] += int(result.DeletedCount)
+	}
+	updateMany := func(collection *mongo.Collection, filter bson.M, update bson.M) {
+		result, err := collection.UpdateMany(context.Background(), filter, update)
+		if err != nil {
+			logger.StdErr.Panicln(err)
+		}
+		anonymized[collection.Name()] += int(result.ModifiedCount)
+	}
+
+	ownedEventIds := make([]primitive.ObjectID, 0, len(records.OwnedEvents))
+	for _, event := range records.OwnedEvents {
+		ownedEventIds = append(ownedEventIds, event.Id)
+	}
+	byOwnedEvent := bson.M{"eventId": bson.M{"$in": ownedEventIds}}
+
+	// Events the subject owns, and everything attached to them
+	if len(ownedEventIds) > 0 {
+		deleteMany(EventsCollection, bson.M{"_id": bson.M{"$in": ownedEventIds}})
+		deleteMany(EventResponsesCollection, byOwnedEvent)
+		deleteMany(AttendeesCollection, byOwnedEvent)
+		deleteMany(ConsentsCollection, byOwnedEvent)
+		deleteMany(ActivitiesCollection, byOwnedEvent)
+		deleteMany(FolderEventsCollection, byOwnedEvent)
+		deleteMany(EventAliasesCollection, byOwnedEvent)
+		deleteMany(AutomationRulesCollection, byOwnedEvent)
+		deleteMany(AutomationRunsCollection, byOwnedEvent)
+	}
+
+	// Records about the subject on other people's events
+	responseIds := make([]primitive.ObjectID, 0, len(records.EventResponses))
+	for _, response := range records.EventResponses {
+		responseIds = append(responseIds, response.Id)
+	}
+	deleteMany(EventResponsesCollection, bson.M{"_id": bson.M{"$in": responseIds}})
+	deleteMany(AttendeesCollection, bson.M{"email": email})
+	deleteMany(ContactsCollection, bson.M{"email": email})
+	deleteMany(LtiIdentitiesCollection, bson.M{"email": email})
+	updateMany(EventsCollection, bson.M{"$or": bson.A{
+		bson.M{"remindees.email": email},
+		bson.M{"authorizedEmails": email},
+	}}, bson.M{"$pull": bson.M{
+		"remindees":        bson.M{"email": email},
+		"authorizedEmails": email,
+	}})
+	updateMany(ContactGroupsCollection, bson.M{"emails": email}, bson.M{"$pull": bson.M{"emails": email}})
+
+	var userHex string
+	if records.User != nil {
+		userHex = records.User.Id.Hex()
+	}
+	deleteMany(ConsentsCollection, dataSubjectFilter(email, records.User, "email", "userId", userHex))
+	updateMany(PaymentsCollection, dataSubjectFilter(email, records.User, "email", "userId", userHex), bson.M{
+		"$set":   bson.M{"userId": ""},
+		"$unset": bson.M{"name": "", "email": "", "answers": ""},
+	})
+
+	// The subject's account
+	if user := records.User; user != nil {
+		deleteMany(ContactsCollection, bson.M{"ownerId": user.Id})
+		deleteMany(ContactGroupsCollection, bson.M{"ownerId": user.Id, "organizationId": bson.M{"$exists": false}})
+		deleteMany(FoldersCollection, bson.M{"userId": user.Id})
+		deleteMany(FolderEventsCollection, bson.M{"userId": user.Id})
+		deleteMany(FriendRequestsCollection, bson.M{"$or": bson.A{bson.M{"from": user.Id}, bson.M{"to": user.Id}}})
+		deleteMany(SlackAccountsCollection, bson.M{"userId": user.Id})
+		deleteMany(WebhooksCollection, bson.M{"ownerId": user.Id})
+		deleteMany(ApiKeyUsageCollection, bson.M{"ownerId": user.Id})
+		byDelegationParty := bson.M{"$or": bson.A{bson.M{"principalId": user.Id}, bson.M{"delegateId": user.Id}}}
+		deleteMany(DelegationsCollection, byDelegationParty)
+		deleteMany(DelegationAuditsCollection, byDelegationParty)
+		deleteMany(AccountMergesCollection, bson.M{"$or": bson.A{bson.M{"intoUserId": user.Id}, bson.M{"fromUserId": user.Id}}})
+		updateMany(DailyUserLogCollection, bson.M{"userIds": user.Id}, bson.M{"$pull": bson.M{"userIds": user.Id}})
+		updateMany(ActivitiesCollection, bson.M{"actorId": user.Id}, bson.M{"$unset": bson.M{"actorId": "", "actorName": ""}})
+		deleteMany(UsersCollection, bson.M{"_id": user.Id})
+	}
+
+	return deleted, anonymized
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the delegation with the given id, or nil if it doesn't exist
+func GetDelegationById(delegationId string) *models.Delegation {
+	objectId, err := primitive.ObjectIDFromHex(delegationId)
+	if err != nil {
+		return nil
+	}
+
+	var delegation models.Delegation
+	err = DelegationsCollection.FindOne(context.Background(), bson.M{"_id": objectId}).Decode(&delegation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &delegation
+}
+
+// Returns the delegation from the principal to the delegate that hasn't been
+// revoked, or nil if there is none
+func GetActiveDelegation(principalId primitive.ObjectID, delegateId primitive.ObjectID) *models.Delegation {
+	var delegation models.Delegation
+	err := DelegationsCollection.FindOne(context.Background(), bson.M{
+		"principalId": principalId,
+		"delegateId":  delegateId,
+		"revokedAt":   bson.M{"$exists": false},
+	}).Decode(&delegation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &delegation
+}
+
+// Returns the delegations that match the filter and haven't been revoked,
+// oldest first
+func GetActiveDelegations(filter bson.M) []models.Delegation {
+	filter["revokedAt"] = bson.M{"$exists": false}
+	cursor, err := DelegationsCollection.Find(context.Background(), filter, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	delegations := make([]models.Delegation, 0)
+	if err := cursor.All(context.Background(), &delegations); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return delegations
+}
+
+func InsertDelegation(delegation *models.Delegation) {
+	if delegation.Id.IsZero() {
+		delegation.Id = primitive.NewObjectID()
+	}
+	_, err := DelegationsCollection.InsertOne(context.Background(), delegation)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Revokes the delegation if it hasn't been revoked yet, returning whether it
+// was revoked
+func RevokeDelegation(delegationId primitive.ObjectID, now time.Time) bool {
+	result, err := DelegationsCollection.UpdateOne(context.Background(), bson.M{
+		"_id":       delegationId,
+		"revokedAt": bson.M{"$exists": false},
+	}, bson.M{
+		"$set": bson.M{"revokedAt": primitive.NewDateTimeFromTime(now)},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return result.ModifiedCount > 0
+}
+
+func InsertDelegationAudit(audit *models.DelegationAudit) {
+	if audit.Id.IsZero() {
+		audit.Id = primitive.NewObjectID()
+	}
+	_, err := DelegationAuditsCollection.InsertOne(context.Background(), audit)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Returns the most recent audit records of the delegation, most recent first
+func GetDelegationAudits(delegationId primitive.ObjectID, limit int64) []models.DelegationAudit {
+	cursor, err := DelegationAuditsCollection.Find(context.Background(), bson.M{"delegationId": delegationId}, options.Find().SetSort(bson.M{"createdAt": -1}).SetLimit(limit))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	audits := make([]models.DelegationAudit, 0)
+	if err := cursor.All(context.Background(), &audits); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return audits
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the suppression of the address, or nil if it isn't suppressed
+func GetEmailSuppression(email string) *models.EmailSuppression {
+	var suppression models.EmailSuppression
+	err := EmailSuppressionsCollection.FindOne(context.Background(), bson.M{"email": strings.ToLower(email)}).Decode(&suppression)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &suppression
+}
+
+// Adds the address to the suppression list. Bounces and complaints replace an
+// unsubscribe, since they suppress more emails, but not the other way around
+func SuppressEmail(email string, reason models.SuppressionReason) {
+	email = strings.ToLower(email)
+	createdAt := primitive.NewDateTimeFromTime(time.Now())
+	update := bson.M{"$setOnInsert": bson.M{"reason": reason, "createdAt": createdAt}}
+	if reason != models.SUPPRESSION_UNSUBSCRIBED {
+		update = bson.M{"$set": bson.M{"reason": reason}, "$setOnInsert": bson.M{"createdAt": createdAt}}
+	}
+	_, err := EmailSuppressionsCollection.UpdateOne(context.Background(), bson.M{"email": email}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonoptions"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Name the data key that encrypts the sensitive fields is stored under in the key vault
+const encryptionKeyAltName = "timeful"
+
+const (
+	deterministicAlgorithm = "AEAD_AES_256_CBC_HMAC_SHA_512-Deterministic"
+	randomAlgorithm        = "AEAD_AES_256_CBC_HMAC_SHA_512-Random"
+)
+
+// Whether sensitive fields are encrypted with client-side field level
+// encryption, set with MONGODB_ENCRYPTION_ENABLED. Requires building with the
+// cse tag and libmongocrypt
+func EncryptionEnabled() bool {
+	return os.Getenv("MONGODB_ENCRYPTION_ENABLED") == "true"
+}
+
+// Returns the namespace of the key vault, set with MONGODB_ENCRYPTION_KEY_VAULT
+func getKeyVaultNamespace() string {
+	if namespace := os.Getenv("MONGODB_ENCRYPTION_KEY_VAULT"); len(namespace) > 0 {
+		return namespace
+	}
+	return "encryption.__keyVault"
+}
+
+// Returns the local KMS provider with the master key in
+// MONGODB_ENCRYPTION_MASTER_KEY, which has to be 96 base64 encoded bytes
+func getKmsProviders() (map[string]map[string]interface{}, error) {
+	masterKey, err := base64.StdEncoding.DecodeString(os.Getenv("MONGODB_ENCRYPTION_MASTER_KEY"))
+	if err != nil || len(masterKey) != 96 {
+		return nil, fmt.Errorf("MONGODB_ENCRYPTION_MASTER_KEY must be 96 base64 encoded bytes")
+	}
+	return map[string]map[string]interface{}{"local": {"key": masterKey}}, nil
+}
+
+func encryptedField(bsonType string, algorithm string) bson.M {
+	return bson.M{"encrypt": bson.M{"bsonType": bsonType, "algorithm": algorithm}}
+}
+
+// Returns the JSON schemas of the encrypted fields of each collection, keyed by
+// namespace. Emails are encrypted deterministically so users, attendees and
+// respondents can still be looked up by email, everything else randomly.
+// Fields that are queried by prefix or inside arrays (e.g. contacts and
+// remindees) stay in plaintext
+func GetEncryptionSchemaMap(dbName string, keyId primitive.Binary) map[string]interface{} {
+	schema := func(properties bson.M) bson.M {
+		return bson.M{
+			"bsonType":        "object",
+			"encryptMetadata": bson.M{"keyId": bson.A{keyId}},
+			"properties":      properties,
+		}
+	}
+
+	return map[string]interface{}{
+		dbName + ".users": schema(bson.M{
+			"email":            encryptedField("string", deterministicAlgorithm),
+			"calendarAccounts": encryptedField("object", randomAlgorithm),
+		}),
+		dbName + ".organizations": schema(bson.M{
+			"sendingDomain": bson.M{
+				"bsonType": "object",
+				"properties": bson.M{
+					"dkimPrivateKey": encryptedField("string", randomAlgorithm),
+				},
+			},
+		}),
+		dbName + ".attendees": schema(bson.M{
+			"email": encryptedField("string", deterministicAlgorithm),
+		}),
+		dbName + ".eventResponses": schema(bson.M{
+			"sourceIp": encryptedField("string", randomAlgorithm),
+			"response": bson.M{
+				"bsonType": "object",
+				"properties": bson.M{
+					"email":              encryptedField("string", deterministicAlgorithm),
+					"answers":            encryptedField("object", randomAlgorithm),
+					"fields":             encryptedField("object", randomAlgorithm),
+					"availability":       encryptedField("array", randomAlgorithm),
+					"ifNeeded":           encryptedField("array", randomAlgorithm),
+					"manualAvailability": encryptedField("object", randomAlgorithm),
+				},
+			},
+		}),
+	}
+}
+
+// Returns the registry the client encodes documents with when encryption is
+// enabled. Encrypted fields can't be null, so availabilities that were never
+// set are stored as empty arrays
+func GetEncryptionRegistry() *bsoncodec.Registry {
+	registry := bson.NewRegistry()
+	registry.RegisterTypeEncoder(
+		reflect.TypeOf([]primitive.DateTime{}),
+		bsoncodec.NewSliceCodec(bsonoptions.SliceCodec().SetEncodeNilAsEmpty(true)),
+	)
+	return registry
+}
+
+// Returns the options that make the client encrypt and decrypt the sensitive
+// fields automatically, creating the data key in the key vault if it doesn't
+// exist yet
+func getAutoEncryptionOptions(ctx context.Context, mongoURI string, dbName string) (*options.AutoEncryptionOptions, error) {
+	kmsProviders, err := getKmsProviders()
+	if err != nil {
+		return nil, err
+	}
+	keyVaultNamespace := getKeyVaultNamespace()
+	keyVaultDb, keyVaultColl, found := strings.Cut(keyVaultNamespace, ".")
+	if !found {
+		return nil, fmt.Errorf("MONGODB_ENCRYPTION_KEY_VAULT must be a namespace, e.g. encryption.__keyVault")
+	}
+
+	keyVaultClient, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		return nil, err
+	}
+	defer keyVaultClient.Disconnect(ctx)
+
+	// Reuse the data key if it was already created
+	var keyId primitive.Binary
+	var key struct {
+		Id primitive.Binary `bson:"_id"`
+	}
+	err = keyVaultClient.Database(keyVaultDb).Collection(keyVaultColl).FindOne(ctx, bson.M{"keyAltNames": encryptionKeyAltName}).Decode(&key)
+	if err == nil {
+		keyId = key.Id
+	} else if err == mongo.ErrNoDocuments {
+		clientEncryption, err := mongo.NewClientEncryption(keyVaultClient, options.ClientEncryption().
+			SetKeyVaultNamespace(keyVaultNamespace).
+			SetKmsProviders(kmsProviders))
+		if err != nil {
+			return nil, err
+		}
+		defer clientEncryption.Close(ctx)
+
+		keyId, err = clientEncryption.CreateDataKey(ctx, "local", options.DataKey().SetKeyAltNames([]string{encryptionKeyAltName}))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+
+	autoEncryptionOptions := options.AutoEncryption().
+		SetKeyVaultNamespace(keyVaultNamespace).
+		SetKmsProviders(kmsProviders).
+		SetSchemaMap(GetEncryptionSchemaMap(dbName, keyId))
+	if path := os.Getenv("MONGODB_CRYPT_SHARED_LIB_PATH"); len(path) > 0 {
+		autoEncryptionOptions.SetExtraOptions(map[string]interface{}{"cryptSharedLibPath": path, "cryptSharedLibRequired": true})
+	}
+	return autoEncryptionOptions, nil
+}
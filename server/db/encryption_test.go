@@ -0,0 +1,127 @@
+package db_test
+
+import (
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/models"
+)
+
+func TestGetEncryptionSchemaMap(t *testing.T) {
+	keyId := primitive.Binary{Subtype: 4, Data: make([]byte, 16)}
+	schemaMap := db.GetEncryptionSchemaMap("schej-it", keyId)
+
+	users, ok := schemaMap["schej-it.users"].(bson.M)
+	if !ok {
+		t.Fatalf("expected a schema for users, got %v", schemaMap)
+	}
+	email := users["properties"].(bson.M)["email"].(bson.M)["encrypt"].(bson.M)
+	if email["algorithm"] != "AEAD_AES_256_CBC_HMAC_SHA_512-Deterministic" {
+		t.Errorf("expected emails to be encrypted deterministically so they can be queried, got %v", email)
+	}
+	if !keyId.Equal(users["encryptMetadata"].(bson.M)["keyId"].(bson.A)[0].(primitive.Binary)) {
+		t.Errorf("expected the schema to use the data key")
+	}
+
+	if _, ok := schemaMap["schej-it.eventResponses"]; !ok {
+		t.Error("expected a schema for event responses")
+	}
+}
+
+// Returns the paths of the encrypted fields in the schema, with their algorithms
+func getEncryptedFields(schema bson.M, prefix string) map[string]string {
+	fields := make(map[string]string)
+	properties, _ := schema["properties"].(bson.M)
+	for name, property := range properties {
+		property := property.(bson.M)
+		if encrypt, ok := property["encrypt"].(bson.M); ok {
+			fields[prefix+name] = encrypt["algorithm"].(string)
+			continue
+		}
+		for path, algorithm := range getEncryptedFields(property, prefix+name+".") {
+			fields[path] = algorithm
+		}
+	}
+	return fields
+}
+
+func TestEncryptedFieldsAreQueryable(t *testing.T) {
+	schemaMap := db.GetEncryptionSchemaMap("schej-it", primitive.Binary{Subtype: 4, Data: make([]byte, 16)})
+
+	// Fields that are looked up by value, which only works when they're
+	// encrypted deterministically
+	for namespace, path := range map[string]string{
+		"schej-it.users":          "email",
+		"schej-it.attendees":      "email",
+		"schej-it.eventResponses": "response.email",
+	} {
+		fields := getEncryptedFields(schemaMap[namespace].(bson.M), "")
+		if algorithm := fields[path]; algorithm != "AEAD_AES_256_CBC_HMAC_SHA_512-Deterministic" {
+			t.Errorf("expected %s in %s to be queryable, got %q", path, namespace, algorithm)
+		}
+	}
+}
+
+func TestMergedUserUpdateSetsEncryptedFieldsWhole(t *testing.T) {
+	schemaMap := db.GetEncryptionSchemaMap("schej-it", primitive.Binary{Subtype: 4, Data: make([]byte, 16)})
+	encrypted := getEncryptedFields(schemaMap["schej-it.users"].(bson.M), "")
+
+	customerId := "cus_123"
+	into := &models.User{
+		Id:               primitive.NewObjectID(),
+		CalendarAccounts: map[string]models.CalendarAccount{"sam@work.com_google": {Email: "sam@work.com"}},
+	}
+	from := &models.User{
+		Id:               primitive.NewObjectID(),
+		StripeCustomerId: &customerId,
+		CalendarAccounts: map[string]models.CalendarAccount{
+			"sam@work.com_google":  {Email: "other@work.com"},
+			"sam@gmail.com_google": {Email: "sam@gmail.com"},
+		},
+	}
+
+	update := db.GetMergedUserUpdate(from, into)
+	for key := range update {
+		for path := range encrypted {
+			if strings.HasPrefix(key, path+".") {
+				t.Errorf("expected %s to be set whole since it's encrypted, got an update of %s", path, key)
+			}
+		}
+	}
+
+	calendarAccounts, ok := update["calendarAccounts"].(map[string]models.CalendarAccount)
+	if !ok || len(calendarAccounts) != 2 || calendarAccounts["sam@work.com_google"].Email != "sam@work.com" {
+		t.Errorf("expected the from user's calendar accounts to be added to the into user's, got %v", update["calendarAccounts"])
+	}
+}
+
+func TestEncryptionRegistry(t *testing.T) {
+	response := models.Response{Name: "Sam"}
+	decode := func(data []byte, err error) bson.M {
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc := bson.M{}
+		if err := bson.Unmarshal(data, &doc); err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+
+	// Encrypted fields can't be null
+	doc := decode(bson.MarshalWithRegistry(db.GetEncryptionRegistry(), response))
+	for _, field := range []string{"availability", "ifNeeded"} {
+		if value, ok := doc[field].(bson.A); !ok || len(value) != 0 {
+			t.Errorf("expected %s to be an empty array, got %#v", field, doc[field])
+		}
+	}
+
+	// Without encryption, responses are stored as they always were
+	doc = decode(bson.Marshal(response))
+	if value, ok := doc["availability"]; !ok || value != nil {
+		t.Errorf("expected availability to be null, got %#v", value)
+	}
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the alias, or nil if it doesn't exist
+func GetEventAlias(alias string) *models.EventAlias {
+	var eventAlias models.EventAlias
+	err := EventAliasesCollection.FindOne(context.Background(), bson.M{"_id": alias}).Decode(&eventAlias)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &eventAlias
+}
+
+// Returns the aliases of the event, oldest first
+func GetEventAliases(eventId primitive.ObjectID) []models.EventAlias {
+	cursor, err := EventAliasesCollection.Find(context.Background(), bson.M{"eventId": eventId}, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	aliases := make([]models.EventAlias, 0)
+	if err := cursor.All(context.Background(), &aliases); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return aliases
+}
+
+// Points the alias at its event, replacing the event it pointed at if any
+func UpsertEventAlias(eventAlias *models.EventAlias) {
+	_, err := EventAliasesCollection.ReplaceOne(context.Background(), bson.M{"_id": eventAlias.Alias}, eventAlias, options.Replace().SetUpsert(true))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Deletes the alias, returning whether it existed
+func DeleteEventAlias(alias string) bool {
+	result, err := EventAliasesCollection.DeleteOne(context.Background(), bson.M{"_id": alias})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return result.DeletedCount > 0
+}
@@ -8,6 +8,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"schej.it/server/logger"
 	"schej.it/server/models"
 )
@@ -137,10 +138,16 @@ func GetEventsCreatedThisMonth(userId primitive.ObjectID) int {
 	now := time.Now()
 	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
 
+	// Drafts only count once they're published
 	result, err := EventsCollection.CountDocuments(context.Background(), bson.M{
 		"ownerId": userId,
-		"_id": bson.M{
-			"$gte": primitive.NewObjectIDFromTimestamp(startOfMonth),
+		"isDraft": bson.M{"$ne": true},
+		"$or": bson.A{
+			bson.M{
+				"_id":         bson.M{"$gte": primitive.NewObjectIDFromTimestamp(startOfMonth)},
+				"publishedAt": bson.M{"$exists": false},
+			},
+			bson.M{"publishedAt": bson.M{"$gte": primitive.NewDateTimeFromTime(startOfMonth)}},
 		},
 	})
 	if err != nil {
@@ -202,3 +209,382 @@ func UpdateGuestResponseName(eventId string, oldName string, newName string) {
 		logger.StdErr.Panicln(err)
 	}
 }
+
+// Returns the events owned by the given user that are not deleted or archived, newest first
+func GetActiveEventsOwnedByUser(userId primitive.ObjectID) []models.Event {
+	cursor, err := EventsCollection.Find(context.Background(), bson.M{
+		"ownerId": userId,
+		"$and": bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"isDeleted": bson.M{"$exists": false}},
+				bson.M{"isDeleted": false},
+			}},
+			bson.M{"$or": bson.A{
+				bson.M{"isArchived": bson.M{"$exists": false}},
+				bson.M{"isArchived": false},
+			}},
+		},
+	}, options.Find().SetSort(bson.M{"_id": -1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	events := make([]models.Event, 0)
+	if err := cursor.All(context.Background(), &events); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return events
+}
+
+// Returns the events that the given email was invited to (as a remindee or an
+// availability group attendee) but hasn't responded to yet
+func GetEventsPendingResponse(email string) []models.Event {
+	eventIds := make([]primitive.ObjectID, 0)
+
+	// Availability groups the user was added to but hasn't responded to
+	cursor, err := AttendeesCollection.Find(context.Background(), bson.M{"email": email, "declined": false})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	var attendees []models.Attendee
+	if err := cursor.All(context.Background(), &attendees); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	user := GetUserByEmail(email)
+	for _, attendee := range attendees {
+		if user != nil {
+			count, err := EventResponsesCollection.CountDocuments(context.Background(), bson.M{
+				"eventId": attendee.EventId,
+				"userId":  user.Id.Hex(),
+			})
+			if err != nil {
+				logger.StdErr.Panicln(err)
+			}
+			if count > 0 {
+				continue
+			}
+		}
+		eventIds = append(eventIds, attendee.EventId)
+	}
+
+	cursor, err = EventsCollection.Find(context.Background(), bson.M{
+		"$and": bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"_id": bson.M{"$in": eventIds}},
+				bson.M{"remindees": bson.M{"$elemMatch": bson.M{"email": email, "responded": false}}},
+			}},
+			bson.M{"$or": bson.A{
+				bson.M{"isDeleted": bson.M{"$exists": false}},
+				bson.M{"isDeleted": false},
+			}},
+			bson.M{"scheduledEvent": bson.M{"$exists": false}},
+		},
+	}, options.Find().SetSort(bson.M{"_id": -1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	events := make([]models.Event, 0)
+	if err := cursor.All(context.Background(), &events); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return events
+}
+
+// Returns the events owned by or responded to by the given user that have been
+// scheduled and haven't ended yet, soonest first
+func GetUpcomingScheduledEvents(userId primitive.ObjectID) []models.Event {
+	eventIds := make([]primitive.ObjectID, 0)
+	for _, eventResponse := range GetEventResponsesByUserId(userId) {
+		eventIds = append(eventIds, eventResponse.EventId)
+	}
+
+	cursor, err := EventsCollection.Find(context.Background(), bson.M{
+		"$and": bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"_id": bson.M{"$in": eventIds}},
+				bson.M{"ownerId": userId},
+			}},
+			bson.M{"$or": bson.A{
+				bson.M{"isDeleted": bson.M{"$exists": false}},
+				bson.M{"isDeleted": false},
+			}},
+			bson.M{"scheduledEvent.endDate": bson.M{"$gte": primitive.NewDateTimeFromTime(time.Now())}},
+		},
+	}, options.Find().SetSort(bson.M{"scheduledEvent.startDate": 1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	events := make([]models.Event, 0)
+	if err := cursor.All(context.Background(), &events); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return events
+}
+
+// Returns the events the given user owns or responded to that were finalized
+// at a time ending after since, either at a single time or in sessions
+func GetFinalizedEventsSince(userId primitive.ObjectID, since time.Time) []models.Event {
+	eventIds := make([]primitive.ObjectID, 0)
+	for _, eventResponse := range GetEventResponsesByUserId(userId) {
+		eventIds = append(eventIds, eventResponse.EventId)
+	}
+
+	cursor, err := EventsCollection.Find(context.Background(), bson.M{
+		"$and": bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"_id": bson.M{"$in": eventIds}},
+				bson.M{"ownerId": userId},
+			}},
+			bson.M{"$or": bson.A{
+				bson.M{"isDeleted": bson.M{"$exists": false}},
+				bson.M{"isDeleted": false},
+			}},
+			bson.M{"$or": bson.A{
+				bson.M{"scheduledEvent.endDate": bson.M{"$gte": primitive.NewDateTimeFromTime(since)}},
+				bson.M{"sessions.endDate": bson.M{"$gte": primitive.NewDateTimeFromTime(since)}},
+			}},
+		},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	events := make([]models.Event, 0)
+	if err := cursor.All(context.Background(), &events); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return events
+}
+
+// Returns the events owned by the given user that are scheduled at a time
+// overlapping the given time range
+func GetScheduledEventsInRange(ownerId primitive.ObjectID, start time.Time, end time.Time) []models.Event {
+	cursor, err := EventsCollection.Find(context.Background(), bson.M{
+		"ownerId": ownerId,
+		"$or": bson.A{
+			bson.M{"isDeleted": bson.M{"$exists": false}},
+			bson.M{"isDeleted": false},
+		},
+		"scheduledEvent.startDate": bson.M{"$lt": primitive.NewDateTimeFromTime(end)},
+		"scheduledEvent.endDate":   bson.M{"$gt": primitive.NewDateTimeFromTime(start)},
+	}, options.Find().SetSort(bson.M{"scheduledEvent.startDate": 1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	events := make([]models.Event, 0)
+	if err := cursor.All(context.Background(), &events); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return events
+}
+
+// Returns the events of the given organization that are scheduled at a time
+// overlapping the given time range, soonest first
+func GetOrganizationScheduledEventsInRange(orgId primitive.ObjectID, start time.Time, end time.Time) []models.Event {
+	cursor, err := EventsCollection.Find(context.Background(), bson.M{
+		"organizationId": orgId,
+		"$or": bson.A{
+			bson.M{"isDeleted": bson.M{"$exists": false}},
+			bson.M{"isDeleted": false},
+		},
+		"scheduledEvent.startDate": bson.M{"$lt": primitive.NewDateTimeFromTime(end)},
+		"scheduledEvent.endDate":   bson.M{"$gt": primitive.NewDateTimeFromTime(start)},
+	}, options.Find().SetSort(bson.M{"scheduledEvent.startDate": 1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	events := make([]models.Event, 0)
+	if err := cursor.All(context.Background(), &events); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return events
+}
+
+// Returns all the event responses of the given user
+func GetEventResponsesByUserId(userId primitive.ObjectID) []models.EventResponse {
+	cursor, err := EventResponsesCollection.Find(context.Background(), bson.M{"userId": userId.Hex()})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	eventResponses := make([]models.EventResponse, 0)
+	if err := cursor.All(context.Background(), &eventResponses); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return eventResponses
+}
+
+// Inserts the given event, filling in the id, short id, and counters if they
+// aren't set. Returns the id of the inserted event
+func InsertEvent(event *models.Event) string {
+	if event.Id.IsZero() {
+		event.Id = primitive.NewObjectID()
+	}
+	if event.ShortId == nil {
+		shortId := GenerateShortEventId(event.Id)
+		event.ShortId = &shortId
+	}
+	if event.NumResponses == nil {
+		numResponses := 0
+		event.NumResponses = &numResponses
+	}
+	if event.SignUpResponses == nil {
+		event.SignUpResponses = make(map[string]*models.SignUpResponse)
+	}
+
+	result, err := EventsCollection.InsertOne(context.Background(), event)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return result.InsertedID.(primitive.ObjectID).Hex()
+}
+
+// Returns the events that keep their remindees in sync with a Google
+// Classroom roster, and aren't deleted or scheduled
+func GetEventsWithClassroomRoster() []models.Event {
+	cursor, err := EventsCollection.Find(context.Background(), bson.M{
+		"classroomRoster": bson.M{"$exists": true},
+		"isDeleted":       bson.M{"$ne": true},
+		"scheduledEvent":  bson.M{"$exists": false},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	events := make([]models.Event, 0)
+	if err := cursor.All(context.Background(), &events); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return events
+}
+
+// Returns the event whose email poll has a recipient with the given key
+// Returns the event with the given availability share token, or nil if there
+// is none
+func GetEventByAvailabilityShareToken(token string) *models.Event {
+	var event models.Event
+	err := EventsCollection.FindOne(context.Background(), bson.M{
+		"availabilityShares.token": token,
+		"isDeleted":                bson.M{"$ne": true},
+	}).Decode(&event)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &event
+}
+
+func GetEventByEmailPollKey(key string) *models.Event {
+	var event models.Event
+	err := EventsCollection.FindOne(context.Background(), bson.M{
+		"emailPoll.recipients.key": key,
+		"isDeleted":                bson.M{"$ne": true},
+	}).Decode(&event)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &event
+}
+
+// Returns the user's drafts, most recently created first
+func GetDraftEvents(ownerId primitive.ObjectID) []models.Event {
+	cursor, err := EventsCollection.Find(context.Background(), bson.M{
+		"ownerId":   ownerId,
+		"isDraft":   true,
+		"isDeleted": bson.M{"$ne": true},
+	}, options.Find().SetSort(bson.M{"_id": -1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	events := make([]models.Event, 0)
+	if err := cursor.All(context.Background(), &events); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return events
+}
+
+// Returns the days only polls whose next phase is due to be created, and
+// hasn't been yet
+func GetEventsDueForNextPhase(now time.Time) []models.Event {
+	cursor, err := EventsCollection.Find(context.Background(), bson.M{
+		"nextPhase.advanceAt": bson.M{"$lte": primitive.NewDateTimeFromTime(now)},
+		"nextPhase.eventId":   bson.M{"$exists": false},
+		"isDeleted":           bson.M{"$ne": true},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	events := make([]models.Event, 0)
+	if err := cursor.All(context.Background(), &events); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return events
+}
+
+// Returns the events with the given ids that aren't deleted
+func GetEventsByIds(eventIds []primitive.ObjectID) []models.Event {
+	cursor, err := EventsCollection.Find(context.Background(), bson.M{
+		"_id": bson.M{"$in": eventIds},
+		"$or": bson.A{
+			bson.M{"isDeleted": bson.M{"$exists": false}},
+			bson.M{"isDeleted": false},
+		},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	events := make([]models.Event, 0)
+	if err := cursor.All(context.Background(), &events); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return events
+}
+
+// Returns the most recently created events owned by the given user that
+// aren't deleted or drafts
+func GetRecentEventsByOwner(ownerId primitive.ObjectID, limit int64) []models.Event {
+	cursor, err := EventsCollection.Find(context.Background(), bson.M{
+		"ownerId": ownerId,
+		"isDraft": bson.M{"$ne": true},
+		"$or": bson.A{
+			bson.M{"isDeleted": bson.M{"$exists": false}},
+			bson.M{"isDeleted": false},
+		},
+	}, options.Find().SetSort(bson.M{"_id": -1}).SetLimit(limit))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	events := make([]models.Event, 0)
+	if err := cursor.All(context.Background(), &events); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return events
+}
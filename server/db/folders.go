@@ -5,6 +5,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"schej.it/server/logger"
 	"schej.it/server/models"
@@ -36,13 +37,38 @@ func GetFolderById(folderId primitive.ObjectID, userId primitive.ObjectID) (*mod
 	return &folder, nil
 }
 
-func GetAllFolders(userId primitive.ObjectID) ([]models.Folder, error) {
-	cursor, err := FoldersCollection.Find(context.Background(), bson.M{
-		"userId": userId,
+// Returns the folder with the given id whoever created it, which callers
+// check access to
+func GetFolder(folderId primitive.ObjectID) (*models.Folder, error) {
+	var folder models.Folder
+	err := FoldersCollection.FindOne(context.Background(), bson.M{
+		"_id": folderId,
 		"$or": bson.A{
 			bson.M{"isDeleted": bson.M{"$exists": false}},
 			bson.M{"isDeleted": false},
 		},
+	}).Decode(&folder)
+	if err != nil {
+		return nil, err
+	}
+
+	return &folder, nil
+}
+
+// Returns the user's personal folders and the folders of the given
+// organizations
+func GetAllFolders(userId primitive.ObjectID, orgIds []primitive.ObjectID) ([]models.Folder, error) {
+	cursor, err := FoldersCollection.Find(context.Background(), bson.M{
+		"$and": bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"userId": userId, "organizationId": bson.M{"$exists": false}},
+				bson.M{"organizationId": bson.M{"$in": orgIds}},
+			}},
+			bson.M{"$or": bson.A{
+				bson.M{"isDeleted": bson.M{"$exists": false}},
+				bson.M{"isDeleted": false},
+			}},
+		},
 	})
 	if err != nil {
 		return nil, err
@@ -53,8 +79,8 @@ func GetAllFolders(userId primitive.ObjectID) ([]models.Folder, error) {
 		return nil, err
 	}
 
-	for i, folder := range folders {
-		events, err := GetEventsInFolder(folder.Id, userId)
+	for i := range folders {
+		events, err := GetFolderEventIds(&folders[i])
 		if err != nil {
 			return nil, err
 		}
@@ -92,6 +118,31 @@ func GetEventsInFolder(folderId primitive.ObjectID, userId primitive.ObjectID) (
 	return eventIds, nil
 }
 
+// Returns the events in the folder: the ones its creator put in it, or the
+// ones any member put in it for organization folders
+func GetFolderEventIds(folder *models.Folder) ([]primitive.ObjectID, error) {
+	if folder.OrganizationId == nil {
+		return GetEventsInFolder(folder.Id, folder.UserId)
+	}
+
+	cursor, err := FolderEventsCollection.Find(context.Background(), bson.M{"folderId": folder.Id}, options.Find().SetProjection(bson.M{"eventId": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var folderEvents []models.FolderEvent
+	if err = cursor.All(context.Background(), &folderEvents); err != nil {
+		return nil, err
+	}
+
+	eventIds := make([]primitive.ObjectID, len(folderEvents))
+	for i, folderEvent := range folderEvents {
+		eventIds[i] = folderEvent.EventId
+	}
+
+	return eventIds, nil
+}
+
 func UpdateFolder(folderId primitive.ObjectID, userId primitive.ObjectID, updates bson.M) error {
 	_, err := FoldersCollection.UpdateOne(context.Background(), bson.M{"_id": folderId, "userId": userId}, bson.M{"$set": updates})
 	return err
@@ -151,3 +202,59 @@ func DeleteFolder(folderId primitive.ObjectID, userId primitive.ObjectID) error
 
 	return nil
 }
+
+// Returns the organization's folder with the given external id, or nil if it
+// doesn't exist
+func GetFolderByExternalId(orgId primitive.ObjectID, externalId string) *models.Folder {
+	var folder models.Folder
+	err := FoldersCollection.FindOne(context.Background(), bson.M{
+		"organizationId": orgId,
+		"externalId":     externalId,
+		"$or": bson.A{
+			bson.M{"isDeleted": bson.M{"$exists": false}},
+			bson.M{"isDeleted": false},
+		},
+	}).Decode(&folder)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &folder
+}
+
+// Deletes the folder of an organization. Unlike personal folders, its events
+// aren't deleted since they belong to the members that put them in it
+func DeleteOrganizationFolder(folderId primitive.ObjectID) error {
+	ctx := context.Background()
+	_, err := FoldersCollection.UpdateByID(ctx, folderId, bson.M{"$set": bson.M{"isDeleted": true}})
+	if err != nil {
+		return err
+	}
+
+	_, err = FolderEventsCollection.DeleteMany(ctx, bson.M{"folderId": folderId})
+	return err
+}
+
+// Returns the folder the user put the event in, or nil if it isn't in one
+func GetEventFolder(eventId primitive.ObjectID, userId primitive.ObjectID) *models.Folder {
+	var folderEvent models.FolderEvent
+	err := FolderEventsCollection.FindOne(context.Background(), bson.M{"eventId": eventId, "userId": userId}).Decode(&folderEvent)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	folder, err := GetFolder(folderEvent.FolderId)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return folder
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Records that the given event was shared to the given Google Chat space
+func AddGoogleChatShare(eventId primitive.ObjectID, space string) {
+	_, err := GoogleChatSharesCollection.UpdateOne(
+		context.Background(),
+		bson.M{"eventId": eventId, "space": space},
+		bson.M{"$set": bson.M{"sharedAt": primitive.NewDateTimeFromTime(time.Now())}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Returns all the Google Chat spaces the given event was shared to
+func GetGoogleChatShares(eventId primitive.ObjectID) []models.GoogleChatShare {
+	cursor, err := GoogleChatSharesCollection.Find(context.Background(), bson.M{"eventId": eventId})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	shares := make([]models.GoogleChatShare, 0)
+	if err := cursor.All(context.Background(), &shares); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return shares
+}
+
+// Removes all the shares for the given Google Chat space (e.g. when the app is removed from the space)
+func DeleteGoogleChatSharesForSpace(space string) {
+	_, err := GoogleChatSharesCollection.DeleteMany(context.Background(), bson.M{"space": space})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
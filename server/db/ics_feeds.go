@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the cached feed with the given url, or nil if it isn't cached
+func GetIcsFeedByUrl(url string) *models.IcsFeed {
+	var feed models.IcsFeed
+	err := IcsFeedsCollection.FindOne(context.Background(), bson.M{"url": url}).Decode(&feed)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return &feed
+}
+
+// Inserts the feed, or replaces the cached feed with the same url
+func UpsertIcsFeed(feed *models.IcsFeed) {
+	update := bson.M{
+		"busyTimes":  feed.BusyTimes,
+		"fetchedAt":  feed.FetchedAt,
+		"lastUsedAt": feed.LastUsedAt,
+		"lastError":  feed.LastError,
+	}
+	_, err := IcsFeedsCollection.UpdateOne(context.Background(), bson.M{"url": feed.Url}, bson.M{"$set": update}, options.Update().SetUpsert(true))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Returns the feeds used since usedSince that were last fetched before
+// fetchedBefore
+func GetIcsFeedsToRefresh(usedSince time.Time, fetchedBefore time.Time) []models.IcsFeed {
+	return findAll[models.IcsFeed](IcsFeedsCollection, bson.M{
+		"lastUsedAt": bson.M{"$gte": primitive.NewDateTimeFromTime(usedSince)},
+		"fetchedAt":  bson.M{"$lt": primitive.NewDateTimeFromTime(fetchedBefore)},
+	})
+}
+
+// Deletes the feeds that haven't been used since the given time
+func DeleteUnusedIcsFeeds(usedBefore time.Time) {
+	_, err := IcsFeedsCollection.DeleteMany(context.Background(), bson.M{"lastUsedAt": bson.M{"$lt": primitive.NewDateTimeFromTime(usedBefore)}})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
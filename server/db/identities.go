@@ -0,0 +1,158 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the user the external identity is linked to, or nil if it isn't
+// linked to anyone
+func GetUserByIdentity(provider models.IdentityProvider, subject string) *models.User {
+	result := UsersCollection.FindOne(context.Background(), bson.M{
+		"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}},
+	})
+	if result.Err() == mongo.ErrNoDocuments {
+		return nil
+	}
+
+	var user models.User
+	if err := result.Decode(&user); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return &user
+}
+
+// Links the identity to the user, replacing the user's existing link to the
+// same external account
+func AddUserIdentity(userId primitive.ObjectID, identity models.Identity) {
+	RemoveUserIdentity(userId, identity.Provider, identity.Subject)
+	_, err := UsersCollection.UpdateByID(context.Background(), userId, bson.M{
+		"$push": bson.M{"identities": identity},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Unlinks the identity from the user
+func RemoveUserIdentity(userId primitive.ObjectID, provider models.IdentityProvider, subject string) {
+	_, err := UsersCollection.UpdateByID(context.Background(), userId, bson.M{
+		"$pull": bson.M{"identities": bson.M{"provider": provider, "subject": subject}},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Moves everything that belongs to the from user to the into user, then
+// deletes the from user. Responses of the from user to events the into user
+// already responded to are dropped in favor of the into user's, and the into
+// user keeps their stripe customer if both have one. Returns how many
+// documents were moved in each collection
+func MergeUsers(from *models.User, into *models.User) map[string]int {
+	moved := make(map[string]int)
+	updateMany := func(collection *mongo.Collection, filter bson.M, update bson.M) {
+		result, err := collection.UpdateMany(context.Background(), filter, update)
+		if err != nil {
+			logger.StdErr.Panicln(err)
+		}
+		moved[collection.Name()] += int(result.ModifiedCount)
+	}
+	deleteMany := func(collection *mongo.Collection, filter bson.M) {
+		if _, err := collection.DeleteMany(context.Background(), filter); err != nil {
+			logger.StdErr.Panicln(err)
+		}
+	}
+
+	fromHex, intoHex := from.Id.Hex(), into.Id.Hex()
+
+	// Responses, keeping the into user's when both responded to an event
+	intoEventIds := make([]primitive.ObjectID, 0)
+	for _, response := range findAll[models.EventResponse](EventResponsesCollection, bson.M{"userId": intoHex}) {
+		intoEventIds = append(intoEventIds, response.EventId)
+	}
+	deleteMany(EventResponsesCollection, bson.M{"userId": fromHex, "eventId": bson.M{"$in": intoEventIds}})
+	updateMany(EventResponsesCollection, bson.M{"userId": fromHex}, bson.M{"$set": bson.M{"userId": intoHex, "response.userId": into.Id}})
+
+	// Events and everything else owned by the from user
+	updateMany(EventsCollection, bson.M{"ownerId": from.Id}, bson.M{"$set": bson.M{"ownerId": into.Id}})
+	updateMany(EventsCollection, bson.M{"coOrganizers.userId": from.Id}, bson.M{"$set": bson.M{"coOrganizers.$.userId": into.Id}})
+	updateMany(ContactsCollection, bson.M{"ownerId": from.Id}, bson.M{"$set": bson.M{"ownerId": into.Id}})
+	updateMany(ContactGroupsCollection, bson.M{"ownerId": from.Id}, bson.M{"$set": bson.M{"ownerId": into.Id}})
+	updateMany(WebhooksCollection, bson.M{"ownerId": from.Id}, bson.M{"$set": bson.M{"ownerId": into.Id}})
+	updateMany(ApiKeysCollection, bson.M{"ownerId": from.Id}, bson.M{"$set": bson.M{"ownerId": into.Id}})
+	updateMany(ApiKeyUsageCollection, bson.M{"ownerId": from.Id}, bson.M{"$set": bson.M{"ownerId": into.Id}})
+	updateMany(FoldersCollection, bson.M{"userId": from.Id}, bson.M{"$set": bson.M{"userId": into.Id}})
+	updateMany(FolderEventsCollection, bson.M{"userId": from.Id}, bson.M{"$set": bson.M{"userId": into.Id}})
+	updateMany(SlackAccountsCollection, bson.M{"userId": from.Id}, bson.M{"$set": bson.M{"userId": into.Id}})
+	updateMany(ConsentsCollection, bson.M{"userId": fromHex}, bson.M{"$set": bson.M{"userId": intoHex}})
+	updateMany(PaymentsCollection, bson.M{"userId": fromHex}, bson.M{"$set": bson.M{"userId": intoHex}})
+	updateMany(ActivitiesCollection, bson.M{"actorId": from.Id}, bson.M{"$set": bson.M{"actorId": into.Id}})
+	updateMany(AutomationRulesCollection, bson.M{"createdBy": from.Id}, bson.M{"$set": bson.M{"createdBy": into.Id}})
+	updateMany(DelegationsCollection, bson.M{"principalId": from.Id}, bson.M{"$set": bson.M{"principalId": into.Id}})
+	updateMany(DelegationsCollection, bson.M{"delegateId": from.Id}, bson.M{"$set": bson.M{"delegateId": into.Id}})
+	updateMany(DelegationAuditsCollection, bson.M{"principalId": from.Id}, bson.M{"$set": bson.M{"principalId": into.Id}})
+	updateMany(DelegationAuditsCollection, bson.M{"delegateId": from.Id}, bson.M{"$set": bson.M{"delegateId": into.Id}})
+	updateMany(DelegationsCollection, bson.M{"principalId": into.Id, "delegateId": into.Id, "revokedAt": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"revokedAt": primitive.NewDateTimeFromTime(time.Now())}})
+
+	// Organization memberships, keeping the into user's role when both are members
+	updateMany(OrganizationsCollection, bson.M{"$and": bson.A{
+		bson.M{"members.userId": from.Id},
+		bson.M{"members.userId": into.Id},
+	}}, bson.M{"$pull": bson.M{"members": bson.M{"userId": from.Id}}})
+	updateMany(OrganizationsCollection, bson.M{"members.userId": from.Id}, bson.M{"$set": bson.M{"members.$.userId": into.Id}})
+
+	deleteMany(FriendRequestsCollection, bson.M{"$or": bson.A{bson.M{"from": from.Id}, bson.M{"to": from.Id}}})
+	updateMany(DailyUserLogCollection, bson.M{"userIds": from.Id}, bson.M{"$pull": bson.M{"userIds": from.Id}})
+
+	// Identities of the from user, without overriding the into user's
+	updateMany(UsersCollection, bson.M{"_id": into.Id}, bson.M{"$set": GetMergedUserUpdate(from, into)})
+	for _, identity := range from.Identities {
+		AddUserIdentity(into.Id, identity)
+	}
+
+	deleteMany(AccountMergesCollection, bson.M{"$or": bson.A{bson.M{"intoUserId": from.Id}, bson.M{"fromUserId": from.Id}}})
+	deleteMany(UsersCollection, bson.M{"_id": from.Id})
+
+	return moved
+}
+
+// Returns the fields of the into user that take on the from user's calendar
+// accounts, billing and counts when the from user is merged into them, without
+// overriding the into user's
+func GetMergedUserUpdate(from *models.User, into *models.User) bson.M {
+	set := bson.M{}
+
+	// Calendar accounts. The whole map is set, since it's encrypted as one field
+	// when field level encryption is enabled
+	calendarAccounts := make(map[string]models.CalendarAccount, len(into.CalendarAccounts)+len(from.CalendarAccounts))
+	for key, calendarAccount := range into.CalendarAccounts {
+		calendarAccounts[key] = calendarAccount
+	}
+	for key, calendarAccount := range from.CalendarAccounts {
+		if _, ok := calendarAccounts[key]; !ok {
+			calendarAccounts[key] = calendarAccount
+			set["calendarAccounts"] = calendarAccounts
+		}
+	}
+
+	// Billing, keeping the into user's subscription and payout account if they have them
+	if from.StripeCustomerId != nil && into.StripeCustomerId == nil {
+		set["stripeCustomerId"] = *from.StripeCustomerId
+		set["isPremium"] = from.IsPremium
+	}
+	if from.StripeAccountId != nil && into.StripeAccountId == nil {
+		set["stripeAccountId"] = *from.StripeAccountId
+		set["stripeAccountEnabled"] = from.StripeAccountEnabled
+		set["stripePayoutsEnabled"] = from.StripePayoutsEnabled
+	}
+	set["numEventsCreated"] = into.NumEventsCreated + from.NumEventsCreated
+	return set
+}
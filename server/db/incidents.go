@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Checks that the database is reachable
+func Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return Client.Ping(ctx, readpref.Primary())
+}
+
+func GetIncidentById(incidentId string) *models.Incident {
+	objectId, err := primitive.ObjectIDFromHex(incidentId)
+	if err != nil {
+		return nil
+	}
+
+	var incident models.Incident
+	if err := IncidentsCollection.FindOne(context.Background(), bson.M{"_id": objectId}).Decode(&incident); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &incident
+}
+
+// Returns the incidents that are unresolved or were resolved after the given
+// time, most recent first
+func GetRecentIncidents(resolvedAfter time.Time) []models.Incident {
+	cursor, err := IncidentsCollection.Find(context.Background(), bson.M{
+		"$or": bson.A{
+			bson.M{"resolvedAt": bson.M{"$exists": false}},
+			bson.M{"resolvedAt": bson.M{"$gte": primitive.NewDateTimeFromTime(resolvedAfter)}},
+		},
+	}, options.Find().SetSort(bson.M{"createdAt": -1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	incidents := make([]models.Incident, 0)
+	if err := cursor.All(context.Background(), &incidents); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return incidents
+}
+
+func InsertIncident(incident *models.Incident) {
+	if incident.Id.IsZero() {
+		incident.Id = primitive.NewObjectID()
+	}
+	_, err := IncidentsCollection.InsertOne(context.Background(), incident)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+func UpdateIncident(incident *models.Incident) {
+	_, err := IncidentsCollection.ReplaceOne(context.Background(), bson.M{"_id": incident.Id}, incident)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+func DeleteIncident(incidentId primitive.ObjectID) {
+	_, err := IncidentsCollection.DeleteOne(context.Background(), bson.M{"_id": incidentId})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"net"
 	"os"
 	"strings"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"schej.it/server/logger"
+	"schej.it/server/services/faults"
 )
 
 var Client *mongo.Client
@@ -21,6 +23,44 @@ var EventResponsesCollection *mongo.Collection
 var AttendeesCollection *mongo.Collection
 var FoldersCollection *mongo.Collection
 var FolderEventsCollection *mongo.Collection
+var SlackAccountsCollection *mongo.Collection
+var SlackLinkCodesCollection *mongo.Collection
+var GoogleChatSharesCollection *mongo.Collection
+var TermsCollection *mongo.Collection
+var ResourcesCollection *mongo.Collection
+var ResourceBookingsCollection *mongo.Collection
+var PaymentsCollection *mongo.Collection
+var ConsentsCollection *mongo.Collection
+var NotificationOptOutsCollection *mongo.Collection
+var BroadcastsCollection *mongo.Collection
+var OrganizationsCollection *mongo.Collection
+var WebhooksCollection *mongo.Collection
+var WebhookDeliveriesCollection *mongo.Collection
+var LtiPlatformsCollection *mongo.Collection
+var LtiIdentitiesCollection *mongo.Collection
+var ContactsCollection *mongo.Collection
+var ContactGroupsCollection *mongo.Collection
+var ActivitiesCollection *mongo.Collection
+var IncidentsCollection *mongo.Collection
+var SubmissionNoncesCollection *mongo.Collection
+var LegalDocumentsCollection *mongo.Collection
+var ScheduledTasksCollection *mongo.Collection
+var UserSessionsCollection *mongo.Collection
+var IcsFeedsCollection *mongo.Collection
+var InviteBatchesCollection *mongo.Collection
+var ApiKeysCollection *mongo.Collection
+var UsageCountersCollection *mongo.Collection
+var ApiKeyUsageCollection *mongo.Collection
+var EmailSuppressionsCollection *mongo.Collection
+var AccountMergesCollection *mongo.Collection
+var TelemetryReportsCollection *mongo.Collection
+var AutomationRulesCollection *mongo.Collection
+var AutomationRunsCollection *mongo.Collection
+var DelegationsCollection *mongo.Collection
+var DelegationAuditsCollection *mongo.Collection
+var EventAliasesCollection *mongo.Collection
+var RegionsCollection *mongo.Collection
+var InstanceSettingsCollection *mongo.Collection
 
 func Init() func() {
 	// Establish mongodb connection
@@ -42,14 +82,14 @@ func Init() func() {
 
 	// Try primary URI, then fall back to localhost if the host "mongo" is unreachable (common when running API outside compose).
 	connectURI := mongoURI
-	Client, err = mongo.Connect(ctx, options.Client().ApplyURI(connectURI))
+	Client, err = connect(ctx, connectURI)
 	if err != nil {
 		logger.StdErr.Printf("failed to connect to Mongo at %s: %v\n", connectURI, err)
 		// Always try a localhost fallback on any error (covers parse errors or host resolution failures)
 		fallback := "mongodb://localhost:27017"
 		if connectURI != fallback {
 			logger.StdErr.Printf("retrying Mongo connection with fallback %s\n", fallback)
-			Client, err = mongo.Connect(ctx, options.Client().ApplyURI(fallback))
+			Client, err = connect(ctx, fallback)
 		}
 		if err != nil {
 			logger.StdErr.Panicln(err)
@@ -66,11 +106,71 @@ func Init() func() {
 	AttendeesCollection = Db.Collection("attendees")
 	FoldersCollection = Db.Collection("folders")
 	FolderEventsCollection = Db.Collection("folderEvents")
+	SlackAccountsCollection = Db.Collection("slackAccounts")
+	SlackLinkCodesCollection = Db.Collection("slackLinkCodes")
+	GoogleChatSharesCollection = Db.Collection("googleChatShares")
+	TermsCollection = Db.Collection("terms")
+	ResourcesCollection = Db.Collection("resources")
+	ResourceBookingsCollection = Db.Collection("resourceBookings")
+	PaymentsCollection = Db.Collection("payments")
+	ConsentsCollection = Db.Collection("consents")
+	NotificationOptOutsCollection = Db.Collection("notificationOptOuts")
+	BroadcastsCollection = Db.Collection("broadcasts")
+	OrganizationsCollection = Db.Collection("organizations")
+	WebhooksCollection = Db.Collection("webhooks")
+	WebhookDeliveriesCollection = Db.Collection("webhookDeliveries")
+	LtiPlatformsCollection = Db.Collection("ltiPlatforms")
+	LtiIdentitiesCollection = Db.Collection("ltiIdentities")
+	ContactsCollection = Db.Collection("contacts")
+	ContactGroupsCollection = Db.Collection("contactGroups")
+	ActivitiesCollection = Db.Collection("activities")
+	IncidentsCollection = Db.Collection("incidents")
+	SubmissionNoncesCollection = Db.Collection("submissionNonces")
+	LegalDocumentsCollection = Db.Collection("legalDocuments")
+	ScheduledTasksCollection = Db.Collection("scheduledTasks")
+	UserSessionsCollection = Db.Collection("userSessions")
+	IcsFeedsCollection = Db.Collection("icsFeeds")
+	InviteBatchesCollection = Db.Collection("inviteBatches")
+	ApiKeysCollection = Db.Collection("apiKeys")
+	UsageCountersCollection = Db.Collection("usageCounters")
+	ApiKeyUsageCollection = Db.Collection("apiKeyUsage")
+	EmailSuppressionsCollection = Db.Collection("emailSuppressions")
+	AccountMergesCollection = Db.Collection("accountMerges")
+	TelemetryReportsCollection = Db.Collection("telemetryReports")
+	AutomationRulesCollection = Db.Collection("automationRules")
+	AutomationRunsCollection = Db.Collection("automationRuns")
+	DelegationsCollection = Db.Collection("delegations")
+	DelegationAuditsCollection = Db.Collection("delegationAudits")
+	EventAliasesCollection = Db.Collection("eventAliases")
+	RegionsCollection = Db.Collection("regions")
+	InstanceSettingsCollection = Db.Collection("instanceSettings")
 
 	// Return a function to close the connection
 	return func() {
-		Client.Disconnect(ctx)
+		// The connection context has expired by now, so disconnect with a new one
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := Client.Disconnect(ctx); err != nil {
+			logger.StdErr.Println(err)
+		}
+	}
+}
+
+// Connects to the database, encrypting the sensitive fields if encryption is enabled
+func connect(ctx context.Context, uri string) (*mongo.Client, error) {
+	clientOptions := options.Client().ApplyURI(uri)
+	if faults.Enabled() {
+		clientOptions.SetDialer(&faults.Dialer{Target: faults.MONGO, Base: &net.Dialer{}})
+	}
+	if EncryptionEnabled() {
+		autoEncryptionOptions, err := getAutoEncryptionOptions(ctx, uri, "schej-it")
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetAutoEncryptionOptions(autoEncryptionOptions)
+		clientOptions.SetRegistry(GetEncryptionRegistry())
 	}
+	return mongo.Connect(ctx, clientOptions)
 }
 
 // MongoDB backup / restore commands
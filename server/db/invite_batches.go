@@ -0,0 +1,159 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the invite batch of the event with the given id, or nil if it
+// doesn't exist
+func GetInviteBatchById(eventId primitive.ObjectID, batchId string) *models.InviteBatch {
+	objectId, err := primitive.ObjectIDFromHex(batchId)
+	if err != nil {
+		return nil
+	}
+
+	var batch models.InviteBatch
+	err = InviteBatchesCollection.FindOne(context.Background(), bson.M{"_id": objectId, "eventId": eventId}).Decode(&batch)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &batch
+}
+
+// Returns the invite batches of the given event, most recent first
+func GetEventInviteBatches(eventId primitive.ObjectID) []models.InviteBatch {
+	cursor, err := InviteBatchesCollection.Find(context.Background(), bson.M{"eventId": eventId}, options.Find().SetSort(bson.M{"createdAt": -1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	batches := make([]models.InviteBatch, 0)
+	if err := cursor.All(context.Background(), &batches); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return batches
+}
+
+func InsertInviteBatch(batch *models.InviteBatch) {
+	if batch.Id.IsZero() {
+		batch.Id = primitive.NewObjectID()
+	}
+	_, err := InviteBatchesCollection.InsertOne(context.Background(), batch)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Claims a batch that still has invites to send, locking it until the given
+// time. Returns nil if there are none
+func ClaimInviteBatch(now time.Time, lockedUntil time.Time) *models.InviteBatch {
+	var batch models.InviteBatch
+	err := InviteBatchesCollection.FindOneAndUpdate(context.Background(), bson.M{
+		"status": models.INVITE_BATCH_SENDING,
+		"$or": bson.A{
+			bson.M{"lockedUntil": bson.M{"$exists": false}},
+			bson.M{"lockedUntil": bson.M{"$lte": primitive.NewDateTimeFromTime(now)}},
+		},
+	}, bson.M{
+		"$set": bson.M{"lockedUntil": primitive.NewDateTimeFromTime(lockedUntil)},
+	}, options.FindOneAndUpdate().SetSort(bson.M{"createdAt": 1}).SetReturnDocument(options.After)).Decode(&batch)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &batch
+}
+
+// Saves the status of the invitee after trying to send their invite, and
+// counts it in the batch's totals
+func SetInviteStatus(batchId primitive.ObjectID, invitee *models.Invitee) {
+	counts := map[models.InviteStatus]string{
+		models.INVITE_SENT:    "numSent",
+		models.INVITE_FAILED:  "numFailed",
+		models.INVITE_SKIPPED: "numSkipped",
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"invitees.$.status": invitee.Status,
+			"invitees.$.error":  invitee.Error,
+			"invitees.$.sentAt": invitee.SentAt,
+		},
+	}
+	if count, ok := counts[invitee.Status]; ok {
+		update["$inc"] = bson.M{count: 1}
+	}
+	_, err := InviteBatchesCollection.UpdateOne(context.Background(), bson.M{"_id": batchId, "invitees.key": invitee.Key}, update)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Unlocks the claimed batch, marking it as sent if done and it wasn't
+// cancelled in the meantime
+func ReleaseInviteBatch(batchId primitive.ObjectID, done bool, now time.Time) {
+	_, err := InviteBatchesCollection.UpdateByID(context.Background(), batchId, bson.M{"$unset": bson.M{"lockedUntil": ""}})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	if done {
+		_, err := InviteBatchesCollection.UpdateOne(context.Background(), bson.M{"_id": batchId, "status": models.INVITE_BATCH_SENDING}, bson.M{
+			"$set": bson.M{"status": models.INVITE_BATCH_SENT, "completedAt": primitive.NewDateTimeFromTime(now)},
+		})
+		if err != nil {
+			logger.StdErr.Panicln(err)
+		}
+	}
+}
+
+// Cancels the batch if it's still sending, returning whether it was
+func CancelInviteBatch(batchId primitive.ObjectID, now time.Time) bool {
+	result, err := InviteBatchesCollection.UpdateOne(context.Background(), bson.M{"_id": batchId, "status": models.INVITE_BATCH_SENDING}, bson.M{
+		"$set": bson.M{"status": models.INVITE_BATCH_CANCELLED, "completedAt": primitive.NewDateTimeFromTime(now)},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return result.ModifiedCount > 0
+}
+
+// Records when the invitee with the given key first opened their link,
+// returning the event of their batch, or nil if the key doesn't exist
+func SetInviteOpened(key string, now time.Time) *primitive.ObjectID {
+	var batch models.InviteBatch
+	err := InviteBatchesCollection.FindOne(context.Background(), bson.M{"invitees.key": key}, options.FindOne().SetProjection(bson.M{"eventId": 1})).Decode(&batch)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	_, err = InviteBatchesCollection.UpdateOne(context.Background(), bson.M{
+		"_id":      batch.Id,
+		"invitees": bson.M{"$elemMatch": bson.M{"key": key, "openedAt": bson.M{"$exists": false}}},
+	}, bson.M{
+		"$set": bson.M{"invitees.$.openedAt": primitive.NewDateTimeFromTime(now)},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return &batch.EventId
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns every published version of the legal documents, oldest first
+func GetLegalDocuments() []models.LegalDocument {
+	cursor, err := LegalDocumentsCollection.Find(context.Background(), bson.M{}, options.Find().SetSort(bson.D{{Key: "type", Value: 1}, {Key: "version", Value: 1}}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	documents := make([]models.LegalDocument, 0)
+	if err := cursor.All(context.Background(), &documents); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return documents
+}
+
+func InsertLegalDocument(document *models.LegalDocument) {
+	if document.Id.IsZero() {
+		document.Id = primitive.NewObjectID()
+	}
+	_, err := LegalDocumentsCollection.InsertOne(context.Background(), document)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Returns how many users accepted each version of the legal documents, keyed
+// by type and then version
+func CountLegalAcceptances() map[models.LegalDocumentType]map[int]int {
+	cursor, err := UsersCollection.Aggregate(context.Background(), mongo.Pipeline{
+		{{Key: "$unwind", Value: "$legalAcceptances"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"type": "$legalAcceptances.type", "version": "$legalAcceptances.version"},
+			"count": bson.M{"$sum": 1},
+		}}},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	var results []struct {
+		Id struct {
+			Type    models.LegalDocumentType `bson:"type"`
+			Version int                      `bson:"version"`
+		} `bson:"_id"`
+		Count int `bson:"count"`
+	}
+	if err := cursor.All(context.Background(), &results); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	counts := make(map[models.LegalDocumentType]map[int]int)
+	for _, result := range results {
+		if _, ok := counts[result.Id.Type]; !ok {
+			counts[result.Id.Type] = make(map[int]int)
+		}
+		counts[result.Id.Type][result.Id.Version] = result.Count
+	}
+	return counts
+}
+
+func SetLegalAcceptances(userId primitive.ObjectID, acceptances []models.LegalAcceptance) {
+	_, err := UsersCollection.UpdateByID(context.Background(), userId, bson.M{
+		"$set": bson.M{"legalAcceptances": acceptances},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
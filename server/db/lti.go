@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+func GetLtiPlatformById(platformId string) *models.LtiPlatform {
+	platformIdObj, err := primitive.ObjectIDFromHex(platformId)
+	if err != nil {
+		return nil
+	}
+
+	var platform models.LtiPlatform
+	if err := LtiPlatformsCollection.FindOne(context.Background(), bson.M{"_id": platformIdObj}).Decode(&platform); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &platform
+}
+
+// Returns the platform registered with the given issuer and client id. The
+// client id is optional in login requests, in which case only the issuer is matched
+func GetLtiPlatformByIssuer(issuer string, clientId string) *models.LtiPlatform {
+	filter := bson.M{"issuer": issuer}
+	if len(clientId) > 0 {
+		filter["clientId"] = clientId
+	}
+
+	var platform models.LtiPlatform
+	if err := LtiPlatformsCollection.FindOne(context.Background(), filter).Decode(&platform); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &platform
+}
+
+func GetLtiPlatformsByOwnerId(ownerId primitive.ObjectID) []models.LtiPlatform {
+	cursor, err := LtiPlatformsCollection.Find(context.Background(), bson.M{"ownerId": ownerId})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	platforms := make([]models.LtiPlatform, 0)
+	if err := cursor.All(context.Background(), &platforms); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return platforms
+}
+
+func InsertLtiPlatform(platform *models.LtiPlatform) {
+	if platform.Id.IsZero() {
+		platform.Id = primitive.NewObjectID()
+	}
+	if _, err := LtiPlatformsCollection.InsertOne(context.Background(), platform); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Deletes the platform and the identities of its users
+func DeleteLtiPlatform(platformId primitive.ObjectID) {
+	if _, err := LtiPlatformsCollection.DeleteOne(context.Background(), bson.M{"_id": platformId}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	if _, err := LtiIdentitiesCollection.DeleteMany(context.Background(), bson.M{"platformId": platformId}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Returns the identity of the given LMS user, or nil if they never launched Timeful
+func GetLtiIdentity(platformId primitive.ObjectID, subject string) *models.LtiIdentity {
+	var identity models.LtiIdentity
+	err := LtiIdentitiesCollection.FindOne(context.Background(), bson.M{"platformId": platformId, "subject": subject}).Decode(&identity)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &identity
+}
+
+// Returns whether another user of the platform already responds with the given name
+func IsLtiIdentityNameTaken(platformId primitive.ObjectID, name string) bool {
+	count, err := LtiIdentitiesCollection.CountDocuments(context.Background(), bson.M{"platformId": platformId, "name": name})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return count > 0
+}
+
+func InsertLtiIdentity(identity *models.LtiIdentity) {
+	if identity.Id.IsZero() {
+		identity.Id = primitive.NewObjectID()
+	}
+	if _, err := LtiIdentitiesCollection.InsertOne(context.Background(), identity); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+func UpdateLtiIdentity(identity *models.LtiIdentity) {
+	if _, err := LtiIdentitiesCollection.ReplaceOne(context.Background(), bson.M{"_id": identity.Id}, identity); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the emails of the invitees that opted out of nudges for the given event
+func GetNotificationOptOutEmails(eventId primitive.ObjectID) models.Set[string] {
+	cursor, err := NotificationOptOutsCollection.Find(context.Background(), bson.M{"eventId": eventId})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	optOuts := make([]models.NotificationOptOut, 0)
+	if err := cursor.All(context.Background(), &optOuts); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	emails := make(models.Set[string])
+	for _, optOut := range optOuts {
+		emails[optOut.Email] = struct{}{}
+	}
+	return emails
+}
+
+// Opts the invitee out of nudges for the given event
+func OptOutOfNotifications(eventId primitive.ObjectID, email string) {
+	email = strings.ToLower(email)
+	_, err := NotificationOptOutsCollection.UpdateOne(context.Background(), bson.M{
+		"eventId": eventId,
+		"email":   email,
+	}, bson.M{
+		"$setOnInsert": models.NotificationOptOut{
+			EventId:   eventId,
+			Email:     email,
+			CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+		},
+	}, options.Update().SetUpsert(true))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the users that connected a Notion database
+func GetUsersWithNotionConnection() []models.User {
+	return findAll[models.User](UsersCollection, bson.M{"notionConnection": bson.M{"$exists": true}})
+}
+
+// Returns the events of the owner to write to their Notion database: those
+// created since the given time, and those that already have a row. Deleted
+// events are included so their row can be archived
+func GetNotionEvents(ownerId primitive.ObjectID, since time.Time) []models.Event {
+	return findAll[models.Event](EventsCollection, bson.M{
+		"ownerId": ownerId,
+		"$or": bson.A{
+			bson.M{"_id": bson.M{"$gte": primitive.NewObjectIDFromTimestamp(since)}, "isDeleted": bson.M{"$ne": true}},
+			bson.M{"notionPage": bson.M{"$exists": true}},
+		},
+	})
+}
+
+// Sets the row of the event in its owner's Notion database, or unsets it if
+// page is nil
+func SetNotionPage(eventId primitive.ObjectID, page *models.NotionPage) {
+	update := bson.M{"$set": bson.M{"notionPage": page}}
+	if page == nil {
+		update = bson.M{"$unset": bson.M{"notionPage": ""}}
+	}
+	if _, err := EventsCollection.UpdateByID(context.Background(), eventId, update); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Forgets the Notion rows of all the owner's events, e.g. when they connect
+// a different database
+func UnsetNotionPages(ownerId primitive.ObjectID) {
+	_, err := EventsCollection.UpdateMany(context.Background(), bson.M{
+		"ownerId":    ownerId,
+		"notionPage": bson.M{"$exists": true},
+	}, bson.M{"$unset": bson.M{"notionPage": ""}})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Sets the error of the user's last Notion sync, or clears it if it's empty
+func SetNotionSyncError(userId primitive.ObjectID, syncError string) {
+	update := bson.M{"$set": bson.M{"notionConnection.lastError": syncError}}
+	if len(syncError) == 0 {
+		update = bson.M{"$unset": bson.M{"notionConnection.lastError": ""}}
+	}
+	if _, err := UsersCollection.UpdateByID(context.Background(), userId, update); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the organization with the given id, or nil if it doesn't exist
+func GetOrganizationById(orgId string) *models.Organization {
+	objectId, err := primitive.ObjectIDFromHex(orgId)
+	if err != nil {
+		return nil
+	}
+
+	var org models.Organization
+	err = OrganizationsCollection.FindOne(context.Background(), bson.M{"_id": objectId}).Decode(&org)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &org
+}
+
+// Returns the organizations the given user is a member of, oldest first
+func GetOrganizationsByUserId(userId primitive.ObjectID) []models.Organization {
+	cursor, err := OrganizationsCollection.Find(context.Background(), bson.M{"members.userId": userId}, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	orgs := make([]models.Organization, 0)
+	if err := cursor.All(context.Background(), &orgs); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return orgs
+}
+
+func InsertOrganization(org *models.Organization) {
+	if org.Id.IsZero() {
+		org.Id = primitive.NewObjectID()
+	}
+	_, err := OrganizationsCollection.InsertOne(context.Background(), org)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+func UpdateOrganization(org *models.Organization) {
+	_, err := OrganizationsCollection.ReplaceOne(context.Background(), bson.M{"_id": org.Id}, org)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Returns the organization that verified the given email domain, or nil if none did
+func GetOrganizationByVerifiedDomain(domain string) *models.Organization {
+	var org models.Organization
+	err := OrganizationsCollection.FindOne(context.Background(), bson.M{
+		"domains": bson.M{"$elemMatch": bson.M{"domain": domain, "verifiedAt": bson.M{"$exists": true}}},
+	}).Decode(&org)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &org
+}
+
+// Returns the organization that is billed to the given stripe customer, or nil if none is
+func GetOrganizationByStripeCustomerId(customerId string) *models.Organization {
+	var org models.Organization
+	err := OrganizationsCollection.FindOne(context.Background(), bson.M{"stripeCustomerId": customerId}).Decode(&org)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &org
+}
+
+// Returns the organizations that invited the given email to join them
+func GetOrganizationsByInvitedEmail(email string) []models.Organization {
+	cursor, err := OrganizationsCollection.Find(context.Background(), bson.M{"invites.email": strings.ToLower(email)})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	orgs := make([]models.Organization, 0)
+	if err := cursor.All(context.Background(), &orgs); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return orgs
+}
+
+// Returns the organization with the given external id that the user is a
+// member of, or nil if there is none. External ids are chosen by whoever
+// provisions the organization, so they're only unique per member
+func GetOrganizationByExternalId(userId primitive.ObjectID, externalId string) *models.Organization {
+	var org models.Organization
+	err := OrganizationsCollection.FindOne(context.Background(), bson.M{"externalId": externalId, "members.userId": userId}).Decode(&org)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &org
+}
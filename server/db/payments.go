@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the payment with the given id, or nil if it doesn't exist
+func GetPaymentById(paymentId string) *models.Payment {
+	objectId, err := primitive.ObjectIDFromHex(paymentId)
+	if err != nil {
+		return nil
+	}
+
+	var payment models.Payment
+	err = PaymentsCollection.FindOne(context.Background(), bson.M{"_id": objectId}).Decode(&payment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &payment
+}
+
+// Returns the payments of the given respondent for the given event with the given status
+func GetRespondentPayments(eventId primitive.ObjectID, userId string, status models.PaymentStatus) []models.Payment {
+	return findPayments(bson.M{"eventId": eventId, "userId": userId, "status": status})
+}
+
+// Returns the payments collected for the given event, most recent first
+func GetEventPayments(eventId primitive.ObjectID) []models.Payment {
+	return findPayments(bson.M{"eventId": eventId, "status": bson.M{"$ne": models.PAYMENT_PENDING}})
+}
+
+// Returns all the payments collected for the given owner, most recent first
+func GetPaymentsByOwnerId(ownerId primitive.ObjectID) []models.Payment {
+	return findPayments(bson.M{"ownerId": ownerId, "status": bson.M{"$ne": models.PAYMENT_PENDING}})
+}
+
+func findPayments(filter bson.M) []models.Payment {
+	cursor, err := PaymentsCollection.Find(context.Background(), filter, options.Find().SetSort(bson.M{"createdAt": -1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	payments := make([]models.Payment, 0)
+	if err := cursor.All(context.Background(), &payments); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return payments
+}
+
+func InsertPayment(payment *models.Payment) {
+	if payment.Id.IsZero() {
+		payment.Id = primitive.NewObjectID()
+	}
+	_, err := PaymentsCollection.InsertOne(context.Background(), payment)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+func UpdatePayment(payment *models.Payment) {
+	_, err := PaymentsCollection.ReplaceOne(context.Background(), bson.M{"_id": payment.Id}, payment)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Sets the status of the payment if it currently has the given status,
+// returning whether it was updated. Used to make fulfillment idempotent
+func TransitionPaymentStatus(paymentId primitive.ObjectID, from models.PaymentStatus, update bson.M) bool {
+	result, err := PaymentsCollection.UpdateOne(context.Background(), bson.M{"_id": paymentId, "status": from}, bson.M{"$set": update})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return result.ModifiedCount > 0
+}
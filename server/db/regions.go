@@ -0,0 +1,36 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+func UpsertRegion(region *models.Region) {
+	_, err := RegionsCollection.ReplaceOne(context.Background(), bson.M{"_id": region.Name}, region, options.Replace().SetUpsert(true))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Returns the regions that reported since the given time
+func GetActiveRegions(since time.Time) []models.Region {
+	cursor, err := RegionsCollection.Find(context.Background(), bson.M{
+		"heartbeatAt": bson.M{"$gte": primitive.NewDateTimeFromTime(since)},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	regions := make([]models.Region, 0)
+	if err := cursor.All(context.Background(), &regions); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return regions
+}
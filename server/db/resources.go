@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the resource with the given id, or nil if it doesn't exist
+func GetResourceById(resourceId string) *models.Resource {
+	objectId, err := primitive.ObjectIDFromHex(resourceId)
+	if err != nil {
+		return nil
+	}
+
+	var resource models.Resource
+	err = ResourcesCollection.FindOne(context.Background(), bson.M{
+		"_id": objectId,
+		"$or": bson.A{
+			bson.M{"isDeleted": bson.M{"$exists": false}},
+			bson.M{"isDeleted": false},
+		},
+	}).Decode(&resource)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &resource
+}
+
+// Returns all the resources owned by the given user, sorted by name
+func GetResourcesByOwnerId(ownerId primitive.ObjectID) []models.Resource {
+	return findResources(bson.M{"ownerId": ownerId})
+}
+
+// Returns the resources with the given ids, skipping ids that don't exist
+func GetResourcesByIds(resourceIds []primitive.ObjectID) []models.Resource {
+	if len(resourceIds) == 0 {
+		return make([]models.Resource, 0)
+	}
+	return findResources(bson.M{"_id": bson.M{"$in": resourceIds}})
+}
+
+func findResources(filter bson.M) []models.Resource {
+	filter["$or"] = bson.A{
+		bson.M{"isDeleted": bson.M{"$exists": false}},
+		bson.M{"isDeleted": false},
+	}
+	cursor, err := ResourcesCollection.Find(context.Background(), filter, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	resources := make([]models.Resource, 0)
+	if err := cursor.All(context.Background(), &resources); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return resources
+}
+
+func InsertResource(resource *models.Resource) {
+	if resource.Id.IsZero() {
+		resource.Id = primitive.NewObjectID()
+	}
+	_, err := ResourcesCollection.InsertOne(context.Background(), resource)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+func UpdateResource(resource *models.Resource) {
+	_, err := ResourcesCollection.ReplaceOne(context.Background(), bson.M{"_id": resource.Id}, resource)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Returns the bookings of the given resources that overlap the time range
+func GetResourceBookings(resourceIds []primitive.ObjectID, start primitive.DateTime, end primitive.DateTime) []models.ResourceBooking {
+	cursor, err := ResourceBookingsCollection.Find(context.Background(), bson.M{
+		"resourceId": bson.M{"$in": resourceIds},
+		"startDate":  bson.M{"$lt": end},
+		"endDate":    bson.M{"$gt": start},
+	}, options.Find().SetSort(bson.M{"startDate": 1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	bookings := make([]models.ResourceBooking, 0)
+	if err := cursor.All(context.Background(), &bookings); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return bookings
+}
+
+// Replaces the resource bookings of the given event
+func SetEventResourceBookings(eventId primitive.ObjectID, bookings []models.ResourceBooking) {
+	_, err := ResourceBookingsCollection.DeleteMany(context.Background(), bson.M{"eventId": eventId})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	if len(bookings) == 0 {
+		return
+	}
+
+	documents := make([]interface{}, 0)
+	for _, booking := range bookings {
+		booking.EventId = eventId
+		documents = append(documents, booking)
+	}
+	_, err = ResourceBookingsCollection.InsertMany(context.Background(), documents)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
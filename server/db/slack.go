@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the slack account linked to the given slack user id, or nil if the
+// slack user hasn't linked their Timeful account
+func GetSlackAccountBySlackUserId(slackUserId string) *models.SlackAccount {
+	result := SlackAccountsCollection.FindOne(context.Background(), bson.M{"slackUserId": slackUserId})
+	if result.Err() == mongo.ErrNoDocuments {
+		return nil
+	}
+
+	var slackAccount models.SlackAccount
+	if err := result.Decode(&slackAccount); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return &slackAccount
+}
+
+// Returns all the slack accounts linked to the given Timeful user
+func GetSlackAccountsByUserId(userId primitive.ObjectID) []models.SlackAccount {
+	cursor, err := SlackAccountsCollection.Find(context.Background(), bson.M{"userId": userId})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	slackAccounts := make([]models.SlackAccount, 0)
+	if err := cursor.All(context.Background(), &slackAccounts); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return slackAccounts
+}
+
+// Links the given slack user to the given Timeful user, replacing any existing link
+func LinkSlackAccount(slackUserId string, slackTeamId string, userId primitive.ObjectID) {
+	_, err := SlackAccountsCollection.UpdateOne(
+		context.Background(),
+		bson.M{"slackUserId": slackUserId},
+		bson.M{"$set": models.SlackAccount{
+			SlackUserId: slackUserId,
+			SlackTeamId: slackTeamId,
+			UserId:      userId,
+			LinkedAt:    primitive.NewDateTimeFromTime(time.Now()),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Removes the link between the given slack user and their Timeful account
+func UnlinkSlackAccount(slackUserId string) {
+	_, err := SlackAccountsCollection.DeleteOne(context.Background(), bson.M{"slackUserId": slackUserId})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Creates a one-time code that the given slack user can redeem to link their
+// Timeful account. Codes expire after 15 minutes
+func CreateSlackLinkCode(slackUserId string, slackTeamId string) string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	code := hex.EncodeToString(bytes)
+
+	_, err := SlackLinkCodesCollection.InsertOne(context.Background(), models.SlackLinkCode{
+		Code:        code,
+		SlackUserId: slackUserId,
+		SlackTeamId: slackTeamId,
+		ExpiresAt:   primitive.NewDateTimeFromTime(time.Now().Add(15 * time.Minute)),
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return code
+}
+
+// Returns the given link code without redeeming it, or nil if the code doesn't
+// exist or has expired
+func GetSlackLinkCode(code string) *models.SlackLinkCode {
+	var linkCode models.SlackLinkCode
+	err := SlackLinkCodesCollection.FindOne(context.Background(), bson.M{
+		"code":      code,
+		"expiresAt": bson.M{"$gt": primitive.NewDateTimeFromTime(time.Now())},
+	}).Decode(&linkCode)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &linkCode
+}
+
+// Deletes and returns the given link code, or nil if the code doesn't exist or has expired
+func ConsumeSlackLinkCode(code string) *models.SlackLinkCode {
+	result := SlackLinkCodesCollection.FindOneAndDelete(context.Background(), bson.M{
+		"code":      code,
+		"expiresAt": bson.M{"$gt": primitive.NewDateTimeFromTime(time.Now())},
+	})
+	if result.Err() == mongo.ErrNoDocuments {
+		return nil
+	}
+
+	var linkCode models.SlackLinkCode
+	if err := result.Decode(&linkCode); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return &linkCode
+}
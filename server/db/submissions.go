@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"schej.it/server/logger"
+)
+
+// Marks the nonce of a submission token as used. Returns false if it was
+// already used, i.e. the submission is a replay
+func UseSubmissionNonce(nonce string, eventId primitive.ObjectID) bool {
+	_, err := SubmissionNoncesCollection.InsertOne(context.Background(), bson.M{
+		"_id":     nonce,
+		"eventId": eventId,
+		"usedAt":  primitive.NewDateTimeFromTime(time.Now()),
+	})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false
+		}
+		logger.StdErr.Panicln(err)
+	}
+	return true
+}
+
+// Deletes the nonces used before the given time, whose tokens have expired
+func DeleteSubmissionNoncesUsedBefore(before time.Time) {
+	_, err := SubmissionNoncesCollection.DeleteMany(context.Background(), bson.M{
+		"usedAt": bson.M{"$lt": primitive.NewDateTimeFromTime(before)},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+func InsertScheduledTask(task *models.ScheduledTask) {
+	if task.Id.IsZero() {
+		task.Id = primitive.NewObjectID()
+	}
+	_, err := ScheduledTasksCollection.InsertOne(context.Background(), task)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+func UpdateScheduledTask(task *models.ScheduledTask) {
+	_, err := ScheduledTasksCollection.ReplaceOne(context.Background(), bson.M{"_id": task.Id}, task)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Deletes the task if it hasn't run yet, returning whether it was deleted
+func DeletePendingScheduledTask(taskId primitive.ObjectID) bool {
+	result, err := ScheduledTasksCollection.DeleteOne(context.Background(), bson.M{
+		"_id":    taskId,
+		"status": models.SCHEDULED_TASK_PENDING,
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return result.DeletedCount > 0
+}
+
+// Claims a pending task that is due by pushing its schedule time back by the
+// lease, so no other server runs it at the same time and it's retried if this
+// server goes down mid-run. If region is set, tasks of other regions are only
+// claimed once they're overdue by the takeover delay, e.g. if their region is
+// down. Returns nil if there are none
+func ClaimDueScheduledTask(now time.Time, lease time.Duration, region string, takeoverDelay time.Duration) *models.ScheduledTask {
+	filter := bson.M{
+		"status":       models.SCHEDULED_TASK_PENDING,
+		"scheduleTime": bson.M{"$lte": primitive.NewDateTimeFromTime(now)},
+	}
+	if len(region) > 0 {
+		filter["$or"] = bson.A{
+			bson.M{"region": bson.M{"$in": bson.A{nil, "", region}}},
+			bson.M{"scheduleTime": bson.M{"$lte": primitive.NewDateTimeFromTime(now.Add(-takeoverDelay))}},
+		}
+	}
+
+	var task models.ScheduledTask
+	err := ScheduledTasksCollection.FindOneAndUpdate(context.Background(), filter, bson.M{
+		"$set": bson.M{"scheduleTime": primitive.NewDateTimeFromTime(now.Add(lease))},
+	}, options.FindOneAndUpdate().SetSort(bson.M{"scheduleTime": 1}).SetReturnDocument(options.After)).Decode(&task)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &task
+}
+
+// Deletes the tasks that finished before the given time
+func DeleteFinishedScheduledTasks(before time.Time) {
+	_, err := ScheduledTasksCollection.DeleteMany(context.Background(), bson.M{
+		"status":     bson.M{"$ne": models.SCHEDULED_TASK_PENDING},
+		"finishedAt": bson.M{"$lt": primitive.NewDateTimeFromTime(before)},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
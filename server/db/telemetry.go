@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Usage of the instance that telemetry reports, before it's rounded
+type TelemetryCounts struct {
+	Users            int64
+	Events           int64
+	EventsLast30Days int64
+	Responses        int64
+}
+
+// Returns the number of users, events, and responses on the instance
+func GetTelemetryCounts(now time.Time) TelemetryCounts {
+	count := func(collection *mongo.Collection) int64 {
+		n, err := collection.EstimatedDocumentCount(context.Background())
+		if err != nil {
+			logger.StdErr.Panicln(err)
+		}
+		return n
+	}
+
+	recentEvents, err := EventsCollection.CountDocuments(context.Background(), bson.M{
+		"_id": bson.M{"$gte": primitive.NewObjectIDFromTimestamp(now.AddDate(0, 0, -30))},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return TelemetryCounts{
+		Users:            count(UsersCollection),
+		Events:           count(EventsCollection),
+		EventsLast30Days: recentEvents,
+		Responses:        count(EventResponsesCollection),
+	}
+}
+
+// Returns the id the instance's telemetry reports are sent with, storing newId
+// as the id if the instance doesn't have one yet
+func GetOrCreateTelemetryInstanceId(newId string) string {
+	result := InstanceSettingsCollection.FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": "telemetryInstanceId"},
+		bson.M{"$setOnInsert": bson.M{"value": newId}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	)
+
+	var setting struct {
+		Value string `bson:"value"`
+	}
+	if err := result.Decode(&setting); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return setting.Value
+}
+
+// Stores the report, replacing the instance's report for the same day
+func UpsertTelemetryReport(report *models.TelemetryReport) {
+	_, err := TelemetryReportsCollection.ReplaceOne(
+		context.Background(),
+		bson.M{"instanceId": report.InstanceId, "date": report.Date},
+		report,
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
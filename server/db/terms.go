@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the term with the given id, or nil if it doesn't exist
+func GetTermById(termId string) *models.Term {
+	objectId, err := primitive.ObjectIDFromHex(termId)
+	if err != nil {
+		return nil
+	}
+
+	var term models.Term
+	err = TermsCollection.FindOne(context.Background(), bson.M{
+		"_id": objectId,
+		"$or": bson.A{
+			bson.M{"isDeleted": bson.M{"$exists": false}},
+			bson.M{"isDeleted": false},
+		},
+	}).Decode(&term)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &term
+}
+
+// Returns all the terms owned by the given user, most recent first
+func GetTermsByOwnerId(ownerId primitive.ObjectID) []models.Term {
+	cursor, err := TermsCollection.Find(context.Background(), bson.M{
+		"ownerId": ownerId,
+		"$or": bson.A{
+			bson.M{"isDeleted": bson.M{"$exists": false}},
+			bson.M{"isDeleted": false},
+		},
+	}, options.Find().SetSort(bson.M{"startDate": -1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	terms := make([]models.Term, 0)
+	if err := cursor.All(context.Background(), &terms); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return terms
+}
+
+func InsertTerm(term *models.Term) {
+	if term.Id.IsZero() {
+		term.Id = primitive.NewObjectID()
+	}
+	_, err := TermsCollection.InsertOne(context.Background(), term)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+func UpdateTerm(term *models.Term) {
+	_, err := TermsCollection.ReplaceOne(context.Background(), bson.M{"_id": term.Id}, term)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
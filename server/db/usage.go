@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns all the events created in the organization since the given time,
+// including deleted ones
+func GetOrganizationEventsSince(orgId primitive.ObjectID, since time.Time) []models.Event {
+	cursor, err := EventsCollection.Find(context.Background(), bson.M{
+		"organizationId": orgId,
+		"_id":            bson.M{"$gte": primitive.NewObjectIDFromTimestamp(since)},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	events := make([]models.Event, 0)
+	if err := cursor.All(context.Background(), &events); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return events
+}
+
+// Returns the responses to the given events that were submitted since the given time
+func GetEventResponsesSince(eventIds []primitive.ObjectID, since time.Time) []models.EventResponse {
+	eventResponses := make([]models.EventResponse, 0)
+	if len(eventIds) == 0 {
+		return eventResponses
+	}
+
+	cursor, err := EventResponsesCollection.Find(context.Background(), bson.M{
+		"eventId": bson.M{"$in": eventIds},
+		"_id":     bson.M{"$gte": primitive.NewObjectIDFromTimestamp(since)},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	if err := cursor.All(context.Background(), &eventResponses); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return eventResponses
+}
+
+// Returns the daily user logs since the given time, only keeping the given users in them
+func GetDailyUserLogsSince(userIds []primitive.ObjectID, since time.Time) []models.DailyUserLog {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"date": bson.M{"$gte": primitive.NewDateTimeFromTime(since)}}}},
+		{{Key: "$project", Value: bson.M{
+			"date":    1,
+			"userIds": bson.M{"$setIntersection": bson.A{"$userIds", userIds}},
+		}}},
+	}
+	cursor, err := DailyUserLogCollection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	logs := make([]models.DailyUserLog, 0)
+	if err := cursor.All(context.Background(), &logs); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return logs
+}
+
+// Returns the slack accounts the given users linked since the given time
+func GetSlackAccountsLinkedSince(userIds []primitive.ObjectID, since time.Time) []models.SlackAccount {
+	cursor, err := SlackAccountsCollection.Find(context.Background(), bson.M{
+		"userId":   bson.M{"$in": userIds},
+		"linkedAt": bson.M{"$gte": primitive.NewDateTimeFromTime(since)},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	accounts := make([]models.SlackAccount, 0)
+	if err := cursor.All(context.Background(), &accounts); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return accounts
+}
+
+// Returns the Google Chat shares of the given events since the given time
+func GetGoogleChatSharesSince(eventIds []primitive.ObjectID, since time.Time) []models.GoogleChatShare {
+	shares := make([]models.GoogleChatShare, 0)
+	if len(eventIds) == 0 {
+		return shares
+	}
+
+	cursor, err := GoogleChatSharesCollection.Find(context.Background(), bson.M{
+		"eventId":  bson.M{"$in": eventIds},
+		"sharedAt": bson.M{"$gte": primitive.NewDateTimeFromTime(since)},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	if err := cursor.All(context.Background(), &shares); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return shares
+}
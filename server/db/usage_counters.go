@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Adds one to the counter with the given id, creating it if needed, and
+// returns the new count
+func IncrementUsageCounter(counterId string, expiresAt time.Time) int {
+	var counter models.UsageCounter
+	err := UsageCountersCollection.FindOneAndUpdate(context.Background(),
+		bson.M{"_id": counterId},
+		bson.M{"$inc": bson.M{"count": 1}, "$setOnInsert": bson.M{"expiresAt": primitive.NewDateTimeFromTime(expiresAt)}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return counter.Count
+}
+
+// Returns the count of the counter with the given id, or 0 if it doesn't exist
+func GetUsageCount(counterId string) int {
+	var counter models.UsageCounter
+	err := UsageCountersCollection.FindOne(context.Background(), bson.M{"_id": counterId}).Decode(&counter)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return counter.Count
+}
+
+func DeleteExpiredUsageCounters(now time.Time) {
+	if _, err := UsageCountersCollection.DeleteMany(context.Background(), bson.M{"expiresAt": bson.M{"$lte": primitive.NewDateTimeFromTime(now)}}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
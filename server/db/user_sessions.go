@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the session with the given id, or nil if it doesn't exist
+func GetUserSession(sessionId string) *models.UserSession {
+	var session models.UserSession
+	err := UserSessionsCollection.FindOne(context.Background(), bson.M{"_id": sessionId}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return &session
+}
+
+// Returns the unexpired sessions of the user, most recently seen first
+func GetUserSessionsByUserId(userId string, now time.Time) []models.UserSession {
+	cursor, err := UserSessionsCollection.Find(context.Background(), bson.M{
+		"userId":    userId,
+		"expiresAt": bson.M{"$gt": now},
+	}, options.Find().SetSort(bson.M{"lastSeenAt": -1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	sessions := make([]models.UserSession, 0)
+	if err := cursor.All(context.Background(), &sessions); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return sessions
+}
+
+func UpsertUserSession(session *models.UserSession) {
+	_, err := UserSessionsCollection.ReplaceOne(context.Background(), bson.M{"_id": session.Id}, session, options.Replace().SetUpsert(true))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+func DeleteUserSession(sessionId string) {
+	if _, err := UserSessionsCollection.DeleteOne(context.Background(), bson.M{"_id": sessionId}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+func DeleteExpiredUserSessions(now time.Time) {
+	if _, err := UserSessionsCollection.DeleteMany(context.Background(), bson.M{"expiresAt": bson.M{"$lte": now}}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
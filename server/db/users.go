@@ -34,6 +34,28 @@ func GetUserById(userId string) *models.User {
 	return &user
 }
 
+// Returns the users with the given _ids, keyed by their hex _id. Malformatted
+// and nonexistent ids are left out
+func GetUsersByIds(userIds []string) map[string]*models.User {
+	objectIds := make([]primitive.ObjectID, 0)
+	for _, userId := range userIds {
+		if objectId, err := primitive.ObjectIDFromHex(userId); err == nil {
+			objectIds = append(objectIds, objectId)
+		}
+	}
+
+	users := make(map[string]*models.User)
+	if len(objectIds) == 0 {
+		return users
+	}
+	for _, user := range findAll[models.User](UsersCollection, bson.M{"_id": bson.M{"$in": objectIds}}) {
+		user := user
+		users[user.Id.Hex()] = &user
+	}
+
+	return users
+}
+
 func GetUserByStripeCustomerId(stripeCustomerId string) *models.User {
 	result := UsersCollection.FindOne(context.Background(), bson.M{
 		"stripeCustomerId": stripeCustomerId,
@@ -53,6 +75,26 @@ func GetUserByStripeCustomerId(stripeCustomerId string) *models.User {
 	return &user
 }
 
+// Returns the user with the given calendar feed token, or nil if there is none
+func GetUserByCalendarFeedToken(token string) *models.User {
+	if len(token) == 0 {
+		return nil
+	}
+	result := UsersCollection.FindOne(context.Background(), bson.M{
+		"calendarFeedToken": token,
+	})
+	if result.Err() == mongo.ErrNoDocuments {
+		return nil
+	}
+
+	var user models.User
+	if err := result.Decode(&user); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return &user
+}
+
 func GetUserByEmail(email string) *models.User {
 	result := UsersCollection.FindOne(context.Background(), bson.M{
 		"email": email,
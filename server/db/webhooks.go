@@ -0,0 +1,171 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// Returns the webhook with the given id, or nil if it doesn't exist
+func GetWebhookById(webhookId string) *models.Webhook {
+	objectId, err := primitive.ObjectIDFromHex(webhookId)
+	if err != nil {
+		return nil
+	}
+
+	var webhook models.Webhook
+	err = WebhooksCollection.FindOne(context.Background(), bson.M{"_id": objectId}).Decode(&webhook)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &webhook
+}
+
+// Returns the webhooks of the given user, oldest first
+func GetWebhooksByOwner(ownerId primitive.ObjectID) []models.Webhook {
+	cursor, err := WebhooksCollection.Find(context.Background(), bson.M{"ownerId": ownerId}, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	webhooks := make([]models.Webhook, 0)
+	if err := cursor.All(context.Background(), &webhooks); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return webhooks
+}
+
+// Returns the enabled webhooks that the given type of event of the given
+// event is posted to
+func GetSubscribedWebhooks(event *models.Event, eventType models.WebhookEventType) []models.Webhook {
+	cursor, err := WebhooksCollection.Find(context.Background(), bson.M{
+		"ownerId":    event.OwnerId,
+		"enabled":    true,
+		"eventTypes": eventType,
+		"$or": bson.A{
+			bson.M{"eventId": bson.M{"$exists": false}},
+			bson.M{"eventId": event.Id},
+		},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	webhooks := make([]models.Webhook, 0)
+	if err := cursor.All(context.Background(), &webhooks); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return webhooks
+}
+
+func InsertWebhook(webhook *models.Webhook) {
+	if webhook.Id.IsZero() {
+		webhook.Id = primitive.NewObjectID()
+	}
+	_, err := WebhooksCollection.InsertOne(context.Background(), webhook)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+func UpdateWebhook(webhook *models.Webhook) {
+	_, err := WebhooksCollection.ReplaceOne(context.Background(), bson.M{"_id": webhook.Id}, webhook)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Deletes the webhook along with its deliveries
+func DeleteWebhook(webhookId primitive.ObjectID) {
+	if _, err := WebhooksCollection.DeleteOne(context.Background(), bson.M{"_id": webhookId}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	if _, err := WebhookDeliveriesCollection.DeleteMany(context.Background(), bson.M{"webhookId": webhookId}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Returns the most recent deliveries of the webhook, most recent first
+func GetWebhookDeliveries(webhookId primitive.ObjectID, limit int64) []models.WebhookDelivery {
+	cursor, err := WebhookDeliveriesCollection.Find(context.Background(), bson.M{"webhookId": webhookId}, options.Find().SetSort(bson.M{"createdAt": -1}).SetLimit(limit))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	if err := cursor.All(context.Background(), &deliveries); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	return deliveries
+}
+
+// Returns the delivery with the given id to the given webhook, or nil if it
+// doesn't exist
+func GetWebhookDelivery(webhookId primitive.ObjectID, deliveryId string) *models.WebhookDelivery {
+	objectId, err := primitive.ObjectIDFromHex(deliveryId)
+	if err != nil {
+		return nil
+	}
+
+	var delivery models.WebhookDelivery
+	err = WebhookDeliveriesCollection.FindOne(context.Background(), bson.M{"_id": objectId, "webhookId": webhookId}).Decode(&delivery)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &delivery
+}
+
+func InsertWebhookDelivery(delivery *models.WebhookDelivery) {
+	if delivery.Id.IsZero() {
+		delivery.Id = primitive.NewObjectID()
+	}
+	_, err := WebhookDeliveriesCollection.InsertOne(context.Background(), delivery)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+func UpdateWebhookDelivery(delivery *models.WebhookDelivery) {
+	_, err := WebhookDeliveriesCollection.ReplaceOne(context.Background(), bson.M{"_id": delivery.Id}, delivery)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Claims a pending delivery that is due by pushing its next attempt back by
+// the lease, so no other server attempts it at the same time and it's retried
+// if this server goes down mid-attempt. Returns nil if there are none
+func ClaimDueWebhookDelivery(now time.Time, lease time.Duration) *models.WebhookDelivery {
+	var delivery models.WebhookDelivery
+	err := WebhookDeliveriesCollection.FindOneAndUpdate(context.Background(), bson.M{
+		"status":        models.WEBHOOK_DELIVERY_PENDING,
+		"nextAttemptAt": bson.M{"$lte": primitive.NewDateTimeFromTime(now)},
+	}, bson.M{
+		"$set": bson.M{"nextAttemptAt": primitive.NewDateTimeFromTime(now.Add(lease))},
+	}, options.FindOneAndUpdate().SetSort(bson.M{"nextAttemptAt": 1}).SetReturnDocument(options.After)).Decode(&delivery)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		logger.StdErr.Panicln(err)
+	}
+
+	return &delivery
+}
@@ -8,16 +8,114 @@ import (
 // Errors enum
 // TODO: make these an actual type (i.e. Errors.NotSignedIn)
 const (
-	NotSignedIn           string = "not-signed-in"
-	UserDoesNotExist      string = "user-does-not-exist"
-	EventNotFound         string = "event-not-found"
-	FriendRequestNotFound string = "friend-request-not-found"
-	UserNotFriends        string = "user-not-friends"
-	UserNotEventOwner     string = "user-not-event-owner"
-	RemindeeEmailNotFound string = "remindee-email-not-found"
-	AttendeeEmailNotFound string = "attendee-email-not-found"
-	EventNotGroup         string = "event-not-group"
-	InvalidCredentials    string = "invalid-credentials"
+	NotSignedIn                  string = "not-signed-in"
+	UserDoesNotExist             string = "user-does-not-exist"
+	EventNotFound                string = "event-not-found"
+	FriendRequestNotFound        string = "friend-request-not-found"
+	UserNotFriends               string = "user-not-friends"
+	UserNotEventOwner            string = "user-not-event-owner"
+	RemindeeEmailNotFound        string = "remindee-email-not-found"
+	AttendeeEmailNotFound        string = "attendee-email-not-found"
+	EventNotGroup                string = "event-not-group"
+	InvalidCredentials           string = "invalid-credentials"
+	ScheduleConflict             string = "schedule-conflict"
+	TermNotFound                 string = "term-not-found"
+	EventNotShiftSchedule        string = "event-not-shift-schedule"
+	ShiftNotFound                string = "shift-not-found"
+	ShiftScheduleNotPublished    string = "shift-schedule-not-published"
+	ResourceNotFound             string = "resource-not-found"
+	ResourceUnavailable          string = "resource-unavailable"
+	PaymentRequired              string = "payment-required"
+	PaymentsNotEnabled           string = "payments-not-enabled"
+	SignUpBlockFull              string = "sign-up-block-full"
+	ConsentRequired              string = "consent-required"
+	NudgeRateLimited             string = "nudge-rate-limited"
+	InvalidOptOutToken           string = "invalid-opt-out-token"
+	BroadcastNotFound            string = "broadcast-not-found"
+	BroadcastNotScheduled        string = "broadcast-not-scheduled"
+	UserNotEventOrganizer        string = "user-not-event-organizer"
+	OrganizationNotFound         string = "organization-not-found"
+	UserNotOrganizationAdmin     string = "user-not-organization-admin"
+	UserNotOrganizationMember    string = "user-not-organization-member"
+	OrganizationInviteNotFound   string = "organization-invite-not-found"
+	PolicyViolation              string = "policy-violation"
+	DomainAlreadyClaimed         string = "domain-already-claimed"
+	DomainNotFound               string = "domain-not-found"
+	DomainNotVerified            string = "domain-not-verified"
+	JoinRequestNotFound          string = "join-request-not-found"
+	UserNotBillingAdmin          string = "user-not-billing-admin"
+	BillingCustomerNotOwned      string = "billing-customer-not-owned"
+	WebhookNotFound              string = "webhook-not-found"
+	WebhookDeliveryNotFound      string = "webhook-delivery-not-found"
+	LtiPlatformNotFound          string = "lti-platform-not-found"
+	LtiPlatformAlreadyRegistered string = "lti-platform-already-registered"
+	InvalidLtiLaunch             string = "invalid-lti-launch"
+	InvalidLtiToken              string = "invalid-lti-token"
+	ClassroomRosterNotSupported  string = "classroom-roster-not-supported"
+	ContactNotFound              string = "contact-not-found"
+	ContactGroupNotFound         string = "contact-group-not-found"
+	UserNotInvited               string = "user-not-invited"
+	EmailPollNotFound            string = "email-poll-not-found"
+	EmailPollNotSupported        string = "email-poll-not-supported"
+	EventNotDraft                string = "event-not-draft"
+	DraftIncomplete              string = "draft-incomplete"
+	RespondentBlocked            string = "respondent-blocked"
+	BlockedRespondentNotFound    string = "blocked-respondent-not-found"
+	FolderNotFound               string = "folder-not-found"
+	FindTimeNotAllowed           string = "find-time-not-allowed"
+	UserNotAdmin                 string = "user-not-admin"
+	InvalidSubmissionToken       string = "invalid-submission-token"
+	SubmissionReplayed           string = "submission-replayed"
+	InvalidResponseToken         string = "invalid-response-token"
+	IncidentNotFound             string = "incident-not-found"
+	SessionNotFound              string = "session-not-found"
+	InvalidConfirmationToken     string = "invalid-confirmation-token"
+	PremiumRequired              string = "premium-required"
+	EventExpired                 string = "event-expired"
+	LegalAcceptanceRequired      string = "legal-acceptance-required"
+	LegalDocumentNotFound        string = "legal-document-not-found"
+	NotionDatabaseNotAccessible  string = "notion-database-not-accessible"
+	NotionDatabaseInvalid        string = "notion-database-invalid"
+	UserSessionNotFound          string = "user-session-not-found"
+	IssueTrackerNotConfigured    string = "issue-tracker-not-configured"
+	IssueTrackerUnauthorized     string = "issue-tracker-unauthorized"
+	IssueNotFound                string = "issue-not-found"
+	IcsFeedUnreachable           string = "ics-feed-unreachable"
+	IcsFeedInvalid               string = "ics-feed-invalid"
+	RosterEmpty                  string = "roster-empty"
+	InviteBatchNotFound          string = "invite-batch-not-found"
+	InvalidApiKey                string = "invalid-api-key"
+	ApiKeyNotFound               string = "api-key-not-found"
+	SessionRequired              string = "session-required"
+	RateLimited                  string = "rate-limited"
+	SendingDomainNotFound        string = "sending-domain-not-found"
+	EmailTemplateNotFound        string = "email-template-not-found"
+	FinalizationNotPending       string = "finalization-not-pending"
+	UserNotFinalizationApprover  string = "user-not-finalization-approver"
+	SsoRequired                  string = "sso-required"
+	InvalidOAuthToken            string = "invalid-oauth-token"
+	InvalidIdentity              string = "invalid-identity"
+	IdentityNotFound             string = "identity-not-found"
+	IdentityLinkedElsewhere      string = "identity-linked-elsewhere"
+	IdentityEmailInUse           string = "identity-email-in-use"
+	InvalidMergeToken            string = "invalid-merge-token"
+	AccountMergeNotFound         string = "account-merge-not-found"
+	AccountMergeExpired          string = "account-merge-expired"
+	InvalidMergeCode             string = "invalid-merge-code"
+	CannotMergeSameAccount       string = "cannot-merge-same-account"
+	MergeBillingConflict         string = "merge-billing-conflict"
+	ServerBusy                   string = "server-busy"
+	ConnectionNotFound           string = "connection-not-found"
+	LiveSelectionsDisabled       string = "live-selections-disabled"
+	FinalizeRejectedByHook       string = "finalize-rejected-by-hook"
+	AutomationRuleNotFound       string = "automation-rule-not-found"
+	TooManyAutomationRules       string = "too-many-automation-rules"
+	DelegationNotFound           string = "delegation-not-found"
+	CannotDelegateToSelf         string = "cannot-delegate-to-self"
+	DelegationExists             string = "delegation-exists"
+	TooManyDelegates             string = "too-many-delegates"
+	EventAliasExists             string = "event-alias-exists"
+	EventAliasNotFound           string = "event-alias-not-found"
 )
 
 type GoogleAPIError struct {
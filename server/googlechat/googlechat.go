@@ -0,0 +1,224 @@
+// Google Chat app, mirroring the Slack bot for Google Workspace organizations
+package googlechat
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services/jwks"
+	"schej.it/server/utils"
+)
+
+const (
+	// Google Chat signs requests with this service account
+	chatIssuer  = "chat@system.gserviceaccount.com"
+	chatJwksUrl = "https://www.googleapis.com/service_accounts/v1/jwk/chat@system.gserviceaccount.com"
+
+	// Used when the app is configured to send ID tokens with the endpoint url as the audience
+	googleIssuer  = "https://accounts.google.com"
+	googleJwksUrl = "https://www.googleapis.com/oauth2/v3/certs"
+)
+
+// Action functions invoked by card buttons
+const (
+	shareFunction = "share"
+)
+
+// An interaction event sent by Google Chat.
+// See https://developers.google.com/workspace/chat/api/reference/rest/v1/Event
+type chatEvent struct {
+	Type  string `json:"type"`
+	Space struct {
+		Name string `json:"name"`
+	} `json:"space"`
+	User struct {
+		DisplayName string `json:"displayName"`
+		Email       string `json:"email"`
+	} `json:"user"`
+	Message struct {
+		Text         string `json:"text"`
+		ArgumentText string `json:"argumentText"`
+	} `json:"message"`
+	Common struct {
+		InvokedFunction string            `json:"invokedFunction"`
+		Parameters      map[string]string `json:"parameters"`
+		TimeZone        struct {
+			Id string `json:"id"`
+		} `json:"timeZone"`
+	} `json:"common"`
+}
+
+func InitGoogleChat(router *gin.RouterGroup) {
+	googleChatRouter := router.Group("/googlechat")
+
+	googleChatRouter.POST("", verifyGoogleChatRequest(), handleChatEvent)
+}
+
+// Middleware that rejects requests that weren't sent by Google Chat. The
+// bearer token is verified against GOOGLE_CHAT_AUDIENCE, which is either the
+// project number or the endpoint url depending on how the app is configured
+func verifyGoogleChatRequest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		audience := os.Getenv("GOOGLE_CHAT_AUDIENCE")
+		if audience == "" {
+			logger.StdErr.Println("GOOGLE_CHAT_AUDIENCE not set")
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		unverified, err := jwks.ParseUnverified(token)
+		if err != nil {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		var claims jwks.Claims
+		switch unverified.String("iss") {
+		case chatIssuer:
+			claims, err = jwks.Verify(token, chatJwksUrl)
+		case googleIssuer, "accounts.google.com":
+			claims, err = jwks.Verify(token, googleJwksUrl)
+			if err == nil && claims.String("email") != chatIssuer {
+				err = fmt.Errorf("unexpected token email: %s", claims.String("email"))
+			}
+		default:
+			err = fmt.Errorf("unexpected token issuer: %s", unverified.String("iss"))
+		}
+		if err != nil || !claims.HasAudience(audience) {
+			if err != nil {
+				logger.StdErr.Println(err)
+			}
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// @Summary Receives interaction events from Google Chat
+// @Tags googlechat
+// @Accept json
+// @Produce json
+// @Success 200 {object} object "A Google Chat message"
+// @Router /googlechat [post]
+func handleChatEvent(c *gin.Context) {
+	var event chatEvent
+	if err := c.BindJSON(&event); err != nil {
+		return
+	}
+
+	switch event.Type {
+	case "ADDED_TO_SPACE":
+		c.JSON(http.StatusOK, textMessage("Thanks for adding Timeful! "+helpText))
+	case "REMOVED_FROM_SPACE":
+		db.DeleteGoogleChatSharesForSpace(event.Space.Name)
+		c.Status(http.StatusOK)
+	case "MESSAGE":
+		c.JSON(http.StatusOK, handleMessage(&event))
+	case "CARD_CLICKED":
+		c.JSON(http.StatusOK, handleCardClicked(&event))
+	default:
+		c.Status(http.StatusOK)
+	}
+}
+
+const helpText = "Here's what I can do:\n" +
+	"• `create <event name>` - create a new event for the next week\n" +
+	"• `share <event link>` - share an event with this space\n" +
+	"I'll post here once a shared event has been scheduled."
+
+var eventLinkRegex = regexp.MustCompile(`(?:/e/)?(\w+)/?$`)
+
+// Handles a message sent to the app, either directly or by mentioning it in a space
+func handleMessage(event *chatEvent) message {
+	text := strings.TrimSpace(event.Message.ArgumentText)
+	if text == "" {
+		text = strings.TrimSpace(event.Message.Text)
+	}
+
+	command, args, _ := strings.Cut(text, " ")
+	args = strings.TrimSpace(args)
+
+	switch strings.ToLower(command) {
+	case "create":
+		if args == "" {
+			return textMessage("Please give your event a name, e.g. `create Team lunch`")
+		}
+		newEvent := createEvent(args, event)
+		db.AddGoogleChatShare(newEvent.Id, event.Space.Name)
+		return eventCardMessage(newEvent, ":tada: Event created! Add your availability:", true)
+	case "share":
+		match := eventLinkRegex.FindStringSubmatch(args)
+		if match == nil {
+			return textMessage("Please include a link to the event, e.g. `share https://timeful.app/e/abc12`")
+		}
+		sharedEvent := db.GetEventByEitherId(match[1])
+		if sharedEvent == nil {
+			return textMessage("I couldn't find that event.")
+		}
+		db.AddGoogleChatShare(sharedEvent.Id, event.Space.Name)
+		return eventCardMessage(sharedEvent, fmt.Sprintf("%s shared an event. Add your availability:", event.User.DisplayName), false)
+	default:
+		return textMessage(helpText)
+	}
+}
+
+// Handles a button click on one of the app's cards
+func handleCardClicked(event *chatEvent) message {
+	switch event.Common.InvokedFunction {
+	case shareFunction:
+		sharedEvent := db.GetEventById(event.Common.Parameters["eventId"])
+		if sharedEvent == nil {
+			return textMessage("I couldn't find that event.")
+		}
+		db.AddGoogleChatShare(sharedEvent.Id, event.Space.Name)
+
+		// Post the card as a new message so that everyone in the space sees it
+		msg := eventCardMessage(sharedEvent, fmt.Sprintf("%s shared an event. Add your availability:", event.User.DisplayName), false)
+		msg.ActionResponse = &actionResponse{Type: "NEW_MESSAGE"}
+		return msg
+	default:
+		return textMessage(helpText)
+	}
+}
+
+// Creates an event with the given name spanning the next 7 days, owned by the
+// Timeful user with the same email as the chat user (if they exist)
+func createEvent(name string, event *chatEvent) *models.Event {
+	loc, err := time.LoadLocation(event.Common.TimeZone.Id)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	dates, duration := utils.GetDefaultEventDates(loc)
+
+	newEvent := models.Event{
+		Name:     name,
+		Duration: &duration,
+		Dates:    dates,
+		Type:     models.SPECIFIC_DATES,
+	}
+	if len(event.User.Email) > 0 {
+		if user := db.GetUserByEmail(event.User.Email); user != nil {
+			newEvent.OwnerId = user.Id
+		}
+	}
+	db.InsertEvent(&newEvent)
+
+	return &newEvent
+}
+
+// Returns the url of the given event
+func eventUrl(event *models.Event) string {
+	return fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId())
+}
@@ -0,0 +1,162 @@
+package googlechat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"golang.org/x/oauth2/google"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+// Cards documentation: https://developers.google.com/workspace/chat/api/reference/rest/v1/cards
+// Card builder: https://addons.gsuite.google.com/uikit/builder
+
+type message struct {
+	Text           string          `json:"text,omitempty"`
+	CardsV2        []card          `json:"cardsV2,omitempty"`
+	ActionResponse *actionResponse `json:"actionResponse,omitempty"`
+}
+
+type actionResponse struct {
+	Type string `json:"type"`
+}
+
+type card struct {
+	CardId string      `json:"cardId"`
+	Card   interface{} `json:"card"`
+}
+
+func textMessage(text string) message {
+	return message{Text: text}
+}
+
+// Returns a message containing a card with info about the given event. If
+// showShareButton is true, the card contains a button to share it with the space
+func eventCardMessage(event *models.Event, text string, showShareButton bool) message {
+	numResponses := utils.Coalesce(event.NumResponses)
+
+	buttons := []interface{}{
+		map[string]interface{}{
+			"text":    "Add availability",
+			"onClick": map[string]interface{}{"openLink": map[string]interface{}{"url": eventUrl(event)}},
+		},
+	}
+	if showShareButton {
+		buttons = append(buttons, map[string]interface{}{
+			"text": "Share with space",
+			"onClick": map[string]interface{}{"action": map[string]interface{}{
+				"function":   shareFunction,
+				"parameters": []interface{}{map[string]interface{}{"key": "eventId", "value": event.Id.Hex()}},
+			}},
+		})
+	}
+
+	return message{
+		Text: text,
+		CardsV2: []card{{
+			CardId: "event-" + event.Id.Hex(),
+			Card: map[string]interface{}{
+				"header": map[string]interface{}{
+					"title":    event.Name,
+					"subtitle": fmt.Sprintf("%d %s so far", numResponses, utils.Pluralize(numResponses, "response", "responses")),
+				},
+				"sections": []interface{}{
+					map[string]interface{}{
+						"widgets": []interface{}{
+							map[string]interface{}{"buttonList": map[string]interface{}{"buttons": buttons}},
+						},
+					},
+				},
+			},
+		}},
+	}
+}
+
+// Announces the scheduled time of the given event in every Google Chat space
+// it was shared to
+func SendEventFinalizedMessage(event *models.Event) {
+	if event.ScheduledEvent == nil {
+		return
+	}
+
+	start := event.ScheduledEvent.StartDate.Time().Unix()
+	end := event.ScheduledEvent.EndDate.Time().Unix()
+	msg := message{
+		Text: fmt.Sprintf(":white_check_mark: *%s* has been scheduled!", event.Name),
+		CardsV2: []card{{
+			CardId: "scheduled-" + event.Id.Hex(),
+			Card: map[string]interface{}{
+				"header": map[string]interface{}{"title": event.Name, "subtitle": "Scheduled"},
+				"sections": []interface{}{
+					map[string]interface{}{
+						"widgets": []interface{}{
+							map[string]interface{}{"decoratedText": map[string]interface{}{
+								"topLabel": "When",
+								"text":     fmt.Sprintf("<time>%d</time> - <time>%d</time>", start, end),
+							}},
+							map[string]interface{}{"buttonList": map[string]interface{}{"buttons": []interface{}{
+								map[string]interface{}{
+									"text":    "View event",
+									"onClick": map[string]interface{}{"openLink": map[string]interface{}{"url": eventUrl(event)}},
+								},
+							}}},
+						},
+					},
+				},
+			},
+		}},
+	}
+
+	for _, share := range db.GetGoogleChatShares(event.Id) {
+		if err := createMessage(share.Space, msg); err != nil {
+			logger.StdErr.Println(err)
+		}
+	}
+}
+
+// Posts a message to the given space using the Chat API, authenticating as the
+// app with the service account key
+func createMessage(space string, msg message) error {
+	credsFile := os.Getenv("SERVICE_ACCOUNT_KEY_PATH")
+	if credsFile == "" {
+		return fmt.Errorf("SERVICE_ACCOUNT_KEY_PATH not set")
+	}
+	credsJson, err := os.ReadFile(credsFile)
+	if err != nil {
+		return err
+	}
+	creds, err := google.CredentialsFromJSON(context.Background(), credsJson, "https://www.googleapis.com/auth/chat.bot")
+	if err != nil {
+		return err
+	}
+	token, err := creds.TokenSource.Token()
+	if err != nil {
+		return err
+	}
+
+	body, _ := json.Marshal(msg)
+	req, err := http.NewRequest("POST", fmt.Sprintf("https://chat.googleapis.com/v1/%s/messages", space), bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	token.SetAuthHeader(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to post google chat message to %s: %s", space, resp.Status)
+	}
+	return nil
+}
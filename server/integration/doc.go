@@ -0,0 +1,6 @@
+// End-to-end tests that boot the server against a mongo container and mocks
+// of Google and Stripe, and drive it over HTTP like a client would. They need
+// docker and only build with the integration tag:
+//
+//	cd server/integration && go test -tags integration ./...
+package integration
@@ -0,0 +1,217 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mongodb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/models"
+	"schej.it/server/services/apikeys"
+)
+
+// Address the server listens on
+const baseUrl = "http://localhost:3002"
+
+// How long the server has to become ready
+const startupTimeout = 2 * time.Minute
+
+var (
+	mocks   *providers
+	mongoDb *mongo.Database
+)
+
+// Starts mongo, the provider mocks and the server once for all the tests
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp("", "timeful-integration")
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	container, err := mongodb.RunContainer(ctx, testcontainers.WithImage("mongo:6"))
+	if err != nil {
+		fmt.Println("failed to start mongo, is docker running?", err)
+		return 1
+	}
+	defer container.Terminate(ctx)
+	mongoUri, err := container.ConnectionString(ctx)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoUri))
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer client.Disconnect(ctx)
+	mongoDb = client.Database("schej-it")
+
+	mocks, err = startProviders(dir)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer mocks.Close()
+
+	stop, err := startServer(dir, mongoUri)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer stop()
+
+	return m.Run()
+}
+
+// Builds and starts the server, returning once it's ready. The server runs in
+// the temp dir, so it doesn't pick up a local .env or frontend build
+func startServer(dir string, mongoUri string) (func(), error) {
+	binary := filepath.Join(dir, "server")
+	build := exec.Command("go", "build", "-o", binary, ".")
+	build.Dir = ".."
+	build.Stdout, build.Stderr = os.Stdout, os.Stderr
+	if err := build.Run(); err != nil {
+		return nil, fmt.Errorf("failed to build the server: %w", err)
+	}
+
+	server := exec.Command(binary)
+	server.Dir = dir
+	server.Env = append(os.Environ(),
+		"MONGODB_URI="+mongoUri,
+		"HTTPS_PROXY="+mocks.ProxyUrl,
+		"SSL_CERT_FILE="+mocks.CaFile,
+		"STRIPE_API_KEY=sk_test_integration",
+		"ENCRYPTION_KEY=integration",
+		"TASK_QUEUE_BACKEND=mongo",
+	)
+	logFile, err := os.Create(filepath.Join(dir, "server.out"))
+	if err != nil {
+		return nil, err
+	}
+	server.Stdout, server.Stderr = logFile, logFile
+	if err := server.Start(); err != nil {
+		return nil, err
+	}
+	stop := func() {
+		server.Process.Signal(syscall.SIGTERM)
+		server.Wait()
+		logFile.Close()
+	}
+
+	deadline := time.Now().Add(startupTimeout)
+	for {
+		resp, err := http.Get(baseUrl + "/readyz")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return stop, nil
+			}
+		}
+		if time.Now().After(deadline) {
+			stop()
+			output, _ := os.ReadFile(logFile.Name())
+			return nil, fmt.Errorf("server wasn't ready after %v:\n%s", startupTimeout, output)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// A user the tests act as, authenticated with an API key
+type testUser struct {
+	*models.User
+	ApiKey string
+}
+
+// Inserts a user along with an API key for them
+func createUser(t *testing.T, firstName string, calendarAccounts map[string]models.CalendarAccount) testUser {
+	t.Helper()
+	ctx := context.Background()
+	user := &models.User{
+		Id:               primitive.NewObjectID(),
+		Email:            fmt.Sprintf("%s.%s@example.com", firstName, primitive.NewObjectID().Hex()),
+		FirstName:        firstName,
+		LastName:         "Tester",
+		CalendarAccounts: calendarAccounts,
+	}
+	if _, err := mongoDb.Collection("users").InsertOne(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	key := apikeys.NewKey()
+	_, err := mongoDb.Collection("apiKeys").InsertOne(ctx, models.ApiKey{
+		Id:        primitive.NewObjectID(),
+		OwnerId:   user.Id,
+		Name:      "integration",
+		Hint:      apikeys.GetHint(key),
+		KeyHash:   apikeys.Hash(key),
+		CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testUser{User: user, ApiKey: key}
+}
+
+// Makes a request to the API as the user, returning the status and body. The
+// body is decoded into result if it's given
+func request(t *testing.T, user *testUser, method string, path string, body any, result any) (int, []byte) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, baseUrl+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if user != nil {
+		req.Header.Set("Authorization", "Bearer "+user.ApiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != nil && resp.StatusCode < 300 {
+		if err := json.Unmarshal(data, result); err != nil {
+			t.Fatalf("%s %s: %v\n%s", method, path, err, data)
+		}
+	}
+	return resp.StatusCode, data
+}
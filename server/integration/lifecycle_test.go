@@ -0,0 +1,134 @@
+//go:build integration
+
+package integration
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"schej.it/server/models"
+)
+
+// Creates an event, responds to it, reads its aggregate, finalizes it around
+// a conflict on the organizer's Google calendar, and exports its responses
+func TestEventLifecycle(t *testing.T) {
+	enabled := true
+	organizer := createUser(t, "Organizer", map[string]models.CalendarAccount{
+		"organizer_google": {
+			CalendarType: models.GoogleCalendarType,
+			OAuth2CalendarAuth: &models.OAuth2CalendarAuth{
+				AccessToken:  "google-access-token",
+				RefreshToken: "google-refresh-token",
+			},
+			Email:        "organizer@example.com",
+			Enabled:      &enabled,
+			SubCalendars: &map[string]models.SubCalendar{"primary": {Name: "Work", Enabled: &enabled}},
+		},
+	})
+	respondent := createUser(t, "Respondent", nil)
+
+	if !mocks.Received("GET", "api.stripe.com/v1/balance") {
+		t.Error("expected the readiness check to reach the Stripe mock")
+	}
+
+	// Create
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 7).Add(9 * time.Hour)
+	created := struct {
+		EventId string `json:"eventId"`
+	}{}
+	status, body := request(t, &organizer, "POST", "/api/events", map[string]any{
+		"name":          "Integration planning",
+		"duration":      2,
+		"dates":         []time.Time{day},
+		"type":          models.SPECIFIC_DATES,
+		"timeIncrement": 30,
+	}, &created)
+	if status != http.StatusCreated || len(created.EventId) == 0 {
+		t.Fatalf("failed to create the event: %d %s", status, body)
+	}
+	eventPath := "/api/events/" + created.EventId
+
+	// Respond
+	for _, r := range []struct {
+		user         *testUser
+		availability []time.Time
+	}{
+		{&organizer, []time.Time{day, day.Add(30 * time.Minute)}},
+		{&respondent, []time.Time{day, day.Add(time.Hour)}},
+	} {
+		status, body := request(t, r.user, "POST", eventPath+"/response", map[string]any{
+			"guest":        false,
+			"availability": r.availability,
+		}, nil)
+		if status != http.StatusOK {
+			t.Fatalf("failed to respond as %s: %d %s", r.user.FirstName, status, body)
+		}
+	}
+
+	// Aggregate
+	aggregate := struct {
+		NumRespondents int `json:"numRespondents"`
+		Heatmap        []struct {
+			Start     time.Time `json:"start"`
+			Available int       `json:"available"`
+		} `json:"heatmap"`
+	}{}
+	status, body = request(t, nil, "GET", eventPath+"/public-aggregate", nil, &aggregate)
+	if status != http.StatusOK || aggregate.NumRespondents != 2 {
+		t.Fatalf("got aggregate %d %s", status, body)
+	}
+	for _, cell := range aggregate.Heatmap {
+		want := 0
+		switch {
+		case cell.Start.Equal(day):
+			want = 2
+		case cell.Start.Equal(day.Add(30 * time.Minute)), cell.Start.Equal(day.Add(time.Hour)):
+			want = 1
+		}
+		if cell.Available != want {
+			t.Errorf("got %d available at %v, want %d", cell.Available, cell.Start, want)
+		}
+	}
+
+	// Finalize, which first runs into a meeting on the organizer's calendar
+	mocks.SetBusy(busyTime{Summary: "Standup", Start: day.Add(-15 * time.Minute), End: day.Add(15 * time.Minute)})
+	finalize := map[string]any{"startDate": day, "endDate": day.Add(30 * time.Minute)}
+	status, body = request(t, &organizer, "POST", eventPath+"/finalize", finalize, nil)
+	if status != http.StatusConflict || !strings.Contains(string(body), "Standup") {
+		t.Fatalf("expected a conflict with the Google calendar mock: %d %s", status, body)
+	}
+	if !mocks.Received("GET", "www.googleapis.com/calendar/v3/calendars/primary/events") {
+		t.Error("expected the organizer's calendar to be read from the Google mock")
+	}
+
+	finalize["ignoreConflicts"] = true
+	status, body = request(t, &organizer, "POST", eventPath+"/finalize", finalize, nil)
+	if status != http.StatusOK {
+		t.Fatalf("failed to finalize: %d %s", status, body)
+	}
+	event := models.Event{}
+	request(t, &organizer, "GET", eventPath, nil, &event)
+	if event.ScheduledEvent == nil || !event.ScheduledEvent.StartDate.Time().Equal(day) {
+		t.Errorf("expected the event to be scheduled at %v: %+v", day, event.ScheduledEvent)
+	}
+
+	// Export
+	rows := []struct {
+		Name string `json:"name"`
+	}{}
+	status, body = request(t, &organizer, "GET", eventPath+"/responses/export", nil, &rows)
+	if status != http.StatusOK || len(rows) != 2 {
+		t.Fatalf("got export %d %s", status, body)
+	}
+	status, body = request(t, &organizer, "GET", eventPath+"/responses/export?format=csv", nil, nil)
+	if status != http.StatusOK || !strings.Contains(string(body), "Respondent Tester") {
+		t.Errorf("got csv export %d %s", status, body)
+	}
+
+	// Respondents can't export the organizer's event
+	if status, _ := request(t, &respondent, "GET", eventPath+"/responses/export", nil, nil); status == http.StatusOK {
+		t.Error("expected respondents to be forbidden from exporting")
+	}
+}
@@ -0,0 +1,244 @@
+//go:build integration
+
+package integration
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mocks of the Google and Stripe APIs. The server reaches them through an
+// HTTPS proxy that terminates TLS with certificates of a test CA, which the
+// server trusts through SSL_CERT_FILE. Requests to any other host fail, so the
+// tests never reach the real providers
+type providers struct {
+	// URL of the proxy, for HTTPS_PROXY
+	ProxyUrl string
+
+	// Path of the CA certificate, for SSL_CERT_FILE
+	CaFile string
+
+	proxy    *httptest.Server
+	server   *http.Server
+	listener *connListener
+
+	mutex    sync.Mutex
+	requests []string
+	busy     []busyTime
+}
+
+// A busy time the Google calendar mock returns
+type busyTime struct {
+	Summary string
+	Start   time.Time
+	End     time.Time
+}
+
+func startProviders(dir string) (*providers, error) {
+	ca, caKey, caPem, err := newCa()
+	if err != nil {
+		return nil, err
+	}
+	p := &providers{
+		CaFile:   filepath.Join(dir, "ca.pem"),
+		listener: &connListener{conns: make(chan net.Conn), closed: make(chan struct{})},
+	}
+	if err := os.WriteFile(p.CaFile, caPem, 0600); err != nil {
+		return nil, err
+	}
+
+	p.server = &http.Server{
+		Handler: http.HandlerFunc(p.serveProvider),
+		TLSConfig: &tls.Config{
+			GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+				return newLeaf(ca, caKey, hello.ServerName)
+			},
+		},
+	}
+	go p.server.ServeTLS(p.listener, "", "")
+
+	p.proxy = httptest.NewServer(http.HandlerFunc(p.serveConnect))
+	p.ProxyUrl = p.proxy.URL
+	return p, nil
+}
+
+func (p *providers) Close() {
+	p.proxy.Close()
+	p.server.Close()
+}
+
+// Sets the busy times on the organizer's Google calendar
+func (p *providers) SetBusy(busy ...busyTime) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.busy = busy
+}
+
+// Returns the requests the mocks received, e.g. "GET api.stripe.com/v1/balance"
+func (p *providers) Requests() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]string{}, p.requests...)
+}
+
+// Returns whether a request to the host was made with the path prefix
+func (p *providers) Received(method string, hostPath string) bool {
+	for _, request := range p.Requests() {
+		if strings.HasPrefix(request, method+" "+hostPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// Accepts CONNECT requests and hands the tunnels to the TLS server
+func (p *providers) serveConnect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodConnect {
+		http.Error(w, "only CONNECT is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return
+	}
+	if _, err := conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		conn.Close()
+		return
+	}
+	select {
+	case p.listener.conns <- conn:
+	case <-p.listener.closed:
+		conn.Close()
+	}
+}
+
+func (p *providers) serveProvider(w http.ResponseWriter, r *http.Request) {
+	p.mutex.Lock()
+	p.requests = append(p.requests, r.Method+" "+r.Host+r.URL.Path)
+	busy := append([]busyTime{}, p.busy...)
+	p.mutex.Unlock()
+
+	host := strings.Split(r.Host, ":")[0]
+	switch {
+	case host == "oauth2.googleapis.com" && r.URL.Path == "/token":
+		writeJson(w, map[string]any{"access_token": "google-access-token", "expires_in": 3600, "scope": r.FormValue("scope"), "token_type": "Bearer"})
+
+	case host == "www.googleapis.com" && r.URL.Path == "/calendar/v3/users/me/calendarList":
+		writeJson(w, map[string]any{"items": []map[string]any{{"id": "primary", "summary": "Work", "selected": true}}})
+
+	case host == "www.googleapis.com" && strings.HasPrefix(r.URL.Path, "/calendar/v3/calendars/") && strings.HasSuffix(r.URL.Path, "/events"):
+		items := make([]map[string]any, 0)
+		for i, b := range busy {
+			items = append(items, map[string]any{
+				"id":      fmt.Sprintf("busy%d", i),
+				"summary": b.Summary,
+				"start":   map[string]any{"dateTime": b.Start.Format(time.RFC3339)},
+				"end":     map[string]any{"dateTime": b.End.Format(time.RFC3339)},
+			})
+		}
+		writeJson(w, map[string]any{"items": items})
+
+	case host == "api.stripe.com" && r.URL.Path == "/v1/balance":
+		writeJson(w, map[string]any{"object": "balance", "livemode": false, "available": []any{}, "pending": []any{}})
+
+	default:
+		http.Error(w, "no mock for "+r.Host+r.URL.Path, http.StatusBadGateway)
+	}
+}
+
+func writeJson(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+// Listener that accepts the connections tunneled through the proxy
+type connListener struct {
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, errors.New("listener closed")
+	}
+}
+
+func (l *connListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *connListener) Addr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+}
+
+func newCa() (*x509.Certificate, *ecdsa.PrivateKey, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Timeful integration test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return cert, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+func newLeaf(ca *x509.Certificate, caKey *ecdsa.PrivateKey, host string) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
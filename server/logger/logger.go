@@ -15,3 +15,13 @@ func Init(logFile io.Writer) {
 
 	StdOut.Println("######### Server Restarted #########")
 }
+
+// Tags every log line with the region the server runs in, so the logs of
+// multiple regions can be told apart once they're aggregated
+func SetRegion(region string) {
+	if len(region) == 0 {
+		return
+	}
+	StdOut.SetPrefix("[INFO] [" + region + "] ")
+	StdErr.SetPrefix("[ERROR] [" + region + "] ")
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -8,20 +10,47 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/sessions"
-	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/stripe/stripe-go/v82"
 	"schej.it/server/db"
+	"schej.it/server/googlechat"
 	"schej.it/server/logger"
+	"schej.it/server/middleware"
 	"schej.it/server/routes"
+	"schej.it/server/services/aliases"
+	"schej.it/server/services/apikeys"
+	"schej.it/server/services/automations"
+	"schej.it/server/services/classroom"
+	"schej.it/server/services/entitlements"
+	"schej.it/server/services/faults"
 	"schej.it/server/services/gcloud"
+	"schej.it/server/services/hooks"
+	"schej.it/server/services/ics"
+	"schej.it/server/services/invites"
+	"schej.it/server/services/jobs"
+	"schej.it/server/services/metrics"
+	"schej.it/server/services/notifications"
+	"schej.it/server/services/notion"
+	"schej.it/server/services/phases"
+	"schej.it/server/services/policies"
+	"schej.it/server/services/priority"
+	"schej.it/server/services/regions"
+	"schej.it/server/services/sessionstore"
+	"schej.it/server/services/submissions"
+	"schej.it/server/services/suppressions"
+	"schej.it/server/services/tasks"
+	"schej.it/server/services/telemetry"
+	"schej.it/server/services/updates"
+	"schej.it/server/services/webhooks"
 	"schej.it/server/slackbot"
 	"schej.it/server/utils"
 
@@ -60,9 +89,21 @@ func main() {
 
 	// Load .env variables
 	loadDotEnv()
+	if faults.Enabled() {
+		// Lets admins inject faults into the requests to Google and Stripe
+		http.DefaultTransport = &faults.Transport{Base: http.DefaultTransport}
+	}
+	logger.SetRegion(regions.GetRegion())
+	metrics.Default.SetRegion(regions.GetRegion())
 
 	// Init router
 	router := gin.New()
+
+	// Only trust the client ip the proxies in front of the server forward, so
+	// it can't be spoofed by setting X-Forwarded-For
+	if err := router.SetTrustedProxies(utils.GetTrustedProxies()); err != nil {
+		logger.StdErr.Panicln(err)
+	}
 	router.Use(gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
 		var statusColor, methodColor, resetColor string
 		if param.IsOutputColor() {
@@ -85,24 +126,18 @@ func main() {
 		)
 	}))
 	router.Use(gin.Recovery())
+	router.Use(metrics.Middleware())
+	router.Use(priority.Middleware())
+
+	// Probes and metrics, registered before the cors and session middleware
+	routes.InitHealth(router)
+
+	// Redirect links shared on legacy hosts, e.g. schej.it
+	router.Use(middleware.RedirectLegacyHosts())
 
 	// Cors
 	router.Use(cors.New(cors.Config{
-	    AllowOrigins: []string{
-	        "http://localhost:8080",
-	
-	        // EasyPanel (teste)
-	        "https://timeful-timeful-app.4kaj9t.easypanel.host",
-	
-	        // Seu domínio real
-	        "https://timeful.viaaha.com.br",
-	
-	        // Domínios oficiais do projeto
-	        "https://www.schej.it",
-	        "https://schej.it",
-	        "https://www.timeful.app",
-	        "https://timeful.app",
-	    },
+	    AllowOrigins: utils.AllowedOrigins,
 	    AllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 	    AllowHeaders: []string{"Origin", "Content-Type", "Authorization"},
 	    AllowCredentials: true,
@@ -112,24 +147,74 @@ func main() {
 	closeConnection := db.Init()
 	defer closeConnection()
 
-	// Init google cloud stuff
+	// Check the suppression list before sending emails
+	utils.IsEmailSuppressed = suppressions.IsSuppressed
+
+	// Load the HTTP hooks that integrations are called through
+	hooks.Init()
+
+	// Let approvers review finalizations from Slack
+	slackbot.ReviewFinalization = routes.ReviewFinalization
+
+	// Let automation rules finalize events
+	automations.Finalize = routes.FinalizeAutomatically
+
+	// Init the task queue (Cloud Tasks or mongo)
 	closeTasks := gcloud.InitTasks()
 	defer closeTasks()
 
-	// Session
-	store := cookie.NewStore([]byte("secret"))
-	router.Use(sessions.Sessions("session", store))
+	// Session (stored in mongo or redis)
+	closeSessions := sessionstore.Init()
+	defer closeSessions()
+	router.Use(sessions.Sessions("session", sessionstore.Default))
 
 	// Init routes
-	apiRouter := router.Group("/api")
+	apiRouter := router.Group("/api", middleware.Authenticate(middleware.ApiKeyPrincipal, middleware.OAuthTokenPrincipal, middleware.SessionPrincipal), middleware.RecordApiKeyUsage(), middleware.ApiRateLimit())
 	routes.InitAuth(apiRouter)
 	routes.InitUser(apiRouter)
+	routes.InitKeys(apiRouter)
 	routes.InitEvents(apiRouter)
 	routes.InitUsers(apiRouter)
 	routes.InitAnalytics(apiRouter)
 	routes.InitStripe(apiRouter)
 	routes.InitFolders(apiRouter)
+	routes.InitInbound(apiRouter)
+	routes.InitTerms(apiRouter)
+	routes.InitResources(apiRouter)
+	routes.InitOrgs(apiRouter)
+	routes.InitProvisioning(apiRouter)
+	routes.InitUnsubscribe(apiRouter)
+	routes.InitLti(apiRouter)
+	routes.InitStatus(apiRouter)
+	routes.InitConfig(apiRouter)
+	routes.InitTelemetry(apiRouter)
+	routes.InitAdmin(apiRouter)
+	routes.InitLegal(apiRouter)
+	routes.InitAvailability(apiRouter)
 	slackbot.InitSlackbot(apiRouter)
+	googlechat.InitGoogleChat(apiRouter)
+
+	// Start background jobs
+	jobs.Register("broadcasts", time.Minute, notifications.SendDueBroadcasts)
+	jobs.Register("retention", time.Hour, policies.DeleteExpiredEvents)
+	jobs.Register("classroom-rosters", time.Hour, classroom.SyncRosters)
+	jobs.Register("submission-nonces", time.Hour, submissions.DeleteExpiredNonces)
+	jobs.Register("webhooks", 15*time.Second, webhooks.DeliverDue)
+	jobs.Register("tasks", 30*time.Second, tasks.RunDue)
+	jobs.Register("notion", 5*time.Minute, notion.SyncEvents)
+	jobs.Register("sessions", time.Hour, sessionstore.DeleteExpired)
+	jobs.Register("ics-feeds", 15*time.Minute, ics.RefreshFeeds)
+	jobs.Register("invites", 30*time.Second, invites.SendDue)
+	jobs.Register("usage-counters", time.Hour, entitlements.DeleteExpiredCounters)
+	jobs.Register("next-phases", 5*time.Minute, phases.AdvanceDue)
+	jobs.Register("telemetry", 24*time.Hour, telemetry.Send)
+	jobs.Register("update-check", updates.CHECK_INTERVAL, updates.Check)
+	jobs.Register("automations", time.Minute, automations.RunDue)
+	jobs.Register("api-key-usage", time.Hour, apikeys.DeleteExpiredUsage)
+	jobs.Register("region-heartbeat", 30*time.Second, regions.Report)
+	stopJobs := jobs.Start()
+	defer stopJobs()
+	routes.StartWarmUp()
 
 	// Serve built frontend if it exists (production/release). In dev, frontend is served separately.
 	frontendDist := "../frontend/dist"
@@ -168,8 +253,36 @@ func main() {
 	// Init swagger documentation
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 
-	// Run server
-	router.Run(":3002")
+	// Run server until it's interrupted or terminated
+	server := &http.Server{Addr: ":3002", Handler: router}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.StdErr.Panicln(err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	logger.StdOut.Println("Shutting down server...")
+
+	// Stop being ready, then drain the in-flight requests. The jobs, sessions,
+	// task queue and database are closed afterwards by the deferred functions
+	routes.SetShuttingDown()
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout())
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		logger.StdErr.Println("Server forced to shut down:", err)
+	}
+}
+
+// How long in-flight requests are given to finish when shutting down, from
+// SHUTDOWN_TIMEOUT (defaults to 20s)
+func shutdownTimeout() time.Duration {
+	if timeout, err := time.ParseDuration(os.Getenv("SHUTDOWN_TIMEOUT")); err == nil && timeout > 0 {
+		return timeout
+	}
+	return 20 * time.Second
 }
 
 // Load .env variables (optional in containers)
@@ -188,10 +301,19 @@ func noRouteHandler() gin.HandlerFunc {
 		path := c.Request.URL.Path
 
 		// Determine meta tags based off URL
-		if match := regexp.MustCompile(`\/e\/(\w+)`).FindStringSubmatchIndex(path); match != nil {
-			// /e/:eventId
+		if match := regexp.MustCompile(`\/[egs]\/(\w+)`).FindStringSubmatchIndex(path); match != nil {
+			// /e/:eventId, /g/:groupId or /s/:signUpId
 			eventId := path[match[2]:match[3]]
-			event := db.GetEventByEitherId(eventId)
+			event := aliases.GetEvent(eventId)
+
+			// Links with an alias of the event (e.g. legacy short ids) are
+			// permanently redirected to its canonical link
+			if event != nil && eventId != event.GetId() && eventId != event.Id.Hex() {
+				canonicalUrl := *c.Request.URL
+				canonicalUrl.Path = path[:match[2]] + event.GetId() + path[match[3]:]
+				c.Redirect(http.StatusMovedPermanently, canonicalUrl.RequestURI())
+				return
+			}
 
 			if event != nil {
 				title := fmt.Sprintf("%s - Timeful (formerly Schej)", event.Name)
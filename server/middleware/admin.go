@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/errs"
+	"schej.it/server/responses"
+	"schej.it/server/utils"
+)
+
+// Only lets through the users whose email is in ADMIN_EMAILS, a comma
+// separated list. Must come after AuthRequired
+func AdminRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := utils.GetAuthUser(c)
+		for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+			email = strings.TrimSpace(email)
+			if len(email) > 0 && strings.EqualFold(email, user.Email) {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotAdmin})
+		c.Abort()
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/apikeys"
+	"schej.it/server/utils"
+)
+
+// How often the last use of an API key is recorded
+const apiKeyUsedInterval = 5 * time.Minute
+
+// Resolves the owner of the API key in an "Authorization: Bearer <API key>"
+// header
+func ApiKeyPrincipal(c *gin.Context) (*models.Principal, bool) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !apikeys.IsApiKey(token) {
+		return nil, true
+	}
+
+	apiKey := db.GetApiKeyByHash(apikeys.Hash(token))
+	if apiKey == nil {
+		c.JSON(http.StatusUnauthorized, responses.Error{Error: errs.InvalidApiKey})
+		return nil, false
+	}
+	now := time.Now()
+	if apiKey.LastUsedAt == nil || now.Sub(apiKey.LastUsedAt.Time()) > apiKeyUsedInterval {
+		db.SetApiKeyUsed(apiKey.Id, now)
+	}
+
+	return &models.Principal{
+		UserId: apiKey.OwnerId,
+		Method: models.API_KEY_AUTH,
+		ApiKey: apiKey,
+	}, true
+}
+
+// Counts the requests made with API keys by endpoint, for the usage stats of
+// their owners. Must run after Authenticate and before ApiRateLimit, to also
+// count the requests it rejects
+func RecordApiKeyUsage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := utils.GetPrincipal(c)
+		if principal == nil || principal.Method != models.API_KEY_AUTH || principal.ApiKey == nil {
+			c.Next()
+			return
+		}
+
+		c.Next()
+
+		// Requests that don't match a route are grouped together
+		route := c.FullPath()
+		if len(route) == 0 {
+			route = "unmatched"
+		}
+		status := c.Writer.Status()
+		db.IncrementApiKeyUsage(principal.ApiKey, apikeys.GetUsageDay(time.Now()), c.Request.Method, route, status >= 400, status == http.StatusTooManyRequests)
+	}
+}
@@ -5,24 +5,87 @@ import (
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"schej.it/server/db"
 	"schej.it/server/errs"
+	"schej.it/server/models"
 	"schej.it/server/responses"
+	"schej.it/server/utils"
 )
 
+// Resolves the principal of a request from one kind of credentials. Returns
+// a nil principal if the request doesn't have that kind of credentials. If
+// they're invalid, the resolver responds with an error and returns ok=false
+type Resolver func(c *gin.Context) (principal *models.Principal, ok bool)
+
+// Resolves the principal of the request with the first resolver that finds
+// credentials, which routes get with utils.GetPrincipal and utils.GetUserId.
+// Requests without credentials continue anonymously
+func Authenticate(resolvers ...Resolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, resolve := range resolvers {
+			principal, ok := resolve(c)
+			if !ok {
+				c.Abort()
+				return
+			}
+			if principal != nil {
+				SetPrincipal(c, principal)
+				break
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// Sets the principal of the request
+func SetPrincipal(c *gin.Context, principal *models.Principal) {
+	c.Set("principal", principal)
+}
+
+// Resolves the user signed in with the session cookie
+func SessionPrincipal(c *gin.Context) (*models.Principal, bool) {
+	session := sessions.Default(c)
+	userId, err := primitive.ObjectIDFromHex(utils.Coalesce(getSessionString(session, "userId")))
+	if err != nil {
+		return nil, true
+	}
+	return &models.Principal{
+		UserId:       userId,
+		Method:       models.SESSION_AUTH,
+		AuthProvider: models.CalendarType(utils.Coalesce(getSessionString(session, "authProvider"))),
+	}, true
+}
+
+// Resolves the Slack user's linked account, for actions Slack sends on their
+// behalf. Returns nil if the Slack user hasn't linked an account
+func SlackPrincipal(slackUserId string) *models.Principal {
+	slackAccount := db.GetSlackAccountBySlackUserId(slackUserId)
+	if slackAccount == nil {
+		return nil
+	}
+	return &models.Principal{
+		UserId:      slackAccount.UserId,
+		Method:      models.SLACK_AUTH,
+		SlackUserId: slackUserId,
+	}
+}
+
+// Only lets requests made on behalf of an existing user through, and sets
+// "authUser" to the user for utils.GetAuthUser. Must run after Authenticate
 func AuthRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Check if userId is set
-		session := sessions.Default(c)
-		if session.Get("userId") == nil {
-			// User id is not set, user is not signed in!
+		principal := utils.GetPrincipal(c)
+		if principal == nil {
+			// User is not signed in!
 			c.JSON(http.StatusUnauthorized, responses.Error{Error: errs.NotSignedIn})
 			c.Abort()
 			return
 		}
 
 		// Check if user with user id exists
-		user := db.GetUserById(session.Get("userId").(string))
+		user := db.GetUserById(principal.UserId.Hex())
 
 		if user == nil {
 			c.JSON(http.StatusUnauthorized, responses.Error{Error: errs.UserDoesNotExist})
@@ -30,8 +93,35 @@ func AuthRequired() gin.HandlerFunc {
 			return
 		}
 
+		if !checkLegalAcceptance(c, user) {
+			return
+		}
+
 		c.Set("authUser", user)
 
 		c.Next()
 	}
 }
+
+// Only lets through users signed in with a session, for routes that manage the
+// account itself (its credentials, sessions, identities, and admin actions).
+// API keys and OAuth tokens can't be scoped yet, so they're kept from
+// escalating to more credentials. Must run after Authenticate
+func SessionRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if principal := utils.GetPrincipal(c); principal != nil && principal.Method != models.SESSION_AUTH {
+			c.JSON(http.StatusForbidden, responses.Error{Error: errs.SessionRequired})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func getSessionString(session sessions.Session, key string) *string {
+	if value, ok := session.Get(key).(string); ok {
+		return &value
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/entitlements"
+	"schej.it/server/services/organizations"
+)
+
+// Only lets admins and billing admins of the :orgId organization through, and
+// sets "billingOrg" to the organization. Must run after AuthRequired
+func BillingAdminRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		org := db.GetOrganizationById(c.Param("orgId"))
+		user := c.MustGet("authUser").(*models.User)
+		if org == nil || organizations.GetMember(org, user.Id) == nil {
+			c.JSON(http.StatusNotFound, responses.Error{Error: errs.OrganizationNotFound})
+			c.Abort()
+			return
+		}
+		if !organizations.CanManageBilling(org, user.Id) {
+			c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotBillingAdmin})
+			c.Abort()
+			return
+		}
+
+		c.Set("billingOrg", org)
+
+		c.Next()
+	}
+}
+
+// Only lets premium users, and members of premium organizations, through.
+// Must run after AuthRequired
+func PremiumRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.MustGet("authUser").(*models.User)
+		if entitlements.GetUserPlan(user) != entitlements.PLAN_PREMIUM {
+			c.JSON(http.StatusPaymentRequired, responses.Error{Error: errs.PremiumRequired})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
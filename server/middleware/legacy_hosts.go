@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/services/aliases"
+)
+
+// Permanently redirects pages requested on legacy hosts (LEGACY_HOSTS, e.g.
+// schej.it) to the same page on the app, so previously shared links keep
+// working. Other requests, e.g. API calls from open tabs, are served as usual
+func RedirectLegacyHosts() gin.HandlerFunc {
+	hosts := aliases.GetLegacyHosts()
+	return func(c *gin.Context) {
+		isPage := (c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead) && !strings.HasPrefix(c.Request.URL.Path, "/api/")
+		if len(hosts) == 0 || !isPage {
+			c.Next()
+			return
+		}
+
+		if target, ok := aliases.GetLegacyRedirect(hosts, c.Request.Host, c.Request.URL.RequestURI()); ok {
+			c.Redirect(http.StatusMovedPermanently, target)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
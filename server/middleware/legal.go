@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/models"
+	"schej.it/server/services/legal"
+)
+
+// Routes users can still use before accepting new required versions of the
+// legal documents, so they can read and accept them, or sign out
+var legalExemptRoutes = []string{"/api/legal", "/api/auth/", "/api/user/profile"}
+
+// Blocks users that haven't accepted the latest required versions of the legal
+// documents, responding with the versions they have to accept
+func checkLegalAcceptance(c *gin.Context, user *models.User) bool {
+	for _, route := range legalExemptRoutes {
+		if strings.HasPrefix(c.FullPath(), route) {
+			return true
+		}
+	}
+
+	pending := legal.GetPending(db.GetLegalDocuments(), user.LegalAcceptances)
+	if len(pending) == 0 {
+		return true
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{"error": errs.LegalAcceptanceRequired, "pending": pending})
+	c.Abort()
+	return false
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/jwks"
+	"schej.it/server/utils"
+)
+
+const (
+	googleIssuer  = "https://accounts.google.com"
+	googleJwksUrl = "https://www.googleapis.com/oauth2/v3/certs"
+)
+
+// Resolves the user of a Google ID token in an "Authorization: Bearer <token>"
+// header, e.g. from the mobile apps. Only tokens issued to one of the
+// instance's client ids are resolved, other bearer tokens (e.g. from Google
+// Chat) are left to the routes that expect them
+func OAuthTokenPrincipal(c *gin.Context) (*models.Principal, bool) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	unverified, err := jwks.ParseUnverified(token)
+	if err != nil || !isOwnGoogleToken(unverified) {
+		return nil, true
+	}
+
+	claims, err := jwks.Verify(token, googleJwksUrl)
+	if err != nil || !isOwnGoogleToken(claims) || claims["email_verified"] != true {
+		if err != nil {
+			logger.StdErr.Println(err)
+		}
+		c.JSON(http.StatusUnauthorized, responses.Error{Error: errs.InvalidOAuthToken})
+		return nil, false
+	}
+
+	user := db.GetUserByEmail(claims.String("email"))
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, responses.Error{Error: errs.UserDoesNotExist})
+		return nil, false
+	}
+	return &models.Principal{
+		UserId:       user.Id,
+		Method:       models.OAUTH_TOKEN_AUTH,
+		AuthProvider: models.GoogleCalendarType,
+	}, true
+}
+
+// Returns whether the claims are of a Google token issued to one of the
+// instance's client ids
+func isOwnGoogleToken(claims jwks.Claims) bool {
+	switch claims.String("iss") {
+	case googleIssuer, "accounts.google.com":
+	default:
+		return false
+	}
+	for _, origin := range []models.TokenOriginType{models.WEB, models.IOS, models.ANDROID} {
+		if clientId := utils.GetClientIdFromTokenOrigin(origin); len(clientId) > 0 && claims.HasAudience(clientId) {
+			return true
+		}
+	}
+	return false
+}
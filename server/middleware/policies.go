@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/models"
+	"schej.it/server/services/policies"
+	"schej.it/server/utils"
+)
+
+// Rejects requests that violate the policies of the organization of the event
+// (or, when creating an event, of the organization it is created in)
+func EnforcePolicies(action policies.Action) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Read the body and put it back for the handler
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+
+		payload := struct {
+			OrganizationId *primitive.ObjectID `json:"organizationId"`
+			Guest          *bool               `json:"guest"`
+			Email          string              `json:"email"`
+			Remindees      []string            `json:"remindees"`
+			Attendees      []string            `json:"attendees"`
+			Emails         []string            `json:"emails"`
+		}{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			// Let the handler report malformed payloads
+			c.Next()
+			return
+		}
+
+		org := getPolicyOrg(c, action, payload.OrganizationId)
+		if org == nil {
+			c.Next()
+			return
+		}
+
+		memberEmails := make(models.Set[string])
+		if org.Policies.DisableExternalSharing {
+			for _, member := range org.Members {
+				if user := db.GetUserById(member.UserId.Hex()); user != nil {
+					memberEmails[strings.ToLower(user.Email)] = struct{}{}
+				}
+			}
+		}
+
+		request := policies.Request{
+			Action:   action,
+			Guest:    payload.Guest != nil && *payload.Guest,
+			Email:    payload.Email,
+			Invitees: append(append(payload.Remindees, payload.Attendees...), payload.Emails...),
+		}
+		if violations := policies.Evaluate(org.Policies, memberEmails, request); len(violations) > 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": errs.PolicyViolation, "violations": violations})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Returns the organization whose policies apply to the request, or nil if none
+func getPolicyOrg(c *gin.Context, action policies.Action, orgId *primitive.ObjectID) *models.Organization {
+	if action != policies.CREATE_EVENT {
+		event := db.GetEventByEitherId(c.Param("eventId"))
+		if event == nil || event.OrganizationId.IsZero() {
+			return nil
+		}
+		return db.GetOrganizationById(event.OrganizationId.Hex())
+	}
+
+	// Events are created in the given organization, or the user's first one
+	userId, signedIn := utils.GetUserId(c)
+	if !signedIn {
+		return nil
+	}
+	if orgId != nil {
+		return db.GetOrganizationById(orgId.Hex())
+	}
+	objectId, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil
+	}
+	orgs := db.GetOrganizationsByUserId(objectId)
+	if len(orgs) == 0 {
+		return nil
+	}
+	return &orgs[0]
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/entitlements"
+	"schej.it/server/utils"
+)
+
+// Limits requests authenticated with an API key to the quota of the key
+// owner's plan, and reports their usage in X-RateLimit-* headers. Requests
+// from the web app aren't limited. Must run after Authenticate
+func ApiRateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := utils.GetPrincipal(c)
+		if principal == nil || principal.Method != models.API_KEY_AUTH {
+			c.Next()
+			return
+		}
+		owner := db.GetUserById(principal.UserId.Hex())
+		if owner == nil {
+			c.JSON(http.StatusUnauthorized, responses.Error{Error: errs.InvalidApiKey})
+			c.Abort()
+			return
+		}
+
+		now := time.Now()
+		status := entitlements.Consume(owner, entitlements.GetUserPlan(owner), entitlements.METRIC_API_REQUESTS, now)
+		c.Header("X-RateLimit-Limit", strconv.Itoa(status.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(status.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(status.ResetAt.Unix(), 10))
+		if status.Exceeded() {
+			c.Header("Retry-After", strconv.Itoa(int(status.ResetAt.Sub(now).Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": errs.RateLimited, "resetAt": status.ResetAt})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/models"
+	"schej.it/server/services/policies"
+	"schej.it/server/utils"
+)
+
+// Rejects viewers of an organization's event that haven't signed in with the
+// organization's identity provider, if its policies require it. Responds with
+// a hint of how to sign in, so that the client can redirect the viewer
+func RequireOrgSso() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventId := c.Param("eventId")
+		if len(eventId) == 0 {
+			c.Next()
+			return
+		}
+		event := db.GetEventByEitherId(eventId)
+		if event == nil || event.OrganizationId.IsZero() {
+			c.Next()
+			return
+		}
+		org := db.GetOrganizationById(event.OrganizationId.Hex())
+		if org == nil || !org.Policies.RequireSso {
+			c.Next()
+			return
+		}
+
+		var user *models.User
+		var provider models.CalendarType
+		if principal := utils.GetPrincipal(c); principal != nil {
+			user = db.GetUserById(principal.UserId.Hex())
+			provider = principal.AuthProvider
+		}
+
+		// The owner can always see their event
+		if user != nil && user.Id == event.OwnerId || policies.MeetsSso(org, provider, user) {
+			c.Next()
+			return
+		}
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": errs.SsoRequired, "sso": policies.GetSsoHint(org, event)})
+		c.Abort()
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// A pending merge of two accounts of the same person. Codes are sent to the
+// emails of both accounts, and the merge happens once both are entered
+type AccountMerge struct {
+	Id primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+
+	// Account that is kept, i.e. the one that requested the merge
+	IntoUserId primitive.ObjectID `json:"intoUserId" bson:"intoUserId"`
+	IntoEmail  string             `json:"intoEmail" bson:"intoEmail"`
+
+	// Account that is moved into the other one and deleted. Not shown, since
+	// the merge looks the same whether or not an account has the email
+	FromUserId primitive.ObjectID `json:"-" bson:"fromUserId"`
+	FromEmail  string             `json:"fromEmail" bson:"fromEmail"`
+
+	// Hashes of the codes sent to each email
+	IntoCodeHash string `json:"-" bson:"intoCodeHash"`
+	FromCodeHash string `json:"-" bson:"fromCodeHash"`
+
+	// Number of wrong codes entered
+	Attempts int `json:"-" bson:"attempts"`
+
+	CreatedAt primitive.DateTime `json:"createdAt" bson:"createdAt"`
+	ExpiresAt primitive.DateTime `json:"expiresAt" bson:"expiresAt"`
+}
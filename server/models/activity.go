@@ -0,0 +1,32 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type ActivityType string
+
+const (
+	ACTIVITY_CREATED          ActivityType = "created"
+	ACTIVITY_PUBLISHED        ActivityType = "published"
+	ACTIVITY_SETTINGS_CHANGED ActivityType = "settingsChanged"
+	ACTIVITY_RESPONDED        ActivityType = "responded"
+	ACTIVITY_RESPONSE_REMOVED ActivityType = "responseRemoved"
+	ACTIVITY_REMINDER_SENT    ActivityType = "reminderSent"
+	ACTIVITY_FINALIZED        ActivityType = "finalized"
+)
+
+// An entry in the activity feed of an event
+type Activity struct {
+	Id      primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	EventId primitive.ObjectID `json:"eventId" bson:"eventId"`
+	Type    ActivityType       `json:"type" bson:"type"`
+
+	// Who did it, if it wasn't done by an anonymous guest or by Timeful itself
+	ActorId   primitive.ObjectID `json:"actorId,omitempty" bson:"actorId,omitempty"`
+	ActorName string             `json:"actorName,omitempty" bson:"actorName,omitempty"`
+
+	// Depending on the type, the settings that changed, the respondents that
+	// were removed, or the invitees that were reminded
+	Details []string `json:"details,omitempty" bson:"details,omitempty"`
+
+	CreatedAt primitive.DateTime `json:"createdAt" bson:"createdAt"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// A key that authenticates API requests as its owner, e.g. from scripts or the
+// timeful CLI
+type ApiKey struct {
+	Id      primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	OwnerId primitive.ObjectID `json:"ownerId" bson:"ownerId"`
+	Name    string             `json:"name" bson:"name"`
+
+	// Start of the key, to tell keys apart
+	Hint string `json:"hint" bson:"hint"`
+
+	// Hash of the key, which is only shown when it's created
+	KeyHash string `json:"-" bson:"keyHash"`
+
+	CreatedAt  primitive.DateTime  `json:"createdAt" bson:"createdAt"`
+	LastUsedAt *primitive.DateTime `json:"lastUsedAt" bson:"lastUsedAt,omitempty"`
+}
+
+// Requests made with an API key to an endpoint on a day (in UTC)
+type ApiKeyUsage struct {
+	Id       primitive.ObjectID `json:"-" bson:"_id,omitempty"`
+	ApiKeyId primitive.ObjectID `json:"apiKeyId" bson:"apiKeyId"`
+	OwnerId  primitive.ObjectID `json:"-" bson:"ownerId"`
+	Day      primitive.DateTime `json:"day" bson:"day"`
+
+	// Method and route of the endpoint, e.g. "GET /api/events/:eventId"
+	Method string `json:"method" bson:"method"`
+	Route  string `json:"route" bson:"route"`
+
+	Requests int `json:"requests" bson:"requests"`
+
+	// Requests that got a 4xx or 5xx status, and those that were rejected
+	// for exceeding the rate limit (which are also errors)
+	Errors      int `json:"errors" bson:"errors"`
+	RateLimited int `json:"rateLimited" bson:"rateLimited"`
+}
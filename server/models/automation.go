@@ -0,0 +1,83 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type AutomationTrigger string
+
+const (
+	// The event has at least Threshold responses
+	RESPONSES_REACHED_TRIGGER AutomationTrigger = "responsesReached"
+
+	// The rule's deadline, or the event's expiresAt if it has none, passed
+	DEADLINE_PASSED_TRIGGER AutomationTrigger = "deadlinePassed"
+)
+
+var AutomationTriggers = []AutomationTrigger{RESPONSES_REACHED_TRIGGER, DEADLINE_PASSED_TRIGGER}
+
+type AutomationAction string
+
+const (
+	// Messages the organizers that linked their account to Slack
+	NOTIFY_SLACK_ACTION AutomationAction = "notifySlack"
+
+	// Emails the organizers
+	NOTIFY_EMAIL_ACTION AutomationAction = "notifyEmail"
+
+	// Finalizes the event at the upcoming slot the most respondents can make
+	FINALIZE_TOP_SLOT_ACTION AutomationAction = "finalizeTopSlot"
+)
+
+var AutomationActions = []AutomationAction{NOTIFY_SLACK_ACTION, NOTIFY_EMAIL_ACTION, FINALIZE_TOP_SLOT_ACTION}
+
+// A rule that runs an action once its trigger fires, e.g. "when there are 10
+// responses, notify Slack". Rules are evaluated by a job, and fire once
+type AutomationRule struct {
+	Id        primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	EventId   primitive.ObjectID `json:"eventId" bson:"eventId"`
+	CreatedBy primitive.ObjectID `json:"createdBy" bson:"createdBy"`
+
+	Name    string            `json:"name" bson:"name,omitempty"`
+	Trigger AutomationTrigger `json:"trigger" bson:"trigger"`
+	Action  AutomationAction  `json:"action" bson:"action"`
+	Enabled bool              `json:"enabled" bson:"enabled"`
+
+	// Number of responses for responsesReached triggers
+	Threshold int `json:"threshold" bson:"threshold,omitempty"`
+
+	// Deadline of deadlinePassed triggers, defaults to the event's expiresAt
+	Deadline *primitive.DateTime `json:"deadline" bson:"deadline,omitempty"`
+
+	// Message of notify actions, defaults to describing the trigger
+	Message string `json:"message" bson:"message,omitempty"`
+
+	// Length of the meeting finalizeTopSlot actions schedule in minutes,
+	// defaults to the event's time increment
+	DurationMinutes int `json:"durationMinutes" bson:"durationMinutes,omitempty"`
+
+	CreatedAt primitive.DateTime  `json:"createdAt" bson:"createdAt"`
+	FiredAt   *primitive.DateTime `json:"firedAt" bson:"firedAt,omitempty"`
+}
+
+type AutomationRunStatus string
+
+const (
+	AUTOMATION_RUN_SUCCEEDED AutomationRunStatus = "succeeded"
+	AUTOMATION_RUN_FAILED    AutomationRunStatus = "failed"
+	AUTOMATION_RUN_SKIPPED   AutomationRunStatus = "skipped"
+)
+
+// An execution of an automation rule's action
+type AutomationRun struct {
+	Id      primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	RuleId  primitive.ObjectID `json:"ruleId" bson:"ruleId"`
+	EventId primitive.ObjectID `json:"eventId" bson:"eventId"`
+
+	Trigger AutomationTrigger   `json:"trigger" bson:"trigger"`
+	Action  AutomationAction    `json:"action" bson:"action"`
+	Status  AutomationRunStatus `json:"status" bson:"status"`
+
+	// What the action did, or why it failed or was skipped
+	Message string `json:"message" bson:"message,omitempty"`
+
+	RanAt primitive.DateTime `json:"ranAt" bson:"ranAt"`
+}
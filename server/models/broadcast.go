@@ -0,0 +1,62 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type BroadcastStatus string
+
+const (
+	BROADCAST_SCHEDULED BroadcastStatus = "scheduled"
+	BROADCAST_SENDING   BroadcastStatus = "sending"
+	BROADCAST_SENT      BroadcastStatus = "sent"
+	BROADCAST_CANCELLED BroadcastStatus = "cancelled"
+)
+
+type DeliveryStatus string
+
+const (
+	DELIVERY_SENT    DeliveryStatus = "sent"
+	DELIVERY_FAILED  DeliveryStatus = "failed"
+	DELIVERY_SKIPPED DeliveryStatus = "skipped"
+)
+
+type DeliveryChannel string
+
+const (
+	EMAIL_CHANNEL DeliveryChannel = "email"
+	SLACK_CHANNEL DeliveryChannel = "slack"
+)
+
+// A one-off message from the owner of an event to all of its respondents,
+// sent at the scheduled time
+type Broadcast struct {
+	Id      primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	EventId primitive.ObjectID `json:"eventId" bson:"eventId"`
+	OwnerId primitive.ObjectID `json:"ownerId" bson:"ownerId"`
+
+	Subject string `json:"subject" bson:"subject"`
+	Message string `json:"message" bson:"message"`
+
+	Status    BroadcastStatus     `json:"status" bson:"status"`
+	SendAt    primitive.DateTime  `json:"sendAt" bson:"sendAt"`
+	CreatedAt primitive.DateTime  `json:"createdAt" bson:"createdAt"`
+	SentAt    *primitive.DateTime `json:"sentAt" bson:"sentAt,omitempty"`
+
+	// Delivery status for every respondent and channel, set once sent
+	Deliveries []Delivery `json:"deliveries" bson:"deliveries,omitempty"`
+	NumSent    int        `json:"numSent" bson:"numSent"`
+	NumFailed  int        `json:"numFailed" bson:"numFailed"`
+}
+
+// Status of a message sent to a respondent over a channel
+type Delivery struct {
+	// Id of the respondent (user id, or name for guests)
+	UserId string `json:"userId" bson:"userId"`
+	Name   string `json:"name" bson:"name,omitempty"`
+	Email  string `json:"email" bson:"email,omitempty"`
+
+	Channel DeliveryChannel `json:"channel" bson:"channel"`
+	Status  DeliveryStatus  `json:"status" bson:"status"`
+
+	// Why the delivery failed or was skipped
+	Error string `json:"error" bson:"error,omitempty"`
+}
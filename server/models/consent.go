@@ -0,0 +1,34 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Terms respondents have to agree to before responding to an event, e.g. a
+// liability waiver or tutoring contract
+type ConsentDocument struct {
+	Title string `json:"title" bson:"title"`
+	Text  string `json:"text" bson:"text"`
+
+	// Incremented whenever the title or text changes
+	Version   int                `json:"version" bson:"version"`
+	UpdatedAt primitive.DateTime `json:"updatedAt" bson:"updatedAt"`
+}
+
+// Record of a respondent agreeing to a version of an event's consent document
+type Consent struct {
+	Id      primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	EventId primitive.ObjectID `json:"eventId" bson:"eventId"`
+
+	// Id of the respondent (user id, or name for guests)
+	UserId string `json:"userId" bson:"userId"`
+	Name   string `json:"name" bson:"name,omitempty"`
+	Email  string `json:"email" bson:"email,omitempty"`
+
+	// The version agreed to, and a hash of its title and text so the exact
+	// wording can be verified later
+	DocumentVersion int    `json:"documentVersion" bson:"documentVersion"`
+	DocumentHash    string `json:"documentHash" bson:"documentHash"`
+
+	IpAddress string             `json:"ipAddress" bson:"ipAddress"`
+	UserAgent string             `json:"userAgent" bson:"userAgent"`
+	AgreedAt  primitive.DateTime `json:"agreedAt" bson:"agreedAt"`
+}
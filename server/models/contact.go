@@ -0,0 +1,29 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Someone the user invited to their events, or added to their contact book
+type Contact struct {
+	Id      primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	OwnerId primitive.ObjectID `json:"-" bson:"ownerId"`
+	Email   string             `json:"email" bson:"email"`
+	Name    string             `json:"name" bson:"name,omitempty"`
+
+	// How often and when the user last invited them
+	NumInvites    int                 `json:"numInvites" bson:"numInvites"`
+	LastInvitedAt *primitive.DateTime `json:"lastInvitedAt" bson:"lastInvitedAt,omitempty"`
+}
+
+// A named list of emails (e.g. "Design team") that can be invited at once
+type ContactGroup struct {
+	Id      primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	OwnerId primitive.ObjectID `json:"-" bson:"ownerId"`
+
+	// Set for groups shared with an organization's members, which only its
+	// admins can edit
+	OrganizationId primitive.ObjectID `json:"organizationId" bson:"organizationId,omitempty"`
+
+	Name      string             `json:"name" bson:"name"`
+	Emails    []string           `json:"emails" bson:"emails"`
+	CreatedAt primitive.DateTime `json:"createdAt" bson:"createdAt"`
+}
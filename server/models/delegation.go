@@ -0,0 +1,45 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Permission a user (the principal) gives another user (the delegate, e.g.
+// their executive assistant) to respond to events on their behalf, from the
+// principal's connected calendars
+type Delegation struct {
+	Id          primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	PrincipalId primitive.ObjectID `json:"principalId" bson:"principalId"`
+	DelegateId  primitive.ObjectID `json:"delegateId" bson:"delegateId"`
+
+	CreatedAt primitive.DateTime  `json:"createdAt" bson:"createdAt"`
+	RevokedAt *primitive.DateTime `json:"revokedAt" bson:"revokedAt,omitempty"`
+
+	Principal *User `json:"principal,omitempty" bson:"-"`
+	Delegate  *User `json:"delegate,omitempty" bson:"-"`
+}
+
+type DelegationAuditAction string
+
+const (
+	DELEGATION_GRANTED DelegationAuditAction = "granted"
+	DELEGATION_REVOKED DelegationAuditAction = "revoked"
+	DELEGATE_RESPONDED DelegationAuditAction = "responded"
+	DELEGATE_READ_BUSY DelegationAuditAction = "readBusyTimes"
+)
+
+// A record of a change to a delegation or of something the delegate did with
+// it, shown to both the principal and the delegate
+type DelegationAudit struct {
+	Id           primitive.ObjectID    `json:"_id" bson:"_id,omitempty"`
+	DelegationId primitive.ObjectID    `json:"delegationId" bson:"delegationId"`
+	PrincipalId  primitive.ObjectID    `json:"principalId" bson:"principalId"`
+	DelegateId   primitive.ObjectID    `json:"delegateId" bson:"delegateId"`
+	Action       DelegationAuditAction `json:"action" bson:"action"`
+
+	// Who did it, the principal or the delegate
+	ActorId primitive.ObjectID `json:"actorId" bson:"actorId"`
+
+	// Event the delegate responded to
+	EventId *primitive.ObjectID `json:"eventId" bson:"eventId,omitempty"`
+
+	CreatedAt primitive.DateTime `json:"createdAt" bson:"createdAt"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Proposed slots emailed to an event's invitees, who respond by replying with
+// the numbers of the slots that work for them, or clicking a link per slot
+type EmailPoll struct {
+	Slots      []EmailPollSlot      `json:"slots" bson:"slots"`
+	Recipients []EmailPollRecipient `json:"-" bson:"recipients"`
+	SentAt     primitive.DateTime   `json:"sentAt" bson:"sentAt"`
+
+	// Whether the emails have a tentative hold attached for each slot, which
+	// recipients can accept or decline from their mail client
+	AttachHolds bool `json:"attachHolds" bson:"attachHolds,omitempty"`
+}
+
+type EmailPollSlot struct {
+	Start primitive.DateTime `json:"start" bson:"start"`
+	End   primitive.DateTime `json:"end" bson:"end"`
+}
+
+type EmailPollRecipient struct {
+	Email string `json:"email" bson:"email"`
+
+	// Secret that identifies the recipient in the reply address and links of
+	// their email
+	Key string `json:"-" bson:"key"`
+
+	RespondedAt *primitive.DateTime `json:"respondedAt" bson:"respondedAt,omitempty"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type SuppressionReason string
+
+const (
+	SUPPRESSION_UNSUBSCRIBED SuppressionReason = "unsubscribed"
+	SUPPRESSION_BOUNCED      SuppressionReason = "bounced"
+	SUPPRESSION_COMPLAINED   SuppressionReason = "complained"
+)
+
+// An address that isn't sent bulk emails (reminders, broadcasts, invites)
+// because it unsubscribed, or any emails because it bounced or marked one as
+// spam
+type EmailSuppression struct {
+	Id     primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	Email  string             `json:"email" bson:"email"`
+	Reason SuppressionReason  `json:"reason" bson:"reason"`
+
+	CreatedAt primitive.DateTime `json:"createdAt" bson:"createdAt"`
+}
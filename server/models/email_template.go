@@ -0,0 +1,24 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type EmailTemplateType string
+
+const (
+	EMAIL_TEMPLATE_INVITE   EmailTemplateType = "invite"
+	EMAIL_TEMPLATE_REMINDER EmailTemplateType = "reminder"
+)
+
+var EmailTemplateTypes = []EmailTemplateType{EMAIL_TEMPLATE_INVITE, EMAIL_TEMPLATE_REMINDER}
+
+// An email customized by an organization, with {{variables}} filled in when
+// it's sent
+type EmailTemplate struct {
+	Type    EmailTemplateType `json:"type" bson:"type"`
+	Subject string            `json:"subject" bson:"subject"`
+
+	// Sanitized HTML, limited to basic formatting and links
+	Body string `json:"body" bson:"body"`
+
+	UpdatedAt primitive.DateTime `json:"updatedAt" bson:"updatedAt"`
+}
@@ -13,18 +13,151 @@ const (
 )
 
 // Object containing information associated with the remindee
+// Who can see the respondents of an event and their availability. Organizers
+// always see everything, and respondents always see their own response
+type PrivacyLevel string
+
+const (
+	// Anyone with the link sees who responded and when they're available
+	PRIVACY_PUBLIC PrivacyLevel = "public"
+
+	// Anyone with the link sees how many respondents are available at each
+	// time, but not who they are
+	PRIVACY_COUNTS PrivacyLevel = "counts"
+
+	// Only the invitees (remindees and authorized emails) see the responses
+	PRIVACY_INVITEES PrivacyLevel = "invitees"
+
+	// Only the organizers see the responses
+	PRIVACY_ORGANIZERS PrivacyLevel = "organizers"
+)
+
 type Remindee struct {
 	Email     string   `json:"email" bson:"email,omitempty"`
 	TaskIds   []string `json:"-" bson:"taskIds,omitempty"` // Task IDs of the scheduled emails
 	Responded *bool    `json:"responded" bson:"responded,omitempty"`
 }
 
+// A Google Classroom course imported as the remindees of an event
+type ClassroomRoster struct {
+	CourseId   string              `json:"courseId" bson:"courseId"`
+	CourseName string              `json:"courseName" bson:"courseName"`
+	SyncedAt   *primitive.DateTime `json:"syncedAt" bson:"syncedAt,omitempty"`
+}
+
 type SignUpBlock struct {
 	Id        primitive.ObjectID  `json:"_id" bson:"_id,omitempty"`
 	Name      string              `json:"name" bson:"name,omitempty"`
 	Capacity  *int                `json:"capacity" bson:"capacity,omitempty"`
 	StartDate *primitive.DateTime `json:"startDate" bson:"startDate,omitempty"`
 	EndDate   *primitive.DateTime `json:"endDate" bson:"endDate,omitempty"`
+
+	// Price to sign up for the block, in the smallest unit of the event's payment currency
+	Price *int64 `json:"price" bson:"price,omitempty"`
+}
+
+type Shift struct {
+	Id        primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	Name      string             `json:"name" bson:"name,omitempty"`
+	StartDate primitive.DateTime `json:"startDate" bson:"startDate"`
+	EndDate   primitive.DateTime `json:"endDate" bson:"endDate"`
+
+	// Number of people needed for the shift
+	Needed int `json:"needed" bson:"needed"`
+}
+
+type ShiftAssignment struct {
+	ShiftId primitive.ObjectID `json:"shiftId" bson:"shiftId"`
+
+	// Id of the respondent (user id, or name for guests)
+	UserId string `json:"userId" bson:"userId"`
+
+	// Whether the assignment was made manually by the organizer, in which
+	// case it is kept when reassigning shifts
+	Locked bool `json:"locked" bson:"locked,omitempty"`
+}
+
+type PanelPool struct {
+	Name string `json:"name" bson:"name"`
+
+	// Number of members of the pool needed for the panel
+	Count int `json:"count" bson:"count"`
+
+	// Ids of the respondents in the pool (user id, or name for guests)
+	Members []string `json:"members" bson:"members,omitempty"`
+
+	// Resources in the pool, such as rooms, whose availability comes from
+	// calendars on the organizer's connected calendar accounts
+	Rooms []PanelRoom `json:"rooms" bson:"rooms,omitempty"`
+}
+
+type PanelRoom struct {
+	Name               string `json:"name" bson:"name"`
+	CalendarAccountKey string `json:"calendarAccountKey" bson:"calendarAccountKey"`
+	CalendarId         string `json:"calendarId" bson:"calendarId"`
+}
+
+type QuestionType string
+
+const (
+	TEXT_QUESTION     QuestionType = "text"
+	SELECT_QUESTION   QuestionType = "select"
+	CHECKBOX_QUESTION QuestionType = "checkbox"
+)
+
+// A custom question respondents answer when responding to the event
+type Question struct {
+	Id       primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	Type     QuestionType       `json:"type" bson:"type"`
+	Label    string             `json:"label" bson:"label"`
+	Required bool               `json:"required" bson:"required,omitempty"`
+
+	// Choices for select (pick one) and checkbox (pick any) questions
+	Options []string `json:"options" bson:"options,omitempty"`
+
+	// Only show the question if the condition is met
+	ShowIf *QuestionCondition `json:"showIf" bson:"showIf,omitempty"`
+}
+
+// A detail respondents provide about themselves, e.g. "Grade" or "Team"
+type RespondentField struct {
+	Id       primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	Label    string             `json:"label" bson:"label"`
+	Required bool               `json:"required" bson:"required,omitempty"`
+
+	// Values respondents pick from, any value is allowed if empty
+	Options []string `json:"options" bson:"options,omitempty"`
+}
+
+// Condition on the answer to an earlier question
+type QuestionCondition struct {
+	QuestionId primitive.ObjectID `json:"questionId" bson:"questionId"`
+
+	// The condition is met if the answer contains any of these values
+	AnyOf []string `json:"anyOf" bson:"anyOf"`
+}
+
+// Cancellation and no-show policy for paid sign up blocks
+type BookingPolicy struct {
+	// Respondents that cancel at least this many hours before their first
+	// block get a full refund
+	CancellationWindowHours int `json:"cancellationWindowHours" bson:"cancellationWindowHours"`
+
+	// Percentage of the payment refunded for later cancellations
+	LateCancellationRefundPercent int `json:"lateCancellationRefundPercent" bson:"lateCancellationRefundPercent"`
+
+	// Fee charged to respondents that don't show up, in the smallest unit of
+	// the event's payment currency
+	NoShowFee int64 `json:"noShowFee" bson:"noShowFee,omitempty"`
+}
+
+// Source of a response the organizer removed and blocked from responding again
+type BlockedRespondent struct {
+	Id        primitive.ObjectID `json:"_id" bson:"_id"`
+	Ip        string             `json:"ip" bson:"ip,omitempty"`
+	UserId    primitive.ObjectID `json:"userId" bson:"userId,omitempty"`
+	Email     string             `json:"email" bson:"email,omitempty"`
+	BlockedAt primitive.DateTime `json:"blockedAt" bson:"blockedAt"`
 }
 
 type SignUpResponse struct {
@@ -37,7 +170,19 @@ type SignUpResponse struct {
 
 	// User information
 	UserId primitive.ObjectID `json:"userId" bson:"userId,omitempty"`
-	User   *User              `json:"user" bson:",omitempty"`
+
+	// Answers to the event's questions, mapping question id to the answer values
+	Answers map[string][]string `json:"answers" bson:"answers,omitempty"`
+	User    *User               `json:"user" bson:",omitempty"`
+
+	// Values of the event's respondent fields, mapping field id to the value
+	Fields map[string]string `json:"fields" bson:"fields,omitempty"`
+
+	// IP address the response was submitted from
+	SourceIp string `json:"-" bson:"sourceIp,omitempty"`
+
+	// Hashes of where the guest submitted from
+	Fingerprint *Fingerprint `json:"-" bson:"fingerprint,omitempty"`
 }
 
 // Representation of an Event in the mongoDB database
@@ -50,6 +195,14 @@ type Event struct {
 	IsArchived  *bool              `json:"isArchived" bson:"isArchived,omitempty"`
 	IsDeleted   *bool              `json:"isDeleted" bson:"isDeleted,omitempty"`
 
+	// Whether the event is a draft that hasn't been sent out yet (e.g. created by email)
+	IsDraft *bool `json:"isDraft" bson:"isDraft,omitempty"`
+
+	// When the draft was published, and the attendees of group drafts, which
+	// are only invited once the draft is published
+	PublishedAt    *primitive.DateTime `json:"publishedAt" bson:"publishedAt,omitempty"`
+	DraftAttendees []string            `json:"draftAttendees" bson:"draftAttendees,omitempty"`
+
 	Duration                 *float32             `json:"duration" bson:"duration,omitempty"`
 	Dates                    []primitive.DateTime `json:"dates" bson:"dates,omitempty"`
 	NotificationsEnabled     *bool                `json:"notificationsEnabled" bson:"notificationsEnabled,omitempty"`
@@ -71,6 +224,45 @@ type Event struct {
 	IsSignUpForm    *bool                      `json:"isSignUpForm" bson:"isSignUpForm,omitempty"`
 	SignUpBlocks    *[]SignUpBlock             `json:"signUpBlocks" bson:"signUpBlocks,omitempty"`
 	SignUpResponses map[string]*SignUpResponse `json:"signUpResponses" bson:"signUpResponses"`
+	PaymentCurrency *string                    `json:"paymentCurrency" bson:"paymentCurrency,omitempty"`
+	BookingPolicy   *BookingPolicy             `json:"bookingPolicy" bson:"bookingPolicy,omitempty"`
+
+	// Custom questions respondents answer when submitting availability or signing up
+	Questions *[]Question `json:"questions" bson:"questions,omitempty"`
+
+	// Structured details respondents provide, e.g. "Grade" or "Team", that
+	// availability and exports can be grouped by
+	RespondentFields []RespondentField `json:"respondentFields" bson:"respondentFields,omitempty"`
+
+	// Terms respondents have to agree to before responding or signing up
+	ConsentDocument *ConsentDocument `json:"consentDocument" bson:"consentDocument,omitempty"`
+
+	// Only organizers and signed in users with an authorized email can respond
+	// to invite only events
+	InviteOnly       *bool    `json:"inviteOnly" bson:"inviteOnly,omitempty"`
+	AuthorizedEmails []string `json:"authorizedEmails" bson:"authorizedEmails,omitempty"`
+
+	// Shift schedule details, where volunteers are assigned to shifts based on their availability
+	IsShiftSchedule       *bool               `json:"isShiftSchedule" bson:"isShiftSchedule,omitempty"`
+	Shifts                *[]Shift            `json:"shifts" bson:"shifts,omitempty"`
+	MaxShiftsPerVolunteer *int                `json:"maxShiftsPerVolunteer" bson:"maxShiftsPerVolunteer,omitempty"`
+	ShiftAssignments      []ShiftAssignment   `json:"-" bson:"shiftAssignments,omitempty"`
+	ShiftsPublishedAt     *primitive.DateTime `json:"shiftsPublishedAt" bson:"shiftsPublishedAt,omitempty"`
+
+	// Pools of people and rooms to pick from when scheduling a panel (e.g. one interviewer from each team and a room)
+	PanelPools *[]PanelPool `json:"panelPools" bson:"panelPools,omitempty"`
+
+	// Organization the event was created in, whose settings apply to it
+	OrganizationId primitive.ObjectID `json:"organizationId" bson:"organizationId,omitempty"`
+
+	// Labels the organizer gave the event, e.g. to filter the organization's calendar
+	Tags []string `json:"tags" bson:"tags,omitempty"`
+
+	// Display settings, defaulting to the organization's
+	Timezone        *string          `json:"timezone" bson:"timezone,omitempty"`
+	WorkingHours    *WorkingHours    `json:"workingHours" bson:"workingHours,omitempty"`
+	ReminderCadence *ReminderCadence `json:"reminderCadence" bson:"reminderCadence,omitempty"`
+	Branding        *Branding        `json:"branding" bson:"branding,omitempty"`
 
 	// Whether to start the event on Monday (as opposed to Sunday, used for DOW events)
 	StartOnMonday *bool `json:"startOnMonday" bson:"startOnMonday,omitempty"`
@@ -78,9 +270,20 @@ type Event struct {
 	// Whether to enable blind availability
 	BlindAvailabilityEnabled *bool `json:"blindAvailabilityEnabled" bson:"blindAvailabilityEnabled,omitempty"`
 
+	// Who can see the event's respondents and their availability, defaults to
+	// anyone with the link
+	PrivacyLevel *PrivacyLevel `json:"privacyLevel" bson:"privacyLevel,omitempty"`
+
 	// Whether to only poll for days, not times
 	DaysOnly *bool `json:"daysOnly" bson:"daysOnly,omitempty"`
 
+	// Public holidays to flag or exclude when suggesting times
+	HolidaySettings *HolidaySettings `json:"holidaySettings" bson:"holidaySettings,omitempty"`
+
+	// Whether suggestions down-weight respondents that often marked themselves
+	// available for past events but didn't attend
+	WeightByAttendance *bool `json:"weightByAttendance" bson:"weightByAttendance,omitempty"`
+
 	// Availability responses - old format for backward compatibility (fetched from eventResponses collection)
 	ResponsesMap map[string]*Response `json:"responses" bson:"-"`
 
@@ -91,14 +294,179 @@ type Event struct {
 	ScheduledEvent  *CalendarEvent `json:"scheduledEvent" bson:"scheduledEvent,omitempty"`
 	CalendarEventId string         `json:"calendarEventId" bson:"calendarEventId,omitempty"`
 
+	// Ids of the respondents that must attend the scheduled event
+	RequiredAttendees []string `json:"requiredAttendees" bson:"requiredAttendees,omitempty"`
+
+	// Times the event was finalized at when it runs several times (e.g. two
+	// training sessions), instead of at a single scheduled time
+	Sessions []EventSession `json:"sessions" bson:"sessions,omitempty"`
+
+	// Rotation of the scheduled time for recurring meetings across timezones
+	Rotation *Rotation `json:"rotation" bson:"rotation,omitempty"`
+
+	// Rule for when enough respondents can make a time to schedule the event
+	Quorum *Quorum `json:"quorum" bson:"quorum,omitempty"`
+
+	// Time grid poll to create for the days most respondents of this days only
+	// poll can make, and the days only poll a time grid poll was created from
+	NextPhase            *NextPhase          `json:"nextPhase" bson:"nextPhase,omitempty"`
+	PreviousPhaseEventId *primitive.ObjectID `json:"previousPhaseEventId" bson:"previousPhaseEventId,omitempty"`
+
+	// When the poll closes, after which the share link no longer shows the grid
+	ExpiresAt *primitive.DateTime `json:"expiresAt" bson:"expiresAt,omitempty"`
+
+	// What respondents see after submitting their availability
+	PostSubmission *PostSubmission `json:"postSubmission" bson:"postSubmission,omitempty"`
+
+	// Read-only links to the aggregated availability, e.g. for dashboards
+	AvailabilityShares []AvailabilityShare `json:"-" bson:"availabilityShares,omitempty"`
+
+	// Sources of removed responses that can't respond again
+	BlockedRespondents []BlockedRespondent `json:"-" bson:"blockedRespondents,omitempty"`
+
+	// Respondents that can no longer make the scheduled event
+	Cancellations []Cancellation `json:"cancellations" bson:"cancellations,omitempty"`
+
+	// Resources (rooms, equipment) booked for the scheduled event
+	ResourceIds []primitive.ObjectID `json:"resourceIds" bson:"resourceIds,omitempty"`
+
 	// Remindees
 	Remindees *[]Remindee `json:"remindees" bson:"remindees,omitempty"`
 
+	// Slots emailed to invitees who respond by email instead of on the grid
+	EmailPoll *EmailPoll `json:"emailPoll" bson:"emailPoll,omitempty"`
+
+	// Google Classroom course whose students are kept in sync as the remindees
+	ClassroomRoster *ClassroomRoster `json:"classroomRoster" bson:"classroomRoster,omitempty"`
+
+	// Linear or Jira issue the scheduled time is posted to
+	IssueLink *IssueLink `json:"issueLink" bson:"issueLink,omitempty"`
+
+	// Row of the event in the owner's Notion database
+	NotionPage *NotionPage `json:"-" bson:"notionPage,omitempty"`
+
+	// Users that help the owner organize the event
+	CoOrganizers []CoOrganizer `json:"coOrganizers" bson:"coOrganizers,omitempty"`
+
+	// Co-organizers that have to approve finalizing the event, and the
+	// finalization waiting for their approval
+	FinalizationApprovers *FinalizationApprovers `json:"finalizationApprovers" bson:"finalizationApprovers,omitempty"`
+	PendingFinalization   *PendingFinalization   `json:"pendingFinalization" bson:"pendingFinalization,omitempty"`
+
+	// Which alerts the owner and co-organizers receive, by user
+	NotificationRules []NotificationRule `json:"-" bson:"notificationRules,omitempty"`
+
+	// When the owner last nudged the invitees that haven't responded
+	LastNudgedAt *primitive.DateTime `json:"lastNudgedAt" bson:"lastNudgedAt,omitempty"`
+
 	// Attendees for an availability group (fetched from Attendees collection)
 	Attendees *[]Attendee `json:"attendees" bson:"-"`
 
 	// Whether the user has responded to the availability group (fetched based on whether user is in Attendees)
 	HasResponded *bool `json:"hasResponded" bson:"-"`
+
+	// Single use token guests submit their response with (issued with the event page)
+	SubmissionToken string `json:"submissionToken,omitempty" bson:"-"`
+}
+
+type HolidaySettings struct {
+	// ISO 3166-1 alpha-2 country code, e.g. "US"
+	Country string `json:"country" bson:"country"`
+
+	// Optional ISO 3166-2 region code, e.g. "US-CA"
+	Region string `json:"region" bson:"region,omitempty"`
+
+	// Whether to exclude holidays from suggestions, instead of just flagging them
+	Exclude bool `json:"exclude" bson:"exclude,omitempty"`
+}
+
+// A quorum, e.g. "at least 5 people and both leads"
+type Quorum struct {
+	// Number of respondents that must be able to make it
+	MinAttendees int `json:"minAttendees" bson:"minAttendees"`
+
+	// Ids of the respondents that must be able to make it (user id, or name for guests)
+	Required []string `json:"required" bson:"required,omitempty"`
+
+	// Length of the meeting in minutes, defaults to the event's time increment
+	DurationMinutes int `json:"durationMinutes" bson:"durationMinutes,omitempty"`
+
+	// Whether respondents that are only available if needed count towards the quorum
+	CountIfNeeded bool `json:"countIfNeeded" bson:"countIfNeeded,omitempty"`
+
+	// Whether to finalize the event at the best slot as soon as quorum is met
+	AutoFinalize bool `json:"autoFinalize" bson:"autoFinalize,omitempty"`
+
+	// When quorum was first met, after which the organizers aren't notified again
+	MetAt *primitive.DateTime `json:"metAt" bson:"metAt,omitempty"`
+}
+
+// Second phase of a two phase poll, where a days only poll narrows down the
+// days and a time grid poll is then created for the winning days
+type NextPhase struct {
+	// Number of days to carry forward, the days the most respondents can make
+	NumDays int `json:"numDays" bson:"numDays"`
+
+	// Daily time range of the time grid, in the event's timezone
+	Hours WorkingHours `json:"hours" bson:"hours"`
+
+	// Time increment of the time grid in minutes, defaults to 15
+	TimeIncrement int `json:"timeIncrement" bson:"timeIncrement,omitempty"`
+
+	// When to create the time grid poll automatically, if ever
+	AdvanceAt *primitive.DateTime `json:"advanceAt" bson:"advanceAt,omitempty"`
+
+	// Id of the time grid poll, once it's created
+	EventId *primitive.ObjectID `json:"eventId" bson:"eventId,omitempty"`
+}
+
+// One of several times an event was finalized at, and the respondents assigned to it
+type EventSession struct {
+	Id        primitive.ObjectID `json:"_id" bson:"_id"`
+	StartDate primitive.DateTime `json:"startDate" bson:"startDate"`
+	EndDate   primitive.DateTime `json:"endDate" bson:"endDate"`
+
+	Attendees []SessionAttendee `json:"attendees" bson:"attendees"`
+}
+
+type SessionAttendee struct {
+	// Id of the respondent (user id, or name for guests)
+	UserId string `json:"userId" bson:"userId"`
+	Name   string `json:"name" bson:"name"`
+	Email  string `json:"-" bson:"email,omitempty"`
+
+	// Whether the respondent is only available for the session if needed
+	IfNeeded bool `json:"ifNeeded" bson:"ifNeeded,omitempty"`
+
+	// When the respondent confirmed they will attend the session
+	ConfirmedAt *primitive.DateTime `json:"confirmedAt" bson:"confirmedAt,omitempty"`
+}
+
+// A respondent cancelling their attendance of a scheduled event
+type Cancellation struct {
+	// Id of the respondent (user id, or name for guests)
+	UserId    string             `json:"userId" bson:"userId"`
+	Reason    string             `json:"reason" bson:"reason,omitempty"`
+	CreatedAt primitive.DateTime `json:"createdAt" bson:"createdAt"`
+
+	// The scheduled time that was cancelled
+	StartDate primitive.DateTime `json:"startDate" bson:"startDate"`
+	EndDate   primitive.DateTime `json:"endDate" bson:"endDate"`
+}
+
+// Where respondents are sent after submitting, or the thank-you message shown
+// to them. The redirect URL has to be on an allowed domain
+type PostSubmission struct {
+	RedirectUrl string `json:"redirectUrl" bson:"redirectUrl,omitempty"`
+	Message     string `json:"message" bson:"message,omitempty"`
+}
+
+// A read-only link to the aggregated availability of an event, which doesn't
+// reveal who responded
+type AvailabilityShare struct {
+	Token     string             `json:"token" bson:"token"`
+	Label     string             `json:"label" bson:"label,omitempty"`
+	CreatedAt primitive.DateTime `json:"createdAt" bson:"createdAt"`
 }
 
 func (e *Event) GetId() string {
@@ -0,0 +1,11 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Another id an event can be opened by, e.g. the short id of a link shared
+// before the event was migrated from schej.it
+type EventAlias struct {
+	Alias     string             `json:"alias" bson:"_id"`
+	EventId   primitive.ObjectID `json:"eventId" bson:"eventId"`
+	CreatedAt primitive.DateTime `json:"createdAt" bson:"createdAt"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Co-organizers that have to approve finalizing an event before the scheduled
+// time is set
+type FinalizationApprovers struct {
+	UserIds []primitive.ObjectID `json:"userIds" bson:"userIds"`
+
+	// Number of approvals needed, between 1 and the number of approvers
+	Quorum int `json:"quorum" bson:"quorum"`
+}
+
+// A finalization waiting for the approvers to approve it
+type PendingFinalization struct {
+	StartDate       primitive.DateTime   `json:"startDate" bson:"startDate"`
+	EndDate         primitive.DateTime   `json:"endDate" bson:"endDate"`
+	Required        []string             `json:"required" bson:"required"`
+	ResourceIds     []primitive.ObjectID `json:"resourceIds" bson:"resourceIds,omitempty"`
+	InviteAttendees bool                 `json:"inviteAttendees" bson:"inviteAttendees,omitempty"`
+
+	RequestedBy primitive.ObjectID   `json:"requestedBy" bson:"requestedBy"`
+	RequestedAt primitive.DateTime   `json:"requestedAt" bson:"requestedAt"`
+	ApprovedBy  []primitive.ObjectID `json:"approvedBy" bson:"approvedBy"`
+}
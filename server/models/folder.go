@@ -6,9 +6,23 @@ type Folder struct {
 	Id     primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
 	UserId primitive.ObjectID `json:"userId" bson:"userId"`
 
+	// Organization whose members share the folder, which its admins manage.
+	// UserId is then the member that created it
+	OrganizationId *primitive.ObjectID `json:"organizationId,omitempty" bson:"organizationId,omitempty"`
+
+	// Stable id of an organization folder set by the provisioning API
+	ExternalId *string `json:"externalId,omitempty" bson:"externalId,omitempty"`
+
 	Name      string  `json:"name,omitempty" bson:"name,omitempty"`
 	Color     *string `json:"color,omitempty" bson:"color,omitempty"`
 	IsDeleted *bool   `json:"isDeleted,omitempty" bson:"isDeleted,omitempty"`
 
+	// Issue tracker finalized events in the folder are posted to
+	IssueTracker *IssueTracker `json:"issueTracker,omitempty" bson:"issueTracker,omitempty"`
+
+	// Emails of the users made co-organizers of events their owners add to
+	// the folder
+	DefaultCoOrganizers []string `json:"defaultCoOrganizers,omitempty" bson:"defaultCoOrganizers,omitempty"`
+
 	EventIds []primitive.ObjectID `json:"eventIds" bson:"-"`
 }
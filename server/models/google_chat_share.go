@@ -0,0 +1,12 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Records that an event was shared to a Google Chat space, so that the space
+// can be notified once the event is scheduled
+type GoogleChatShare struct {
+	Id       primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	EventId  primitive.ObjectID `json:"eventId" bson:"eventId,omitempty"`
+	Space    string             `json:"space" bson:"space,omitempty"` // Resource name of the space, e.g. "spaces/AAAA"
+	SharedAt primitive.DateTime `json:"sharedAt" bson:"sharedAt,omitempty"`
+}
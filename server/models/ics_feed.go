@@ -0,0 +1,20 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Public iCalendar feed respondents imported busy times from, cached so it
+// isn't fetched on every import and refreshed periodically while in use
+type IcsFeed struct {
+	Id  primitive.ObjectID `json:"-" bson:"_id,omitempty"`
+	Url string             `json:"-" bson:"url"`
+
+	// Busy times parsed from the feed, with recurring events expanded, over a
+	// window around when it was fetched
+	BusyTimes []CalendarEvent `json:"busyTimes" bson:"busyTimes"`
+
+	FetchedAt  primitive.DateTime `json:"fetchedAt" bson:"fetchedAt"`
+	LastUsedAt primitive.DateTime `json:"-" bson:"lastUsedAt"`
+
+	// Error of the last refresh, if it failed
+	LastError string `json:"-" bson:"lastError,omitempty"`
+}
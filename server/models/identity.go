@@ -0,0 +1,34 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Provider of an external identity linked to a user
+type IdentityProvider string
+
+const (
+	GOOGLE_IDENTITY    IdentityProvider = "google"
+	MICROSOFT_IDENTITY IdentityProvider = "microsoft"
+	APPLE_IDENTITY     IdentityProvider = "apple"
+	SLACK_IDENTITY     IdentityProvider = "slack"
+	DISCORD_IDENTITY   IdentityProvider = "discord"
+)
+
+var IdentityProviders = []IdentityProvider{GOOGLE_IDENTITY, MICROSOFT_IDENTITY, APPLE_IDENTITY, SLACK_IDENTITY, DISCORD_IDENTITY}
+
+// An account with an external provider that belongs to a user. Slack
+// identities are stored as slack accounts, so that the slackbot can look
+// them up
+type Identity struct {
+	Provider IdentityProvider `json:"provider" bson:"provider"`
+
+	// Id of the account with the provider
+	Subject string `json:"subject" bson:"subject"`
+
+	// Verified email of the account, empty if the provider doesn't have one
+	Email string `json:"email" bson:"email,omitempty"`
+
+	// Workspace of slack identities
+	TeamId string `json:"teamId,omitempty" bson:"teamId,omitempty"`
+
+	LinkedAt primitive.DateTime `json:"linkedAt" bson:"linkedAt"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type IncidentStatus string
+
+const (
+	INCIDENT_INVESTIGATING IncidentStatus = "investigating"
+	INCIDENT_IDENTIFIED    IncidentStatus = "identified"
+	INCIDENT_MONITORING    IncidentStatus = "monitoring"
+	INCIDENT_RESOLVED      IncidentStatus = "resolved"
+)
+
+type IncidentImpact string
+
+const (
+	INCIDENT_DEGRADED IncidentImpact = "degraded"
+	INCIDENT_OUTAGE   IncidentImpact = "outage"
+)
+
+// An incident shown on the status page, managed by admins
+type Incident struct {
+	Id     primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	Title  string             `json:"title" bson:"title"`
+	Impact IncidentImpact     `json:"impact" bson:"impact"`
+	Status IncidentStatus     `json:"status" bson:"status"`
+
+	// Components affected by the incident, e.g. "email"
+	Components []string `json:"components" bson:"components"`
+
+	// Updates posted about the incident, oldest first
+	Updates []IncidentUpdate `json:"updates" bson:"updates"`
+
+	CreatedAt  primitive.DateTime  `json:"createdAt" bson:"createdAt"`
+	ResolvedAt *primitive.DateTime `json:"resolvedAt,omitempty" bson:"resolvedAt,omitempty"`
+}
+
+type IncidentUpdate struct {
+	Status    IncidentStatus     `json:"status" bson:"status"`
+	Message   string             `json:"message" bson:"message"`
+	CreatedAt primitive.DateTime `json:"createdAt" bson:"createdAt"`
+}
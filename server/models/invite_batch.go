@@ -0,0 +1,61 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type InviteBatchStatus string
+
+const (
+	INVITE_BATCH_SENDING   InviteBatchStatus = "sending"
+	INVITE_BATCH_SENT      InviteBatchStatus = "sent"
+	INVITE_BATCH_CANCELLED InviteBatchStatus = "cancelled"
+)
+
+type InviteStatus string
+
+const (
+	INVITE_PENDING InviteStatus = "pending"
+	INVITE_SENT    InviteStatus = "sent"
+	INVITE_FAILED  InviteStatus = "failed"
+	INVITE_SKIPPED InviteStatus = "skipped"
+)
+
+// Invite emails to the invitees of a roster uploaded by the owner of an
+// event, sent a few at a time by the jobs scheduler
+type InviteBatch struct {
+	Id      primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	EventId primitive.ObjectID `json:"eventId" bson:"eventId"`
+	OwnerId primitive.ObjectID `json:"ownerId" bson:"ownerId"`
+
+	// Locale of the emails of invitees without one in the roster
+	Locale string `json:"locale" bson:"locale"`
+	// Note from the owner added to every email
+	Message string `json:"message" bson:"message,omitempty"`
+
+	Status      InviteBatchStatus   `json:"status" bson:"status"`
+	CreatedAt   primitive.DateTime  `json:"createdAt" bson:"createdAt"`
+	CompletedAt *primitive.DateTime `json:"completedAt" bson:"completedAt,omitempty"`
+
+	// Set while a server is sending the next invites, so others skip the batch
+	LockedUntil *primitive.DateTime `json:"-" bson:"lockedUntil,omitempty"`
+
+	Invitees   []Invitee `json:"invitees" bson:"invitees"`
+	NumSent    int       `json:"numSent" bson:"numSent"`
+	NumFailed  int       `json:"numFailed" bson:"numFailed"`
+	NumSkipped int       `json:"numSkipped" bson:"numSkipped"`
+}
+
+type Invitee struct {
+	Email  string `json:"email" bson:"email"`
+	Name   string `json:"name" bson:"name,omitempty"`
+	Locale string `json:"locale" bson:"locale,omitempty"`
+
+	// Secret in the invitee's link, used to track when they open it
+	Key string `json:"-" bson:"key"`
+
+	Status InviteStatus `json:"status" bson:"status"`
+	// Why the invite failed or was skipped
+	Error     string              `json:"error" bson:"error,omitempty"`
+	SentAt    *primitive.DateTime `json:"sentAt" bson:"sentAt,omitempty"`
+	OpenedAt  *primitive.DateTime `json:"openedAt" bson:"openedAt,omitempty"`
+	Responded bool                `json:"responded" bson:"-"`
+}
@@ -0,0 +1,37 @@
+package models
+
+type IssueTrackerType string
+
+const (
+	LinearIssueTracker IssueTrackerType = "linear"
+	JiraIssueTracker   IssueTrackerType = "jira"
+)
+
+// Issue tracker that finalized events in a folder are posted to, as a comment
+// on the event's issue
+type IssueTracker struct {
+	Type    IssueTrackerType `json:"type" bson:"type"`
+	Enabled bool             `json:"enabled" bson:"enabled"`
+
+	// Encrypted Linear API key, or Jira API token
+	Token string `json:"-" bson:"token"`
+
+	// Jira site (e.g. https://acme.atlassian.net) and the email of the
+	// account the token belongs to
+	JiraUrl   string `json:"jiraUrl,omitempty" bson:"jiraUrl,omitempty"`
+	JiraEmail string `json:"jiraEmail,omitempty" bson:"jiraEmail,omitempty"`
+
+	// Linear team id or Jira project key to create an issue in for events
+	// that aren't linked to one. Events without an issue are skipped if empty
+	Project string `json:"project,omitempty" bson:"project,omitempty"`
+}
+
+// Issue an event is linked to, and the comment posted on it when the event
+// was finalized, which is updated if it's finalized again
+type IssueLink struct {
+	Type      IssueTrackerType `json:"type" bson:"type"`
+	IssueKey  string           `json:"issueKey" bson:"issueKey"`
+	IssueId   string           `json:"-" bson:"issueId,omitempty"`
+	IssueUrl  string           `json:"issueUrl,omitempty" bson:"issueUrl,omitempty"`
+	CommentId string           `json:"commentId,omitempty" bson:"commentId,omitempty"`
+}
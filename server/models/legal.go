@@ -0,0 +1,35 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type LegalDocumentType string
+
+const (
+	TERMS_OF_SERVICE LegalDocumentType = "termsOfService"
+	PRIVACY_POLICY   LegalDocumentType = "privacyPolicy"
+)
+
+var LegalDocumentTypes = []LegalDocumentType{TERMS_OF_SERVICE, PRIVACY_POLICY}
+
+// A published version of the terms of service or privacy policy
+type LegalDocument struct {
+	Id      primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	Type    LegalDocumentType  `json:"type" bson:"type"`
+	Version int                `json:"version" bson:"version"`
+
+	// Where the full text is published, and a summary of what changed
+	Url     string `json:"url" bson:"url"`
+	Summary string `json:"summary" bson:"summary,omitempty"`
+
+	// Users have to accept required versions before they can keep using the API
+	Required    bool               `json:"required" bson:"required"`
+	PublishedAt primitive.DateTime `json:"publishedAt" bson:"publishedAt"`
+}
+
+// Record of a user accepting a version of the terms of service or privacy policy
+type LegalAcceptance struct {
+	Type       LegalDocumentType  `json:"type" bson:"type"`
+	Version    int                `json:"version" bson:"version"`
+	AcceptedAt primitive.DateTime `json:"acceptedAt" bson:"acceptedAt"`
+	IpAddress  string             `json:"-" bson:"ipAddress,omitempty"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// An LMS (e.g. Canvas or Moodle) registered to launch Timeful events with LTI 1.3
+type LtiPlatform struct {
+	Id      primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	OwnerId primitive.ObjectID `json:"ownerId" bson:"ownerId"`
+	Name    string             `json:"name" bson:"name"`
+
+	// Values given by the LMS when registering Timeful as a tool
+	Issuer        string   `json:"issuer" bson:"issuer"`
+	ClientId      string   `json:"clientId" bson:"clientId"`
+	DeploymentIds []string `json:"deploymentIds" bson:"deploymentIds,omitempty"` // Any deployment is allowed if empty
+	AuthLoginUrl  string   `json:"authLoginUrl" bson:"authLoginUrl"`
+	JwksUrl       string   `json:"jwksUrl" bson:"jwksUrl"`
+
+	CreatedAt primitive.DateTime `json:"createdAt" bson:"createdAt"`
+}
+
+// Maps an LMS user to the guest name they respond to events with, so that
+// their responses stay the same across launches
+type LtiIdentity struct {
+	Id         primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	PlatformId primitive.ObjectID `json:"platformId" bson:"platformId"`
+	Subject    string             `json:"subject" bson:"subject"` // "sub" claim of the launch
+	Name       string             `json:"name" bson:"name"`
+	Email      string             `json:"email" bson:"email,omitempty"`
+
+	LastLaunchedAt primitive.DateTime `json:"lastLaunchedAt" bson:"lastLaunchedAt"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// An invitee that doesn't want to be nudged to respond to an event anymore
+type NotificationOptOut struct {
+	Id        primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	EventId   primitive.ObjectID `json:"eventId" bson:"eventId"`
+	Email     string             `json:"email" bson:"email"`
+	CreatedAt primitive.DateTime `json:"createdAt" bson:"createdAt"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Notion database a user's events are written to, one row per event
+type NotionConnection struct {
+	// Encrypted token of the Notion integration the database is shared with
+	Token string `json:"-" bson:"token"`
+
+	DatabaseId    string             `json:"databaseId" bson:"databaseId"`
+	DatabaseTitle string             `json:"databaseTitle" bson:"databaseTitle,omitempty"`
+	ConnectedAt   primitive.DateTime `json:"connectedAt" bson:"connectedAt"`
+
+	// Error of the last sync, e.g. if the integration lost access to the database
+	LastError string `json:"lastError" bson:"lastError,omitempty"`
+}
+
+// Row of an event in its owner's Notion database
+type NotionPage struct {
+	PageId string `json:"pageId" bson:"pageId"`
+
+	// Hash of the properties last written, so unchanged events aren't rewritten
+	Hash     string             `json:"-" bson:"hash"`
+	SyncedAt primitive.DateTime `json:"syncedAt" bson:"syncedAt"`
+}
@@ -0,0 +1,169 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type OrganizationRole string
+
+const (
+	// Admins that can also add and remove other owners
+	ORG_OWNER OrganizationRole = "owner"
+
+	ORG_ADMIN  OrganizationRole = "admin"
+	ORG_MEMBER OrganizationRole = "member"
+
+	// Manages the organization's subscription and invoices, without being
+	// able to change its members, settings or policies
+	ORG_BILLING_ADMIN OrganizationRole = "billingAdmin"
+)
+
+// Event settings that organization admins can set defaults for and lock
+type OrganizationSetting string
+
+const (
+	TIMEZONE_SETTING         OrganizationSetting = "timezone"
+	WORKING_HOURS_SETTING    OrganizationSetting = "workingHours"
+	ANONYMITY_SETTING        OrganizationSetting = "blindAvailabilityEnabled"
+	REMINDER_CADENCE_SETTING OrganizationSetting = "reminderCadence"
+	BRANDING_SETTING         OrganizationSetting = "branding"
+)
+
+var OrganizationSettingKeys = []OrganizationSetting{TIMEZONE_SETTING, WORKING_HOURS_SETTING, ANONYMITY_SETTING, REMINDER_CADENCE_SETTING, BRANDING_SETTING}
+
+// A group of users whose events share default settings
+type Organization struct {
+	Id       primitive.ObjectID   `json:"_id" bson:"_id,omitempty"`
+	Name     string               `json:"name" bson:"name"`
+	Members  []OrganizationMember `json:"members" bson:"members"`
+	Settings OrganizationSettings `json:"settings" bson:"settings"`
+	Policies OrganizationPolicies `json:"policies" bson:"policies"`
+
+	// Stable id set by the provisioning API, e.g. from a Terraform config
+	ExternalId *string `json:"externalId,omitempty" bson:"externalId,omitempty"`
+
+	// Email domains claimed by the organization, whose new users join it
+	Domains []OrganizationDomain `json:"domains" bson:"domains,omitempty"`
+
+	// Domain that emails about the organization's events are sent from
+	SendingDomain *OrganizationSendingDomain `json:"sendingDomain" bson:"sendingDomain,omitempty"`
+
+	// Custom invite and reminder emails, only used while the organization is
+	// premium
+	EmailTemplates []EmailTemplate `json:"emailTemplates" bson:"emailTemplates,omitempty"`
+
+	// Users from claimed domains waiting for an admin to approve them
+	JoinRequests []OrganizationJoinRequest `json:"joinRequests" bson:"joinRequests,omitempty"`
+
+	// People invited by email that don't have an account yet, who join once
+	// they sign up
+	Invites []OrganizationInvite `json:"invites" bson:"invites,omitempty"`
+
+	// Subscription of the organization, managed by admins and billing admins
+	StripeCustomerId *string            `json:"stripeCustomerId" bson:"stripeCustomerId,omitempty"`
+	IsPremium        *bool              `json:"isPremium" bson:"isPremium,omitempty"`
+	CreatedAt        primitive.DateTime `json:"createdAt" bson:"createdAt"`
+}
+
+// An email domain claimed by an organization. The claim only takes effect once
+// the domain has a TXT record with the verification token
+type OrganizationDomain struct {
+	Domain            string              `json:"domain" bson:"domain"`
+	VerificationToken string              `json:"verificationToken" bson:"verificationToken"`
+	VerifiedAt        *primitive.DateTime `json:"verifiedAt" bson:"verifiedAt,omitempty"`
+
+	// Whether new users need to be approved by an admin to join
+	RequireApproval bool `json:"requireApproval" bson:"requireApproval,omitempty"`
+}
+
+// A domain that invites, reminders, and broadcasts of the organization's events
+// are sent from, e.g. invites@acme.com. It's only used once its verification,
+// SPF, and DKIM records are all verified
+type OrganizationSendingDomain struct {
+	Domain string `json:"domain" bson:"domain"`
+
+	// Local part of the from address, e.g. "invites"
+	LocalPart string `json:"localPart" bson:"localPart"`
+
+	// Name the emails are from, the organization's name if empty
+	FromName string `json:"fromName" bson:"fromName,omitempty"`
+
+	VerificationToken string `json:"verificationToken" bson:"verificationToken"`
+
+	// Key the emails are signed with, whose public half is published at
+	// <selector>._domainkey.<domain>
+	DkimSelector   string `json:"dkimSelector" bson:"dkimSelector"`
+	DkimPrivateKey string `json:"-" bson:"dkimPrivateKey"`
+
+	VerifiedAt *primitive.DateTime `json:"verifiedAt" bson:"verifiedAt,omitempty"`
+}
+
+type OrganizationJoinRequest struct {
+	UserId      primitive.ObjectID `json:"userId" bson:"userId"`
+	RequestedAt primitive.DateTime `json:"requestedAt" bson:"requestedAt"`
+	User        *User              `json:"user" bson:"-"`
+}
+
+// Compliance rules enforced on the organization's events
+type OrganizationPolicies struct {
+	// Guests have to provide their email to respond
+	RequireGuestEmail bool `json:"requireGuestEmail" bson:"requireGuestEmail,omitempty"`
+
+	// Events are deleted this many days after they're created, 0 keeps them
+	RetentionDays int `json:"retentionDays" bson:"retentionDays,omitempty"`
+
+	// Remindees, attendees and co-organizers have to be members of the
+	// organization, or have an email on one of the allowed domains
+	DisableExternalSharing bool     `json:"disableExternalSharing" bson:"disableExternalSharing,omitempty"`
+	AllowedDomains         []string `json:"allowedDomains" bson:"allowedDomains,omitempty"`
+
+	// Viewers have to sign in with the organization's identity provider
+	// ("google" or "outlook"), as a member or with an email on one of its
+	// verified domains, to see the respondents or respond
+	RequireSso  bool         `json:"requireSso" bson:"requireSso,omitempty"`
+	SsoProvider CalendarType `json:"ssoProvider" bson:"ssoProvider,omitempty"`
+}
+
+type OrganizationInvite struct {
+	Email     string             `json:"email" bson:"email"`
+	Role      OrganizationRole   `json:"role" bson:"role"`
+	InvitedBy primitive.ObjectID `json:"invitedBy" bson:"invitedBy"`
+	InvitedAt primitive.DateTime `json:"invitedAt" bson:"invitedAt"`
+}
+
+type OrganizationMember struct {
+	UserId primitive.ObjectID `json:"userId" bson:"userId"`
+	Role   OrganizationRole   `json:"role" bson:"role"`
+	User   *User              `json:"user" bson:"-"`
+}
+
+// Defaults that pre-populate new events created by members. Locked settings
+// can't be changed by members
+type OrganizationSettings struct {
+	Timezone                 *string          `json:"timezone" bson:"timezone,omitempty"`
+	WorkingHours             *WorkingHours    `json:"workingHours" bson:"workingHours,omitempty"`
+	BlindAvailabilityEnabled *bool            `json:"blindAvailabilityEnabled" bson:"blindAvailabilityEnabled,omitempty"`
+	ReminderCadence          *ReminderCadence `json:"reminderCadence" bson:"reminderCadence,omitempty"`
+	Branding                 *Branding        `json:"branding" bson:"branding,omitempty"`
+
+	// Hourly rate per attendee that the cost estimates of the organization's
+	// events use when the organizer doesn't give one
+	HourlyRate *float64 `json:"hourlyRate" bson:"hourlyRate,omitempty"`
+
+	Locked []OrganizationSetting `json:"locked" bson:"locked,omitempty"`
+}
+
+// Daily time range that is shown by default, in the event's timezone
+type WorkingHours struct {
+	StartTime string `json:"startTime" bson:"startTime"` // e.g. "09:00"
+	EndTime   string `json:"endTime" bson:"endTime"`     // e.g. "17:00"
+}
+
+// When remindees get the follow up reminder emails, in hours after the first
+type ReminderCadence struct {
+	SecondReminderHours int `json:"secondReminderHours" bson:"secondReminderHours"`
+	FinalReminderHours  int `json:"finalReminderHours" bson:"finalReminderHours"`
+}
+
+type Branding struct {
+	LogoUrl      string `json:"logoUrl" bson:"logoUrl,omitempty"`
+	PrimaryColor string `json:"primaryColor" bson:"primaryColor,omitempty"` // e.g. "#00994c"
+}
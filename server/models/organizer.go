@@ -0,0 +1,32 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Kinds of alerts sent to the organizers of an event
+type AlertType string
+
+const (
+	RESPONSE_ALERT           AlertType = "response"
+	RESPONSE_THRESHOLD_ALERT AlertType = "responseThreshold"
+	EVERYONE_RESPONDED_ALERT AlertType = "everyoneResponded"
+	RESCHEDULE_ALERT         AlertType = "reschedule"
+	QUORUM_ALERT             AlertType = "quorum"
+)
+
+var AlertTypes = []AlertType{RESPONSE_ALERT, RESPONSE_THRESHOLD_ALERT, EVERYONE_RESPONDED_ALERT, RESCHEDULE_ALERT, QUORUM_ALERT}
+
+// A user that helps the owner organize an event
+type CoOrganizer struct {
+	Email string `json:"email" bson:"email"`
+
+	// Set once the email belongs to a Timeful user
+	UserId primitive.ObjectID `json:"userId" bson:"userId,omitempty"`
+}
+
+// Which alerts an organizer receives for an event, and over which channels.
+// Organizers without a rule receive every alert by email
+type NotificationRule struct {
+	UserId   primitive.ObjectID `json:"userId" bson:"userId"`
+	Alerts   []AlertType        `json:"alerts" bson:"alerts"`
+	Channels []DeliveryChannel  `json:"channels" bson:"channels"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type PaymentType string
+
+const (
+	BOOKING_PAYMENT PaymentType = "booking"
+	NO_SHOW_FEE     PaymentType = "noShowFee"
+)
+
+type PaymentStatus string
+
+const (
+	PAYMENT_PENDING  PaymentStatus = "pending"
+	PAYMENT_PAID     PaymentStatus = "paid"
+	PAYMENT_REFUNDED PaymentStatus = "refunded"
+	PAYMENT_FAILED   PaymentStatus = "failed"
+)
+
+// A payment made by a respondent to confirm sign up slots (collected with
+// Stripe Checkout), or a no-show fee charged to the payment method they saved,
+// on behalf of the event owner's connected Stripe account
+type Payment struct {
+	Id      primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	EventId primitive.ObjectID `json:"eventId" bson:"eventId"`
+	OwnerId primitive.ObjectID `json:"ownerId" bson:"ownerId"`
+	Type    PaymentType        `json:"type" bson:"type"`
+
+	// Id of the respondent (user id, or name for guests) and the sign up blocks they're paying for
+	UserId         string               `json:"userId" bson:"userId"`
+	Name           string               `json:"name" bson:"name,omitempty"`
+	Email          string               `json:"email" bson:"email,omitempty"`
+	SignUpBlockIds []primitive.ObjectID `json:"signUpBlockIds" bson:"signUpBlockIds"`
+
+	// Answers to the event's questions, saved on the sign up once paid
+	Answers map[string][]string `json:"-" bson:"answers,omitempty"`
+
+	// Amounts in the smallest unit of the currency (e.g. cents)
+	Amount         int64  `json:"amount" bson:"amount"`
+	ApplicationFee int64  `json:"applicationFee" bson:"applicationFee"`
+	Currency       string `json:"currency" bson:"currency"`
+	RefundedAmount int64  `json:"refundedAmount" bson:"refundedAmount,omitempty"`
+
+	Status            PaymentStatus `json:"status" bson:"status"`
+	CheckoutSessionId string        `json:"-" bson:"checkoutSessionId,omitempty"`
+	PaymentIntentId   string        `json:"-" bson:"paymentIntentId,omitempty"`
+
+	// Customer and payment method saved at checkout, used to charge no-show fees
+	CustomerId      string `json:"-" bson:"customerId,omitempty"`
+	PaymentMethodId string `json:"-" bson:"paymentMethodId,omitempty"`
+
+	CreatedAt  primitive.DateTime  `json:"createdAt" bson:"createdAt"`
+	PaidAt     *primitive.DateTime `json:"paidAt" bson:"paidAt,omitempty"`
+	RefundedAt *primitive.DateTime `json:"refundedAt" bson:"refundedAt,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// How the principal of a request authenticated
+type AuthMethod string
+
+const (
+	SESSION_AUTH     AuthMethod = "session"
+	API_KEY_AUTH     AuthMethod = "apiKey"
+	OAUTH_TOKEN_AUTH AuthMethod = "oauthToken"
+	SLACK_AUTH       AuthMethod = "slack"
+)
+
+// The user a request is made on behalf of, and how they authenticated
+type Principal struct {
+	UserId primitive.ObjectID `json:"userId"`
+	Method AuthMethod         `json:"method"`
+
+	// Identity provider the user signed in with, for sessions
+	AuthProvider CalendarType `json:"authProvider,omitempty"`
+
+	// Key the request was made with, for API keys
+	ApiKey *ApiKey `json:"-"`
+
+	// Slack user the request was made by, for Slack
+	SlackUserId string `json:"slackUserId,omitempty"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// A region the API is deployed in, reported periodically by its servers so
+// the regions sharing the database can route work between them
+type Region struct {
+	Name        string             `json:"name" bson:"_id"`
+	HeartbeatAt primitive.DateTime `json:"heartbeatAt" bson:"heartbeatAt"`
+
+	// Average latency of the region's requests to each calendar provider, in
+	// milliseconds, e.g. {"google": 120}
+	CalendarLatencies map[string]float64 `json:"calendarLatencies" bson:"calendarLatencies"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type ResourceType string
+
+const (
+	ROOM      ResourceType = "room"
+	EQUIPMENT ResourceType = "equipment"
+)
+
+// A bookable resource, such as a room or a piece of equipment
+type Resource struct {
+	Id          primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	OwnerId     primitive.ObjectID `json:"ownerId" bson:"ownerId"`
+	Name        string             `json:"name" bson:"name"`
+	Type        ResourceType       `json:"type" bson:"type"`
+	Description string             `json:"description" bson:"description,omitempty"`
+
+	// Number of people that fit in a room
+	Capacity *int `json:"capacity" bson:"capacity,omitempty"`
+
+	// Calendar on the owner's connected calendar accounts that also tracks the
+	// resource's bookings, e.g. a Google resource calendar
+	Calendar *ResourceCalendar `json:"calendar" bson:"calendar,omitempty"`
+
+	IsDeleted *bool `json:"isDeleted,omitempty" bson:"isDeleted,omitempty"`
+}
+
+type ResourceCalendar struct {
+	CalendarAccountKey string `json:"calendarAccountKey" bson:"calendarAccountKey"`
+	CalendarId         string `json:"calendarId" bson:"calendarId"`
+}
+
+// A reservation of a resource for the scheduled time of an event
+type ResourceBooking struct {
+	Id         primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	ResourceId primitive.ObjectID `json:"resourceId" bson:"resourceId"`
+	EventId    primitive.ObjectID `json:"eventId" bson:"eventId"`
+	StartDate  primitive.DateTime `json:"startDate" bson:"startDate"`
+	EndDate    primitive.DateTime `json:"endDate" bson:"endDate"`
+}
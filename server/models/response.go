@@ -8,6 +8,44 @@ type EventResponse struct {
 
 	UserId   string    `json:"userId" bson:"userId"`
 	Response *Response `json:"response" bson:"response"`
+
+	// IP address the response was last submitted from
+	SourceIp string `json:"-" bson:"sourceIp,omitempty"`
+
+	// Hashes of where guests last submitted from, used to flag likely duplicate
+	// guests to the organizer
+	Fingerprint *Fingerprint `json:"-" bson:"fingerprint,omitempty"`
+
+	// Timezone offset of the client the response was last submitted from, in
+	// minutes (same as JS getTimezoneOffset())
+	TimezoneOffset *int `json:"-" bson:"timezoneOffset,omitempty"`
+}
+
+// A sign that a response might be low effort or accidental
+type QualityFlag string
+
+const (
+	EVERYTHING_SELECTED QualityFlag = "everythingSelected"
+	NOTHING_SELECTED    QualityFlag = "nothingSelected"
+
+	// Submitted within seconds of loading the event
+	SUBMITTED_QUICKLY QualityFlag = "submittedQuickly"
+)
+
+// A change in a respondent's timezone between their profile (or previous
+// submission) and the client they submitted from, e.g. because of travel or
+// DST. Offsets are in minutes, same as JS getTimezoneOffset()
+type TimezoneShift struct {
+	PreviousOffset int `json:"previousOffset" bson:"previousOffset"`
+	CurrentOffset  int `json:"currentOffset" bson:"currentOffset"`
+}
+
+// Keyed hashes of a guest's submission context. The hashes are specific to
+// the event, so they can't be used to link guests across events
+type Fingerprint struct {
+	Ip      string `bson:"ip"`      // Exact IP address
+	Network string `bson:"network"` // IPv4 /24 or IPv6 /48 the address is in
+	Device  string `bson:"device"`  // User agent and accepted languages
 }
 
 // A response object containing an array of times that the given user is available
@@ -20,6 +58,21 @@ type Response struct {
 	UserId primitive.ObjectID `json:"userId" bson:"userId,omitempty"`
 	User   *User              `json:"user" bson:",omitempty"`
 
+	// Answers to the event's questions, mapping question id to the answer values
+	Answers map[string][]string `json:"answers" bson:"answers,omitempty"`
+
+	// Values of the event's respondent fields, mapping field id to the value
+	Fields map[string]string `json:"fields" bson:"fields,omitempty"`
+
+	// Set when the response was submitted from a different timezone than
+	// expected, so the availability might be shifted, until the respondent
+	// confirms it
+	TimezoneShift *TimezoneShift `json:"timezoneShift" bson:"timezoneShift,omitempty"`
+
+	// Signs that the response might be low effort or accidental, only shown to
+	// the organizer
+	QualityFlags []QualityFlag `json:"qualityFlags" bson:"qualityFlags,omitempty"`
+
 	// Availability
 	Availability []primitive.DateTime `json:"availability" bson:"availability"`
 	IfNeeded     []primitive.DateTime `json:"ifNeeded" bson:"ifNeeded"`
@@ -31,4 +84,13 @@ type Response struct {
 	UseCalendarAvailability *bool                `json:"useCalendarAvailability" bson:"useCalendarAvailability,omitempty"`
 	EnabledCalendars        *map[string][]string `json:"enabledCalendars" bson:"enabledCalendars,omitempty"` // Maps email to an array of sub calendar ids
 	CalendarOptions         *CalendarOptions     `json:"calendarOptions" bson:"calendarOptions,omitempty"`
+
+	// Delegate that submitted the response on behalf of the respondent
+	SubmittedBy *ResponseDelegate `json:"submittedBy" bson:"submittedBy,omitempty"`
+}
+
+type ResponseDelegate struct {
+	UserId      primitive.ObjectID `json:"userId" bson:"userId"`
+	Name        string             `json:"name" bson:"name"`
+	SubmittedAt primitive.DateTime `json:"submittedAt" bson:"submittedAt"`
 }
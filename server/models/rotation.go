@@ -0,0 +1,39 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// A recurring meeting across timezones whose time rotates between candidate
+// times, so that the inconvenient hours are shared fairly across regions
+type Rotation struct {
+	Regions []RotationRegion `json:"regions" bson:"regions"`
+
+	// Times of day the meeting can start at, in UTC, e.g. "15:00"
+	CandidateTimes  []string `json:"candidateTimes" bson:"candidateTimes"`
+	DurationMinutes int      `json:"durationMinutes" bson:"durationMinutes"`
+
+	// Date of the first occurrence, and the number of days between occurrences
+	StartDate    primitive.DateTime `json:"startDate" bson:"startDate"`
+	IntervalDays int                `json:"intervalDays" bson:"intervalDays"`
+
+	// Occurrences that were scheduled so far, oldest first
+	History []RotationOccurrence `json:"history" bson:"history,omitempty"`
+}
+
+type RotationRegion struct {
+	Name     string `json:"name" bson:"name"`
+	Timezone string `json:"timezone" bson:"timezone"` // e.g. "Asia/Tokyo"
+
+	// Local hours that are convenient for the region, defaults to 09:00-17:00
+	WorkingHours *WorkingHours `json:"workingHours" bson:"workingHours,omitempty"`
+
+	// Relative importance of the region, e.g. its number of attendees. Defaults to 1
+	Weight float64 `json:"weight" bson:"weight,omitempty"`
+}
+
+type RotationOccurrence struct {
+	StartDate primitive.DateTime `json:"startDate" bson:"startDate"`
+	EndDate   primitive.DateTime `json:"endDate" bson:"endDate"`
+
+	// Inconvenience of the occurrence for each region, keyed by region name
+	Inconvenience map[string]float64 `json:"inconvenience" bson:"inconvenience"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type ScheduledTaskStatus string
+
+const (
+	SCHEDULED_TASK_PENDING ScheduledTaskStatus = "pending"
+	SCHEDULED_TASK_DONE    ScheduledTaskStatus = "done"
+	SCHEDULED_TASK_FAILED  ScheduledTaskStatus = "failed"
+)
+
+// An HTTP request to make at a later time, for instances that run the task
+// queue in mongo instead of Google Cloud Tasks
+type ScheduledTask struct {
+	Id      primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	Url     string             `json:"url" bson:"url"`
+	Method  string             `json:"method" bson:"method"`
+	Headers map[string]string  `json:"-" bson:"headers,omitempty"`
+	Body    []byte             `json:"-" bson:"body,omitempty"`
+
+	ScheduleTime primitive.DateTime  `json:"scheduleTime" bson:"scheduleTime"`
+	Status       ScheduledTaskStatus `json:"status" bson:"status"`
+	Attempts     int                 `json:"attempts" bson:"attempts"`
+	LastError    string              `json:"lastError,omitempty" bson:"lastError,omitempty"`
+
+	// Region of the server that created the task, which runs it unless it's
+	// overdue. Empty for tasks any region runs right away
+	Region string `json:"region,omitempty" bson:"region,omitempty"`
+
+	CreatedAt  primitive.DateTime  `json:"createdAt" bson:"createdAt"`
+	FinishedAt *primitive.DateTime `json:"finishedAt" bson:"finishedAt,omitempty"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Links a Slack user to a Timeful user
+type SlackAccount struct {
+	Id          primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	SlackUserId string             `json:"slackUserId" bson:"slackUserId,omitempty"`
+	SlackTeamId string             `json:"slackTeamId" bson:"slackTeamId,omitempty"`
+	UserId      primitive.ObjectID `json:"userId" bson:"userId,omitempty"`
+	LinkedAt    primitive.DateTime `json:"linkedAt" bson:"linkedAt,omitempty"`
+}
+
+// One-time code used to link a Slack user to a Timeful user. The code is shown
+// to the slack user in the App Home and redeemed by the signed in Timeful user
+type SlackLinkCode struct {
+	Id          primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	Code        string             `json:"code" bson:"code,omitempty"`
+	SlackUserId string             `json:"slackUserId" bson:"slackUserId,omitempty"`
+	SlackTeamId string             `json:"slackTeamId" bson:"slackTeamId,omitempty"`
+	ExpiresAt   primitive.DateTime `json:"expiresAt" bson:"expiresAt,omitempty"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Anonymous report a self-hosted instance that opted in to telemetry sends
+// once a day. Counts are rounded down to a power of ten, e.g. "100+"
+type TelemetryReport struct {
+	Id primitive.ObjectID `json:"-" bson:"_id,omitempty"`
+
+	// Hash that identifies the instance without revealing anything about it
+	InstanceId string `json:"instanceId" bson:"instanceId"`
+
+	Version   string `json:"version" bson:"version"`
+	GoVersion string `json:"goVersion" bson:"goVersion"`
+	Os        string `json:"os" bson:"os"`
+	Arch      string `json:"arch" bson:"arch"`
+
+	Users            string `json:"users" bson:"users"`
+	Events           string `json:"events" bson:"events"`
+	EventsLast30Days string `json:"eventsLast30Days" bson:"eventsLast30Days"`
+	Responses        string `json:"responses" bson:"responses"`
+
+	// Which optional features are enabled
+	Features map[string]bool `json:"features" bson:"features"`
+
+	// Day the report is for, set by the collector. Instances report at most
+	// once per day
+	Date       string             `json:"-" bson:"date"`
+	ReceivedAt primitive.DateTime `json:"-" bson:"receivedAt"`
+}
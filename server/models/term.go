@@ -0,0 +1,50 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// An academic term, where students' weekly availability is collected once
+// and used to assign them to class sections
+type Term struct {
+	Id      primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	OwnerId primitive.ObjectID `json:"ownerId" bson:"ownerId"`
+	Name    string             `json:"name" bson:"name"`
+
+	// First and last day of classes
+	StartDate primitive.DateTime `json:"startDate" bson:"startDate"`
+	EndDate   primitive.DateTime `json:"endDate" bson:"endDate"`
+
+	// IANA timezone the section times are in, e.g. "America/Los_Angeles"
+	Timezone string `json:"timezone" bson:"timezone"`
+
+	// Days of the week event used to collect students' availability
+	EventId primitive.ObjectID `json:"eventId" bson:"eventId"`
+
+	Sections []Section `json:"sections" bson:"sections"`
+
+	// Mapping from student id (the event response's user id) to section id
+	Assignments map[string]string   `json:"assignments" bson:"assignments,omitempty"`
+	AssignedAt  *primitive.DateTime `json:"assignedAt" bson:"assignedAt,omitempty"`
+
+	IsDeleted *bool `json:"isDeleted,omitempty" bson:"isDeleted,omitempty"`
+}
+
+// A class section that meets at the same times every week
+type Section struct {
+	Id   string `json:"id" bson:"id"`
+	Name string `json:"name" bson:"name"`
+
+	// Maximum number of students, 0 for unlimited
+	Capacity int `json:"capacity" bson:"capacity,omitempty"`
+
+	Meetings []WeeklySlot `json:"meetings" bson:"meetings"`
+}
+
+// A time slot that repeats weekly
+type WeeklySlot struct {
+	// Day of the week, 0 is Sunday
+	DayOfWeek int `json:"dayOfWeek" bson:"dayOfWeek"`
+
+	// Start and end times in hours (e.g. 13.5 is 1:30pm)
+	StartTime float32 `json:"startTime" bson:"startTime"`
+	EndTime   float32 `json:"endTime" bson:"endTime"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Number of times something metered (e.g. API requests) happened in a window
+// of time, to enforce the quotas of the user's plan
+type UsageCounter struct {
+	// "<metric>:<user id>:<unix start of the window>"
+	Id    string `json:"_id" bson:"_id"`
+	Count int    `json:"count" bson:"count"`
+
+	// When the window ends, after which the counter can be deleted
+	ExpiresAt primitive.DateTime `json:"expiresAt" bson:"expiresAt"`
+}
@@ -34,7 +34,43 @@ type User struct {
 	// Stripe customer ID
 	StripeCustomerId *string `json:"stripeCustomerId" bson:"stripeCustomerId,omitempty"`
 	IsPremium        *bool   `json:"isPremium" bson:"isPremium,omitempty"`
-	NumEventsCreated int     `json:"numEventsCreated" bson:"numEventsCreated,omitempty"`
+
+	// Stripe Connect account used to collect payments for sign up slots
+	StripeAccountId      *string `json:"-" bson:"stripeAccountId,omitempty"`
+	StripeAccountEnabled *bool   `json:"stripeAccountEnabled" bson:"stripeAccountEnabled,omitempty"`
+	StripePayoutsEnabled *bool   `json:"stripePayoutsEnabled" bson:"stripePayoutsEnabled,omitempty"`
+	NumEventsCreated     int     `json:"numEventsCreated" bson:"numEventsCreated,omitempty"`
+
+	// Who can find mutual free time with the user
+	FindTimeSettings *FindTimeSettings `json:"-" bson:"findTimeSettings,omitempty"`
+
+	// Secret in the URL of the user's calendar subscription feed
+	CalendarFeedToken string `json:"-" bson:"calendarFeedToken,omitempty"`
+
+	// Notion database the user's events are synced to
+	NotionConnection *NotionConnection `json:"notionConnection" bson:"notionConnection,omitempty"`
+
+	// Latest version of each legal document the user accepted
+	LegalAcceptances []LegalAcceptance `json:"legalAcceptances" bson:"legalAcceptances,omitempty"`
+
+	// External accounts the user can be identified by, besides their calendar accounts
+	Identities []Identity `json:"identities" bson:"identities,omitempty"`
+}
+
+// Who can find mutual free time with a user, and how much of their calendar
+// it's based on. Only free times are shared, never what the user is busy with
+type FindTimeSettings struct {
+	// Emails of the users that can find time with the user
+	AllowedEmails []string `json:"allowedEmails" bson:"allowedEmails,omitempty"`
+
+	// Whether members of the user's organizations can find time with them too
+	AllowOrganizationMembers bool `json:"allowOrganizationMembers" bson:"allowOrganizationMembers,omitempty"`
+
+	// Whether to only offer times within the user's working hours
+	WorkingHoursOnly bool `json:"workingHoursOnly" bson:"workingHoursOnly,omitempty"`
+
+	// How many days ahead others can look
+	MaxDays int `json:"maxDays" bson:"maxDays,omitempty"`
 }
 
 // Declare the possible types of TokenOrigin
@@ -0,0 +1,23 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// A signed in browser, stored server side so it can be listed and revoked.
+// The session cookie only holds the signed id
+type UserSession struct {
+	Id     string `json:"_id" bson:"_id"`
+	UserId string `json:"-" bson:"userId,omitempty"`
+
+	// Encoded values of the session
+	Data []byte `json:"-" bson:"data"`
+
+	UserAgent string `json:"userAgent" bson:"userAgent,omitempty"`
+	Ip        string `json:"ip" bson:"ip,omitempty"`
+
+	CreatedAt  primitive.DateTime `json:"createdAt" bson:"createdAt"`
+	LastSeenAt primitive.DateTime `json:"lastSeenAt" bson:"lastSeenAt"`
+	ExpiresAt  primitive.DateTime `json:"expiresAt" bson:"expiresAt"`
+
+	// Whether it's the session of the request
+	Current bool `json:"current" bson:"-"`
+}
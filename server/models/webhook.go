@@ -0,0 +1,66 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type WebhookEventType string
+
+const (
+	WEBHOOK_RESPONSE_CREATED WebhookEventType = "response.created"
+	WEBHOOK_RESPONSE_UPDATED WebhookEventType = "response.updated"
+	WEBHOOK_EVENT_FINALIZED  WebhookEventType = "event.finalized"
+)
+
+var WebhookEventTypes = []WebhookEventType{WEBHOOK_RESPONSE_CREATED, WEBHOOK_RESPONSE_UPDATED, WEBHOOK_EVENT_FINALIZED}
+
+// A URL that the owner's events are posted to when they change, e.g. to drive
+// Zapier or n8n automations
+type Webhook struct {
+	Id      primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	OwnerId primitive.ObjectID `json:"ownerId" bson:"ownerId"`
+
+	// Only events of this event are posted if set, otherwise events of all the
+	// events the owner owns
+	EventId *primitive.ObjectID `json:"eventId" bson:"eventId,omitempty"`
+
+	Url        string             `json:"url" bson:"url"`
+	EventTypes []WebhookEventType `json:"eventTypes" bson:"eventTypes"`
+	Enabled    bool               `json:"enabled" bson:"enabled"`
+
+	// Key the deliveries are signed with, only shown when the webhook is created
+	Secret string `json:"-" bson:"secret"`
+
+	CreatedAt primitive.DateTime `json:"createdAt" bson:"createdAt"`
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WEBHOOK_DELIVERY_PENDING   WebhookDeliveryStatus = "pending"
+	WEBHOOK_DELIVERY_DELIVERED WebhookDeliveryStatus = "delivered"
+	WEBHOOK_DELIVERY_FAILED    WebhookDeliveryStatus = "failed"
+)
+
+// An event posted to a webhook, retried with backoff until the target accepts it
+type WebhookDelivery struct {
+	Id        primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	WebhookId primitive.ObjectID `json:"webhookId" bson:"webhookId"`
+	Type      WebhookEventType   `json:"type" bson:"type"`
+
+	// JSON body that is posted
+	Payload string `json:"payload" bson:"payload"`
+
+	Status        WebhookDeliveryStatus `json:"status" bson:"status"`
+	Attempts      int                   `json:"attempts" bson:"attempts"`
+	NextAttemptAt primitive.DateTime    `json:"nextAttemptAt" bson:"nextAttemptAt"`
+
+	// Result of the last attempt, and how long the target took to respond
+	LastStatusCode int    `json:"lastStatusCode,omitempty" bson:"lastStatusCode,omitempty"`
+	LastError      string `json:"lastError,omitempty" bson:"lastError,omitempty"`
+	LastLatencyMs  int64  `json:"lastLatencyMs,omitempty" bson:"lastLatencyMs,omitempty"`
+
+	// Delivery this one replays, if the owner replayed it
+	ReplayOf *primitive.ObjectID `json:"replayOf,omitempty" bson:"replayOf,omitempty"`
+
+	CreatedAt   primitive.DateTime  `json:"createdAt" bson:"createdAt"`
+	DeliveredAt *primitive.DateTime `json:"deliveredAt" bson:"deliveredAt,omitempty"`
+}
@@ -0,0 +1,327 @@
+/* The /admin group contains the routes for the admins of this Timeful instance, set by ADMIN_EMAILS */
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/middleware"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/erasure"
+	"schej.it/server/services/faults"
+	"schej.it/server/services/legal"
+	"schej.it/server/services/sessionstore"
+	"schej.it/server/services/status"
+	"schej.it/server/services/updates"
+	"schej.it/server/utils"
+)
+
+func InitAdmin(router *gin.RouterGroup) {
+	adminRouter := router.Group("/admin")
+	adminRouter.Use(middleware.SessionRequired(), middleware.AuthRequired(), middleware.AdminRequired())
+
+	adminRouter.POST("/incidents", createIncident)
+	adminRouter.POST("/incidents/:incidentId/updates", updateIncident)
+	adminRouter.DELETE("/incidents/:incidentId", deleteIncident)
+
+	adminRouter.GET("/legal", getLegalDocumentVersions)
+	adminRouter.POST("/legal", publishLegalDocument)
+
+	adminRouter.GET("/data-subjects", getDataSubjectRecords)
+	adminRouter.POST("/data-subjects/erase", eraseDataSubjectRecords)
+	adminRouter.POST("/data-subjects/verify-report", verifyErasureReport)
+
+	adminRouter.GET("/version", getVersionStatus)
+
+	adminRouter.GET("/event-aliases", getEventAliases)
+	adminRouter.PUT("/event-aliases/:alias", setEventAlias)
+	adminRouter.DELETE("/event-aliases/:alias", deleteEventAlias)
+
+	if faults.Enabled() {
+		adminRouter.GET("/faults", getFaults)
+		adminRouter.PUT("/faults/:target", setFault)
+		adminRouter.DELETE("/faults/:target", clearFault)
+	}
+}
+
+// @Summary Posts an incident to the status page
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param payload body object{title=string,impact=models.IncidentImpact,components=[]string,message=string} true "Title, impact, affected components, and the first update"
+// @Success 201 {object} models.Incident
+// @Router /admin/incidents [post]
+func createIncident(c *gin.Context) {
+	payload := struct {
+		Title      string                `json:"title" binding:"required"`
+		Impact     models.IncidentImpact `json:"impact" binding:"required"`
+		Components []string              `json:"components" binding:"required"`
+		Message    string                `json:"message" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	if payload.Impact != models.INCIDENT_DEGRADED && payload.Impact != models.INCIDENT_OUTAGE {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "impact must be degraded or outage"})
+		return
+	}
+	for _, component := range payload.Components {
+		if !utils.Contains(status.Components, status.Component(component)) {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: "unknown component " + component})
+			return
+		}
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	incident := models.Incident{
+		Title:      strings.TrimSpace(payload.Title),
+		Impact:     payload.Impact,
+		Status:     models.INCIDENT_INVESTIGATING,
+		Components: payload.Components,
+		Updates:    []models.IncidentUpdate{{Status: models.INCIDENT_INVESTIGATING, Message: payload.Message, CreatedAt: now}},
+		CreatedAt:  now,
+	}
+	db.InsertIncident(&incident)
+
+	c.JSON(http.StatusCreated, incident)
+}
+
+// @Summary Posts an update to an incident
+// @Description Also changes the status of the incident. The incident stops affecting the health of its components once it's resolved
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param incidentId path string true "Incident ID"
+// @Param payload body object{status=models.IncidentStatus,message=string,impact=models.IncidentImpact} true "New status, message, and optionally a new impact"
+// @Success 200 {object} models.Incident
+// @Router /admin/incidents/{incidentId}/updates [post]
+func updateIncident(c *gin.Context) {
+	payload := struct {
+		Status  models.IncidentStatus  `json:"status" binding:"required"`
+		Message string                 `json:"message" binding:"required"`
+		Impact  *models.IncidentImpact `json:"impact"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	switch payload.Status {
+	case models.INCIDENT_INVESTIGATING, models.INCIDENT_IDENTIFIED, models.INCIDENT_MONITORING, models.INCIDENT_RESOLVED:
+	default:
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "invalid status " + string(payload.Status)})
+		return
+	}
+	if payload.Impact != nil && *payload.Impact != models.INCIDENT_DEGRADED && *payload.Impact != models.INCIDENT_OUTAGE {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "impact must be degraded or outage"})
+		return
+	}
+	incident := db.GetIncidentById(c.Param("incidentId"))
+	if incident == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.IncidentNotFound})
+		return
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	incident.Status = payload.Status
+	incident.Updates = append(incident.Updates, models.IncidentUpdate{Status: payload.Status, Message: payload.Message, CreatedAt: now})
+	if payload.Impact != nil {
+		incident.Impact = *payload.Impact
+	}
+	if payload.Status == models.INCIDENT_RESOLVED {
+		incident.ResolvedAt = &now
+	} else {
+		incident.ResolvedAt = nil
+	}
+	db.UpdateIncident(incident)
+
+	c.JSON(http.StatusOK, incident)
+}
+
+// @Summary Deletes an incident
+// @Description For incidents posted by mistake. Real incidents should be resolved instead, so they stay on the status page for a while
+// @Tags admin
+// @Param incidentId path string true "Incident ID"
+// @Success 200
+// @Router /admin/incidents/{incidentId} [delete]
+func deleteIncident(c *gin.Context) {
+	incident := db.GetIncidentById(c.Param("incidentId"))
+	if incident == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.IncidentNotFound})
+		return
+	}
+
+	db.DeleteIncident(incident.Id)
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Gets every published version of the legal documents
+// @Description Along with how many users accepted each version
+// @Tags admin
+// @Produce json
+// @Success 200 {object} []object{document=models.LegalDocument,numAccepted=int}
+// @Router /admin/legal [get]
+func getLegalDocumentVersions(c *gin.Context) {
+	counts := db.CountLegalAcceptances()
+	versions := make([]gin.H, 0)
+	for _, document := range db.GetLegalDocuments() {
+		versions = append(versions, gin.H{
+			"document":    document,
+			"numAccepted": counts[document.Type][document.Version],
+		})
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// @Summary Publishes a new version of a legal document
+// @Description Users have to accept required versions before they can keep using routes that require signing in. Versions that aren't required (e.g. typo fixes) don't block anyone
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param payload body object{type=models.LegalDocumentType,url=string,summary=string,required=bool} true "Type of the document, where it's published, a summary of what changed, and whether users have to accept it"
+// @Success 201 {object} models.LegalDocument
+// @Router /admin/legal [post]
+func publishLegalDocument(c *gin.Context) {
+	payload := struct {
+		Type     models.LegalDocumentType `json:"type" binding:"required"`
+		Url      string                   `json:"url" binding:"required"`
+		Summary  string                   `json:"summary"`
+		Required bool                     `json:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	if !utils.Contains(models.LegalDocumentTypes, payload.Type) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "unknown document type " + string(payload.Type)})
+		return
+	}
+
+	version := 1
+	for _, latest := range legal.GetLatest(db.GetLegalDocuments()) {
+		if latest.Type == payload.Type {
+			version = latest.Version + 1
+		}
+	}
+	document := models.LegalDocument{
+		Type:        payload.Type,
+		Version:     version,
+		Url:         strings.TrimSpace(payload.Url),
+		Summary:     strings.TrimSpace(payload.Summary),
+		Required:    payload.Required,
+		PublishedAt: primitive.NewDateTimeFromTime(time.Now()),
+	}
+	db.InsertLegalDocument(&document)
+
+	c.JSON(http.StatusCreated, document)
+}
+
+// @Summary Gets every record stored about the person with an email address
+// @Description For right of access requests. Searches accounts, responses, events, invites, contacts, consents, payments, folders, notification opt outs, and LMS identities
+// @Tags admin
+// @Produce json
+// @Param email query string true "Email address of the data subject"
+// @Param format query string false "Set to download to get the records as a file"
+// @Success 200 {object} db.DataSubjectRecords
+// @Router /admin/data-subjects [get]
+func getDataSubjectRecords(c *gin.Context) {
+	query := struct {
+		Email  string `form:"email" binding:"required"`
+		Format string `form:"format"`
+	}{}
+	if err := c.BindQuery(&query); err != nil {
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(query.Email))
+
+	records := db.FindDataSubjectRecords(email)
+
+	if query.Format == "download" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"data-subject-%s.json\"", erasure.HashEmail(email)[:12]))
+	}
+	c.JSON(http.StatusOK, records)
+}
+
+// @Summary Erases every record stored about the person with an email address
+// @Description For right to erasure requests. The email has to be entered twice to confirm. Payments are anonymized rather than deleted since they're needed for accounting, and notification opt outs are kept so the address isn't contacted again. Returns a signed report of what was erased, which identifies the subject only by a hash of their email
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param payload body object{email=string,confirmEmail=string,reference=string} true "Email address of the data subject, the same address again, and an optional reference to the request (e.g. a ticket number)"
+// @Success 200 {object} erasure.Report
+// @Router /admin/data-subjects/erase [post]
+func eraseDataSubjectRecords(c *gin.Context) {
+	payload := struct {
+		Email        string `json:"email" binding:"required"`
+		ConfirmEmail string `json:"confirmEmail" binding:"required"`
+		Reference    string `json:"reference"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(payload.Email))
+	if email != strings.ToLower(strings.TrimSpace(payload.ConfirmEmail)) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "confirmEmail doesn't match email"})
+		return
+	}
+	admin := utils.GetAuthUser(c)
+	if admin.Email == email {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "admins can't erase their own records"})
+		return
+	}
+
+	records := db.FindDataSubjectRecords(email)
+	if records.User != nil {
+		if err := sessionstore.Default.RevokeAll(records.User.Id.Hex(), ""); err != nil {
+			logger.StdErr.Panicln(err)
+		}
+	}
+	deleted, anonymized := db.EraseDataSubjectRecords(email, records)
+
+	retained := make(map[string]string)
+	if len(records.Payments) > 0 {
+		retained["payments"] = "Anonymized and kept for accounting"
+	}
+	if len(records.NotificationOptOuts) > 0 {
+		retained["notificationOptOuts"] = "Kept so the address isn't contacted again"
+	}
+	if len(records.EmailSuppressions) > 0 {
+		retained["emailSuppressions"] = "Kept so the address isn't contacted again"
+	}
+	report := erasure.NewReport(email, admin.Email, strings.TrimSpace(payload.Reference), deleted, anonymized, retained, time.Now())
+
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary Verifies the signature of an erasure report
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param payload body erasure.Report true "The report"
+// @Success 200 {object} object{valid=bool}
+// @Router /admin/data-subjects/verify-report [post]
+func verifyErasureReport(c *gin.Context) {
+	var report erasure.Report
+	if err := c.BindJSON(&report); err != nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"valid": erasure.Verify(report)})
+}
+
+// @Summary Gets the running version and whether a newer release is available
+// @Description Newer releases are only checked for if UPDATE_CHECK_ENABLED is true, otherwise only the running version is returned
+// @Tags admin
+// @Produce json
+// @Success 200 {object} updates.Status
+// @Router /admin/version [get]
+func getVersionStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, updates.GetStatus(time.Now()))
+}
@@ -0,0 +1,86 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/aliases"
+)
+
+// @Summary Gets the aliases of an event
+// @Tags admin
+// @Produce json
+// @Param eventId query string true "ID or short ID of the event"
+// @Success 200 {object} []models.EventAlias
+// @Router /admin/event-aliases [get]
+func getEventAliases(c *gin.Context) {
+	event := db.GetEventByEitherId(c.Query("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+
+	c.JSON(http.StatusOK, db.GetEventAliases(event.Id))
+}
+
+// @Summary Makes an alias open an event
+// @Description Links with the alias in place of the event's id (e.g. /e/<alias>, or the short id of a legacy schej.it link) open the event, and the pages permanently redirect to its canonical link. Existing aliases are pointed at the new event, but aliases can't shadow the id or short id of another event
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param alias path string true "Alias, up to 64 letters, digits or underscores"
+// @Param payload body object{eventId=string} true "ID or short ID of the event"
+// @Success 200 {object} models.EventAlias
+// @Router /admin/event-aliases/{alias} [put]
+func setEventAlias(c *gin.Context) {
+	payload := struct {
+		EventId string `json:"eventId" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	alias := c.Param("alias")
+	if err := aliases.ValidateAlias(alias); err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	event := db.GetEventByEitherId(payload.EventId)
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+	if existing := db.GetEventByEitherId(alias); existing != nil && db.GetEventAlias(alias) == nil {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.EventAliasExists})
+		return
+	}
+
+	eventAlias := models.EventAlias{
+		Alias:     alias,
+		EventId:   event.Id,
+		CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+	}
+	db.UpsertEventAlias(&eventAlias)
+
+	c.JSON(http.StatusOK, eventAlias)
+}
+
+// @Summary Deletes an event alias
+// @Tags admin
+// @Param alias path string true "Alias"
+// @Success 200
+// @Router /admin/event-aliases/{alias} [delete]
+func deleteEventAlias(c *gin.Context) {
+	if !db.DeleteEventAlias(c.Param("alias")) {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventAliasNotFound})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,61 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/responses"
+	"schej.it/server/services/faults"
+)
+
+// How long a fault lasts unless a duration is given
+const defaultFaultDuration = 10 * time.Minute
+
+// The fault injection routes are only registered outside of release mode, and
+// are deliberately left out of the API docs
+
+// Gets the faults injected into this server
+func getFaults(c *gin.Context) {
+	c.JSON(http.StatusOK, faults.List(time.Now()))
+}
+
+// Injects latency and errors into the calls this server makes to the target,
+// i.e. mongo, google or stripe. Replaces the target's previous fault
+func setFault(c *gin.Context) {
+	payload := struct {
+		LatencyMs       int     `json:"latencyMs"`
+		ErrorRate       float64 `json:"errorRate"`
+		DurationSeconds int     `json:"durationSeconds"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+
+	duration := time.Duration(payload.DurationSeconds) * time.Second
+	if payload.DurationSeconds <= 0 {
+		duration = defaultFaultDuration
+	}
+	if duration > faults.MAX_DURATION {
+		duration = faults.MAX_DURATION
+	}
+
+	fault := faults.Fault{
+		Target:    faults.Target(c.Param("target")),
+		LatencyMs: payload.LatencyMs,
+		ErrorRate: payload.ErrorRate,
+		ExpiresAt: time.Now().Add(duration),
+	}
+	if err := faults.Set(fault); err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, fault)
+}
+
+// Stops injecting faults into the calls to the target
+func clearFault(c *gin.Context) {
+	faults.Clear(faults.Target(c.Param("target")))
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -13,6 +13,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"schej.it/server/db"
 	"schej.it/server/models"
+	"schej.it/server/services/privacy"
 	"schej.it/server/slackbot"
 )
 
@@ -61,11 +62,12 @@ func scannedPoster(c *gin.Context) {
 	}
 
 	if payload.Location != nil {
+		location := privacy.GetAnalyticsLocation(*payload.Location)
 		slackbot.SendTextMessage(
 			fmt.Sprintf(":face_with_monocle: Poster was scanned :face_with_monocle:\n*Location:* %s, %s, %s\n*URL:* %s",
-				payload.Location.City,
-				payload.Location.State,
-				payload.Location.CountryCode,
+				location.City,
+				location.State,
+				location.CountryCode,
 				payload.Url,
 			),
 		)
@@ -100,7 +102,7 @@ func upgradeDialogViewed(c *gin.Context) {
 	if user == nil {
 		message = fmt.Sprintf(":eyes: %s viewed the upgrade dialog (%s), type: %s", payload.UserId, payload.Price, payload.Type)
 	} else {
-		message = fmt.Sprintf(":eyes: %s %s (%s) viewed the upgrade dialog (%s), type: %s", user.FirstName, user.LastName, user.Email, payload.Price, payload.Type)
+		message = fmt.Sprintf(":eyes: %s viewed the upgrade dialog (%s), type: %s", privacy.GetAnalyticsName(user), payload.Price, payload.Type)
 	}
 
 	slackbot.SendTextMessageWithType(
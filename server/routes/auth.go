@@ -161,7 +161,11 @@ func signInHelper(c *gin.Context, token auth.TokenResponse, tokenOrigin models.T
 	calendarAccountKey := utils.GetCalendarAccountKey(email, calendarType)
 
 	var userId primitive.ObjectID
-	findResult := db.UsersCollection.FindOne(context.Background(), bson.M{"email": email})
+	// Users can also sign in with the email of an identity they linked
+	findResult := db.UsersCollection.FindOne(context.Background(), bson.M{"$or": bson.A{
+		bson.M{"email": email},
+		bson.M{"identities.email": email},
+	}})
 	// If user doesn't exist, create a new user
 	if findResult.Err() == mongo.ErrNoDocuments {
 		// Fetch subcalendars
@@ -183,6 +187,12 @@ func signInHelper(c *gin.Context, token auth.TokenResponse, tokenOrigin models.T
 
 		userId = res.InsertedID.(primitive.ObjectID)
 
+		// Join the organizations that invited the user, and the one that
+		// claimed their email domain
+		userData.Id = userId
+		joinInvitedOrgs(&userData)
+		joinDomainOrg(&userData)
+
 		// slackbot.SendTextMessage(fmt.Sprintf(":wave: %s %s (%s) has joined schej.it!", firstName, lastName, email))
 	} else {
 		var user models.User
@@ -191,6 +201,12 @@ func signInHelper(c *gin.Context, token auth.TokenResponse, tokenOrigin models.T
 		}
 		userId = user.Id
 
+		// Signed in with a linked identity, keep the account's own email
+		if user.Email != email {
+			userData.Email = ""
+			userData.PrimaryAccountKey = nil
+		}
+
 		// If user has custom name, do not override first name and last name
 		if user.HasCustomName != nil && *user.HasCustomName {
 			userData.FirstName = ""
@@ -231,6 +247,7 @@ func signInHelper(c *gin.Context, token auth.TokenResponse, tokenOrigin models.T
 	// Set session variables
 	session := sessions.Default(c)
 	session.Set("userId", userId.Hex())
+	session.Set("authProvider", string(calendarType))
 	session.Save()
 
 	userData.Id = userId
@@ -245,9 +262,10 @@ func signInHelper(c *gin.Context, token auth.TokenResponse, tokenOrigin models.T
 // @Success 200
 // @Router /auth/sign-out [post]
 func signOut(c *gin.Context) {
-	// Delete session
+	// Delete session, so it can't be used again
 	session := sessions.Default(c)
-	session.Delete("userId")
+	session.Clear()
+	session.Options(sessions.Options{Path: "/", MaxAge: -1})
 	session.Save()
 
 	c.JSON(http.StatusOK, gin.H{})
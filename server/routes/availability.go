@@ -0,0 +1,60 @@
+/* The /availability group contains the read-only aggregated availability of events shared with a token */
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/responses"
+	"schej.it/server/services/aggregates"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+func InitAvailability(router *gin.RouterGroup) {
+	router.GET("/availability/:token", getSharedAvailability)
+}
+
+// @Summary Gets the aggregated availability of the event shared with the token
+// @Description Only includes how many respondents are available (or available if needed) at each time increment of the grid, never who they are. Doesn't require signing in. If groupBy is set, groups also has a heatmap for each value of that respondent field, e.g. mentors and mentees separately
+// @Tags availability
+// @Produce json
+// @Param token path string true "Token of the read-only link"
+// @Param groupBy query string false "ID or label of the respondent field to group by"
+// @Param values query string false "Comma separated values of the groupBy field to return the groups of, e.g. mentor,mentee"
+// @Success 200 {object} object{name=string,daysOnly=bool,timezone=string,timeIncrement=int,numRespondents=int,scheduledEvent=models.CalendarEvent,heatmap=[]scheduling.HeatmapCell,groups=[]heatmapGroup}
+// @Router /availability/{token} [get]
+func getSharedAvailability(c *gin.Context) {
+	event := db.GetEventByAvailabilityShareToken(c.Param("token"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+
+	groupBy, values, ok := getHeatmapGrouping(c, event)
+	if !ok {
+		return
+	}
+
+	aggregate := aggregates.Get(event, time.Now())
+	result := gin.H{
+		"name":           event.Name,
+		"type":           event.Type,
+		"daysOnly":       utils.Coalesce(event.DaysOnly),
+		"timezone":       event.Timezone,
+		"timeIncrement":  int(scheduling.GetTimeIncrement(event).Minutes()),
+		"numRespondents": aggregate.NumRespondents,
+		"scheduledEvent": event.ScheduledEvent,
+		"heatmap":        aggregate.Heatmap,
+	}
+	if groupBy != nil {
+		respondents := scheduling.GetRespondents(db.GetEventResponses(event.Id.Hex()))
+		result["groups"] = getHeatmapGroups(event, respondents, groupBy, values)
+	}
+
+	c.Header("Cache-Control", "public, max-age=60")
+	c.JSON(http.StatusOK, result)
+}
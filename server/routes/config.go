@@ -0,0 +1,24 @@
+/* The /config group contains the runtime config of the instance for the frontend */
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/services/runtimeconfig"
+)
+
+func InitConfig(router *gin.RouterGroup) {
+	router.GET("/config", getRuntimeConfig)
+}
+
+// @Summary Gets the runtime config of the instance
+// @Description Returns the public config the frontend needs (OAuth client ids, the Stripe publishable key, enabled features, and the instance's branding), so that it doesn't have to be rebuilt for each environment. Doesn't require signing in
+// @Tags config
+// @Produce json
+// @Success 200 {object} runtimeconfig.Config
+// @Router /config [get]
+func getRuntimeConfig(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, runtimeconfig.Get())
+}
@@ -7,7 +7,6 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -17,21 +16,53 @@ import (
 	"schej.it/server/middleware"
 	"schej.it/server/models"
 	"schej.it/server/responses"
+	"schej.it/server/services/activity"
+	"schej.it/server/services/aggregates"
 	"schej.it/server/services/calendar"
+	"schej.it/server/services/contacts"
+	"schej.it/server/services/duplicates"
+	"schej.it/server/services/forms"
 	"schej.it/server/services/gcloud"
 	"schej.it/server/services/listmonk"
+	"schej.it/server/services/notifications"
+	"schej.it/server/services/organizations"
+	"schej.it/server/services/payments"
+	"schej.it/server/services/policies"
+	"schej.it/server/services/privacy"
+	"schej.it/server/services/quality"
+	"schej.it/server/services/realtime"
+	"schej.it/server/services/submissions"
 	"schej.it/server/utils"
 )
 
 func InitEvents(router *gin.RouterGroup) {
+	// Respondent names of events of organizations that require SSO are only
+	// shown to members who signed in with it, so every event route checks it
 	eventRouter := router.Group("/events")
-
-	eventRouter.POST("", createEvent)
-	eventRouter.PUT("/:eventId", editEvent)
+	eventRouter.Use(middleware.RequireOrgSso())
+
+	eventRouter.POST("", middleware.EnforcePolicies(policies.CREATE_EVENT), createEvent)
+	eventRouter.POST("/parse", parseEvent)
+	eventRouter.PUT("/:eventId", middleware.EnforcePolicies(policies.UPDATE_EVENT), editEvent)
+	eventRouter.POST("/drafts", middleware.AuthRequired(), middleware.EnforcePolicies(policies.CREATE_EVENT), createDraft)
+	eventRouter.PATCH("/:eventId/draft", middleware.AuthRequired(), middleware.EnforcePolicies(policies.UPDATE_EVENT), autosaveDraft)
+	eventRouter.POST("/:eventId/publish", middleware.AuthRequired(), publishDraft)
 	eventRouter.GET("/:eventId", getEvent)
 	eventRouter.GET("/:eventId/responses", getResponses)
-	eventRouter.POST("/:eventId/response", updateEventResponse)
+	eventRouter.GET("/:eventId/subscribe", subscribeToEvent)
+	eventRouter.POST("/:eventId/presence", setEventPresence)
+	eventRouter.POST("/:eventId/selection", shareEventSelection)
+	eventRouter.GET("/:eventId/ics", getEventIcs)
+	eventRouter.POST("/:eventId/ics-import", importEventIcs)
+	eventRouter.GET("/:eventId/availability-shares", middleware.AuthRequired(), getAvailabilityShares)
+	eventRouter.POST("/:eventId/availability-shares", middleware.AuthRequired(), createAvailabilityShare)
+	eventRouter.DELETE("/:eventId/availability-shares/:token", middleware.AuthRequired(), deleteAvailabilityShare)
+	eventRouter.PUT("/:eventId/issue", middleware.AuthRequired(), linkEventIssue)
+	eventRouter.DELETE("/:eventId/issue", middleware.AuthRequired(), unlinkEventIssue)
+	eventRouter.POST("/:eventId/response", middleware.EnforcePolicies(policies.RESPOND), updateEventResponse)
 	eventRouter.DELETE("/:eventId/response", deleteEventResponse)
+	eventRouter.POST("/:eventId/response/confirm-timezone", confirmResponseTimezone)
+	eventRouter.POST("/:eventId/response/delegate", middleware.AuthRequired(), respondOnBehalf)
 	eventRouter.POST("/:eventId/rename-user", renameUser)
 	eventRouter.POST("/:eventId/responded", userResponded)
 	eventRouter.POST("/:eventId/decline", middleware.AuthRequired(), declineInvite)
@@ -39,13 +70,82 @@ func InitEvents(router *gin.RouterGroup) {
 	eventRouter.DELETE("/:eventId", middleware.AuthRequired(), deleteEvent)
 	eventRouter.POST("/:eventId/duplicate", middleware.AuthRequired(), duplicateEvent)
 	eventRouter.POST("/:eventId/archive", middleware.AuthRequired(), archiveEvent)
+	eventRouter.POST("/:eventId/assistant", middleware.AuthRequired(), getSchedulingSuggestions)
+	eventRouter.POST("/:eventId/finalize", middleware.AuthRequired(), finalizeEvent)
+	eventRouter.POST("/:eventId/finalize/review", middleware.AuthRequired(), reviewFinalization)
+	eventRouter.DELETE("/:eventId/finalize/pending", middleware.AuthRequired(), cancelPendingFinalization)
+	eventRouter.POST("/:eventId/sessions", middleware.AuthRequired(), finalizeSessions)
+	eventRouter.GET("/:eventId/cost-estimate", middleware.AuthRequired(), getMeetingCostEstimate)
+	eventRouter.POST("/:eventId/cancel-attendance", cancelAttendance)
+	eventRouter.GET("/:eventId/reschedule-proposals", middleware.AuthRequired(), getRescheduleProposals)
+	eventRouter.GET("/:eventId/holidays", getEventHolidays)
+	eventRouter.GET("/:eventId/dst-audit", middleware.AuthRequired(), getDstAudit)
+	eventRouter.PUT("/:eventId/shifts", middleware.AuthRequired(), setShifts)
+	eventRouter.POST("/:eventId/shifts/assign", middleware.AuthRequired(), assignShifts)
+	eventRouter.PUT("/:eventId/shifts/:shiftId/assignees", middleware.AuthRequired(), setShiftAssignees)
+	eventRouter.POST("/:eventId/shifts/publish", middleware.AuthRequired(), publishShiftSchedule)
+	eventRouter.GET("/:eventId/shifts/schedule", getPublishedShiftSchedule)
+	eventRouter.PUT("/:eventId/panel", middleware.AuthRequired(), setPanelPools)
+	eventRouter.GET("/:eventId/panel/slots", middleware.AuthRequired(), getPanelSlots)
+	eventRouter.POST("/:eventId/breakouts", middleware.AuthRequired(), splitIntoBreakouts)
+	eventRouter.PUT("/:eventId/rotation", middleware.AuthRequired(), setRotation)
+	eventRouter.GET("/:eventId/rotation/plan", middleware.AuthRequired(), getRotationPlan)
+	eventRouter.POST("/:eventId/rotation/next", middleware.AuthRequired(), scheduleNextRotation)
+	eventRouter.PUT("/:eventId/quorum", middleware.AuthRequired(), setQuorum)
+	eventRouter.GET("/:eventId/quorum/slots", middleware.AuthRequired(), getQuorumSlots)
+	eventRouter.PUT("/:eventId/next-phase", middleware.AuthRequired(), setNextPhase)
+	eventRouter.POST("/:eventId/next-phase/advance", middleware.AuthRequired(), advanceToNextPhase)
+	eventRouter.PUT("/:eventId/respondent-fields", middleware.AuthRequired(), setRespondentFields)
+	eventRouter.GET("/:eventId/heatmap", middleware.AuthRequired(), getGroupedHeatmap)
+	eventRouter.GET("/:eventId/public-aggregate", getPublicAggregate)
+	eventRouter.POST("/:eventId/checkout", middleware.EnforcePolicies(policies.RESPOND), createSignUpCheckout)
+	eventRouter.GET("/:eventId/payments", middleware.AuthRequired(), getEventPayments)
+	eventRouter.POST("/:eventId/no-show", middleware.AuthRequired(), chargeNoShowFee)
+	eventRouter.GET("/:eventId/responses/export", middleware.AuthRequired(), exportEventResponses)
+	eventRouter.GET("/:eventId/print", middleware.AuthRequired(), getEventPrintView)
+	eventRouter.POST("/:eventId/responses/remove", middleware.AuthRequired(), removeResponses)
+	eventRouter.GET("/:eventId/responses/duplicates", middleware.AuthRequired(), getDuplicateResponses)
+	eventRouter.GET("/:eventId/blocked-respondents", middleware.AuthRequired(), getBlockedRespondents)
+	eventRouter.DELETE("/:eventId/blocked-respondents/:blockId", middleware.AuthRequired(), unblockRespondent)
+	eventRouter.GET("/:eventId/consents", middleware.AuthRequired(), getConsentReport)
+	eventRouter.POST("/:eventId/remind", middleware.AuthRequired(), remindInvitees)
+	eventRouter.GET("/:eventId/summary", middleware.AuthRequired(), getEventSummary)
+	eventRouter.PUT("/:eventId/classroom-roster", middleware.AuthRequired(), importClassroomRoster)
+	eventRouter.DELETE("/:eventId/classroom-roster", middleware.AuthRequired(), removeClassroomRoster)
+	eventRouter.POST("/:eventId/email-poll", middleware.AuthRequired(), createEmailPoll)
+	eventRouter.POST("/:eventId/invites", middleware.AuthRequired(), createInviteBatch)
+	eventRouter.GET("/:eventId/invites", middleware.AuthRequired(), getInviteBatches)
+	eventRouter.GET("/:eventId/invites/:batchId", middleware.AuthRequired(), getInviteBatch)
+	eventRouter.POST("/:eventId/invites/:batchId/cancel", middleware.AuthRequired(), cancelInviteBatch)
+	eventRouter.POST("/:eventId/broadcasts", middleware.AuthRequired(), createBroadcast)
+	eventRouter.GET("/:eventId/broadcasts", middleware.AuthRequired(), getBroadcasts)
+	eventRouter.DELETE("/:eventId/broadcasts/:broadcastId", middleware.AuthRequired(), cancelBroadcast)
+	eventRouter.GET("/:eventId/automations", middleware.AuthRequired(), getAutomationRules)
+	eventRouter.POST("/:eventId/automations", middleware.AuthRequired(), createAutomationRule)
+	eventRouter.PATCH("/:eventId/automations/:ruleId", middleware.AuthRequired(), updateAutomationRule)
+	eventRouter.DELETE("/:eventId/automations/:ruleId", middleware.AuthRequired(), deleteAutomationRule)
+	eventRouter.GET("/:eventId/automations/:ruleId/runs", middleware.AuthRequired(), getAutomationRuns)
+	eventRouter.PUT("/:eventId/co-organizers", middleware.AuthRequired(), middleware.EnforcePolicies(policies.UPDATE_EVENT), setCoOrganizers)
+	eventRouter.PUT("/:eventId/finalization-approvers", middleware.AuthRequired(), middleware.EnforcePolicies(policies.UPDATE_EVENT), setFinalizationApprovers)
+	eventRouter.GET("/:eventId/activity", middleware.AuthRequired(), getEventActivity)
+	eventRouter.GET("/:eventId/notification-rule", middleware.AuthRequired(), getNotificationRule)
+	eventRouter.PUT("/:eventId/notification-rule", middleware.AuthRequired(), setNotificationRule)
+
+	// Routes opened from personal links in emails, which are authorized by the
+	// key in the link and don't show other respondents, so recipients aren't
+	// asked to sign in first
+	linkRouter := router.Group("/events")
+	linkRouter.GET("/:eventId/sessions/:sessionId/confirm", confirmSession)
+	linkRouter.GET("/:eventId/remind/opt-out", optOutOfNudges)
+	linkRouter.GET("/:eventId/email-poll/:key", respondToEmailPollLink)
+	linkRouter.GET("/:eventId/invite/:key", openInvite)
 }
 
 // @Summary Creates a new event
 // @Tags events
 // @Accept json
 // @Produce json
-// @Param payload body object{name=string,duration=float32,dates=[]string,type=models.EventType,isSignUpForm=bool,signUpBlocks=[]models.SignUpBlock,notificationsEnabled=bool,blindAvailabilityEnabled=bool,daysOnly=bool,remindees=[]string,sendEmailAfterXResponses=int,when2meetHref=string,timeIncrement=int,attendees=[]string} true "Object containing info about the event to create"
+// @Param payload body object{name=string,duration=float32,dates=[]string,type=models.EventType,isSignUpForm=bool,signUpBlocks=[]models.SignUpBlock,paymentCurrency=string,bookingPolicy=models.BookingPolicy,questions=[]models.Question,consentDocument=models.ConsentDocument,notificationsEnabled=bool,blindAvailabilityEnabled=bool,daysOnly=bool,remindees=[]string,sendEmailAfterXResponses=int,when2meetHref=string,timeIncrement=int,holidaySettings=models.HolidaySettings,weightByAttendance=bool,organizationId=string,tags=[]string,timezone=string,workingHours=models.WorkingHours,reminderCadence=models.ReminderCadence,branding=models.Branding,attendees=[]string,contactGroupIds=[]string} true "Object containing info about the event to create"
 // @Success 201 {object} object{eventId=string}
 // @Router /events [post]
 func createEvent(c *gin.Context) {
@@ -64,8 +164,13 @@ func createEvent(c *gin.Context) {
 		CreatorPosthogId *string `json:"creatorPosthogId"`
 
 		// Only for sign up form events
-		IsSignUpForm *bool                 `json:"isSignUpForm"`
-		SignUpBlocks *[]models.SignUpBlock `json:"signUpBlocks"`
+		IsSignUpForm    *bool                 `json:"isSignUpForm"`
+		SignUpBlocks    *[]models.SignUpBlock `json:"signUpBlocks"`
+		PaymentCurrency *string               `json:"paymentCurrency"`
+		BookingPolicy   *models.BookingPolicy `json:"bookingPolicy"`
+
+		Questions       *[]models.Question      `json:"questions"`
+		ConsentDocument *models.ConsentDocument `json:"consentDocument"`
 
 		// Only for events (not groups)
 		StartOnMonday            *bool    `json:"startOnMonday"`
@@ -78,18 +183,65 @@ func createEvent(c *gin.Context) {
 		CollectEmails            *bool    `json:"collectEmails"`
 		TimeIncrement            *int     `json:"timeIncrement"`
 
+		// Who can see the responses, defaults to public
+		PrivacyLevel *models.PrivacyLevel `json:"privacyLevel"`
+
+		HolidaySettings    *models.HolidaySettings `json:"holidaySettings"`
+		WeightByAttendance *bool                   `json:"weightByAttendance"`
+
+		// Organization to create the event in, defaults to the user's first organization
+		OrganizationId *primitive.ObjectID `json:"organizationId"`
+		Tags           []string            `json:"tags"`
+
+		// Display settings, defaulting to the organization's
+		Timezone        *string                 `json:"timezone"`
+		WorkingHours    *models.WorkingHours    `json:"workingHours"`
+		ReminderCadence *models.ReminderCadence `json:"reminderCadence"`
+		Branding        *models.Branding        `json:"branding"`
+
 		// Only for availability groups
 		Attendees []string `json:"attendees"`
+
+		// Contact groups whose members are added to the remindees (or attendees, for groups)
+		ContactGroupIds []primitive.ObjectID `json:"contactGroupIds"`
+
+		// Only lets organizers and the authorized emails respond. Members of the
+		// contact groups are authorized if preauthorizeContactGroups is set
+		InviteOnly                *bool    `json:"inviteOnly"`
+		AuthorizedEmails          []string `json:"authorizedEmails"`
+		PreauthorizeContactGroups *bool    `json:"preauthorizeContactGroups"`
+
+		// When the poll closes, defaults to EVENT_EXPIRY_DAYS after the last date
+		ExpiresAt *primitive.DateTime `json:"expiresAt"`
+
+		// Redirect URL or thank-you message shown after submitting
+		PostSubmission *models.PostSubmission `json:"postSubmission"`
 	}{}
 	if err := c.Bind(&payload); err != nil {
 		fmt.Println(err)
 		return
 	}
-	session := sessions.Default(c)
+	if err := forms.ValidateQuestions(utils.Coalesce(payload.Questions)); err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+	if payload.PrivacyLevel != nil && !privacy.IsValidLevel(*payload.PrivacyLevel) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "privacyLevel must be public, counts, invitees or organizers"})
+		return
+	}
+	consentDocument, err := forms.UpdateConsentDocument(nil, payload.ConsentDocument, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+	tags, err := organizations.NormalizeTags(payload.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
 
 	// If user logged in, set owner id to their user id, otherwise set owner id to nil
-	userIdInterface := session.Get("userId")
-	userId, signedIn := userIdInterface.(string)
+	userId, signedIn := utils.GetUserId(c)
 	var user *models.User
 	var ownerId primitive.ObjectID
 	if signedIn {
@@ -99,6 +251,25 @@ func createEvent(c *gin.Context) {
 		ownerId = primitive.NilObjectID
 	}
 
+	authorizedEmails, err := contacts.NormalizeEmails(payload.AuthorizedEmails)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	// Invite the members of the given contact groups
+	if signedIn && len(payload.ContactGroupIds) > 0 {
+		groups := db.GetContactGroupsByIds(ownerId, getOrgIds(ownerId), payload.ContactGroupIds)
+		if payload.Type == models.GROUP {
+			payload.Attendees = contacts.MergeGroups(payload.Attendees, groups)
+		} else {
+			payload.Remindees = contacts.MergeGroups(payload.Remindees, groups)
+		}
+		if utils.Coalesce(payload.PreauthorizeContactGroups) {
+			authorizedEmails = contacts.MergeGroups(authorizedEmails, groups)
+		}
+	}
+
 	// Construct event object
 	numResponses := 0
 	event := models.Event{
@@ -112,19 +283,50 @@ func createEvent(c *gin.Context) {
 		Times:                    payload.Times,
 		IsSignUpForm:             payload.IsSignUpForm,
 		SignUpBlocks:             payload.SignUpBlocks,
+		PaymentCurrency:          payload.PaymentCurrency,
+		BookingPolicy:            payload.BookingPolicy,
+		Questions:                payload.Questions,
+		ConsentDocument:          consentDocument,
+		InviteOnly:               payload.InviteOnly,
+		AuthorizedEmails:         authorizedEmails,
 		StartOnMonday:            payload.StartOnMonday,
 		NotificationsEnabled:     payload.NotificationsEnabled,
 		BlindAvailabilityEnabled: payload.BlindAvailabilityEnabled,
+		PrivacyLevel:             payload.PrivacyLevel,
 		DaysOnly:                 payload.DaysOnly,
 		SendEmailAfterXResponses: payload.SendEmailAfterXResponses,
 		When2meetHref:            payload.When2meetHref,
 		CollectEmails:            payload.CollectEmails,
 		TimeIncrement:            payload.TimeIncrement,
+		HolidaySettings:          payload.HolidaySettings,
+		WeightByAttendance:       payload.WeightByAttendance,
+		Timezone:                 payload.Timezone,
+		WorkingHours:             payload.WorkingHours,
+		ReminderCadence:          payload.ReminderCadence,
+		Branding:                 payload.Branding,
+		ExpiresAt:                payload.ExpiresAt,
+		PostSubmission:           payload.PostSubmission,
 		Type:                     payload.Type,
+		Tags:                     tags,
 		SignUpResponses:          make(map[string]*models.SignUpResponse),
 		NumResponses:             &numResponses,
 	}
 
+	// Apply the defaults and locked settings of the user's organization
+	if signedIn {
+		org, ok := getEventOrg(c, user, payload.OrganizationId)
+		if !ok {
+			return
+		}
+		if org != nil {
+			event.OrganizationId = org.Id
+			organizations.ApplySettings(&event, org.Settings, true)
+		}
+	}
+	if !validatePostSubmission(c, &event) {
+		return
+	}
+
 	// Generate short id
 	shortId := db.GenerateShortEventId(event.Id)
 	event.ShortId = &shortId
@@ -142,7 +344,7 @@ func createEvent(c *gin.Context) {
 		// Schedule email reminders for each of the remindees' emails
 		remindees := make([]models.Remindee, 0)
 		for _, email := range payload.Remindees {
-			taskIds := gcloud.CreateEmailTask(email, ownerName, payload.Name, event.GetId())
+			taskIds := gcloud.CreateEmailTask(email, ownerName, payload.Name, event.GetId(), event.ReminderCadence)
 			remindees = append(remindees, models.Remindee{
 				Email:     email,
 				TaskIds:   taskIds,
@@ -213,6 +415,12 @@ func createEvent(c *gin.Context) {
 		logger.StdErr.Panicln(err)
 	}
 	insertedId := result.InsertedID.(primitive.ObjectID).Hex()
+	recordActivity(&event, models.ACTIVITY_CREATED, user, "", nil)
+
+	// Add the invitees to the owner's contact book
+	if signedIn {
+		recordInvitees(ownerId, append(append(make([]string, 0), payload.Remindees...), payload.Attendees...))
+	}
 
 	// Send slackbot message
 	// var creator string
@@ -232,7 +440,7 @@ func createEvent(c *gin.Context) {
 // @Tags events
 // @Produce json
 // @Param eventId path string true "Event ID"
-// @Param payload body object{name=string,description=string,duration=float32,dates=[]string,type=models.EventType,signUpBlocks=[]models.SignUpBlock,notificationsEnabled=bool,blindAvailabilityEnabled=bool,daysOnly=bool,remindees=[]string,sendEmailAfterXResponses=int,attendees=[]string} true "Object containing info about the event to update"
+// @Param payload body object{name=string,description=string,duration=float32,dates=[]string,type=models.EventType,signUpBlocks=[]models.SignUpBlock,paymentCurrency=string,bookingPolicy=models.BookingPolicy,questions=[]models.Question,consentDocument=models.ConsentDocument,notificationsEnabled=bool,blindAvailabilityEnabled=bool,daysOnly=bool,remindees=[]string,sendEmailAfterXResponses=int,holidaySettings=models.HolidaySettings,weightByAttendance=bool,tags=[]string,timezone=string,workingHours=models.WorkingHours,reminderCadence=models.ReminderCadence,branding=models.Branding,attendees=[]string} true "Object containing info about the event to update"
 // @Success 200
 // @Router /events/{eventId} [put]
 func editEvent(c *gin.Context) {
@@ -251,7 +459,15 @@ func editEvent(c *gin.Context) {
 		Description *string `json:"description"`
 
 		// Only for sign up form events
-		SignUpBlocks *[]models.SignUpBlock `json:"signUpBlocks"`
+		SignUpBlocks    *[]models.SignUpBlock `json:"signUpBlocks"`
+		PaymentCurrency *string               `json:"paymentCurrency"`
+		BookingPolicy   *models.BookingPolicy `json:"bookingPolicy"`
+
+		Questions       *[]models.Question      `json:"questions"`
+		ConsentDocument *models.ConsentDocument `json:"consentDocument"`
+
+		InviteOnly       *bool    `json:"inviteOnly"`
+		AuthorizedEmails []string `json:"authorizedEmails"`
 
 		// Only for events (not groups)
 		StartOnMonday            *bool    `json:"startOnMonday"`
@@ -262,6 +478,25 @@ func editEvent(c *gin.Context) {
 		SendEmailAfterXResponses *int     `json:"sendEmailAfterXResponses"`
 		CollectEmails            *bool    `json:"collectEmails"`
 
+		// Who can see the responses, defaults to public
+		PrivacyLevel *models.PrivacyLevel `json:"privacyLevel"`
+
+		HolidaySettings    *models.HolidaySettings `json:"holidaySettings"`
+		WeightByAttendance *bool                   `json:"weightByAttendance"`
+		Tags               []string                `json:"tags"`
+
+		// Display settings, defaulting to the organization's
+		Timezone        *string                 `json:"timezone"`
+		WorkingHours    *models.WorkingHours    `json:"workingHours"`
+		ReminderCadence *models.ReminderCadence `json:"reminderCadence"`
+		Branding        *models.Branding        `json:"branding"`
+
+		// When the poll closes, defaults to EVENT_EXPIRY_DAYS after the last date
+		ExpiresAt *primitive.DateTime `json:"expiresAt"`
+
+		// Redirect URL or thank-you message shown after submitting
+		PostSubmission *models.PostSubmission `json:"postSubmission"`
+
 		// Only for availability groups
 		Attendees []string `json:"attendees"`
 	}{}
@@ -269,6 +504,14 @@ func editEvent(c *gin.Context) {
 		logger.StdErr.Println(err)
 		return
 	}
+	if err := forms.ValidateQuestions(utils.Coalesce(payload.Questions)); err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+	if payload.PrivacyLevel != nil && !privacy.IsValidLevel(*payload.PrivacyLevel) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "privacyLevel must be public, counts, invitees or organizers"})
+		return
+	}
 
 	eventId := c.Param("eventId")
 	event := db.GetEventByEitherId(eventId)
@@ -278,9 +521,7 @@ func editEvent(c *gin.Context) {
 	}
 
 	// If user logged in, set owner id to their user id, otherwise set owner id to nil
-	session := sessions.Default(c)
-	userIdInterface := session.Get("userId")
-	userId, signedIn := userIdInterface.(string)
+	userId, signedIn := utils.GetUserId(c)
 	var ownerId primitive.ObjectID
 	if signedIn {
 		ownerId = utils.StringToObjectID(userId)
@@ -290,13 +531,14 @@ func editEvent(c *gin.Context) {
 
 	// If event has an owner id, check if user has permissions to edit event
 	if event.OwnerId != primitive.NilObjectID {
-		if event.OwnerId != ownerId {
+		if !canEditEvent(event, ownerId) {
 			c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotEventOwner})
 			return
 		}
 	}
 
 	// Update event
+	before := *event
 	event.Name = payload.Name
 	event.Description = payload.Description
 	event.Duration = payload.Duration
@@ -304,20 +546,57 @@ func editEvent(c *gin.Context) {
 	event.Times = payload.Times
 	event.HasSpecificTimes = payload.HasSpecificTimes
 	event.SignUpBlocks = payload.SignUpBlocks
+	event.PaymentCurrency = payload.PaymentCurrency
+	event.BookingPolicy = payload.BookingPolicy
+	event.Questions = payload.Questions
+	consentDocument, err := forms.UpdateConsentDocument(event.ConsentDocument, payload.ConsentDocument, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+	event.ConsentDocument = consentDocument
+	authorizedEmails, err := contacts.NormalizeEmails(payload.AuthorizedEmails)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+	event.InviteOnly = payload.InviteOnly
+	event.AuthorizedEmails = authorizedEmails
+	tags, err := organizations.NormalizeTags(payload.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+	event.Tags = tags
 	event.StartOnMonday = payload.StartOnMonday
 	event.NotificationsEnabled = payload.NotificationsEnabled
 	event.BlindAvailabilityEnabled = payload.BlindAvailabilityEnabled
+	event.PrivacyLevel = payload.PrivacyLevel
 	event.DaysOnly = payload.DaysOnly
 	event.SendEmailAfterXResponses = payload.SendEmailAfterXResponses
 	event.CollectEmails = payload.CollectEmails
+	event.HolidaySettings = payload.HolidaySettings
+	event.WeightByAttendance = payload.WeightByAttendance
+	event.Timezone = payload.Timezone
+	event.WorkingHours = payload.WorkingHours
+	event.ReminderCadence = payload.ReminderCadence
+	event.Branding = payload.Branding
+	event.ExpiresAt = payload.ExpiresAt
+	event.PostSubmission = payload.PostSubmission
 	event.Type = payload.Type
 
+	// Locked settings of the event's organization can't be changed
+	if !event.OrganizationId.IsZero() {
+		if org := db.GetOrganizationById(event.OrganizationId.Hex()); org != nil {
+			organizations.ApplySettings(event, org.Settings, false)
+		}
+	}
+	if !validatePostSubmission(c, event) {
+		return
+	}
+
 	// Update remindees
 	if event.Type == models.DOW || event.Type == models.SPECIFIC_DATES {
-		origRemindees := utils.Coalesce(event.Remindees)
-		updatedRemindees := make([]models.Remindee, 0)
-		added, removed, kept := utils.FindAddedRemovedKept(payload.Remindees, utils.Map(origRemindees, func(r models.Remindee) string { return r.Email }))
-
 		// Determine owner name
 		var ownerName string
 		if event.OwnerId == primitive.NilObjectID {
@@ -327,32 +606,14 @@ func editEvent(c *gin.Context) {
 			ownerName = owner.FirstName
 		}
 
-		for _, keptEmail := range kept {
-			updatedRemindees = append(updatedRemindees, origRemindees[keptEmail.Index])
-		}
-
-		for _, addedEmail := range added {
-			// Schedule email tasks
-			taskIds := gcloud.CreateEmailTask(addedEmail.Value, ownerName, event.Name, event.GetId())
-			updatedRemindees = append(updatedRemindees, models.Remindee{
-				Email:     addedEmail.Value,
-				TaskIds:   taskIds,
-				Responded: utils.FalsePtr(),
-			})
-		}
-
-		for _, removedEmail := range removed {
-			// Delete email tasks
-			for _, taskId := range origRemindees[removedEmail.Index].TaskIds {
-				gcloud.DeleteEmailTask(taskId)
-			}
-		}
-
-		event.Remindees = &updatedRemindees
+		added := notifications.UpdateRemindees(event, payload.Remindees, ownerName)
+		recordInvitees(event.OwnerId, added)
 	}
 
-	// Update attendees
-	if event.Type == models.GROUP {
+	// Update attendees, which are only invited once drafts are published
+	if event.Type == models.GROUP && utils.Coalesce(event.IsDraft) {
+		event.DraftAttendees = payload.Attendees
+	} else if event.Type == models.GROUP {
 		origAttendees := db.GetAttendees(event.Id.Hex())
 		added, removed, kept := utils.FindAddedRemovedKept(payload.Attendees, utils.Map(origAttendees, func(a models.Attendee) string { return a.Email }))
 
@@ -414,6 +675,7 @@ func editEvent(c *gin.Context) {
 		// Send group update emails
 		if len(added) > 0 {
 			emails := utils.Map(added, func(a utils.ElementWithIndex[string]) string { return a.Value })
+			recordInvitees(event.OwnerId, emails)
 			addedAttendeeEmailId := 11
 
 			for _, keptEmail := range kept {
@@ -428,7 +690,7 @@ func editEvent(c *gin.Context) {
 	}
 
 	// Update event object
-	_, err := db.EventsCollection.UpdateOne(
+	_, err = db.EventsCollection.UpdateOne(
 		context.Background(),
 		bson.M{
 			"_id": event.Id,
@@ -441,15 +703,26 @@ func editEvent(c *gin.Context) {
 	if err != nil {
 		logger.StdErr.Panicln(err)
 	}
+	aggregates.Invalidate(event.Id)
+
+	if changed := activity.GetChangedSettings(&before, event); len(changed) > 0 {
+		var editor *models.User
+		if signedIn {
+			editor = db.GetUserById(userId)
+		}
+		recordActivity(event, models.ACTIVITY_SETTINGS_CHANGED, editor, "", changed)
+	}
 
 	c.Status(http.StatusOK)
 }
 
 // @Summary Gets an event based on its id
+// @Description Polls past their expiry respond with 410 and the finalized time instead, unless the current user is an organizer. The responses are shaped by the event's privacy level: anonymous responses only have the availability and are keyed by an anonymous id, and hidden ones are left out, except the current user's own
 // @Tags events
 // @Produce json
 // @Param eventId path string true "Event ID"
 // @Success 200 {object} models.Event
+// @Failure 401 {object} object{error=string,sso=policies.SsoHint} "The organization requires signing in with its identity provider"
 // @Router /events/{eventId} [get]
 func getEvent(c *gin.Context) {
 	eventId := c.Param("eventId")
@@ -459,6 +732,9 @@ func getEvent(c *gin.Context) {
 		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
 		return
 	}
+	if !checkNotExpired(c, event) {
+		return
+	}
 	eventResponses := db.GetEventResponses(event.Id.Hex())
 
 	// Convert to old format for backward compatibility
@@ -467,6 +743,14 @@ func getEvent(c *gin.Context) {
 	// Convert responses to map format for JSON response
 	responsesMap := getResponsesMap(eventResponses)
 
+	// Answers to questions and respondent fields are only visible to the
+	// organizers and the respondent
+	sessionUserId, _ := utils.GetUserId(c)
+	isOrganizer := canManageEvent(event, sessionUserId)
+	canViewAnswers := func(userId string) bool {
+		return isOrganizer || sessionUserId == userId
+	}
+
 	// Populate user fields
 	for userId, response := range responsesMap {
 		user := db.GetUserById(userId)
@@ -487,6 +771,13 @@ func getEvent(c *gin.Context) {
 			response.User = user
 			response.User.CalendarAccounts = nil
 		}
+		if !canViewAnswers(userId) {
+			response.Answers = nil
+			response.Fields = nil
+		}
+		if !isOrganizer {
+			response.QualityFlags = nil
+		}
 		responsesMap[userId] = response
 
 		// Remove availability arrays
@@ -514,19 +805,30 @@ func getEvent(c *gin.Context) {
 		} else {
 			response.User = user
 		}
+		if !canViewAnswers(userId) {
+			response.Answers = nil
+			response.Fields = nil
+		}
 		event.SignUpResponses[userId] = response
 	}
 
-	if event.Type == models.GROUP {
+	// Leave out what the event's privacy level hides from the current user
+	visibility := getResponseVisibility(c, event)
+	event.ResponsesMap = shapeResponses(event, event.ResponsesMap, sessionUserId, visibility, anonymizeResponse)
+	event.SignUpResponses = shapeResponses(event, event.SignUpResponses, sessionUserId, visibility, anonymizeSignUpResponse)
+
+	if event.Type == models.GROUP && visibility == privacy.VISIBLE_ALL {
 		attendees := db.GetAttendees(event.Id.Hex())
 		event.Attendees = &attendees
 	}
+	event.SubmissionToken = submissions.NewToken(event.Id, time.Now())
 
 	// Create a copy of the event with responses in map format
 	c.JSON(http.StatusOK, event)
 }
 
 // @Summary Gets responses for an event, filtering availability to be within the date ranges
+// @Description Shaped by the event's privacy level like GET /events/{eventId}
 // @Tags events
 // @Produce json
 // @Param eventId path string true "Event ID"
@@ -555,6 +857,8 @@ func getResponses(c *gin.Context) {
 	// Convert to map format and filter availability
 	eventResponses := db.GetEventResponses(event.Id.Hex())
 	responsesMap := getResponsesMap(eventResponses)
+	sessionUserId, _ := utils.GetUserId(c)
+	isOrganizer := canManageEvent(event, sessionUserId)
 
 	// Filter availability slice based on timeMin and timeMax
 	for userId, response := range responsesMap {
@@ -581,10 +885,15 @@ func getResponses(c *gin.Context) {
 			}
 		}
 		response.ManualAvailability = &subsetManualAvailability
+		if !isOrganizer {
+			response.Answers = nil
+			response.Fields = nil
+			response.QualityFlags = nil
+		}
 		responsesMap[userId] = response
 	}
 
-	c.JSON(http.StatusOK, responsesMap)
+	c.JSON(http.StatusOK, shapeResponses(event, responsesMap, sessionUserId, getResponseVisibility(c, event), anonymizeResponse))
 }
 
 // @Summary Updates the current user's availability
@@ -592,8 +901,8 @@ func getResponses(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param eventId path string true "Event ID"
-// @Param payload body object{availability=[]string,ifNeeded=[]string,guest=bool,name=string,useCalendarAvailability=bool,enabledCalendars=map[string][]string,manualAvailability=map[string][]string,calendarOptions=models.CalendarOptions,signUpBlockIds=[]string} true "Object containing info about the event response to update"
-// @Success 200
+// @Param payload body object{availability=[]string,ifNeeded=[]string,guest=bool,name=string,useCalendarAvailability=bool,enabledCalendars=map[string][]string,manualAvailability=map[string][]string,calendarOptions=models.CalendarOptions,signUpBlockIds=[]string,answers=map[string][]string,fields=map[string]string,consentVersion=int,ltiToken=string,submissionToken=string} true "Object containing info about the event response to update"
+// @Success 200 {object} object{submissionToken=string,timezoneShift=models.TimezoneShift,postSubmission=models.PostSubmission,responseToken=string}
 // @Router /events/{eventId}/response [post]
 func updateEventResponse(c *gin.Context) {
 	payload := struct {
@@ -613,21 +922,88 @@ func updateEventResponse(c *gin.Context) {
 
 		// Sign up form variables
 		SignUpBlockIds []primitive.ObjectID `json:"signUpBlockIds"`
+
+		// Answers to the event's questions
+		Answers map[string][]string `json:"answers"`
+
+		// Values of the event's respondent fields
+		Fields map[string]string `json:"fields"`
+
+		// Version of the event's consent document the respondent agreed to
+		ConsentVersion *int `json:"consentVersion"`
+
+		// Identity of the student, when the event is embedded in an LMS
+		LtiToken string `json:"ltiToken"`
+
+		// Single use token from the event page, required for guests
+		SubmissionToken string `json:"submissionToken"`
+
+		// Client's timezone offset in minutes (same as JS getTimezoneOffset()),
+		// used to flag responses submitted from an unexpected timezone
+		TimezoneOffset *int `json:"timezoneOffset"`
 	}{}
 	if err := c.Bind(&payload); err != nil {
 		return
 	}
-	session := sessions.Default(c)
 	eventId := c.Param("eventId")
 	event := db.GetEventByEitherId(eventId)
 	if event == nil {
 		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
 		return
 	}
+
+	// Students launched from an LMS respond as their LMS identity
+	embed, ok := getLtiEmbed(c, event, payload.LtiToken)
+	if !ok {
+		return
+	}
+	if embed != nil {
+		payload.Guest = utils.TruePtr()
+		payload.Name = embed.Name
+		payload.Email = embed.Email
+	}
+
+	answers, validationErr := forms.Validate(utils.Coalesce(event.Questions), payload.Answers)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: validationErr.Error()})
+		return
+	}
+	fields, validationErr := forms.ValidateFieldValues(event.RespondentFields, payload.Fields)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: validationErr.Error()})
+		return
+	}
+	if !checkNotExpired(c, event) {
+		return
+	}
+	if !checkInviteOnly(c, event, *payload.Guest, embed) {
+		return
+	}
+	if !checkNotBlocked(c, event, *payload.Guest, payload.Email) {
+		return
+	}
+	if !checkConsent(c, event, payload.ConsentVersion) {
+		return
+	}
+	if *payload.Guest && embed == nil && !checkSubmissionToken(c, event, payload.SubmissionToken) {
+		return
+	}
+	// Guests identified by an LMS can't respond twice, so they aren't fingerprinted
+	var fingerprint *models.Fingerprint
+	if *payload.Guest && embed == nil {
+		fingerprint = duplicates.NewFingerprint(event.Id, c.ClientIP(), c.GetHeader("User-Agent"), c.GetHeader("Accept-Language"))
+	}
 	eventResponses := db.GetEventResponses(event.Id.Hex())
 
+	// Guests' IP addresses aren't stored on instances that minimize personal data
+	sourceIp := c.ClientIP()
+	if *payload.Guest {
+		sourceIp = privacy.GetGuestIp(sourceIp)
+	}
+
 	var userIdString string
 	var userHasResponded bool
+	var timezoneShift *models.TimezoneShift
 	if !utils.Coalesce(event.IsSignUpForm) {
 		// Populate response differently if guest vs signed in user
 		var response models.Response
@@ -636,22 +1012,26 @@ func updateEventResponse(c *gin.Context) {
 
 			response = models.Response{
 				Name:         payload.Name,
-				Email:        payload.Email,
+				Email:        privacy.GetGuestEmail(payload.Email),
+				Answers:      answers,
+				Fields:       fields,
 				Availability: payload.Availability,
 				IfNeeded:     payload.IfNeeded,
 			}
 		} else {
-			userIdInterface := session.Get("userId")
-			if userIdInterface == nil {
+			sessionUserId, signedIn := utils.GetUserId(c)
+			if !signedIn {
 				c.JSON(http.StatusUnauthorized, responses.Error{Error: errs.NotSignedIn})
 				c.Abort()
 				return
 			}
-			userIdString = userIdInterface.(string)
+			userIdString = sessionUserId
 			userId := utils.StringToObjectID(userIdString)
 
 			response = models.Response{
 				UserId:                  userId,
+				Answers:                 answers,
+				Fields:                  fields,
 				Availability:            payload.Availability,
 				IfNeeded:                payload.IfNeeded,
 				UseCalendarAvailability: payload.UseCalendarAvailability,
@@ -715,26 +1095,53 @@ func updateEventResponse(c *gin.Context) {
 		idx, _ := findResponse(eventResponses, userIdString)
 		userHasResponded = idx != -1
 
+		// Flag the response if the respondent's timezone changed
+		var expectedOffset *int
+		if *payload.Guest {
+			if userHasResponded {
+				expectedOffset = eventResponses[idx].TimezoneOffset
+			}
+		} else if user := db.GetUserById(userIdString); user != nil {
+			expectedOffset = &user.TimezoneOffset
+		}
+		timezoneShift = getTimezoneShift(expectedOffset, payload.TimezoneOffset)
+		response.TimezoneShift = timezoneShift
+
+		// Flag responses that look low effort for the organizer
+		var submissionDuration *time.Duration
+		if issuedAt, err := submissions.GetIssuedAt(payload.SubmissionToken, event.Id, time.Now()); err == nil {
+			duration := time.Since(issuedAt)
+			submissionDuration = &duration
+		}
+		if flags := quality.Check(event, payload.Availability, payload.IfNeeded, submissionDuration); len(flags) > 0 {
+			response.QualityFlags = flags
+		}
+
 		// Update event responses
 		if userHasResponded {
 			db.EventResponsesCollection.UpdateOne(context.Background(), bson.M{
 				"_id": eventResponses[idx].Id,
 			}, bson.M{
 				"$set": bson.M{
-					"response": &response,
+					"response":       &response,
+					"sourceIp":       sourceIp,
+					"fingerprint":    fingerprint,
+					"timezoneOffset": payload.TimezoneOffset,
 				},
 			})
 		} else {
 			db.EventResponsesCollection.InsertOne(context.Background(), models.EventResponse{
-				UserId:   userIdString,
-				Response: &response,
-				EventId:  event.Id,
+				UserId:         userIdString,
+				Response:       &response,
+				EventId:        event.Id,
+				SourceIp:       sourceIp,
+				Fingerprint:    fingerprint,
+				TimezoneOffset: payload.TimezoneOffset,
 			})
 			*event.NumResponses++
 		}
 	} else {
 		var response models.SignUpResponse
-		var userIdString string
 		// Populate response differently if guest vs signed in user
 		if *payload.Guest {
 			userIdString = payload.Name
@@ -742,23 +1149,37 @@ func updateEventResponse(c *gin.Context) {
 			response = models.SignUpResponse{
 				SignUpBlockIds: payload.SignUpBlockIds,
 				Name:           payload.Name,
-				Email:          payload.Email,
+				Email:          privacy.GetGuestEmail(payload.Email),
+				Answers:        answers,
+				Fields:         fields,
 			}
 		} else {
-			userIdInterface := session.Get("userId")
-			if userIdInterface == nil {
+			sessionUserId, signedIn := utils.GetUserId(c)
+			if !signedIn {
 				c.JSON(http.StatusUnauthorized, responses.Error{Error: errs.NotSignedIn})
 				c.Abort()
 				return
 			}
-			userIdString = userIdInterface.(string)
+			userIdString = sessionUserId
 
 			response = models.SignUpResponse{
 				SignUpBlockIds: payload.SignUpBlockIds,
 				UserId:         utils.StringToObjectID(userIdString),
+				Answers:        answers,
+				Fields:         fields,
 			}
 		}
 
+		// Paid blocks can only be signed up for through checkout
+		paid := db.GetRespondentPayments(event.Id, userIdString, models.PAYMENT_PAID)
+		if len(payments.GetUnpaidBlocks(event, payload.SignUpBlockIds, paid)) > 0 {
+			c.JSON(http.StatusPaymentRequired, responses.Error{Error: errs.PaymentRequired})
+			return
+		}
+
+		response.SourceIp = sourceIp
+		response.Fingerprint = fingerprint
+
 		// Check if user has responded to event before (edit response) or not (new response)
 		_, userHasResponded = event.SignUpResponses[userIdString]
 
@@ -770,7 +1191,7 @@ func updateEventResponse(c *gin.Context) {
 	}
 
 	// Send notification emails
-	if (utils.Coalesce(event.NotificationsEnabled) || event.Type == models.GROUP) && !userHasResponded && userIdString != event.OwnerId.Hex() {
+	if (utils.Coalesce(event.NotificationsEnabled) || event.Type == models.GROUP) && !userHasResponded {
 		// Send email asynchronously
 		go func() {
 			// Recover from panics
@@ -780,11 +1201,6 @@ func updateEventResponse(c *gin.Context) {
 				}
 			}()
 
-			creator := db.GetUserById(event.OwnerId.Hex())
-			if creator == nil {
-				return
-			}
-
 			var respondentName string
 			if *payload.Guest {
 				respondentName = payload.Name
@@ -794,21 +1210,27 @@ func updateEventResponse(c *gin.Context) {
 			}
 
 			if event.Type == models.GROUP {
-				someoneRespondedEmailId := 13
-				listmonk.SendEmail(creator.Email, someoneRespondedEmailId, bson.M{
-					"groupName":      event.Name,
-					"ownerName":      creator.FirstName,
-					"respondentName": respondentName,
-					"groupUrl":       fmt.Sprintf("%s/g/%s", utils.GetBaseUrl(), event.GetId()),
-				})
+				groupUrl := fmt.Sprintf("%s/g/%s", utils.GetBaseUrl(), event.GetId())
+				notifications.NotifyOrganizers(event, models.RESPONSE_ALERT, userIdString, func(organizer *models.User) {
+					someoneRespondedEmailId := 13
+					listmonk.SendEmail(organizer.Email, someoneRespondedEmailId, bson.M{
+						"groupName":      event.Name,
+						"ownerName":      organizer.FirstName,
+						"respondentName": respondentName,
+						"groupUrl":       groupUrl,
+					})
+				}, fmt.Sprintf("%s joined <%s|%s>", respondentName, groupUrl, event.Name))
 			} else {
-				someoneRespondedEmailId := 10
-				listmonk.SendEmail(creator.Email, someoneRespondedEmailId, bson.M{
-					"eventName":      event.Name,
-					"ownerName":      creator.FirstName,
-					"respondentName": respondentName,
-					"eventUrl":       fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId()),
-				})
+				eventUrl := fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId())
+				notifications.NotifyOrganizers(event, models.RESPONSE_ALERT, userIdString, func(organizer *models.User) {
+					someoneRespondedEmailId := 10
+					listmonk.SendEmail(organizer.Email, someoneRespondedEmailId, bson.M{
+						"eventName":      event.Name,
+						"ownerName":      organizer.FirstName,
+						"respondentName": respondentName,
+						"eventUrl":       eventUrl,
+					})
+				}, fmt.Sprintf("%s responded to <%s|%s>", respondentName, eventUrl, event.Name))
 			}
 		}()
 	}
@@ -828,18 +1250,17 @@ func updateEventResponse(c *gin.Context) {
 				}
 			}()
 
-			creator := db.GetUserById(event.OwnerId.Hex())
-			if creator == nil {
-				return
-			}
-
-			sendEmailAfterXResponsesEmailId := 14
-			listmonk.SendEmail(creator.Email, sendEmailAfterXResponsesEmailId, bson.M{
-				"eventName":    event.Name,
-				"ownerName":    creator.FirstName,
-				"eventUrl":     fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId()),
-				"numResponses": len(eventResponses) + 1, // We add 1 because eventResponses is the old event responses before the current user is added
-			})
+			eventUrl := fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId())
+			numResponses := len(eventResponses) + 1 // We add 1 because eventResponses is the old event responses before the current user is added
+			notifications.NotifyOrganizers(event, models.RESPONSE_THRESHOLD_ALERT, "", func(organizer *models.User) {
+				sendEmailAfterXResponsesEmailId := 14
+				listmonk.SendEmail(organizer.Email, sendEmailAfterXResponsesEmailId, bson.M{
+					"eventName":    event.Name,
+					"ownerName":    organizer.FirstName,
+					"eventUrl":     eventUrl,
+					"numResponses": numResponses,
+				})
+			}, fmt.Sprintf("<%s|%s> has %d responses", eventUrl, event.Name, numResponses))
 		}()
 	}
 
@@ -853,7 +1274,38 @@ func updateEventResponse(c *gin.Context) {
 		logger.StdErr.Panicln(err)
 	}
 
-	c.JSON(http.StatusOK, gin.H{})
+	recordConsent(c, event, userIdString, payload.Name, payload.Email)
+
+	if event.Quorum != nil && event.Quorum.MetAt == nil && !utils.Coalesce(event.IsSignUpForm) {
+		go checkQuorum(event)
+	}
+
+	if !userHasResponded {
+		if *payload.Guest {
+			recordActivity(event, models.ACTIVITY_RESPONDED, nil, payload.Name, nil)
+		} else {
+			recordActivity(event, models.ACTIVITY_RESPONDED, db.GetUserById(userIdString), "", nil)
+		}
+	}
+
+	if userHasResponded {
+		publishResponseChange(event, realtime.RESPONSE_UPDATED, userIdString)
+		enqueueResponseWebhook(event, models.WEBHOOK_RESPONSE_UPDATED, userIdString)
+	} else {
+		publishResponseChange(event, realtime.RESPONSE_ADDED, userIdString)
+		enqueueResponseWebhook(event, models.WEBHOOK_RESPONSE_CREATED, userIdString)
+	}
+
+	res := gin.H{
+		"submissionToken": submissions.NewToken(event.Id, time.Now()),
+		"timezoneShift":   timezoneShift,
+		"postSubmission":  event.PostSubmission,
+	}
+	// Only the guest who created the response gets to cancel it later on
+	if *payload.Guest && !userHasResponded {
+		res["responseToken"] = submissions.NewResponseToken(event.Id, payload.Name)
+	}
+	c.JSON(http.StatusOK, res)
 }
 
 // @Summary Delete the current user's availability
@@ -861,19 +1313,19 @@ func updateEventResponse(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param eventId path string true "Event ID"
-// @Param payload body object{userId=string,guest=bool,name=string} true "Object containing info about the event response to delete"
+// @Param payload body object{userId=string,guest=bool,name=string,responseToken=string} true "Object containing info about the event response to delete. Paid guest sign ups can only be deleted by the organizer or with the response token the guest got when signing up"
 // @Success 200
 // @Router /events/{eventId}/response [delete]
 func deleteEventResponse(c *gin.Context) {
 	payload := struct {
-		UserId string `json:"userId"`
-		Guest  *bool  `json:"guest" binding:"required"`
-		Name   string `json:"name"`
+		UserId        string `json:"userId"`
+		Guest         *bool  `json:"guest" binding:"required"`
+		Name          string `json:"name"`
+		ResponseToken string `json:"responseToken"`
 	}{}
 	if err := c.Bind(&payload); err != nil {
 		return
 	}
-	session := sessions.Default(c)
 	eventId := c.Param("eventId")
 	event := db.GetEventByEitherId(eventId)
 	if event == nil {
@@ -883,8 +1335,18 @@ func deleteEventResponse(c *gin.Context) {
 	eventResponses := db.GetEventResponses(event.Id.Hex())
 
 	if *payload.Guest {
+		sessionUserId, _ := utils.GetUserId(c)
+		isOwner := sessionUserId == event.OwnerId.Hex()
+
 		if utils.Coalesce(event.IsSignUpForm) {
+			// Deleting a paid sign up refunds it, so only the guest who signed up or
+			// the organizer can
+			paid := db.GetRespondentPayments(event.Id, payload.Name, models.PAYMENT_PAID)
+			if len(paid) > 0 && !isOwner && !checkResponseToken(c, event, payload.Name, payload.ResponseToken) {
+				return
+			}
 			delete(event.SignUpResponses, payload.Name)
+			refundSignUpPayments(event, payload.Name, isOwner)
 		} else {
 			// Remove response from array
 			for i := range eventResponses {
@@ -898,13 +1360,12 @@ func deleteEventResponse(c *gin.Context) {
 			}
 		}
 	} else {
-		userIdInterface := session.Get("userId")
-		if userIdInterface == nil {
+		userIdString, signedIn := utils.GetUserId(c)
+		if !signedIn {
 			c.JSON(http.StatusUnauthorized, responses.Error{Error: errs.NotSignedIn})
 			c.Abort()
 			return
 		}
-		userIdString := userIdInterface.(string)
 
 		// Don't allow user to delete availability of other users if they aren't the owner of the event
 		if payload.UserId != userIdString && event.OwnerId.Hex() != userIdString {
@@ -915,6 +1376,7 @@ func deleteEventResponse(c *gin.Context) {
 
 		if utils.Coalesce(event.IsSignUpForm) {
 			delete(event.SignUpResponses, payload.UserId)
+			refundSignUpPayments(event, payload.UserId, payload.UserId != userIdString)
 		} else {
 			// Remove response from array
 			for i := range eventResponses {
@@ -956,6 +1418,12 @@ func deleteEventResponse(c *gin.Context) {
 		logger.StdErr.Panicln(err)
 	}
 
+	deletedUserId := payload.UserId
+	if *payload.Guest {
+		deletedUserId = payload.Name
+	}
+	publishResponseChange(event, realtime.RESPONSE_DELETED, deletedUserId)
+
 	c.JSON(http.StatusOK, gin.H{})
 }
 
@@ -984,6 +1452,8 @@ func renameUser(c *gin.Context) {
 
 	// Check if old name is a guest response
 	db.UpdateGuestResponseName(event.Id.Hex(), payload.OldName, payload.NewName)
+	publishResponseChange(event, realtime.RESPONSE_DELETED, payload.OldName)
+	publishResponseChange(event, realtime.RESPONSE_ADDED, payload.NewName)
 
 	c.JSON(http.StatusOK, gin.H{})
 }
@@ -1050,19 +1520,18 @@ func userResponded(c *gin.Context) {
 		}
 	}
 	if everyoneResponded {
-		// Get owner
-		owner := db.GetUserById(event.OwnerId.Hex())
-
 		// Get event url
 		baseUrl := utils.GetBaseUrl()
 		eventUrl := fmt.Sprintf("%s/e/%s", baseUrl, eventId)
 
 		// Send email
-		everyoneRespondedEmailTemplateId := 8
-		listmonk.SendEmail(owner.Email, everyoneRespondedEmailTemplateId, bson.M{
-			"eventName": event.Name,
-			"eventUrl":  eventUrl,
-		})
+		notifications.NotifyOrganizers(event, models.EVERYONE_RESPONDED_ALERT, "", func(organizer *models.User) {
+			everyoneRespondedEmailTemplateId := 8
+			listmonk.SendEmail(organizer.Email, everyoneRespondedEmailTemplateId, bson.M{
+				"eventName": event.Name,
+				"eventUrl":  eventUrl,
+			})
+		}, fmt.Sprintf("Everyone responded to <%s|%s>", eventUrl, event.Name))
 	}
 
 	c.JSON(http.StatusOK, gin.H{})
@@ -1308,6 +1777,7 @@ func deleteEvent(c *gin.Context) {
 		if err != nil {
 			logger.StdErr.Panicln(err)
 		}
+		db.DeleteEventActivities(objectId)
 	}
 
 	// Delete gcloud tasks
@@ -1434,6 +1904,47 @@ func archiveEvent(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
+// Returns the event in the eventId param if the current user owns it or is an
+// admin of its organization, otherwise responds with an error and returns nil
+func getOwnedEvent(c *gin.Context) *models.Event {
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return nil
+	}
+	if !canEditEvent(event, utils.GetAuthUser(c).Id) {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotEventOwner})
+		return nil
+	}
+	return event
+}
+
+// Returns whether the user can edit the event, which the admins of its
+// organization can too
+func canEditEvent(event *models.Event, userId primitive.ObjectID) bool {
+	if event.OwnerId == userId {
+		return true
+	}
+	if event.OrganizationId.IsZero() {
+		return false
+	}
+	return organizations.CanEditEvent(db.GetOrganizationById(event.OrganizationId.Hex()), event, userId)
+}
+
+// Returns whether the user organizes the event, i.e. can edit it or is one of
+// its co-organizers
+func canManageEvent(event *models.Event, userId string) bool {
+	id, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return false
+	}
+	if canEditEvent(event, id) {
+		return true
+	}
+	user := db.GetUserById(userId)
+	return user != nil && notifications.IsOrganizer(event, user)
+}
+
 // Helper function to find a response by userId
 func findResponse(responses []models.EventResponse, userId string) (int, *models.Response) {
 	for i, resp := range responses {
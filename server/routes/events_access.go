@@ -0,0 +1,129 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/expiry"
+	"schej.it/server/services/lti"
+	"schej.it/server/services/notifications"
+	"schej.it/server/services/submissions"
+	"schej.it/server/utils"
+)
+
+// Checks that the respondent is allowed to respond to the event, if it's
+// invite only. Guests can't respond to invite only events, unless they were
+// launched from an LMS with an authorized email
+func checkInviteOnly(c *gin.Context, event *models.Event, guest bool, embed *lti.EmbedClaims) bool {
+	if !utils.Coalesce(event.InviteOnly) {
+		return true
+	}
+
+	if embed != nil {
+		if isAuthorizedEmail(event, embed.Email) {
+			return true
+		}
+	} else if userId, signedIn := utils.GetUserId(c); signedIn && !guest {
+		if user := db.GetUserById(userId); user != nil && (notifications.IsOrganizer(event, user) || isAuthorizedEmail(event, user.Email)) {
+			return true
+		}
+	}
+
+	c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotInvited})
+	return false
+}
+
+func isAuthorizedEmail(event *models.Event, email string) bool {
+	if len(email) == 0 {
+		return false
+	}
+	for _, authorizedEmail := range event.AuthorizedEmails {
+		if strings.EqualFold(authorizedEmail, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks that the respondent wasn't blocked by the organizer, by IP address,
+// account, or email
+func checkNotBlocked(c *gin.Context, event *models.Event, guest bool, email string) bool {
+	if len(event.BlockedRespondents) == 0 {
+		return true
+	}
+
+	var userId primitive.ObjectID
+	if id, signedIn := utils.GetUserId(c); signedIn && !guest {
+		userId = utils.StringToObjectID(id)
+		if user := db.GetUserById(id); user != nil {
+			email = user.Email
+		}
+	}
+	ip := c.ClientIP()
+	for _, blocked := range event.BlockedRespondents {
+		if (len(blocked.Ip) > 0 && blocked.Ip == ip) ||
+			(!blocked.UserId.IsZero() && blocked.UserId == userId) ||
+			(len(blocked.Email) > 0 && strings.EqualFold(blocked.Email, email)) {
+			c.JSON(http.StatusForbidden, responses.Error{Error: errs.RespondentBlocked})
+			return false
+		}
+	}
+	return true
+}
+
+// Checks that guests submit with a valid submission token, that wasn't used
+// before. Replays of a submission reuse its token, so they're rejected
+func checkSubmissionToken(c *gin.Context, event *models.Event, token string) bool {
+	nonce, err := submissions.ParseToken(token, event.Id, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidSubmissionToken})
+		return false
+	}
+	if !db.UseSubmissionNonce(nonce, event.Id) {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.SubmissionReplayed})
+		return false
+	}
+	return true
+}
+
+// Checks that the token was issued to the guest when they created their
+// response to the event
+func checkResponseToken(c *gin.Context, event *models.Event, guestName string, token string) bool {
+	if !submissions.CheckResponseToken(token, event.Id, guestName) {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.InvalidResponseToken})
+		return false
+	}
+	return true
+}
+
+// Checks that the event's poll hasn't closed. Organizers can still open closed
+// polls, everyone else gets a "this poll has closed" response with the
+// finalized time, if there is one
+func checkNotExpired(c *gin.Context, event *models.Event) bool {
+	expiresAt := expiry.GetExpiry(event, expiry.GetDefaultDays())
+	if expiresAt == nil || time.Now().Before(*expiresAt) {
+		return true
+	}
+	if userId, signedIn := utils.GetUserId(c); signedIn {
+		if user := db.GetUserById(userId); user != nil && notifications.IsOrganizer(event, user) {
+			return true
+		}
+	}
+
+	c.JSON(http.StatusGone, gin.H{
+		"error":          errs.EventExpired,
+		"message":        "This poll has closed",
+		"name":           event.Name,
+		"expiresAt":      primitive.NewDateTimeFromTime(*expiresAt),
+		"scheduledEvent": event.ScheduledEvent,
+		"sessions":       event.Sessions,
+	})
+	return false
+}
@@ -0,0 +1,68 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/models"
+)
+
+// @Summary Gets the activity feed of an event
+// @Description Lists what happened to the event, most recent first: when it was created, published, and finalized, which settings changed, who responded or had their response removed, and who was reminded. Only organizers and co-organizers can see it
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param before query string false "Only return activity older than this time, for paging"
+// @Param limit query int false "Maximum number of entries to return (default 50, at most 200)"
+// @Success 200 {object} []models.Activity
+// @Router /events/{eventId}/activity [get]
+func getEventActivity(c *gin.Context) {
+	payload := struct {
+		Before *time.Time `form:"before"`
+		Limit  int64      `form:"limit"`
+	}{}
+	if err := c.Bind(&payload); err != nil {
+		return
+	}
+	event := getOrganizedEvent(c)
+	if event == nil {
+		return
+	}
+
+	if payload.Limit <= 0 {
+		payload.Limit = 50
+	} else if payload.Limit > 200 {
+		payload.Limit = 200
+	}
+	var before *primitive.DateTime
+	if payload.Before != nil {
+		beforeDateTime := primitive.NewDateTimeFromTime(*payload.Before)
+		before = &beforeDateTime
+	}
+
+	c.JSON(http.StatusOK, db.GetEventActivities(event.Id, before, payload.Limit))
+}
+
+// Adds an entry to the event's activity feed. actor is nil for guests and for
+// things Timeful did on its own, in which case actorName is shown instead
+func recordActivity(event *models.Event, activityType models.ActivityType, actor *models.User, actorName string, details []string) {
+	activity := models.Activity{
+		EventId:   event.Id,
+		Type:      activityType,
+		ActorName: actorName,
+		Details:   details,
+		CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+	}
+	if actor != nil {
+		activity.ActorId = actor.Id
+		if len(activity.ActorName) == 0 {
+			activity.ActorName = strings.TrimSpace(fmt.Sprintf("%s %s", actor.FirstName, actor.LastName))
+		}
+	}
+	db.InsertActivity(&activity)
+}
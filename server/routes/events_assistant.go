@@ -0,0 +1,124 @@
+package routes
+
+import (
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services/assistant"
+	"schej.it/server/services/attendance"
+	"schej.it/server/services/llm"
+	"schej.it/server/services/resources"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// @Summary Suggests the best times for the event
+// @Description Ranks the event's candidate slots by who can make it, with an explanation for each (e.g. "Tue May 14 3pm works for all 6 required people"). If SCHEDULING_ASSISTANT_LLM_ENABLED is true, a summary is also generated by the configured LLM. dataMinimization controls what is sent to the LLM: "strict" (default) replaces names with pseudonyms, "first_names" sends first names only, "aggregate_only" only sends counts, and "none" disables the LLM summary. Emails are never sent. Slots during which any of the given resources is busy are skipped. If the event weights by attendance, respondents that often missed past events they were available for count for less, and their weights are returned
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{meetingLength=int,required=[]string,resourceIds=[]string,earliestHour=float64,latestHour=float64,limit=int,timezoneOffset=int,dataMinimization=string} true "Meeting length in minutes, ids of required respondents and resources, hours to consider, number of suggestions, the client's timezone offset in minutes, and the data minimization mode"
+// @Success 200 {object} object{suggestions=[]assistant.Suggestion,respondents=[]scheduling.Respondent,attendanceWeights=map[string]float64,summary=string}
+// @Router /events/{eventId}/assistant [post]
+func getSchedulingSuggestions(c *gin.Context) {
+	payload := struct {
+		MeetingLength    *int                 `json:"meetingLength"`
+		Required         []string             `json:"required"`
+		ResourceIds      []primitive.ObjectID `json:"resourceIds"`
+		EarliestHour     *float64             `json:"earliestHour"`
+		LatestHour       *float64             `json:"latestHour"`
+		Limit            *int                 `json:"limit"`
+		TimezoneOffset   *int                 `json:"timezoneOffset"`
+		DataMinimization *string              `json:"dataMinimization"`
+	}{}
+	if err := c.Bind(&payload); err != nil {
+		return
+	}
+
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	loc := utils.GetUserLocation(user)
+	if payload.TimezoneOffset != nil {
+		loc = time.FixedZone("UserOffset", -*payload.TimezoneOffset*60)
+	}
+
+	constraints := assistant.Constraints{
+		Required:     payload.Required,
+		EarliestHour: payload.EarliestHour,
+		LatestHour:   payload.LatestHour,
+		Limit:        utils.Coalesce(payload.Limit),
+	}
+	if payload.MeetingLength != nil {
+		constraints.MeetingLength = time.Duration(*payload.MeetingLength) * time.Minute
+	}
+	if len(payload.ResourceIds) > 0 {
+		resourcesList, ok := getOwnedResources(c, user, payload.ResourceIds)
+		if !ok {
+			return
+		}
+		constraints.Exclude = getResourcesExclude(event, resourcesList)
+	}
+
+	respondents := scheduling.GetRespondents(db.GetEventResponses(event.Id.Hex()))
+	constraints.Weights = getAttendanceWeights(event, respondents)
+	suggestions := assistant.Suggest(event, respondents, constraints, loc)
+
+	// Generate a summary with the LLM, if enabled
+	summary := ""
+	mode := assistant.DataMinimization(utils.Coalesce(payload.DataMinimization))
+	switch mode {
+	case "":
+		mode = assistant.Strict
+	case assistant.Strict, assistant.FirstNames, assistant.AggregateOnly, "none":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dataMinimization"})
+		return
+	}
+	if mode != "none" && len(suggestions) > 0 && os.Getenv("SCHEDULING_ASSISTANT_LLM_ENABLED") == "true" && llm.IsConfigured() {
+		var err error
+		summary, err = assistant.Summarize(suggestions, respondents, payload.Required, mode, loc)
+		if err != nil {
+			logger.StdErr.Println(err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"suggestions":       suggestions,
+		"respondents":       respondents,
+		"attendanceWeights": constraints.Weights,
+		"summary":           summary,
+	})
+}
+
+// Returns the weights of the respondents that missed past events they were
+// available for, or nil if the event doesn't weight by attendance
+func getAttendanceWeights(event *models.Event, respondents []scheduling.Respondent) map[string]float64 {
+	if !utils.Coalesce(event.WeightByAttendance) {
+		return nil
+	}
+	return attendance.GetWeights(event, respondents, time.Now())
+}
+
+// Returns a function that excludes the slots of the event during which any of
+// the resources is busy
+func getResourcesExclude(event *models.Event, resourcesList []models.Resource) func(start time.Time, end time.Time) bool {
+	increments := scheduling.GetTimeIncrements(event)
+	if len(increments) == 0 || event.Type == models.DOW || event.Type == models.GROUP {
+		// Resources are booked on specific dates only
+		return nil
+	}
+	timeMax := increments[len(increments)-1].Add(scheduling.GetTimeIncrement(event))
+	busy := resources.GetBusyTimes(resourcesList, increments[0], timeMax, event.Id)
+	return resources.ExcludeBusy(busy)
+}
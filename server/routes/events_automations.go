@@ -0,0 +1,245 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/automations"
+	"schej.it/server/services/resources"
+	"schej.it/server/utils"
+)
+
+// Number of recent runs returned for an automation rule
+const automationRunsLimit = 50
+
+// Returns the automation rule in the path if it belongs to the event,
+// otherwise responds with a 404 and returns nil
+func getEventAutomationRule(c *gin.Context, event *models.Event) *models.AutomationRule {
+	rule := db.GetAutomationRuleById(c.Param("ruleId"))
+	if rule == nil || rule.EventId != event.Id {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.AutomationRuleNotFound})
+		return nil
+	}
+	return rule
+}
+
+// @Summary Gets the automation rules of the event
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} []models.AutomationRule
+// @Router /events/{eventId}/automations [get]
+func getAutomationRules(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, db.GetEventAutomationRules(event.Id))
+}
+
+// @Summary Creates an automation rule for the event
+// @Description The action runs once, shortly after the trigger fires: responsesReached fires once the event has at least threshold responses, and deadlinePassed once the deadline (which defaults to the event's expiresAt) passes. notifySlack and notifyEmail send the message (or a description of the trigger) to the organizers, and finalizeTopSlot finalizes the event at the upcoming slot of durationMinutes the most respondents can make, asking the finalization approvers first if there are any
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{name=string,trigger=models.AutomationTrigger,action=models.AutomationAction,threshold=int,deadline=string,message=string,durationMinutes=int} true "The rule"
+// @Success 201 {object} models.AutomationRule
+// @Router /events/{eventId}/automations [post]
+func createAutomationRule(c *gin.Context) {
+	payload := struct {
+		Name            string                   `json:"name"`
+		Trigger         models.AutomationTrigger `json:"trigger" binding:"required"`
+		Action          models.AutomationAction  `json:"action" binding:"required"`
+		Threshold       int                      `json:"threshold"`
+		Deadline        *primitive.DateTime      `json:"deadline"`
+		Message         string                   `json:"message"`
+		DurationMinutes int                      `json:"durationMinutes"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	if len(db.GetEventAutomationRules(event.Id)) >= automations.MAX_RULES {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.TooManyAutomationRules})
+		return
+	}
+
+	rule := models.AutomationRule{
+		EventId:         event.Id,
+		CreatedBy:       utils.GetAuthUser(c).Id,
+		Name:            strings.TrimSpace(payload.Name),
+		Trigger:         payload.Trigger,
+		Action:          payload.Action,
+		Enabled:         true,
+		Threshold:       payload.Threshold,
+		Deadline:        payload.Deadline,
+		Message:         strings.TrimSpace(payload.Message),
+		DurationMinutes: payload.DurationMinutes,
+		CreatedAt:       primitive.NewDateTimeFromTime(time.Now()),
+	}
+	if err := automations.Validate(&rule, event); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	db.InsertAutomationRule(&rule)
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// @Summary Updates an automation rule of the event
+// @Description Updating a rule that already fired re-arms it, so it runs again the next time its trigger is checked
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param ruleId path string true "Automation rule ID"
+// @Param payload body object{name=string,trigger=models.AutomationTrigger,action=models.AutomationAction,threshold=int,deadline=string,message=string,durationMinutes=int,enabled=bool} true "Fields to update"
+// @Success 200 {object} models.AutomationRule
+// @Router /events/{eventId}/automations/{ruleId} [patch]
+func updateAutomationRule(c *gin.Context) {
+	payload := struct {
+		Name            *string                   `json:"name"`
+		Trigger         *models.AutomationTrigger `json:"trigger"`
+		Action          *models.AutomationAction  `json:"action"`
+		Threshold       *int                      `json:"threshold"`
+		Deadline        *primitive.DateTime       `json:"deadline"`
+		Message         *string                   `json:"message"`
+		DurationMinutes *int                      `json:"durationMinutes"`
+		Enabled         *bool                     `json:"enabled"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	rule := getEventAutomationRule(c, event)
+	if rule == nil {
+		return
+	}
+
+	if payload.Name != nil {
+		rule.Name = strings.TrimSpace(*payload.Name)
+	}
+	if payload.Trigger != nil {
+		rule.Trigger = *payload.Trigger
+	}
+	if payload.Action != nil {
+		rule.Action = *payload.Action
+	}
+	if payload.Threshold != nil {
+		rule.Threshold = *payload.Threshold
+	}
+	if payload.Deadline != nil {
+		rule.Deadline = payload.Deadline
+	}
+	if payload.Message != nil {
+		rule.Message = strings.TrimSpace(*payload.Message)
+	}
+	if payload.DurationMinutes != nil {
+		rule.DurationMinutes = *payload.DurationMinutes
+	}
+	if payload.Enabled != nil {
+		rule.Enabled = *payload.Enabled
+	}
+	if err := automations.Validate(rule, event); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rule.FiredAt = nil
+	db.UpdateAutomationRule(rule)
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// @Summary Deletes an automation rule of the event, along with its history
+// @Tags events
+// @Param eventId path string true "Event ID"
+// @Param ruleId path string true "Automation rule ID"
+// @Success 200
+// @Router /events/{eventId}/automations/{ruleId} [delete]
+func deleteAutomationRule(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	rule := getEventAutomationRule(c, event)
+	if rule == nil {
+		return
+	}
+	db.DeleteAutomationRule(rule.Id)
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Gets the execution history of an automation rule
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param ruleId path string true "Automation rule ID"
+// @Success 200 {object} []models.AutomationRun
+// @Router /events/{eventId}/automations/{ruleId}/runs [get]
+func getAutomationRuns(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	rule := getEventAutomationRule(c, event)
+	if rule == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, db.GetAutomationRuns(rule.Id, automationRunsLimit))
+}
+
+// Finalizes the event at the given time on behalf of its owner, the way
+// finalizeEvent does, for automation rules. If the event has finalization
+// approvers, asks them to approve it instead and returns false
+func FinalizeAutomatically(event *models.Event, start time.Time, end time.Time) (bool, error) {
+	owner := db.GetUserById(event.OwnerId.Hex())
+	if owner == nil {
+		return false, fmt.Errorf("owner of event %s not found", event.Id.Hex())
+	}
+	if err := runBeforeFinalizeHooks(event, owner, start, end, nil); err != nil {
+		return false, fmt.Errorf("rejected by a hook: %w", err)
+	}
+
+	resourcesList := db.GetResourcesByIds(event.ResourceIds)
+	busy := resources.GetBusyTimes(resourcesList, start, end, event.Id)
+	for _, resource := range resourcesList {
+		if !resources.IsFree(busy[resource.Id.Hex()], start, end) {
+			return false, errors.New(resource.Name + " is already booked")
+		}
+	}
+
+	pending := models.PendingFinalization{
+		StartDate:   primitive.NewDateTimeFromTime(start),
+		EndDate:     primitive.NewDateTimeFromTime(end),
+		ResourceIds: event.ResourceIds,
+		RequestedBy: owner.Id,
+		RequestedAt: primitive.NewDateTimeFromTime(time.Now()),
+		ApprovedBy:  make([]primitive.ObjectID, 0),
+	}
+	if event.FinalizationApprovers != nil {
+		requestFinalizationApproval(event, owner, &pending)
+		return false, nil
+	}
+
+	completeFinalization(event, owner, &pending, resourcesList)
+	return true, nil
+}
@@ -0,0 +1,113 @@
+package routes
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/utils"
+)
+
+func getAvailabilityShareUrl(token string) string {
+	return fmt.Sprintf("%s/api/availability/%s", utils.GetBaseUrl(), token)
+}
+
+func getAvailabilityShareResponses(shares []models.AvailabilityShare) []gin.H {
+	result := make([]gin.H, 0)
+	for _, share := range shares {
+		result = append(result, gin.H{
+			"token":     share.Token,
+			"label":     share.Label,
+			"createdAt": share.CreatedAt,
+			"url":       getAvailabilityShareUrl(share.Token),
+		})
+	}
+	return result
+}
+
+// @Summary Gets the read-only links to the event's aggregated availability
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} []object{token=string,label=string,createdAt=string,url=string}
+// @Router /events/{eventId}/availability-shares [get]
+func getAvailabilityShares(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, getAvailabilityShareResponses(event.AvailabilityShares))
+}
+
+// @Summary Creates a read-only link to the event's aggregated availability
+// @Description The link only shows how many respondents are available at each time, never who they are, so it can be embedded in dashboards or docs without exposing the event
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{label=string} false "Label to tell links apart, e.g. where it's embedded"
+// @Success 201 {object} object{token=string,label=string,createdAt=string,url=string}
+// @Router /events/{eventId}/availability-shares [post]
+func createAvailabilityShare(c *gin.Context) {
+	payload := struct {
+		Label string `json:"label"`
+	}{}
+	// The body is optional
+	if err := c.ShouldBindJSON(&payload); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	tokenBytes := make([]byte, 18)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	share := models.AvailabilityShare{
+		Token:     hex.EncodeToString(tokenBytes),
+		Label:     strings.TrimSpace(payload.Label),
+		CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+	}
+	if _, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{"$push": bson.M{"availabilityShares": share}}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.JSON(http.StatusCreated, getAvailabilityShareResponses([]models.AvailabilityShare{share})[0])
+}
+
+// @Summary Revokes a read-only link to the event's aggregated availability
+// @Tags events
+// @Param eventId path string true "Event ID"
+// @Param token path string true "Token of the link"
+// @Success 200
+// @Router /events/{eventId}/availability-shares/{token} [delete]
+func deleteAvailabilityShare(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	if _, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$pull": bson.M{"availabilityShares": bson.M{"token": c.Param("token")}},
+	}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.Status(http.StatusOK)
+}
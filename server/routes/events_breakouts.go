@@ -0,0 +1,53 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/services/breakouts"
+	"schej.it/server/services/scheduling"
+)
+
+// @Summary Splits the respondents into groups that can each meet
+// @Description Partitions the respondents into groups of at most groupSize members (e.g. breakout labs), each with a slot all its members can make, placing as many respondents as possible. Several groups may meet at the same time. Nothing is saved
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{groupSize=int,numGroups=int,durationMinutes=int} true "Maximum members per group, the number of groups (defaults to enough groups for every respondent), and the length of each group's meeting in minutes (defaults to the event's time increment)"
+// @Success 200 {object} breakouts.Result
+// @Router /events/{eventId}/breakouts [post]
+func splitIntoBreakouts(c *gin.Context) {
+	payload := struct {
+		GroupSize       int `json:"groupSize" binding:"required,min=1"`
+		NumGroups       int `json:"numGroups" binding:"min=0"`
+		DurationMinutes int `json:"durationMinutes" binding:"min=0"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	respondents := scheduling.GetRespondents(db.GetEventResponses(event.Id.Hex()))
+	respondentIds := make([]string, 0)
+	for _, respondent := range respondents {
+		respondentIds = append(respondentIds, respondent.Id)
+	}
+	numGroups := payload.NumGroups
+	if numGroups == 0 {
+		numGroups = breakouts.GetNumGroups(len(respondents), payload.GroupSize)
+	}
+
+	ranked := scheduling.RankSlots(event, respondents, scheduling.Options{
+		MeetingLength: time.Duration(payload.DurationMinutes) * time.Minute,
+		Exclude:       scheduling.ExcludePast(event, time.Now()),
+	})
+
+	c.JSON(http.StatusOK, breakouts.Split(ranked, respondentIds, numGroups, payload.GroupSize))
+}
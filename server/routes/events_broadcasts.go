@@ -0,0 +1,122 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/notifications"
+)
+
+// @Summary Schedules a message to all respondents of the event
+// @Description The message is sent by email, and by Slack to respondents that linked their account, at sendAt (or right away if omitted)
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{subject=string,message=string,sendAt=string} true "Message to send and when to send it"
+// @Success 201 {object} models.Broadcast
+// @Router /events/{eventId}/broadcasts [post]
+func createBroadcast(c *gin.Context) {
+	payload := struct {
+		Subject string              `json:"subject"`
+		Message string              `json:"message" binding:"required"`
+		SendAt  *primitive.DateTime `json:"sendAt"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	if len(strings.TrimSpace(payload.Message)) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "message is required"})
+		return
+	}
+
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	now := time.Now()
+	broadcast := models.Broadcast{
+		EventId:   event.Id,
+		OwnerId:   event.OwnerId,
+		Subject:   strings.TrimSpace(payload.Subject),
+		Message:   strings.TrimSpace(payload.Message),
+		Status:    models.BROADCAST_SCHEDULED,
+		SendAt:    primitive.NewDateTimeFromTime(now),
+		CreatedAt: primitive.NewDateTimeFromTime(now),
+	}
+	if len(broadcast.Subject) == 0 {
+		broadcast.Subject = "Update: " + event.Name
+	}
+	if payload.SendAt != nil && payload.SendAt.Time().After(now) {
+		broadcast.SendAt = *payload.SendAt
+	}
+	db.InsertBroadcast(&broadcast)
+
+	// Send right away if it's due, otherwise the jobs scheduler sends it
+	if !broadcast.SendAt.Time().After(now) && db.TransitionBroadcastStatus(broadcast.Id, models.BROADCAST_SCHEDULED, models.BROADCAST_SENDING) {
+		broadcast.Status = models.BROADCAST_SENDING
+		toSend := broadcast
+		go func() {
+			// Recover from panics
+			defer func() {
+				if err := recover(); err != nil {
+					logger.StdErr.Println(err)
+				}
+			}()
+
+			notifications.SendBroadcast(&toSend)
+		}()
+	}
+
+	c.JSON(http.StatusCreated, broadcast)
+}
+
+// @Summary Gets the broadcasts of the event
+// @Description Includes the delivery status of every message sent
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} []models.Broadcast
+// @Router /events/{eventId}/broadcasts [get]
+func getBroadcasts(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, db.GetEventBroadcasts(event.Id))
+}
+
+// @Summary Cancels a scheduled broadcast
+// @Tags events
+// @Param eventId path string true "Event ID"
+// @Param broadcastId path string true "Broadcast ID"
+// @Success 200
+// @Router /events/{eventId}/broadcasts/{broadcastId} [delete]
+func cancelBroadcast(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	broadcast := db.GetBroadcastById(c.Param("broadcastId"))
+	if broadcast == nil || broadcast.EventId != event.Id {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.BroadcastNotFound})
+		return
+	}
+	if !db.TransitionBroadcastStatus(broadcast.Id, models.BROADCAST_SCHEDULED, models.BROADCAST_CANCELLED) {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.BroadcastNotScheduled})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
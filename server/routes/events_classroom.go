@@ -0,0 +1,81 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/classroom"
+	"schej.it/server/utils"
+)
+
+// @Summary Imports a Google Classroom roster as the remindees of the event
+// @Description Replaces the remindees with the students of the course taught by the current user. The remindees are then kept in sync with the roster until the event is scheduled, and students that already responded keep their response status
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{courseId=string} true "Id of the Classroom course"
+// @Success 200 {object} models.Event
+// @Router /events/{eventId}/classroom-roster [put]
+func importClassroomRoster(c *gin.Context) {
+	payload := struct {
+		CourseId string `json:"courseId" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	if event.Type == models.GROUP {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.ClassroomRosterNotSupported})
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	course, googleErr := classroom.GetCourse(user, payload.CourseId)
+	if googleErr != nil {
+		c.JSON(googleErr.Code, responses.Error{Error: *googleErr})
+		return
+	}
+
+	event.ClassroomRoster = &models.ClassroomRoster{CourseId: course.Id, CourseName: course.Name}
+	if googleErr := classroom.SyncRoster(event, user, time.Now()); googleErr != nil {
+		c.JSON(googleErr.Code, responses.Error{Error: *googleErr})
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
+
+// @Summary Stops syncing the remindees of the event with its Classroom roster
+// @Description The current remindees are kept
+// @Tags events
+// @Param eventId path string true "Event ID"
+// @Success 200
+// @Router /events/{eventId}/classroom-roster [delete]
+func removeClassroomRoster(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$unset": bson.M{"classroomRoster": ""},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,133 @@
+package routes
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/models"
+	"schej.it/server/services/forms"
+	"schej.it/server/services/privacy"
+)
+
+// Checks that the respondent agreed to the current version of the event's
+// consent document, if it has one
+func checkConsent(c *gin.Context, event *models.Event, consentVersion *int) bool {
+	if event.ConsentDocument == nil {
+		return true
+	}
+	if consentVersion == nil || *consentVersion != event.ConsentDocument.Version {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errs.ConsentRequired, "consentDocument": event.ConsentDocument})
+		return false
+	}
+	return true
+}
+
+// Records the respondent's agreement to the event's consent document, along
+// with the request's IP address and user agent. Does nothing if the event has
+// no consent document, or the respondent already agreed to this version
+func recordConsent(c *gin.Context, event *models.Event, userId string, name string, email string) {
+	if event.ConsentDocument == nil {
+		return
+	}
+	documentHash := forms.HashConsentDocument(event.ConsentDocument)
+	if db.HasConsented(event.Id, userId, documentHash) {
+		return
+	}
+
+	ip := c.ClientIP()
+	if user := db.GetUserById(userId); user != nil {
+		name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		email = user.Email
+	} else {
+		email = privacy.GetGuestEmail(email)
+		ip = privacy.GetGuestIp(ip)
+	}
+	db.InsertConsent(&models.Consent{
+		EventId:         event.Id,
+		UserId:          userId,
+		Name:            name,
+		Email:           email,
+		DocumentVersion: event.ConsentDocument.Version,
+		DocumentHash:    documentHash,
+		IpAddress:       ip,
+		UserAgent:       c.Request.UserAgent(),
+		AgreedAt:        primitive.NewDateTimeFromTime(time.Now()),
+	})
+}
+
+// @Summary Gets the consent report of the event
+// @Description Lists every agreement to the event's consent document with its timestamp, IP address, user agent, and the document version agreed to
+// @Tags events
+// @Produce json
+// @Produce text/csv
+// @Param eventId path string true "Event ID"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} object{consentDocument=models.ConsentDocument,consents=[]models.Consent}
+// @Router /events/{eventId}/consents [get]
+func getConsentReport(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	// Contact details and IP addresses are left out on instances that minimize
+	// personal data
+	consents := db.GetEventConsents(event.Id)
+	minimized := privacy.IsMinimized()
+	if minimized {
+		for i := range consents {
+			consents[i].Email = ""
+			consents[i].IpAddress = ""
+		}
+	}
+	if c.Query("format") != "csv" {
+		c.JSON(http.StatusOK, gin.H{"consentDocument": event.ConsentDocument, "consents": consents})
+		return
+	}
+
+	var currentHash string
+	if event.ConsentDocument != nil {
+		currentHash = forms.HashConsentDocument(event.ConsentDocument)
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", event.Name+" consents.csv"))
+	c.Header("Content-Type", "text/csv")
+	w := csv.NewWriter(c.Writer)
+	if minimized {
+		w.Write([]string{"Name", "Agreed at", "Version", "Current version", "Document hash", "User agent"})
+	} else {
+		w.Write([]string{"Name", "Email", "Agreed at", "Version", "Current version", "Document hash", "IP address", "User agent"})
+	}
+	for _, consent := range consents {
+		if minimized {
+			w.Write([]string{
+				consent.Name,
+				consent.AgreedAt.Time().UTC().Format(time.RFC3339),
+				strconv.Itoa(consent.DocumentVersion),
+				strconv.FormatBool(consent.DocumentHash == currentHash),
+				consent.DocumentHash,
+				consent.UserAgent,
+			})
+			continue
+		}
+		w.Write([]string{
+			consent.Name,
+			consent.Email,
+			consent.AgreedAt.Time().UTC().Format(time.RFC3339),
+			strconv.Itoa(consent.DocumentVersion),
+			strconv.FormatBool(consent.DocumentHash == currentHash),
+			consent.DocumentHash,
+			consent.IpAddress,
+			consent.UserAgent,
+		})
+	}
+	w.Flush()
+}
@@ -0,0 +1,158 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/delegations"
+	"schej.it/server/services/notifications"
+	"schej.it/server/services/quality"
+	"schej.it/server/services/realtime"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// @Summary Responds to the event on behalf of another user
+// @Description The current user must be a delegate of the principal (see /user/delegations). The availability defaults to the times on the event's grid that the principal isn't busy on their calendars. Answers and fields of the principal's existing response are kept. The response is attributed to the delegate in submittedBy and in the event's activity feed, and recorded in the delegation's audit log
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{principalId=string,availability=[]string,ifNeeded=[]string} true "User to respond for, and optionally the availability to submit instead of importing it from their calendars"
+// @Success 200 {object} models.Response
+// @Router /events/{eventId}/response/delegate [post]
+func respondOnBehalf(c *gin.Context) {
+	payload := struct {
+		PrincipalId  string                `json:"principalId" binding:"required"`
+		Availability *[]primitive.DateTime `json:"availability"`
+		IfNeeded     []primitive.DateTime  `json:"ifNeeded"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+	if utils.Coalesce(event.IsSignUpForm) || event.Type == models.GROUP {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "delegates can only respond to availability polls"})
+		return
+	}
+
+	delegate := utils.GetAuthUser(c)
+	principalId, err := primitive.ObjectIDFromHex(payload.PrincipalId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.DelegationNotFound})
+		return
+	}
+	delegation := db.GetActiveDelegation(principalId, delegate.Id)
+	if delegation == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.DelegationNotFound})
+		return
+	}
+	principal := db.GetUserById(payload.PrincipalId)
+	if principal == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.UserDoesNotExist})
+		return
+	}
+
+	if !checkNotExpired(c, event) {
+		return
+	}
+	if utils.Coalesce(event.InviteOnly) && !notifications.IsOrganizer(event, principal) && !isAuthorizedEmail(event, principal.Email) {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotInvited})
+		return
+	}
+	for _, blocked := range event.BlockedRespondents {
+		if blocked.UserId == principal.Id || (len(blocked.Email) > 0 && strings.EqualFold(blocked.Email, principal.Email)) {
+			c.JSON(http.StatusForbidden, responses.Error{Error: errs.RespondentBlocked})
+			return
+		}
+	}
+
+	// Import the availability from the principal's calendars
+	var availability []primitive.DateTime
+	if payload.Availability != nil {
+		availability = *payload.Availability
+	} else if start, end, ok := delegations.GetRange(event); ok {
+		availability = delegations.GetAvailability(event, scheduling.GetBusyTimes(principal, start, end))
+	}
+
+	now := time.Now()
+	principalIdString := principal.Id.Hex()
+	response := models.Response{
+		UserId:       principal.Id,
+		Availability: availability,
+		IfNeeded:     payload.IfNeeded,
+		SubmittedBy: &models.ResponseDelegate{
+			UserId:      delegate.Id,
+			Name:        strings.TrimSpace(delegate.FirstName + " " + delegate.LastName),
+			SubmittedAt: primitive.NewDateTimeFromTime(now),
+		},
+	}
+	if flags := quality.Check(event, availability, payload.IfNeeded, nil); len(flags) > 0 {
+		response.QualityFlags = flags
+	}
+
+	eventResponses := db.GetEventResponses(event.Id.Hex())
+	idx, existingResponse := findResponse(eventResponses, principalIdString)
+	if existingResponse != nil {
+		response.Answers = existingResponse.Answers
+		response.Fields = existingResponse.Fields
+		response.CalendarOptions = existingResponse.CalendarOptions
+
+		_, err := db.EventResponsesCollection.UpdateOne(context.Background(), bson.M{
+			"_id": eventResponses[idx].Id,
+		}, bson.M{
+			"$set": bson.M{"response": &response},
+		})
+		if err != nil {
+			logger.StdErr.Panicln(err)
+		}
+	} else {
+		_, err := db.EventResponsesCollection.InsertOne(context.Background(), models.EventResponse{
+			UserId:   principalIdString,
+			Response: &response,
+			EventId:  event.Id,
+		})
+		if err != nil {
+			logger.StdErr.Panicln(err)
+		}
+		_, err = db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{"$inc": bson.M{"numResponses": 1}})
+		if err != nil {
+			logger.StdErr.Panicln(err)
+		}
+		if event.NumResponses != nil {
+			*event.NumResponses++
+		}
+	}
+
+	recordDelegationAudit(delegation, models.DELEGATE_RESPONDED, delegate.Id, &event.Id)
+
+	if event.Quorum != nil && event.Quorum.MetAt == nil {
+		go checkQuorum(event)
+	}
+
+	if existingResponse != nil {
+		publishResponseChange(event, realtime.RESPONSE_UPDATED, principalIdString)
+		enqueueResponseWebhook(event, models.WEBHOOK_RESPONSE_UPDATED, principalIdString)
+	} else {
+		principalName := strings.TrimSpace(principal.FirstName + " " + principal.LastName)
+		recordActivity(event, models.ACTIVITY_RESPONDED, delegate, "", []string{"on behalf of " + principalName})
+		publishResponseChange(event, realtime.RESPONSE_ADDED, principalIdString)
+		enqueueResponseWebhook(event, models.WEBHOOK_RESPONSE_CREATED, principalIdString)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
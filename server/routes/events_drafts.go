@@ -0,0 +1,285 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/contacts"
+	"schej.it/server/services/forms"
+	"schej.it/server/services/gcloud"
+	"schej.it/server/services/listmonk"
+	"schej.it/server/services/notifications"
+	"schej.it/server/services/organizations"
+	"schej.it/server/utils"
+)
+
+// Fields of a draft that can be saved before they're all filled in. Fields
+// that are nil are left as is
+type draftPayload struct {
+	Name             *string               `json:"name"`
+	Description      *string               `json:"description"`
+	Duration         *float32              `json:"duration"`
+	Dates            *[]primitive.DateTime `json:"dates"`
+	HasSpecificTimes *bool                 `json:"hasSpecificTimes"`
+	Times            *[]primitive.DateTime `json:"times"`
+	Type             *models.EventType     `json:"type"`
+	Questions        *[]models.Question    `json:"questions"`
+
+	StartOnMonday        *bool     `json:"startOnMonday"`
+	NotificationsEnabled *bool     `json:"notificationsEnabled"`
+	DaysOnly             *bool     `json:"daysOnly"`
+	CollectEmails        *bool     `json:"collectEmails"`
+	TimeIncrement        *int      `json:"timeIncrement"`
+	Timezone             *string   `json:"timezone"`
+	Tags                 *[]string `json:"tags"`
+
+	// Invitees, who are only sent anything once the draft is published
+	Remindees *[]string `json:"remindees"`
+	Attendees *[]string `json:"attendees"`
+}
+
+// Applies the fields set in the payload to the draft
+func (payload *draftPayload) apply(event *models.Event, ownerName string) error {
+	if payload.Questions != nil {
+		if err := forms.ValidateQuestions(*payload.Questions); err != nil {
+			return err
+		}
+		event.Questions = payload.Questions
+	}
+	if payload.Name != nil {
+		event.Name = *payload.Name
+	}
+	if payload.Description != nil {
+		event.Description = payload.Description
+	}
+	if payload.Duration != nil {
+		event.Duration = payload.Duration
+	}
+	if payload.Dates != nil {
+		event.Dates = *payload.Dates
+	}
+	if payload.HasSpecificTimes != nil {
+		event.HasSpecificTimes = payload.HasSpecificTimes
+	}
+	if payload.Times != nil {
+		event.Times = *payload.Times
+	}
+	if payload.Type != nil {
+		event.Type = *payload.Type
+	}
+	if payload.StartOnMonday != nil {
+		event.StartOnMonday = payload.StartOnMonday
+	}
+	if payload.NotificationsEnabled != nil {
+		event.NotificationsEnabled = payload.NotificationsEnabled
+	}
+	if payload.DaysOnly != nil {
+		event.DaysOnly = payload.DaysOnly
+	}
+	if payload.CollectEmails != nil {
+		event.CollectEmails = payload.CollectEmails
+	}
+	if payload.TimeIncrement != nil {
+		event.TimeIncrement = payload.TimeIncrement
+	}
+	if payload.Timezone != nil {
+		event.Timezone = payload.Timezone
+	}
+	if payload.Tags != nil {
+		tags, err := organizations.NormalizeTags(*payload.Tags)
+		if err != nil {
+			return err
+		}
+		event.Tags = tags
+	}
+	if payload.Remindees != nil {
+		emails, err := contacts.NormalizeEmails(*payload.Remindees)
+		if err != nil {
+			return err
+		}
+		notifications.UpdateRemindees(event, emails, ownerName)
+	}
+	if payload.Attendees != nil {
+		emails, err := contacts.NormalizeEmails(*payload.Attendees)
+		if err != nil {
+			return err
+		}
+		event.DraftAttendees = emails
+	}
+	return nil
+}
+
+// @Summary Creates a draft event
+// @Description Drafts can be saved with only some of their fields filled in, and don't count towards the plan's event limit or send anything to invitees until they're published
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param payload body object{name=string,description=string,duration=float32,dates=[]string,hasSpecificTimes=bool,times=[]string,type=models.EventType,questions=[]models.Question,startOnMonday=bool,notificationsEnabled=bool,daysOnly=bool,collectEmails=bool,timeIncrement=int,timezone=string,tags=[]string,remindees=[]string,attendees=[]string,organizationId=string} true "Fields of the draft filled in so far"
+// @Success 201 {object} object{eventId=string,shortId=string}
+// @Router /events/drafts [post]
+func createDraft(c *gin.Context) {
+	payload := struct {
+		draftPayload
+		OrganizationId *primitive.ObjectID `json:"organizationId"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	numResponses := 0
+	event := models.Event{
+		Id:              primitive.NewObjectID(),
+		OwnerId:         user.Id,
+		Type:            models.SPECIFIC_DATES,
+		IsDraft:         utils.TruePtr(),
+		SignUpResponses: make(map[string]*models.SignUpResponse),
+		NumResponses:    &numResponses,
+	}
+	if err := payload.apply(&event, user.FirstName); err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	org, ok := getEventOrg(c, user, payload.OrganizationId)
+	if !ok {
+		return
+	}
+	if org != nil {
+		event.OrganizationId = org.Id
+		organizations.ApplySettings(&event, org.Settings, true)
+	}
+
+	shortId := db.GenerateShortEventId(event.Id)
+	event.ShortId = &shortId
+	if _, err := db.EventsCollection.InsertOne(context.Background(), event); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	recordActivity(&event, models.ACTIVITY_CREATED, user, "", nil)
+
+	c.JSON(http.StatusCreated, gin.H{"eventId": event.Id.Hex(), "shortId": event.ShortId})
+}
+
+// @Summary Autosaves a draft event
+// @Description Only the fields in the payload are updated, so it can be called as the draft is being filled in
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{name=string,description=string,duration=float32,dates=[]string,hasSpecificTimes=bool,times=[]string,type=models.EventType,questions=[]models.Question,startOnMonday=bool,notificationsEnabled=bool,daysOnly=bool,collectEmails=bool,timeIncrement=int,timezone=string,tags=[]string,remindees=[]string,attendees=[]string} true "Fields of the draft that changed"
+// @Success 200 {object} object{savedAt=string}
+// @Router /events/{eventId}/draft [patch]
+func autosaveDraft(c *gin.Context) {
+	payload := draftPayload{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	if !utils.Coalesce(event.IsDraft) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.EventNotDraft})
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	if err := payload.apply(event, user.FirstName); err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+	if !event.OrganizationId.IsZero() {
+		if org := db.GetOrganizationById(event.OrganizationId.Hex()); org != nil {
+			organizations.ApplySettings(event, org.Settings, false)
+		}
+	}
+
+	if _, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{"$set": event}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"savedAt": time.Now()})
+}
+
+// @Summary Publishes a draft event
+// @Description Schedules the remindees' reminder emails and invites the attendees of groups. The event counts towards the plan's event limit from now on
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} models.Event
+// @Router /events/{eventId}/publish [post]
+func publishDraft(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	if !utils.Coalesce(event.IsDraft) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.EventNotDraft})
+		return
+	}
+	if len(event.Name) == 0 || event.Duration == nil || len(event.Dates) == 0 || len(event.Type) == 0 {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.DraftIncomplete})
+		return
+	}
+	owner := utils.GetAuthUser(c)
+
+	publishedAt := primitive.NewDateTimeFromTime(time.Now())
+	event.IsDraft = nil
+	event.PublishedAt = &publishedAt
+
+	// Schedule the reminders that weren't scheduled while the event was a draft
+	invitees := make([]string, 0)
+	if event.Remindees != nil {
+		for i, remindee := range *event.Remindees {
+			if len(remindee.TaskIds) == 0 && !utils.Coalesce(remindee.Responded) {
+				(*event.Remindees)[i].TaskIds = gcloud.CreateEmailTask(remindee.Email, owner.FirstName, event.Name, event.GetId(), event.ReminderCadence)
+			}
+			invitees = append(invitees, remindee.Email)
+		}
+	}
+
+	// Invite the attendees of groups
+	if event.Type == models.GROUP {
+		attendees := []models.Attendee{{Email: owner.Email, Declined: utils.FalsePtr(), EventId: event.Id}}
+		availabilityGroupInviteEmailId := 9
+		for _, email := range event.DraftAttendees {
+			if email == owner.Email {
+				continue
+			}
+			listmonk.SendEmailAddSubscriberIfNotExist(email, availabilityGroupInviteEmailId, bson.M{
+				"ownerName": owner.FirstName,
+				"groupName": event.Name,
+				"groupUrl":  fmt.Sprintf("%s/g/%s", utils.GetBaseUrl(), event.GetId()),
+			}, false)
+			attendees = append(attendees, models.Attendee{Email: email, Declined: utils.FalsePtr(), EventId: event.Id})
+		}
+		for _, attendee := range attendees {
+			db.AttendeesCollection.InsertOne(context.Background(), attendee)
+		}
+		invitees = append(invitees, event.DraftAttendees...)
+		event.DraftAttendees = nil
+	}
+
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$set":   event,
+		"$unset": bson.M{"isDraft": "", "draftAttendees": ""},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	db.UsersCollection.UpdateByID(context.Background(), owner.Id, bson.M{"$inc": bson.M{"numEventsCreated": 1}})
+	recordInvitees(owner.Id, invitees)
+	recordActivity(event, models.ACTIVITY_PUBLISHED, owner, "", nil)
+
+	c.JSON(http.StatusOK, event)
+}
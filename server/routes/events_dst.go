@@ -0,0 +1,51 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/responses"
+	"schej.it/server/services/dst"
+	"schej.it/server/utils"
+)
+
+// @Summary Audits the event for daylight saving time problems
+// @Description Reports the DST transitions in the event's range, the local times in the event's daily windows that don't exist or are ambiguous because of them, dates that don't start at the same local time as the first date, and respondents with availability that isn't on the event's grid
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param timezone query string false "IANA timezone to audit the event in, defaults to the event's timezone"
+// @Success 200 {object} dst.Report
+// @Router /events/{eventId}/dst-audit [get]
+func getDstAudit(c *gin.Context) {
+	query := struct {
+		Timezone string `form:"timezone"`
+	}{}
+	if err := c.Bind(&query); err != nil {
+		return
+	}
+
+	event := getOrganizedEvent(c)
+	if event == nil {
+		return
+	}
+
+	timezone := query.Timezone
+	if len(timezone) == 0 {
+		timezone = utils.Coalesce(event.Timezone)
+	}
+	if len(timezone) == 0 {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "timezone is required for events without a timezone"})
+		return
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: fmt.Sprintf("invalid timezone %q", timezone)})
+		return
+	}
+
+	c.JSON(http.StatusOK, dst.Audit(event, db.GetEventResponses(event.Id.Hex()), loc))
+}
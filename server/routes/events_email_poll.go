@@ -0,0 +1,351 @@
+package routes
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/contacts"
+	"schej.it/server/services/emailpoll"
+	"schej.it/server/services/gcloud"
+	"schej.it/server/services/realtime"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// @Summary Emails proposed slots to the event's invitees
+// @Description Invitees respond by replying with the numbers of the slots that work for them, by clicking the link of each slot, or, if attachHolds is set, by accepting or declining the tentative hold attached for each slot from their mail client. Their answers are registered as guest responses covering the picked slots. Replaces the event's previous email poll
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{slots=[]models.EmailPollSlot,emails=[]string,attachHolds=bool} true "Proposed slots, the emails to send them to (defaults to the remindees), and whether to attach a calendar hold for each slot"
+// @Success 200 {object} models.EmailPoll
+// @Router /events/{eventId}/email-poll [post]
+func createEmailPoll(c *gin.Context) {
+	payload := struct {
+		Slots       []models.EmailPollSlot `json:"slots" binding:"required"`
+		Emails      []string               `json:"emails"`
+		AttachHolds *bool                  `json:"attachHolds"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	if len(payload.Slots) == 0 || len(payload.Slots) > 9 {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "between 1 and 9 slots must be proposed"})
+		return
+	}
+	for _, slot := range payload.Slots {
+		if !slot.End.Time().After(slot.Start.Time()) {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: "slots must end after they start"})
+			return
+		}
+	}
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	if event.Type == models.GROUP || utils.Coalesce(event.IsSignUpForm) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.EmailPollNotSupported})
+		return
+	}
+	owner := utils.GetAuthUser(c)
+
+	emails := payload.Emails
+	if emails == nil {
+		emails = utils.Map(utils.Coalesce(event.Remindees), func(r models.Remindee) string { return r.Email })
+	}
+	emails, err := contacts.NormalizeEmails(emails)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	poll := models.EmailPoll{
+		Slots:       payload.Slots,
+		Recipients:  make([]models.EmailPollRecipient, 0),
+		SentAt:      primitive.NewDateTimeFromTime(time.Now()),
+		AttachHolds: utils.Coalesce(payload.AttachHolds),
+	}
+	for _, email := range emails {
+		keyBytes := make([]byte, 12)
+		if _, err := rand.Read(keyBytes); err != nil {
+			logger.StdErr.Panicln(err)
+		}
+		poll.Recipients = append(poll.Recipients, models.EmailPollRecipient{Email: email, Key: hex.EncodeToString(keyBytes)})
+	}
+	event.EmailPoll = &poll
+	if _, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{"$set": bson.M{"emailPoll": event.EmailPoll}}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	recordActivity(event, models.ACTIVITY_REMINDER_SENT, owner, "", emails)
+
+	// Send the poll emails asynchronously
+	go func() {
+		// Recover from panics
+		defer func() {
+			if err := recover(); err != nil {
+				logger.StdErr.Println(err)
+			}
+		}()
+
+		for _, recipient := range poll.Recipients {
+			sendEmailPoll(event, owner, recipient)
+		}
+	}()
+
+	c.JSON(http.StatusOK, poll)
+}
+
+// Emails the poll's slots to the recipient, with replies going to the
+// inbound address
+func sendEmailPoll(event *models.Event, owner *models.User, recipient models.EmailPollRecipient) {
+	inboundAddress := os.Getenv("INBOUND_EMAIL_ADDRESS")
+	if inboundAddress == "" {
+		inboundAddress = "create@timeful.app"
+	}
+	replyAddress := emailpoll.GetReplyAddress(inboundAddress, recipient.Key)
+	loc := utils.GetUserLocation(owner)
+	respondUrl := fmt.Sprintf("%s/api/events/%s/email-poll/%s", utils.GetBaseUrl(), event.GetId(), recipient.Key)
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s would like to know when you're available for \"%s\".\n\n", owner.FirstName, event.Name)
+	fmt.Fprintf(&body, "Reply to this email with the numbers of the times that work for you (e.g. \"1 and 3\"), \"all\", or \"none\". Or click the link of each time that works:\n\n")
+	for i, slot := range event.EmailPoll.Slots {
+		fmt.Fprintf(&body, "%d. %s\n   %s?slot=%d\n\n", i+1, emailpoll.FormatSlot(slot, loc), respondUrl, i+1)
+	}
+	fmt.Fprintf(&body, "None of these work: %s?slot=none\n", respondUrl)
+
+	// Attach a tentative hold for each slot, whose RSVPs come back to the reply address
+	attachments := make([]utils.EmailAttachment, 0)
+	if event.EmailPoll.AttachHolds {
+		fmt.Fprintf(&body, "\nYou can also accept the attached calendar hold of each time that works for you, and decline the others.\n")
+		for i := range event.EmailPoll.Slots {
+			invite, err := emailpoll.NewHoldInvite(event.Name, owner.FirstName, replyAddress, recipient, i, event.EmailPoll.Slots, time.Now())
+			if err != nil {
+				logger.StdErr.Println(err)
+				continue
+			}
+			attachments = append(attachments, utils.EmailAttachment{
+				Filename:    fmt.Sprintf("hold-%d.ics", i+1),
+				ContentType: "text/calendar; charset=utf-8; method=REQUEST",
+				Data:        invite,
+			})
+		}
+	}
+
+	err := utils.TrySendEmailWithReplyTo(
+		recipient.Email,
+		replyAddress,
+		fmt.Sprintf("When are you available for %s?", event.Name),
+		body.String(),
+		"text/plain",
+		attachments...,
+	)
+	if err != nil {
+		logger.StdErr.Printf("Failed to send email poll to %s: %v\n", recipient.Email, err)
+	}
+}
+
+// @Summary Responds to an email poll from the link of a slot
+// @Description Followed from the links in email poll emails. Adds the slot to the recipient's availability, or clears it if slot is "none"
+// @Tags events
+// @Produce plain
+// @Param eventId path string true "Event ID"
+// @Param key path string true "Recipient key from the link"
+// @Param slot query string true "Number of the slot, or none"
+// @Success 200
+// @Router /events/{eventId}/email-poll/{key} [get]
+func respondToEmailPollLink(c *gin.Context) {
+	query := struct {
+		Slot string `form:"slot" binding:"required"`
+	}{}
+	if err := c.Bind(&query); err != nil {
+		return
+	}
+
+	event := db.GetEventByEmailPollKey(c.Param("key"))
+	if event == nil || (event.Id.Hex() != c.Param("eventId") && utils.Coalesce(event.ShortId) != c.Param("eventId")) {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EmailPollNotFound})
+		return
+	}
+	recipient := findEmailPollRecipient(event, c.Param("key"))
+
+	selected := make([]int, 0)
+	if query.Slot != "none" {
+		number, err := strconv.Atoi(query.Slot)
+		if err != nil || number < 1 || number > len(event.EmailPoll.Slots) {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: "invalid slot"})
+			return
+		}
+		selected = append(selected, number-1)
+	}
+	clicked := selected
+	if len(selected) > 0 {
+		selected = append(getEmailPollSelection(event, recipient), selected...)
+	}
+	recordEmailPollResponse(event, recipient, selected)
+
+	eventUrl := fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId())
+	if len(clicked) == 0 {
+		c.String(http.StatusOK, fmt.Sprintf("Thanks! We've noted that none of the times work for you for %s.\n\nSee everyone's availability at %s", event.Name, eventUrl))
+		return
+	}
+	loc := time.UTC
+	if owner := db.GetUserById(event.OwnerId.Hex()); owner != nil {
+		loc = utils.GetUserLocation(owner)
+	}
+	c.String(http.StatusOK, fmt.Sprintf("Thanks! You're marked as available on %s for %s.\n\nClick the link of any other time that works, or see everyone's availability at %s", emailpoll.FormatSlot(event.EmailPoll.Slots[clicked[0]], loc), event.Name, eventUrl))
+}
+
+// Registers the reply to an email poll that was sent to the given address,
+// either written in the text or RSVPed to the holds in the calendar part.
+// Returns false if the address isn't the reply address of a poll
+func handleEmailPollReply(from *mail.Address, to []*mail.Address, text string, calendar string) bool {
+	for _, address := range to {
+		key := emailpoll.GetReplyKey(address.Address)
+		if len(key) == 0 {
+			continue
+		}
+
+		event := db.GetEventByEmailPollKey(key)
+		if event == nil {
+			return true
+		}
+		recipient := findEmailPollRecipient(event, key)
+		if !strings.EqualFold(recipient.Email, from.Address) {
+			logger.StdErr.Printf("Ignoring email poll reply for %s from %s\n", recipient.Email, from.Address)
+			return true
+		}
+
+		// RSVPs from the recipient's mail client answer one hold at a time
+		if holdReplies, err := emailpoll.ParseHoldReplies(strings.NewReader(calendar)); len(calendar) > 0 && err == nil {
+			selection := make(models.Set[int])
+			for _, slot := range getEmailPollSelection(event, recipient) {
+				selection[slot] = struct{}{}
+			}
+			for _, reply := range holdReplies {
+				if reply.Key != key || reply.Email != strings.ToLower(recipient.Email) || reply.Slot >= len(event.EmailPoll.Slots) {
+					continue
+				}
+				if reply.Available {
+					selection[reply.Slot] = struct{}{}
+				} else {
+					delete(selection, reply.Slot)
+				}
+			}
+			selected := make([]int, 0)
+			for slot := range selection {
+				selected = append(selected, slot)
+			}
+			recordEmailPollResponse(event, recipient, selected)
+			return true
+		}
+
+		selected, ok := emailpoll.ParseReply(text, len(event.EmailPoll.Slots))
+		if !ok {
+			utils.SendEmail(recipient.Email, fmt.Sprintf("Re: When are you available for %s?", event.Name),
+				"Sorry, we couldn't tell which times work for you. Please reply with the numbers of the times that work (e.g. \"1 and 3\"), \"all\", or \"none\".", "text/plain")
+			return true
+		}
+		recordEmailPollResponse(event, recipient, selected)
+		return true
+	}
+	return false
+}
+
+func findEmailPollRecipient(event *models.Event, key string) *models.EmailPollRecipient {
+	for i := range event.EmailPoll.Recipients {
+		if event.EmailPoll.Recipients[i].Key == key {
+			return &event.EmailPoll.Recipients[i]
+		}
+	}
+	return nil
+}
+
+// Returns the slots the recipient already picked
+func getEmailPollSelection(event *models.Event, recipient *models.EmailPollRecipient) []int {
+	_, existing := findResponse(db.GetEventResponses(event.Id.Hex()), recipient.Email)
+	if existing == nil {
+		return make([]int, 0)
+	}
+	return emailpoll.GetSelectedSlots(event.EmailPoll.Slots, existing.Availability, scheduling.GetTimeIncrement(event))
+}
+
+// Sets the recipient's guest response to the selected slots, and marks them
+// as having responded so they stop getting reminders
+func recordEmailPollResponse(event *models.Event, recipient *models.EmailPollRecipient, selected []int) {
+	increment := scheduling.GetTimeIncrement(event)
+	eventResponses := db.GetEventResponses(event.Id.Hex())
+	idx, existing := findResponse(eventResponses, recipient.Email)
+
+	response := models.Response{
+		Name:         recipient.Email,
+		Email:        recipient.Email,
+		Availability: emailpoll.GetSlotTimes(event.EmailPoll.Slots, selected, increment),
+		IfNeeded:     make([]primitive.DateTime, 0),
+	}
+	if existing != nil {
+		response.Answers = existing.Answers
+	}
+
+	if idx != -1 {
+		if _, err := db.EventResponsesCollection.UpdateByID(context.Background(), eventResponses[idx].Id, bson.M{"$set": bson.M{"response": &response}}); err != nil {
+			logger.StdErr.Panicln(err)
+		}
+		publishResponseChange(event, realtime.RESPONSE_UPDATED, recipient.Email)
+		enqueueResponseWebhook(event, models.WEBHOOK_RESPONSE_UPDATED, recipient.Email)
+	} else {
+		if _, err := db.EventResponsesCollection.InsertOne(context.Background(), models.EventResponse{
+			UserId:   recipient.Email,
+			Response: &response,
+			EventId:  event.Id,
+		}); err != nil {
+			logger.StdErr.Panicln(err)
+		}
+		if event.NumResponses != nil {
+			*event.NumResponses++
+		}
+		recordActivity(event, models.ACTIVITY_RESPONDED, nil, recipient.Email, nil)
+		publishResponseChange(event, realtime.RESPONSE_ADDED, recipient.Email)
+		enqueueResponseWebhook(event, models.WEBHOOK_RESPONSE_CREATED, recipient.Email)
+	}
+
+	respondedAt := primitive.NewDateTimeFromTime(time.Now())
+	recipient.RespondedAt = &respondedAt
+	if event.Remindees != nil {
+		index := utils.Find(*event.Remindees, func(r models.Remindee) bool {
+			return strings.EqualFold(r.Email, recipient.Email)
+		})
+		if index != -1 && !utils.Coalesce((*event.Remindees)[index].Responded) {
+			(*event.Remindees)[index].Responded = utils.TruePtr()
+			for _, taskId := range (*event.Remindees)[index].TaskIds {
+				gcloud.DeleteEmailTask(taskId)
+			}
+		}
+	}
+
+	update := bson.M{"emailPoll": event.EmailPoll, "numResponses": event.NumResponses}
+	if event.Remindees != nil {
+		update["remindees"] = event.Remindees
+	}
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{"$set": update})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
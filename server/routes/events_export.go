@@ -0,0 +1,317 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/forms"
+	"schej.it/server/services/privacy"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/services/spreadsheet"
+	"schej.it/server/utils"
+)
+
+// Most best times listed in the summary of the export
+const numExportedBestTimes = 20
+
+// A row of the responses export
+type exportedResponse struct {
+	UserId string `json:"userId"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+
+	// Names of the sign up blocks, only for sign up forms
+	SignUpBlocks []string `json:"signUpBlocks,omitempty"`
+
+	// Mapping from question label to the answer
+	Answers map[string]string `json:"answers"`
+
+	// Mapping from respondent field label to the value
+	Fields map[string]string `json:"fields"`
+
+	// Time increments the respondent is available, or available if needed,
+	// for. Not set for sign up forms
+	Availability []primitive.DateTime `json:"availability,omitempty"`
+	IfNeeded     []primitive.DateTime `json:"ifNeeded,omitempty"`
+}
+
+// Returns the event's responses with their answers, sorted by name, or by
+// the value of the groupBy respondent field (if set) and then name
+func getExportedResponses(event *models.Event, groupBy *models.RespondentField) []exportedResponse {
+	questions := utils.Coalesce(event.Questions)
+	rows := make([]exportedResponse, 0)
+	addRow := func(userId string, name string, email string, answers map[string][]string, fields map[string]string) *exportedResponse {
+		row := exportedResponse{UserId: userId, Name: name, Email: email, Answers: make(map[string]string), Fields: make(map[string]string)}
+		for _, question := range questions {
+			row.Answers[question.Label] = forms.FormatAnswer(answers, question)
+		}
+		for _, field := range event.RespondentFields {
+			row.Fields[field.Label] = fields[field.Id.Hex()]
+		}
+		if privacy.IsMinimized() {
+			row.Email = ""
+		}
+		rows = append(rows, row)
+		return &rows[len(rows)-1]
+	}
+
+	if utils.Coalesce(event.IsSignUpForm) {
+		blockNames := make(map[string]string)
+		for _, block := range utils.Coalesce(event.SignUpBlocks) {
+			blockNames[block.Id.Hex()] = block.Name
+		}
+		for userId, response := range event.SignUpResponses {
+			name, email := response.Name, response.Email
+			if user := db.GetUserById(userId); user != nil {
+				name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+				email = user.Email
+			}
+			row := addRow(userId, name, email, response.Answers, response.Fields)
+			row.SignUpBlocks = make([]string, 0)
+			for _, blockId := range response.SignUpBlockIds {
+				row.SignUpBlocks = append(row.SignUpBlocks, blockNames[blockId.Hex()])
+			}
+		}
+	} else {
+		for _, eventResponse := range db.GetEventResponses(event.Id.Hex()) {
+			response := eventResponse.Response
+			if response == nil {
+				continue
+			}
+			name, email := response.Name, response.Email
+			if user := db.GetUserById(eventResponse.UserId); user != nil {
+				name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+				email = user.Email
+			} else if len(response.Name) == 0 {
+				// User was deleted
+				continue
+			}
+			row := addRow(eventResponse.UserId, name, email, response.Answers, response.Fields)
+			row.Availability = response.Availability
+			row.IfNeeded = response.IfNeeded
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool { return strings.ToLower(rows[i].Name) < strings.ToLower(rows[j].Name) })
+	if groupBy != nil {
+		// Respondents without a value go last
+		sort.SliceStable(rows, func(i, j int) bool {
+			a, b := rows[i].Fields[groupBy.Label], rows[j].Fields[groupBy.Label]
+			if (a == "") != (b == "") {
+				return b == ""
+			}
+			return a < b
+		})
+	}
+	return rows
+}
+
+// Returns the label of the time increment's column, e.g. "Mon, Mar 2 3:00 PM"
+func getSlotLabel(event *models.Event, t time.Time, loc *time.Location) string {
+	t = t.In(loc)
+	if event.Type == models.DOW || event.Type == models.GROUP {
+		// Dates of weekly events are placeholders for the days of the week
+		if utils.Coalesce(event.DaysOnly) {
+			return t.Format("Monday")
+		}
+		return t.Format("Mon 3:04 PM")
+	}
+	if utils.Coalesce(event.DaysOnly) {
+		return t.Format("Mon, Jan 2")
+	}
+	return t.Format("Mon, Jan 2 3:04 PM")
+}
+
+// Returns the rows of the responses sheet: a row per respondent with their
+// details, answers, and availability for every time increment of the event
+func getResponsesSheet(event *models.Event, rows []exportedResponse, loc *time.Location) [][]interface{} {
+	isSignUpForm := utils.Coalesce(event.IsSignUpForm)
+	minimized := privacy.IsMinimized()
+	increments := make([]time.Time, 0)
+	if !isSignUpForm {
+		increments = scheduling.GetTimeIncrements(event)
+	}
+
+	header := []interface{}{"Name"}
+	if !minimized {
+		header = append(header, "Email")
+	}
+	if isSignUpForm {
+		header = append(header, "Slots")
+	}
+	for _, question := range utils.Coalesce(event.Questions) {
+		header = append(header, question.Label)
+	}
+	for _, field := range event.RespondentFields {
+		header = append(header, field.Label)
+	}
+	for _, t := range increments {
+		header = append(header, getSlotLabel(event, t, loc))
+	}
+
+	sheet := [][]interface{}{header}
+	for _, row := range rows {
+		record := []interface{}{row.Name}
+		if !minimized {
+			record = append(record, row.Email)
+		}
+		if isSignUpForm {
+			record = append(record, strings.Join(row.SignUpBlocks, ", "))
+		}
+		for _, question := range utils.Coalesce(event.Questions) {
+			record = append(record, row.Answers[question.Label])
+		}
+		for _, field := range event.RespondentFields {
+			record = append(record, row.Fields[field.Label])
+		}
+		available := utils.ArrayToSet(row.Availability)
+		ifNeeded := utils.ArrayToSet(row.IfNeeded)
+		for _, t := range increments {
+			key := primitive.NewDateTimeFromTime(t)
+			if _, ok := available[key]; ok {
+				record = append(record, "Available")
+			} else if _, ok := ifNeeded[key]; ok {
+				record = append(record, "If needed")
+			} else {
+				record = append(record, "")
+			}
+		}
+		sheet = append(sheet, record)
+	}
+	return sheet
+}
+
+// Returns the rows of the summary sheet: the times the most respondents can
+// make, with who can make them
+func getBestTimesSheet(event *models.Event, loc *time.Location) [][]interface{} {
+	sheet := [][]interface{}{{"Time", "Available", "If needed", "Unavailable", "Available respondents", "If needed respondents"}}
+	if utils.Coalesce(event.IsSignUpForm) {
+		return sheet
+	}
+
+	respondents := scheduling.GetRespondents(db.GetEventResponses(event.Id.Hex()))
+	names := make(map[string]string)
+	for _, respondent := range respondents {
+		names[respondent.Id] = respondent.Name
+	}
+	getNames := func(ids []string) string {
+		return strings.Join(utils.Map(ids, func(id string) string { return names[id] }), ", ")
+	}
+
+	for _, slot := range scheduling.RankSlots(event, respondents, scheduling.Options{}) {
+		if len(sheet) > numExportedBestTimes || len(slot.Available)+len(slot.IfNeeded) == 0 {
+			break
+		}
+		sheet = append(sheet, []interface{}{
+			getSlotLabel(event, slot.Start, loc),
+			len(slot.Available),
+			len(slot.IfNeeded),
+			len(slot.Unavailable),
+			getNames(slot.Available),
+			getNames(slot.IfNeeded),
+		})
+	}
+	return sheet
+}
+
+// Returns the rows of the grouped summary sheet: the time the most
+// respondents of each group can make
+func getGroupBestTimesSheet(event *models.Event, groupBy *models.RespondentField, loc *time.Location) [][]interface{} {
+	sheet := [][]interface{}{{groupBy.Label, "Respondents", "Best time", "Available", "If needed"}}
+	if utils.Coalesce(event.IsSignUpForm) {
+		return sheet
+	}
+
+	respondents := scheduling.GetRespondents(db.GetEventResponses(event.Id.Hex()))
+	for _, group := range scheduling.GroupRespondents(respondents, groupBy.Id.Hex()) {
+		record := []interface{}{group.Value, len(group.Respondents), "", 0, 0}
+		if slots := scheduling.RankSlots(event, group.Respondents, scheduling.Options{}); len(slots) > 0 && len(slots[0].Available)+len(slots[0].IfNeeded) > 0 {
+			record[2], record[3], record[4] = getSlotLabel(event, slots[0].Start, loc), len(slots[0].Available), len(slots[0].IfNeeded)
+		}
+		sheet = append(sheet, record)
+	}
+	return sheet
+}
+
+// @Summary Exports the event's responses
+// @Description Includes the respondents' contact details (unless the instance minimizes personal data), sign up blocks, answers to the event's questions, respondent fields, and availability. The csv and xlsx exports have a row per respondent and a column per time increment. The xlsx export has a second sheet with the best times, which the csv export returns instead if sheet is summary. If groupBy is set, respondents are sorted by that field, and the xlsx export has a third sheet with the best time for each of its values, which the csv export returns instead if sheet is groups
+// @Tags events
+// @Produce json
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param eventId path string true "Event ID"
+// @Param format query string false "json (default), csv or xlsx"
+// @Param sheet query string false "For csv exports, responses (default), summary, or groups"
+// @Param groupBy query string false "ID or label of the respondent field to group by"
+// @Success 200 {object} []exportedResponse
+// @Router /events/{eventId}/responses/export [get]
+func exportEventResponses(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" && format != "xlsx" {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "format must be json, csv or xlsx"})
+		return
+	}
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	var groupBy *models.RespondentField
+	if len(c.Query("groupBy")) > 0 {
+		groupBy = forms.GetField(event.RespondentFields, c.Query("groupBy"))
+		if groupBy == nil {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: "groupBy must be one of the event's respondent fields"})
+			return
+		}
+	}
+
+	rows := getExportedResponses(event, groupBy)
+	if format == "json" {
+		c.JSON(http.StatusOK, rows)
+		return
+	}
+	loc := utils.GetEventLocation(event, utils.GetAuthUser(c))
+
+	if format == "xlsx" {
+		var buf bytes.Buffer
+		sheets := []spreadsheet.Sheet{
+			{Name: "Responses", Rows: getResponsesSheet(event, rows, loc)},
+			{Name: "Best times", Rows: getBestTimesSheet(event, loc)},
+		}
+		if groupBy != nil {
+			sheets = append(sheets, spreadsheet.Sheet{Name: "Best times by " + groupBy.Label, Rows: getGroupBestTimesSheet(event, groupBy, loc)})
+		}
+		err := spreadsheet.WriteXlsx(&buf, sheets)
+		if err != nil {
+			logger.StdErr.Panicln(err)
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", event.Name+" responses.xlsx"))
+		c.Data(http.StatusOK, spreadsheet.CONTENT_TYPE, buf.Bytes())
+		return
+	}
+
+	sheet, filename := getResponsesSheet(event, rows, loc), event.Name+" responses.csv"
+	if c.Query("sheet") == "summary" {
+		sheet, filename = getBestTimesSheet(event, loc), event.Name+" best times.csv"
+	} else if c.Query("sheet") == "groups" && groupBy != nil {
+		sheet, filename = getGroupBestTimesSheet(event, groupBy, loc), fmt.Sprintf("%s best times by %s.csv", event.Name, groupBy.Label)
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Content-Type", "text/csv")
+	w := csv.NewWriter(c.Writer)
+	for _, row := range sheet {
+		w.Write(utils.Map(row, func(cell interface{}) string { return fmt.Sprint(cell) }))
+	}
+	w.Flush()
+}
@@ -0,0 +1,129 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/forms"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// Aggregated availability of the respondents with the same value for a
+// respondent field
+type heatmapGroup struct {
+	Value          string                   `json:"value"`
+	NumRespondents int                      `json:"numRespondents"`
+	Heatmap        []scheduling.HeatmapCell `json:"heatmap"`
+}
+
+// @Summary Sets the respondent fields of the event
+// @Description Respondent fields are structured details respondents provide with their response, e.g. "Grade" or "Team", that the heatmap (including the shared one) and exports can be grouped by. Fields with options only accept one of them. Existing values of removed fields are ignored
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{fields=[]models.RespondentField} true "The respondent fields"
+// @Success 200 {object} []models.RespondentField
+// @Router /events/{eventId}/respondent-fields [put]
+func setRespondentFields(c *gin.Context) {
+	payload := struct {
+		Fields []models.RespondentField `json:"fields" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	if err := forms.ValidateFields(payload.Fields); err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$set": bson.M{"respondentFields": payload.Fields},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.JSON(http.StatusOK, payload.Fields)
+}
+
+// @Summary Gets the event's availability heatmap, optionally grouped by a respondent field
+// @Description Returns how many respondents are available (or available if needed) at each time increment, for each value of the groupBy field, e.g. mentors and mentees separately. Respondents without a value are in the last group, with an empty value. values only returns the groups with those values. Without groupBy, everyone is in a single group
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param groupBy query string false "ID or label of the respondent field to group by"
+// @Param values query string false "Comma separated values of the groupBy field to return the groups of, e.g. mentor,mentee"
+// @Success 200 {object} []heatmapGroup
+// @Router /events/{eventId}/heatmap [get]
+func getGroupedHeatmap(c *gin.Context) {
+	event := getOrganizedEvent(c)
+	if event == nil {
+		return
+	}
+	groupBy, values, ok := getHeatmapGrouping(c, event)
+	if !ok {
+		return
+	}
+
+	respondents := scheduling.GetRespondents(db.GetEventResponses(event.Id.Hex()))
+	c.JSON(http.StatusOK, getHeatmapGroups(event, respondents, groupBy, values))
+}
+
+// Returns the respondent field to group the heatmap by and the values of the
+// groups to return, from the groupBy and values query parameters. Responds
+// with an error if groupBy isn't one of the event's fields
+func getHeatmapGrouping(c *gin.Context, event *models.Event) (*models.RespondentField, []string, bool) {
+	if len(c.Query("groupBy")) == 0 {
+		return nil, nil, true
+	}
+	groupBy := forms.GetField(event.RespondentFields, c.Query("groupBy"))
+	if groupBy == nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "groupBy must be one of the event's respondent fields"})
+		return nil, nil, false
+	}
+
+	values := make([]string, 0)
+	for _, value := range strings.Split(c.Query("values"), ",") {
+		if value = strings.TrimSpace(value); len(value) > 0 {
+			values = append(values, value)
+		}
+	}
+	return groupBy, values, true
+}
+
+// Returns the heatmap of each group of respondents with the same value for
+// the groupBy field, only for the given values if any. Everyone is in a single
+// group if groupBy is nil
+func getHeatmapGroups(event *models.Event, respondents []scheduling.Respondent, groupBy *models.RespondentField, values []string) []heatmapGroup {
+	groups := []scheduling.RespondentGroup{{Respondents: respondents}}
+	if groupBy != nil {
+		groups = scheduling.GroupRespondents(respondents, groupBy.Id.Hex())
+	}
+
+	result := make([]heatmapGroup, 0)
+	for _, group := range groups {
+		if len(values) > 0 && utils.Find(values, func(value string) bool { return strings.EqualFold(value, group.Value) }) == -1 {
+			continue
+		}
+		result = append(result, heatmapGroup{
+			Value:          group.Value,
+			NumRespondents: len(group.Respondents),
+			Heatmap:        scheduling.GetHeatmap(event, group.Respondents),
+		})
+	}
+	return result
+}
@@ -0,0 +1,356 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/googlechat"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services/approvals"
+	"schej.it/server/services/auth"
+	"schej.it/server/services/calendar"
+	"schej.it/server/services/hooks"
+	"schej.it/server/services/meetingcost"
+	"schej.it/server/services/resources"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// Summary of a finalized event, returned when finalizing it
+type finalizationSummary struct {
+	ScheduledEvent *models.CalendarEvent `json:"scheduledEvent"`
+
+	// Names of the respondents that can and can't make the scheduled time
+	Available   []string `json:"available"`
+	Unavailable []string `json:"unavailable"`
+
+	CostEstimate meetingcost.Estimate `json:"costEstimate"`
+
+	// Conflicts that were ignored when finalizing
+	Conflicts []scheduling.Conflict `json:"conflicts,omitempty"`
+
+	// Resources booked for the scheduled time
+	Resources []models.Resource `json:"resources,omitempty"`
+}
+
+// @Summary Finalizes the event at the given time
+// @Description Sets the scheduled time of the event (clearing any cancellations of the previous time), books the given resources, announces it in the Google Chat spaces the event was shared to, adds it to the organizer's Outlook calendar if they connected one, and returns a summary including an estimated meeting cost. If the time overlaps another of the organizer's scheduled events or calendar entries, nothing is changed and a 409 is returned with the conflicts, unless ignoreConflicts is true. A 409 is always returned if one of the resources is already busy, or if a beforeFinalize hook rejects the finalization (with its reason). If the event has finalization approvers, nothing is changed until enough of them approve it, and a 202 is returned with the pending finalization
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{startDate=string,endDate=string,required=[]string,resourceIds=[]string,hourlyRate=float64,ignoreConflicts=bool,inviteAttendees=bool} true "Start and end of the scheduled time, ids of the respondents that must attend and of the resources to book (both default to the previous ones), an optional hourly rate for the cost estimate (defaults to the organization's), whether to finalize despite conflicts, and whether to invite the respondents from the organizer's calendar"
+// @Success 200 {object} finalizationSummary
+// @Success 202 {object} object{pendingFinalization=models.PendingFinalization}
+// @Failure 409 {object} object{error=string,conflicts=[]scheduling.Conflict,resources=[]models.Resource,reason=string}
+// @Router /events/{eventId}/finalize [post]
+func finalizeEvent(c *gin.Context) {
+	payload := struct {
+		StartDate   primitive.DateTime    `json:"startDate" binding:"required"`
+		EndDate     primitive.DateTime    `json:"endDate" binding:"required"`
+		Required    []string              `json:"required"`
+		ResourceIds *[]primitive.ObjectID `json:"resourceIds"`
+		HourlyRate  *float64              `json:"hourlyRate"`
+
+		IgnoreConflicts bool `json:"ignoreConflicts"`
+
+		// Whether to invite the respondents from the organizer's calendar
+		InviteAttendees bool `json:"inviteAttendees"`
+	}{}
+	if err := c.Bind(&payload); err != nil {
+		return
+	}
+	if payload.EndDate <= payload.StartDate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "endDate must be after startDate"})
+		return
+	}
+
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	// Warn the organizer instead of double booking them
+	conflicts := scheduling.FindConflicts(user, event, payload.StartDate.Time(), payload.EndDate.Time())
+	if len(conflicts) > 0 && !payload.IgnoreConflicts {
+		c.JSON(http.StatusConflict, gin.H{"error": errs.ScheduleConflict, "conflicts": conflicts})
+		return
+	}
+
+	// Make sure the resources are free, ignoring bookings for the previous time
+	if payload.ResourceIds != nil {
+		event.ResourceIds = *payload.ResourceIds
+	}
+	resourcesList, ok := getOwnedResources(c, user, event.ResourceIds)
+	if !ok {
+		return
+	}
+	busy := resources.GetBusyTimes(resourcesList, payload.StartDate.Time(), payload.EndDate.Time(), event.Id)
+	unavailable := make([]models.Resource, 0)
+	for _, resource := range resourcesList {
+		if !resources.IsFree(busy[resource.Id.Hex()], payload.StartDate.Time(), payload.EndDate.Time()) {
+			unavailable = append(unavailable, resource)
+		}
+	}
+	if len(unavailable) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": errs.ResourceUnavailable, "resources": unavailable})
+		return
+	}
+
+	// Let the beforeFinalize hooks reject the finalization
+	if err := runBeforeFinalizeHooks(event, user, payload.StartDate.Time(), payload.EndDate.Time(), payload.Required); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": errs.FinalizeRejectedByHook, "reason": err.Error()})
+		return
+	}
+
+	pending := models.PendingFinalization{
+		StartDate:       payload.StartDate,
+		EndDate:         payload.EndDate,
+		Required:        payload.Required,
+		ResourceIds:     event.ResourceIds,
+		InviteAttendees: payload.InviteAttendees,
+		RequestedBy:     user.Id,
+		RequestedAt:     primitive.NewDateTimeFromTime(time.Now()),
+		ApprovedBy:      make([]primitive.ObjectID, 0),
+	}
+
+	// Wait for the approvers to approve the finalization first
+	if event.FinalizationApprovers != nil {
+		if approvals.IsApprover(event.FinalizationApprovers, user.Id) {
+			pending.ApprovedBy = append(pending.ApprovedBy, user.Id)
+		}
+		if !approvals.IsApproved(event.FinalizationApprovers, &pending) {
+			requestFinalizationApproval(event, user, &pending)
+			c.JSON(http.StatusAccepted, gin.H{"pendingFinalization": pending})
+			return
+		}
+	}
+
+	completeFinalization(event, user, &pending, resourcesList)
+
+	summary := getFinalizationSummary(event, payload.HourlyRate)
+	summary.Conflicts = conflicts
+	summary.Resources = resourcesList
+	c.JSON(http.StatusOK, summary)
+}
+
+// Calls the beforeFinalize hooks, returning why they rejected finalizing the
+// event at the given time, if they did
+func runBeforeFinalizeHooks(event *models.Event, user *models.User, start time.Time, end time.Time, required []string) error {
+	return hooks.Run(hooks.BEFORE_FINALIZE, map[string]interface{}{
+		"event":       getWebhookEventData(event),
+		"startDate":   start,
+		"endDate":     end,
+		"required":    required,
+		"requestedBy": user.Id.Hex(),
+	})
+}
+
+// Sets the scheduled time of the event, books the resources, and announces it
+func completeFinalization(event *models.Event, user *models.User, pending *models.PendingFinalization, resourcesList []models.Resource) {
+	event.ScheduledEvent = &models.CalendarEvent{
+		Summary:   event.Name,
+		StartDate: pending.StartDate,
+		EndDate:   pending.EndDate,
+	}
+	if pending.Required != nil {
+		event.RequiredAttendees = pending.Required
+	}
+	event.ResourceIds = pending.ResourceIds
+	event.Cancellations = nil
+	event.Sessions = nil
+	event.PendingFinalization = nil
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$set": bson.M{
+			"scheduledEvent":    event.ScheduledEvent,
+			"requiredAttendees": event.RequiredAttendees,
+			"resourceIds":       event.ResourceIds,
+		},
+		"$unset": bson.M{"cancellations": "", "sessions": "", "pendingFinalization": ""},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	bookings := make([]models.ResourceBooking, 0)
+	for _, resource := range resourcesList {
+		bookings = append(bookings, models.ResourceBooking{
+			ResourceId: resource.Id,
+			StartDate:  pending.StartDate,
+			EndDate:    pending.EndDate,
+		})
+	}
+	db.SetEventResourceBookings(event.Id, bookings)
+	recordActivity(event, models.ACTIVITY_FINALIZED, user, "", nil)
+	enqueueFinalizedWebhook(event)
+	postFinalizedToIssueTracker(event)
+
+	// Announce the scheduled time
+	go func() {
+		// Recover from panics
+		defer func() {
+			if err := recover(); err != nil {
+				logger.StdErr.Println(err)
+			}
+		}()
+
+		googlechat.SendEventFinalizedMessage(event)
+		writeScheduledEvent(user, event, pending.InviteAttendees)
+	}()
+}
+
+// Writes the scheduled event to the organizer's calendar, preferring their
+// primary account, and updates the calendar event written when the event was
+// finalized before. Does nothing if none of their calendars can be written to
+func writeScheduledEvent(user *models.User, event *models.Event, inviteAttendees bool) {
+	keys := make([]string, 0)
+	for key := range user.CalendarAccounts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		isPrimary := func(key string) bool { return user.PrimaryAccountKey != nil && *user.PrimaryAccountKey == key }
+		if isPrimary(keys[i]) != isPrimary(keys[j]) {
+			return isPrimary(keys[i])
+		}
+		return keys[i] < keys[j]
+	})
+
+	for _, key := range keys {
+		if calendar.GetCalendarWriter(user.CalendarAccounts[key]) == nil {
+			continue
+		}
+		auth.RefreshUserTokenIfNecessary(user, models.Set[string]{key: {}})
+		writer := calendar.GetCalendarWriter(user.CalendarAccounts[key])
+
+		attendeeEmails := make([]string, 0)
+		if inviteAttendees {
+			for _, respondent := range scheduling.GetRespondents(db.GetEventResponses(event.Id.Hex())) {
+				if len(respondent.Email) > 0 && !strings.EqualFold(respondent.Email, user.Email) {
+					attendeeEmails = append(attendeeEmails, respondent.Email)
+				}
+			}
+		}
+		calendarEventId, err := writer.WriteEvent(event.CalendarEventId, calendar.WritableEvent{
+			Summary:        event.Name,
+			Description:    fmt.Sprintf("Scheduled with Timeful: %s/e/%s", utils.GetBaseUrl(), event.GetId()),
+			Start:          event.ScheduledEvent.StartDate.Time(),
+			End:            event.ScheduledEvent.EndDate.Time(),
+			AttendeeEmails: attendeeEmails,
+		})
+		if err != nil {
+			logger.StdErr.Println(err)
+			return
+		}
+
+		if calendarEventId != event.CalendarEventId {
+			event.CalendarEventId = calendarEventId
+			_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+				"$set": bson.M{"calendarEventId": calendarEventId},
+			})
+			if err != nil {
+				logger.StdErr.Panicln(err)
+			}
+		}
+		return
+	}
+}
+
+// Returns the summary of the given finalized event
+func getFinalizationSummary(event *models.Event, hourlyRate *float64) finalizationSummary {
+	start := event.ScheduledEvent.StartDate.Time()
+	end := event.ScheduledEvent.EndDate.Time()
+
+	summary := finalizationSummary{
+		ScheduledEvent: event.ScheduledEvent,
+		Available:      make([]string, 0),
+		Unavailable:    make([]string, 0),
+	}
+	increment := scheduling.GetTimeIncrement(event)
+	for _, respondent := range scheduling.GetRespondents(db.GetEventResponses(event.Id.Hex())) {
+		if respondent.IsAvailable(start, end, increment, true) {
+			summary.Available = append(summary.Available, respondent.Name)
+		} else {
+			summary.Unavailable = append(summary.Unavailable, respondent.Name)
+		}
+	}
+	summary.CostEstimate = meetingcost.Calculate(len(summary.Available), end.Sub(start), getHourlyRate(event, hourlyRate))
+
+	return summary
+}
+
+// Returns the hourly rate of the event's cost estimate, the given one or else
+// the one set by the event's organization. Nil uses the default hourly rate
+func getHourlyRate(event *models.Event, hourlyRate *float64) *float64 {
+	if hourlyRate != nil || event.OrganizationId.IsZero() {
+		return hourlyRate
+	}
+	if org := db.GetOrganizationById(event.OrganizationId.Hex()); org != nil {
+		return org.Settings.HourlyRate
+	}
+	return nil
+}
+
+// @Summary Estimates the cost of the event's meeting
+// @Description Defaults to the scheduled time and the respondents that can make it if the event is finalized, otherwise to all respondents and an hour. The hourly rate defaults to the one set by the event's organization, or else MEETING_COST_DEFAULT_HOURLY_RATE
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param attendees query int false "Number of attendees"
+// @Param durationMinutes query int false "Length of the meeting in minutes"
+// @Param hourlyRate query number false "Hourly rate per attendee"
+// @Success 200 {object} meetingcost.Estimate
+// @Router /events/{eventId}/cost-estimate [get]
+func getMeetingCostEstimate(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	// Determine defaults
+	attendees := utils.Coalesce(event.NumResponses)
+	duration := time.Hour
+	if event.ScheduledEvent != nil {
+		summary := getFinalizationSummary(event, nil)
+		attendees = summary.CostEstimate.Attendees
+		duration = time.Duration(summary.CostEstimate.DurationMinutes) * time.Minute
+	}
+
+	// Override defaults with query params
+	if attendeesString := c.Query("attendees"); attendeesString != "" {
+		var err error
+		if attendees, err = strconv.Atoi(attendeesString); err != nil || attendees < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attendees"})
+			return
+		}
+	}
+	if durationString := c.Query("durationMinutes"); durationString != "" {
+		minutes, err := strconv.Atoi(durationString)
+		if err != nil || minutes <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid durationMinutes"})
+			return
+		}
+		duration = time.Duration(minutes) * time.Minute
+	}
+	var hourlyRate *float64
+	if hourlyRateString := c.Query("hourlyRate"); hourlyRateString != "" {
+		rate, err := strconv.ParseFloat(hourlyRateString, 64)
+		if err != nil || rate < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid hourlyRate"})
+			return
+		}
+		hourlyRate = &rate
+	}
+
+	c.JSON(http.StatusOK, meetingcost.Calculate(attendees, duration, getHourlyRate(event, hourlyRate)))
+}
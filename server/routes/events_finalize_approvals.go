@@ -0,0 +1,242 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/approvals"
+	"schej.it/server/services/slack"
+	"schej.it/server/slackbot"
+	"schej.it/server/utils"
+)
+
+// @Summary Sets the co-organizers that have to approve finalizing the event
+// @Description Once set, finalizing the event waits until quorum of the approvers approve it, from the Slack buttons they're sent or the review endpoint. Approvers have to be co-organizers with a Timeful account. Pass null approvers to remove them, which also drops a pending finalization
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{approvers=models.FinalizationApprovers} true "User ids of the approvers and the number of approvals needed"
+// @Success 200 {object} models.FinalizationApprovers
+// @Router /events/{eventId}/finalization-approvers [put]
+func setFinalizationApprovers(c *gin.Context) {
+	payload := struct {
+		Approvers *models.FinalizationApprovers `json:"approvers"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	if payload.Approvers != nil {
+		if err := approvals.Validate(payload.Approvers, event); err != nil {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+			return
+		}
+	}
+
+	update := bson.M{"$set": bson.M{"finalizationApprovers": payload.Approvers}}
+	if payload.Approvers == nil {
+		update = bson.M{"$unset": bson.M{"finalizationApprovers": "", "pendingFinalization": ""}}
+	}
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, update)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.JSON(http.StatusOK, payload.Approvers)
+}
+
+// @Summary Approves or rejects the event's pending finalization
+// @Description Available to the approvers. The event is finalized once quorum of the approvers approve it, and a single rejection drops the pending finalization
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{approve=bool} true "Whether to approve or reject the finalization"
+// @Success 200 {object} object{finalized=bool}
+// @Router /events/{eventId}/finalize/review [post]
+func reviewFinalization(c *gin.Context) {
+	payload := struct {
+		Approve *bool `json:"approve" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+
+	event := getOrganizedEvent(c)
+	if event == nil {
+		return
+	}
+	user := utils.GetAuthUser(c)
+	if !approvals.IsApprover(event.FinalizationApprovers, user.Id) {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotFinalizationApprover})
+		return
+	}
+
+	finalized, ok := ReviewFinalization(event, user, *payload.Approve)
+	if !ok {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.FinalizationNotPending})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"finalized": finalized})
+}
+
+// @Summary Cancels the event's pending finalization
+// @Tags events
+// @Param eventId path string true "Event ID"
+// @Success 200
+// @Router /events/{eventId}/finalize/pending [delete]
+func cancelPendingFinalization(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	if event.PendingFinalization == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.FinalizationNotPending})
+		return
+	}
+
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$unset": bson.M{"pendingFinalization": ""},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// Saves the pending finalization, replacing any previous one, and asks the
+// approvers that haven't approved it yet to review it
+func requestFinalizationApproval(event *models.Event, requester *models.User, pending *models.PendingFinalization) {
+	event.PendingFinalization = pending
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$set": bson.M{"pendingFinalization": pending},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	go func() {
+		// Recover from panics
+		defer func() {
+			if err := recover(); err != nil {
+				logger.StdErr.Println(err)
+			}
+		}()
+
+		eventUrl := fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId())
+		for _, approverId := range event.FinalizationApprovers.UserIds {
+			if utils.Contains(pending.ApprovedBy, approverId) {
+				continue
+			}
+
+			// Email approvers that can't approve it from Slack
+			if slackbot.SendFinalizationApprovalRequest(approverId, requester, event) {
+				continue
+			}
+			if approver := db.GetUserById(approverId.Hex()); approver != nil {
+				body := fmt.Sprintf("%s wants to finalize \"%s\" on %s.\n\nApprove or reject it here: %s\n", requester.FirstName, event.Name, pending.StartDate.Time().UTC().Format("Mon Jan 2, 15:04 MST"), eventUrl)
+				utils.SendEmail(approver.Email, fmt.Sprintf("Approve finalizing %s", event.Name), body, "text/plain")
+			}
+		}
+	}()
+}
+
+// Approves or rejects the event's pending finalization on behalf of the
+// approver, finalizing the event once quorum of the approvers approved it.
+// Returns whether the event was finalized, and false for ok if the
+// finalization is no longer waiting for the approver
+func ReviewFinalization(event *models.Event, approver *models.User, approve bool) (bool, bool) {
+	pending := event.PendingFinalization
+	if pending == nil || !approvals.IsApprover(event.FinalizationApprovers, approver.Id) {
+		return false, false
+	}
+
+	// Only review the finalization the approver was asked about, not one
+	// requested since
+	filter := bson.M{"_id": event.Id, "pendingFinalization.requestedAt": pending.RequestedAt}
+	if !approve {
+		result, err := db.EventsCollection.UpdateOne(context.Background(), filter, bson.M{"$unset": bson.M{"pendingFinalization": ""}})
+		if err != nil {
+			logger.StdErr.Panicln(err)
+		}
+		if result.ModifiedCount == 0 {
+			return false, false
+		}
+		go notifyFinalizationRejected(event, approver, pending)
+		return false, true
+	}
+
+	var updated models.Event
+	err := db.EventsCollection.FindOneAndUpdate(context.Background(), filter, bson.M{
+		"$addToSet": bson.M{"pendingFinalization.approvedBy": approver.Id},
+	}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return false, false
+	} else if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	if !approvals.IsApproved(updated.FinalizationApprovers, updated.PendingFinalization) {
+		return false, true
+	}
+
+	// Only the approval that claims the finalization completes it
+	result, err := db.EventsCollection.UpdateOne(context.Background(), filter, bson.M{"$unset": bson.M{"pendingFinalization": ""}})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	if result.ModifiedCount == 0 {
+		return false, true
+	}
+
+	requester := db.GetUserById(pending.RequestedBy.Hex())
+	if requester == nil {
+		requester = approver
+	}
+	completeFinalization(&updated, requester, updated.PendingFinalization, db.GetResourcesByIds(updated.PendingFinalization.ResourceIds))
+	return true, true
+}
+
+// Tells the organizer that requested the finalization that it was rejected
+func notifyFinalizationRejected(event *models.Event, approver *models.User, pending *models.PendingFinalization) {
+	// Recover from panics
+	defer func() {
+		if err := recover(); err != nil {
+			logger.StdErr.Println(err)
+		}
+	}()
+
+	requester := db.GetUserById(pending.RequestedBy.Hex())
+	if requester == nil || requester.Id == approver.Id {
+		return
+	}
+
+	eventUrl := fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId())
+	slackText := fmt.Sprintf("%s rejected finalizing <%s|%s>", approver.FirstName, eventUrl, event.Name)
+	accounts := db.GetSlackAccountsByUserId(requester.Id)
+	for _, account := range accounts {
+		if err := slack.PostMessage(account.SlackUserId, slackText, nil); err != nil {
+			logger.StdErr.Println(err)
+		}
+	}
+	if len(accounts) == 0 {
+		body := fmt.Sprintf("%s rejected finalizing \"%s\". View it here: %s\n", approver.FirstName, event.Name, eventUrl)
+		utils.SendEmail(requester.Email, fmt.Sprintf("Finalizing %s was rejected", event.Name), body, "text/plain")
+	}
+}
@@ -0,0 +1,54 @@
+package routes
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/responses"
+	"schej.it/server/services/holidays"
+	"schej.it/server/utils"
+)
+
+// @Summary Gets the public holidays that fall on the event's dates
+// @Description Uses the country and region in the event's holiday settings. Returns an empty array if none are set
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param timezoneOffset query int false "Client's timezone offset in minutes, used to determine the local dates of the event"
+// @Success 200 {object} []holidays.Holiday
+// @Router /events/{eventId}/holidays [get]
+func getEventHolidays(c *gin.Context) {
+	query := struct {
+		TimezoneOffset *int `form:"timezoneOffset"`
+	}{}
+	if err := c.Bind(&query); err != nil {
+		return
+	}
+
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+
+	loc := time.UTC
+	if query.TimezoneOffset != nil {
+		loc = time.FixedZone("UserOffset", -*query.TimezoneOffset*60)
+	} else if userId, signedIn := utils.GetUserId(c); signedIn {
+		if user := db.GetUserById(userId); user != nil {
+			loc = utils.GetUserLocation(user)
+		}
+	}
+
+	result := make([]holidays.Holiday, 0)
+	for _, holiday := range holidays.GetHolidaysForEvent(event, loc) {
+		result = append(result, holiday)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Date < result[j].Date })
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,132 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/ics"
+	"schej.it/server/services/privacy"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// @Summary Gets the finalized times of the event as an iCalendar file
+// @Description Contains the scheduled time, or each session if the event was finalized into sessions. Session attendees are listed by name unless blind availability is enabled
+// @Tags events
+// @Produce text/calendar
+// @Param eventId path string true "Event ID"
+// @Param respondent query string false "Only include the sessions this respondent attends (user id, or name for guests)"
+// @Success 200 {string} string "iCalendar file"
+// @Router /events/{eventId}/ics [get]
+func getEventIcs(c *gin.Context) {
+	query := struct {
+		Respondent string `form:"respondent"`
+	}{}
+	if err := c.BindQuery(&query); err != nil {
+		return
+	}
+
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+	if event.ScheduledEvent == nil && len(event.Sessions) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event has not been scheduled"})
+		return
+	}
+
+	sessionUserId, _ := utils.GetUserId(c)
+	withAttendees := (!utils.Coalesce(event.BlindAvailabilityEnabled) || sessionUserId == event.OwnerId.Hex()) && getResponseVisibility(c, event) == privacy.VISIBLE_ALL
+	data, err := ics.Encode(event.Name, ics.GetEvents(event, query.Respondent, withAttendees, time.Now()))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.ics\"", event.GetId()))
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", data)
+}
+
+// @Summary Imports busy times from a public iCalendar feed
+// @Description For respondents without a connected calendar, e.g. a school timetable or sports schedule. Returns the busy times on the event's dates, and the times on the event's grid that don't overlap them, to fill in the respondent's availability with. Feeds are cached and refreshed periodically, so importing again picks up changes. Doesn't require signing in
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{url=string} true "Public http(s) or webcal url of the feed"
+// @Success 200 {object} object{busyTimes=[]models.CalendarEvent,availability=[]string,fetchedAt=string}
+// @Router /events/{eventId}/ics-import [post]
+func importEventIcs(c *gin.Context) {
+	payload := struct {
+		Url string `json:"url" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+	if event.Type == models.DOW {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "busy times can only be imported for events on specific dates"})
+		return
+	}
+	feedUrl, err := ics.NormalizeUrl(payload.Url)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	feed, err := ics.GetFeed(feedUrl, time.Now())
+	if err != nil {
+		if errors.Is(err, ics.ErrInvalid) {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: errs.IcsFeedInvalid})
+		} else {
+			c.JSON(http.StatusBadGateway, responses.Error{Error: errs.IcsFeedUnreachable})
+		}
+		return
+	}
+
+	increments := scheduling.GetTimeIncrements(event)
+	increment := scheduling.GetTimeIncrement(event)
+	if utils.Coalesce(event.DaysOnly) {
+		increment = 24 * time.Hour
+	}
+	busyTimes := make([]models.CalendarEvent, 0)
+	if len(increments) > 0 {
+		timeMin, timeMax := increments[0], increments[len(increments)-1].Add(increment)
+		for _, busy := range feed.BusyTimes {
+			if busy.EndDate.Time().After(timeMin) && busy.StartDate.Time().Before(timeMax) {
+				busyTimes = append(busyTimes, busy)
+			}
+		}
+	}
+
+	// All day events don't block availability, same as calendar autofill
+	availability := make([]primitive.DateTime, 0)
+	for _, t := range increments {
+		available := true
+		for _, busy := range busyTimes {
+			if !busy.AllDay && busy.StartDate.Time().Before(t.Add(increment)) && busy.EndDate.Time().After(t) {
+				available = false
+				break
+			}
+		}
+		if available {
+			availability = append(availability, primitive.NewDateTimeFromTime(t))
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"busyTimes": busyTimes, "availability": availability, "fetchedAt": feed.FetchedAt})
+}
@@ -0,0 +1,192 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/i18n"
+	"schej.it/server/services/invites"
+	"schej.it/server/utils"
+)
+
+// Largest roster file accepted
+const maxRosterSize = 1 << 20
+
+// @Summary Emails personal invites to the invitees of a CSV roster
+// @Description The roster has an email column, and optionally name and locale columns (see invites.ParseRoster). Each invitee gets an email in their locale (or the given one) with a personal link to the event, sent in batches in the background. Rows with invalid emails are skipped and returned as invalidRows
+// @Tags events
+// @Accept multipart/form-data
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param file formData file true "CSV roster"
+// @Param locale formData string false "Locale of invitees without one in the roster, defaults to en"
+// @Param message formData string false "Note added to every email"
+// @Success 200 {object} object{batch=models.InviteBatch,invalidRows=[]invites.RowError}
+// @Router /events/{eventId}/invites [post]
+func createInviteBatch(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	owner := utils.GetAuthUser(c)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "file is required"})
+		return
+	}
+	if fileHeader.Size > maxRosterSize {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "roster is too large"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "file is required"})
+		return
+	}
+	defer file.Close()
+
+	invitees, rowErrors, err := invites.ParseRoster(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+	if len(invitees) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errs.RosterEmpty, "invalidRows": rowErrors})
+		return
+	}
+
+	batch := models.InviteBatch{
+		EventId:   event.Id,
+		OwnerId:   owner.Id,
+		Locale:    i18n.Resolve(c.PostForm("locale")),
+		Message:   strings.TrimSpace(c.PostForm("message")),
+		Status:    models.INVITE_BATCH_SENDING,
+		CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+		Invitees:  invitees,
+	}
+	db.InsertInviteBatch(&batch)
+
+	c.JSON(http.StatusOK, gin.H{"batch": batch, "invalidRows": rowErrors})
+}
+
+// Marks the invitees that responded to the event
+func setInviteesResponded(event *models.Event, batches []models.InviteBatch) {
+	responded := make(models.Set[string])
+	for _, eventResponse := range db.GetEventResponses(event.Id.Hex()) {
+		email := ""
+		if user := db.GetUserById(eventResponse.UserId); user != nil {
+			email = user.Email
+		} else if eventResponse.Response != nil {
+			email = eventResponse.Response.Email
+		}
+		responded[strings.ToLower(email)] = struct{}{}
+	}
+	for userId, response := range event.SignUpResponses {
+		email := response.Email
+		if user := db.GetUserById(userId); user != nil {
+			email = user.Email
+		}
+		responded[strings.ToLower(email)] = struct{}{}
+	}
+
+	for i := range batches {
+		for j := range batches[i].Invitees {
+			_, batches[i].Invitees[j].Responded = responded[batches[i].Invitees[j].Email]
+		}
+	}
+}
+
+// @Summary Gets the event's invite batches
+// @Description Includes the delivery status of every invite, when the invitee opened their link, and whether they responded
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} []models.InviteBatch
+// @Router /events/{eventId}/invites [get]
+func getInviteBatches(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	batches := db.GetEventInviteBatches(event.Id)
+	setInviteesResponded(event, batches)
+
+	c.JSON(http.StatusOK, batches)
+}
+
+// @Summary Gets an invite batch of the event
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param batchId path string true "Invite batch ID"
+// @Success 200 {object} models.InviteBatch
+// @Router /events/{eventId}/invites/{batchId} [get]
+func getInviteBatch(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	batch := db.GetInviteBatchById(event.Id, c.Param("batchId"))
+	if batch == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.InviteBatchNotFound})
+		return
+	}
+	batches := []models.InviteBatch{*batch}
+	setInviteesResponded(event, batches)
+
+	c.JSON(http.StatusOK, batches[0])
+}
+
+// @Summary Stops sending the invites of a batch that haven't been sent yet
+// @Tags events
+// @Param eventId path string true "Event ID"
+// @Param batchId path string true "Invite batch ID"
+// @Success 200
+// @Router /events/{eventId}/invites/{batchId}/cancel [post]
+func cancelInviteBatch(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	batch := db.GetInviteBatchById(event.Id, c.Param("batchId"))
+	if batch == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.InviteBatchNotFound})
+		return
+	}
+	if !db.CancelInviteBatch(batch.Id, time.Now()) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "invites have already been sent"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Opens the event from an invitee's personal link
+// @Description Followed from invite emails. Records when the invitee first opened the link and redirects to the event
+// @Tags events
+// @Param eventId path string true "Event ID"
+// @Param key path string true "Invitee key from the link"
+// @Success 302
+// @Router /events/{eventId}/invite/{key} [get]
+func openInvite(c *gin.Context) {
+	eventId := c.Param("eventId")
+	if batchEventId := db.SetInviteOpened(c.Param("key"), time.Now()); batchEventId != nil {
+		if event := db.GetEventById(batchEventId.Hex()); event != nil {
+			eventId = event.GetId()
+		}
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), eventId))
+}
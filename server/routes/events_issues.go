@@ -0,0 +1,142 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/issuetracker"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// Returns the enabled issue tracker of the folder the owner put the event in,
+// or nil if there is none
+func getEventIssueTracker(event *models.Event) *models.IssueTracker {
+	folder := db.GetEventFolder(event.Id, event.OwnerId)
+	if folder == nil || folder.IssueTracker == nil || !folder.IssueTracker.Enabled {
+		return nil
+	}
+	return folder.IssueTracker
+}
+
+func setEventIssueLink(event *models.Event, link *models.IssueLink) {
+	event.IssueLink = link
+	update := bson.M{"$set": bson.M{"issueLink": link}}
+	if link == nil {
+		update = bson.M{"$unset": bson.M{"issueLink": ""}}
+	}
+	if _, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, update); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// @Summary Links the event to an issue in its folder's issue tracker
+// @Description The scheduled time and attendees are posted as a comment on the issue when the event is finalized. The event has to be in a folder with an issue tracker
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{issueKey=string} true "Key of the issue, e.g. ENG-123"
+// @Success 200 {object} models.IssueLink
+// @Router /events/{eventId}/issue [put]
+func linkEventIssue(c *gin.Context) {
+	payload := struct {
+		IssueKey string `json:"issueKey" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	tracker := getEventIssueTracker(event)
+	if tracker == nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.IssueTrackerNotConfigured})
+		return
+	}
+	client, err := issuetracker.NewClient(tracker)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	link, err := client.GetIssue(strings.TrimSpace(payload.IssueKey))
+	if err != nil {
+		if trackerErr, ok := err.(*issuetracker.Error); ok && trackerErr.Status == http.StatusNotFound {
+			c.JSON(http.StatusNotFound, responses.Error{Error: errs.IssueNotFound})
+			return
+		}
+		logger.StdErr.Println(err)
+		c.JSON(http.StatusBadGateway, responses.Error{Error: errs.IssueTrackerUnauthorized})
+		return
+	}
+	if event.IssueLink != nil && event.IssueLink.Type == link.Type && event.IssueLink.IssueKey == link.IssueKey {
+		// Keep the comment so it's updated instead of posted again
+		link.CommentId = event.IssueLink.CommentId
+	}
+	setEventIssueLink(event, link)
+
+	c.JSON(http.StatusOK, link)
+}
+
+// @Summary Unlinks the event from its issue
+// @Description The comment already posted on the issue is left as it is
+// @Tags events
+// @Param eventId path string true "Event ID"
+// @Success 200
+// @Router /events/{eventId}/issue [delete]
+func unlinkEventIssue(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	setEventIssueLink(event, nil)
+
+	c.Status(http.StatusOK)
+}
+
+// Posts the scheduled time and attendees of the finalized event to the issue
+// tracker of its folder, if it has one
+func postFinalizedToIssueTracker(event *models.Event) {
+	go func() {
+		// Recover from panics
+		defer func() {
+			if err := recover(); err != nil {
+				logger.StdErr.Println(err)
+			}
+		}()
+
+		tracker := getEventIssueTracker(event)
+		if tracker == nil {
+			return
+		}
+		owner := db.GetUserById(event.OwnerId.Hex())
+		if owner == nil {
+			return
+		}
+		client, err := issuetracker.NewClient(tracker)
+		if err != nil {
+			logger.StdErr.Println(err)
+			return
+		}
+
+		respondents := scheduling.GetRespondents(db.GetEventResponses(event.Id.Hex()))
+		comment := issuetracker.FormatComment(event, issuetracker.GetMeetingTimes(event, respondents), utils.GetEventLocation(event, owner))
+		link, err := issuetracker.PostMeeting(client, tracker, event.IssueLink, event.Name, comment)
+		if err != nil {
+			logger.StdErr.Printf("Failed to post event %s to the issue tracker: %v\n", event.Id.Hex(), err)
+			return
+		}
+		if link != nil {
+			setEventIssueLink(event, link)
+		}
+	}()
+}
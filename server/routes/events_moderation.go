@@ -0,0 +1,205 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/duplicates"
+	"schej.it/server/services/realtime"
+	"schej.it/server/utils"
+)
+
+// @Summary Removes responses from an event
+// @Description Removes the responses of the given respondents (user ids, or names for guests), e.g. spam or responses from the wrong person. If block is set, the IP address, account, and email the responses came from can't respond to the event again. Paid sign ups are refunded
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{respondents=[]string,block=bool} true "Respondents to remove, and whether to block them"
+// @Success 200 {object} object{numRemoved=int,blockedRespondents=[]models.BlockedRespondent}
+// @Router /events/{eventId}/responses/remove [post]
+func removeResponses(c *gin.Context) {
+	payload := struct {
+		Respondents []string `json:"respondents" binding:"required"`
+		Block       *bool    `json:"block"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	eventResponses := db.GetEventResponses(event.Id.Hex())
+	removedEmails := make(models.Set[string])
+	removed := make([]string, 0)
+	for _, respondent := range payload.Respondents {
+		var ip, email string
+		var userId primitive.ObjectID
+		if utils.Coalesce(event.IsSignUpForm) {
+			response, ok := event.SignUpResponses[respondent]
+			if !ok {
+				continue
+			}
+			ip, email, userId = response.SourceIp, response.Email, response.UserId
+			delete(event.SignUpResponses, respondent)
+			refundSignUpPayments(event, respondent, true)
+		} else {
+			idx, response := findResponse(eventResponses, respondent)
+			if idx == -1 {
+				continue
+			}
+			ip, email, userId = eventResponses[idx].SourceIp, response.Email, response.UserId
+			if _, err := db.EventResponsesCollection.DeleteOne(context.Background(), bson.M{"_id": eventResponses[idx].Id}); err != nil {
+				logger.StdErr.Panicln(err)
+			}
+			if event.NumResponses != nil {
+				*event.NumResponses--
+			}
+		}
+		removed = append(removed, respondent)
+
+		if !userId.IsZero() {
+			if user := db.GetUserById(userId.Hex()); user != nil {
+				email = user.Email
+			}
+		}
+		if len(email) > 0 {
+			removedEmails[strings.ToLower(email)] = struct{}{}
+		}
+		if utils.Coalesce(payload.Block) {
+			event.BlockedRespondents = append(event.BlockedRespondents, models.BlockedRespondent{
+				Id:        primitive.NewObjectID(),
+				Ip:        ip,
+				UserId:    userId,
+				Email:     strings.ToLower(email),
+				BlockedAt: primitive.NewDateTimeFromTime(time.Now()),
+			})
+		}
+	}
+
+	// Removed respondents no longer count as having responded
+	if event.Remindees != nil {
+		for i, remindee := range *event.Remindees {
+			if _, ok := removedEmails[strings.ToLower(remindee.Email)]; ok {
+				(*event.Remindees)[i].Responded = utils.FalsePtr()
+			}
+		}
+	}
+	if event.EmailPoll != nil {
+		for i, recipient := range event.EmailPoll.Recipients {
+			if _, ok := removedEmails[strings.ToLower(recipient.Email)]; ok {
+				event.EmailPoll.Recipients[i].RespondedAt = nil
+			}
+		}
+	}
+
+	if _, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{"$set": event}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	for _, respondent := range removed {
+		publishResponseChange(event, realtime.RESPONSE_DELETED, respondent)
+	}
+	if len(removed) > 0 {
+		recordActivity(event, models.ACTIVITY_RESPONSE_REMOVED, utils.GetAuthUser(c), "", removed)
+	}
+
+	blocked := event.BlockedRespondents
+	if blocked == nil {
+		blocked = make([]models.BlockedRespondent, 0)
+	}
+	c.JSON(http.StatusOK, gin.H{"numRemoved": len(removed), "blockedRespondents": blocked})
+}
+
+// @Summary Gets the respondents blocked from responding to an event
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} []models.BlockedRespondent
+// @Router /events/{eventId}/blocked-respondents [get]
+func getBlockedRespondents(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	blocked := event.BlockedRespondents
+	if blocked == nil {
+		blocked = make([]models.BlockedRespondent, 0)
+	}
+	c.JSON(http.StatusOK, blocked)
+}
+
+// @Summary Lets a blocked respondent respond to an event again
+// @Tags events
+// @Param eventId path string true "Event ID"
+// @Param blockId path string true "ID of the block"
+// @Success 200
+// @Router /events/{eventId}/blocked-respondents/{blockId} [delete]
+func unblockRespondent(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	index := utils.Find(event.BlockedRespondents, func(b models.BlockedRespondent) bool {
+		return b.Id.Hex() == c.Param("blockId")
+	})
+	if index == -1 {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.BlockedRespondentNotFound})
+		return
+	}
+
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$pull": bson.M{"blockedRespondents": bson.M{"_id": event.BlockedRespondents[index].Id}},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Gets the guests that likely responded to an event more than once
+// @Description Groups guests whose responses were submitted from the same device or network, depending on the sensitivity the instance is configured with. The groups are only a hint for the organizer, who can remove the extra responses
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} object{enabled=bool,sensitivity=string,groups=[][]string}
+// @Router /events/{eventId}/responses/duplicates [get]
+func getDuplicateResponses(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	respondents := make([]duplicates.Respondent, 0)
+	if utils.Coalesce(event.IsSignUpForm) {
+		for respondent, response := range event.SignUpResponses {
+			respondents = append(respondents, duplicates.Respondent{Id: respondent, Fingerprint: response.Fingerprint})
+		}
+		sort.Slice(respondents, func(i, j int) bool { return respondents[i].Id < respondents[j].Id })
+	} else {
+		for _, response := range db.GetEventResponses(event.Id.Hex()) {
+			respondents = append(respondents, duplicates.Respondent{Id: response.UserId, Fingerprint: response.Fingerprint})
+		}
+	}
+
+	sensitivity := duplicates.GetSensitivity()
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":     duplicates.Enabled(),
+		"sensitivity": sensitivity,
+		"groups":      duplicates.FindDuplicates(respondents, sensitivity),
+	})
+}
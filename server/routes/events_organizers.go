@@ -0,0 +1,156 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/notifications"
+	"schej.it/server/utils"
+)
+
+// @Summary Sets the co-organizers of the event
+// @Description Co-organizers receive the event's alerts, and can choose which of them they receive and over which channels
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{emails=[]string} true "Emails of the co-organizers"
+// @Success 200 {object} []models.CoOrganizer
+// @Router /events/{eventId}/co-organizers [put]
+func setCoOrganizers(c *gin.Context) {
+	payload := struct {
+		Emails []string `json:"emails" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	owner := utils.GetAuthUser(c)
+
+	coOrganizers := newCoOrganizers(notifications.NormalizeCoOrganizerEmails(payload.Emails, owner.Email))
+	userIds := make(models.Set[string])
+	for _, coOrganizer := range coOrganizers {
+		if !coOrganizer.UserId.IsZero() {
+			userIds[coOrganizer.UserId.Hex()] = struct{}{}
+		}
+	}
+
+	// Drop the rules of removed co-organizers
+	rules := make([]models.NotificationRule, 0)
+	for _, rule := range event.NotificationRules {
+		if _, ok := userIds[rule.UserId.Hex()]; ok || rule.UserId == event.OwnerId {
+			rules = append(rules, rule)
+		}
+	}
+
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$set": bson.M{"coOrganizers": coOrganizers, "notificationRules": rules},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.JSON(http.StatusOK, coOrganizers)
+}
+
+// Returns co-organizers with the given normalized emails, linked to the users
+// that have those emails
+func newCoOrganizers(emails []string) []models.CoOrganizer {
+	coOrganizers := make([]models.CoOrganizer, 0)
+	for _, email := range emails {
+		coOrganizer := models.CoOrganizer{Email: email}
+		if user := db.GetUserByEmail(email); user != nil {
+			coOrganizer.UserId = user.Id
+		}
+		coOrganizers = append(coOrganizers, coOrganizer)
+	}
+	return coOrganizers
+}
+
+// Returns the event if the current user is one of its organizers, otherwise
+// responds with an error and returns nil
+func getOrganizedEvent(c *gin.Context) *models.Event {
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return nil
+	}
+	if !notifications.IsOrganizer(event, utils.GetAuthUser(c)) {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotEventOrganizer})
+		return nil
+	}
+	return event
+}
+
+// @Summary Gets the current user's notification rule for the event
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} models.NotificationRule
+// @Router /events/{eventId}/notification-rule [get]
+func getNotificationRule(c *gin.Context) {
+	event := getOrganizedEvent(c)
+	if event == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, notifications.GetNotificationRule(event, utils.GetAuthUser(c)))
+}
+
+// @Summary Sets which alerts the current user receives for the event
+// @Description Available to the owner and co-organizers. Slack alerts are sent to the Slack accounts linked to the user
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{alerts=[]models.AlertType,channels=[]models.DeliveryChannel} true "Alerts to receive and the channels to receive them on"
+// @Success 200 {object} models.NotificationRule
+// @Router /events/{eventId}/notification-rule [put]
+func setNotificationRule(c *gin.Context) {
+	payload := struct {
+		Alerts   []models.AlertType       `json:"alerts" binding:"required"`
+		Channels []models.DeliveryChannel `json:"channels" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+
+	event := getOrganizedEvent(c)
+	if event == nil {
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	rule := models.NotificationRule{UserId: user.Id, Alerts: payload.Alerts, Channels: payload.Channels}
+	if err := notifications.ValidateNotificationRule(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	// Replace the user's rule atomically so organizers can't overwrite each other's rules
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$pull": bson.M{"notificationRules": bson.M{"userId": user.Id}},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	_, err = db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$push": bson.M{"notificationRules": rule},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
@@ -0,0 +1,131 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/panel"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// @Summary Sets the panel pools of the event
+// @Description A panel needs a number of members from each pool, e.g. one interviewer from each team and a room. Pool members are respondents, rooms are calendars on the organizer's connected calendar accounts
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{pools=[]models.PanelPool} true "Panel pools, with unique names"
+// @Success 200 {object} []models.PanelPool
+// @Router /events/{eventId}/panel [put]
+func setPanelPools(c *gin.Context) {
+	payload := struct {
+		Pools []models.PanelPool `json:"pools" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	names := make(models.Set[string])
+	for _, pool := range payload.Pools {
+		if _, ok := names[pool.Name]; ok || pool.Count <= 0 || pool.Count > len(pool.Members)+len(pool.Rooms) {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: fmt.Sprintf("invalid pool %s", pool.Name)})
+			return
+		}
+		names[pool.Name] = struct{}{}
+	}
+
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$set": bson.M{"panelPools": payload.Pools},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.JSON(http.StatusOK, payload.Pools)
+}
+
+// @Summary Finds times when the event's panel can meet
+// @Description Returns the upcoming slots where enough members of every pool are free, taking into account the respondents' availability, their connected calendars, and the room calendars. A member in several pools is only picked once per slot
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param durationMinutes query int false "Length of the panel in minutes, defaults to 60"
+// @Param limit query int false "Maximum number of slots, defaults to 10"
+// @Success 200 {object} []panel.Slot
+// @Router /events/{eventId}/panel/slots [get]
+func getPanelSlots(c *gin.Context) {
+	query := struct {
+		DurationMinutes *int `form:"durationMinutes"`
+		Limit           *int `form:"limit"`
+	}{}
+	if err := c.Bind(&query); err != nil {
+		return
+	}
+
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	meetingLength := time.Hour
+	if query.DurationMinutes != nil && *query.DurationMinutes > 0 {
+		meetingLength = time.Duration(*query.DurationMinutes) * time.Minute
+	}
+	limit := 10
+	if query.Limit != nil {
+		limit = *query.Limit
+	}
+
+	// Get up to date availability for the respondents and rooms
+	now := time.Now()
+	increment := scheduling.GetTimeIncrement(event)
+	increments := scheduling.GetTimeIncrements(event)
+	if len(increments) == 0 || !increments[len(increments)-1].After(now) {
+		c.JSON(http.StatusOK, make([]panel.Slot, 0))
+		return
+	}
+	timeMax := increments[len(increments)-1].Add(increment)
+
+	eventResponses := db.GetEventResponses(event.Id.Hex())
+	respondents := scheduling.GetRespondents(eventResponses)
+	scheduling.RemoveCalendarBusyTimes(event, eventResponses, respondents, now, timeMax)
+	respondentsById := make(map[string]scheduling.Respondent)
+	for _, respondent := range respondents {
+		respondentsById[respondent.Id] = respondent
+	}
+
+	pools := make([]panel.Pool, 0)
+	for _, panelPool := range utils.Coalesce(event.PanelPools) {
+		pool := panel.Pool{Name: panelPool.Name, Count: panelPool.Count, Members: make([]scheduling.Respondent, 0)}
+		for _, memberId := range panelPool.Members {
+			if respondent, ok := respondentsById[memberId]; ok {
+				pool.Members = append(pool.Members, respondent)
+			}
+		}
+		pool.Members = append(pool.Members, panel.GetRoomAvailability(user, event, panelPool.Rooms, now, timeMax)...)
+		pools = append(pools, pool)
+	}
+
+	slots := panel.FindSlots(event, pools, meetingLength, func(start time.Time, end time.Time) bool {
+		return start.Before(now)
+	})
+	if limit > 0 && len(slots) > limit {
+		slots = slots[:limit]
+	}
+
+	c.JSON(http.StatusOK, slots)
+}
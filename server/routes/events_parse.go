@@ -0,0 +1,90 @@
+package routes
+
+import (
+	"math"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services/entitlements"
+	"schej.it/server/services/eventparser"
+	"schej.it/server/services/llm"
+	"schej.it/server/utils"
+)
+
+// @Summary Parses a free text description into an event draft
+// @Description Uses a rules-based parser, or the configured LLM if EVENT_PARSER_LLM_ENABLED is true and the user is signed in (falling back to the rules-based parser if it fails, or the user used up their daily quota of LLM parses). The returned draft can be passed to POST /events
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param payload body object{text=string,timezoneOffset=int} true "Free text description of the event, and the client's timezone offset in minutes (same as JS getTimezoneOffset())"
+// @Success 200 {object} object{name=string,duration=float32,dates=[]string,type=models.EventType,daysOnly=bool,meetingLength=int,source=string}
+// @Router /events/parse [post]
+func parseEvent(c *gin.Context) {
+	payload := struct {
+		Text           string `json:"text" binding:"required"`
+		TimezoneOffset *int   `json:"timezoneOffset"`
+	}{}
+	if err := c.Bind(&payload); err != nil {
+		return
+	}
+
+	// Determine the location to resolve relative dates in
+	var user *models.User
+	if userId, signedIn := utils.GetUserId(c); signedIn {
+		user = db.GetUserById(userId)
+	}
+	loc := time.UTC
+	if payload.TimezoneOffset != nil {
+		loc = time.FixedZone("UserOffset", -*payload.TimezoneOffset*60)
+	} else if user != nil {
+		loc = utils.GetUserLocation(user)
+	}
+	now := time.Now().In(loc)
+
+	var draft eventparser.Draft
+	source := "rules"
+	if os.Getenv("EVENT_PARSER_LLM_ENABLED") == "true" && llm.IsConfigured() && user != nil &&
+		!entitlements.Consume(user, entitlements.GetUserPlan(user), entitlements.METRIC_LLM_EVENT_PARSES, now).Exceeded() {
+		var err error
+		draft, err = eventparser.ParseWithLLM(payload.Text, now)
+		if err == nil {
+			source = "llm"
+		} else {
+			logger.StdErr.Println(err)
+		}
+	}
+	if source == "rules" {
+		draft = eventparser.Parse(payload.Text, now)
+	}
+
+	// Convert the draft to the format expected by POST /events, i.e. each date
+	// is the start of the time range on that day
+	startHours, startFraction := math.Modf(draft.StartHour)
+	dates := make([]primitive.DateTime, 0)
+	for _, date := range draft.Dates {
+		if !draft.DaysOnly {
+			date = date.Add(time.Duration(startHours)*time.Hour + time.Duration(startFraction*60)*time.Minute)
+		}
+		dates = append(dates, primitive.NewDateTimeFromTime(date))
+	}
+	duration := float32(draft.EndHour - draft.StartHour)
+	if draft.DaysOnly {
+		duration = 0
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":          draft.Name,
+		"duration":      duration,
+		"dates":         dates,
+		"type":          models.SPECIFIC_DATES,
+		"daysOnly":      draft.DaysOnly,
+		"meetingLength": draft.MeetingLength,
+		"source":        source,
+	})
+}
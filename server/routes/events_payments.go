@@ -0,0 +1,418 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stripe/stripe-go/v82"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/forms"
+	"schej.it/server/services/payments"
+	"schej.it/server/services/privacy"
+	"schej.it/server/services/submissions"
+	"schej.it/server/utils"
+)
+
+// @Summary Starts the payment for sign up slots
+// @Description Creates a Stripe Checkout Session for the selected paid sign up blocks. The respondent is signed up for the blocks once the payment succeeds, and refunded for any that filled up in the meantime. The url to return to must be on one of the allowed origins
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{guest=bool,name=string,email=string,signUpBlockIds=[]string,answers=map[string][]string,consentVersion=int,originUrl=string,submissionToken=string} true "Respondent details, the blocks to pay for, answers to the event's questions, the version of the consent document agreed to, the url to return to after checkout, and for guests, the submission token from the event page"
+// @Success 200 {object} object{url=string,submissionToken=string,responseToken=string}
+// @Router /events/{eventId}/checkout [post]
+func createSignUpCheckout(c *gin.Context) {
+	payload := struct {
+		Guest          *bool                `json:"guest" binding:"required"`
+		Name           string               `json:"name"`
+		Email          string               `json:"email"`
+		SignUpBlockIds []primitive.ObjectID `json:"signUpBlockIds" binding:"required"`
+		Answers        map[string][]string  `json:"answers"`
+		ConsentVersion *int                 `json:"consentVersion"`
+		OriginUrl      string               `json:"originUrl" binding:"required"`
+
+		// Single use token from the event page, required for guests
+		SubmissionToken string `json:"submissionToken"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+	owner := db.GetUserById(event.OwnerId.Hex())
+	if !utils.Coalesce(event.IsSignUpForm) || owner == nil || !utils.Coalesce(owner.StripeAccountEnabled) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.PaymentsNotEnabled})
+		return
+	}
+	answers, err := forms.Validate(utils.Coalesce(event.Questions), payload.Answers)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+	if !checkInviteOnly(c, event, *payload.Guest, nil) {
+		return
+	}
+	if !checkNotBlocked(c, event, *payload.Guest, payload.Email) {
+		return
+	}
+	if !checkConsent(c, event, payload.ConsentVersion) {
+		return
+	}
+	// Checkout only returns to the app, so it can't be used as an open redirect
+	if !utils.IsAllowedOrigin(payload.OriginUrl) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid originUrl"})
+		return
+	}
+	successUrl, err := url.Parse(payload.OriginUrl)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid originUrl"})
+		return
+	}
+	if *payload.Guest && !checkSubmissionToken(c, event, payload.SubmissionToken) {
+		return
+	}
+
+	var userId string
+	if *payload.Guest {
+		userId = payload.Name
+		payload.Email = privacy.GetGuestEmail(payload.Email)
+	} else if id, signedIn := utils.GetUserId(c); signedIn {
+		userId = id
+		if user := db.GetUserById(id); user != nil {
+			payload.Name = fmt.Sprintf("%s %s", user.FirstName, user.LastName)
+			payload.Email = user.Email
+		}
+	}
+	if len(userId) == 0 {
+		c.JSON(http.StatusUnauthorized, responses.Error{Error: errs.NotSignedIn})
+		return
+	}
+
+	paid := db.GetRespondentPayments(event.Id, userId, models.PAYMENT_PAID)
+	unpaid := payments.GetUnpaidBlocks(event, payload.SignUpBlockIds, paid)
+	amount := payments.GetPrice(event, unpaid)
+	if amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to pay for"})
+		return
+	}
+	if len(payments.GetFullBlocks(event, unpaid, userId)) > 0 {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.SignUpBlockFull})
+		return
+	}
+
+	payment := models.Payment{
+		EventId:        event.Id,
+		OwnerId:        event.OwnerId,
+		Type:           models.BOOKING_PAYMENT,
+		UserId:         userId,
+		Name:           payload.Name,
+		Email:          payload.Email,
+		SignUpBlockIds: unpaid,
+		Answers:        answers,
+		Amount:         amount,
+		ApplicationFee: payments.GetApplicationFee(amount),
+		Currency:       payments.GetCurrency(event),
+		Status:         models.PAYMENT_PENDING,
+		CreatedAt:      primitive.NewDateTimeFromTime(time.Now()),
+	}
+	db.InsertPayment(&payment)
+
+	query := successUrl.Query()
+	query.Set("payment", "success")
+	successUrl.RawQuery = query.Encode()
+	query.Set("payment", "cancel")
+	cancelUrl := *successUrl
+	cancelUrl.RawQuery = query.Encode()
+
+	cs, err := payments.CreateCheckoutSession(event, owner, &payment, successUrl.String(), cancelUrl.String())
+	if err != nil {
+		logger.StdErr.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create checkout session"})
+		return
+	}
+	payment.CheckoutSessionId = cs.ID
+	db.UpdatePayment(&payment)
+	recordConsent(c, event, userId, payload.Name, payload.Email)
+
+	res := gin.H{"url": cs.URL, "submissionToken": submissions.NewToken(event.Id, time.Now())}
+	// Only the guest who first signs up under the name gets to cancel it later on
+	if _, signedUp := event.SignUpResponses[userId]; *payload.Guest && !signedUp && len(paid) == 0 {
+		res["responseToken"] = submissions.NewResponseToken(event.Id, userId)
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// Marks the payment of the completed checkout session as paid and signs the
+// respondent up for the blocks they paid for. Safe to call multiple times
+func fulfillSignUpPayment(cs *stripe.CheckoutSession) {
+	payment := db.GetPaymentById(cs.Metadata["paymentId"])
+	if payment == nil || cs.PaymentStatus == stripe.CheckoutSessionPaymentStatusUnpaid {
+		return
+	}
+
+	update := bson.M{
+		"status": models.PAYMENT_PAID,
+		"paidAt": primitive.NewDateTimeFromTime(time.Now()),
+	}
+	if cs.PaymentIntent != nil {
+		update["paymentIntentId"] = cs.PaymentIntent.ID
+		if cs.PaymentIntent.PaymentMethod != nil {
+			update["paymentMethodId"] = cs.PaymentIntent.PaymentMethod.ID
+		}
+	}
+	if cs.Customer != nil {
+		update["customerId"] = cs.Customer.ID
+	}
+	if !db.TransitionPaymentStatus(payment.Id, models.PAYMENT_PENDING, update) {
+		return
+	}
+
+	event := db.GetEventById(payment.EventId.Hex())
+	if event == nil {
+		return
+	}
+
+	// Blocks can fill up while the respondent is paying, so those are refunded
+	// instead of signed up for
+	signUpBlockIds := payment.SignUpBlockIds
+	if full := payments.GetFullBlocks(event, payment.SignUpBlockIds, payment.UserId); len(full) > 0 {
+		signUpBlockIds = make([]primitive.ObjectID, 0)
+		for _, blockId := range payment.SignUpBlockIds {
+			if !utils.Contains(full, blockId) {
+				signUpBlockIds = append(signUpBlockIds, blockId)
+			}
+		}
+		refundFullBlocks(event, db.GetPaymentById(payment.Id.Hex()), full, signUpBlockIds)
+		if len(signUpBlockIds) == 0 {
+			return
+		}
+	}
+
+	response, ok := event.SignUpResponses[payment.UserId]
+	if !ok || response == nil {
+		response = &models.SignUpResponse{Name: payment.Name, Email: payment.Email}
+		if userId, err := primitive.ObjectIDFromHex(payment.UserId); err == nil && db.GetUserById(payment.UserId) != nil {
+			response = &models.SignUpResponse{UserId: userId}
+		}
+	}
+	if payment.Answers != nil {
+		response.Answers = payment.Answers
+	}
+	for _, blockId := range signUpBlockIds {
+		if !utils.Contains(response.SignUpBlockIds, blockId) {
+			response.SignUpBlockIds = append(response.SignUpBlockIds, blockId)
+		}
+	}
+	if event.SignUpResponses == nil {
+		event.SignUpResponses = make(map[string]*models.SignUpResponse)
+	}
+	event.SignUpResponses[payment.UserId] = response
+
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$set": bson.M{"signUpResponses": event.SignUpResponses},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Refunds the blocks of the just paid payment that filled up during checkout,
+// keeping only the remaining blocks on the payment, and emails the respondent
+func refundFullBlocks(event *models.Event, payment *models.Payment, full []primitive.ObjectID, remaining []primitive.ObjectID) {
+	amount := payments.GetPrice(event, full)
+	if amount > payment.Amount {
+		amount = payment.Amount
+	}
+	if err := payments.Refund(payment, amount); err != nil {
+		logger.StdErr.Println(err)
+		return
+	}
+
+	update := bson.M{
+		"signUpBlockIds": remaining,
+		"refundedAmount": payment.RefundedAmount + amount,
+		"refundedAt":     primitive.NewDateTimeFromTime(time.Now()),
+	}
+	if len(remaining) == 0 {
+		update["status"] = models.PAYMENT_REFUNDED
+	}
+	db.TransitionPaymentStatus(payment.Id, models.PAYMENT_PAID, update)
+
+	if len(payment.Email) > 0 {
+		go func() {
+			// Recover from panics
+			defer func() {
+				if err := recover(); err != nil {
+					logger.StdErr.Println(err)
+				}
+			}()
+
+			body := fmt.Sprintf("Some of the slots you paid for in \"%s\" filled up before your payment went through, so you weren't signed up for them.\n\nRefunded: %s of %s\n", event.Name, payments.FormatAmount(amount, payment.Currency), payments.FormatAmount(payment.Amount, payment.Currency))
+			utils.SendEmail(payment.Email, fmt.Sprintf("Slots full: %s", event.Name), body, "text/plain")
+		}()
+	}
+}
+
+// Refunds the paid payments of the respondent for the event after their sign
+// up was cancelled, following the event's booking policy, and emails them the
+// refunded amount
+func refundSignUpPayments(event *models.Event, userId string, byOwner bool) {
+	go func() {
+		// Recover from panics
+		defer func() {
+			if err := recover(); err != nil {
+				logger.StdErr.Println(err)
+			}
+		}()
+
+		now := time.Now()
+		for _, payment := range db.GetRespondentPayments(event.Id, userId, models.PAYMENT_PAID) {
+			if payment.Type == models.NO_SHOW_FEE {
+				continue
+			}
+
+			amount := payments.GetRefundAmount(event, &payment, now, byOwner)
+			if amount > 0 {
+				if err := payments.Refund(&payment, amount); err != nil {
+					logger.StdErr.Println(err)
+					continue
+				}
+			}
+			db.TransitionPaymentStatus(payment.Id, models.PAYMENT_PAID, bson.M{
+				"status":         models.PAYMENT_REFUNDED,
+				"refundedAmount": payment.RefundedAmount + amount,
+				"refundedAt":     primitive.NewDateTimeFromTime(now),
+			})
+
+			if len(payment.Email) > 0 {
+				body := fmt.Sprintf("Your sign up for \"%s\" was cancelled.\n\nRefunded: %s of %s\n", event.Name, payments.FormatAmount(amount, payment.Currency), payments.FormatAmount(payment.Amount, payment.Currency))
+				if amount < payment.Amount && event.BookingPolicy != nil {
+					body += fmt.Sprintf("\nCancellations less than %d hours before the start are refunded %d%%.\n", event.BookingPolicy.CancellationWindowHours, event.BookingPolicy.LateCancellationRefundPercent)
+				}
+				utils.SendEmail(payment.Email, fmt.Sprintf("Cancelled: %s", event.Name), body, "text/plain")
+			}
+		}
+	}()
+}
+
+// @Summary Charges the no-show fee to a respondent
+// @Description Charges the event's no-show fee to the payment method the respondent saved when paying for their sign up. Can only be charged once per respondent, after their first block has started
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{userId=string} true "Id of the respondent (user id, or name for guests)"
+// @Success 200 {object} models.Payment
+// @Router /events/{eventId}/no-show [post]
+func chargeNoShowFee(c *gin.Context) {
+	payload := struct {
+		UserId string `json:"userId" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	user := utils.GetAuthUser(c)
+	if event.BookingPolicy == nil || event.BookingPolicy.NoShowFee <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event has no no-show fee"})
+		return
+	}
+
+	// Find the booking whose payment method is charged
+	now := time.Now()
+	var booking *models.Payment
+	for _, payment := range db.GetRespondentPayments(event.Id, payload.UserId, models.PAYMENT_PAID) {
+		payment := payment
+		if payment.Type == models.NO_SHOW_FEE {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "no-show fee already charged"})
+			return
+		}
+		if len(payment.PaymentMethodId) > 0 && payments.HasStarted(event, &payment, now) {
+			booking = &payment
+		}
+	}
+	if booking == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no started booking with a saved payment method"})
+		return
+	}
+
+	fee := models.Payment{
+		EventId:        event.Id,
+		OwnerId:        event.OwnerId,
+		Type:           models.NO_SHOW_FEE,
+		UserId:         booking.UserId,
+		Name:           booking.Name,
+		Email:          booking.Email,
+		SignUpBlockIds: booking.SignUpBlockIds,
+		Amount:         event.BookingPolicy.NoShowFee,
+		ApplicationFee: payments.GetApplicationFee(event.BookingPolicy.NoShowFee),
+		Currency:       booking.Currency,
+		Status:         models.PAYMENT_PENDING,
+		CreatedAt:      primitive.NewDateTimeFromTime(now),
+	}
+	db.InsertPayment(&fee)
+
+	paymentIntent, err := payments.ChargeNoShowFee(user, booking, &fee)
+	if err != nil {
+		logger.StdErr.Println(err)
+		fee.Status = models.PAYMENT_FAILED
+		db.UpdatePayment(&fee)
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": "Failed to charge no-show fee"})
+		return
+	}
+	paidAt := primitive.NewDateTimeFromTime(now)
+	fee.Status = models.PAYMENT_PAID
+	fee.PaidAt = &paidAt
+	fee.PaymentIntentId = paymentIntent.ID
+	db.UpdatePayment(&fee)
+
+	if len(fee.Email) > 0 {
+		go func() {
+			// Recover from panics
+			defer func() {
+				if err := recover(); err != nil {
+					logger.StdErr.Println(err)
+				}
+			}()
+
+			body := fmt.Sprintf("You were marked as a no-show for \"%s\", so the no-show fee of %s was charged to your saved payment method.\n", event.Name, payments.FormatAmount(fee.Amount, fee.Currency))
+			utils.SendEmail(fee.Email, fmt.Sprintf("No-show fee: %s", event.Name), body, "text/plain")
+		}()
+	}
+
+	c.JSON(http.StatusOK, fee)
+}
+
+// @Summary Gets the payments collected for the event
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} []models.Payment
+// @Router /events/{eventId}/payments [get]
+func getEventPayments(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, db.GetEventPayments(event.Id))
+}
@@ -0,0 +1,87 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/phases"
+)
+
+// @Summary Sets the next phase of a days only poll
+// @Description Makes the days only poll the first phase of a two phase poll. Once advanced (at advanceAt, or manually), a time grid poll is created for the numDays days the most respondents can make, inviting the remindees and respondents of this poll. Pass a null nextPhase to remove it
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{nextPhase=models.NextPhase} true "The next phase"
+// @Success 200 {object} models.NextPhase
+// @Router /events/{eventId}/next-phase [put]
+func setNextPhase(c *gin.Context) {
+	payload := struct {
+		NextPhase *models.NextPhase `json:"nextPhase"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	update := bson.M{"$unset": bson.M{"nextPhase": ""}}
+	if payload.NextPhase != nil {
+		if err := phases.Validate(event, payload.NextPhase); err != nil {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+			return
+		}
+		payload.NextPhase.EventId = nil
+		update = bson.M{"$set": bson.M{"nextPhase": payload.NextPhase}}
+	} else if event.NextPhase != nil && event.NextPhase.EventId != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: phases.ErrAlreadyAdvanced.Error()})
+		return
+	}
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, update)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.JSON(http.StatusOK, payload.NextPhase)
+}
+
+// @Summary Creates the next phase of a days only poll
+// @Description Creates the time grid poll for the days the most respondents can make, links it to this poll, and invites the remindees and respondents of this poll to it
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 201 {object} object{eventId=string,shortId=string,dates=[]string}
+// @Router /events/{eventId}/next-phase/advance [post]
+func advanceToNextPhase(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	if event.NextPhase == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event has no next phase"})
+		return
+	}
+
+	next, err := phases.Advance(event, time.Now())
+	if errors.Is(err, phases.ErrAlreadyAdvanced) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "eventId": event.NextPhase.EventId})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"eventId": next.Id.Hex(), "shortId": next.ShortId, "dates": next.Dates})
+}
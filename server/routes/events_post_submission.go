@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/redirects"
+)
+
+// Validates what respondents see after submitting, only allowing redirects to
+// the app, the domains allowed by the instance, and the verified domains of the
+// event's organization
+func validatePostSubmission(c *gin.Context, event *models.Event) bool {
+	if event.PostSubmission == nil {
+		return true
+	}
+
+	var org *models.Organization
+	if !event.OrganizationId.IsZero() {
+		org = db.GetOrganizationById(event.OrganizationId.Hex())
+	}
+	if err := redirects.Validate(event.PostSubmission, redirects.GetAllowedDomains(org)); err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return false
+	}
+	if len(event.PostSubmission.RedirectUrl) == 0 && len(event.PostSubmission.Message) == 0 {
+		event.PostSubmission = nil
+	}
+	return true
+}
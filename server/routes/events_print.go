@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/services/printview"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// @Summary Gets the event's responses laid out for printing
+// @Description Available to the owner and co-organizers. Returns the respondent matrix (a row per respondent and a column per time increment, with how many can make each), the legend of the statuses, and the times the event was finalized at, in the event's timezone. The print view and the PDF export are both rendered from it
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} printview.PrintView
+// @Router /events/{eventId}/print [get]
+func getEventPrintView(c *gin.Context) {
+	event := getOrganizedEvent(c)
+	if event == nil {
+		return
+	}
+	if utils.Coalesce(event.IsSignUpForm) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sign up forms don't have a print view"})
+		return
+	}
+
+	respondents := scheduling.GetRespondents(db.GetEventResponses(event.Id.Hex()))
+	loc := utils.GetEventLocation(event, utils.GetAuthUser(c))
+
+	c.JSON(http.StatusOK, printview.Build(event, respondents, loc))
+}
@@ -0,0 +1,63 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/models"
+	"schej.it/server/services/notifications"
+	"schej.it/server/services/privacy"
+	"schej.it/server/utils"
+)
+
+// Returns how much of the event's responses the current user sees, given the
+// event's privacy level
+func getResponseVisibility(c *gin.Context, event *models.Event) privacy.Visibility {
+	viewer := privacy.Viewer{}
+	if userId, signedIn := utils.GetUserId(c); signedIn {
+		if user := db.GetUserById(userId); user != nil {
+			viewer.Organizer = notifications.IsOrganizer(event, user)
+			viewer.Invitee = privacy.IsInvitee(event, user.Email)
+		}
+	}
+	return privacy.GetVisibility(event, viewer)
+}
+
+// Returns the responses (keyed by respondent) the viewer sees. Anonymous
+// responses are keyed by privacy.GetAnonymousId, and the viewer's own
+// response is always kept. Every endpoint that returns responses to people
+// other than organizers shapes them with this
+func shapeResponses[T any](event *models.Event, responses map[string]T, viewerId string, visibility privacy.Visibility, anonymize func(T) T) map[string]T {
+	if visibility == privacy.VISIBLE_ALL || responses == nil {
+		return responses
+	}
+
+	shaped := make(map[string]T)
+	for respondentId, response := range responses {
+		if len(viewerId) > 0 && respondentId == viewerId {
+			shaped[respondentId] = response
+		} else if visibility == privacy.VISIBLE_ANONYMOUS {
+			shaped[privacy.GetAnonymousId(event.Id, respondentId)] = anonymize(response)
+		}
+	}
+	return shaped
+}
+
+// Returns only the availability of the response
+func anonymizeResponse(response *models.Response) *models.Response {
+	if response == nil {
+		return nil
+	}
+	return &models.Response{
+		Availability:       response.Availability,
+		IfNeeded:           response.IfNeeded,
+		ManualAvailability: response.ManualAvailability,
+	}
+}
+
+// Returns only the blocks of the sign up
+func anonymizeSignUpResponse(response *models.SignUpResponse) *models.SignUpResponse {
+	if response == nil {
+		return nil
+	}
+	return &models.SignUpResponse{SignUpBlockIds: response.SignUpBlockIds}
+}
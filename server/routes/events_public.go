@@ -0,0 +1,81 @@
+package routes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/aggregates"
+	"schej.it/server/services/expiry"
+	"schej.it/server/services/privacy"
+	"schej.it/server/utils"
+)
+
+// How long CDNs can serve the aggregate without revalidating, and how much
+// longer they can serve a stale copy while they revalidate in the background
+const (
+	publicAggregateMaxAge               = 30 * time.Second
+	publicAggregateStaleWhileRevalidate = 5 * time.Minute
+)
+
+// @Summary Gets the aggregated availability of a public event, cacheable by CDNs
+// @Description Returns how many respondents are available at each time increment, without who they are, so popular events can be served mostly from a CDN. Doesn't depend on who is signed in. Only available for events that anyone with the link can see, i.e. not blind, invite only, closed, behind an organization's SSO, or with a privacy level that hides the responses. Supports If-None-Match
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} aggregates.Aggregate
+// @Success 304
+// @Router /events/{eventId}/public-aggregate [get]
+func getPublicAggregate(c *gin.Context) {
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil || !isPublicEvent(event) {
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+
+	body, err := json.Marshal(aggregates.Get(event, time.Now()))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	hash := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(hash[:16]) + `"`
+
+	c.Header("Cache-Control", getPublicCacheControl())
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// Returns whether anyone with the event's link can see its aggregated
+// availability, so it can be cached publicly
+func isPublicEvent(event *models.Event) bool {
+	if utils.Coalesce(event.IsDeleted) || utils.Coalesce(event.BlindAvailabilityEnabled) || utils.Coalesce(event.InviteOnly) || !privacy.ShowsAvailability(event) {
+		return false
+	}
+	if expiresAt := expiry.GetExpiry(event, expiry.GetDefaultDays()); expiresAt != nil && !time.Now().Before(*expiresAt) {
+		return false
+	}
+	if !event.OrganizationId.IsZero() {
+		if org := db.GetOrganizationById(event.OrganizationId.Hex()); org != nil && org.Policies.RequireSso {
+			return false
+		}
+	}
+	return true
+}
+
+func getPublicCacheControl() string {
+	return fmt.Sprintf("public, max-age=0, s-maxage=%d, stale-while-revalidate=%d", int(publicAggregateMaxAge.Seconds()), int(publicAggregateStaleWhileRevalidate.Seconds()))
+}
@@ -0,0 +1,163 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/googlechat"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/notifications"
+	"schej.it/server/services/quorum"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// @Summary Sets the quorum of the event
+// @Description A quorum is met once a slot works for at least minAttendees respondents, including all the required ones. Organizers are notified the first time it is met, and if autoFinalize is true the event is finalized at the best such slot. Pass a null quorum to remove it
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{quorum=models.Quorum} true "The quorum"
+// @Success 200 {object} models.Quorum
+// @Router /events/{eventId}/quorum [put]
+func setQuorum(c *gin.Context) {
+	payload := struct {
+		Quorum *models.Quorum `json:"quorum"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	if payload.Quorum != nil {
+		if err := quorum.Validate(payload.Quorum); err != nil {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+			return
+		}
+		payload.Quorum.MetAt = nil
+	}
+
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	update := bson.M{"$set": bson.M{"quorum": payload.Quorum}}
+	if payload.Quorum == nil {
+		update = bson.M{"$unset": bson.M{"quorum": ""}}
+	}
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, update)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	// The existing responses might already meet the new quorum
+	if payload.Quorum != nil {
+		event.Quorum = payload.Quorum
+		go checkQuorum(event)
+	}
+
+	c.JSON(http.StatusOK, payload.Quorum)
+}
+
+// @Summary Gets the slots where the event's quorum is met
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} []scheduling.Slot
+// @Router /events/{eventId}/quorum/slots [get]
+func getQuorumSlots(c *gin.Context) {
+	event := getOrganizedEvent(c)
+	if event == nil {
+		return
+	}
+	if event.Quorum == nil {
+		c.JSON(http.StatusOK, make([]scheduling.Slot, 0))
+		return
+	}
+
+	c.JSON(http.StatusOK, getQuorumSlotsForEvent(event))
+}
+
+// Returns the upcoming slots where the event's quorum is met, ranked from best to worst
+func getQuorumSlotsForEvent(event *models.Event) []scheduling.Slot {
+	respondents := scheduling.GetRespondents(db.GetEventResponses(event.Id.Hex()))
+	return quorum.FindSlots(event, respondents, event.Quorum, scheduling.ExcludePast(event, time.Now()))
+}
+
+// Notifies the organizers the first time the event's quorum is met, and
+// finalizes the event at the best slot if the quorum says to
+func checkQuorum(event *models.Event) {
+	// Recover from panics
+	defer func() {
+		if err := recover(); err != nil {
+			logger.StdErr.Println(err)
+		}
+	}()
+
+	if event.Quorum == nil || event.Quorum.MetAt != nil {
+		return
+	}
+	slots := getQuorumSlotsForEvent(event)
+	if len(slots) == 0 {
+		return
+	}
+
+	// Only the first check to see the quorum met notifies the organizers
+	metAt := primitive.NewDateTimeFromTime(time.Now())
+	result, err := db.EventsCollection.UpdateOne(context.Background(), bson.M{
+		"_id":          event.Id,
+		"quorum":       bson.M{"$exists": true},
+		"quorum.metAt": bson.M{"$exists": false},
+	}, bson.M{"$set": bson.M{"quorum.metAt": metAt}})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	if result.ModifiedCount == 0 {
+		return
+	}
+	event.Quorum.MetAt = &metAt
+
+	best := slots[0]
+	finalized := event.Quorum.AutoFinalize && event.ScheduledEvent == nil
+	if finalized {
+		event.ScheduledEvent = &models.CalendarEvent{
+			Summary:   event.Name,
+			StartDate: primitive.NewDateTimeFromTime(best.Start),
+			EndDate:   primitive.NewDateTimeFromTime(best.End),
+		}
+		event.RequiredAttendees = event.Quorum.Required
+		_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+			"$set": bson.M{
+				"scheduledEvent":    event.ScheduledEvent,
+				"requiredAttendees": event.RequiredAttendees,
+			},
+			"$unset": bson.M{"cancellations": ""},
+		})
+		if err != nil {
+			logger.StdErr.Panicln(err)
+		}
+		recordActivity(event, models.ACTIVITY_FINALIZED, nil, "Quorum", nil)
+		googlechat.SendEventFinalizedMessage(event)
+	}
+
+	eventUrl := fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId())
+	body := fmt.Sprintf("Enough people can make \"%s\" on %s (%d available).\n\n", event.Name, best.Start.UTC().Format("Mon Jan 2, 15:04 MST"), len(best.Available))
+	slackText := fmt.Sprintf("<%s|%s> reached quorum", eventUrl, event.Name)
+	if finalized {
+		body += fmt.Sprintf("The event was finalized at that time. View it here: %s\n", eventUrl)
+		slackText += " and was finalized"
+	} else {
+		body += fmt.Sprintf("Finalize the event here: %s\n", eventUrl)
+	}
+	notifications.NotifyOrganizers(event, models.QUORUM_ALERT, "", func(organizer *models.User) {
+		utils.SendEmail(organizer.Email, fmt.Sprintf("%s reached quorum", event.Name), body, "text/plain")
+	}, slackText)
+}
@@ -0,0 +1,179 @@
+package routes
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/aggregates"
+	"schej.it/server/services/privacy"
+	"schej.it/server/services/realtime"
+	"schej.it/server/utils"
+)
+
+// How often a ping is sent to keep idle connections from timing out
+const subscriptionPingInterval = 25 * time.Second
+
+// Longest name shown to others while filling out an event
+const maxPresenceNameLength = 50
+
+// Most time increments a selection can have
+const maxSelectionTimes = 1000
+
+// @Summary Subscribes to changes to the event's responses
+// @Description Server-sent events stream with a "change" event each time a response is added, updated, or deleted, so clients can refetch the responses and update the heatmap live. A "connected" event with the connection's id comes first, a "presence" event whenever the number of people viewing or filling out the event changes, and a "selection" event while others select times. A "ping" event is sent every 25 seconds to keep the connection open
+// @Tags events
+// @Produce text/event-stream
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} realtime.Change
+// @Router /events/{eventId}/subscribe [get]
+func subscribeToEvent(c *gin.Context) {
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+	if !checkNotExpired(c, event) {
+		return
+	}
+
+	changes, connectionId, disconnect := realtime.DefaultHub.Connect(event.Id.Hex())
+	defer disconnect()
+
+	// Disable proxy buffering so changes are delivered right away
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	ticker := time.NewTicker(subscriptionPingInterval)
+	defer ticker.Stop()
+	c.SSEvent("connected", gin.H{"connectionId": connectionId})
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				return false
+			}
+			switch change.Type {
+			case realtime.PRESENCE_CHANGED:
+				c.SSEvent("presence", change.Presence)
+			case realtime.SELECTION_CHANGED:
+				c.SSEvent("selection", change.Selection)
+			default:
+				c.SSEvent("change", change)
+			}
+			return true
+		case <-ticker.C:
+			c.SSEvent("ping", "")
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// Notifies the clients subscribed to the event that a response changed, and
+// drops the event's cached aggregate. The respondent is left out unless anyone
+// with the link can see who responded
+func publishResponseChange(event *models.Event, changeType realtime.ChangeType, userId string) {
+	aggregates.Invalidate(event.Id)
+	if !privacy.ShowsNames(event) {
+		userId = ""
+	}
+	realtime.DefaultHub.Publish(event.Id.Hex(), realtime.Change{Type: changeType, UserId: userId})
+}
+
+// @Summary Sets whether the person on a subscription is filling out the event
+// @Description Updates the presence the event's subscribers see, e.g. "3 people are currently filling this out". Names are only shown if the person chose to share theirs, and never for events with blind availability or a privacy level other than public
+// @Tags events
+// @Accept json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{connectionId=string,editing=bool,shareName=bool,name=string} true "Connection id from the subscription's connected event, and the name of guests that share theirs"
+// @Success 200
+// @Router /events/{eventId}/presence [post]
+func setEventPresence(c *gin.Context) {
+	payload := struct {
+		ConnectionId string `json:"connectionId" binding:"required"`
+		Editing      bool   `json:"editing"`
+		ShareName    bool   `json:"shareName"`
+		Name         string `json:"name"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+
+	name := ""
+	if payload.ShareName && privacy.ShowsNames(event) {
+		name = strings.TrimSpace(payload.Name)
+		if userId, signedIn := utils.GetUserId(c); signedIn {
+			if user := db.GetUserById(userId); user != nil {
+				name = user.FirstName
+			}
+		}
+		if runes := []rune(name); len(runes) > maxPresenceNameLength {
+			name = string(runes[:maxPresenceNameLength])
+		}
+	}
+
+	if !realtime.DefaultHub.SetPresence(event.Id.Hex(), payload.ConnectionId, payload.Editing, name) {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.ConnectionNotFound})
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// @Summary Shares the times the person on a subscription is selecting
+// @Description Broadcasts the selection to everyone else subscribed to the event, so they see the grid converging live when filling it out together, e.g. on a call. Selections aren't saved, the response is submitted as usual. Not available for events with blind availability, or a privacy level that hides the responses
+// @Tags events
+// @Accept json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{connectionId=string,times=[]int,mode=string} true "Connection id from the subscription's connected event, the unix milliseconds of the selected time increments, and whether they're being marked available, ifNeeded, or unavailable"
+// @Success 200
+// @Router /events/{eventId}/selection [post]
+func shareEventSelection(c *gin.Context) {
+	payload := struct {
+		ConnectionId string  `json:"connectionId" binding:"required"`
+		Times        []int64 `json:"times"`
+		Mode         string  `json:"mode" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	if payload.Mode != "available" && payload.Mode != "ifNeeded" && payload.Mode != "unavailable" {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "mode must be available, ifNeeded, or unavailable"})
+		return
+	}
+	if len(payload.Times) > maxSelectionTimes {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "too many times"})
+		return
+	}
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+	if utils.Coalesce(event.BlindAvailabilityEnabled) || !privacy.ShowsAvailability(event) {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.LiveSelectionsDisabled})
+		return
+	}
+
+	selection := realtime.Selection{Times: payload.Times, Mode: payload.Mode}
+	if selection.Times == nil {
+		selection.Times = make([]int64, 0)
+	}
+	if !realtime.DefaultHub.PublishSelection(event.Id.Hex(), payload.ConnectionId, selection) {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.ConnectionNotFound})
+		return
+	}
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,177 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/gcloud"
+	"schej.it/server/services/notifications"
+	"schej.it/server/utils"
+)
+
+// @Summary Nudges the invitees that haven't responded
+// @Description Sends a reminder by email (and Slack for invitees that linked their account) to every remindee, or group attendee, that hasn't responded and hasn't opted out. Can be used at most once every 24 hours per event
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} object{numNudged=int,nextNudgeAt=string}
+// @Failure 429 {object} object{error=string,nextNudgeAt=string}
+// @Router /events/{eventId}/remind [post]
+func remindInvitees(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	owner := utils.GetAuthUser(c)
+
+	now := time.Now()
+	nextNudgeAt := notifications.GetNextNudgeTime(event, now)
+	if nextNudgeAt.After(now) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": errs.NudgeRateLimited, "nextNudgeAt": nextNudgeAt})
+		return
+	}
+
+	invitees, responded := getInviteesAndResponded(event)
+	pending := notifications.GetPendingInvitees(invitees, responded, db.GetNotificationOptOutEmails(event.Id))
+	if len(pending) == 0 {
+		c.JSON(http.StatusOK, gin.H{"numNudged": 0, "nextNudgeAt": nextNudgeAt})
+		return
+	}
+
+	// Only update if the event wasn't nudged in the meantime
+	result, err := db.EventsCollection.UpdateOne(context.Background(), bson.M{
+		"_id": event.Id,
+		"$or": bson.A{
+			bson.M{"lastNudgedAt": bson.M{"$exists": false}},
+			bson.M{"lastNudgedAt": bson.M{"$lte": primitive.NewDateTimeFromTime(now.Add(-notifications.NudgeInterval))}},
+		},
+	}, bson.M{
+		"$set": bson.M{"lastNudgedAt": primitive.NewDateTimeFromTime(now)},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	if result.ModifiedCount == 0 {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": errs.NudgeRateLimited, "nextNudgeAt": now.Add(notifications.NudgeInterval)})
+		return
+	}
+
+	recordActivity(event, models.ACTIVITY_REMINDER_SENT, owner, "", pending)
+
+	// Send nudges asynchronously
+	go func() {
+		// Recover from panics
+		defer func() {
+			if err := recover(); err != nil {
+				logger.StdErr.Println(err)
+			}
+		}()
+
+		for _, email := range pending {
+			notifications.SendNudge(event, owner, email)
+		}
+	}()
+
+	c.JSON(http.StatusOK, gin.H{"numNudged": len(pending), "nextNudgeAt": now.Add(notifications.NudgeInterval)})
+}
+
+// @Summary Opts an invitee out of nudges for the event
+// @Description Followed from the link in nudge emails. Also cancels the scheduled reminder emails of the remindee
+// @Tags events
+// @Produce plain
+// @Param eventId path string true "Event ID"
+// @Param email query string true "Email of the invitee"
+// @Param token query string true "Token from the opt out link"
+// @Success 200
+// @Router /events/{eventId}/remind/opt-out [get]
+func optOutOfNudges(c *gin.Context) {
+	query := struct {
+		Email string `form:"email" binding:"required"`
+		Token string `form:"token" binding:"required"`
+	}{}
+	if err := c.Bind(&query); err != nil {
+		return
+	}
+
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+	if !notifications.VerifyOptOutToken(event.Id, query.Email, query.Token) {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.InvalidOptOutToken})
+		return
+	}
+
+	db.OptOutOfNotifications(event.Id, query.Email)
+
+	// Cancel the scheduled reminder emails
+	if event.Remindees != nil {
+		index := utils.Find(*event.Remindees, func(r models.Remindee) bool {
+			return strings.EqualFold(r.Email, query.Email)
+		})
+		if index != -1 && len((*event.Remindees)[index].TaskIds) > 0 {
+			for _, taskId := range (*event.Remindees)[index].TaskIds {
+				gcloud.DeleteEmailTask(taskId)
+			}
+			(*event.Remindees)[index].TaskIds = nil
+			db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+				"$set": bson.M{"remindees": event.Remindees},
+			})
+		}
+	}
+
+	c.String(http.StatusOK, fmt.Sprintf("You won't receive any more reminders for %s.", event.Name))
+}
+
+// Returns the event's invitees (its remindees, or its attendees for groups),
+// and the lowercased emails of everyone that responded, treating declined
+// attendees as having responded
+func getInviteesAndResponded(event *models.Event) ([]string, models.Set[string]) {
+	invitees := make([]string, 0)
+	responded := make(models.Set[string])
+	if event.Type == models.GROUP {
+		for _, attendee := range db.GetAttendees(event.Id.Hex()) {
+			if utils.Coalesce(attendee.Declined) {
+				responded[strings.ToLower(attendee.Email)] = struct{}{}
+			}
+			invitees = append(invitees, attendee.Email)
+		}
+	} else {
+		for _, remindee := range utils.Coalesce(event.Remindees) {
+			if utils.Coalesce(remindee.Responded) {
+				responded[strings.ToLower(remindee.Email)] = struct{}{}
+			}
+			invitees = append(invitees, remindee.Email)
+		}
+	}
+	for _, eventResponse := range db.GetEventResponses(event.Id.Hex()) {
+		email := ""
+		if user := db.GetUserById(eventResponse.UserId); user != nil {
+			email = user.Email
+		} else if eventResponse.Response != nil {
+			email = eventResponse.Response.Email
+		}
+		responded[strings.ToLower(email)] = struct{}{}
+	}
+	for userId, response := range event.SignUpResponses {
+		email := response.Email
+		if user := db.GetUserById(userId); user != nil {
+			email = user.Email
+		}
+		responded[strings.ToLower(email)] = struct{}{}
+	}
+
+	return invitees, responded
+}
@@ -0,0 +1,233 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/assistant"
+	"schej.it/server/services/notifications"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// Most emails the organizers of an event get about cancellations in an hour
+const maxRescheduleAlertsPerHour = 3
+
+// Most replacement times proposed at once
+const maxRescheduleProposals = 20
+
+// @Summary Cancels the current user's attendance of the scheduled event
+// @Description If the respondent is required, the organizer is emailed a link to pick a replacement time, at most 3 times an hour
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{guestName=string,responseToken=string,reason=string} true "Name of the guest and the response token they got when responding (only if not signed in), and an optional reason"
+// @Success 200
+// @Router /events/{eventId}/cancel-attendance [post]
+func cancelAttendance(c *gin.Context) {
+	payload := struct {
+		GuestName     *string `json:"guestName"`
+		ResponseToken string  `json:"responseToken"`
+		Reason        string  `json:"reason"`
+	}{}
+	if err := c.Bind(&payload); err != nil {
+		return
+	}
+
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+	if event.ScheduledEvent == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event has not been scheduled"})
+		return
+	}
+
+	// Determine the respondent
+	var userId string
+	if id, signedIn := utils.GetUserId(c); signedIn {
+		userId = id
+	} else if payload.GuestName != nil && len(*payload.GuestName) > 0 {
+		if !checkResponseToken(c, event, *payload.GuestName, payload.ResponseToken) {
+			return
+		}
+		userId = *payload.GuestName
+	} else {
+		c.JSON(http.StatusUnauthorized, responses.Error{Error: errs.NotSignedIn})
+		return
+	}
+	_, response := findResponse(db.GetEventResponses(event.Id.Hex()), userId)
+	if response == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user has not responded to event"})
+		return
+	}
+	for _, cancellation := range event.Cancellations {
+		if cancellation.UserId == userId {
+			c.JSON(http.StatusOK, gin.H{})
+			return
+		}
+	}
+
+	cancellation := models.Cancellation{
+		UserId:    userId,
+		Reason:    payload.Reason,
+		CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+		StartDate: event.ScheduledEvent.StartDate,
+		EndDate:   event.ScheduledEvent.EndDate,
+	}
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$push": bson.M{"cancellations": cancellation},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	// Let the organizer know if a required respondent cancelled
+	windowStart := time.Now().UTC().Truncate(time.Hour)
+	alertsCounterId := fmt.Sprintf("rescheduleAlerts:%s:%d", event.Id.Hex(), windowStart.Unix())
+	if utils.Contains(event.RequiredAttendees, userId) && db.IncrementUsageCounter(alertsCounterId, windowStart.Add(time.Hour)) <= maxRescheduleAlertsPerHour {
+		go func() {
+			// Recover from panics
+			defer func() {
+				if err := recover(); err != nil {
+					logger.StdErr.Println(err)
+				}
+			}()
+
+			name := userId
+			if user := db.GetUserById(userId); user != nil {
+				name = user.FirstName
+			}
+			eventUrl := fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId())
+			body := fmt.Sprintf("%s can no longer make \"%s\".\n\nPick a new time from the suggested replacements here: %s\n", name, event.Name, eventUrl)
+			if len(payload.Reason) > 0 {
+				body += fmt.Sprintf("\nReason: %s\n", payload.Reason)
+			}
+			notifications.NotifyOrganizers(event, models.RESCHEDULE_ALERT, "", func(organizer *models.User) {
+				utils.SendEmail(organizer.Email, fmt.Sprintf("%s needs to be rescheduled", event.Name), body, "text/plain")
+			}, fmt.Sprintf("%s can no longer make <%s|%s>, pick a new time from the suggested replacements", name, eventUrl, event.Name))
+		}()
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Proposes replacement times for a scheduled event
+// @Description Computes slots that work for all required respondents from the availability that is still valid (i.e. excluding the cancelled time for respondents that cancelled), the respondents' connected calendars, and the booked resources. Each proposal contains the payload to pass to POST /events/{eventId}/finalize to re-finalize the event
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param limit query int false "Maximum number of proposals, defaults to 3, at most 20"
+// @Param timezoneOffset query int false "Client's timezone offset in minutes, used for explanations"
+// @Success 200 {object} object{needsReschedule=bool,cancellations=[]models.Cancellation,proposals=[]object{startDate=string,endDate=string,explanation=string,finalize=object}}
+// @Router /events/{eventId}/reschedule-proposals [get]
+func getRescheduleProposals(c *gin.Context) {
+	query := struct {
+		Limit          *int `form:"limit"`
+		TimezoneOffset *int `form:"timezoneOffset"`
+	}{}
+	if err := c.Bind(&query); err != nil {
+		return
+	}
+
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	user := utils.GetAuthUser(c)
+	if event.ScheduledEvent == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event has not been scheduled"})
+		return
+	}
+
+	loc := utils.GetUserLocation(user)
+	if query.TimezoneOffset != nil {
+		loc = time.FixedZone("UserOffset", -*query.TimezoneOffset*60)
+	}
+
+	needsReschedule := false
+	for _, cancellation := range event.Cancellations {
+		if utils.Contains(event.RequiredAttendees, cancellation.UserId) {
+			needsReschedule = true
+		}
+	}
+
+	// Availability for the cancelled time is no longer valid
+	eventResponses := db.GetEventResponses(event.Id.Hex())
+	respondents := scheduling.GetRespondents(eventResponses)
+	increment := scheduling.GetTimeIncrement(event)
+	for _, cancellation := range event.Cancellations {
+		for i := range respondents {
+			if respondents[i].Id == cancellation.UserId {
+				respondents[i].RemoveAvailability(cancellation.StartDate.Time(), cancellation.EndDate.Time(), increment)
+			}
+		}
+	}
+
+	// Remove times that are now busy on connected calendars
+	now := time.Now()
+	increments := scheduling.GetTimeIncrements(event)
+	if len(increments) > 0 && increments[len(increments)-1].After(now) {
+		scheduling.RemoveCalendarBusyTimes(event, eventResponses, respondents, now, increments[len(increments)-1].Add(increment))
+	}
+
+	scheduledStart := event.ScheduledEvent.StartDate.Time()
+	scheduledEnd := event.ScheduledEvent.EndDate.Time()
+	limit := 3
+	if query.Limit != nil && *query.Limit > 0 && *query.Limit <= maxRescheduleProposals {
+		limit = *query.Limit
+	}
+	resourcesExclude := getResourcesExclude(event, db.GetResourcesByIds(event.ResourceIds))
+	suggestions := assistant.Suggest(event, respondents, assistant.Constraints{
+		MeetingLength: scheduledEnd.Sub(scheduledStart),
+		Required:      event.RequiredAttendees,
+		Limit:         limit,
+		RequireAll:    true,
+		Weights:       getAttendanceWeights(event, respondents),
+		Exclude: func(start time.Time, end time.Time) bool {
+			// Exclude past slots, the currently scheduled time, and times the
+			// booked resources are busy
+			if start.Before(now) || (start.Before(scheduledEnd) && end.After(scheduledStart)) {
+				return true
+			}
+			return resourcesExclude != nil && resourcesExclude(start, end)
+		},
+	}, loc)
+
+	proposals := make([]gin.H, 0)
+	for _, suggestion := range suggestions {
+		startDate := primitive.NewDateTimeFromTime(suggestion.Start)
+		endDate := primitive.NewDateTimeFromTime(suggestion.Start.Add(scheduledEnd.Sub(scheduledStart)))
+		proposals = append(proposals, gin.H{
+			"startDate":   startDate,
+			"endDate":     endDate,
+			"explanation": suggestion.Explanation,
+			"available":   suggestion.Available,
+			"ifNeeded":    suggestion.IfNeeded,
+			"unavailable": suggestion.Unavailable,
+			"finalize": gin.H{
+				"startDate": startDate,
+				"endDate":   endDate,
+				"required":  event.RequiredAttendees,
+			},
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"needsReschedule": needsReschedule,
+		"cancellations":   event.Cancellations,
+		"proposals":       proposals,
+	})
+}
@@ -0,0 +1,144 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"schej.it/server/db"
+	"schej.it/server/googlechat"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/rotation"
+	"schej.it/server/utils"
+)
+
+// @Summary Sets the rotation of the event
+// @Description Makes the event a recurring meeting across timezones whose time rotates between the candidate times, so that the hours outside each region's working hours are shared fairly. The history of scheduled occurrences is kept. Pass a null rotation to remove it
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{rotation=models.Rotation} true "The rotation"
+// @Success 200 {object} models.Rotation
+// @Router /events/{eventId}/rotation [put]
+func setRotation(c *gin.Context) {
+	payload := struct {
+		Rotation *models.Rotation `json:"rotation"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	if payload.Rotation != nil {
+		if err := rotation.Validate(payload.Rotation); err != nil {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+			return
+		}
+	}
+
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	update := bson.M{"$unset": bson.M{"rotation": ""}}
+	if payload.Rotation != nil {
+		payload.Rotation.History = nil
+		if event.Rotation != nil {
+			payload.Rotation.History = event.Rotation.History
+		}
+		update = bson.M{"$set": bson.M{"rotation": payload.Rotation}}
+	}
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, update)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.JSON(http.StatusOK, payload.Rotation)
+}
+
+// @Summary Previews the upcoming occurrences of the event's rotation
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param count query int false "Number of occurrences, defaults to 4"
+// @Success 200 {object} object{burden=map[string]float64,occurrences=[]models.RotationOccurrence}
+// @Router /events/{eventId}/rotation/plan [get]
+func getRotationPlan(c *gin.Context) {
+	query := struct {
+		Count *int `form:"count"`
+	}{}
+	if err := c.Bind(&query); err != nil {
+		return
+	}
+
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	if event.Rotation == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event has no rotation"})
+		return
+	}
+
+	count := 4
+	if query.Count != nil && *query.Count > 0 && *query.Count <= 52 {
+		count = *query.Count
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"burden":      rotation.GetBurden(event.Rotation),
+		"occurrences": rotation.Plan(event.Rotation, count),
+	})
+}
+
+// @Summary Schedules the next occurrence of the event's rotation
+// @Description Finalizes the event at the next occurrence of the rotation and adds it to the rotation's history, which later occurrences take into account
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} object{occurrence=models.RotationOccurrence,burden=map[string]float64}
+// @Router /events/{eventId}/rotation/next [post]
+func scheduleNextRotation(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	if event.Rotation == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event has no rotation"})
+		return
+	}
+
+	occurrence := rotation.Plan(event.Rotation, 1)[0]
+	event.Rotation.History = append(event.Rotation.History, occurrence)
+	event.ScheduledEvent = &models.CalendarEvent{
+		Summary:   event.Name,
+		StartDate: occurrence.StartDate,
+		EndDate:   occurrence.EndDate,
+	}
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$push":  bson.M{"rotation.history": occurrence},
+		"$set":   bson.M{"scheduledEvent": event.ScheduledEvent},
+		"$unset": bson.M{"cancellations": "", "sessions": ""},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	recordActivity(event, models.ACTIVITY_FINALIZED, utils.GetAuthUser(c), "", nil)
+
+	// Announce the scheduled time
+	go func() {
+		// Recover from panics
+		defer func() {
+			if err := recover(); err != nil {
+				logger.StdErr.Println(err)
+			}
+		}()
+
+		googlechat.SendEventFinalizedMessage(event)
+	}()
+
+	c.JSON(http.StatusOK, gin.H{"occurrence": occurrence, "burden": rotation.GetBurden(event.Rotation)})
+}
@@ -0,0 +1,195 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/services/sessions"
+	"schej.it/server/utils"
+)
+
+// @Summary Finalizes the event at several times
+// @Description Finalizes the event as multiple sessions, e.g. two training sessions so everyone can attend one, replacing any single scheduled time. The sessions are either given, or the count slots that let the most respondents attend one of them are picked. Each respondent is assigned to at most one session they can make, and is emailed their session with a link to confirm
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{sessions=[]object{startDate=string,endDate=string},count=int,durationMinutes=int,capacity=int} true "Either the sessions, or the number of sessions to pick and their length in minutes (defaults to the event's time increment). Capacity is the maximum number of respondents per session, 0 for unlimited"
+// @Success 200 {object} object{sessions=[]models.EventSession,unassigned=[]string}
+// @Router /events/{eventId}/sessions [post]
+func finalizeSessions(c *gin.Context) {
+	payload := struct {
+		Sessions []struct {
+			StartDate primitive.DateTime `json:"startDate" binding:"required"`
+			EndDate   primitive.DateTime `json:"endDate" binding:"required"`
+		} `json:"sessions" binding:"dive"`
+		Count           int `json:"count"`
+		DurationMinutes int `json:"durationMinutes"`
+		Capacity        int `json:"capacity"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	if len(payload.Sessions) == 0 && payload.Count <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sessions or count is required"})
+		return
+	}
+	if payload.DurationMinutes < 0 || payload.Capacity < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "durationMinutes and capacity can't be negative"})
+		return
+	}
+
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	user := utils.GetAuthUser(c)
+	respondents := scheduling.GetRespondents(db.GetEventResponses(event.Id.Hex()))
+
+	sessionsList := make([]models.EventSession, 0)
+	if len(payload.Sessions) > 0 {
+		for _, session := range payload.Sessions {
+			if session.EndDate <= session.StartDate {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "endDate must be after startDate"})
+				return
+			}
+			sessionsList = append(sessionsList, models.EventSession{
+				Id:        primitive.NewObjectID(),
+				StartDate: session.StartDate,
+				EndDate:   session.EndDate,
+			})
+		}
+	} else {
+		ranked := scheduling.RankSlots(event, respondents, scheduling.Options{
+			MeetingLength: time.Duration(payload.DurationMinutes) * time.Minute,
+			Exclude:       scheduling.ExcludePast(event, time.Now()),
+		})
+		for _, slot := range sessions.PickSlots(ranked, payload.Count) {
+			sessionsList = append(sessionsList, models.EventSession{
+				Id:        primitive.NewObjectID(),
+				StartDate: primitive.NewDateTimeFromTime(slot.Start),
+				EndDate:   primitive.NewDateTimeFromTime(slot.End),
+			})
+		}
+		if len(sessionsList) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "no upcoming slots to pick from"})
+			return
+		}
+	}
+	unassigned := sessions.Assign(event, respondents, sessionsList, payload.Capacity)
+
+	event.Sessions = sessionsList
+	event.ScheduledEvent = nil
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$set":   bson.M{"sessions": sessionsList},
+		"$unset": bson.M{"scheduledEvent": "", "cancellations": ""},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	details := make([]string, 0)
+	for _, session := range sessionsList {
+		details = append(details, session.StartDate.Time().UTC().Format(time.RFC3339))
+	}
+	recordActivity(event, models.ACTIVITY_FINALIZED, user, "", details)
+	enqueueFinalizedWebhook(event)
+	postFinalizedToIssueTracker(event)
+
+	// Let each attendee know which session they were assigned to
+	go func() {
+		// Recover from panics
+		defer func() {
+			if err := recover(); err != nil {
+				logger.StdErr.Println(err)
+			}
+		}()
+
+		eventUrl := fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId())
+		for _, session := range sessionsList {
+			when := session.StartDate.Time().UTC().Format("Mon Jan 2, 15:04 MST")
+			for _, attendee := range session.Attendees {
+				if len(attendee.Email) == 0 {
+					continue
+				}
+				body := fmt.Sprintf(
+					"Hi %s,\n\n%s is happening at several times, and you're signed up for the one on %s.\n\nConfirm you'll be there: %s\n\nView the event: %s\n",
+					attendee.Name, event.Name, when, sessions.GetConfirmationUrl(event.Id, session.Id, attendee.UserId), eventUrl,
+				)
+				utils.SendEmail(attendee.Email, fmt.Sprintf("Your session for %s", event.Name), body, "text/plain")
+			}
+		}
+	}()
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessionsList, "unassigned": unassigned})
+}
+
+// @Summary Confirms a respondent will attend their session
+// @Description Followed from the link in the email respondents receive when the event is finalized as multiple sessions
+// @Tags events
+// @Produce plain
+// @Param eventId path string true "Event ID"
+// @Param sessionId path string true "Session ID"
+// @Param userId query string true "Id of the respondent"
+// @Param token query string true "Token from the confirmation link"
+// @Success 200
+// @Router /events/{eventId}/sessions/{sessionId}/confirm [get]
+func confirmSession(c *gin.Context) {
+	query := struct {
+		UserId string `form:"userId" binding:"required"`
+		Token  string `form:"token" binding:"required"`
+	}{}
+	if err := c.Bind(&query); err != nil {
+		return
+	}
+
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+	sessionId, err := primitive.ObjectIDFromHex(c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.SessionNotFound})
+		return
+	}
+	index := utils.Find(event.Sessions, func(s models.EventSession) bool { return s.Id == sessionId })
+	if index == -1 {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.SessionNotFound})
+		return
+	}
+	if !sessions.VerifyConfirmationToken(event.Id, sessionId, query.UserId, query.Token) {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.InvalidConfirmationToken})
+		return
+	}
+	session := event.Sessions[index]
+	if utils.Find(session.Attendees, func(a models.SessionAttendee) bool { return a.UserId == query.UserId }) == -1 {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.SessionNotFound})
+		return
+	}
+
+	_, err = db.EventsCollection.UpdateOne(context.Background(),
+		bson.M{"_id": event.Id},
+		bson.M{"$set": bson.M{"sessions.$[session].attendees.$[attendee].confirmedAt": primitive.NewDateTimeFromTime(time.Now())}},
+		options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+			bson.M{"session._id": sessionId},
+			bson.M{"attendee.userId": query.UserId},
+		}}),
+	)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.String(http.StatusOK, fmt.Sprintf("Thanks for confirming, see you at %s on %s.", event.Name, session.StartDate.Time().UTC().Format("Mon Jan 2, 15:04 MST")))
+}
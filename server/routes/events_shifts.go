@@ -0,0 +1,354 @@
+package routes
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/services/shifts"
+	"schej.it/server/utils"
+)
+
+// Saves the shift assignments of the event
+func updateShiftAssignments(event *models.Event) {
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$set": bson.M{"shiftAssignments": event.ShiftAssignments},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// @Summary Sets the shifts of the event
+// @Description Turns the event into a shift schedule. Assignments to removed shifts are deleted
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{shifts=[]models.Shift,maxShiftsPerVolunteer=int} true "Shifts, and the maximum number of shifts per volunteer (0 for unlimited)"
+// @Success 200 {object} []models.Shift
+// @Router /events/{eventId}/shifts [put]
+func setShifts(c *gin.Context) {
+	payload := struct {
+		Shifts                []models.Shift `json:"shifts" binding:"required"`
+		MaxShiftsPerVolunteer *int           `json:"maxShiftsPerVolunteer"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	for i := range payload.Shifts {
+		shift := &payload.Shifts[i]
+		if shift.Id.IsZero() {
+			shift.Id = primitive.NewObjectID()
+		}
+		if shift.EndDate <= shift.StartDate || shift.Needed <= 0 {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: fmt.Sprintf("invalid shift %s", shift.Name)})
+			return
+		}
+	}
+
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	shiftIds := make(models.Set[primitive.ObjectID])
+	for _, shift := range payload.Shifts {
+		shiftIds[shift.Id] = struct{}{}
+	}
+	assignments := make([]models.ShiftAssignment, 0)
+	for _, assignment := range event.ShiftAssignments {
+		if _, ok := shiftIds[assignment.ShiftId]; ok {
+			assignments = append(assignments, assignment)
+		}
+	}
+
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$set": bson.M{
+			"isShiftSchedule":       true,
+			"shifts":                payload.Shifts,
+			"maxShiftsPerVolunteer": utils.Coalesce(payload.MaxShiftsPerVolunteer),
+			"shiftAssignments":      assignments,
+		},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.JSON(http.StatusOK, payload.Shifts)
+}
+
+// @Summary Assigns volunteers to the event's shifts
+// @Description Fills as many spots as possible from the respondents' availability, preferring available over "if needed" and spreading shifts evenly across volunteers. Manual assignments are kept, all others are replaced
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} []shiftSchedule
+// @Router /events/{eventId}/shifts/assign [post]
+func assignShifts(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+	if !utils.Coalesce(event.IsShiftSchedule) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.EventNotShiftSchedule})
+		return
+	}
+
+	increment := scheduling.GetTimeIncrement(event)
+	shiftsList := make([]shifts.Shift, 0)
+	for _, shift := range utils.Coalesce(event.Shifts) {
+		shiftsList = append(shiftsList, shifts.Shift{
+			Id:     shift.Id.Hex(),
+			Start:  shift.StartDate.Time(),
+			End:    shift.EndDate.Time(),
+			Needed: shift.Needed,
+		})
+	}
+
+	volunteers := make([]shifts.Volunteer, 0)
+	for _, respondent := range scheduling.GetRespondents(db.GetEventResponses(event.Id.Hex())) {
+		volunteer := shifts.Volunteer{Id: respondent.Id, Available: make(models.Set[string]), IfNeeded: make(models.Set[string])}
+		for _, shift := range shiftsList {
+			if respondent.IsAvailable(shift.Start, shift.End, increment, false) {
+				volunteer.Available[shift.Id] = struct{}{}
+			} else if respondent.IsAvailable(shift.Start, shift.End, increment, true) {
+				volunteer.IfNeeded[shift.Id] = struct{}{}
+			}
+		}
+		volunteers = append(volunteers, volunteer)
+	}
+
+	locked := make(map[string][]string)
+	lockedSet := make(models.Set[string])
+	for _, assignment := range event.ShiftAssignments {
+		if assignment.Locked {
+			locked[assignment.ShiftId.Hex()] = append(locked[assignment.ShiftId.Hex()], assignment.UserId)
+			lockedSet[assignment.ShiftId.Hex()+"/"+assignment.UserId] = struct{}{}
+		}
+	}
+
+	result := shifts.Assign(shiftsList, volunteers, shifts.Options{
+		MaxShiftsPerVolunteer: utils.Coalesce(event.MaxShiftsPerVolunteer),
+		Locked:                locked,
+	})
+
+	event.ShiftAssignments = make([]models.ShiftAssignment, 0)
+	for shiftId, userIds := range result.Assignments {
+		for _, userId := range userIds {
+			_, isLocked := lockedSet[shiftId+"/"+userId]
+			event.ShiftAssignments = append(event.ShiftAssignments, models.ShiftAssignment{
+				ShiftId: utils.StringToObjectID(shiftId),
+				UserId:  userId,
+				Locked:  isLocked,
+			})
+		}
+	}
+	updateShiftAssignments(event)
+
+	c.JSON(http.StatusOK, getShiftSchedule(event, true))
+}
+
+// @Summary Manually sets the volunteers assigned to a shift
+// @Description The assignments are kept when volunteers are reassigned
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param shiftId path string true "Shift ID"
+// @Param payload body object{userIds=[]string} true "Ids of the respondents to assign (user id, or name for guests)"
+// @Success 200 {object} []shiftSchedule
+// @Router /events/{eventId}/shifts/{shiftId}/assignees [put]
+func setShiftAssignees(c *gin.Context) {
+	payload := struct {
+		UserIds []string `json:"userIds" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	var shift *models.Shift
+	for i, s := range utils.Coalesce(event.Shifts) {
+		if s.Id.Hex() == c.Param("shiftId") {
+			shift = &(*event.Shifts)[i]
+		}
+	}
+	if shift == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.ShiftNotFound})
+		return
+	}
+
+	assignments := make([]models.ShiftAssignment, 0)
+	for _, assignment := range event.ShiftAssignments {
+		if assignment.ShiftId != shift.Id {
+			assignments = append(assignments, assignment)
+		}
+	}
+	for _, userId := range payload.UserIds {
+		assignments = append(assignments, models.ShiftAssignment{ShiftId: shift.Id, UserId: userId, Locked: true})
+	}
+	event.ShiftAssignments = assignments
+	updateShiftAssignments(event)
+
+	c.JSON(http.StatusOK, getShiftSchedule(event, true))
+}
+
+// @Summary Publishes or unpublishes the event's shift schedule
+// @Description Published schedules can be viewed by anyone with the link
+// @Tags events
+// @Accept json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{published=bool} true "Whether the schedule is published"
+// @Success 200
+// @Router /events/{eventId}/shifts/publish [post]
+func publishShiftSchedule(c *gin.Context) {
+	payload := struct {
+		Published *bool `json:"published" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	update := bson.M{"$unset": bson.M{"shiftsPublishedAt": ""}}
+	if *payload.Published {
+		update = bson.M{"$set": bson.M{"shiftsPublishedAt": primitive.NewDateTimeFromTime(time.Now())}}
+	}
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, update)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// A shift and the volunteers assigned to it
+type shiftSchedule struct {
+	Shift     models.Shift    `json:"shift"`
+	Assignees []shiftAssignee `json:"assignees"`
+	Unfilled  int             `json:"unfilled"`
+}
+
+type shiftAssignee struct {
+	UserId string `json:"userId"`
+	Name   string `json:"name"`
+	Email  string `json:"email,omitempty"`
+	Locked bool   `json:"locked,omitempty"`
+}
+
+// Returns the event's shifts with their assignees, sorted by start time.
+// Emails and whether assignments are locked are only included for the organizer
+func getShiftSchedule(event *models.Event, isOwner bool) []shiftSchedule {
+	respondents := make(map[string]scheduling.Respondent)
+	for _, respondent := range scheduling.GetRespondents(db.GetEventResponses(event.Id.Hex())) {
+		respondents[respondent.Id] = respondent
+	}
+
+	schedule := make([]shiftSchedule, 0)
+	for _, shift := range utils.Coalesce(event.Shifts) {
+		entry := shiftSchedule{Shift: shift, Assignees: make([]shiftAssignee, 0)}
+		for _, assignment := range event.ShiftAssignments {
+			if assignment.ShiftId != shift.Id {
+				continue
+			}
+			assignee := shiftAssignee{UserId: assignment.UserId, Name: assignment.UserId}
+			if respondent, ok := respondents[assignment.UserId]; ok {
+				assignee.Name = respondent.Name
+				if isOwner {
+					assignee.Email = respondent.Email
+				}
+			}
+			if isOwner {
+				assignee.Locked = assignment.Locked
+			}
+			entry.Assignees = append(entry.Assignees, assignee)
+		}
+		if missing := shift.Needed - len(entry.Assignees); missing > 0 {
+			entry.Unfilled = missing
+		}
+		schedule = append(schedule, entry)
+	}
+	sort.SliceStable(schedule, func(i, j int) bool { return schedule[i].Shift.StartDate < schedule[j].Shift.StartDate })
+
+	return schedule
+}
+
+// @Summary Gets the event's shift schedule
+// @Description Anyone can view a published schedule, only the organizer can view it before it's published
+// @Tags events
+// @Produce json
+// @Produce text/csv
+// @Param eventId path string true "Event ID"
+// @Param format query string false "json (default) or csv"
+// @Param timezoneOffset query int false "Client's timezone offset in minutes, used for times in the csv export"
+// @Success 200 {object} []shiftSchedule
+// @Router /events/{eventId}/shifts/schedule [get]
+func getPublishedShiftSchedule(c *gin.Context) {
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+
+	userId, _ := utils.GetUserId(c)
+	isOwner := len(userId) > 0 && event.OwnerId.Hex() == userId
+	if !isOwner && event.ShiftsPublishedAt == nil {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.ShiftScheduleNotPublished})
+		return
+	}
+
+	schedule := getShiftSchedule(event, isOwner)
+	if c.Query("format") != "csv" {
+		c.JSON(http.StatusOK, schedule)
+		return
+	}
+
+	loc := time.UTC
+	if offset := c.Query("timezoneOffset"); offset != "" {
+		if minutes, err := strconv.Atoi(offset); err == nil {
+			loc = time.FixedZone("UserOffset", -minutes*60)
+		}
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", event.Name+" shifts.csv"))
+	c.Header("Content-Type", "text/csv")
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"Shift", "Start", "End", "Volunteers", "Unfilled"})
+	for _, entry := range schedule {
+		names := make([]string, 0)
+		for _, assignee := range entry.Assignees {
+			names = append(names, assignee.Name)
+		}
+		w.Write([]string{
+			entry.Shift.Name,
+			entry.Shift.StartDate.Time().In(loc).Format("2006-01-02 15:04"),
+			entry.Shift.EndDate.Time().In(loc).Format("2006-01-02 15:04"),
+			strings.Join(names, ", "),
+			fmt.Sprint(entry.Unfilled),
+		})
+	}
+	w.Flush()
+}
@@ -0,0 +1,81 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/services/expiry"
+	"schej.it/server/services/notifications"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// Number of best times in an event's summary
+const numSummarySlots = 3
+
+type summarySlot struct {
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	NumAvailable int       `json:"numAvailable"`
+	NumIfNeeded  int       `json:"numIfNeeded"`
+}
+
+type eventSummary struct {
+	Name         string `json:"name"`
+	NumResponses int    `json:"numResponses"`
+	IsScheduled  bool   `json:"isScheduled"`
+
+	// Best times that at least one respondent can make, best first
+	TopSlots []summarySlot `json:"topSlots"`
+
+	// Emails of the invitees that haven't responded yet
+	MissingInvitees []string `json:"missingInvitees"`
+
+	// When the poll closes, null if it never does
+	Deadline *time.Time `json:"deadline"`
+}
+
+// @Summary Gets a glanceable summary of the event for its organizer
+// @Description Small payload for widgets and the Slack home tab, with the number of responses, the best 3 times, the invitees that haven't responded, and when the poll closes
+// @Tags events
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} eventSummary
+// @Router /events/{eventId}/summary [get]
+func getEventSummary(c *gin.Context) {
+	event := getOwnedEvent(c)
+	if event == nil {
+		return
+	}
+
+	invitees, responded := getInviteesAndResponded(event)
+	summary := eventSummary{
+		Name:            event.Name,
+		IsScheduled:     event.ScheduledEvent != nil,
+		TopSlots:        make([]summarySlot, 0),
+		MissingInvitees: notifications.GetPendingInvitees(invitees, responded, nil),
+		Deadline:        expiry.GetExpiry(event, expiry.GetDefaultDays()),
+	}
+
+	if utils.Coalesce(event.IsSignUpForm) {
+		summary.NumResponses = len(event.SignUpResponses)
+	} else {
+		respondents := scheduling.GetRespondents(db.GetEventResponses(event.Id.Hex()))
+		summary.NumResponses = len(respondents)
+		for _, slot := range scheduling.RankSlots(event, respondents, scheduling.Options{}) {
+			if len(summary.TopSlots) == numSummarySlots || len(slot.Available)+len(slot.IfNeeded) == 0 {
+				break
+			}
+			summary.TopSlots = append(summary.TopSlots, summarySlot{
+				Start:        slot.Start,
+				End:          slot.End,
+				NumAvailable: len(slot.Available),
+				NumIfNeeded:  len(slot.IfNeeded),
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,84 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/utils"
+)
+
+// Returns the shift between the expected timezone offset of a respondent and
+// the offset of the client they submitted from, or nil if either is unknown
+// or they match
+func getTimezoneShift(expectedOffset *int, currentOffset *int) *models.TimezoneShift {
+	if expectedOffset == nil || currentOffset == nil || *expectedOffset == *currentOffset {
+		return nil
+	}
+	return &models.TimezoneShift{PreviousOffset: *expectedOffset, CurrentOffset: *currentOffset}
+}
+
+// @Summary Confirms a response flagged as submitted from a different timezone
+// @Description Clears the timezone shift flag of the response once the respondent confirms their availability is correct. Signed in users can also update their profile's timezone to the one they submitted from
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param eventId path string true "Event ID"
+// @Param payload body object{guest=bool,name=string,updateProfile=bool} true "Whether the respondent is a guest and their name, and whether to update the user's profile timezone"
+// @Success 200
+// @Router /events/{eventId}/response/confirm-timezone [post]
+func confirmResponseTimezone(c *gin.Context) {
+	payload := struct {
+		Guest         *bool  `json:"guest" binding:"required"`
+		Name          string `json:"name"`
+		UpdateProfile bool   `json:"updateProfile"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	event := db.GetEventByEitherId(c.Param("eventId"))
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+
+	userId := payload.Name
+	if !*payload.Guest {
+		userIdString, ok := utils.GetUserId(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, responses.Error{Error: errs.NotSignedIn})
+			return
+		}
+		userId = userIdString
+	}
+	index, response := findResponse(db.GetEventResponses(event.Id.Hex()), userId)
+	if index == -1 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user has not responded to event"})
+		return
+	}
+
+	_, err := db.EventResponsesCollection.UpdateOne(context.Background(), bson.M{
+		"eventId": event.Id,
+		"userId":  userId,
+	}, bson.M{"$unset": bson.M{"response.timezoneShift": ""}})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	if !*payload.Guest && payload.UpdateProfile && response.TimezoneShift != nil {
+		_, err := db.UsersCollection.UpdateByID(context.Background(), response.UserId, bson.M{
+			"$set": bson.M{"timezoneOffset": response.TimezoneShift.CurrentOffset},
+		})
+		if err != nil {
+			logger.StdErr.Panicln(err)
+		}
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,78 @@
+package routes
+
+import (
+	"fmt"
+
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services/hooks"
+	"schej.it/server/services/webhooks"
+	"schej.it/server/utils"
+)
+
+// Summary of the event included in every webhook payload
+func getWebhookEventData(event *models.Event) map[string]interface{} {
+	return map[string]interface{}{
+		"_id":  event.Id.Hex(),
+		"name": event.Name,
+		"url":  fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId()),
+	}
+}
+
+// Queues a response.created or response.updated delivery to the owner's
+// webhooks, and calls the afterResponseCreated hooks for new responses
+func enqueueResponseWebhook(event *models.Event, eventType models.WebhookEventType, userId string) {
+	go func() {
+		// Recover from panics
+		defer func() {
+			if err := recover(); err != nil {
+				logger.StdErr.Println(err)
+			}
+		}()
+
+		_, response := findResponse(db.GetEventResponses(event.Id.Hex()), userId)
+		if response == nil {
+			return
+		}
+		respondent := map[string]interface{}{
+			"userId":       userId,
+			"name":         response.Name,
+			"email":        response.Email,
+			"answers":      response.Answers,
+			"availability": response.Availability,
+			"ifNeeded":     response.IfNeeded,
+		}
+		if user := db.GetUserById(userId); user != nil {
+			respondent["name"] = user.FirstName + " " + user.LastName
+			respondent["email"] = user.Email
+		}
+
+		data := map[string]interface{}{
+			"event":      getWebhookEventData(event),
+			"respondent": respondent,
+		}
+		webhooks.Enqueue(event, eventType, data)
+		if eventType == models.WEBHOOK_RESPONSE_CREATED {
+			hooks.Dispatch(hooks.AFTER_RESPONSE_CREATED, data)
+		}
+	}()
+}
+
+// Queues an event.finalized delivery to the owner's webhooks
+func enqueueFinalizedWebhook(event *models.Event) {
+	go func() {
+		// Recover from panics
+		defer func() {
+			if err := recover(); err != nil {
+				logger.StdErr.Println(err)
+			}
+		}()
+
+		webhooks.Enqueue(event, models.WEBHOOK_EVENT_FINALIZED, map[string]interface{}{
+			"event":          getWebhookEventData(event),
+			"scheduledEvent": event.ScheduledEvent,
+			"sessions":       event.Sessions,
+		})
+	}()
+}
@@ -1,15 +1,23 @@
 package routes
 
 import (
+	"context"
 	"net/http"
+	"strings"
 
-	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
 	"schej.it/server/middleware"
 	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/notifications"
+	"schej.it/server/services/organizations"
+	"schej.it/server/services/policies"
+	"schej.it/server/utils"
 )
 
 func InitFolders(router *gin.RouterGroup) {
@@ -21,25 +29,30 @@ func InitFolders(router *gin.RouterGroup) {
 	folderRouter.GET("/:folderId", GetFolder)
 	folderRouter.PATCH("/:folderId", UpdateFolder)
 	folderRouter.DELETE("/:folderId", DeleteFolder)
+	folderRouter.GET("/:folderId/insights", getFolderInsights)
+	folderRouter.PUT("/:folderId/issue-tracker", setFolderIssueTracker)
+	folderRouter.DELETE("/:folderId/issue-tracker", deleteFolderIssueTracker)
+	folderRouter.PUT("/:folderId/default-co-organizers", setFolderDefaultCoOrganizers)
 }
 
 // @Summary Get all folders
 // @Tags folders
 // @Produce json
+// @Description Includes the folders of the user's organizations
 // @Success 200 {array} models.Folder "A list of all folders for the user"
 // @Failure 400 {object} map[string]string "Invalid user ID"
 // @Failure 500 {object} map[string]string "Failed to get folders"
 // @Router /user/folders [get]
 func GetAllFolders(c *gin.Context) {
-	session := sessions.Default(c)
-	userIdString := session.Get("userId").(string)
+	userIdString, _ := utils.GetUserId(c)
 	userId, err := primitive.ObjectIDFromHex(userIdString)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	folders, err := db.GetAllFolders(userId)
+	orgIds := utils.Map(db.GetOrganizationsByUserId(userId), func(org models.Organization) primitive.ObjectID { return org.Id })
+	folders, err := db.GetAllFolders(userId, orgIds)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get folders"})
 		return
@@ -57,32 +70,17 @@ func GetAllFolders(c *gin.Context) {
 // @Produce json
 // @Param folderId path string true "Folder ID"
 // @Success 200 {object} models.Folder "The folder object with events"
-// @Failure 400 {object} map[string]string "Invalid user ID or folder ID"
+// @Failure 400 {object} map[string]string "Invalid folder ID"
 // @Failure 404 {object} map[string]string "Folder not found"
 // @Failure 500 {object} map[string]string "Failed to get events in folder"
 // @Router /user/folders/{folderId} [get]
 func GetFolder(c *gin.Context) {
-	session := sessions.Default(c)
-	userIdString := session.Get("userId").(string)
-	userId, err := primitive.ObjectIDFromHex(userIdString)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
-	folderId, err := primitive.ObjectIDFromHex(c.Param("folderId"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
-		return
-	}
-
-	folder, err := db.GetFolderById(folderId, userId)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
+	folder := getAccessibleFolder(c, c.Param("folderId"), false)
+	if folder == nil {
 		return
 	}
 
-	events, err := db.GetEventsInFolder(folderId, userId)
+	events, err := db.GetFolderEventIds(folder)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get events in folder"})
 		return
@@ -100,15 +98,17 @@ type CreateFolderResponse struct {
 // @Tags folders
 // @Accept json
 // @Produce json
-// @Param payload body object{name=string,color=string} true "Folder name and optional color"
+// @Description Folders created in an organization are shared with its members. Only its admins can create them
+// @Param payload body object{name=string,color=string,organizationId=string} true "Folder name, optional color, and optional organization"
 // @Success 201 {object} CreateFolderResponse "The ID of the created folder"
 // @Failure 400 {object} map[string]string "Invalid user ID or request body"
 // @Failure 500 {object} map[string]string "Failed to create folder"
 // @Router /user/folders [post]
 func CreateFolder(c *gin.Context) {
 	var body struct {
-		Name  string  `json:"name" binding:"required"`
-		Color *string `json:"color"`
+		Name           string              `json:"name" binding:"required"`
+		Color          *string             `json:"color"`
+		OrganizationId *primitive.ObjectID `json:"organizationId"`
 	}
 
 	if err := c.ShouldBindJSON(&body); err != nil {
@@ -116,18 +116,26 @@ func CreateFolder(c *gin.Context) {
 		return
 	}
 
-	session := sessions.Default(c)
-	userIdString := session.Get("userId").(string)
+	userIdString, _ := utils.GetUserId(c)
 	userId, err := primitive.ObjectIDFromHex(userIdString)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
+	if body.OrganizationId != nil {
+		org := db.GetOrganizationById(body.OrganizationId.Hex())
+		if org == nil || !organizations.IsAdmin(org, userId) {
+			c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotOrganizationAdmin})
+			return
+		}
+	}
+
 	folder := models.Folder{
-		UserId: userId,
-		Name:   body.Name,
-		Color:  body.Color,
+		UserId:         userId,
+		OrganizationId: body.OrganizationId,
+		Name:           body.Name,
+		Color:          body.Color,
 	}
 
 	id, err := db.CreateFolder(&folder)
@@ -146,15 +154,10 @@ func CreateFolder(c *gin.Context) {
 // @Param folderId path string true "Folder ID"
 // @Param payload body object{name=string,color=string} true "New folder name and/or color"
 // @Success 200
-// @Failure 400 {object} map[string]string "Invalid user ID or folder ID"
+// @Failure 400 {object} map[string]string "Invalid folder ID"
 // @Failure 500 {object} map[string]string "Failed to update folder"
 // @Router /user/folders/{folderId} [patch]
 func UpdateFolder(c *gin.Context) {
-	folderId, err := primitive.ObjectIDFromHex(c.Param("folderId"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
-		return
-	}
 	var body struct {
 		Name  *string `json:"name"`
 		Color *string `json:"color"`
@@ -165,11 +168,8 @@ func UpdateFolder(c *gin.Context) {
 		return
 	}
 
-	session := sessions.Default(c)
-	userIdString := session.Get("userId").(string)
-	userId, err := primitive.ObjectIDFromHex(userIdString)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	folder := getAccessibleFolder(c, c.Param("folderId"), true)
+	if folder == nil {
 		return
 	}
 
@@ -181,7 +181,7 @@ func UpdateFolder(c *gin.Context) {
 		updates["color"] = body.Color
 	}
 
-	err = db.UpdateFolder(folderId, userId, updates)
+	err := db.UpdateFolder(folder.Id, folder.UserId, updates)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update folder"})
 		return
@@ -195,27 +195,141 @@ func UpdateFolder(c *gin.Context) {
 // @Produce json
 // @Param folderId path string true "Folder ID"
 // @Success 200
-// @Failure 400 {object} map[string]string "Invalid user ID or folder ID"
+// @Failure 400 {object} map[string]string "Invalid folder ID"
 // @Failure 500 {object} map[string]string "Failed to delete folder"
 // @Router /user/folders/{folderId} [delete]
 func DeleteFolder(c *gin.Context) {
-	folderId, err := primitive.ObjectIDFromHex(c.Param("folderId"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+	folder := getAccessibleFolder(c, c.Param("folderId"), true)
+	if folder == nil {
 		return
 	}
-	session := sessions.Default(c)
-	userIdString := session.Get("userId").(string)
-	userId, err := primitive.ObjectIDFromHex(userIdString)
+
+	var err error
+	if folder.OrganizationId != nil {
+		err = db.DeleteOrganizationFolder(folder.Id)
+	} else {
+		err = db.DeleteFolder(folder.Id, folder.UserId)
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete folder"})
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// @Summary Sets the default co-organizers of a folder
+// @Description When the owner of an event adds it to the folder, these users become co-organizers of the event. Events already in the folder aren't changed. Send an empty list to stop adding co-organizers
+// @Tags folders
+// @Accept json
+// @Produce json
+// @Param folderId path string true "Folder ID"
+// @Param payload body object{emails=[]string} true "Emails of the default co-organizers"
+// @Success 200 {object} []string
+// @Router /user/folders/{folderId}/default-co-organizers [put]
+func setFolderDefaultCoOrganizers(c *gin.Context) {
+	payload := struct {
+		Emails []string `json:"emails" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+
+	folder := getAccessibleFolder(c, c.Param("folderId"), true)
+	if folder == nil {
 		return
 	}
 
-	err = db.DeleteFolder(folderId, userId)
+	emails := notifications.NormalizeCoOrganizerEmails(payload.Emails, "")
+	if folder.OrganizationId != nil {
+		org := db.GetOrganizationById(folder.OrganizationId.Hex())
+		if violations := getSharingViolations(org, emails); len(violations) > 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": errs.PolicyViolation, "violations": violations})
+			return
+		}
+	}
+
+	err := db.UpdateFolder(folder.Id, folder.UserId, bson.M{"defaultCoOrganizers": emails})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete folder"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update folder"})
 		return
 	}
-	c.Status(http.StatusOK)
+
+	c.JSON(http.StatusOK, emails)
+}
+
+// Makes the folder's default co-organizers co-organizers of the event, except
+// the ones the policies of the event's organization don't allow sharing with
+func addDefaultCoOrganizers(event *models.Event, folder *models.Folder, owner *models.User) {
+	var org *models.Organization
+	if !event.OrganizationId.IsZero() {
+		org = db.GetOrganizationById(event.OrganizationId.Hex())
+	}
+
+	emails := make([]string, 0)
+	for _, email := range notifications.NormalizeCoOrganizerEmails(folder.DefaultCoOrganizers, owner.Email) {
+		if utils.Find(event.CoOrganizers, func(o models.CoOrganizer) bool { return strings.EqualFold(o.Email, email) }) != -1 {
+			continue
+		}
+		if len(getSharingViolations(org, []string{email})) > 0 {
+			continue
+		}
+		emails = append(emails, email)
+	}
+	if len(emails) == 0 {
+		return
+	}
+
+	coOrganizers := append(event.CoOrganizers, newCoOrganizers(emails)...)
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+		"$set": bson.M{"coOrganizers": coOrganizers},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Returns the violations of the organization's policies from sharing its
+// events with the given emails
+func getSharingViolations(org *models.Organization, emails []string) []policies.Violation {
+	if org == nil || !org.Policies.DisableExternalSharing {
+		return nil
+	}
+	memberEmails := make(models.Set[string])
+	for _, member := range org.Members {
+		if user := db.GetUserById(member.UserId.Hex()); user != nil {
+			memberEmails[strings.ToLower(user.Email)] = struct{}{}
+		}
+	}
+	return policies.Evaluate(org.Policies, memberEmails, policies.Request{Action: policies.UPDATE_EVENT, Invitees: emails})
+}
+
+// Returns the folder if the current user can see it, or edit it if edit is
+// set (see organizations.CanAccessFolder). Otherwise responds with an error
+// and returns nil
+func getAccessibleFolder(c *gin.Context, id string, edit bool) *models.Folder {
+	folderId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return nil
+	}
+	folder, err := db.GetFolder(folderId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.FolderNotFound})
+		return nil
+	}
+
+	var org *models.Organization
+	if folder.OrganizationId != nil {
+		org = db.GetOrganizationById(folder.OrganizationId.Hex())
+	}
+	user := utils.GetAuthUser(c)
+	if !organizations.CanAccessFolder(org, folder, user.Id, false) {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.FolderNotFound})
+		return nil
+	}
+	if edit && !organizations.CanAccessFolder(org, folder, user.Id, true) {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotOrganizationAdmin})
+		return nil
+	}
+	return folder
 }
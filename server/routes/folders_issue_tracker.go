@@ -0,0 +1,102 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/issuetracker"
+	"schej.it/server/utils"
+)
+
+// @Summary Sets the issue tracker finalized events in the folder are posted to
+// @Description When an event in the folder is finalized, its scheduled time and attendees are posted as a comment on the Linear or Jira issue the event is linked to, and the comment is updated if it's finalized again. Events that aren't linked to an issue get a new issue in the project if one is given. The token is a Linear API key, or a Jira API token of the account with the given email
+// @Tags folders
+// @Accept json
+// @Produce json
+// @Param folderId path string true "Folder ID"
+// @Param payload body object{type=string,token=string,jiraUrl=string,jiraEmail=string,project=string,enabled=bool} true "Tracker (linear or jira), credentials, and the Linear team id or Jira project key to create issues in"
+// @Success 200 {object} models.IssueTracker
+// @Router /user/folders/{folderId}/issue-tracker [put]
+func setFolderIssueTracker(c *gin.Context) {
+	payload := struct {
+		Type      models.IssueTrackerType `json:"type" binding:"required"`
+		Token     string                  `json:"token" binding:"required"`
+		JiraUrl   string                  `json:"jiraUrl"`
+		JiraEmail string                  `json:"jiraEmail"`
+		Project   string                  `json:"project"`
+		Enabled   *bool                   `json:"enabled"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+
+	folder := getAccessibleFolder(c, c.Param("folderId"), true)
+	if folder == nil {
+		return
+	}
+
+	tracker := models.IssueTracker{
+		Type:    payload.Type,
+		Enabled: payload.Enabled == nil || *payload.Enabled,
+		Project: payload.Project,
+	}
+	switch payload.Type {
+	case models.LinearIssueTracker:
+	case models.JiraIssueTracker:
+		parsed, err := url.Parse(payload.JiraUrl)
+		if err != nil || len(parsed.Host) == 0 || (parsed.Scheme != "https" && (parsed.Scheme != "http" || utils.IsRelease())) {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: "jiraUrl must be an https url"})
+			return
+		}
+		if len(payload.JiraEmail) == 0 {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: "jiraEmail is required"})
+			return
+		}
+		tracker.JiraUrl = parsed.Scheme + "://" + parsed.Host
+		tracker.JiraEmail = payload.JiraEmail
+	default:
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "type must be linear or jira"})
+		return
+	}
+
+	if _, err := issuetracker.Connect(&tracker, payload.Token); err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.IssueTrackerUnauthorized})
+		return
+	}
+	var err error
+	if tracker.Token, err = utils.Encrypt(payload.Token); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	if err := db.UpdateFolder(folder.Id, folder.UserId, bson.M{"issueTracker": tracker}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.JSON(http.StatusOK, tracker)
+}
+
+// @Summary Stops posting finalized events in the folder to an issue tracker
+// @Tags folders
+// @Param folderId path string true "Folder ID"
+// @Success 200
+// @Router /user/folders/{folderId}/issue-tracker [delete]
+func deleteFolderIssueTracker(c *gin.Context) {
+	folder := getAccessibleFolder(c, c.Param("folderId"), true)
+	if folder == nil {
+		return
+	}
+
+	if _, err := db.FoldersCollection.UpdateByID(context.Background(), folder.Id, bson.M{"$unset": bson.M{"issueTracker": ""}}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,157 @@
+/* The health routes are the probes and metrics used when running behind an orchestrator */
+package routes
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stripe/stripe-go/v82"
+	"github.com/stripe/stripe-go/v82/balance"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/services/aggregates"
+	"schej.it/server/services/gcloud"
+	"schej.it/server/services/metrics"
+	"schej.it/server/services/status"
+)
+
+// How long the results of the external checks are reused, so that frequent
+// probes don't hit Stripe and GCP on every request
+const readinessCacheDuration = 30 * time.Second
+
+var shuttingDown atomic.Bool
+var warmingUp atomic.Bool
+
+// Marks the server as shutting down, so that it stops being ready and gets
+// taken out of rotation while in-flight requests drain
+func SetShuttingDown() {
+	shuttingDown.Store(true)
+}
+
+// Starts warming up in the background if WARMUP_ENABLED is set, during which
+// the server isn't ready. Warming up preloads the aggregates of recently active
+// events and verifies the credentials of Stripe and Cloud Tasks, so the first
+// requests after a deploy don't pay for it
+func StartWarmUp() {
+	if !aggregates.IsWarmUpEnabled() {
+		return
+	}
+
+	warmingUp.Store(true)
+	go func() {
+		defer warmingUp.Store(false)
+		defer func() {
+			if r := recover(); r != nil {
+				logger.StdErr.Printf("warm-up failed: %v\n", r)
+			}
+		}()
+
+		start := time.Now()
+		for name, err := range runExternalChecks(start) {
+			if err != nil {
+				logger.StdErr.Printf("warm-up: %s check failed: %v\n", name, err)
+			}
+		}
+		numEvents := aggregates.PreloadHotEvents(start)
+		logger.StdOut.Printf("warm-up: preloaded %d events in %v\n", numEvents, time.Since(start))
+	}()
+}
+
+func InitHealth(router *gin.Engine) {
+	router.GET("/healthz", getHealth)
+	router.GET("/readyz", getReadiness)
+	router.GET("/metrics", metrics.Handler)
+}
+
+// @Summary Liveness probe
+// @Description Succeeds as long as the server is running
+// @Tags health
+// @Produce json
+// @Success 200 {object} object{status=string}
+// @Router /healthz [get]
+func getHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+type readinessCheck struct {
+	mutex     sync.Mutex
+	checkedAt time.Time
+	err       error
+}
+
+// Runs the check, reusing its last result if it's recent enough
+func (r *readinessCheck) get(now time.Time, check func() error) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.checkedAt.IsZero() || now.Sub(r.checkedAt) >= readinessCacheDuration {
+		r.err = check()
+		r.checkedAt = now
+	}
+	return r.err
+}
+
+var stripeCheck readinessCheck
+var gcpCheck readinessCheck
+
+// Checks the external services that are configured, reusing recent results
+func runExternalChecks(now time.Time) map[string]error {
+	results := map[string]error{}
+	if len(stripe.Key) > 0 {
+		results["stripe"] = stripeCheck.get(now, func() error {
+			_, err := balance.Get(nil)
+			return err
+		})
+	}
+
+	if gcloud.TasksClient != nil {
+		results["gcp"] = gcpCheck.get(now, func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return gcloud.PingTasks(ctx)
+		})
+	}
+	return results
+}
+
+// @Summary Readiness probe
+// @Description Checks that mongo is reachable, along with Stripe and Cloud Tasks if they're configured. Fails while the server is warming up or shutting down
+// @Tags health
+// @Produce json
+// @Success 200 {object} object{status=string,checks=map[string]string}
+// @Failure 503 {object} object{status=string,checks=map[string]string}
+// @Router /readyz [get]
+func getReadiness(c *gin.Context) {
+	if shuttingDown.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting down"})
+		return
+	}
+	if warmingUp.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "warming up"})
+		return
+	}
+
+	results := runExternalChecks(time.Now())
+	results["mongo"] = db.Ping()
+	status.Record(status.DATABASE, results["mongo"])
+
+	code := http.StatusOK
+	checks := gin.H{}
+	for name, err := range results {
+		if err != nil {
+			code = http.StatusServiceUnavailable
+			checks[name] = err.Error()
+		} else {
+			checks[name] = "ok"
+		}
+	}
+
+	if code == http.StatusOK {
+		c.JSON(code, gin.H{"status": "ok", "checks": checks})
+	} else {
+		c.JSON(code, gin.H{"status": "unavailable", "checks": checks})
+	}
+}
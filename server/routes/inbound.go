@@ -0,0 +1,434 @@
+/* The /inbound group contains the webhooks called by email providers when an email is received, and when emails bounce or are marked as spam. Replies to email polls are registered as responses, any other email creates a draft event, and addresses that bounce or complain are added to the suppression list. Received emails that fail SPF and DKIM for their sender are ignored */
+package routes
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services/senderauth"
+	"schej.it/server/services/suppressions"
+	"schej.it/server/utils"
+)
+
+func InitInbound(router *gin.RouterGroup) {
+	inboundRouter := router.Group("/inbound")
+	inboundRouter.Use(inboundSecretRequired())
+
+	inboundRouter.POST("/email/sendgrid", sendgridInboundEmail)
+	inboundRouter.POST("/email/ses", sesInboundEmail)
+	inboundRouter.POST("/email/sendgrid/events", sendgridEmailEvents)
+	inboundRouter.POST("/email/ses/feedback", sesEmailFeedback)
+}
+
+// Middleware that checks the secret configured in the inbound email provider's webhook url
+func inboundSecretRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := os.Getenv("INBOUND_EMAIL_SECRET")
+		if secret == "" {
+			logger.StdErr.Println("INBOUND_EMAIL_SECRET not set")
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(c.Query("secret")), []byte(secret)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// @Summary Receives an email from the SendGrid inbound parse webhook
+// @Tags inbound
+// @Accept multipart/form-data
+// @Produce json
+// @Param secret query string true "Inbound email secret"
+// @Success 200 {object} object{eventId=string}
+// @Router /inbound/email/sendgrid [post]
+func sendgridInboundEmail(c *gin.Context) {
+	payload := struct {
+		From    string `form:"from" binding:"required"`
+		To      string `form:"to"`
+		Cc      string `form:"cc"`
+		Subject string `form:"subject"`
+		Text    string `form:"text"`
+
+		// Results of SendGrid's checks of the sender
+		Spf      string `form:"SPF"`
+		Dkim     string `form:"dkim"`
+		Envelope string `form:"envelope"`
+	}{}
+	if err := c.Bind(&payload); err != nil {
+		return
+	}
+
+	from, err := mail.ParseAddress(payload.From)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from address"})
+		return
+	}
+	if !senderauth.CheckSendgrid(from, payload.Spf, payload.Dkim, payload.Envelope) {
+		// Acknowledged so that SendGrid doesn't retry it
+		logger.StdOut.Printf("Ignoring inbound email from %s that failed SPF and DKIM\n", from.Address)
+		c.JSON(http.StatusOK, gin.H{})
+		return
+	}
+
+	recipients := append(parseAddressList(payload.To), parseAddressList(payload.Cc)...)
+	if handleEmailPollReply(from, recipients, payload.Text, getSendgridCalendarAttachment(c)) {
+		c.JSON(http.StatusOK, gin.H{})
+		return
+	}
+	event := createDraftEventFromEmail(from, recipients, payload.Subject)
+
+	c.JSON(http.StatusOK, gin.H{"eventId": event.Id.Hex()})
+}
+
+// @Summary Receives an email from Amazon SES through an SNS notification
+// @Tags inbound
+// @Accept json
+// @Produce json
+// @Param secret query string true "Inbound email secret"
+// @Success 200
+// @Router /inbound/email/ses [post]
+func sesInboundEmail(c *gin.Context) {
+	// SNS sends notifications with a text/plain content type, so decode the body manually
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	var notification struct {
+		Type         string `json:"Type"`
+		Message      string `json:"Message"`
+		SubscribeURL string `json:"SubscribeURL"`
+	}
+	if err := json.Unmarshal(body, &notification); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	switch notification.Type {
+	case "SubscriptionConfirmation":
+		if !confirmSnsSubscription(c, notification.SubscribeURL) {
+			return
+		}
+	case "Notification":
+		var message struct {
+			NotificationType string `json:"notificationType"`
+			Mail             struct {
+				Source        string `json:"source"`
+				CommonHeaders struct {
+					From    []string `json:"from"`
+					To      []string `json:"to"`
+					Cc      []string `json:"cc"`
+					Subject string   `json:"subject"`
+				} `json:"commonHeaders"`
+			} `json:"mail"`
+			Receipt senderauth.SesReceipt `json:"receipt"`
+			Content string                `json:"content"`
+		}
+		if err := json.Unmarshal([]byte(notification.Message), &message); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		if message.NotificationType != "Received" || len(message.Mail.CommonHeaders.From) == 0 {
+			break
+		}
+
+		headers := message.Mail.CommonHeaders
+		from, err := mail.ParseAddress(headers.From[0])
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		if !senderauth.CheckSes(from, message.Receipt, message.Mail.Source, message.Content) {
+			logger.StdOut.Printf("Ignoring inbound email from %s that failed SPF and DKIM\n", from.Address)
+			break
+		}
+		recipients := append(parseAddressList(strings.Join(headers.To, ",")), parseAddressList(strings.Join(headers.Cc, ","))...)
+		if !handleEmailPollReply(from, recipients, getMimePart(message.Content, "text/plain"), getMimePart(message.Content, "text/calendar")) {
+			createDraftEventFromEmail(from, recipients, headers.Subject)
+		}
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Confirms an SNS subscription, making sure we only call back to AWS.
+// Responds with an error and returns false if it couldn't be confirmed
+func confirmSnsSubscription(c *gin.Context, subscribeURL string) bool {
+	subscribeUrl, err := url.Parse(subscribeURL)
+	if err != nil || subscribeUrl.Scheme != "https" || !strings.HasSuffix(subscribeUrl.Hostname(), ".amazonaws.com") {
+		c.Status(http.StatusBadRequest)
+		return false
+	}
+	resp, err := http.Get(subscribeUrl.String())
+	if err != nil {
+		logger.StdErr.Println(err)
+		c.Status(http.StatusBadGateway)
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
+
+// @Summary Receives events from the SendGrid event webhook
+// @Description Suppresses addresses that bounced, reported an email as spam, or unsubscribed through SendGrid
+// @Tags inbound
+// @Accept json
+// @Param secret query string true "Inbound email secret"
+// @Success 200
+// @Router /inbound/email/sendgrid/events [post]
+func sendgridEmailEvents(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	parsed, err := suppressions.ParseSendgridEvents(body)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	suppressions.Apply(parsed)
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Receives SES bounce and complaint notifications through SNS
+// @Description Suppresses addresses that bounced permanently or reported an email as spam
+// @Tags inbound
+// @Accept json
+// @Param secret query string true "Inbound email secret"
+// @Success 200
+// @Router /inbound/email/ses/feedback [post]
+func sesEmailFeedback(c *gin.Context) {
+	// SNS sends notifications with a text/plain content type, so decode the body manually
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	var notification struct {
+		Type         string `json:"Type"`
+		Message      string `json:"Message"`
+		SubscribeURL string `json:"SubscribeURL"`
+	}
+	if err := json.Unmarshal(body, &notification); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	switch notification.Type {
+	case "SubscriptionConfirmation":
+		if !confirmSnsSubscription(c, notification.SubscribeURL) {
+			return
+		}
+	case "Notification":
+		parsed, err := suppressions.ParseSesNotification([]byte(notification.Message))
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		suppressions.Apply(parsed)
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Creates a draft event from a received email, with everyone on the email
+// (other than the sender and the inbound address) added as participants, and
+// emails the share link back to the sender
+func createDraftEventFromEmail(from *mail.Address, recipients []*mail.Address, subject string) *models.Event {
+	inboundAddress := os.Getenv("INBOUND_EMAIL_ADDRESS")
+	if inboundAddress == "" {
+		inboundAddress = "create@timeful.app"
+	}
+	senderEmail := strings.ToLower(from.Address)
+
+	// Participants are everyone else on the thread
+	participants := make([]string, 0)
+	seen := models.Set[string]{senderEmail: {}, strings.ToLower(inboundAddress): {}}
+	for _, recipient := range recipients {
+		email := strings.ToLower(recipient.Address)
+		if _, ok := seen[email]; ok {
+			continue
+		}
+		seen[email] = struct{}{}
+		participants = append(participants, email)
+	}
+
+	// Use the sender's timezone if they have an account
+	owner := db.GetUserByEmail(senderEmail)
+	loc := time.UTC
+	if owner != nil {
+		loc = utils.GetUserLocation(owner)
+	}
+	dates, duration := utils.GetDefaultEventDates(loc)
+
+	// Reminder emails aren't scheduled until the draft is sent out
+	remindees := make([]models.Remindee, 0)
+	for _, email := range participants {
+		remindees = append(remindees, models.Remindee{Email: email, Responded: utils.FalsePtr()})
+	}
+
+	event := models.Event{
+		Name:      getEventNameFromSubject(subject),
+		Duration:  &duration,
+		Dates:     dates,
+		Type:      models.SPECIFIC_DATES,
+		IsDraft:   utils.TruePtr(),
+		Remindees: &remindees,
+	}
+	if owner != nil {
+		event.OwnerId = owner.Id
+	}
+	db.InsertEvent(&event)
+
+	// Email the share link back to the sender
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				logger.StdErr.Println(err)
+			}
+		}()
+
+		eventUrl := fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId())
+		body := fmt.Sprintf("Your draft event \"%s\" is ready!\n\nReview it and share it with everyone here: %s\n", event.Name, eventUrl)
+		if len(participants) > 0 {
+			body += fmt.Sprintf("\nParticipants: %s\n", strings.Join(participants, ", "))
+		}
+		utils.SendEmail(senderEmail, fmt.Sprintf("Your Timeful event: %s", event.Name), body, "text/plain")
+	}()
+
+	return &event
+}
+
+var subjectPrefixRegex = regexp.MustCompile(`(?i)^\s*(re|fwd?|aw|wg)\s*:\s*`)
+
+// Strips reply and forward prefixes (e.g. "Re: Fwd: ") from the given subject
+func getEventNameFromSubject(subject string) string {
+	for subjectPrefixRegex.MatchString(subject) {
+		subject = subjectPrefixRegex.ReplaceAllString(subject, "")
+	}
+	subject = strings.TrimSpace(subject)
+	if subject == "" {
+		return "New event"
+	}
+	return subject
+}
+
+// Parses a comma separated list of addresses, ignoring any malformed addresses
+func parseAddressList(list string) []*mail.Address {
+	if strings.TrimSpace(list) == "" {
+		return []*mail.Address{}
+	}
+
+	addresses, err := mail.ParseAddressList(list)
+	if err == nil {
+		return addresses
+	}
+
+	// Fall back to parsing each address individually
+	addresses = make([]*mail.Address, 0)
+	for _, s := range strings.Split(list, ",") {
+		if address, err := mail.ParseAddress(strings.TrimSpace(s)); err == nil {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+// Returns the first calendar attachment of an email received by SendGrid,
+// which is where mail clients put RSVPs
+func getSendgridCalendarAttachment(c *gin.Context) string {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return ""
+	}
+	for _, files := range form.File {
+		for _, file := range files {
+			if mediaType, _, _ := mime.ParseMediaType(file.Header.Get("Content-Type")); mediaType != "text/calendar" {
+				continue
+			}
+			f, err := file.Open()
+			if err != nil {
+				continue
+			}
+			data, err := io.ReadAll(f)
+			f.Close()
+			if err == nil {
+				return string(data)
+			}
+		}
+	}
+	return ""
+}
+
+// Returns the first part of the raw email with the given media type, which
+// SES includes in the notification when the receipt rule publishes the
+// email's content
+func getMimePart(raw string, mediaType string) string {
+	message, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		return ""
+	}
+	return findMimePart(message.Header.Get("Content-Type"), message.Header.Get("Content-Transfer-Encoding"), message.Body, mediaType)
+}
+
+func findMimePart(contentType string, transferEncoding string, body io.Reader, want string) string {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err != nil {
+				return ""
+			}
+			if text := findMimePart(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part, want); text != "" {
+				return text
+			}
+		}
+	}
+	if mediaType != want {
+		return ""
+	}
+
+	switch strings.ToLower(transferEncoding) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	text, err := io.ReadAll(body)
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
@@ -0,0 +1,171 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/models"
+	"schej.it/server/services/insights"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// Maximum number of a user's recent events insights are computed from
+const maxInsightsEvents = 100
+
+type insightsQuery struct {
+	TimezoneOffset *int `form:"timezoneOffset"`
+	Hours          *int `form:"hours"`
+	Limit          *int `form:"limit"`
+	MinEvents      *int `form:"minEvents"`
+}
+
+// Weekly availability of a group aggregated over several events
+type insightsResponse struct {
+	NumEvents   int               `json:"numEvents"`
+	Cells       []insights.Cell   `json:"cells"`
+	BestWindows []insights.Window `json:"bestWindows"`
+}
+
+// @Summary Gets weekly availability insights for the events in a folder
+// @Description Aggregates the availability of the respondents of the folder's events by hour of the week, and returns the best windows, e.g. Tuesdays 3-5pm
+// @Tags folders
+// @Produce json
+// @Param folderId path string true "Folder ID"
+// @Param timezoneOffset query int false "Client's timezone offset in minutes, defaults to the user's"
+// @Param hours query int false "Length of the best windows in hours, defaults to 2"
+// @Param limit query int false "Maximum number of best windows, defaults to 3"
+// @Param minEvents query int false "Minimum number of events an hour must have been polled in to be part of a best window, defaults to 2"
+// @Success 200 {object} insightsResponse
+// @Router /user/folders/{folderId}/insights [get]
+func getFolderInsights(c *gin.Context) {
+	query := insightsQuery{}
+	if err := c.Bind(&query); err != nil {
+		return
+	}
+
+	user := utils.GetAuthUser(c)
+	folder := getAccessibleFolder(c, c.Param("folderId"), false)
+	if folder == nil {
+		return
+	}
+	eventIds, err := db.GetFolderEventIds(folder)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get events in folder"})
+		return
+	}
+
+	c.JSON(http.StatusOK, getInsights(user, query, db.GetEventsByIds(eventIds), nil))
+}
+
+// @Summary Gets weekly availability insights for a contact group
+// @Description Aggregates the availability of the group's members across the user's recent events by hour of the week, and returns the best windows, e.g. Tuesdays 3-5pm
+// @Tags user
+// @Produce json
+// @Param groupId path string true "Contact group ID"
+// @Param timezoneOffset query int false "Client's timezone offset in minutes, defaults to the user's"
+// @Param hours query int false "Length of the best windows in hours, defaults to 2"
+// @Param limit query int false "Maximum number of best windows, defaults to 3"
+// @Param minEvents query int false "Minimum number of events an hour must have been polled in to be part of a best window, defaults to 2"
+// @Success 200 {object} insightsResponse
+// @Router /user/contact-groups/{groupId}/insights [get]
+func getContactGroupInsights(c *gin.Context) {
+	query := insightsQuery{}
+	if err := c.Bind(&query); err != nil {
+		return
+	}
+
+	group := getContactGroup(c, false)
+	if group == nil {
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	members := make(models.Set[string])
+	for _, email := range group.Emails {
+		members[strings.ToLower(email)] = struct{}{}
+	}
+	c.JSON(http.StatusOK, getInsights(user, query, db.GetRecentEventsByOwner(user.Id, maxInsightsEvents), func(respondent scheduling.Respondent) bool {
+		_, ok := members[strings.ToLower(respondent.Email)]
+		return ok
+	}))
+}
+
+// Aggregates the availability of the respondents of the events that include
+// returns true for (or all respondents if include is nil)
+func getInsights(user *models.User, query insightsQuery, events []models.Event, include func(respondent scheduling.Respondent) bool) insightsResponse {
+	loc := utils.GetUserLocation(user)
+	if query.TimezoneOffset != nil {
+		loc = time.FixedZone("UserOffset", -*query.TimezoneOffset*60)
+	}
+	hours, limit, minEvents := 2, 3, 2
+	if query.Hours != nil && *query.Hours > 0 && *query.Hours <= 24 {
+		hours = *query.Hours
+	}
+	if query.Limit != nil && *query.Limit > 0 {
+		limit = *query.Limit
+	}
+	if query.MinEvents != nil && *query.MinEvents > 0 {
+		minEvents = *query.MinEvents
+	}
+
+	heatmap := insights.NewHeatmap(loc)
+	numEvents := 0
+	for i := range events {
+		event := &events[i]
+		// Availability groups and sign up forms don't poll for times
+		if event.Type == models.GROUP || utils.Coalesce(event.IsSignUpForm) {
+			continue
+		}
+		heatmap.AddEvent(event, scheduling.GetRespondents(db.GetEventResponses(event.Id.Hex())), include)
+		numEvents++
+	}
+	if minEvents > numEvents {
+		minEvents = numEvents
+	}
+
+	return insightsResponse{
+		NumEvents:   numEvents,
+		Cells:       heatmap.Cells(),
+		BestWindows: heatmap.BestWindows(hours, limit, minEvents),
+	}
+}
+
+// @Summary Summarizes the user's scheduling patterns
+// @Description Computed from the user's recent events and their activity: how long events take to finalize, how many people respond, and which reminder timing brings in the most responses. Only available to premium users
+// @Tags user
+// @Produce json
+// @Success 200 {object} insights.OrganizerSummary
+// @Failure 402 {object} responses.Error
+// @Router /user/insights [get]
+func getOrganizerInsights(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+
+	events := db.GetRecentEventsByOwner(user.Id, maxInsightsEvents)
+	eventIds := make([]primitive.ObjectID, 0)
+	for _, event := range events {
+		eventIds = append(eventIds, event.Id)
+	}
+	activitiesByEvent := make(map[primitive.ObjectID][]models.Activity)
+	for _, activity := range db.GetActivitiesByEventIds(eventIds) {
+		activitiesByEvent[activity.EventId] = append(activitiesByEvent[activity.EventId], activity)
+	}
+
+	histories := make([]insights.EventHistory, 0)
+	for i := range events {
+		history := insights.EventHistory{Event: &events[i], Activities: activitiesByEvent[events[i].Id]}
+		for _, remindee := range utils.Coalesce(events[i].Remindees) {
+			history.NumInvitees++
+			if utils.Coalesce(remindee.Responded) {
+				history.NumResponded++
+			}
+		}
+		histories = append(histories, history)
+	}
+
+	c.JSON(http.StatusOK, insights.Summarize(histories))
+}
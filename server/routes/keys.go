@@ -0,0 +1,59 @@
+/* The /keys group contains the routes that integrators use to monitor their API keys */
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/middleware"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/apikeys"
+	"schej.it/server/utils"
+)
+
+func InitKeys(router *gin.RouterGroup) {
+	keysRouter := router.Group("/keys")
+	keysRouter.Use(middleware.AuthRequired())
+
+	keysRouter.GET("/:id/usage", getApiKeyUsage)
+}
+
+// @Summary Gets the usage of an API key
+// @Description Totals the requests made with the key over the last days (7 by default, at most 30), and breaks them down by endpoint and by day. Errors are requests that got a 4xx or 5xx status, including the ones rejected for exceeding the rate limit. Signed in users can get the usage of any of their keys, and requests made with a key only the usage of that key
+// @Tags keys
+// @Produce json
+// @Param id path string true "API key ID"
+// @Param days query int false "Number of days, including today (in UTC)"
+// @Success 200 {object} apikeys.Usage
+// @Router /keys/{id}/usage [get]
+func getApiKeyUsage(c *gin.Context) {
+	query := struct {
+		Days int `form:"days,default=7" binding:"min=1,max=30"`
+	}{}
+	if err := c.Bind(&query); err != nil {
+		return
+	}
+
+	user := utils.GetAuthUser(c)
+	apiKeys := db.GetApiKeysByOwner(user.Id)
+	i := utils.Find(apiKeys, func(apiKey models.ApiKey) bool { return apiKey.Id.Hex() == c.Param("id") })
+	if i == -1 {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.ApiKeyNotFound})
+		return
+	}
+
+	// Other keys and OAuth tokens can't see the key's usage
+	if principal := utils.GetPrincipal(c); principal != nil && principal.Method != models.SESSION_AUTH {
+		if principal.ApiKey == nil || principal.ApiKey.Id != apiKeys[i].Id {
+			c.JSON(http.StatusNotFound, responses.Error{Error: errs.ApiKeyNotFound})
+			return
+		}
+	}
+
+	since := apikeys.GetUsageDay(time.Now()).AddDate(0, 0, 1-query.Days)
+	c.JSON(http.StatusOK, apikeys.SummarizeUsage(db.GetApiKeyUsage(apiKeys[i].Id, since)))
+}
@@ -0,0 +1,85 @@
+/* The /legal group contains the routes to read and accept the terms of service and privacy policy */
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/middleware"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/legal"
+	"schej.it/server/utils"
+)
+
+func InitLegal(router *gin.RouterGroup) {
+	legalRouter := router.Group("/legal")
+
+	legalRouter.GET("", getLegalDocuments)
+	legalRouter.GET("/pending", middleware.AuthRequired(), getPendingLegalDocuments)
+	legalRouter.POST("/accept", middleware.AuthRequired(), acceptLegalDocument)
+}
+
+// @Summary Gets the latest versions of the terms of service and privacy policy
+// @Description Doesn't require signing in
+// @Tags legal
+// @Produce json
+// @Success 200 {object} []models.LegalDocument
+// @Router /legal [get]
+func getLegalDocuments(c *gin.Context) {
+	c.JSON(http.StatusOK, legal.GetLatest(db.GetLegalDocuments()))
+}
+
+// @Summary Gets the legal documents the current user has to accept
+// @Description Users that haven't accepted the latest required versions get a 403 with the legal-acceptance-required error from every other route that requires signing in
+// @Tags legal
+// @Produce json
+// @Success 200 {object} []models.LegalDocument
+// @Router /legal/pending [get]
+func getPendingLegalDocuments(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	c.JSON(http.StatusOK, legal.GetPending(db.GetLegalDocuments(), user.LegalAcceptances))
+}
+
+// @Summary Accepts a version of a legal document for the current user
+// @Description Only the latest version of each document can be accepted
+// @Tags legal
+// @Accept json
+// @Produce json
+// @Param payload body object{type=models.LegalDocumentType,version=int} true "Type and version of the document"
+// @Success 200 {object} object{pending=[]models.LegalDocument}
+// @Router /legal/accept [post]
+func acceptLegalDocument(c *gin.Context) {
+	payload := struct {
+		Type    models.LegalDocumentType `json:"type" binding:"required"`
+		Version int                      `json:"version" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	documents := db.GetLegalDocuments()
+	var document *models.LegalDocument
+	for _, latest := range legal.GetLatest(documents) {
+		if latest.Type == payload.Type {
+			document = &latest
+		}
+	}
+	if document == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.LegalDocumentNotFound})
+		return
+	}
+	if document.Version != payload.Version {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "only the latest version can be accepted"})
+		return
+	}
+
+	user.LegalAcceptances = legal.Accept(user.LegalAcceptances, *document, c.ClientIP(), time.Now())
+	db.SetLegalAcceptances(user.Id, user.LegalAcceptances)
+
+	c.JSON(http.StatusOK, gin.H{"pending": legal.GetPending(documents, user.LegalAcceptances)})
+}
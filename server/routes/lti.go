@@ -0,0 +1,250 @@
+/* The /lti group contains all the routes for LTI 1.3, which lets teachers embed events in LMS (e.g. Canvas or Moodle) assignments with students identified by the LMS */
+package routes
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/middleware"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/lti"
+	"schej.it/server/utils"
+)
+
+func InitLti(router *gin.RouterGroup) {
+	ltiRouter := router.Group("/lti")
+
+	ltiRouter.GET("/platforms", middleware.AuthRequired(), getLtiPlatforms)
+	ltiRouter.POST("/platforms", middleware.AuthRequired(), createLtiPlatform)
+	ltiRouter.DELETE("/platforms/:platformId", middleware.AuthRequired(), deleteLtiPlatform)
+	ltiRouter.GET("/login", ltiLogin)
+	ltiRouter.POST("/login", ltiLogin)
+	ltiRouter.POST("/launch", ltiLaunch)
+}
+
+// @Summary Gets the LMS platforms registered by the current user
+// @Tags lti
+// @Produce json
+// @Success 200 {object} []models.LtiPlatform
+// @Router /lti/platforms [get]
+func getLtiPlatforms(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	c.JSON(http.StatusOK, db.GetLtiPlatformsByOwnerId(user.Id))
+}
+
+// @Summary Registers an LMS platform
+// @Description The values come from registering Timeful as an LTI 1.3 tool in the LMS, with /api/lti/login as the login url and /api/lti/launch as the redirect url
+// @Tags lti
+// @Accept json
+// @Produce json
+// @Param payload body object{name=string,issuer=string,clientId=string,deploymentIds=[]string,authLoginUrl=string,jwksUrl=string} true "Platform registration"
+// @Success 201 {object} models.LtiPlatform
+// @Router /lti/platforms [post]
+func createLtiPlatform(c *gin.Context) {
+	payload := struct {
+		Name          string   `json:"name" binding:"required"`
+		Issuer        string   `json:"issuer" binding:"required"`
+		ClientId      string   `json:"clientId" binding:"required"`
+		DeploymentIds []string `json:"deploymentIds"`
+		AuthLoginUrl  string   `json:"authLoginUrl" binding:"required"`
+		JwksUrl       string   `json:"jwksUrl" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	for _, rawUrl := range []string{payload.AuthLoginUrl, payload.JwksUrl} {
+		if parsed, err := url.Parse(rawUrl); err != nil || parsed.Scheme != "https" {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: "platform urls must use https"})
+			return
+		}
+	}
+	if db.GetLtiPlatformByIssuer(payload.Issuer, payload.ClientId) != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.LtiPlatformAlreadyRegistered})
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	platform := models.LtiPlatform{
+		OwnerId:       user.Id,
+		Name:          strings.TrimSpace(payload.Name),
+		Issuer:        payload.Issuer,
+		ClientId:      payload.ClientId,
+		DeploymentIds: payload.DeploymentIds,
+		AuthLoginUrl:  payload.AuthLoginUrl,
+		JwksUrl:       payload.JwksUrl,
+		CreatedAt:     primitive.NewDateTimeFromTime(time.Now()),
+	}
+	db.InsertLtiPlatform(&platform)
+
+	c.JSON(http.StatusCreated, platform)
+}
+
+// @Summary Deletes an LMS platform
+// @Description Students launched from the platform keep their responses, but the platform can no longer launch events
+// @Tags lti
+// @Param platformId path string true "Platform ID"
+// @Success 200
+// @Router /lti/platforms/{platformId} [delete]
+func deleteLtiPlatform(c *gin.Context) {
+	platform := db.GetLtiPlatformById(c.Param("platformId"))
+	user := utils.GetAuthUser(c)
+	if platform == nil || platform.OwnerId != user.Id {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.LtiPlatformNotFound})
+		return
+	}
+
+	db.DeleteLtiPlatform(platform.Id)
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Starts an LTI 1.3 launch
+// @Description OIDC third party initiated login. Redirects to the platform's login url, which posts the id token to /lti/launch
+// @Tags lti
+// @Param iss query string true "Issuer of the platform"
+// @Param login_hint query string true "Opaque hint of the platform"
+// @Param target_link_uri query string true "Link that was launched"
+// @Param lti_message_hint query string false "Opaque hint of the platform"
+// @Param client_id query string false "Client id of Timeful in the platform"
+// @Success 302
+// @Router /lti/login [get]
+func ltiLogin(c *gin.Context) {
+	payload := struct {
+		Issuer         string `form:"iss" binding:"required"`
+		LoginHint      string `form:"login_hint" binding:"required"`
+		TargetLinkUri  string `form:"target_link_uri" binding:"required"`
+		LtiMessageHint string `form:"lti_message_hint"`
+		ClientId       string `form:"client_id"`
+	}{}
+	if err := c.Bind(&payload); err != nil {
+		return
+	}
+
+	platform := db.GetLtiPlatformByIssuer(payload.Issuer, payload.ClientId)
+	if platform == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.LtiPlatformNotFound})
+		return
+	}
+
+	nonceBytes := make([]byte, 16)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	nonce := hex.EncodeToString(nonceBytes)
+
+	query := url.Values{}
+	query.Set("scope", "openid")
+	query.Set("response_type", "id_token")
+	query.Set("response_mode", "form_post")
+	query.Set("prompt", "none")
+	query.Set("client_id", platform.ClientId)
+	query.Set("redirect_uri", utils.GetBaseUrl()+"/api/lti/launch")
+	query.Set("login_hint", payload.LoginHint)
+	query.Set("state", lti.NewState(platform.Id, nonce))
+	query.Set("nonce", nonce)
+	if len(payload.LtiMessageHint) > 0 {
+		query.Set("lti_message_hint", payload.LtiMessageHint)
+	}
+
+	c.Redirect(http.StatusFound, platform.AuthLoginUrl+"?"+query.Encode())
+}
+
+// @Summary Completes an LTI 1.3 launch
+// @Description Verifies the id token posted by the platform, maps the LMS user to a guest identity, and redirects to the launched event with a token that lets them respond as that identity
+// @Tags lti
+// @Accept x-www-form-urlencoded
+// @Param id_token formData string true "Id token signed by the platform"
+// @Param state formData string true "State of the login request"
+// @Success 303
+// @Router /lti/launch [post]
+func ltiLaunch(c *gin.Context) {
+	payload := struct {
+		IdToken string `form:"id_token" binding:"required"`
+		State   string `form:"state" binding:"required"`
+	}{}
+	if err := c.Bind(&payload); err != nil {
+		return
+	}
+
+	platformId, nonce, err := lti.ParseState(payload.State)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidLtiLaunch})
+		return
+	}
+	platform := db.GetLtiPlatformById(platformId.Hex())
+	if platform == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.LtiPlatformNotFound})
+		return
+	}
+
+	claims, err := lti.ParseIdToken(payload.IdToken, lti.GetPublicKeyFunc(platform.JwksUrl))
+	if err == nil {
+		err = lti.ValidateLaunch(claims, platform, nonce, time.Now())
+	}
+	if err != nil {
+		logger.StdErr.Printf("Invalid LTI launch from %s: %v\n", platform.Issuer, err)
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidLtiLaunch})
+		return
+	}
+
+	eventId := lti.GetEventId(claims)
+	event := db.GetEventByEitherId(eventId)
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+		return
+	}
+
+	// Map the LMS user to the guest name they respond with. The name is only
+	// picked on the first launch so that their responses survive name changes
+	now := primitive.NewDateTimeFromTime(time.Now())
+	identity := db.GetLtiIdentity(platform.Id, claims.Subject)
+	if identity == nil {
+		identity = &models.LtiIdentity{
+			PlatformId: platform.Id,
+			Subject:    claims.Subject,
+			Name: lti.GetUniqueName(lti.GetDisplayName(claims), func(name string) bool {
+				return db.IsLtiIdentityNameTaken(platform.Id, name)
+			}),
+			Email:          claims.Email,
+			LastLaunchedAt: now,
+		}
+		db.InsertLtiIdentity(identity)
+	} else {
+		if len(claims.Email) > 0 {
+			identity.Email = claims.Email
+		}
+		identity.LastLaunchedAt = now
+		db.UpdateLtiIdentity(identity)
+	}
+
+	query := url.Values{}
+	query.Set("embed", "true")
+	query.Set("ltiToken", lti.NewEmbedToken(event.Id, identity))
+	c.Redirect(http.StatusSeeOther, utils.GetBaseUrl()+"/e/"+eventId+"?"+query.Encode())
+}
+
+// Returns the LMS identity the embedded event was launched for, or nil if
+// ltiToken is empty. Responds with an error and returns false if the token
+// isn't valid for the event
+func getLtiEmbed(c *gin.Context, event *models.Event, ltiToken string) (*lti.EmbedClaims, bool) {
+	if len(ltiToken) == 0 {
+		return nil, true
+	}
+
+	embed, err := lti.ParseEmbedToken(ltiToken)
+	if err != nil || embed.EventId != event.Id {
+		c.JSON(http.StatusUnauthorized, responses.Error{Error: errs.InvalidLtiToken})
+		return nil, false
+	}
+	return embed, true
+}
@@ -0,0 +1,585 @@
+/* The /orgs group contains all the routes for organizations, whose admins set the default settings and compliance policies of events created by members */
+package routes
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/middleware"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/organizations"
+	"schej.it/server/services/policies"
+	"schej.it/server/utils"
+)
+
+func InitOrgs(router *gin.RouterGroup) {
+	// Organizations are served under both paths
+	for _, path := range []string{"/orgs", "/organizations"} {
+		orgRouter := router.Group(path)
+
+		orgRouter.GET("", middleware.AuthRequired(), getOrgs)
+		orgRouter.POST("", middleware.AuthRequired(), createOrg)
+		orgRouter.GET("/:orgId", middleware.AuthRequired(), getOrg)
+		orgRouter.POST("/:orgId/members", middleware.AuthRequired(), addOrgMember)
+		orgRouter.DELETE("/:orgId/members/:userId", middleware.AuthRequired(), removeOrgMember)
+		orgRouter.DELETE("/:orgId/invites/:email", middleware.AuthRequired(), revokeOrgInvite)
+		orgRouter.GET("/:orgId/settings", middleware.AuthRequired(), getOrgSettings)
+		orgRouter.PUT("/:orgId/settings", middleware.AuthRequired(), updateOrgSettings)
+		orgRouter.PUT("/:orgId/policies", middleware.AuthRequired(), updateOrgPolicies)
+		orgRouter.GET("/:orgId/usage", middleware.AuthRequired(), getOrgUsage)
+		orgRouter.GET("/:orgId/calendar", middleware.AuthRequired(), getOrgCalendar)
+		orgRouter.POST("/:orgId/domains", middleware.AuthRequired(), claimOrgDomain)
+		orgRouter.POST("/:orgId/domains/:domain/verify", middleware.AuthRequired(), verifyOrgDomain)
+		orgRouter.DELETE("/:orgId/domains/:domain", middleware.AuthRequired(), removeOrgDomain)
+		orgRouter.GET("/:orgId/sending-domain", middleware.AuthRequired(), getOrgSendingDomain)
+		orgRouter.PUT("/:orgId/sending-domain", middleware.AuthRequired(), setOrgSendingDomain)
+		orgRouter.POST("/:orgId/sending-domain/verify", middleware.AuthRequired(), verifyOrgSendingDomain)
+		orgRouter.DELETE("/:orgId/sending-domain", middleware.AuthRequired(), removeOrgSendingDomain)
+		orgRouter.GET("/:orgId/email-templates", middleware.AuthRequired(), getOrgEmailTemplates)
+		orgRouter.PUT("/:orgId/email-templates/:type", middleware.AuthRequired(), setOrgEmailTemplate)
+		orgRouter.DELETE("/:orgId/email-templates/:type", middleware.AuthRequired(), removeOrgEmailTemplate)
+		orgRouter.POST("/:orgId/email-templates/:type/preview", middleware.AuthRequired(), previewOrgEmailTemplate)
+		orgRouter.POST("/:orgId/join-requests/:userId/approve", middleware.AuthRequired(), approveJoinRequest)
+		orgRouter.DELETE("/:orgId/join-requests/:userId", middleware.AuthRequired(), rejectJoinRequest)
+	}
+}
+
+// @Summary Gets the organizations the current user is a member of
+// @Tags orgs
+// @Produce json
+// @Success 200 {object} []models.Organization
+// @Router /orgs [get]
+func getOrgs(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	c.JSON(http.StatusOK, db.GetOrganizationsByUserId(user.Id))
+}
+
+// @Summary Creates a new organization
+// @Description The current user becomes its owner
+// @Tags orgs
+// @Accept json
+// @Produce json
+// @Param payload body object{name=string} true "Name of the organization"
+// @Success 201 {object} models.Organization
+// @Router /orgs [post]
+func createOrg(c *gin.Context) {
+	payload := struct {
+		Name string `json:"name" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	org := models.Organization{
+		Name:      strings.TrimSpace(payload.Name),
+		Members:   []models.OrganizationMember{{UserId: user.Id, Role: models.ORG_OWNER}},
+		CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+	}
+	db.InsertOrganization(&org)
+
+	c.JSON(http.StatusCreated, org)
+}
+
+// Returns the organization if the current user is a member (or an admin, if
+// adminOnly), otherwise responds with an error and returns nil
+func getMemberOrg(c *gin.Context, adminOnly bool) *models.Organization {
+	org := db.GetOrganizationById(c.Param("orgId"))
+	user := utils.GetAuthUser(c)
+	if org == nil || organizations.GetMember(org, user.Id) == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.OrganizationNotFound})
+		return nil
+	}
+	if adminOnly && !organizations.IsAdmin(org, user.Id) {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotOrganizationAdmin})
+		return nil
+	}
+	return org
+}
+
+// @Summary Gets an organization and its members
+// @Tags orgs
+// @Produce json
+// @Param orgId path string true "Organization ID"
+// @Success 200 {object} models.Organization
+// @Router /orgs/{orgId} [get]
+func getOrg(c *gin.Context) {
+	org := getMemberOrg(c, false)
+	if org == nil {
+		return
+	}
+
+	for i := range org.Members {
+		org.Members[i].User = db.GetUserById(org.Members[i].UserId.Hex())
+	}
+	for i := range org.JoinRequests {
+		org.JoinRequests[i].User = db.GetUserById(org.JoinRequests[i].UserId.Hex())
+	}
+	c.JSON(http.StatusOK, org)
+}
+
+// @Summary Adds a member to the organization, or changes their role
+// @Description Admins can add members, admins and billing admins, and owners can also add owners. People without an account are emailed an invite, and join with the role once they sign up
+// @Tags orgs
+// @Accept json
+// @Produce json
+// @Param orgId path string true "Organization ID"
+// @Param payload body object{email=string,role=models.OrganizationRole} true "Email of the user to add, and their role"
+// @Success 200 {object} models.Organization
+// @Router /orgs/{orgId}/members [post]
+func addOrgMember(c *gin.Context) {
+	payload := struct {
+		Email string                  `json:"email" binding:"required"`
+		Role  models.OrganizationRole `json:"role" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	if !organizations.IsValidRole(payload.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+		return
+	}
+	address, err := mail.ParseAddress(strings.TrimSpace(payload.Email))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email"})
+		return
+	}
+	email := strings.ToLower(address.Address)
+
+	org := getMemberOrg(c, true)
+	if org == nil {
+		return
+	}
+	if !setOrgMember(c, org, email, payload.Role) {
+		return
+	}
+
+	c.JSON(http.StatusOK, org)
+}
+
+// Checks whether the current user can give the user the role, or remove them
+// if the role is empty. Responds with an error and returns false if not
+func checkRoleChange(c *gin.Context, org *models.Organization, userId primitive.ObjectID, role models.OrganizationRole) bool {
+	err := organizations.CheckRoleChange(org, utils.GetAuthUser(c).Id, userId, role)
+	if err == organizations.ErrRoleChangeForbidden {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotOrganizationAdmin})
+		return false
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// Adds the user with the email to the organization with the role, or changes
+// their role. People without an account are invited instead, and emailed the
+// first time. Responds with an error and returns false if the current user
+// can't give the role
+func setOrgMember(c *gin.Context, org *models.Organization, email string, role models.OrganizationRole) bool {
+	user := utils.GetAuthUser(c)
+	invitedUser := db.GetUserByEmail(email)
+	invitedUserId := primitive.NilObjectID
+	if invitedUser != nil {
+		invitedUserId = invitedUser.Id
+	}
+	if !checkRoleChange(c, org, invitedUserId, role) {
+		return false
+	}
+
+	if invitedUser == nil {
+		invite := models.OrganizationInvite{
+			Email:     email,
+			Role:      role,
+			InvitedBy: user.Id,
+			InvitedAt: primitive.NewDateTimeFromTime(time.Now()),
+		}
+		index := utils.Find(org.Invites, func(i models.OrganizationInvite) bool { return i.Email == email })
+		if index != -1 {
+			org.Invites[index].Role = role
+		} else {
+			org.Invites = append(org.Invites, invite)
+		}
+		db.UpdateOrganization(org)
+
+		if index == -1 {
+			inviterName := strings.TrimSpace(user.FirstName + " " + user.LastName)
+			body := fmt.Sprintf("Hi,\n\n%s invited you to join %s on Timeful, where you'll share its folders and events.\n\nSign up with this email address to join: %s\n", inviterName, org.Name, utils.GetBaseUrl())
+			if err := utils.TrySendEmail(email, fmt.Sprintf("%s invited you to join %s", inviterName, org.Name), body, "text/plain"); err != nil {
+				logger.StdErr.Println(err)
+			}
+		}
+		return true
+	}
+
+	if member := organizations.GetMember(org, invitedUser.Id); member != nil {
+		member.Role = role
+	} else {
+		org.Members = append(org.Members, models.OrganizationMember{UserId: invitedUser.Id, Role: role})
+	}
+	db.UpdateOrganization(org)
+	return true
+}
+
+// @Summary Removes a member from the organization
+// @Description Admins can remove anyone but owners, which only owners can remove, and members can remove themselves
+// @Tags orgs
+// @Param orgId path string true "Organization ID"
+// @Param userId path string true "ID of the member to remove"
+// @Success 200
+// @Router /orgs/{orgId}/members/{userId} [delete]
+func removeOrgMember(c *gin.Context) {
+	org := getMemberOrg(c, false)
+	if org == nil {
+		return
+	}
+	userId := c.Param("userId")
+
+	index := utils.Find(org.Members, func(m models.OrganizationMember) bool { return m.UserId.Hex() == userId })
+	if index == -1 {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.UserNotOrganizationMember})
+		return
+	}
+	if !checkRoleChange(c, org, org.Members[index].UserId, "") {
+		return
+	}
+	org.Members = append(org.Members[:index], org.Members[index+1:]...)
+	db.UpdateOrganization(org)
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Revokes the invite of someone without an account
+// @Tags orgs
+// @Param orgId path string true "Organization ID"
+// @Param email path string true "Email of the invite"
+// @Success 200
+// @Router /orgs/{orgId}/invites/{email} [delete]
+func revokeOrgInvite(c *gin.Context) {
+	org := getMemberOrg(c, true)
+	if org == nil {
+		return
+	}
+	email := strings.ToLower(c.Param("email"))
+
+	index := utils.Find(org.Invites, func(i models.OrganizationInvite) bool { return i.Email == email })
+	if index == -1 {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.OrganizationInviteNotFound})
+		return
+	}
+	if org.Invites[index].Role == models.ORG_OWNER && !checkRoleChange(c, org, primitive.NilObjectID, models.ORG_OWNER) {
+		return
+	}
+	org.Invites = append(org.Invites[:index], org.Invites[index+1:]...)
+	db.UpdateOrganization(org)
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Gets the default event settings of the organization
+// @Description Used to pre-populate new events. Locked settings can't be changed by members
+// @Tags orgs
+// @Produce json
+// @Param orgId path string true "Organization ID"
+// @Success 200 {object} models.OrganizationSettings
+// @Router /orgs/{orgId}/settings [get]
+func getOrgSettings(c *gin.Context) {
+	org := getMemberOrg(c, false)
+	if org == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, org.Settings)
+}
+
+// @Summary Sets the default event settings of the organization
+// @Description Defaults apply to events created afterwards. Locked settings also apply to existing events the next time they're edited
+// @Tags orgs
+// @Accept json
+// @Produce json
+// @Param orgId path string true "Organization ID"
+// @Param payload body models.OrganizationSettings true "Default settings, and the settings to lock"
+// @Success 200 {object} models.OrganizationSettings
+// @Router /orgs/{orgId}/settings [put]
+func updateOrgSettings(c *gin.Context) {
+	var settings models.OrganizationSettings
+	if err := c.BindJSON(&settings); err != nil {
+		return
+	}
+	if err := organizations.ValidateSettings(settings); err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	org := getMemberOrg(c, true)
+	if org == nil {
+		return
+	}
+	org.Settings = settings
+	db.UpdateOrganization(org)
+
+	c.JSON(http.StatusOK, org.Settings)
+}
+
+// @Summary Sets the compliance policies of the organization
+// @Description Policies are enforced when the organization's events are created, updated, viewed, or responded to. Events past the retention period are deleted
+// @Tags orgs
+// @Accept json
+// @Produce json
+// @Param orgId path string true "Organization ID"
+// @Param payload body models.OrganizationPolicies true "Policies of the organization"
+// @Success 200 {object} models.OrganizationPolicies
+// @Router /orgs/{orgId}/policies [put]
+func updateOrgPolicies(c *gin.Context) {
+	var orgPolicies models.OrganizationPolicies
+	if err := c.BindJSON(&orgPolicies); err != nil {
+		return
+	}
+	if orgPolicies.RetentionDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retentionDays must not be negative"})
+		return
+	}
+	if err := policies.ValidateSso(orgPolicies); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	org := getMemberOrg(c, true)
+	if org == nil {
+		return
+	}
+	org.Policies = orgPolicies
+	db.UpdateOrganization(org)
+
+	c.JSON(http.StatusOK, org.Policies)
+}
+
+// @Summary Claims an email domain for the organization
+// @Description New users signing up with an email on the domain join the organization, once the domain is verified with a TXT record
+// @Tags orgs
+// @Accept json
+// @Produce json
+// @Param orgId path string true "Organization ID"
+// @Param payload body object{domain=string,requireApproval=bool} true "Domain to claim, and whether new users need to be approved by an admin"
+// @Success 200 {object} object{domain=models.OrganizationDomain,recordName=string,recordValue=string}
+// @Router /orgs/{orgId}/domains [post]
+func claimOrgDomain(c *gin.Context) {
+	payload := struct {
+		Domain          string `json:"domain" binding:"required"`
+		RequireApproval bool   `json:"requireApproval"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	domainName, err := organizations.NormalizeDomain(payload.Domain)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	org := getMemberOrg(c, true)
+	if org == nil {
+		return
+	}
+	if other := db.GetOrganizationByVerifiedDomain(domainName); other != nil && other.Id != org.Id {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.DomainAlreadyClaimed})
+		return
+	}
+
+	domain := organizations.GetDomain(org, domainName)
+	if domain == nil {
+		org.Domains = append(org.Domains, models.OrganizationDomain{Domain: domainName, VerificationToken: organizations.NewVerificationToken()})
+		domain = &org.Domains[len(org.Domains)-1]
+	}
+	domain.RequireApproval = payload.RequireApproval
+	db.UpdateOrganization(org)
+
+	recordName, recordValue := organizations.GetVerificationRecord(*domain)
+	c.JSON(http.StatusOK, gin.H{"domain": domain, "recordName": recordName, "recordValue": recordValue})
+}
+
+// @Summary Verifies a claimed domain
+// @Description Checks that the domain has a TXT record with the verification token
+// @Tags orgs
+// @Produce json
+// @Param orgId path string true "Organization ID"
+// @Param domain path string true "Claimed domain"
+// @Success 200 {object} models.OrganizationDomain
+// @Router /orgs/{orgId}/domains/{domain}/verify [post]
+func verifyOrgDomain(c *gin.Context) {
+	org := getMemberOrg(c, true)
+	if org == nil {
+		return
+	}
+	domain := organizations.GetDomain(org, strings.ToLower(c.Param("domain")))
+	if domain == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.DomainNotFound})
+		return
+	}
+	if domain.VerifiedAt != nil {
+		c.JSON(http.StatusOK, domain)
+		return
+	}
+
+	if !organizations.IsDomainVerified(*domain, net.LookupTXT) {
+		recordName, recordValue := organizations.GetVerificationRecord(*domain)
+		c.JSON(http.StatusBadRequest, gin.H{"error": errs.DomainNotVerified, "recordName": recordName, "recordValue": recordValue})
+		return
+	}
+	if other := db.GetOrganizationByVerifiedDomain(domain.Domain); other != nil && other.Id != org.Id {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.DomainAlreadyClaimed})
+		return
+	}
+
+	verifiedAt := primitive.NewDateTimeFromTime(time.Now())
+	domain.VerifiedAt = &verifiedAt
+	db.UpdateOrganization(org)
+
+	c.JSON(http.StatusOK, domain)
+}
+
+// @Summary Removes a claimed domain
+// @Tags orgs
+// @Param orgId path string true "Organization ID"
+// @Param domain path string true "Claimed domain"
+// @Success 200
+// @Router /orgs/{orgId}/domains/{domain} [delete]
+func removeOrgDomain(c *gin.Context) {
+	org := getMemberOrg(c, true)
+	if org == nil {
+		return
+	}
+	domainName := strings.ToLower(c.Param("domain"))
+	index := utils.Find(org.Domains, func(d models.OrganizationDomain) bool { return d.Domain == domainName })
+	if index == -1 {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.DomainNotFound})
+		return
+	}
+	org.Domains = append(org.Domains[:index], org.Domains[index+1:]...)
+	db.UpdateOrganization(org)
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Approves a user's request to join the organization
+// @Tags orgs
+// @Produce json
+// @Param orgId path string true "Organization ID"
+// @Param userId path string true "ID of the user that requested to join"
+// @Success 200 {object} models.Organization
+// @Router /orgs/{orgId}/join-requests/{userId}/approve [post]
+func approveJoinRequest(c *gin.Context) {
+	org := getMemberOrg(c, true)
+	if org == nil {
+		return
+	}
+	request := removeJoinRequest(c, org)
+	if request == nil {
+		return
+	}
+	if organizations.GetMember(org, request.UserId) == nil {
+		org.Members = append(org.Members, models.OrganizationMember{UserId: request.UserId, Role: models.ORG_MEMBER})
+	}
+	db.UpdateOrganization(org)
+
+	c.JSON(http.StatusOK, org)
+}
+
+// @Summary Rejects a user's request to join the organization
+// @Tags orgs
+// @Param orgId path string true "Organization ID"
+// @Param userId path string true "ID of the user that requested to join"
+// @Success 200
+// @Router /orgs/{orgId}/join-requests/{userId} [delete]
+func rejectJoinRequest(c *gin.Context) {
+	org := getMemberOrg(c, true)
+	if org == nil {
+		return
+	}
+	if removeJoinRequest(c, org) == nil {
+		return
+	}
+	db.UpdateOrganization(org)
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// Removes the join request of the user in the path from the organization,
+// responding with an error and returning nil if there is none
+func removeJoinRequest(c *gin.Context, org *models.Organization) *models.OrganizationJoinRequest {
+	userId := c.Param("userId")
+	index := utils.Find(org.JoinRequests, func(r models.OrganizationJoinRequest) bool { return r.UserId.Hex() == userId })
+	if index == -1 {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.JoinRequestNotFound})
+		return nil
+	}
+	request := org.JoinRequests[index]
+	org.JoinRequests = append(org.JoinRequests[:index], org.JoinRequests[index+1:]...)
+	return &request
+}
+
+// Adds the new user to the organization that verified their email domain, or
+// requests to join it if it requires approval
+func joinDomainOrg(user *models.User) {
+	org := db.GetOrganizationByVerifiedDomain(organizations.GetEmailDomain(user.Email))
+	if org == nil || organizations.GetMember(org, user.Id) != nil {
+		return
+	}
+
+	if organizations.GetDomain(org, organizations.GetEmailDomain(user.Email)).RequireApproval {
+		if utils.Find(org.JoinRequests, func(r models.OrganizationJoinRequest) bool { return r.UserId == user.Id }) == -1 {
+			org.JoinRequests = append(org.JoinRequests, models.OrganizationJoinRequest{UserId: user.Id, RequestedAt: primitive.NewDateTimeFromTime(time.Now())})
+		}
+	} else {
+		org.Members = append(org.Members, models.OrganizationMember{UserId: user.Id, Role: models.ORG_MEMBER})
+	}
+	db.UpdateOrganization(org)
+}
+
+// Adds the new user to the organizations that invited their email, with the
+// role they were invited with
+func joinInvitedOrgs(user *models.User) {
+	email := strings.ToLower(user.Email)
+	for _, org := range db.GetOrganizationsByInvitedEmail(email) {
+		org := org
+		index := utils.Find(org.Invites, func(i models.OrganizationInvite) bool { return i.Email == email })
+		if index == -1 {
+			continue
+		}
+		invite := org.Invites[index]
+		org.Invites = append(org.Invites[:index], org.Invites[index+1:]...)
+		if organizations.GetMember(&org, user.Id) == nil {
+			org.Members = append(org.Members, models.OrganizationMember{UserId: user.Id, Role: invite.Role})
+		}
+		db.UpdateOrganization(&org)
+	}
+}
+
+// Returns the organization new events of the user are created in: the given
+// one, or the user's first organization. Responds with an error and returns
+// false if the user isn't a member of the given organization
+func getEventOrg(c *gin.Context, user *models.User, orgId *primitive.ObjectID) (*models.Organization, bool) {
+	if orgId != nil {
+		org := db.GetOrganizationById(orgId.Hex())
+		if org == nil || organizations.GetMember(org, user.Id) == nil {
+			c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotOrganizationMember})
+			return nil, false
+		}
+		return org, true
+	}
+
+	orgs := db.GetOrganizationsByUserId(user.Id)
+	if len(orgs) == 0 {
+		return nil, true
+	}
+	return &orgs[0], true
+}
@@ -0,0 +1,123 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/organizations"
+	"schej.it/server/utils"
+)
+
+// The most days the calendar can be fetched for at once
+const maxOrgCalendarDays = 366
+
+// A scheduled event on the organization's calendar
+type orgCalendarEntry struct {
+	EventId      primitive.ObjectID   `json:"eventId"`
+	ShortId      *string              `json:"shortId"`
+	Name         string               `json:"name"`
+	StartDate    primitive.DateTime   `json:"startDate"`
+	EndDate      primitive.DateTime   `json:"endDate"`
+	OwnerId      primitive.ObjectID   `json:"ownerId"`
+	CoOrganizers []models.CoOrganizer `json:"coOrganizers"`
+	Tags         []string             `json:"tags"`
+	ResourceIds  []primitive.ObjectID `json:"resourceIds"`
+	Timezone     *string              `json:"timezone"`
+}
+
+// @Summary Gets the organization's calendar
+// @Description Lists the finalized events of the organization that overlap the given range, soonest first, for showing a shared team calendar
+// @Tags orgs
+// @Produce json
+// @Param orgId path string true "Organization ID"
+// @Param start query string true "Start of the range"
+// @Param end query string true "End of the range, at most a year after the start"
+// @Param folderId query string false "Only events in this folder of the current user"
+// @Param tags query string false "Comma separated tags, only events with at least one of them"
+// @Param memberId query string false "Only events this member owns or co-organizes"
+// @Success 200 {object} []orgCalendarEntry
+// @Router /orgs/{orgId}/calendar [get]
+func getOrgCalendar(c *gin.Context) {
+	query := struct {
+		Start    time.Time `form:"start" binding:"required"`
+		End      time.Time `form:"end" binding:"required"`
+		FolderId string    `form:"folderId"`
+		Tags     string    `form:"tags"`
+		MemberId string    `form:"memberId"`
+	}{}
+	if err := c.Bind(&query); err != nil {
+		return
+	}
+	if !query.End.After(query.Start) || query.End.Sub(query.Start) > maxOrgCalendarDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end must be after start and at most a year later"})
+		return
+	}
+
+	org := getMemberOrg(c, false)
+	if org == nil {
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	filter := organizations.CalendarFilter{}
+	if len(query.FolderId) > 0 {
+		folderId, err := primitive.ObjectIDFromHex(query.FolderId)
+		if err != nil {
+			c.JSON(http.StatusNotFound, responses.Error{Error: errs.FolderNotFound})
+			return
+		}
+		folder, err := db.GetFolder(folderId)
+		if err != nil || !organizations.CanAccessFolder(org, folder, user.Id, false) {
+			c.JSON(http.StatusNotFound, responses.Error{Error: errs.FolderNotFound})
+			return
+		}
+		eventIds, err := db.GetFolderEventIds(folder)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, responses.Error{Error: err.Error()})
+			return
+		}
+		filter.EventIds = utils.ArrayToSet(eventIds)
+	}
+	if len(query.Tags) > 0 {
+		tags, err := organizations.NormalizeTags(strings.Split(query.Tags, ","))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+			return
+		}
+		filter.Tags = tags
+	}
+	if len(query.MemberId) > 0 {
+		memberId, err := primitive.ObjectIDFromHex(query.MemberId)
+		if err != nil || organizations.GetMember(org, memberId) == nil {
+			c.JSON(http.StatusNotFound, responses.Error{Error: errs.UserNotOrganizationMember})
+			return
+		}
+		filter.MemberId = memberId
+	}
+
+	events := db.GetOrganizationScheduledEventsInRange(org.Id, query.Start, query.End)
+	entries := make([]orgCalendarEntry, 0)
+	for _, event := range organizations.FilterCalendarEvents(events, filter) {
+		entries = append(entries, orgCalendarEntry{
+			EventId:      event.Id,
+			ShortId:      event.ShortId,
+			Name:         event.Name,
+			StartDate:    event.ScheduledEvent.StartDate,
+			EndDate:      event.ScheduledEvent.EndDate,
+			OwnerId:      event.OwnerId,
+			CoOrganizers: event.CoOrganizers,
+			Tags:         event.Tags,
+			ResourceIds:  event.ResourceIds,
+			Timezone:     event.Timezone,
+		})
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
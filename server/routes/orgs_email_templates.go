@@ -0,0 +1,144 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/emailtemplates"
+	"schej.it/server/utils"
+)
+
+type emailTemplatePayload struct {
+	Subject string `json:"subject" binding:"required"`
+	Body    string `json:"body" binding:"required"`
+}
+
+// @Summary Gets the organization's custom emails
+// @Description Includes the variables each type of template can use, with example values
+// @Tags orgs
+// @Produce json
+// @Param orgId path string true "Organization ID"
+// @Success 200 {object} object{templates=[]models.EmailTemplate,variables=map[string]map[string]string}
+// @Router /orgs/{orgId}/email-templates [get]
+func getOrgEmailTemplates(c *gin.Context) {
+	org := getMemberOrg(c, true)
+	if org == nil {
+		return
+	}
+
+	templates := org.EmailTemplates
+	if templates == nil {
+		templates = make([]models.EmailTemplate, 0)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"templates": templates, "variables": emailtemplates.Variables})
+}
+
+// @Summary Sets the organization's custom invite or reminder email
+// @Description Premium only. The body is limited HTML (formatting, lists, and links), and anything else is removed. {{variables}} are filled in when the email is sent, and an opt out link is added if the body doesn't use {{optOutUrl}}
+// @Tags orgs
+// @Accept json
+// @Produce json
+// @Param orgId path string true "Organization ID"
+// @Param type path string true "invite or reminder"
+// @Param payload body emailTemplatePayload true "Subject and HTML body"
+// @Success 200 {object} models.EmailTemplate
+// @Router /orgs/{orgId}/email-templates/{type} [put]
+func setOrgEmailTemplate(c *gin.Context) {
+	var payload emailTemplatePayload
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	org := getMemberOrg(c, true)
+	if org == nil {
+		return
+	}
+	if !utils.Coalesce(org.IsPremium) {
+		c.JSON(http.StatusPaymentRequired, responses.Error{Error: errs.PremiumRequired})
+		return
+	}
+
+	template := models.EmailTemplate{
+		Type:      models.EmailTemplateType(c.Param("type")),
+		Subject:   strings.TrimSpace(payload.Subject),
+		Body:      emailtemplates.Sanitize(payload.Body),
+		UpdatedAt: primitive.NewDateTimeFromTime(time.Now()),
+	}
+	if err := emailtemplates.Validate(template); err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	index := utils.Find(org.EmailTemplates, func(t models.EmailTemplate) bool { return t.Type == template.Type })
+	if index == -1 {
+		org.EmailTemplates = append(org.EmailTemplates, template)
+	} else {
+		org.EmailTemplates[index] = template
+	}
+	db.UpdateOrganization(org)
+
+	c.JSON(http.StatusOK, template)
+}
+
+// @Summary Removes the organization's custom invite or reminder email
+// @Description The default email is sent again
+// @Tags orgs
+// @Param orgId path string true "Organization ID"
+// @Param type path string true "invite or reminder"
+// @Success 200
+// @Router /orgs/{orgId}/email-templates/{type} [delete]
+func removeOrgEmailTemplate(c *gin.Context) {
+	org := getMemberOrg(c, true)
+	if org == nil {
+		return
+	}
+
+	index := utils.Find(org.EmailTemplates, func(t models.EmailTemplate) bool { return string(t.Type) == c.Param("type") })
+	if index == -1 {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.EmailTemplateNotFound})
+		return
+	}
+	org.EmailTemplates = append(org.EmailTemplates[:index], org.EmailTemplates[index+1:]...)
+	db.UpdateOrganization(org)
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Previews a custom invite or reminder email
+// @Description Sanitizes the body and fills in sample values, without saving the template
+// @Tags orgs
+// @Accept json
+// @Produce json
+// @Param orgId path string true "Organization ID"
+// @Param type path string true "invite or reminder"
+// @Param payload body emailTemplatePayload true "Subject and HTML body"
+// @Success 200 {object} object{subject=string,body=string}
+// @Router /orgs/{orgId}/email-templates/{type}/preview [post]
+func previewOrgEmailTemplate(c *gin.Context) {
+	var payload emailTemplatePayload
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	if org := getMemberOrg(c, true); org == nil {
+		return
+	}
+
+	subject, body, err := emailtemplates.Preview(models.EmailTemplate{
+		Type:    models.EmailTemplateType(c.Param("type")),
+		Subject: strings.TrimSpace(payload.Subject),
+		Body:    payload.Body,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subject": subject, "body": body})
+}
@@ -0,0 +1,147 @@
+package routes
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/organizations"
+)
+
+// Returns the DNS records the sending domain needs, marked as verified if
+// they're published
+func checkSendingDomain(sendingDomain *models.OrganizationSendingDomain) []organizations.DnsRecord {
+	records, err := organizations.CheckSendingDomain(*sendingDomain, net.LookupTXT)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return records
+}
+
+// @Summary Gets the domain the organization's emails are sent from
+// @Description Includes the DNS records the domain needs, and whether each of them is published
+// @Tags orgs
+// @Produce json
+// @Param orgId path string true "Organization ID"
+// @Success 200 {object} object{sendingDomain=models.OrganizationSendingDomain,records=[]organizations.DnsRecord}
+// @Router /orgs/{orgId}/sending-domain [get]
+func getOrgSendingDomain(c *gin.Context) {
+	org := getMemberOrg(c, true)
+	if org == nil {
+		return
+	}
+	if org.SendingDomain == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.SendingDomainNotFound})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sendingDomain": org.SendingDomain, "records": checkSendingDomain(org.SendingDomain)})
+}
+
+// @Summary Sets the domain the organization's emails are sent from
+// @Description Invites, reminders, and broadcasts of the organization's events are sent from <localPart>@<domain> and signed with a DKIM key of the domain, once its verification, SPF, and DKIM records are published and verified. Changing the domain generates a new key and needs verifying again
+// @Tags orgs
+// @Accept json
+// @Produce json
+// @Param orgId path string true "Organization ID"
+// @Param payload body object{domain=string,localPart=string,fromName=string} true "Domain, local part of the from address (e.g. invites), and optionally the name emails are from"
+// @Success 200 {object} object{sendingDomain=models.OrganizationSendingDomain,records=[]organizations.DnsRecord}
+// @Router /orgs/{orgId}/sending-domain [put]
+func setOrgSendingDomain(c *gin.Context) {
+	payload := struct {
+		Domain    string `json:"domain" binding:"required"`
+		LocalPart string `json:"localPart" binding:"required"`
+		FromName  string `json:"fromName"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	domain, err := organizations.NormalizeDomain(payload.Domain)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+	if err := organizations.ValidateLocalPart(payload.LocalPart); err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	org := getMemberOrg(c, true)
+	if org == nil {
+		return
+	}
+
+	if org.SendingDomain != nil && org.SendingDomain.Domain == domain {
+		org.SendingDomain.LocalPart = strings.ToLower(payload.LocalPart)
+		org.SendingDomain.FromName = strings.TrimSpace(payload.FromName)
+	} else {
+		sendingDomain, err := organizations.NewSendingDomain(domain, payload.LocalPart, payload.FromName)
+		if err != nil {
+			logger.StdErr.Panicln(err)
+		}
+		org.SendingDomain = sendingDomain
+	}
+	db.UpdateOrganization(org)
+
+	c.JSON(http.StatusOK, gin.H{"sendingDomain": org.SendingDomain, "records": checkSendingDomain(org.SendingDomain)})
+}
+
+// @Summary Verifies the domain the organization's emails are sent from
+// @Description Checks that the verification, SPF, and DKIM records are published. Responds with the records and which of them are missing if they aren't all verified
+// @Tags orgs
+// @Produce json
+// @Param orgId path string true "Organization ID"
+// @Success 200 {object} object{sendingDomain=models.OrganizationSendingDomain,records=[]organizations.DnsRecord}
+// @Router /orgs/{orgId}/sending-domain/verify [post]
+func verifyOrgSendingDomain(c *gin.Context) {
+	org := getMemberOrg(c, true)
+	if org == nil {
+		return
+	}
+	if org.SendingDomain == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.SendingDomainNotFound})
+		return
+	}
+
+	records := checkSendingDomain(org.SendingDomain)
+	if !organizations.AreRecordsVerified(records) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errs.DomainNotVerified, "sendingDomain": org.SendingDomain, "records": records})
+		return
+	}
+	if org.SendingDomain.VerifiedAt == nil {
+		verifiedAt := primitive.NewDateTimeFromTime(time.Now())
+		org.SendingDomain.VerifiedAt = &verifiedAt
+		db.UpdateOrganization(org)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sendingDomain": org.SendingDomain, "records": records})
+}
+
+// @Summary Removes the domain the organization's emails are sent from
+// @Description Emails are sent from the default address again
+// @Tags orgs
+// @Param orgId path string true "Organization ID"
+// @Success 200
+// @Router /orgs/{orgId}/sending-domain [delete]
+func removeOrgSendingDomain(c *gin.Context) {
+	org := getMemberOrg(c, true)
+	if org == nil {
+		return
+	}
+	if org.SendingDomain == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.SendingDomainNotFound})
+		return
+	}
+	org.SendingDomain = nil
+	db.UpdateOrganization(org)
+
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,86 @@
+package routes
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/services/organizations"
+)
+
+// @Summary Gets the monthly usage of the organization
+// @Description Summarises the events created, responses collected, active members, and integration usage of the organization for each of the last months, e.g. for internal chargeback
+// @Tags orgs
+// @Produce json
+// @Produce text/csv
+// @Param orgId path string true "Organization ID"
+// @Param months query int false "Number of months, including the current one, defaults to 12"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} []organizations.MonthlyUsage
+// @Router /orgs/{orgId}/usage [get]
+func getOrgUsage(c *gin.Context) {
+	query := struct {
+		Months *int   `form:"months"`
+		Format string `form:"format"`
+	}{}
+	if err := c.Bind(&query); err != nil {
+		return
+	}
+	numMonths := 12
+	if query.Months != nil {
+		if *query.Months <= 0 || *query.Months > 60 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "months must be between 1 and 60"})
+			return
+		}
+		numMonths = *query.Months
+	}
+
+	org := getMemberOrg(c, true)
+	if org == nil {
+		return
+	}
+
+	start := organizations.GetUsageStart(time.Now(), numMonths)
+	memberIds := make([]primitive.ObjectID, 0)
+	for _, member := range org.Members {
+		memberIds = append(memberIds, member.UserId)
+	}
+	events := db.GetOrganizationEventsSince(org.Id, start)
+	eventIds := make([]primitive.ObjectID, 0)
+	for _, event := range events {
+		eventIds = append(eventIds, event.Id)
+	}
+	usage := organizations.GetMonthlyUsage(organizations.UsageRecords{
+		Events:           events,
+		Responses:        db.GetEventResponsesSince(eventIds, start),
+		DailyUserLogs:    db.GetDailyUserLogsSince(memberIds, start),
+		SlackAccounts:    db.GetSlackAccountsLinkedSince(memberIds, start),
+		GoogleChatShares: db.GetGoogleChatSharesSince(eventIds, start),
+	}, org.Members, start, numMonths)
+
+	if query.Format != "csv" {
+		c.JSON(http.StatusOK, usage)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", org.Name+" usage.csv"))
+	c.Header("Content-Type", "text/csv")
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"Month", "Events created", "Responses collected", "Active members", "Slack accounts linked", "Google Chat shares"})
+	for _, month := range usage {
+		w.Write([]string{
+			month.Month,
+			strconv.Itoa(month.EventsCreated),
+			strconv.Itoa(month.ResponsesCollected),
+			strconv.Itoa(month.ActiveMembers),
+			strconv.Itoa(month.SlackAccountsLinked),
+			strconv.Itoa(month.GoogleChatShares),
+		})
+	}
+	w.Flush()
+}
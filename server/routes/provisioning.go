@@ -0,0 +1,403 @@
+/* The /provisioning group lets infrastructure tools (e.g. Terraform) manage organizations, their members, and their folders declaratively, by stable external ids. Every PUT creates the resource or brings it to the given state, and DELETEs succeed if the resource is already gone */
+package routes
+
+import (
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/middleware"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/organizations"
+	"schej.it/server/utils"
+)
+
+func InitProvisioning(router *gin.RouterGroup) {
+	provisioningRouter := router.Group("/provisioning/orgs/:externalId")
+	provisioningRouter.Use(middleware.AuthRequired())
+
+	provisioningRouter.GET("", getProvisionedOrg)
+	provisioningRouter.PUT("", putProvisionedOrg)
+	provisioningRouter.GET("/members/:email", getProvisionedMember)
+	provisioningRouter.PUT("/members/:email", putProvisionedMember)
+	provisioningRouter.DELETE("/members/:email", deleteProvisionedMember)
+	provisioningRouter.GET("/folders/:folderExternalId", getProvisionedFolder)
+	provisioningRouter.PUT("/folders/:folderExternalId", putProvisionedFolder)
+	provisioningRouter.DELETE("/folders/:folderExternalId", deleteProvisionedFolder)
+}
+
+// A member of a provisioned organization, by email
+type provisionedMember struct {
+	Email  string                  `json:"email"`
+	Role   models.OrganizationRole `json:"role"`
+	UserId *primitive.ObjectID     `json:"userId"`
+
+	// Whether they were invited and haven't signed up yet
+	Pending bool `json:"pending"`
+}
+
+// Returns the external id in the path, or responds with an error and returns
+// false if it's invalid
+func getExternalIdParam(c *gin.Context, name string) (string, bool) {
+	externalId := c.Param(name)
+	if err := organizations.ValidateExternalId(externalId); err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return "", false
+	}
+	return externalId, true
+}
+
+// Returns the current user's organization with the external id in the path
+// if they're a member (or an admin, if adminOnly), otherwise responds with an
+// error and returns nil
+func getExternalOrg(c *gin.Context, adminOnly bool) *models.Organization {
+	externalId, ok := getExternalIdParam(c, "externalId")
+	if !ok {
+		return nil
+	}
+	user := utils.GetAuthUser(c)
+	org := db.GetOrganizationByExternalId(user.Id, externalId)
+	if org == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.OrganizationNotFound})
+		return nil
+	}
+	if adminOnly && !organizations.IsAdmin(org, user.Id) {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotOrganizationAdmin})
+		return nil
+	}
+	return org
+}
+
+// Returns the lowercased email in the path, or responds with an error and
+// returns false if it's invalid
+func getEmailParam(c *gin.Context) (string, bool) {
+	address, err := mail.ParseAddress(strings.TrimSpace(c.Param("email")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email"})
+		return "", false
+	}
+	return strings.ToLower(address.Address), true
+}
+
+// Returns the member or invitee of the organization with the email, or nil if
+// there is none
+func findProvisionedMember(org *models.Organization, email string) *provisionedMember {
+	if user := db.GetUserByEmail(email); user != nil {
+		if member := organizations.GetMember(org, user.Id); member != nil {
+			return &provisionedMember{Email: email, Role: member.Role, UserId: &user.Id}
+		}
+	}
+	if index := utils.Find(org.Invites, func(i models.OrganizationInvite) bool { return i.Email == email }); index != -1 {
+		return &provisionedMember{Email: email, Role: org.Invites[index].Role, Pending: true}
+	}
+	return nil
+}
+
+// @Summary Gets an organization by its external id
+// @Tags provisioning
+// @Produce json
+// @Param externalId path string true "External ID of the organization"
+// @Success 200 {object} models.Organization
+// @Router /provisioning/orgs/{externalId} [get]
+func getProvisionedOrg(c *gin.Context) {
+	org := getExternalOrg(c, false)
+	if org == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, org)
+}
+
+// @Summary Creates or updates an organization by its external id
+// @Description Creates the organization with the current user as its owner (201), or sets the name, and the settings and policies if given, of the organization the current user administers (200). Organizations can't be deleted through the API
+// @Tags provisioning
+// @Accept json
+// @Produce json
+// @Param externalId path string true "External ID of the organization"
+// @Param payload body object{name=string,settings=models.OrganizationSettings,policies=models.OrganizationPolicies} true "Name, and optionally the default settings and policies"
+// @Success 200 {object} models.Organization
+// @Success 201 {object} models.Organization
+// @Router /provisioning/orgs/{externalId} [put]
+func putProvisionedOrg(c *gin.Context) {
+	payload := struct {
+		Name     string                       `json:"name" binding:"required"`
+		Settings *models.OrganizationSettings `json:"settings"`
+		Policies *models.OrganizationPolicies `json:"policies"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	if payload.Settings != nil {
+		if err := organizations.ValidateSettings(*payload.Settings); err != nil {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+			return
+		}
+	}
+	if payload.Policies != nil && payload.Policies.RetentionDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retentionDays must not be negative"})
+		return
+	}
+	externalId, ok := getExternalIdParam(c, "externalId")
+	if !ok {
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	org := db.GetOrganizationByExternalId(user.Id, externalId)
+	status := http.StatusOK
+	if org == nil {
+		org = &models.Organization{
+			ExternalId: &externalId,
+			Members:    []models.OrganizationMember{{UserId: user.Id, Role: models.ORG_OWNER}},
+			CreatedAt:  primitive.NewDateTimeFromTime(time.Now()),
+		}
+		status = http.StatusCreated
+	} else if !organizations.IsAdmin(org, user.Id) {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotOrganizationAdmin})
+		return
+	}
+
+	org.Name = strings.TrimSpace(payload.Name)
+	if payload.Settings != nil {
+		org.Settings = *payload.Settings
+	}
+	if payload.Policies != nil {
+		org.Policies = *payload.Policies
+	}
+	if status == http.StatusCreated {
+		db.InsertOrganization(org)
+	} else {
+		db.UpdateOrganization(org)
+	}
+
+	c.JSON(status, org)
+}
+
+// @Summary Gets a member of an organization by email
+// @Description Includes people invited without an account, as pending members
+// @Tags provisioning
+// @Produce json
+// @Param externalId path string true "External ID of the organization"
+// @Param email path string true "Email of the member"
+// @Success 200 {object} provisionedMember
+// @Router /provisioning/orgs/{externalId}/members/{email} [get]
+func getProvisionedMember(c *gin.Context) {
+	org := getExternalOrg(c, false)
+	if org == nil {
+		return
+	}
+	email, ok := getEmailParam(c)
+	if !ok {
+		return
+	}
+
+	member := findProvisionedMember(org, email)
+	if member == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.UserNotOrganizationMember})
+		return
+	}
+	c.JSON(http.StatusOK, member)
+}
+
+// @Summary Adds a member to an organization by email, or sets their role
+// @Description People without an account are invited, and emailed the first time only, so applying the same config again doesn't send more emails
+// @Tags provisioning
+// @Accept json
+// @Produce json
+// @Param externalId path string true "External ID of the organization"
+// @Param email path string true "Email of the member"
+// @Param payload body object{role=models.OrganizationRole} true "Role of the member"
+// @Success 200 {object} provisionedMember
+// @Router /provisioning/orgs/{externalId}/members/{email} [put]
+func putProvisionedMember(c *gin.Context) {
+	payload := struct {
+		Role models.OrganizationRole `json:"role" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	if !organizations.IsValidRole(payload.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+		return
+	}
+	org := getExternalOrg(c, true)
+	if org == nil {
+		return
+	}
+	email, ok := getEmailParam(c)
+	if !ok {
+		return
+	}
+
+	if !setOrgMember(c, org, email, payload.Role) {
+		return
+	}
+	c.JSON(http.StatusOK, findProvisionedMember(org, email))
+}
+
+// @Summary Removes a member, or the invite of someone without an account, from an organization
+// @Tags provisioning
+// @Param externalId path string true "External ID of the organization"
+// @Param email path string true "Email of the member"
+// @Success 200
+// @Router /provisioning/orgs/{externalId}/members/{email} [delete]
+func deleteProvisionedMember(c *gin.Context) {
+	org := getExternalOrg(c, true)
+	if org == nil {
+		return
+	}
+	email, ok := getEmailParam(c)
+	if !ok {
+		return
+	}
+
+	member := findProvisionedMember(org, email)
+	if member == nil {
+		c.Status(http.StatusOK)
+		return
+	}
+	if member.Pending {
+		if member.Role == models.ORG_OWNER && !checkRoleChange(c, org, primitive.NilObjectID, models.ORG_OWNER) {
+			return
+		}
+		index := utils.Find(org.Invites, func(i models.OrganizationInvite) bool { return i.Email == email })
+		org.Invites = append(org.Invites[:index], org.Invites[index+1:]...)
+	} else {
+		if !checkRoleChange(c, org, *member.UserId, "") {
+			return
+		}
+		index := utils.Find(org.Members, func(m models.OrganizationMember) bool { return m.UserId == *member.UserId })
+		org.Members = append(org.Members[:index], org.Members[index+1:]...)
+	}
+	db.UpdateOrganization(org)
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Gets a folder of an organization by its external id
+// @Tags provisioning
+// @Produce json
+// @Param externalId path string true "External ID of the organization"
+// @Param folderExternalId path string true "External ID of the folder"
+// @Success 200 {object} models.Folder
+// @Router /provisioning/orgs/{externalId}/folders/{folderExternalId} [get]
+func getProvisionedFolder(c *gin.Context) {
+	org := getExternalOrg(c, false)
+	if org == nil {
+		return
+	}
+	folderExternalId, ok := getExternalIdParam(c, "folderExternalId")
+	if !ok {
+		return
+	}
+
+	folder := db.GetFolderByExternalId(org.Id, folderExternalId)
+	if folder == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.FolderNotFound})
+		return
+	}
+	eventIds, err := db.GetFolderEventIds(folder)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	folder.EventIds = eventIds
+
+	c.JSON(http.StatusOK, folder)
+}
+
+// @Summary Creates or updates a folder of an organization by its external id
+// @Description The folder is shared with the organization's members. Responds with 201 if it was created
+// @Tags provisioning
+// @Accept json
+// @Produce json
+// @Param externalId path string true "External ID of the organization"
+// @Param folderExternalId path string true "External ID of the folder"
+// @Param payload body object{name=string,color=string} true "Name and optional color of the folder"
+// @Success 200 {object} models.Folder
+// @Success 201 {object} models.Folder
+// @Router /provisioning/orgs/{externalId}/folders/{folderExternalId} [put]
+func putProvisionedFolder(c *gin.Context) {
+	payload := struct {
+		Name  string  `json:"name" binding:"required"`
+		Color *string `json:"color"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	org := getExternalOrg(c, true)
+	if org == nil {
+		return
+	}
+	folderExternalId, ok := getExternalIdParam(c, "folderExternalId")
+	if !ok {
+		return
+	}
+
+	folder := db.GetFolderByExternalId(org.Id, folderExternalId)
+	if folder == nil {
+		folder = &models.Folder{
+			UserId:         utils.GetAuthUser(c).Id,
+			OrganizationId: &org.Id,
+			ExternalId:     &folderExternalId,
+			Name:           payload.Name,
+			Color:          payload.Color,
+			EventIds:       make([]primitive.ObjectID, 0),
+		}
+		id, err := db.CreateFolder(folder)
+		if err != nil {
+			logger.StdErr.Panicln(err)
+		}
+		folder.Id = id
+
+		c.JSON(http.StatusCreated, folder)
+		return
+	}
+
+	folder.Name = payload.Name
+	folder.Color = payload.Color
+	updates := bson.M{"name": folder.Name, "color": folder.Color}
+	if err := db.UpdateFolder(folder.Id, folder.UserId, updates); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	eventIds, err := db.GetFolderEventIds(folder)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	folder.EventIds = eventIds
+
+	c.JSON(http.StatusOK, folder)
+}
+
+// @Summary Deletes a folder of an organization by its external id
+// @Description The events in the folder aren't deleted
+// @Tags provisioning
+// @Param externalId path string true "External ID of the organization"
+// @Param folderExternalId path string true "External ID of the folder"
+// @Success 200
+// @Router /provisioning/orgs/{externalId}/folders/{folderExternalId} [delete]
+func deleteProvisionedFolder(c *gin.Context) {
+	org := getExternalOrg(c, true)
+	if org == nil {
+		return
+	}
+	folderExternalId, ok := getExternalIdParam(c, "folderExternalId")
+	if !ok {
+		return
+	}
+
+	if folder := db.GetFolderByExternalId(org.Id, folderExternalId); folder != nil {
+		if err := db.DeleteOrganizationFolder(folder.Id); err != nil {
+			logger.StdErr.Panicln(err)
+		}
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,182 @@
+/* The /resources group contains all the routes for bookable resources (rooms, equipment) that can be reserved when finalizing events */
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/middleware"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/resources"
+	"schej.it/server/utils"
+)
+
+func InitResources(router *gin.RouterGroup) {
+	resourceRouter := router.Group("/resources")
+
+	resourceRouter.GET("", middleware.AuthRequired(), getResources)
+	resourceRouter.POST("", middleware.AuthRequired(), createResource)
+	resourceRouter.PUT("/:resourceId", middleware.AuthRequired(), editResource)
+	resourceRouter.DELETE("/:resourceId", middleware.AuthRequired(), deleteResource)
+	resourceRouter.GET("/:resourceId/busy", middleware.AuthRequired(), getResourceBusyTimes)
+}
+
+// Payload used to create and edit resources
+type resourcePayload struct {
+	Name        string                   `json:"name" binding:"required"`
+	Type        models.ResourceType      `json:"type" binding:"required"`
+	Description string                   `json:"description"`
+	Capacity    *int                     `json:"capacity"`
+	Calendar    *models.ResourceCalendar `json:"calendar"`
+}
+
+// Returns the resource in the resourceId param if it's owned by the current
+// user, otherwise responds with an error and returns nil
+func getOwnedResource(c *gin.Context) *models.Resource {
+	resource := db.GetResourceById(c.Param("resourceId"))
+	if resource == nil || resource.OwnerId != utils.GetAuthUser(c).Id {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.ResourceNotFound})
+		return nil
+	}
+	return resource
+}
+
+// Returns the resources with the given ids if they're all owned by the given
+// user, otherwise responds with an error and returns false
+func getOwnedResources(c *gin.Context, user *models.User, resourceIds []primitive.ObjectID) ([]models.Resource, bool) {
+	resourcesList := db.GetResourcesByIds(resourceIds)
+	if len(resourcesList) != len(utils.ArrayToSet(resourceIds)) {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.ResourceNotFound})
+		return nil, false
+	}
+	for _, resource := range resourcesList {
+		if resource.OwnerId != user.Id {
+			c.JSON(http.StatusNotFound, responses.Error{Error: errs.ResourceNotFound})
+			return nil, false
+		}
+	}
+	return resourcesList, true
+}
+
+// @Summary Gets all the resources of the current user
+// @Tags resources
+// @Produce json
+// @Success 200 {object} []models.Resource
+// @Router /resources [get]
+func getResources(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	c.JSON(http.StatusOK, db.GetResourcesByOwnerId(user.Id))
+}
+
+// @Summary Creates a new resource
+// @Tags resources
+// @Accept json
+// @Produce json
+// @Param payload body resourcePayload true "Resource details. calendar optionally links a calendar (e.g. a Google resource calendar) on one of the user's calendar accounts"
+// @Success 201 {object} models.Resource
+// @Router /resources [post]
+func createResource(c *gin.Context) {
+	payload := resourcePayload{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	if payload.Type != models.ROOM && payload.Type != models.EQUIPMENT {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "invalid type"})
+		return
+	}
+
+	user := utils.GetAuthUser(c)
+	resource := models.Resource{
+		OwnerId:     user.Id,
+		Name:        payload.Name,
+		Type:        payload.Type,
+		Description: payload.Description,
+		Capacity:    payload.Capacity,
+		Calendar:    payload.Calendar,
+	}
+	db.InsertResource(&resource)
+
+	c.JSON(http.StatusCreated, resource)
+}
+
+// @Summary Edits a resource
+// @Tags resources
+// @Accept json
+// @Param resourceId path string true "Resource ID"
+// @Param payload body resourcePayload true "Resource details"
+// @Success 200
+// @Router /resources/{resourceId} [put]
+func editResource(c *gin.Context) {
+	payload := resourcePayload{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	if payload.Type != models.ROOM && payload.Type != models.EQUIPMENT {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "invalid type"})
+		return
+	}
+
+	resource := getOwnedResource(c)
+	if resource == nil {
+		return
+	}
+
+	resource.Name = payload.Name
+	resource.Type = payload.Type
+	resource.Description = payload.Description
+	resource.Capacity = payload.Capacity
+	resource.Calendar = payload.Calendar
+	db.UpdateResource(resource)
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Deletes a resource
+// @Description Existing bookings are kept
+// @Tags resources
+// @Param resourceId path string true "Resource ID"
+// @Success 200
+// @Router /resources/{resourceId} [delete]
+func deleteResource(c *gin.Context) {
+	resource := getOwnedResource(c)
+	if resource == nil {
+		return
+	}
+
+	resource.IsDeleted = utils.TruePtr()
+	db.UpdateResource(resource)
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Gets the times a resource is busy
+// @Description Includes bookings made when finalizing events and busy events on the resource's linked calendar
+// @Tags resources
+// @Produce json
+// @Param resourceId path string true "Resource ID"
+// @Param timeMin query string true "Lower bound for the busy times"
+// @Param timeMax query string true "Upper bound for the busy times"
+// @Success 200 {object} []resources.Busy
+// @Router /resources/{resourceId}/busy [get]
+func getResourceBusyTimes(c *gin.Context) {
+	query := struct {
+		TimeMin time.Time `form:"timeMin" binding:"required"`
+		TimeMax time.Time `form:"timeMax" binding:"required"`
+	}{}
+	if err := c.Bind(&query); err != nil {
+		return
+	}
+
+	resource := getOwnedResource(c)
+	if resource == nil {
+		return
+	}
+
+	busy := resources.GetBusyTimes([]models.Resource{*resource}, query.TimeMin, query.TimeMax, primitive.NilObjectID)
+	c.JSON(http.StatusOK, busy[resource.Id.Hex()])
+}
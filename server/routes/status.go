@@ -0,0 +1,56 @@
+/* The /status group contains the public health summary for the status page */
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/models"
+	"schej.it/server/services/status"
+)
+
+// How long resolved incidents stay on the status page
+const resolvedIncidentDays = 7
+
+func InitStatus(router *gin.RouterGroup) {
+	router.GET("/status", getServiceStatus)
+}
+
+// @Summary Gets the health of the service
+// @Description Summarises the health of each component from the results of recent calls to it, taking into account the incidents posted by admins, along with the incidents that are ongoing or were resolved in the last week. Doesn't require signing in
+// @Tags status
+// @Produce json
+// @Success 200 {object} object{health=status.Health,components=[]status.ComponentStatus,incidents=[]models.Incident}
+// @Router /status [get]
+func getServiceStatus(c *gin.Context) {
+	now := time.Now()
+	pingErr := db.Ping()
+	status.Record(status.DATABASE, pingErr)
+
+	// Incidents can't be fetched while the database is unreachable
+	components := status.GetStatuses(now)
+	incidents := make([]models.Incident, 0)
+	if pingErr == nil {
+		incidents = db.GetRecentIncidents(now.AddDate(0, 0, -resolvedIncidentDays))
+	}
+
+	// Ongoing incidents override the health of the components they affect
+	health := status.OPERATIONAL
+	for i := range components {
+		for _, incident := range incidents {
+			if incident.Status == models.INCIDENT_RESOLVED {
+				continue
+			}
+			for _, component := range incident.Components {
+				if component == string(components[i].Component) {
+					components[i].Health = status.Worst(components[i].Health, status.Health(incident.Impact))
+				}
+			}
+		}
+		health = status.Worst(health, components[i].Health)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"health": health, "components": components, "incidents": incidents})
+}
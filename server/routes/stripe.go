@@ -19,7 +19,11 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"schej.it/server/db"
+	"schej.it/server/errs"
 	"schej.it/server/logger"
+	"schej.it/server/middleware"
+	"schej.it/server/responses"
+	"schej.it/server/services/privacy"
 	"schej.it/server/slackbot"
 	"schej.it/server/utils"
 )
@@ -32,6 +36,16 @@ func InitStripe(router *gin.RouterGroup) {
 	stripeRouter.POST("/fulfill-checkout", fulfillCheckout)
 	stripeRouter.POST("/webhook", stripeWebhook)
 	stripeRouter.GET("/billing-portal", getBillingPortalUrl)
+	stripeRouter.POST("/connect/onboard", middleware.AuthRequired(), onboardConnectAccount)
+	stripeRouter.GET("/connect/status", middleware.AuthRequired(), getConnectStatus)
+	stripeRouter.POST("/connect/dashboard", middleware.AuthRequired(), getConnectDashboardUrl)
+	stripeRouter.GET("/connect/payouts", middleware.AuthRequired(), getConnectPayouts)
+
+	// Billing of organizations, managed by their admins and billing admins
+	orgBillingRouter := stripeRouter.Group("/orgs/:orgId", middleware.AuthRequired(), middleware.BillingAdminRequired())
+	orgBillingRouter.POST("/create-checkout-session", createOrgCheckoutSession)
+	orgBillingRouter.GET("/billing-portal", getOrgBillingPortalUrl)
+	orgBillingRouter.GET("/invoices", getOrgInvoices)
 }
 
 type CheckoutSessionPayload struct {
@@ -48,36 +62,12 @@ func createCheckoutSession(c *gin.Context) {
 		return
 	}
 
-	originURL := payload.OriginURL
-	finalRedirectURL := originURL // This is where the user should end up AFTER the /stripe-redirect page
-
-	// Get the base URL for constructing the intermediate redirect path
-	baseURL := utils.GetBaseUrl()
-	intermediateRedirectBase, err := url.Parse(baseURL)
+	successURLStr, cancelURLStr, err := getCheckoutRedirectUrls(payload.OriginURL)
 	if err != nil {
-		logger.StdErr.Printf("Error parsing Base URL '%s': %v. Cannot construct redirect URLs.", baseURL, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error configuring redirect"})
 		return
 	}
 
-	// Create success URL (points to /stripe-redirect)
-	successURL := *intermediateRedirectBase // Start with base URL
-	successURL.Path = "/stripe-redirect"    // Set path
-	successQuery := url.Values{}
-	successQuery.Set("upgrade", "success")
-	successQuery.Set("redirect_url", finalRedirectURL) // Add the final destination
-	successURL.RawQuery = successQuery.Encode()
-	successURLStr := successURL.String()
-
-	// Create cancel URL (points to /stripe-redirect)
-	cancelURL := *intermediateRedirectBase // Start with base URL
-	cancelURL.Path = "/stripe-redirect"    // Set path
-	cancelQuery := url.Values{}
-	cancelQuery.Set("upgrade", "cancel")
-	cancelQuery.Set("redirect_url", finalRedirectURL) // Add the final destination
-	cancelURL.RawQuery = cancelQuery.Encode()
-	cancelURLStr := cancelURL.String()
-
 	params := &stripe.CheckoutSessionParams{
 		ClientReferenceID: stripe.String(payload.UserID),
 		LineItems: []*stripe.CheckoutSessionLineItemParams{
@@ -112,6 +102,38 @@ func createCheckoutSession(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"url": s.URL})
 }
 
+// Returns the success and cancel URLs of a checkout session, which go through
+// the /stripe-redirect page before ending up at originURL
+func getCheckoutRedirectUrls(originURL string) (string, string, error) {
+	finalRedirectURL := originURL // This is where the user should end up AFTER the /stripe-redirect page
+
+	// Get the base URL for constructing the intermediate redirect path
+	baseURL := utils.GetBaseUrl()
+	intermediateRedirectBase, err := url.Parse(baseURL)
+	if err != nil {
+		logger.StdErr.Printf("Error parsing Base URL '%s': %v. Cannot construct redirect URLs.", baseURL, err)
+		return "", "", err
+	}
+
+	// Create success URL (points to /stripe-redirect)
+	successURL := *intermediateRedirectBase // Start with base URL
+	successURL.Path = "/stripe-redirect"    // Set path
+	successQuery := url.Values{}
+	successQuery.Set("upgrade", "success")
+	successQuery.Set("redirect_url", finalRedirectURL) // Add the final destination
+	successURL.RawQuery = successQuery.Encode()
+
+	// Create cancel URL (points to /stripe-redirect)
+	cancelURL := *intermediateRedirectBase // Start with base URL
+	cancelURL.Path = "/stripe-redirect"    // Set path
+	cancelQuery := url.Values{}
+	cancelQuery.Set("upgrade", "cancel")
+	cancelQuery.Set("redirect_url", finalRedirectURL) // Add the final destination
+	cancelURL.RawQuery = cancelQuery.Encode()
+
+	return successURL.String(), cancelURL.String(), nil
+}
+
 func getPrice(c *gin.Context) {
 	// Get the experiment query parameter
 	exp := c.Query("exp")
@@ -199,9 +221,22 @@ func _fulfillCheckout(sessionId string) {
 	// Retrieve the Checkout Session from the API with line_items expanded
 	params := &stripe.CheckoutSessionParams{}
 	params.AddExpand("line_items")
+	params.AddExpand("payment_intent")
 
 	cs, _ := session.Get(sessionId, params)
 
+	// Payments for sign up slots are fulfilled separately
+	if cs != nil && cs.Metadata["paymentId"] != "" {
+		fulfillSignUpPayment(cs)
+		return
+	}
+
+	// Organization subscriptions are fulfilled separately
+	if cs != nil && cs.Metadata["orgId"] != "" {
+		fulfillOrgCheckout(cs)
+		return
+	}
+
 	// Check the Checkout Session's payment_status property
 	// to determine if fulfillment should be performed
 	if cs.PaymentStatus != stripe.CheckoutSessionPaymentStatusUnpaid {
@@ -241,7 +276,7 @@ func _fulfillCheckout(sessionId string) {
 					}
 					amountTotal := float32(cs.LineItems.Data[0].AmountTotal) / 100.0
 
-					message := fmt.Sprintf(":moneybag: %s %s (%s) paid for Schej ($%.2f, %s) :moneybag:", user.FirstName, user.LastName, user.Email, amountTotal, priceDescription)
+					message := fmt.Sprintf(":moneybag: %s paid for Schej ($%.2f, %s) :moneybag:", privacy.GetAnalyticsName(user), amountTotal, priceDescription)
 					slackbot.SendTextMessageWithType(message, slackbot.MONETIZATION)
 				}
 
@@ -300,6 +335,7 @@ func stripeWebhook(c *gin.Context) {
 			return
 		}
 		db.UsersCollection.UpdateOne(context.Background(), bson.M{"stripeCustomerId": inv.Customer.ID}, bson.M{"$set": bson.M{"isPremium": true}})
+		setOrgPremium(inv.Customer.ID, true)
 		logger.StdOut.Printf("Customer %s renewed Schej!\n", inv.Customer.ID)
 	} else if event.Type == stripe.EventTypeInvoicePaymentFailed {
 		var inv stripe.Invoice
@@ -309,6 +345,11 @@ func stripeWebhook(c *gin.Context) {
 			c.AbortWithStatus(http.StatusBadRequest)
 			return
 		}
+		if setOrgPremium(inv.Customer.ID, false) {
+			logger.StdOut.Printf("Organization customer %s failed to pay for Schej!\n", inv.Customer.ID)
+			c.Status(http.StatusOK)
+			return
+		}
 		user := db.GetUserByStripeCustomerId(inv.Customer.ID)
 		if user == nil {
 			logger.StdErr.Printf("Error getting user: %v", err)
@@ -317,7 +358,7 @@ func stripeWebhook(c *gin.Context) {
 		db.UsersCollection.UpdateOne(context.Background(), bson.M{"stripeCustomerId": inv.Customer.ID}, bson.M{"$set": bson.M{"isPremium": false}})
 		logger.StdOut.Printf("Customer %s failed to pay for Schej!\n", inv.Customer.ID)
 
-		message := fmt.Sprintf(":x: %s %s (%s) failed to pay for Schej :x:", user.FirstName, user.LastName, user.Email)
+		message := fmt.Sprintf(":x: %s failed to pay for Schej :x:", privacy.GetAnalyticsName(user))
 		slackbot.SendTextMessageWithType(message, slackbot.MONETIZATION)
 	} else if event.Type == stripe.EventTypeCustomerSubscriptionDeleted {
 		var sub stripe.Subscription
@@ -327,6 +368,11 @@ func stripeWebhook(c *gin.Context) {
 			c.AbortWithStatus(http.StatusBadRequest)
 			return
 		}
+		if setOrgPremium(sub.Customer.ID, false) {
+			logger.StdOut.Printf("Organization customer %s cancelled their subscription!\n", sub.Customer.ID)
+			c.Status(http.StatusOK)
+			return
+		}
 		user := db.GetUserByStripeCustomerId(sub.Customer.ID)
 		if user == nil {
 			logger.StdErr.Printf("Error getting user: %v", err)
@@ -335,8 +381,25 @@ func stripeWebhook(c *gin.Context) {
 		db.UsersCollection.UpdateOne(context.Background(), bson.M{"stripeCustomerId": sub.Customer.ID}, bson.M{"$set": bson.M{"isPremium": false}})
 		logger.StdOut.Printf("Customer %s cancelled their subscription!\n", sub.Customer.ID)
 
-		message := fmt.Sprintf(":x: %s %s (%s) cancelled their subscription :x:", user.FirstName, user.LastName, user.Email)
+		message := fmt.Sprintf(":x: %s cancelled their subscription :x:", privacy.GetAnalyticsName(user))
 		slackbot.SendTextMessageWithType(message, slackbot.MONETIZATION)
+	} else if event.Type == stripe.EventTypeAccountUpdated {
+		var acct stripe.Account
+		err := json.Unmarshal(event.Data.Raw, &acct)
+		if err != nil {
+			logger.StdErr.Printf("Error parsing webhook JSON: %v\n", err)
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		updateConnectAccountStatus(&acct)
+		logger.StdOut.Printf("Connect account %s updated (charges enabled: %v)\n", acct.ID, acct.ChargesEnabled)
+	} else if event.Type == stripe.EventTypeAccountApplicationDeauthorized {
+		db.UsersCollection.UpdateOne(context.Background(), bson.M{"stripeAccountId": event.Account}, bson.M{"$unset": bson.M{
+			"stripeAccountId":      "",
+			"stripeAccountEnabled": "",
+			"stripePayoutsEnabled": "",
+		}})
+		logger.StdOut.Printf("Connect account %s disconnected\n", event.Account)
 	}
 
 	c.Status(http.StatusOK) // Return 200 OK to acknowledge receipt of the event
@@ -356,6 +419,12 @@ func getBillingPortalUrl(c *gin.Context) {
 		return
 	}
 
+	// Organizations' portals are only for their admins and billing admins
+	if db.GetOrganizationByStripeCustomerId(customerID) != nil {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.BillingCustomerNotOwned})
+		return
+	}
+
 	params := &stripe.BillingPortalSessionParams{
 		Customer:  stripe.String(customerID),
 		ReturnURL: stripe.String(returnURL),
@@ -0,0 +1,189 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stripe/stripe-go/v82"
+	portalsession "github.com/stripe/stripe-go/v82/billingportal/session"
+	"github.com/stripe/stripe-go/v82/checkout/session"
+	"github.com/stripe/stripe-go/v82/invoice"
+	"go.mongodb.org/mongo-driver/bson"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/slackbot"
+	"schej.it/server/utils"
+)
+
+// Returns the organization set by the BillingAdminRequired middleware
+func getBillingOrg(c *gin.Context) *models.Organization {
+	return c.MustGet("billingOrg").(*models.Organization)
+}
+
+// Sets whether the organization billed to the given customer is premium, and
+// returns whether there is such an organization
+func setOrgPremium(customerId string, isPremium bool) bool {
+	result, err := db.OrganizationsCollection.UpdateOne(context.Background(), bson.M{"stripeCustomerId": customerId}, bson.M{"$set": bson.M{"isPremium": isPremium}})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return result.MatchedCount > 0
+}
+
+// @Summary Creates a checkout session for the organization's subscription
+// @Description Only admins and billing admins of the organization can subscribe it
+// @Tags stripe
+// @Accept json
+// @Produce json
+// @Param orgId path string true "Organization ID"
+// @Param payload body object{priceId=string,originUrl=string} true "Price to subscribe to, and the url to return to"
+// @Success 200 {object} object{url=string}
+// @Router /stripe/orgs/{orgId}/create-checkout-session [post]
+func createOrgCheckoutSession(c *gin.Context) {
+	payload := struct {
+		PriceID   string `json:"priceId" binding:"required"`
+		OriginURL string `json:"originUrl" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	org := getBillingOrg(c)
+	user := utils.GetAuthUser(c)
+
+	successURLStr, cancelURLStr, err := getCheckoutRedirectUrls(payload.OriginURL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error configuring redirect"})
+		return
+	}
+
+	params := &stripe.CheckoutSessionParams{
+		ClientReferenceID: stripe.String(user.Id.Hex()),
+		LineItems: []*stripe.CheckoutSessionLineItemParams{
+			{
+				Price:    stripe.String(payload.PriceID),
+				Quantity: stripe.Int64(1),
+			},
+		},
+		Mode:         stripe.String(string(stripe.CheckoutSessionModeSubscription)),
+		SuccessURL:   stripe.String(successURLStr + "&session_id={CHECKOUT_SESSION_ID}"),
+		CancelURL:    stripe.String(cancelURLStr),
+		AutomaticTax: &stripe.CheckoutSessionAutomaticTaxParams{Enabled: stripe.Bool(true)},
+		Metadata:     map[string]string{"orgId": org.Id.Hex()},
+	}
+	if org.StripeCustomerId != nil {
+		params.Customer = org.StripeCustomerId
+	}
+
+	s, err := session.New(params)
+	if err != nil {
+		logger.StdErr.Printf("session.New: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create checkout session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"url": s.URL})
+}
+
+// Saves the customer of a completed organization checkout session on the
+// organization, and upgrades it
+func fulfillOrgCheckout(cs *stripe.CheckoutSession) {
+	if cs.PaymentStatus == stripe.CheckoutSessionPaymentStatusUnpaid || cs.Customer == nil {
+		return
+	}
+
+	org := db.GetOrganizationById(cs.Metadata["orgId"])
+	if org == nil {
+		logger.StdErr.Printf("Organization %s of checkout session %s not found", cs.Metadata["orgId"], cs.ID)
+		return
+	}
+	if org.StripeCustomerId != nil && *org.StripeCustomerId == cs.Customer.ID && utils.Coalesce(org.IsPremium) {
+		return
+	}
+
+	_, err := db.OrganizationsCollection.UpdateOne(context.Background(), bson.M{"_id": org.Id}, bson.M{"$set": bson.M{
+		"stripeCustomerId": cs.Customer.ID,
+		"isPremium":        true,
+	}})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	message := fmt.Sprintf(":moneybag: Organization %s subscribed to Schej :moneybag:", org.Name)
+	slackbot.SendTextMessageWithType(message, slackbot.MONETIZATION)
+}
+
+// @Summary Gets the billing portal url of the organization
+// @Description Only admins and billing admins of the organization can manage its subscription
+// @Tags stripe
+// @Produce json
+// @Param orgId path string true "Organization ID"
+// @Param returnUrl query string false "Url to return to after managing billing"
+// @Success 200 {object} object{url=string}
+// @Router /stripe/orgs/{orgId}/billing-portal [get]
+func getOrgBillingPortalUrl(c *gin.Context) {
+	org := getBillingOrg(c)
+	if org.StripeCustomerId == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization has no subscription"})
+		return
+	}
+
+	returnURL := c.Query("returnUrl")
+	if returnURL == "" {
+		returnURL = utils.GetBaseUrl()
+	}
+
+	ps, err := portalsession.New(&stripe.BillingPortalSessionParams{
+		Customer:  org.StripeCustomerId,
+		ReturnURL: stripe.String(returnURL),
+	})
+	if err != nil {
+		logger.StdErr.Printf("portalsession.New: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create billing portal session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"url": ps.URL})
+}
+
+// @Summary Gets the most recent invoices of the organization
+// @Description Only admins and billing admins of the organization can see its invoices
+// @Tags stripe
+// @Produce json
+// @Param orgId path string true "Organization ID"
+// @Success 200 {object} []object{id=string,number=string,status=string,amountDue=int,amountPaid=int,currency=string,created=int,hostedInvoiceUrl=string,invoicePdf=string}
+// @Router /stripe/orgs/{orgId}/invoices [get]
+func getOrgInvoices(c *gin.Context) {
+	org := getBillingOrg(c)
+
+	invoices := make([]gin.H, 0)
+	if org.StripeCustomerId != nil {
+		params := &stripe.InvoiceListParams{Customer: org.StripeCustomerId}
+		params.Limit = stripe.Int64(24)
+		params.Single = true
+		iter := invoice.List(params)
+		for iter.Next() {
+			inv := iter.Invoice()
+			invoices = append(invoices, gin.H{
+				"id":               inv.ID,
+				"number":           inv.Number,
+				"status":           inv.Status,
+				"amountDue":        inv.AmountDue,
+				"amountPaid":       inv.AmountPaid,
+				"currency":         inv.Currency,
+				"created":          inv.Created,
+				"hostedInvoiceUrl": inv.HostedInvoiceURL,
+				"invoicePdf":       inv.InvoicePDF,
+			})
+		}
+		if err := iter.Err(); err != nil {
+			logger.StdErr.Printf("invoice.List: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invoices"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, invoices)
+}
@@ -0,0 +1,192 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stripe/stripe-go/v82"
+	"github.com/stripe/stripe-go/v82/account"
+	"github.com/stripe/stripe-go/v82/accountlink"
+	"github.com/stripe/stripe-go/v82/loginlink"
+	"github.com/stripe/stripe-go/v82/payout"
+	"go.mongodb.org/mongo-driver/bson"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services/payments"
+	"schej.it/server/utils"
+)
+
+// Saves whether the connect account can accept payments and receive payouts
+// on the user it belongs to
+func updateConnectAccountStatus(acct *stripe.Account) {
+	_, err := db.UsersCollection.UpdateOne(context.Background(), bson.M{"stripeAccountId": acct.ID}, bson.M{"$set": bson.M{
+		"stripeAccountEnabled": acct.ChargesEnabled,
+		"stripePayoutsEnabled": acct.PayoutsEnabled,
+	}})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// @Summary Starts Stripe Connect onboarding for the current user
+// @Description Creates a Stripe Connect account for the user if they don't have one, and returns the onboarding link. Once onboarding is complete, the user can require payment for sign up slots
+// @Tags stripe
+// @Produce json
+// @Param returnUrl query string false "Url to return to after onboarding"
+// @Success 200 {object} object{url=string}
+// @Router /stripe/connect/onboard [post]
+func onboardConnectAccount(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	returnURL := c.Query("returnUrl")
+	if returnURL == "" {
+		returnURL = utils.GetBaseUrl()
+	}
+
+	if user.StripeAccountId == nil {
+		acct, err := account.New(&stripe.AccountParams{
+			Type:  stripe.String(string(stripe.AccountTypeExpress)),
+			Email: stripe.String(user.Email),
+		})
+		if err != nil {
+			logger.StdErr.Printf("account.New: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create connect account"})
+			return
+		}
+		user.StripeAccountId = &acct.ID
+		db.UsersCollection.UpdateOne(context.Background(), bson.M{"_id": user.Id}, bson.M{"$set": bson.M{"stripeAccountId": acct.ID}})
+	}
+
+	link, err := accountlink.New(&stripe.AccountLinkParams{
+		Account:    user.StripeAccountId,
+		RefreshURL: stripe.String(returnURL),
+		ReturnURL:  stripe.String(returnURL),
+		Type:       stripe.String("account_onboarding"),
+	})
+	if err != nil {
+		logger.StdErr.Printf("accountlink.New: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create onboarding link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"url": link.URL})
+}
+
+// @Summary Gets the status of the current user's connect account
+// @Description Also refreshes the account status saved on the user, in case a webhook was missed
+// @Tags stripe
+// @Produce json
+// @Success 200 {object} object{connected=bool,chargesEnabled=bool,payoutsEnabled=bool,detailsSubmitted=bool,requirementsDue=[]string,disabledReason=string,platformFee=object{percent=float64,fixed=int}}
+// @Router /stripe/connect/status [get]
+func getConnectStatus(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	percent, fixed := payments.GetPlatformFee()
+	status := gin.H{
+		"connected":   user.StripeAccountId != nil,
+		"platformFee": gin.H{"percent": percent, "fixed": fixed},
+	}
+	if user.StripeAccountId == nil {
+		c.JSON(http.StatusOK, status)
+		return
+	}
+
+	acct, err := account.GetByID(*user.StripeAccountId, nil)
+	if err != nil {
+		logger.StdErr.Printf("account.GetByID: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get connect account"})
+		return
+	}
+	updateConnectAccountStatus(acct)
+
+	status["chargesEnabled"] = acct.ChargesEnabled
+	status["payoutsEnabled"] = acct.PayoutsEnabled
+	status["detailsSubmitted"] = acct.DetailsSubmitted
+	if acct.Requirements != nil {
+		status["requirementsDue"] = acct.Requirements.CurrentlyDue
+		status["disabledReason"] = acct.Requirements.DisabledReason
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// @Summary Gets a link to the Stripe Express dashboard of the current user's connect account
+// @Tags stripe
+// @Produce json
+// @Success 200 {object} object{url=string}
+// @Router /stripe/connect/dashboard [post]
+func getConnectDashboardUrl(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	if user.StripeAccountId == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No connect account"})
+		return
+	}
+
+	link, err := loginlink.New(&stripe.LoginLinkParams{Account: user.StripeAccountId})
+	if err != nil {
+		logger.StdErr.Printf("loginlink.New: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create dashboard link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"url": link.URL})
+}
+
+// @Summary Gets the payments and payouts of the current user's connect account
+// @Description Totals are grouped by currency, in the smallest unit of the currency
+// @Tags stripe
+// @Produce json
+// @Success 200 {object} object{accountEnabled=bool,totals=map[string]object{paid=int,refunded=int,fees=int},payments=[]models.Payment,payouts=[]object{id=string,amount=int,currency=string,status=string,arrivalDate=int}}
+// @Router /stripe/connect/payouts [get]
+func getConnectPayouts(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	userPayments := db.GetPaymentsByOwnerId(user.Id)
+
+	type total struct {
+		Paid     int64 `json:"paid"`
+		Refunded int64 `json:"refunded"`
+		Fees     int64 `json:"fees"`
+	}
+	totals := make(map[string]*total)
+	for _, payment := range userPayments {
+		if totals[payment.Currency] == nil {
+			totals[payment.Currency] = &total{}
+		}
+		if payment.Status == models.PAYMENT_FAILED {
+			continue
+		}
+		totals[payment.Currency].Paid += payment.Amount
+		totals[payment.Currency].Refunded += payment.RefundedAmount
+		if payment.Status != models.PAYMENT_REFUNDED {
+			totals[payment.Currency].Fees += payment.ApplicationFee
+		}
+	}
+
+	payouts := make([]gin.H, 0)
+	if user.StripeAccountId != nil {
+		params := &stripe.PayoutListParams{}
+		params.SetStripeAccount(*user.StripeAccountId)
+		params.Limit = stripe.Int64(20)
+		params.Single = true
+		iter := payout.List(params)
+		for iter.Next() {
+			p := iter.Payout()
+			payouts = append(payouts, gin.H{
+				"id":          p.ID,
+				"amount":      p.Amount,
+				"currency":    p.Currency,
+				"status":      p.Status,
+				"arrivalDate": p.ArrivalDate,
+			})
+		}
+		if err := iter.Err(); err != nil {
+			logger.StdErr.Printf("payout.List: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"accountEnabled": utils.Coalesce(user.StripeAccountEnabled),
+		"totals":         totals,
+		"payments":       userPayments,
+		"payouts":        payouts,
+	})
+}
@@ -0,0 +1,49 @@
+/* The /telemetry group collects the anonymous reports of self-hosted instances that opted in */
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/telemetry"
+)
+
+func InitTelemetry(router *gin.RouterGroup) {
+	router.POST("/telemetry", collectTelemetryReport)
+}
+
+// @Summary Collects a telemetry report from a self-hosted instance
+// @Description Only enabled on instances with TELEMETRY_COLLECTOR_ENABLED=true, i.e. the maintainers'. Each instance's report replaces its earlier report for the same day
+// @Tags telemetry
+// @Accept json
+// @Param payload body models.TelemetryReport true "The report"
+// @Success 204
+// @Router /telemetry [post]
+func collectTelemetryReport(c *gin.Context) {
+	if !telemetry.CollectorEnabled() {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	var report models.TelemetryReport
+	if err := c.BindJSON(&report); err != nil {
+		return
+	}
+	if err := telemetry.Validate(&report); err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	now := time.Now()
+	report.Id = primitive.NilObjectID
+	report.Date = now.UTC().Format("2006-01-02")
+	report.ReceivedAt = primitive.NewDateTimeFromTime(now)
+	db.UpsertTelemetryReport(&report)
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,488 @@
+/* The /terms group contains all the routes for academic terms, where students' availability is collected once and used to assign them to class sections */
+package routes
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/middleware"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/services/sections"
+	"schej.it/server/utils"
+)
+
+func InitTerms(router *gin.RouterGroup) {
+	termRouter := router.Group("/terms")
+
+	termRouter.GET("", middleware.AuthRequired(), getTerms)
+	termRouter.POST("", middleware.AuthRequired(), createTerm)
+	termRouter.GET("/:termId", middleware.AuthRequired(), getTerm)
+	termRouter.PUT("/:termId", middleware.AuthRequired(), editTerm)
+	termRouter.DELETE("/:termId", middleware.AuthRequired(), deleteTerm)
+	termRouter.POST("/:termId/assign", middleware.AuthRequired(), assignSections)
+	termRouter.GET("/:termId/assignments", middleware.AuthRequired(), getSectionAssignments)
+	termRouter.GET("/:termId/assignments/:studentId", getStudentAssignment)
+	termRouter.GET("/:termId/export", middleware.AuthRequired(), exportSectionAssignments)
+}
+
+// Payload used to create and edit terms
+type termPayload struct {
+	Name      string             `json:"name" binding:"required"`
+	StartDate primitive.DateTime `json:"startDate" binding:"required"`
+	EndDate   primitive.DateTime `json:"endDate" binding:"required"`
+	Timezone  string             `json:"timezone" binding:"required"`
+	Sections  []models.Section   `json:"sections" binding:"required"`
+}
+
+// Validates the payload, returning the location of its timezone
+func (payload *termPayload) validate() (*time.Location, error) {
+	if payload.EndDate < payload.StartDate {
+		return nil, fmt.Errorf("endDate must not be before startDate")
+	}
+	loc, err := time.LoadLocation(payload.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone")
+	}
+	if len(payload.Sections) == 0 {
+		return nil, fmt.Errorf("at least one section is required")
+	}
+
+	ids := make(models.Set[string])
+	for i := range payload.Sections {
+		section := &payload.Sections[i]
+		if len(section.Id) == 0 {
+			section.Id = primitive.NewObjectID().Hex()
+		}
+		if _, ok := ids[section.Id]; ok {
+			return nil, fmt.Errorf("duplicate section id %s", section.Id)
+		}
+		ids[section.Id] = struct{}{}
+
+		if len(section.Meetings) == 0 {
+			return nil, fmt.Errorf("section %s has no meetings", section.Name)
+		}
+		for _, meeting := range section.Meetings {
+			if meeting.DayOfWeek < 0 || meeting.DayOfWeek > 6 || meeting.StartTime < 0 || meeting.EndTime > 24 || meeting.StartTime >= meeting.EndTime {
+				return nil, fmt.Errorf("section %s has an invalid meeting", section.Name)
+			}
+			// Meetings must line up with the availability grid
+			if !isQuarterHour(meeting.StartTime) || !isQuarterHour(meeting.EndTime) {
+				return nil, fmt.Errorf("section %s meetings must start and end on the quarter hour", section.Name)
+			}
+		}
+	}
+
+	return loc, nil
+}
+
+func isQuarterHour(hours float32) bool {
+	quarters := float64(hours) * 4
+	return quarters == math.Trunc(quarters)
+}
+
+// Returns the dates and duration of the days of the week event needed to
+// collect availability for all the sections' meetings
+func getTermEventDates(sectionsList []models.Section, loc *time.Location) ([]primitive.DateTime, float32) {
+	days := make(models.Set[int])
+	minStart := float32(24)
+	maxEnd := float32(0)
+	for _, section := range sectionsList {
+		for _, meeting := range section.Meetings {
+			days[meeting.DayOfWeek] = struct{}{}
+			if meeting.StartTime < minStart {
+				minStart = meeting.StartTime
+			}
+			if meeting.EndTime > maxEnd {
+				maxEnd = meeting.EndTime
+			}
+		}
+	}
+
+	dates := make([]primitive.DateTime, 0)
+	for day := 0; day < 7; day++ {
+		if _, ok := days[day]; ok {
+			start, _ := sections.GetDowTimes(models.WeeklySlot{DayOfWeek: day, StartTime: minStart, EndTime: maxEnd}, loc)
+			dates = append(dates, primitive.NewDateTimeFromTime(start))
+		}
+	}
+	return dates, maxEnd - minStart
+}
+
+// @Summary Gets all the terms of the current user
+// @Tags terms
+// @Produce json
+// @Success 200 {object} []models.Term
+// @Router /terms [get]
+func getTerms(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	c.JSON(http.StatusOK, db.GetTermsByOwnerId(user.Id))
+}
+
+// @Summary Creates a new term
+// @Description Also creates the days of the week event used to collect students' availability for the sections' meetings
+// @Tags terms
+// @Accept json
+// @Produce json
+// @Param payload body object{name=string,startDate=string,endDate=string,timezone=string,sections=[]models.Section} true "Term details, sections' meeting times are in the given timezone"
+// @Success 201 {object} object{termId=string,eventId=string}
+// @Router /terms [post]
+func createTerm(c *gin.Context) {
+	payload := termPayload{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	loc, err := payload.validate()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	user := utils.GetAuthUser(c)
+
+	// Create the event used to collect students' availability
+	dates, duration := getTermEventDates(payload.Sections, loc)
+	event := models.Event{
+		OwnerId:       user.Id,
+		Name:          payload.Name,
+		Type:          models.DOW,
+		Dates:         dates,
+		Duration:      &duration,
+		CollectEmails: utils.TruePtr(),
+	}
+	db.InsertEvent(&event)
+
+	term := models.Term{
+		OwnerId:   user.Id,
+		Name:      payload.Name,
+		StartDate: payload.StartDate,
+		EndDate:   payload.EndDate,
+		Timezone:  payload.Timezone,
+		EventId:   event.Id,
+		Sections:  payload.Sections,
+	}
+	db.InsertTerm(&term)
+
+	c.JSON(http.StatusCreated, gin.H{"termId": term.Id.Hex(), "eventId": event.GetId()})
+}
+
+// Returns the term in the termId param if it's owned by the current user,
+// otherwise responds with an error and returns nil
+func getOwnedTerm(c *gin.Context) *models.Term {
+	term := db.GetTermById(c.Param("termId"))
+	if term == nil || term.OwnerId != utils.GetAuthUser(c).Id {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.TermNotFound})
+		return nil
+	}
+	return term
+}
+
+// @Summary Gets a term
+// @Tags terms
+// @Produce json
+// @Param termId path string true "Term ID"
+// @Success 200 {object} object{term=models.Term,event=models.Event}
+// @Router /terms/{termId} [get]
+func getTerm(c *gin.Context) {
+	term := getOwnedTerm(c)
+	if term == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"term":  term,
+		"event": db.GetEventById(term.EventId.Hex()),
+	})
+}
+
+// @Summary Edits a term
+// @Description Updates the availability event to cover the new sections' meetings. Existing section assignments are kept for sections that still exist
+// @Tags terms
+// @Accept json
+// @Produce json
+// @Param termId path string true "Term ID"
+// @Param payload body object{name=string,startDate=string,endDate=string,timezone=string,sections=[]models.Section} true "Term details"
+// @Success 200
+// @Router /terms/{termId} [put]
+func editTerm(c *gin.Context) {
+	payload := termPayload{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	loc, err := payload.validate()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+
+	term := getOwnedTerm(c)
+	if term == nil {
+		return
+	}
+
+	term.Name = payload.Name
+	term.StartDate = payload.StartDate
+	term.EndDate = payload.EndDate
+	term.Timezone = payload.Timezone
+	term.Sections = payload.Sections
+
+	// Remove assignments to deleted sections
+	sectionIds := make(models.Set[string])
+	for _, section := range term.Sections {
+		sectionIds[section.Id] = struct{}{}
+	}
+	for studentId, sectionId := range term.Assignments {
+		if _, ok := sectionIds[sectionId]; !ok {
+			delete(term.Assignments, studentId)
+		}
+	}
+	db.UpdateTerm(term)
+
+	dates, duration := getTermEventDates(term.Sections, loc)
+	_, err = db.EventsCollection.UpdateByID(context.Background(), term.EventId, bson.M{
+		"$set": bson.M{"name": term.Name, "dates": dates, "duration": duration},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Deletes a term
+// @Tags terms
+// @Param termId path string true "Term ID"
+// @Success 200
+// @Router /terms/{termId} [delete]
+func deleteTerm(c *gin.Context) {
+	term := getOwnedTerm(c)
+	if term == nil {
+		return
+	}
+
+	term.IsDeleted = utils.TruePtr()
+	db.UpdateTerm(term)
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Assigns students to sections
+// @Description Assigns as many students as possible to a section that works for them, respecting section capacities and preferring sections students are available for over "if needed" sections. Replaces any previous assignments
+// @Tags terms
+// @Accept json
+// @Produce json
+// @Param termId path string true "Term ID"
+// @Param payload body object{allowConflicts=bool} false "Whether students can be assigned to sections they're unavailable for when no other section has room"
+// @Success 200 {object} sections.Result
+// @Router /terms/{termId}/assign [post]
+func assignSections(c *gin.Context) {
+	payload := struct {
+		AllowConflicts bool `json:"allowConflicts"`
+	}{}
+	if c.Request.ContentLength > 0 {
+		if err := c.BindJSON(&payload); err != nil {
+			return
+		}
+	}
+
+	term := getOwnedTerm(c)
+	if term == nil {
+		return
+	}
+	event := db.GetEventById(term.EventId.Hex())
+	if event == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.TermNotFound})
+		return
+	}
+
+	students := getTermStudents(term, event)
+	sectionsList := make([]sections.Section, 0)
+	for _, section := range term.Sections {
+		sectionsList = append(sectionsList, sections.Section{Id: section.Id, Capacity: section.Capacity})
+	}
+	result := sections.Assign(students, sectionsList, payload.AllowConflicts)
+
+	term.Assignments = make(map[string]string)
+	for _, assignment := range result.Assignments {
+		term.Assignments[assignment.StudentId] = assignment.SectionId
+	}
+	now := primitive.NewDateTimeFromTime(time.Now())
+	term.AssignedAt = &now
+	db.UpdateTerm(term)
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Returns the term's students with their preference for each section
+func getTermStudents(term *models.Term, event *models.Event) []sections.Student {
+	loc, err := time.LoadLocation(term.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	increment := scheduling.GetTimeIncrement(event)
+
+	students := make([]sections.Student, 0)
+	for _, respondent := range scheduling.GetRespondents(db.GetEventResponses(event.Id.Hex())) {
+		student := sections.Student{Id: respondent.Id, Preferences: make(map[string]sections.Preference)}
+		for _, section := range term.Sections {
+			preference := sections.Available
+			for _, meeting := range section.Meetings {
+				start, end := sections.GetDowTimes(meeting, loc)
+				if respondent.IsAvailable(start, end, increment, false) {
+					continue
+				}
+				if respondent.IsAvailable(start, end, increment, true) {
+					preference = sections.IfNeeded
+					continue
+				}
+				preference = sections.Unavailable
+				break
+			}
+			student.Preferences[section.Id] = preference
+		}
+		students = append(students, student)
+	}
+
+	return students
+}
+
+// A student's section assignment
+type sectionAssignment struct {
+	StudentId string          `json:"studentId"`
+	Name      string          `json:"name"`
+	Email     string          `json:"email,omitempty"`
+	Section   *models.Section `json:"section"`
+}
+
+// Returns the assignments of all the term's students. Students without a
+// section are included with a nil section
+func getTermAssignments(term *models.Term) []sectionAssignment {
+	sectionsById := make(map[string]*models.Section)
+	for i := range term.Sections {
+		sectionsById[term.Sections[i].Id] = &term.Sections[i]
+	}
+
+	assignments := make([]sectionAssignment, 0)
+	for _, respondent := range scheduling.GetRespondents(db.GetEventResponses(term.EventId.Hex())) {
+		assignments = append(assignments, sectionAssignment{
+			StudentId: respondent.Id,
+			Name:      respondent.Name,
+			Email:     respondent.Email,
+			Section:   sectionsById[term.Assignments[respondent.Id]],
+		})
+	}
+	sort.SliceStable(assignments, func(i, j int) bool {
+		return strings.ToLower(assignments[i].Name) < strings.ToLower(assignments[j].Name)
+	})
+
+	return assignments
+}
+
+// @Summary Gets the section assignments of all students in the term
+// @Tags terms
+// @Produce json
+// @Param termId path string true "Term ID"
+// @Success 200 {object} []sectionAssignment
+// @Router /terms/{termId}/assignments [get]
+func getSectionAssignments(c *gin.Context) {
+	term := getOwnedTerm(c)
+	if term == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, getTermAssignments(term))
+}
+
+// @Summary Gets the section a student was assigned to
+// @Tags terms
+// @Produce json
+// @Param termId path string true "Term ID"
+// @Param studentId path string true "Student ID (user id, or name for guests)"
+// @Success 200 {object} object{termName=string,timezone=string,startDate=string,endDate=string,section=models.Section}
+// @Router /terms/{termId}/assignments/{studentId} [get]
+func getStudentAssignment(c *gin.Context) {
+	term := db.GetTermById(c.Param("termId"))
+	if term == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.TermNotFound})
+		return
+	}
+
+	var section *models.Section
+	if sectionId, ok := term.Assignments[c.Param("studentId")]; ok {
+		for i := range term.Sections {
+			if term.Sections[i].Id == sectionId {
+				section = &term.Sections[i]
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"termName":  term.Name,
+		"timezone":  term.Timezone,
+		"startDate": term.StartDate,
+		"endDate":   term.EndDate,
+		"section":   section,
+	})
+}
+
+// @Summary Exports the section assignments of all students in the term
+// @Tags terms
+// @Produce text/csv
+// @Produce json
+// @Param termId path string true "Term ID"
+// @Param format query string false "csv (default) or json"
+// @Success 200
+// @Router /terms/{termId}/export [get]
+func exportSectionAssignments(c *gin.Context) {
+	term := getOwnedTerm(c)
+	if term == nil {
+		return
+	}
+
+	assignments := getTermAssignments(term)
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, assignments)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", term.Name+" sections.csv"))
+	c.Header("Content-Type", "text/csv")
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"Name", "Email", "Section", "Meetings"})
+	for _, assignment := range assignments {
+		sectionName, meetings := "", ""
+		if assignment.Section != nil {
+			sectionName = assignment.Section.Name
+			meetings = formatMeetings(assignment.Section.Meetings)
+		}
+		w.Write([]string{assignment.Name, assignment.Email, sectionName, meetings})
+	}
+	w.Flush()
+}
+
+// Formats the meetings, e.g. "Mon 9:00-10:30, Wed 9:00-10:30"
+func formatMeetings(meetings []models.WeeklySlot) string {
+	formatted := make([]string, 0)
+	for _, meeting := range meetings {
+		formatted = append(formatted, fmt.Sprintf("%s %s-%s", time.Weekday(meeting.DayOfWeek).String()[:3], formatHours(meeting.StartTime), formatHours(meeting.EndTime)))
+	}
+	return strings.Join(formatted, ", ")
+}
+
+func formatHours(hours float32) string {
+	minutes := int(math.Round(float64(hours) * 60))
+	return fmt.Sprintf("%d:%02d", minutes/60, minutes%60)
+}
@@ -0,0 +1,90 @@
+/* The /unsubscribe group handles RFC 8058 one-click unsubscribes from the List-Unsubscribe header of bulk emails, which add the address to the suppression list */
+package routes
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/notifications"
+)
+
+func InitUnsubscribe(router *gin.RouterGroup) {
+	unsubscribeRouter := router.Group("/unsubscribe")
+
+	unsubscribeRouter.GET("", getUnsubscribe)
+	unsubscribeRouter.POST("", unsubscribe)
+}
+
+type unsubscribeQuery struct {
+	Email string `form:"email" binding:"required"`
+	Token string `form:"token" binding:"required"`
+}
+
+// Returns the email and token of the unsubscribe link, or responds with an
+// error and returns nil if the token doesn't authorize the email
+func getUnsubscribeQuery(c *gin.Context) *unsubscribeQuery {
+	var query unsubscribeQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return nil
+	}
+	if !notifications.VerifyUnsubscribeToken(query.Email, query.Token) {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.InvalidOptOutToken})
+		return nil
+	}
+	return &query
+}
+
+// @Summary Shows a page confirming the unsubscribe
+// @Description Opened from the unsubscribe link. Doesn't unsubscribe by itself, since link scanners open links too, but asks to confirm with a POST
+// @Tags unsubscribe
+// @Produce html
+// @Param email query string true "Email to unsubscribe"
+// @Param token query string true "Token from the unsubscribe link"
+// @Success 200
+// @Router /unsubscribe [get]
+func getUnsubscribe(c *gin.Context) {
+	query := getUnsubscribeQuery(c)
+	if query == nil {
+		return
+	}
+
+	values := url.Values{}
+	values.Set("email", query.Email)
+	values.Set("token", query.Token)
+	page := fmt.Sprintf(
+		`<!DOCTYPE html><html><head><meta charset="utf-8"><meta name="viewport" content="width=device-width"><title>Unsubscribe</title></head>`+
+			`<body style="font-family: sans-serif; max-width: 32rem; margin: 4rem auto; padding: 0 1rem">`+
+			`<p>Stop sending reminders, messages, and invites from Timeful to %s?</p>`+
+			`<form method="post" action="?%s"><button type="submit">Unsubscribe</button></form></body></html>`,
+		html.EscapeString(query.Email), html.EscapeString(values.Encode()),
+	)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+}
+
+// @Summary Unsubscribes the email from bulk emails
+// @Description RFC 8058 one-click unsubscribe, posted to by mail clients from the List-Unsubscribe header with a List-Unsubscribe=One-Click body, or by the confirmation page. Emails that aren't bulk (e.g. sign in links) are still sent
+// @Tags unsubscribe
+// @Accept x-www-form-urlencoded
+// @Produce plain
+// @Param email query string true "Email to unsubscribe"
+// @Param token query string true "Token from the unsubscribe link"
+// @Success 200
+// @Router /unsubscribe [post]
+func unsubscribe(c *gin.Context) {
+	query := getUnsubscribeQuery(c)
+	if query == nil {
+		return
+	}
+
+	db.SuppressEmail(query.Email, models.SUPPRESSION_UNSUBSCRIBED)
+
+	c.String(http.StatusOK, fmt.Sprintf("%s won't receive any more reminders, messages, or invites from Timeful.", query.Email))
+}
@@ -19,19 +19,26 @@ import (
 	"schej.it/server/responses"
 	"schej.it/server/services/auth"
 	"schej.it/server/services/calendar"
+	"schej.it/server/services/classroom"
 	"schej.it/server/services/contacts"
 	"schej.it/server/services/microsoftgraph"
+	"schej.it/server/services/sessionstore"
 	"schej.it/server/utils"
 )
 
 func InitUser(router *gin.RouterGroup) {
 	userRouter := router.Group("/user")
+
+	// Polled by calendar apps, which authenticate with the token in the URL
+	userRouter.GET("/calendar.ics", getCalendarFeedIcs)
+
 	userRouter.Use(middleware.AuthRequired())
 
 	userRouter.GET("/profile", getProfile)
 	userRouter.PATCH("/name", updateName)
 	userRouter.PATCH("/calendar-options", updateCalendarOptions)
 	userRouter.GET("/events", getEvents)
+	userRouter.GET("/drafts", getDrafts)
 	userRouter.POST("/events/:eventId/set-folder", setEventFolder)
 	userRouter.GET("/calendars", getCalendars)
 	userRouter.POST("/add-google-calendar-account", addGoogleCalendarAccount)
@@ -41,7 +48,52 @@ func InitUser(router *gin.RouterGroup) {
 	userRouter.POST("/toggle-calendar", toggleCalendar)
 	userRouter.POST("/toggle-sub-calendar", toggleSubCalendar)
 	userRouter.GET("/searchContacts", searchContacts)
-	userRouter.DELETE("", deleteUser)
+	userRouter.GET("/classroom/courses", getClassroomCourses)
+	userRouter.GET("/contacts", getContacts)
+	userRouter.POST("/contacts", addContact)
+	userRouter.DELETE("/contacts/:contactId", deleteContact)
+	userRouter.GET("/contact-groups", getContactGroups)
+	userRouter.POST("/contact-groups", createContactGroup)
+	userRouter.GET("/contact-groups/:groupId", getContactGroupById)
+	userRouter.PUT("/contact-groups/:groupId", updateContactGroup)
+	userRouter.DELETE("/contact-groups/:groupId", deleteContactGroup)
+	userRouter.POST("/contact-groups/:groupId/members", addContactGroupMembers)
+	userRouter.DELETE("/contact-groups/:groupId/members/:email", removeContactGroupMember)
+	userRouter.GET("/contact-groups/:groupId/insights", getContactGroupInsights)
+	userRouter.GET("/insights", middleware.PremiumRequired(), getOrganizerInsights)
+	userRouter.GET("/find-time-settings", getFindTimeSettings)
+	userRouter.PUT("/find-time-settings", updateFindTimeSettings)
+	userRouter.GET("/find-time/:userId", findMutualFreeTime)
+	userRouter.GET("/webhooks", middleware.SessionRequired(), getWebhooks)
+	userRouter.POST("/webhooks", middleware.SessionRequired(), createWebhook)
+	userRouter.PATCH("/webhooks/:webhookId", middleware.SessionRequired(), updateWebhook)
+	userRouter.DELETE("/webhooks/:webhookId", middleware.SessionRequired(), deleteWebhook)
+	userRouter.GET("/webhooks/:webhookId/deliveries", middleware.SessionRequired(), getWebhookDeliveries)
+	userRouter.POST("/webhooks/:webhookId/deliveries/:deliveryId/replay", middleware.SessionRequired(), replayWebhookDelivery)
+	userRouter.GET("/api-keys", middleware.SessionRequired(), getApiKeys)
+	userRouter.POST("/api-keys", middleware.SessionRequired(), createApiKey)
+	userRouter.DELETE("/api-keys/:apiKeyId", middleware.SessionRequired(), deleteApiKey)
+	userRouter.GET("/usage", getUsage)
+	userRouter.GET("/sessions", middleware.SessionRequired(), getUserSessions)
+	userRouter.DELETE("/sessions", middleware.SessionRequired(), revokeOtherUserSessions)
+	userRouter.DELETE("/sessions/:sessionId", middleware.SessionRequired(), revokeUserSession)
+	userRouter.GET("/identities", middleware.SessionRequired(), getIdentities)
+	userRouter.POST("/identities/merge", middleware.SessionRequired(), mergeIdentityAccount)
+	userRouter.POST("/identities/:provider", middleware.SessionRequired(), linkIdentity)
+	userRouter.DELETE("/identities/:provider/:subject", middleware.SessionRequired(), unlinkIdentity)
+	userRouter.POST("/merge", middleware.SessionRequired(), requestAccountMerge)
+	userRouter.POST("/merge/:mergeId/confirm", middleware.SessionRequired(), confirmAccountMerge)
+	userRouter.GET("/delegations", middleware.SessionRequired(), getDelegations)
+	userRouter.POST("/delegations", middleware.SessionRequired(), grantDelegation)
+	userRouter.DELETE("/delegations/:delegationId", middleware.SessionRequired(), revokeDelegation)
+	userRouter.GET("/delegations/:delegationId/audit", middleware.SessionRequired(), getDelegationAudit)
+	userRouter.GET("/delegations/:delegationId/busy", middleware.SessionRequired(), getDelegatedBusyTimes)
+	userRouter.GET("/notion", getNotionConnection)
+	userRouter.PUT("/notion", middleware.SessionRequired(), connectNotion)
+	userRouter.DELETE("/notion", middleware.SessionRequired(), disconnectNotion)
+	userRouter.GET("/calendar-feed", middleware.SessionRequired(), getCalendarFeed)
+	userRouter.POST("/calendar-feed/reset", middleware.SessionRequired(), resetCalendarFeed)
+	userRouter.DELETE("", middleware.SessionRequired(), deleteUser)
 }
 
 // @Summary Gets the user's profile
@@ -143,7 +195,7 @@ func updateCalendarOptions(c *gin.Context) {
 }
 
 // @Summary Gets all the user's events
-// @Description Returns an array containing all the user's events
+// @Description Returns an array containing all the user's events. Drafts are listed separately by /user/drafts
 // @Tags user
 // @Produce json
 // @Success 200 {object} []models.Event
@@ -206,6 +258,7 @@ func getEvents(c *gin.Context) {
 						bson.M{"isDeleted": false},
 					},
 				},
+				bson.M{"isDraft": bson.M{"$ne": true}},
 			},
 		},
 		opts,
@@ -231,7 +284,19 @@ func getEvents(c *gin.Context) {
 	c.JSON(http.StatusOK, events)
 }
 
+// @Summary Gets the user's drafts
+// @Description Drafts are events that haven't been published yet, most recently created first
+// @Tags user
+// @Produce json
+// @Success 200 {object} []models.Event
+// @Router /user/drafts [get]
+func getDrafts(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	c.JSON(http.StatusOK, db.GetDraftEvents(user.Id))
+}
+
 // @Summary Sets the folder for the specified event
+// @Description The folder can be one of the user's, or one of their organization's. If the user owns the event, the folder's default co-organizers become co-organizers of it
 // @Tags user
 // @Accept json
 // @Produce json
@@ -254,22 +319,21 @@ func setEventFolder(c *gin.Context) {
 		return
 	}
 
-	session := sessions.Default(c)
-	userIdString := session.Get("userId").(string)
+	userIdString, _ := utils.GetUserId(c)
 	userId, err := primitive.ObjectIDFromHex(userIdString)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
+	var folder *models.Folder
 	var folderId *primitive.ObjectID
 	if body.FolderId != nil {
-		id, err := primitive.ObjectIDFromHex(*body.FolderId)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		folder = getAccessibleFolder(c, *body.FolderId, false)
+		if folder == nil {
 			return
 		}
-		folderId = &id
+		folderId = &folder.Id
 	}
 
 	err = db.SetEventFolder(eventId, folderId, userId)
@@ -278,6 +342,12 @@ func setEventFolder(c *gin.Context) {
 		return
 	}
 
+	if folder != nil && len(folder.DefaultCoOrganizers) > 0 {
+		if event := db.GetEventById(eventId.Hex()); event != nil && event.OwnerId == userId {
+			addDefaultCoOrganizers(event, folder, utils.GetAuthUser(c))
+		}
+	}
+
 	c.Status(http.StatusOK)
 }
 
@@ -653,6 +723,24 @@ func searchContacts(c *gin.Context) {
 	c.JSON(http.StatusOK, contacts)
 }
 
+// @Summary Gets the active Google Classroom courses the user teaches
+// @Description Uses the user's primary Google account, which needs the classroom.courses.readonly and classroom.rosters.readonly scopes
+// @Tags user
+// @Produce json
+// @Success 200 {object} []classroom.Course
+// @Router /user/classroom/courses [get]
+func getClassroomCourses(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+
+	courses, googleError := classroom.GetCourses(user)
+	if googleError != nil {
+		c.JSON(googleError.Code, responses.Error{Error: *googleError})
+		return
+	}
+
+	c.JSON(http.StatusOK, courses)
+}
+
 // @Summary Deletes the currently signed in user
 // @Tags user
 // @Produce json
@@ -667,9 +755,13 @@ func deleteUser(c *gin.Context) {
 		logger.StdErr.Panicln(err)
 	}
 
-	// Delete session
+	// Delete sessions, so they can't be used again
+	if err := sessionstore.Default.RevokeAll(user.Id.Hex(), ""); err != nil {
+		logger.StdErr.Panicln(err)
+	}
 	session := sessions.Default(c)
-	session.Delete("userId")
+	session.Clear()
+	session.Options(sessions.Options{Path: "/", MaxAge: -1})
 	session.Save()
 
 	c.JSON(http.StatusOK, gin.H{})
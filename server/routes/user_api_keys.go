@@ -0,0 +1,73 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/apikeys"
+	"schej.it/server/utils"
+)
+
+// @Summary Gets the current user's API keys
+// @Tags user
+// @Produce json
+// @Success 200 {object} []models.ApiKey
+// @Router /user/api-keys [get]
+func getApiKeys(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	c.JSON(http.StatusOK, db.GetApiKeysByOwner(user.Id))
+}
+
+// @Summary Creates an API key
+// @Description Requests with an "Authorization: Bearer <key>" header act as the current user, e.g. from the timeful CLI. Keys can do everything the user can, except manage API keys, sessions, identities and merges, delete the account, or use admin routes, which need a signed in session. The key isn't shown again
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param payload body object{name=string} true "Name to remember the key by"
+// @Success 201 {object} object{apiKey=models.ApiKey,key=string}
+// @Router /user/api-keys [post]
+func createApiKey(c *gin.Context) {
+	payload := struct {
+		Name string `json:"name" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	key := apikeys.NewKey()
+	apiKey := models.ApiKey{
+		OwnerId:   user.Id,
+		Name:      strings.TrimSpace(payload.Name),
+		Hint:      apikeys.GetHint(key),
+		KeyHash:   apikeys.Hash(key),
+		CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+	}
+	db.InsertApiKey(&apiKey)
+
+	c.JSON(http.StatusCreated, gin.H{"apiKey": apiKey, "key": key})
+}
+
+// @Summary Deletes an API key
+// @Tags user
+// @Param apiKeyId path string true "API key ID"
+// @Success 200
+// @Router /user/api-keys/{apiKeyId} [delete]
+func deleteApiKey(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	apiKeyId, err := primitive.ObjectIDFromHex(c.Param("apiKeyId"))
+	if err != nil || !db.DeleteApiKey(user.Id, apiKeyId.Hex()) {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.ApiKeyNotFound})
+		return
+	}
+	db.DeleteApiKeyUsage(apiKeyId)
+
+	c.Status(http.StatusOK)
+}
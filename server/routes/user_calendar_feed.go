@@ -0,0 +1,97 @@
+package routes
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services/ics"
+	"schej.it/server/utils"
+)
+
+// How far back finalized events stay in the calendar feed
+const calendarFeedHistory = 30 * 24 * time.Hour
+
+func getCalendarFeedUrl(token string) string {
+	return fmt.Sprintf("%s/api/user/calendar.ics?token=%s", utils.GetBaseUrl(), token)
+}
+
+func setCalendarFeedToken(user *models.User) {
+	tokenBytes := make([]byte, 24)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	user.CalendarFeedToken = hex.EncodeToString(tokenBytes)
+	if _, err := db.UsersCollection.UpdateByID(context.Background(), user.Id, bson.M{"$set": bson.M{"calendarFeedToken": user.CalendarFeedToken}}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// @Summary Gets the URL of the current user's calendar subscription feed
+// @Description Calendar apps (e.g. Apple Calendar, Outlook, Google Calendar) can subscribe to the URL to show the user's finalized events without connecting an account. Anyone with the URL can see the feed
+// @Tags user
+// @Produce json
+// @Success 200 {object} object{url=string}
+// @Router /user/calendar-feed [get]
+func getCalendarFeed(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	if len(user.CalendarFeedToken) == 0 {
+		setCalendarFeedToken(user)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"url": getCalendarFeedUrl(user.CalendarFeedToken)})
+}
+
+// @Summary Resets the URL of the current user's calendar subscription feed
+// @Description The old URL stops working, e.g. if it was shared by mistake
+// @Tags user
+// @Produce json
+// @Success 200 {object} object{url=string}
+// @Router /user/calendar-feed/reset [post]
+func resetCalendarFeed(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	setCalendarFeedToken(user)
+
+	c.JSON(http.StatusOK, gin.H{"url": getCalendarFeedUrl(user.CalendarFeedToken)})
+}
+
+// @Summary Gets the calendar subscription feed of a user
+// @Description iCalendar feed of the finalized events the user owns or responded to, from the last 30 days on. Only the sessions the user attends are included, unless they own the event
+// @Tags user
+// @Produce text/calendar
+// @Param token query string true "Token in the feed URL"
+// @Success 200 {string} string "iCalendar file"
+// @Router /user/calendar.ics [get]
+func getCalendarFeedIcs(c *gin.Context) {
+	user := db.GetUserByCalendarFeedToken(c.Query("token"))
+	if user == nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	now := time.Now()
+	userId := user.Id.Hex()
+	events := make([]*ical.Event, 0)
+	for _, event := range db.GetFinalizedEventsSince(user.Id, now.Add(-calendarFeedHistory)) {
+		respondentId := userId
+		if event.OwnerId == user.Id {
+			respondentId = ""
+		}
+		events = append(events, ics.GetEvents(&event, respondentId, false, now)...)
+	}
+
+	data, err := ics.Encode("Timeful", events)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", data)
+}
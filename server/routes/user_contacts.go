@@ -0,0 +1,310 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/contacts"
+	"schej.it/server/services/organizations"
+	"schej.it/server/utils"
+)
+
+// Adds the invitees of an event to its owner's contact book
+func recordInvitees(ownerId primitive.ObjectID, emails []string) {
+	if ownerId.IsZero() {
+		return
+	}
+	valid := make([]string, 0)
+	for _, email := range emails {
+		if normalized, err := contacts.NormalizeEmails([]string{email}); err == nil {
+			valid = append(valid, normalized...)
+		}
+	}
+	deduped, _ := contacts.NormalizeEmails(valid)
+	db.RecordInvitees(ownerId, deduped, time.Now())
+}
+
+// Returns the ids of the organizations the user is a member of
+func getOrgIds(userId primitive.ObjectID) []primitive.ObjectID {
+	return utils.Map(db.GetOrganizationsByUserId(userId), func(org models.Organization) primitive.ObjectID { return org.Id })
+}
+
+// Returns the contact group with the groupId param if the user can see it, or
+// edit it if edit is set. Responds with an error and returns nil otherwise
+func getContactGroup(c *gin.Context, edit bool) *models.ContactGroup {
+	user := utils.GetAuthUser(c)
+	group := db.GetContactGroupById(c.Param("groupId"))
+	if group == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.ContactGroupNotFound})
+		return nil
+	}
+
+	if group.OrganizationId.IsZero() {
+		if group.OwnerId != user.Id {
+			c.JSON(http.StatusNotFound, responses.Error{Error: errs.ContactGroupNotFound})
+			return nil
+		}
+		return group
+	}
+
+	org := db.GetOrganizationById(group.OrganizationId.Hex())
+	if org == nil || organizations.GetMember(org, user.Id) == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.ContactGroupNotFound})
+		return nil
+	}
+	if edit && !organizations.IsAdmin(org, user.Id) {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotOrganizationAdmin})
+		return nil
+	}
+	return group
+}
+
+// @Summary Searches the user's contact book
+// @Description The contact book has everyone the user invited to their events, most frequently invited first
+// @Tags user
+// @Produce json
+// @Param query query string false "Prefix of the email or name"
+// @Param limit query int false "Maximum number of contacts, defaults to 20"
+// @Success 200 {object} []models.Contact
+// @Router /user/contacts [get]
+func getContacts(c *gin.Context) {
+	query := struct {
+		Query string `form:"query"`
+		Limit *int64 `form:"limit"`
+	}{}
+	if err := c.Bind(&query); err != nil {
+		return
+	}
+	limit := int64(20)
+	if query.Limit != nil && *query.Limit > 0 && *query.Limit <= 100 {
+		limit = *query.Limit
+	}
+	user := utils.GetAuthUser(c)
+
+	c.JSON(http.StatusOK, db.SearchContacts(user.Id, strings.TrimSpace(query.Query), limit))
+}
+
+// @Summary Adds a contact to the user's contact book
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param payload body object{email=string,name=string} true "Contact to add"
+// @Success 200 {object} models.Contact
+// @Router /user/contacts [post]
+func addContact(c *gin.Context) {
+	payload := struct {
+		Email string `json:"email" binding:"required"`
+		Name  string `json:"name"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	emails, err := contacts.NormalizeEmails([]string{payload.Email})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	c.JSON(http.StatusOK, db.UpsertContact(user.Id, emails[0], strings.TrimSpace(payload.Name)))
+}
+
+// @Summary Removes a contact from the user's contact book
+// @Description The contact is added back the next time the user invites them
+// @Tags user
+// @Param contactId path string true "Contact ID"
+// @Success 200
+// @Router /user/contacts/{contactId} [delete]
+func deleteContact(c *gin.Context) {
+	contactId, err := primitive.ObjectIDFromHex(c.Param("contactId"))
+	user := utils.GetAuthUser(c)
+	if err != nil || !db.DeleteContact(user.Id, contactId) {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.ContactNotFound})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Gets the user's contact groups
+// @Description Includes the groups shared with the organizations the user is a member of
+// @Tags user
+// @Produce json
+// @Success 200 {object} []models.ContactGroup
+// @Router /user/contact-groups [get]
+func getContactGroups(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	c.JSON(http.StatusOK, db.GetContactGroups(user.Id, getOrgIds(user.Id)))
+}
+
+// @Summary Gets a contact group
+// @Tags user
+// @Produce json
+// @Param groupId path string true "Contact group ID"
+// @Success 200 {object} models.ContactGroup
+// @Router /user/contact-groups/{groupId} [get]
+func getContactGroupById(c *gin.Context) {
+	group := getContactGroup(c, false)
+	if group == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// @Summary Creates a contact group
+// @Description Pass the group's id as contactGroupIds when creating an event to invite all of its members. Groups created with an organizationId are shared with the organization's members, and only its admins can create and edit them
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param payload body object{name=string,emails=[]string,organizationId=string} true "Name and emails of the group, and the organization to share it with"
+// @Success 201 {object} models.ContactGroup
+// @Router /user/contact-groups [post]
+func createContactGroup(c *gin.Context) {
+	payload := struct {
+		Name           string              `json:"name" binding:"required"`
+		Emails         []string            `json:"emails"`
+		OrganizationId *primitive.ObjectID `json:"organizationId"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	emails, err := contacts.NormalizeEmails(payload.Emails)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	group := models.ContactGroup{
+		OwnerId:   user.Id,
+		Name:      strings.TrimSpace(payload.Name),
+		Emails:    emails,
+		CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+	}
+	if payload.OrganizationId != nil {
+		org := db.GetOrganizationById(payload.OrganizationId.Hex())
+		if org == nil || organizations.GetMember(org, user.Id) == nil {
+			c.JSON(http.StatusNotFound, responses.Error{Error: errs.OrganizationNotFound})
+			return
+		}
+		if !organizations.IsAdmin(org, user.Id) {
+			c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotOrganizationAdmin})
+			return
+		}
+		group.OrganizationId = org.Id
+	}
+	db.InsertContactGroup(&group)
+
+	c.JSON(http.StatusCreated, group)
+}
+
+// @Summary Renames a contact group or replaces its emails
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param groupId path string true "Contact group ID"
+// @Param payload body object{name=string,emails=[]string} true "Name and emails of the group"
+// @Success 200 {object} models.ContactGroup
+// @Router /user/contact-groups/{groupId} [put]
+func updateContactGroup(c *gin.Context) {
+	payload := struct {
+		Name   *string  `json:"name"`
+		Emails []string `json:"emails"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	group := getContactGroup(c, true)
+	if group == nil {
+		return
+	}
+
+	if payload.Name != nil {
+		group.Name = strings.TrimSpace(*payload.Name)
+	}
+	if payload.Emails != nil {
+		emails, err := contacts.NormalizeEmails(payload.Emails)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+			return
+		}
+		group.Emails = emails
+	}
+	db.UpdateContactGroup(group)
+
+	c.JSON(http.StatusOK, group)
+}
+
+// @Summary Deletes a contact group
+// @Description The contacts in the group stay in the contact book
+// @Tags user
+// @Param groupId path string true "Contact group ID"
+// @Success 200
+// @Router /user/contact-groups/{groupId} [delete]
+func deleteContactGroup(c *gin.Context) {
+	group := getContactGroup(c, true)
+	if group == nil {
+		return
+	}
+
+	db.DeleteContactGroup(group)
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Adds members to a contact group
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param groupId path string true "Contact group ID"
+// @Param payload body object{emails=[]string} true "Emails to add"
+// @Success 200 {object} models.ContactGroup
+// @Router /user/contact-groups/{groupId}/members [post]
+func addContactGroupMembers(c *gin.Context) {
+	payload := struct {
+		Emails []string `json:"emails" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	emails, err := contacts.NormalizeEmails(payload.Emails)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+	group := getContactGroup(c, true)
+	if group == nil {
+		return
+	}
+
+	db.AddContactGroupMembers(group.Id, emails)
+	group.Emails = contacts.MergeGroups(group.Emails, []models.ContactGroup{{Emails: emails}})
+
+	c.JSON(http.StatusOK, group)
+}
+
+// @Summary Removes a member from a contact group
+// @Description Events the group was already invited to are left as is
+// @Tags user
+// @Param groupId path string true "Contact group ID"
+// @Param email path string true "Email of the member"
+// @Success 200
+// @Router /user/contact-groups/{groupId}/members/{email} [delete]
+func removeContactGroupMember(c *gin.Context) {
+	group := getContactGroup(c, true)
+	if group == nil {
+		return
+	}
+
+	db.RemoveContactGroupMember(group.Id, strings.ToLower(c.Param("email")))
+
+	c.Status(http.StatusOK)
+}
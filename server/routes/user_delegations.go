@@ -0,0 +1,195 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/delegations"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// Number of recent audit records returned for a delegation
+const delegationAuditLimit = 100
+
+// Longest range of busy times a delegate can read at once
+const maxDelegatedBusyRange = 62 * 24 * time.Hour
+
+// Returns the delegation in the path if the current user is its principal or
+// delegate and it hasn't been revoked, otherwise responds with a 404 and
+// returns nil
+func getPartyDelegation(c *gin.Context) *models.Delegation {
+	user := utils.GetAuthUser(c)
+	delegation := db.GetDelegationById(c.Param("delegationId"))
+	if delegation == nil || delegation.RevokedAt != nil || (delegation.PrincipalId != user.Id && delegation.DelegateId != user.Id) {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.DelegationNotFound})
+		return nil
+	}
+	return delegation
+}
+
+// Records the action in the delegation's audit log
+func recordDelegationAudit(delegation *models.Delegation, action models.DelegationAuditAction, actorId primitive.ObjectID, eventId *primitive.ObjectID) {
+	db.InsertDelegationAudit(&models.DelegationAudit{
+		DelegationId: delegation.Id,
+		PrincipalId:  delegation.PrincipalId,
+		DelegateId:   delegation.DelegateId,
+		Action:       action,
+		ActorId:      actorId,
+		EventId:      eventId,
+		CreatedAt:    primitive.NewDateTimeFromTime(time.Now()),
+	})
+}
+
+// @Summary Gets the current user's delegations
+// @Description granted are the delegates the user allowed to respond on their behalf, and received are the principals the user can respond for
+// @Tags user
+// @Produce json
+// @Success 200 {object} object{granted=[]models.Delegation,received=[]models.Delegation}
+// @Router /user/delegations [get]
+func getDelegations(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+
+	granted := db.GetActiveDelegations(bson.M{"principalId": user.Id})
+	for i := range granted {
+		granted[i].Delegate = db.GetUserById(granted[i].DelegateId.Hex())
+	}
+	received := db.GetActiveDelegations(bson.M{"delegateId": user.Id})
+	for i := range received {
+		received[i].Principal = db.GetUserById(received[i].PrincipalId.Hex())
+	}
+
+	c.JSON(http.StatusOK, gin.H{"granted": granted, "received": received})
+}
+
+// @Summary Allows another Timeful user to respond to events on the current user's behalf
+// @Description The delegate can read when the current user is busy on their enabled calendars (not what the entries are), and submit responses for them from it. Every response they submit is attributed to them and recorded in the delegation's audit log
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param payload body object{email=string} true "Email of the delegate's Timeful account"
+// @Success 201 {object} models.Delegation
+// @Router /user/delegations [post]
+func grantDelegation(c *gin.Context) {
+	payload := struct {
+		Email string `json:"email" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	delegate := db.GetUserByEmail(strings.TrimSpace(payload.Email))
+	if delegate == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.UserDoesNotExist})
+		return
+	}
+	if delegate.Id == user.Id {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.CannotDelegateToSelf})
+		return
+	}
+	if db.GetActiveDelegation(user.Id, delegate.Id) != nil {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.DelegationExists})
+		return
+	}
+	if len(db.GetActiveDelegations(bson.M{"principalId": user.Id})) >= delegations.MAX_DELEGATES {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.TooManyDelegates})
+		return
+	}
+
+	delegation := models.Delegation{
+		PrincipalId: user.Id,
+		DelegateId:  delegate.Id,
+		CreatedAt:   primitive.NewDateTimeFromTime(time.Now()),
+	}
+	db.InsertDelegation(&delegation)
+	recordDelegationAudit(&delegation, models.DELEGATION_GRANTED, user.Id, nil)
+	delegation.Delegate = delegate
+
+	c.JSON(http.StatusCreated, delegation)
+}
+
+// @Summary Revokes a delegation
+// @Description Either the principal or the delegate can revoke it. Responses the delegate already submitted are kept
+// @Tags user
+// @Param delegationId path string true "Delegation ID"
+// @Success 200
+// @Router /user/delegations/{delegationId} [delete]
+func revokeDelegation(c *gin.Context) {
+	delegation := getPartyDelegation(c)
+	if delegation == nil {
+		return
+	}
+
+	if db.RevokeDelegation(delegation.Id, time.Now()) {
+		recordDelegationAudit(delegation, models.DELEGATION_REVOKED, utils.GetAuthUser(c).Id, nil)
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// @Summary Gets the audit log of a delegation
+// @Tags user
+// @Produce json
+// @Param delegationId path string true "Delegation ID"
+// @Success 200 {object} []models.DelegationAudit
+// @Router /user/delegations/{delegationId}/audit [get]
+func getDelegationAudit(c *gin.Context) {
+	delegation := getPartyDelegation(c)
+	if delegation == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, db.GetDelegationAudits(delegation.Id, delegationAuditLimit))
+}
+
+// @Summary Gets when the principal of a delegation is busy
+// @Description Only the delegate can read them. Only the times are returned, from the principal's scheduled events and enabled calendars
+// @Tags user
+// @Produce json
+// @Param delegationId path string true "Delegation ID"
+// @Param timeMin query string true "Start of the range"
+// @Param timeMax query string true "End of the range, at most 62 days after timeMin"
+// @Success 200 {object} []scheduling.TimeRange
+// @Router /user/delegations/{delegationId}/busy [get]
+func getDelegatedBusyTimes(c *gin.Context) {
+	query := struct {
+		TimeMin time.Time `form:"timeMin" binding:"required"`
+		TimeMax time.Time `form:"timeMax" binding:"required"`
+	}{}
+	if err := c.Bind(&query); err != nil {
+		return
+	}
+	if !query.TimeMax.After(query.TimeMin) || query.TimeMax.Sub(query.TimeMin) > maxDelegatedBusyRange {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "timeMax must be after timeMin, and at most 62 days after it"})
+		return
+	}
+
+	user := utils.GetAuthUser(c)
+	delegation := getPartyDelegation(c)
+	if delegation == nil {
+		return
+	}
+	if delegation.DelegateId != user.Id {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.DelegationNotFound})
+		return
+	}
+	principal := db.GetUserById(delegation.PrincipalId.Hex())
+	if principal == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.UserDoesNotExist})
+		return
+	}
+
+	busy := scheduling.GetBusyTimes(principal, query.TimeMin, query.TimeMax)
+	recordDelegationAudit(delegation, models.DELEGATE_READ_BUSY, user.Id, nil)
+
+	c.JSON(http.StatusOK, busy)
+}
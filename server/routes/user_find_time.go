@@ -0,0 +1,152 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/contacts"
+	"schej.it/server/services/organizations"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// How many days ahead free time can be found, unless the other user allows fewer
+const maxFindTimeDays = 14
+
+// @Summary Gets who can find mutual free time with the current user
+// @Tags user
+// @Produce json
+// @Success 200 {object} models.FindTimeSettings
+// @Router /user/find-time-settings [get]
+func getFindTimeSettings(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	settings := utils.Coalesce(user.FindTimeSettings)
+	if settings.AllowedEmails == nil {
+		settings.AllowedEmails = make([]string, 0)
+	}
+	if settings.MaxDays == 0 {
+		settings.MaxDays = maxFindTimeDays
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// @Summary Sets who can find mutual free time with the current user
+// @Description Nobody can find time with a user until they allow it. Others only ever see the resulting free times, not the user's calendar entries
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param payload body models.FindTimeSettings true "Allowed users, and how much of the calendar to share"
+// @Success 200 {object} models.FindTimeSettings
+// @Router /user/find-time-settings [put]
+func updateFindTimeSettings(c *gin.Context) {
+	payload := models.FindTimeSettings{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	emails, err := contacts.NormalizeEmails(payload.AllowedEmails)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: err.Error()})
+		return
+	}
+	payload.AllowedEmails = emails
+	if payload.MaxDays <= 0 || payload.MaxDays > maxFindTimeDays {
+		payload.MaxDays = maxFindTimeDays
+	}
+	user := utils.GetAuthUser(c)
+
+	_, err = db.UsersCollection.UpdateByID(context.Background(), user.Id, bson.M{
+		"$set": bson.M{"findTimeSettings": payload},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.JSON(http.StatusOK, payload)
+}
+
+// @Summary Finds mutual free time with another user
+// @Description Returns the times in the next days that both users are free, based on their scheduled events and connected calendars, within the current user's working hours (and the other user's, if they only share those). The other user has to allow the current user to find time with them
+// @Tags user
+// @Produce json
+// @Param userId path string true "ID of the other user"
+// @Param days query int false "Number of days to look ahead, defaults to 7"
+// @Param duration query int false "Minimum length of the free times in minutes, defaults to 30"
+// @Success 200 {object} []scheduling.TimeRange
+// @Router /user/find-time/{userId} [get]
+func findMutualFreeTime(c *gin.Context) {
+	query := struct {
+		Days     int `form:"days"`
+		Duration int `form:"duration"`
+	}{}
+	if err := c.Bind(&query); err != nil {
+		return
+	}
+	if query.Days == 0 {
+		query.Days = 7
+	}
+	if query.Duration == 0 {
+		query.Duration = 30
+	}
+	if query.Days < 0 || query.Duration < 0 || query.Duration > 24*60 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days and duration must be positive, and duration at most a day"})
+		return
+	}
+
+	user := utils.GetAuthUser(c)
+	other := db.GetUserById(c.Param("userId"))
+	if other == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.UserDoesNotExist})
+		return
+	}
+	if !canFindTime(other, user) {
+		c.JSON(http.StatusForbidden, responses.Error{Error: errs.FindTimeNotAllowed})
+		return
+	}
+
+	settings := utils.Coalesce(other.FindTimeSettings)
+	maxDays := maxFindTimeDays
+	if settings.MaxDays > 0 && settings.MaxDays < maxDays {
+		maxDays = settings.MaxDays
+	}
+	if query.Days > maxDays {
+		query.Days = maxDays
+	}
+
+	start := time.Now().Truncate(time.Minute)
+	end := start.AddDate(0, 0, query.Days)
+	allowed := [][]scheduling.TimeRange{scheduling.GetWorkingHours(user, start, end)}
+	if settings.WorkingHoursOnly {
+		allowed = append(allowed, scheduling.GetWorkingHours(other, start, end))
+	}
+	busy := append(scheduling.GetBusyTimes(user, start, end), scheduling.GetBusyTimes(other, start, end)...)
+
+	c.JSON(http.StatusOK, scheduling.GetFreeTimes(scheduling.IntersectTimes(allowed...), busy, time.Duration(query.Duration)*time.Minute))
+}
+
+// Returns whether the owner allows the requester to find time with them
+func canFindTime(owner *models.User, requester *models.User) bool {
+	if owner.Id == requester.Id || owner.FindTimeSettings == nil {
+		return false
+	}
+	if utils.Contains(owner.FindTimeSettings.AllowedEmails, strings.ToLower(requester.Email)) {
+		return true
+	}
+	if owner.FindTimeSettings.AllowOrganizationMembers {
+		for _, org := range db.GetOrganizationsByUserId(owner.Id) {
+			org := org
+			if organizations.GetMember(&org, requester.Id) != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
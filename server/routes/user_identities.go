@@ -0,0 +1,187 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/identities"
+	"schej.it/server/services/sessionstore"
+	"schej.it/server/utils"
+)
+
+// @Summary Gets the external identities linked to the current user
+// @Tags user
+// @Produce json
+// @Success 200 {object} []models.Identity
+// @Router /user/identities [get]
+func getIdentities(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	c.JSON(http.StatusOK, getUserIdentities(user))
+}
+
+// @Summary Links an external identity to the current user
+// @Description If the identity is already linked to another account, responds with 409 and a merge token that moves the other account into the current user's. If only its verified email belongs to another account, responds with 409 without a merge token, since that account has to confirm merging through /user/merge
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param provider path string true "google, microsoft, apple, slack, or discord"
+// @Param payload body identities.Credentials true "What the provider's sign in flow returned"
+// @Success 200 {object} []models.Identity
+// @Failure 409 {object} object{error=string,mergeToken=string,email=string}
+// @Router /user/identities/{provider} [post]
+func linkIdentity(c *gin.Context) {
+	var credentials identities.Credentials
+	if err := c.BindJSON(&credentials); err != nil {
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	identity, err := identities.Verify(models.IdentityProvider(c.Param("provider")), credentials, utils.GetOrigin(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidIdentity})
+		return
+	}
+
+	if other := getOtherIdentityOwner(user, identity); other != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      errs.IdentityLinkedElsewhere,
+			"mergeToken": identities.NewMergeToken(user.Id, other.Id, *identity, time.Now()),
+			"email":      other.Email,
+		})
+		return
+	}
+	if other := getEmailOwner(user, identity); other != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": errs.IdentityEmailInUse,
+			"email": other.Email,
+		})
+		return
+	}
+
+	addIdentity(user, identity)
+	c.JSON(http.StatusOK, getUserIdentities(db.GetUserById(user.Id.Hex())))
+}
+
+// @Summary Merges the account an identity belonged to into the current user's
+// @Description Moves the other account's events, responses, and everything else it owns to the current user, links the identity, and deletes the other account
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param payload body object{mergeToken=string} true "Merge token from the conflicting link"
+// @Success 200 {object} []models.Identity
+// @Router /user/identities/merge [post]
+func mergeIdentityAccount(c *gin.Context) {
+	payload := struct {
+		MergeToken string `json:"mergeToken" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	otherUserId, identity, err := identities.ParseMergeToken(payload.MergeToken, user.Id, time.Now())
+	if err != nil || otherUserId == user.Id {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidMergeToken})
+		return
+	}
+	other := db.GetUserById(otherUserId.Hex())
+	if other == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.UserDoesNotExist})
+		return
+	}
+
+	db.MergeUsers(other, user)
+	if err := sessionstore.Default.RevokeAll(other.Id.Hex(), ""); err != nil {
+		logger.StdErr.Println(err)
+	}
+
+	addIdentity(user, identity)
+	c.JSON(http.StatusOK, getUserIdentities(db.GetUserById(user.Id.Hex())))
+}
+
+// @Summary Unlinks an external identity from the current user
+// @Tags user
+// @Param provider path string true "Identity provider"
+// @Param subject path string true "ID of the account with the provider"
+// @Success 200
+// @Router /user/identities/{provider}/{subject} [delete]
+func unlinkIdentity(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	provider := models.IdentityProvider(c.Param("provider"))
+	subject := c.Param("subject")
+
+	if utils.Find(getUserIdentities(user), func(identity models.Identity) bool {
+		return identity.Provider == provider && identity.Subject == subject
+	}) == -1 {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.IdentityNotFound})
+		return
+	}
+
+	if provider == models.SLACK_IDENTITY {
+		db.UnlinkSlackAccount(subject)
+	} else {
+		db.RemoveUserIdentity(user.Id, provider, subject)
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Returns the identities linked to the user, including their slack accounts
+func getUserIdentities(user *models.User) []models.Identity {
+	result := make([]models.Identity, 0, len(user.Identities))
+	result = append(result, user.Identities...)
+	for _, slackAccount := range db.GetSlackAccountsByUserId(user.Id) {
+		result = append(result, models.Identity{
+			Provider: models.SLACK_IDENTITY,
+			Subject:  slackAccount.SlackUserId,
+			TeamId:   slackAccount.SlackTeamId,
+			LinkedAt: slackAccount.LinkedAt,
+		})
+	}
+	return result
+}
+
+// Returns the other account the identity is linked to, or nil if it only
+// belongs to the user. Signing in with the identity proves the user owns the
+// other account too, so the two can be merged
+func getOtherIdentityOwner(user *models.User, identity *models.Identity) *models.User {
+	if identity.Provider == models.SLACK_IDENTITY {
+		if slackAccount := db.GetSlackAccountBySlackUserId(identity.Subject); slackAccount != nil && slackAccount.UserId != user.Id {
+			return db.GetUserById(slackAccount.UserId.Hex())
+		}
+		return nil
+	}
+
+	if owner := db.GetUserByIdentity(identity.Provider, identity.Subject); owner != nil && owner.Id != user.Id {
+		return owner
+	}
+	return nil
+}
+
+// Returns the other account whose email is the identity's, or nil if there
+// isn't one. Sharing an email doesn't prove the user owns that account
+func getEmailOwner(user *models.User, identity *models.Identity) *models.User {
+	if len(identity.Email) == 0 || identity.Email == user.Email {
+		return nil
+	}
+	if owner := db.GetUserByEmail(identity.Email); owner != nil && owner.Id != user.Id {
+		return owner
+	}
+	return nil
+}
+
+// Links the identity to the user
+func addIdentity(user *models.User, identity *models.Identity) {
+	if identity.Provider == models.SLACK_IDENTITY {
+		// The slackbot looks users up by their slack account
+		db.LinkSlackAccount(identity.Subject, identity.TeamId, user.Id)
+		return
+	}
+	db.AddUserIdentity(user.Id, *identity)
+}
@@ -0,0 +1,117 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/responses"
+	"schej.it/server/services/accountmerge"
+	"schej.it/server/services/sessionstore"
+	"schej.it/server/utils"
+)
+
+// @Summary Starts merging another account into the current user's
+// @Description For people with two accounts, e.g. a personal and a work Google account. Emails a code to both accounts, which are entered to confirm the merge. Responds the same whether or not an account has the email, and can be requested 5 times an hour
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param payload body object{email=string} true "Email of the account to merge into the current user's"
+// @Success 201 {object} models.AccountMerge
+// @Router /user/merge [post]
+func requestAccountMerge(c *gin.Context) {
+	payload := struct {
+		Email string `json:"email" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	user := utils.GetAuthUser(c)
+	email := strings.ToLower(strings.TrimSpace(payload.Email))
+	now := time.Now()
+
+	if !accountmerge.AllowRequest(user.Id, now) {
+		c.JSON(http.StatusTooManyRequests, responses.Error{Error: errs.RateLimited})
+		return
+	}
+
+	other := db.GetUserByEmail(email)
+	merge, intoCode, fromCode, err := accountmerge.NewMerge(user, other, email, now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.CannotMergeSameAccount})
+		return
+	}
+	db.InsertAccountMerge(merge)
+
+	subject := "Confirm merging your Timeful accounts"
+	utils.SendEmail(user.Email, subject, fmt.Sprintf("Someone signed in as %s asked to merge %s into this account. Everything in %s will be moved here and %s will be deleted.\n\nIf this was you, enter this code: %s\n\nIt expires in %d minutes.\n", user.Email, email, email, email, intoCode, int(accountmerge.EXPIRY.Minutes())), "text/plain")
+	if other == nil {
+		c.JSON(http.StatusCreated, merge)
+		return
+	}
+	utils.SendEmail(other.Email, subject, fmt.Sprintf("Someone signed in as %s asked to merge this account into theirs. Everything in this account will be moved to %s and this account will be deleted.\n\nIf this was you, enter this code: %s\n\nIt expires in %d minutes.\n", user.Email, user.Email, fromCode, int(accountmerge.EXPIRY.Minutes())), "text/plain")
+
+	c.JSON(http.StatusCreated, merge)
+}
+
+// @Summary Confirms merging another account into the current user's
+// @Description Moves the other account's events, responses, folders, billing, and everything else it owns to the current user, then deletes it
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param mergeId path string true "Account merge ID"
+// @Param payload body object{intoCode=string,fromCode=string} true "Codes emailed to the current user's and the other account's emails"
+// @Success 200 {object} object{moved=map[string]int}
+// @Router /user/merge/{mergeId}/confirm [post]
+func confirmAccountMerge(c *gin.Context) {
+	payload := struct {
+		IntoCode string `json:"intoCode" binding:"required"`
+		FromCode string `json:"fromCode" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	merge := db.GetAccountMerge(user.Id, c.Param("mergeId"))
+	if merge == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.AccountMergeNotFound})
+		return
+	}
+	switch accountmerge.Check(merge, payload.IntoCode, payload.FromCode, time.Now()) {
+	case nil:
+	case accountmerge.ErrIncorrectCode:
+		db.IncrementAccountMergeAttempts(merge.Id)
+		c.JSON(http.StatusBadRequest, responses.Error{Error: errs.InvalidMergeCode})
+		return
+	default:
+		c.JSON(http.StatusGone, responses.Error{Error: errs.AccountMergeExpired})
+		return
+	}
+
+	other := db.GetUserById(merge.FromUserId.Hex())
+	if other == nil {
+		db.DeleteAccountMerge(merge.Id)
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.UserDoesNotExist})
+		return
+	}
+
+	// The other account's subscription would be left without an account
+	if utils.Coalesce(other.IsPremium) && other.StripeCustomerId != nil && user.StripeCustomerId != nil && *other.StripeCustomerId != *user.StripeCustomerId {
+		c.JSON(http.StatusConflict, responses.Error{Error: errs.MergeBillingConflict})
+		return
+	}
+
+	moved := db.MergeUsers(other, user)
+	db.DeleteAccountMerge(merge.Id)
+	if err := sessionstore.Default.RevokeAll(other.Id.Hex(), ""); err != nil {
+		logger.StdErr.Println(err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"moved": moved})
+}
@@ -0,0 +1,102 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/notion"
+	"schej.it/server/utils"
+)
+
+// @Summary Gets the Notion database the current user's events are synced to
+// @Tags user
+// @Produce json
+// @Success 200 {object} models.NotionConnection
+// @Router /user/notion [get]
+func getNotionConnection(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	c.JSON(http.StatusOK, user.NotionConnection)
+}
+
+// @Summary Connects a Notion database to sync the current user's events to
+// @Description The database must be shared with the Notion integration the token belongs to, and have these properties: Name (title), Status (select), Responses (number), Best times (text), Scheduled (date) and Link (url). A row is added for each event created from 30 days before connecting on, and kept up to date as people respond and the event is scheduled
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param payload body object{token=string,databaseId=string} true "Token of the Notion integration and the id of the database"
+// @Success 200 {object} models.NotionConnection
+// @Router /user/notion [put]
+func connectNotion(c *gin.Context) {
+	payload := struct {
+		Token      string `json:"token" binding:"required"`
+		DatabaseId string `json:"databaseId" binding:"required"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	user := utils.GetAuthUser(c)
+	databaseId := strings.ReplaceAll(strings.TrimSpace(payload.DatabaseId), "-", "")
+
+	title, missing, apiErr := notion.GetDatabase(payload.Token, databaseId)
+	if apiErr != nil {
+		if apiErr.IsUnauthorized() || apiErr.Status == http.StatusNotFound || apiErr.Status == http.StatusBadRequest {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: errs.NotionDatabaseNotAccessible})
+			return
+		}
+		logger.StdErr.Println(apiErr)
+		c.JSON(http.StatusBadGateway, responses.Error{Error: apiErr.Message})
+		return
+	}
+	if len(missing) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errs.NotionDatabaseInvalid, "missing": missing})
+		return
+	}
+
+	token, err := utils.Encrypt(payload.Token)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	connection := &models.NotionConnection{
+		Token:         token,
+		DatabaseId:    databaseId,
+		DatabaseTitle: title,
+		ConnectedAt:   primitive.NewDateTimeFromTime(time.Now()),
+	}
+	if user.NotionConnection != nil && user.NotionConnection.DatabaseId == databaseId {
+		connection.ConnectedAt = user.NotionConnection.ConnectedAt
+	} else {
+		// Rows in the previous database aren't updated anymore
+		db.UnsetNotionPages(user.Id)
+	}
+
+	if _, err := db.UsersCollection.UpdateByID(context.Background(), user.Id, bson.M{"$set": bson.M{"notionConnection": connection}}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.JSON(http.StatusOK, connection)
+}
+
+// @Summary Disconnects the current user's Notion database
+// @Description Rows already in the database are left as they are, but aren't updated anymore
+// @Tags user
+// @Success 200
+// @Router /user/notion [delete]
+func disconnectNotion(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	if _, err := db.UsersCollection.UpdateByID(context.Background(), user.Id, bson.M{"$unset": bson.M{"notionConnection": ""}}); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	db.UnsetNotionPages(user.Id)
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,63 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/responses"
+	"schej.it/server/services/sessionstore"
+	"schej.it/server/utils"
+)
+
+// @Summary Gets the current user's active sessions
+// @Description Each browser the user is signed in on, with the session of the request marked as current
+// @Tags user
+// @Produce json
+// @Success 200 {object} []models.UserSession
+// @Router /user/sessions [get]
+func getUserSessions(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	userSessions, err := sessionstore.Default.GetUserSessions(user.Id.Hex(), sessions.Default(c).ID())
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.JSON(http.StatusOK, userSessions)
+}
+
+// @Summary Revokes one of the current user's sessions
+// @Description Signs the user out on the browser of the session
+// @Tags user
+// @Param sessionId path string true "Session ID"
+// @Success 200
+// @Router /user/sessions/{sessionId} [delete]
+func revokeUserSession(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	revoked, err := sessionstore.Default.Revoke(user.Id.Hex(), c.Param("sessionId"))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	if !revoked {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.UserSessionNotFound})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Revokes all of the current user's other sessions
+// @Description Signs the user out everywhere except the browser of the request
+// @Tags user
+// @Success 200
+// @Router /user/sessions [delete]
+func revokeOtherUserSessions(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	if err := sessionstore.Default.RevokeAll(user.Id.Hex(), sessions.Default(c).ID()); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,36 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/services/entitlements"
+	"schej.it/server/utils"
+)
+
+type usage struct {
+	Plan              entitlements.Plan   `json:"plan"`
+	ApiRequests       entitlements.Status `json:"apiRequests"`
+	WebhookDeliveries entitlements.Status `json:"webhookDeliveries"`
+	LlmEventParses    entitlements.Status `json:"llmEventParses"`
+}
+
+// @Summary Gets the current user's plan and their usage of its quotas
+// @Description API requests are counted per minute, and webhook deliveries and event descriptions parsed by the LLM per day (UTC). Requests over the quota get a 429, deliveries over it are postponed until it resets, and descriptions over it are parsed by the rules-based parser
+// @Tags user
+// @Produce json
+// @Success 200 {object} usage
+// @Router /user/usage [get]
+func getUsage(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	plan := entitlements.GetUserPlan(user)
+	now := time.Now()
+
+	c.JSON(http.StatusOK, usage{
+		Plan:              plan,
+		ApiRequests:       entitlements.GetStatus(user, plan, entitlements.METRIC_API_REQUESTS, now),
+		WebhookDeliveries: entitlements.GetStatus(user, plan, entitlements.METRIC_WEBHOOK_DELIVERIES, now),
+		LlmEventParses:    entitlements.GetStatus(user, plan, entitlements.METRIC_LLM_EVENT_PARSES, now),
+	})
+}
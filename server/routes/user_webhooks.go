@@ -0,0 +1,203 @@
+package routes
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/models"
+	"schej.it/server/responses"
+	"schej.it/server/services/webhooks"
+	"schej.it/server/utils"
+)
+
+// Number of recent deliveries returned for a webhook
+const webhookDeliveriesLimit = 50
+
+// Returns the webhook in the path if the current user owns it, otherwise
+// responds with a 404 and returns nil
+func getOwnedWebhook(c *gin.Context) *models.Webhook {
+	webhook := db.GetWebhookById(c.Param("webhookId"))
+	if webhook == nil || webhook.OwnerId != utils.GetAuthUser(c).Id {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.WebhookNotFound})
+		return nil
+	}
+	return webhook
+}
+
+// Validates the target url and event types of a webhook, responding with a 400
+// and returning false if they're invalid. Only https urls are allowed, except
+// for local development
+func validateWebhook(c *gin.Context, targetUrl string, eventTypes []models.WebhookEventType) bool {
+	parsed, err := url.Parse(targetUrl)
+	if err != nil || len(parsed.Host) == 0 || (parsed.Scheme != "https" && (parsed.Scheme != "http" || utils.IsRelease())) {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "url must be an https url"})
+		return false
+	}
+	if len(eventTypes) == 0 {
+		c.JSON(http.StatusBadRequest, responses.Error{Error: "eventTypes must not be empty"})
+		return false
+	}
+	for _, eventType := range eventTypes {
+		if !utils.Contains(models.WebhookEventTypes, eventType) {
+			c.JSON(http.StatusBadRequest, responses.Error{Error: "unknown event type " + string(eventType)})
+			return false
+		}
+	}
+	return true
+}
+
+// @Summary Gets the current user's webhooks
+// @Tags user
+// @Produce json
+// @Success 200 {object} []models.Webhook
+// @Router /user/webhooks [get]
+func getWebhooks(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+	c.JSON(http.StatusOK, db.GetWebhooksByOwner(user.Id))
+}
+
+// @Summary Registers a webhook
+// @Description The url is posted a JSON payload each time one of the event types happens on the given event, or on any event the user owns if no event is given. Deliveries are signed with the returned secret, which isn't shown again: the X-Timeful-Signature header is "sha256=" followed by the hex HMAC-SHA256 of "<X-Timeful-Timestamp>.<body>". Failed deliveries are retried with exponential backoff
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param payload body object{url=string,eventTypes=[]models.WebhookEventType,eventId=string} true "Target url, the event types to post, and optionally the event to limit the webhook to"
+// @Success 201 {object} object{webhook=models.Webhook,secret=string}
+// @Router /user/webhooks [post]
+func createWebhook(c *gin.Context) {
+	payload := struct {
+		Url        string                    `json:"url" binding:"required"`
+		EventTypes []models.WebhookEventType `json:"eventTypes" binding:"required"`
+		EventId    *string                   `json:"eventId"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	if !validateWebhook(c, payload.Url, payload.EventTypes) {
+		return
+	}
+	user := utils.GetAuthUser(c)
+
+	webhook := models.Webhook{
+		OwnerId:    user.Id,
+		Url:        payload.Url,
+		EventTypes: payload.EventTypes,
+		Enabled:    true,
+		Secret:     webhooks.NewSecret(),
+		CreatedAt:  primitive.NewDateTimeFromTime(time.Now()),
+	}
+	if payload.EventId != nil {
+		event := db.GetEventByEitherId(*payload.EventId)
+		if event == nil {
+			c.JSON(http.StatusNotFound, responses.Error{Error: errs.EventNotFound})
+			return
+		}
+		if event.OwnerId != user.Id {
+			c.JSON(http.StatusForbidden, responses.Error{Error: errs.UserNotEventOwner})
+			return
+		}
+		webhook.EventId = &event.Id
+	}
+	db.InsertWebhook(&webhook)
+
+	c.JSON(http.StatusCreated, gin.H{"webhook": webhook, "secret": webhook.Secret})
+}
+
+// @Summary Updates a webhook
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param webhookId path string true "Webhook ID"
+// @Param payload body object{url=string,eventTypes=[]models.WebhookEventType,enabled=bool} true "Fields to update"
+// @Success 200 {object} models.Webhook
+// @Router /user/webhooks/{webhookId} [patch]
+func updateWebhook(c *gin.Context) {
+	payload := struct {
+		Url        *string                    `json:"url"`
+		EventTypes *[]models.WebhookEventType `json:"eventTypes"`
+		Enabled    *bool                      `json:"enabled"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+	webhook := getOwnedWebhook(c)
+	if webhook == nil {
+		return
+	}
+
+	if payload.Url != nil {
+		webhook.Url = *payload.Url
+	}
+	if payload.EventTypes != nil {
+		webhook.EventTypes = *payload.EventTypes
+	}
+	if payload.Enabled != nil {
+		webhook.Enabled = *payload.Enabled
+	}
+	if !validateWebhook(c, webhook.Url, webhook.EventTypes) {
+		return
+	}
+	db.UpdateWebhook(webhook)
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// @Summary Deletes a webhook
+// @Description Pending deliveries are dropped
+// @Tags user
+// @Param webhookId path string true "Webhook ID"
+// @Success 200
+// @Router /user/webhooks/{webhookId} [delete]
+func deleteWebhook(c *gin.Context) {
+	webhook := getOwnedWebhook(c)
+	if webhook == nil {
+		return
+	}
+
+	db.DeleteWebhook(webhook.Id)
+
+	c.Status(http.StatusOK)
+}
+
+// @Summary Gets the most recent deliveries of a webhook
+// @Description Including the payload, and the status code, error and latency of the last attempt, for debugging the target
+// @Tags user
+// @Produce json
+// @Param webhookId path string true "Webhook ID"
+// @Success 200 {object} []models.WebhookDelivery
+// @Router /user/webhooks/{webhookId}/deliveries [get]
+func getWebhookDeliveries(c *gin.Context) {
+	webhook := getOwnedWebhook(c)
+	if webhook == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, db.GetWebhookDeliveries(webhook.Id, webhookDeliveriesLimit))
+}
+
+// @Summary Replays a delivery of a webhook
+// @Description Queues the delivery's payload to be posted again as a new delivery, e.g. after fixing the target. Replays count towards the daily delivery quota
+// @Tags user
+// @Produce json
+// @Param webhookId path string true "Webhook ID"
+// @Param deliveryId path string true "Delivery ID"
+// @Success 202 {object} models.WebhookDelivery
+// @Router /user/webhooks/{webhookId}/deliveries/{deliveryId}/replay [post]
+func replayWebhookDelivery(c *gin.Context) {
+	webhook := getOwnedWebhook(c)
+	if webhook == nil {
+		return
+	}
+	delivery := db.GetWebhookDelivery(webhook.Id, c.Param("deliveryId"))
+	if delivery == nil {
+		c.JSON(http.StatusNotFound, responses.Error{Error: errs.WebhookDeliveryNotFound})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, webhooks.Replay(delivery, time.Now()))
+}
@@ -48,6 +48,12 @@ func searchUsers(c *gin.Context) {
 
 	users := make([]models.User, 0)
 
+	// Encrypted emails can't be matched, so only names are searched then
+	searchFields := bson.A{"$firstName", " ", "$lastName", " ", "$email"}
+	if db.EncryptionEnabled() {
+		searchFields = bson.A{"$firstName", " ", "$lastName"}
+	}
+
 	cursor, err := db.UsersCollection.Find(context.Background(), bson.M{
 		"$expr": bson.M{
 			"$reduce": bson.M{
@@ -59,9 +65,7 @@ func searchUsers(c *gin.Context) {
 						bson.M{
 							"$regexMatch": bson.M{
 								"input": bson.M{
-									"$concat": bson.A{
-										"$firstName", " ", "$lastName", " ", "$email",
-									},
+									"$concat": searchFields,
 								},
 								"regex": "$$this",
 							},
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"schej.it/server/db"
+)
+
+func main() {
+	// Initialize database connection
+	disconnect := db.Init()
+	defer disconnect()
+
+	// A link code is created each time an unlinked user opens the App Home, so
+	// have mongo delete them once they expire
+	_, err := db.SlackLinkCodesCollection.Indexes().CreateOne(
+		context.Background(),
+		mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "expiresAt", Value: 1},
+			},
+			Options: options.Index().
+				SetName("expiresAt_ttl").
+				SetExpireAfterSeconds(0),
+		},
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Created TTL index on slackLinkCodes.expiresAt")
+
+	// Look up codes by their value when they're redeemed
+	_, err = db.SlackLinkCodesCollection.Indexes().CreateOne(
+		context.Background(),
+		mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "code", Value: 1},
+			},
+			Options: options.Index().
+				SetName("code_1").
+				SetUnique(true),
+		},
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Created unique index on slackLinkCodes.code")
+
+	os.Exit(0)
+}
@@ -0,0 +1,106 @@
+// Codes that verify the person merging two accounts controls the emails of both
+package accountmerge
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+// How long the codes can be entered after they're sent
+const EXPIRY = 30 * time.Minute
+
+// How many wrong codes can be entered before the merge has to be requested again
+const MAX_ATTEMPTS = 5
+
+// How many merges a user can request per hour, since each one sends emails
+const MAX_REQUESTS_PER_HOUR = 5
+
+var (
+	ErrExpired         = errors.New("merge expired")
+	ErrTooManyAttempts = errors.New("too many wrong codes")
+	ErrIncorrectCode   = errors.New("incorrect code")
+	ErrSameAccount     = errors.New("can't merge an account into itself")
+)
+
+func getSecret() []byte {
+	return []byte(os.Getenv("ENCRYPTION_KEY"))
+}
+
+// Returns a random 6 digit code
+func NewCode() string {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return fmt.Sprintf("%06d", n.Int64())
+}
+
+// Returns the hash the code of the merge is stored as
+func HashCode(mergeId primitive.ObjectID, code string) string {
+	mac := hmac.New(sha256.New, getSecret())
+	mac.Write([]byte(mergeId.Hex() + ":" + strings.TrimSpace(code)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Counts a merge requested by the user, returning false if they already
+// requested too many this hour
+func AllowRequest(userId primitive.ObjectID, now time.Time) bool {
+	windowStart := now.UTC().Truncate(time.Hour)
+	counterId := fmt.Sprintf("accountMerges:%s:%d", userId.Hex(), windowStart.Unix())
+	return db.IncrementUsageCounter(counterId, windowStart.Add(time.Hour)) <= MAX_REQUESTS_PER_HOUR
+}
+
+// Returns a merge of the from user into the into user, along with the codes to
+// send to each of their emails. The from user is nil if no account has the
+// email, in which case the merge can't be confirmed, but looks the same to the
+// requester so that they can't find out which emails have accounts
+func NewMerge(into *models.User, from *models.User, fromEmail string, now time.Time) (*models.AccountMerge, string, string, error) {
+	if from != nil && into.Id == from.Id || strings.EqualFold(into.Email, fromEmail) {
+		return nil, "", "", ErrSameAccount
+	}
+
+	intoCode, fromCode := NewCode(), NewCode()
+	merge := &models.AccountMerge{
+		Id:         primitive.NewObjectID(),
+		IntoUserId: into.Id,
+		IntoEmail:  into.Email,
+		FromEmail:  fromEmail,
+		CreatedAt:  primitive.NewDateTimeFromTime(now),
+		ExpiresAt:  primitive.NewDateTimeFromTime(now.Add(EXPIRY)),
+	}
+	if from != nil {
+		merge.FromUserId = from.Id
+	}
+	merge.IntoCodeHash = HashCode(merge.Id, intoCode)
+	merge.FromCodeHash = HashCode(merge.Id, fromCode)
+	return merge, intoCode, fromCode, nil
+}
+
+// Returns nil if the codes entered are the ones sent to both emails of the merge
+func Check(merge *models.AccountMerge, intoCode string, fromCode string, now time.Time) error {
+	if now.After(merge.ExpiresAt.Time()) {
+		return ErrExpired
+	}
+	if merge.Attempts >= MAX_ATTEMPTS {
+		return ErrTooManyAttempts
+	}
+	intoOk := hmac.Equal([]byte(merge.IntoCodeHash), []byte(HashCode(merge.Id, intoCode)))
+	fromOk := hmac.Equal([]byte(merge.FromCodeHash), []byte(HashCode(merge.Id, fromCode)))
+	if !intoOk || !fromOk || merge.FromUserId.IsZero() {
+		return ErrIncorrectCode
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package accountmerge
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func TestCheck(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEY", "test-key")
+	now := time.Date(2024, time.May, 14, 12, 0, 0, 0, time.UTC)
+	into := &models.User{Id: primitive.NewObjectID(), Email: "sam@work.com"}
+	from := &models.User{Id: primitive.NewObjectID(), Email: "sam@gmail.com"}
+
+	merge, intoCode, fromCode, err := NewMerge(into, from, from.Email, now)
+	if err != nil {
+		t.Fatalf("got %v", err)
+	}
+	if merge.IntoUserId != into.Id || merge.FromUserId != from.Id || len(intoCode) != 6 || len(fromCode) != 6 {
+		t.Fatalf("got %+v, %q, %q", merge, intoCode, fromCode)
+	}
+
+	if err := Check(merge, intoCode, fromCode, now.Add(time.Minute)); err != nil {
+		t.Errorf("got %v", err)
+	}
+	if intoCode != fromCode {
+		if err := Check(merge, fromCode, intoCode, now); err != ErrIncorrectCode {
+			t.Errorf("expected swapped codes to be incorrect, got %v", err)
+		}
+	}
+	if err := Check(merge, intoCode, "", now); err != ErrIncorrectCode {
+		t.Errorf("expected both codes to be required, got %v", err)
+	}
+	if err := Check(merge, intoCode, fromCode, now.Add(EXPIRY+time.Minute)); err != ErrExpired {
+		t.Errorf("expected the merge to expire, got %v", err)
+	}
+
+	merge.Attempts = MAX_ATTEMPTS
+	if err := Check(merge, intoCode, fromCode, now); err != ErrTooManyAttempts {
+		t.Errorf("expected too many attempts, got %v", err)
+	}
+}
+
+func TestNewMergeSameAccount(t *testing.T) {
+	user := &models.User{Id: primitive.NewObjectID(), Email: "sam@work.com"}
+	if _, _, _, err := NewMerge(user, user, user.Email, time.Now()); err != ErrSameAccount {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestNewMergeUnknownEmail(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEY", "test-key")
+	now := time.Now()
+	into := &models.User{Id: primitive.NewObjectID(), Email: "sam@work.com"}
+
+	merge, intoCode, fromCode, err := NewMerge(into, nil, "nobody@gmail.com", now)
+	if err != nil || merge.FromEmail != "nobody@gmail.com" {
+		t.Fatalf("got %+v, %v", merge, err)
+	}
+	if err := Check(merge, intoCode, fromCode, now); err != ErrIncorrectCode {
+		t.Errorf("expected a merge without an account to never be confirmed, got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+// Helpers for the activity feed of events
+package activity
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"schej.it/server/models"
+)
+
+// Settings of an event that organizers can change, by their json name
+var settings = []string{
+	"name", "description", "duration", "dates", "hasSpecificTimes", "times", "type",
+	"signUpBlocks", "paymentCurrency", "bookingPolicy", "questions", "consentDocument",
+	"inviteOnly", "authorizedEmails", "startOnMonday", "notificationsEnabled",
+	"blindAvailabilityEnabled", "daysOnly", "remindees", "sendEmailAfterXResponses",
+	"collectEmails", "timeIncrement", "holidaySettings", "tags", "timezone", "workingHours",
+	"reminderCadence", "branding",
+}
+
+// Values that mean the setting isn't set
+var unsetValues = [][]byte{[]byte("null"), []byte("[]"), []byte("{}"), []byte(`""`), []byte("false"), []byte("0")}
+
+// Returns the json names of the settings that differ between the two versions
+// of an event. Settings that are unset in both, e.g. null in one and empty in
+// the other, aren't counted as changed
+func GetChangedSettings(before *models.Event, after *models.Event) []string {
+	beforeValues := getSettingValues(before)
+	afterValues := getSettingValues(after)
+
+	changed := make([]string, 0)
+	for _, setting := range settings {
+		beforeValue, afterValue := beforeValues[setting], afterValues[setting]
+		if isUnset(beforeValue) && isUnset(afterValue) {
+			continue
+		}
+		if !bytes.Equal(beforeValue, afterValue) {
+			changed = append(changed, setting)
+		}
+	}
+	return changed
+}
+
+func getSettingValues(event *models.Event) map[string]json.RawMessage {
+	values := make(map[string]json.RawMessage)
+	data, err := json.Marshal(event)
+	if err != nil {
+		return values
+	}
+	json.Unmarshal(data, &values)
+	return values
+}
+
+func isUnset(value json.RawMessage) bool {
+	if len(value) == 0 {
+		return true
+	}
+	for _, unset := range unsetValues {
+		if bytes.Equal(value, unset) {
+			return true
+		}
+	}
+	return false
+}
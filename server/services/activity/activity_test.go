@@ -0,0 +1,36 @@
+package activity
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+func TestGetChangedSettings(t *testing.T) {
+	description := "Weekly sync"
+	before := &models.Event{
+		Name:        "Standup",
+		Description: &description,
+		Dates:       []primitive.DateTime{primitive.DateTime(0)},
+		Type:        models.SPECIFIC_DATES,
+	}
+
+	// Unset settings that became empty aren't changes
+	after := *before
+	after.Times = make([]primitive.DateTime, 0)
+	after.NotificationsEnabled = utils.FalsePtr()
+	if changed := GetChangedSettings(before, &after); len(changed) != 0 {
+		t.Errorf("got %v, want no changes", changed)
+	}
+
+	after.Name = "Daily standup"
+	after.Description = nil
+	after.CollectEmails = utils.TruePtr()
+	want := []string{"name", "description", "collectEmails"}
+	if changed := GetChangedSettings(before, &after); !reflect.DeepEqual(changed, want) {
+		t.Errorf("got %v, want %v", changed, want)
+	}
+}
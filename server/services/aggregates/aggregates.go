@@ -0,0 +1,87 @@
+// In-memory cache of the aggregated availability of events, so the aggregates
+// of busy events aren't recomputed from every response on each request
+package aggregates
+
+import (
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+)
+
+// How long an aggregate is reused. Changes to the responses invalidate it on
+// the instance they're made on right away, so this bounds how stale the other
+// instances can be
+const TTL = time.Minute
+
+// Most aggregates kept, after which the expired ones are dropped
+const maxEntries = 10000
+
+// How many respondents are available at each time increment of an event,
+// without who they are
+type Aggregate struct {
+	NumRespondents int                      `json:"numRespondents"`
+	Heatmap        []scheduling.HeatmapCell `json:"heatmap"`
+}
+
+type entry struct {
+	aggregate  Aggregate
+	computedAt time.Time
+}
+
+var cache = make(map[primitive.ObjectID]entry)
+var cacheMutex sync.Mutex
+
+// Returns the aggregate of the event, computing it if it isn't cached
+func Get(event *models.Event, now time.Time) Aggregate {
+	cacheMutex.Lock()
+	cached, ok := cache[event.Id]
+	cacheMutex.Unlock()
+	if ok && now.Sub(cached.computedAt) < TTL {
+		return cached.aggregate
+	}
+
+	return compute(event, now)
+}
+
+// Computes and caches the aggregates of the events, e.g. on startup. Returns
+// how many were computed
+func Preload(events []models.Event, now time.Time) int {
+	for i := range events {
+		compute(&events[i], now)
+	}
+	return len(events)
+}
+
+// Drops the cached aggregate of the event, e.g. after a response changed
+func Invalidate(eventId primitive.ObjectID) {
+	cacheMutex.Lock()
+	delete(cache, eventId)
+	cacheMutex.Unlock()
+}
+
+func compute(event *models.Event, now time.Time) Aggregate {
+	respondents := scheduling.GetRespondents(db.GetEventResponses(event.Id.Hex()))
+	aggregate := Aggregate{
+		NumRespondents: len(respondents),
+		Heatmap:        scheduling.GetHeatmap(event, respondents),
+	}
+	store(event.Id, aggregate, now)
+	return aggregate
+}
+
+func store(eventId primitive.ObjectID, aggregate Aggregate, now time.Time) {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	if len(cache) >= maxEntries {
+		for id, cached := range cache {
+			if now.Sub(cached.computedAt) >= TTL {
+				delete(cache, id)
+			}
+		}
+	}
+	cache[eventId] = entry{aggregate: aggregate, computedAt: now}
+}
@@ -0,0 +1,47 @@
+package aggregates
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func TestGetReusesCachedAggregate(t *testing.T) {
+	now := time.Date(2024, time.May, 14, 9, 0, 0, 0, time.UTC)
+	event := &models.Event{Id: primitive.NewObjectID()}
+	store(event.Id, Aggregate{NumRespondents: 3}, now)
+	defer Invalidate(event.Id)
+
+	if got := Get(event, now.Add(TTL-time.Second)); got.NumRespondents != 3 {
+		t.Errorf("got %d respondents, want the cached 3", got.NumRespondents)
+	}
+
+	Invalidate(event.Id)
+	cacheMutex.Lock()
+	_, ok := cache[event.Id]
+	cacheMutex.Unlock()
+	if ok {
+		t.Error("expected the aggregate to be dropped")
+	}
+}
+
+func TestWarmUpSettings(t *testing.T) {
+	t.Setenv("WARMUP_ENABLED", "")
+	t.Setenv("WARMUP_HOURS", "")
+	t.Setenv("WARMUP_EVENT_LIMIT", "-1")
+	if IsWarmUpEnabled() {
+		t.Error("expected warm-up to be disabled by default")
+	}
+	if GetWarmUpWindow() != defaultWarmUpHours*time.Hour || GetWarmUpLimit() != defaultWarmUpLimit {
+		t.Errorf("got window %v and limit %d, want the defaults", GetWarmUpWindow(), GetWarmUpLimit())
+	}
+
+	t.Setenv("WARMUP_ENABLED", "true")
+	t.Setenv("WARMUP_HOURS", "6")
+	t.Setenv("WARMUP_EVENT_LIMIT", "50")
+	if !IsWarmUpEnabled() || GetWarmUpWindow() != 6*time.Hour || GetWarmUpLimit() != 50 {
+		t.Errorf("got enabled %v, window %v and limit %d", IsWarmUpEnabled(), GetWarmUpWindow(), GetWarmUpLimit())
+	}
+}
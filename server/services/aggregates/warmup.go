@@ -0,0 +1,52 @@
+package aggregates
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"schej.it/server/db"
+)
+
+// Defaults for how far back events count as recently active, and how many of
+// them are preloaded on startup
+const (
+	defaultWarmUpHours = 24
+	defaultWarmUpLimit = 200
+)
+
+// Returns whether the server warms up before reporting ready, set with
+// WARMUP_ENABLED
+func IsWarmUpEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("WARMUP_ENABLED"))
+	return enabled
+}
+
+// Returns how far back events with activity are preloaded on startup, set in
+// hours with WARMUP_HOURS
+func GetWarmUpWindow() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv("WARMUP_HOURS"))
+	if err != nil || hours <= 0 {
+		hours = defaultWarmUpHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// Returns the most events preloaded on startup, set with WARMUP_EVENT_LIMIT
+func GetWarmUpLimit() int {
+	limit, err := strconv.Atoi(os.Getenv("WARMUP_EVENT_LIMIT"))
+	if err != nil || limit <= 0 {
+		return defaultWarmUpLimit
+	}
+	return limit
+}
+
+// Preloads the aggregates of the most recently active events. Returns how many
+// were preloaded
+func PreloadHotEvents(now time.Time) int {
+	eventIds := db.GetRecentlyActiveEventIds(now.Add(-GetWarmUpWindow()), int64(GetWarmUpLimit()))
+	if len(eventIds) == 0 {
+		return 0
+	}
+	return Preload(db.GetEventsByIds(eventIds), now)
+}
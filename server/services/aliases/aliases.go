@@ -0,0 +1,37 @@
+// Other ids events can be opened by (e.g. the short ids of legacy links), and
+// redirects from the hosts of legacy links (e.g. schej.it) to the app
+package aliases
+
+import (
+	"errors"
+	"regexp"
+
+	"schej.it/server/db"
+	"schej.it/server/models"
+)
+
+// Longest alias an event can be given
+const MAX_ALIAS_LENGTH = 64
+
+var aliasPattern = regexp.MustCompile(`^\w+$`)
+
+// Validates an alias an event can also be opened by, e.g. the short id of a
+// legacy link
+func ValidateAlias(alias string) error {
+	if len(alias) == 0 || len(alias) > MAX_ALIAS_LENGTH || !aliasPattern.MatchString(alias) {
+		return errors.New("alias must be up to 64 letters, digits or underscores")
+	}
+	return nil
+}
+
+// Returns the event by either its _id, shortId, or one of its aliases, or nil
+// if there is no such event
+func GetEvent(id string) *models.Event {
+	if event := db.GetEventByEitherId(id); event != nil {
+		return event
+	}
+	if alias := db.GetEventAlias(id); alias != nil {
+		return db.GetEventById(alias.EventId.Hex())
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package aliases
+
+import "testing"
+
+func TestGetLegacyRedirect(t *testing.T) {
+	hosts := ParseLegacyHosts("schej.it, WWW.schej.it, old.example.com=https://timeful.example.com/ ,", "https://timeful.app")
+	tests := []struct {
+		host   string
+		uri    string
+		target string
+	}{
+		{"schej.it", "/e/abc123?tab=responses", "https://timeful.app/e/abc123?tab=responses"},
+		{"www.schej.it:443", "/g/xyz", "https://timeful.app/g/xyz"},
+		{"old.example.com", "/", "https://timeful.example.com/"},
+		{"timeful.app", "/e/abc123", ""},
+	}
+	for _, test := range tests {
+		target, ok := GetLegacyRedirect(hosts, test.host, test.uri)
+		if target != test.target || ok != (len(test.target) > 0) {
+			t.Errorf("GetLegacyRedirect(%q, %q) = %q, %v, want %q", test.host, test.uri, target, ok, test.target)
+		}
+	}
+}
+
+func TestValidateAlias(t *testing.T) {
+	for alias, valid := range map[string]bool{"abc123": true, "old_event": true, "": false, "a/b": false, "with space": false} {
+		if err := ValidateAlias(alias); (err == nil) != valid {
+			t.Errorf("ValidateAlias(%q) = %v, want valid %v", alias, err, valid)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package aliases
+
+import (
+	"net"
+	"os"
+	"strings"
+
+	"schej.it/server/utils"
+)
+
+// Returns the legacy hosts set in LEGACY_HOSTS, mapped to the base URL their
+// links are redirected to. See ParseLegacyHosts
+func GetLegacyHosts() map[string]string {
+	return ParseLegacyHosts(os.Getenv("LEGACY_HOSTS"), utils.GetBaseUrl())
+}
+
+// Parses a comma separated list of hosts whose links (e.g. schej.it/e/abc123)
+// are redirected to the same path on baseUrl, or on the given base URL for
+// entries like "schej.it=https://timeful.example.com"
+func ParseLegacyHosts(value string, baseUrl string) map[string]string {
+	hosts := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		host, target, found := strings.Cut(strings.TrimSpace(entry), "=")
+		host = strings.ToLower(strings.TrimSpace(host))
+		if len(host) == 0 {
+			continue
+		}
+		target = strings.TrimSpace(target)
+		if !found || len(target) == 0 {
+			target = baseUrl
+		}
+		hosts[host] = strings.TrimSuffix(target, "/")
+	}
+	return hosts
+}
+
+// Returns where a request to the host for the given path and query should be
+// permanently redirected to, if the host is a legacy one
+func GetLegacyRedirect(hosts map[string]string, host string, requestUri string) (string, bool) {
+	if splitHost, _, err := net.SplitHostPort(host); err == nil {
+		host = splitHost
+	}
+	target, ok := hosts[strings.ToLower(host)]
+	if !ok {
+		return "", false
+	}
+	if !strings.HasPrefix(requestUri, "/") {
+		requestUri = "/" + requestUri
+	}
+	return target + requestUri, true
+}
@@ -0,0 +1,47 @@
+// API keys let scripts and the timeful CLI act as a user without a browser
+// session. Only the hashes of keys are stored
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"schej.it/server/logger"
+)
+
+// Every key starts with this, so they can be told apart from other bearer
+// tokens and found by secret scanners
+const PREFIX = "tf_"
+
+// Number of characters of a key kept as its hint, after the prefix
+const hintLength = 4
+
+// Returns a new random key
+func NewKey() string {
+	keyBytes := make([]byte, 24)
+	if _, err := rand.Read(keyBytes); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return PREFIX + hex.EncodeToString(keyBytes)
+}
+
+// Returns the hash of the key that is stored and looked up
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Returns the start of the key, shown to tell keys apart
+func GetHint(key string) string {
+	if len(key) < len(PREFIX)+hintLength {
+		return key
+	}
+	return key[:len(PREFIX)+hintLength]
+}
+
+// Returns whether the bearer token is an API key
+func IsApiKey(token string) bool {
+	return strings.HasPrefix(token, PREFIX)
+}
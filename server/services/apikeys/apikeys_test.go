@@ -0,0 +1,25 @@
+package apikeys
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewKey(t *testing.T) {
+	key, other := NewKey(), NewKey()
+	if !IsApiKey(key) || len(key) != len(PREFIX)+48 {
+		t.Errorf("unexpected key %q", key)
+	}
+	if key == other || Hash(key) == Hash(other) {
+		t.Errorf("expected different keys and hashes")
+	}
+	if Hash(key) != Hash(key) || strings.Contains(Hash(key), key) {
+		t.Errorf("expected a stable hash that doesn't contain the key")
+	}
+	if hint := GetHint(key); hint != key[:7] || !strings.HasPrefix(hint, PREFIX) {
+		t.Errorf("unexpected hint %q", hint)
+	}
+	if IsApiKey("ya29.a0Af") {
+		t.Errorf("other bearer tokens aren't API keys")
+	}
+}
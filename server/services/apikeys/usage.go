@@ -0,0 +1,109 @@
+package apikeys
+
+import (
+	"sort"
+	"time"
+
+	"schej.it/server/db"
+	"schej.it/server/models"
+)
+
+// Number of days the usage of API keys is kept for
+const USAGE_RETENTION_DAYS = 30
+
+// Usage of an API key over a range of days
+type Usage struct {
+	Requests    int     `json:"requests"`
+	Errors      int     `json:"errors"`
+	RateLimited int     `json:"rateLimited"`
+	ErrorRate   float64 `json:"errorRate"`
+
+	// Busiest endpoints first
+	Endpoints []EndpointUsage `json:"endpoints"`
+
+	// Oldest day first, only days with requests
+	Days []DayUsage `json:"days"`
+}
+
+type EndpointUsage struct {
+	Method      string  `json:"method"`
+	Route       string  `json:"route"`
+	Requests    int     `json:"requests"`
+	Errors      int     `json:"errors"`
+	RateLimited int     `json:"rateLimited"`
+	ErrorRate   float64 `json:"errorRate"`
+}
+
+type DayUsage struct {
+	Day         time.Time `json:"day"`
+	Requests    int       `json:"requests"`
+	Errors      int       `json:"errors"`
+	RateLimited int       `json:"rateLimited"`
+}
+
+// Returns the start of the day (in UTC) usage at the given time is counted in
+func GetUsageDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Totals the usage records of an API key
+func SummarizeUsage(records []models.ApiKeyUsage) Usage {
+	usage := Usage{Endpoints: make([]EndpointUsage, 0), Days: make([]DayUsage, 0)}
+	endpoints := make(map[string]*EndpointUsage)
+	days := make(map[time.Time]*DayUsage)
+	for _, record := range records {
+		usage.Requests += record.Requests
+		usage.Errors += record.Errors
+		usage.RateLimited += record.RateLimited
+
+		key := record.Method + " " + record.Route
+		if _, ok := endpoints[key]; !ok {
+			endpoints[key] = &EndpointUsage{Method: record.Method, Route: record.Route}
+		}
+		endpoints[key].Requests += record.Requests
+		endpoints[key].Errors += record.Errors
+		endpoints[key].RateLimited += record.RateLimited
+
+		day := record.Day.Time().UTC()
+		if _, ok := days[day]; !ok {
+			days[day] = &DayUsage{Day: day}
+		}
+		days[day].Requests += record.Requests
+		days[day].Errors += record.Errors
+		days[day].RateLimited += record.RateLimited
+	}
+	usage.ErrorRate = getErrorRate(usage.Errors, usage.Requests)
+
+	for _, endpoint := range endpoints {
+		endpoint.ErrorRate = getErrorRate(endpoint.Errors, endpoint.Requests)
+		usage.Endpoints = append(usage.Endpoints, *endpoint)
+	}
+	sort.Slice(usage.Endpoints, func(i, j int) bool {
+		a, b := usage.Endpoints[i], usage.Endpoints[j]
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		return a.Method+" "+a.Route < b.Method+" "+b.Route
+	})
+
+	for _, day := range days {
+		usage.Days = append(usage.Days, *day)
+	}
+	sort.Slice(usage.Days, func(i, j int) bool { return usage.Days[i].Day.Before(usage.Days[j].Day) })
+
+	return usage
+}
+
+func getErrorRate(errors int, requests int) float64 {
+	if requests == 0 {
+		return 0
+	}
+	return float64(errors) / float64(requests)
+}
+
+// Deletes the usage older than the retention period. Run periodically by the
+// jobs scheduler
+func DeleteExpiredUsage(now time.Time) {
+	db.DeleteApiKeyUsageBefore(GetUsageDay(now).AddDate(0, 0, -USAGE_RETENTION_DAYS))
+}
@@ -0,0 +1,40 @@
+package apikeys
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func TestGetUsageDay(t *testing.T) {
+	loc := time.FixedZone("UTC-5", -5*60*60)
+	if day := GetUsageDay(time.Date(2024, time.May, 14, 22, 30, 0, 0, loc)); !day.Equal(time.Date(2024, time.May, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("GetUsageDay = %v, expected the UTC day", day)
+	}
+}
+
+func TestSummarizeUsage(t *testing.T) {
+	monday := primitive.NewDateTimeFromTime(time.Date(2024, time.May, 13, 0, 0, 0, 0, time.UTC))
+	tuesday := primitive.NewDateTimeFromTime(time.Date(2024, time.May, 14, 0, 0, 0, 0, time.UTC))
+	usage := SummarizeUsage([]models.ApiKeyUsage{
+		{Day: tuesday, Method: "GET", Route: "/api/events/:eventId", Requests: 3, Errors: 1},
+		{Day: monday, Method: "GET", Route: "/api/events/:eventId", Requests: 5, Errors: 3, RateLimited: 2},
+		{Day: tuesday, Method: "POST", Route: "/api/events", Requests: 2},
+	})
+
+	if usage.Requests != 10 || usage.Errors != 4 || usage.RateLimited != 2 || usage.ErrorRate != 0.4 {
+		t.Errorf("got totals %+v", usage)
+	}
+	if len(usage.Endpoints) != 2 || usage.Endpoints[0].Route != "/api/events/:eventId" || usage.Endpoints[0].Requests != 8 || usage.Endpoints[0].ErrorRate != 0.5 {
+		t.Errorf("got endpoints %+v", usage.Endpoints)
+	}
+	if len(usage.Days) != 2 || !usage.Days[0].Day.Equal(monday.Time()) || usage.Days[1].Requests != 5 {
+		t.Errorf("got days %+v", usage.Days)
+	}
+
+	if empty := SummarizeUsage(nil); empty.ErrorRate != 0 || len(empty.Endpoints) != 0 {
+		t.Errorf("got %+v for no usage", empty)
+	}
+}
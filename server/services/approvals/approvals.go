@@ -0,0 +1,61 @@
+// Tracks the co-organizers' approvals of a finalization, which only takes
+// effect once enough of them approved it
+package approvals
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+// Validates the approvers, who have to be co-organizers of the event with a
+// Timeful account. Removes duplicate approvers
+func Validate(approvers *models.FinalizationApprovers, event *models.Event) error {
+	userIds := make([]primitive.ObjectID, 0)
+	for _, userId := range approvers.UserIds {
+		if utils.Contains(userIds, userId) {
+			continue
+		}
+		isCoOrganizer := false
+		for _, coOrganizer := range event.CoOrganizers {
+			if !coOrganizer.UserId.IsZero() && coOrganizer.UserId == userId {
+				isCoOrganizer = true
+			}
+		}
+		if !isCoOrganizer {
+			return errors.New("approvers have to be co-organizers with a Timeful account")
+		}
+		userIds = append(userIds, userId)
+	}
+	approvers.UserIds = userIds
+
+	if len(approvers.UserIds) == 0 {
+		return errors.New("at least one approver is required")
+	}
+	if approvers.Quorum < 1 || approvers.Quorum > len(approvers.UserIds) {
+		return errors.New("quorum has to be between 1 and the number of approvers")
+	}
+	return nil
+}
+
+// Returns whether the user is one of the approvers
+func IsApprover(approvers *models.FinalizationApprovers, userId primitive.ObjectID) bool {
+	return approvers != nil && utils.Contains(approvers.UserIds, userId)
+}
+
+// Returns whether enough of the current approvers approved the finalization.
+// Approvals of users that are no longer approvers don't count
+func IsApproved(approvers *models.FinalizationApprovers, pending *models.PendingFinalization) bool {
+	if approvers == nil {
+		return true
+	}
+	numApprovals := 0
+	for _, userId := range pending.ApprovedBy {
+		if IsApprover(approvers, userId) {
+			numApprovals++
+		}
+	}
+	return numApprovals >= approvers.Quorum
+}
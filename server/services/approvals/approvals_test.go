@@ -0,0 +1,59 @@
+package approvals
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func TestValidate(t *testing.T) {
+	a, b, stranger := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	event := &models.Event{CoOrganizers: []models.CoOrganizer{
+		{Email: "a@example.com", UserId: a},
+		{Email: "b@example.com", UserId: b},
+		{Email: "pending@example.com"},
+	}}
+
+	approvers := models.FinalizationApprovers{UserIds: []primitive.ObjectID{a, b, a}, Quorum: 2}
+	if err := Validate(&approvers, event); err != nil {
+		t.Fatal(err)
+	}
+	if len(approvers.UserIds) != 2 {
+		t.Errorf("expected duplicates to be removed, got %v", approvers.UserIds)
+	}
+
+	if err := Validate(&models.FinalizationApprovers{UserIds: []primitive.ObjectID{stranger}, Quorum: 1}, event); err == nil {
+		t.Error("expected non co-organizer to be invalid")
+	}
+	if err := Validate(&models.FinalizationApprovers{UserIds: []primitive.ObjectID{primitive.NilObjectID}, Quorum: 1}, event); err == nil {
+		t.Error("expected co-organizer without an account to be invalid")
+	}
+	if err := Validate(&models.FinalizationApprovers{UserIds: []primitive.ObjectID{a}, Quorum: 2}, event); err == nil {
+		t.Error("expected quorum above the number of approvers to be invalid")
+	}
+	if err := Validate(&models.FinalizationApprovers{}, event); err == nil {
+		t.Error("expected no approvers to be invalid")
+	}
+}
+
+func TestIsApproved(t *testing.T) {
+	a, b, c := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	approvers := &models.FinalizationApprovers{UserIds: []primitive.ObjectID{a, b}, Quorum: 2}
+
+	pending := &models.PendingFinalization{ApprovedBy: []primitive.ObjectID{a}}
+	if IsApproved(approvers, pending) {
+		t.Error("expected one approval to not meet the quorum")
+	}
+	pending.ApprovedBy = append(pending.ApprovedBy, c)
+	if IsApproved(approvers, pending) {
+		t.Error("expected approvals of non approvers to not count")
+	}
+	pending.ApprovedBy = append(pending.ApprovedBy, b)
+	if !IsApproved(approvers, pending) {
+		t.Error("expected quorum to be met")
+	}
+	if !IsApproved(nil, &models.PendingFinalization{}) {
+		t.Error("expected events without approvers to be approved")
+	}
+}
@@ -0,0 +1,304 @@
+// Scheduling assistant that ranks an event's candidate slots against the
+// organizer's constraints and explains the tradeoffs in plain language
+package assistant
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"schej.it/server/models"
+	"schej.it/server/services/holidays"
+	"schej.it/server/services/llm"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// Constraints given by the organizer
+type Constraints struct {
+	MeetingLength time.Duration
+
+	// Ids of the respondents that must be able to make it
+	Required []string
+
+	// Only consider slots between these hours (in the organizer's timezone)
+	EarliestHour *float64
+	LatestHour   *float64
+
+	// Maximum number of suggestions to return
+	Limit int
+
+	// Only suggest slots that work for all required respondents
+	RequireAll bool
+
+	// Slots for which Exclude returns true are not suggested
+	Exclude func(start time.Time, end time.Time) bool
+
+	// Weight of each respondent, defaults to 1
+	Weights map[string]float64
+}
+
+// A ranked slot and an explanation of why it was ranked where it was
+type Suggestion struct {
+	scheduling.Slot
+	Explanation string `json:"explanation"`
+
+	// Public holiday the slot falls on, if any
+	Holiday *holidays.Holiday `json:"holiday,omitempty"`
+}
+
+// Controls how much data about respondents is sent to the LLM backend
+type DataMinimization string
+
+const (
+	// Respondents are replaced with pseudonyms ("Person 1"), which are mapped
+	// back to names in the reply. Nothing else about respondents is sent
+	Strict DataMinimization = "strict"
+
+	// Only first names are sent
+	FirstNames DataMinimization = "first_names"
+
+	// Only the number of respondents available for each slot is sent
+	AggregateOnly DataMinimization = "aggregate_only"
+)
+
+// Backend used to generate the summary. Any LLM can be plugged in by
+// implementing this interface
+type Backend interface {
+	Complete(systemPrompt string, userPrompt string) (string, error)
+}
+
+// Default backend, using the configured OpenAI-compatible API
+type llmBackend struct{}
+
+func (llmBackend) Complete(systemPrompt string, userPrompt string) (string, error) {
+	return llm.Complete(systemPrompt, userPrompt, false)
+}
+
+var backend Backend = llmBackend{}
+
+// Replaces the backend used to generate summaries
+func SetBackend(b Backend) {
+	backend = b
+}
+
+// Returns the top slots for the event given the constraints, each with an
+// explanation. Times in explanations are formatted in loc
+func Suggest(event *models.Event, respondents []scheduling.Respondent, constraints Constraints, loc *time.Location) []Suggestion {
+	required := make(models.Set[string])
+	for _, id := range constraints.Required {
+		required[id] = struct{}{}
+	}
+
+	slots := scheduling.RankSlots(event, respondents, scheduling.Options{
+		MeetingLength: constraints.MeetingLength,
+		Required:      required,
+		Weights:       constraints.Weights,
+		Exclude: func(start time.Time, end time.Time) bool {
+			localStart := start.In(loc)
+			localEnd := end.In(loc)
+			startHour := float64(localStart.Hour()) + float64(localStart.Minute())/60
+			endHour := float64(localEnd.Hour()) + float64(localEnd.Minute())/60
+			if localEnd.YearDay() != localStart.YearDay() {
+				endHour += 24
+			}
+			if constraints.EarliestHour != nil && startHour < *constraints.EarliestHour {
+				return true
+			}
+			if constraints.LatestHour != nil && endHour > *constraints.LatestHour {
+				return true
+			}
+			return constraints.Exclude != nil && constraints.Exclude(start, end)
+		},
+	})
+
+	limit := constraints.Limit
+	if limit <= 0 {
+		limit = 5
+	}
+
+	names := make(map[string]string)
+	for _, respondent := range respondents {
+		names[respondent.Id] = respondent.Name
+	}
+
+	eventHolidays := holidays.GetHolidaysForEvent(event, loc)
+	excludeHolidays := event.HolidaySettings != nil && event.HolidaySettings.Exclude
+
+	suggestions := make([]Suggestion, 0)
+	for _, slot := range slots {
+		if len(suggestions) >= limit {
+			break
+		}
+		if constraints.RequireAll && len(slot.MissingRequired) > 0 {
+			// Slots are sorted by the number of missing required respondents
+			break
+		}
+		// Skip slots overlapping a better slot, so that suggestions are distinct
+		overlaps := false
+		for _, s := range suggestions {
+			if slot.Start.Before(s.End) && slot.End.After(s.Start) {
+				overlaps = true
+				break
+			}
+		}
+		if overlaps {
+			continue
+		}
+
+		suggestion := Suggestion{
+			Slot:        slot,
+			Explanation: explain(&slot, names, required, len(respondents), loc),
+		}
+		if holiday, ok := eventHolidays[slot.Start.In(loc).Format("2006-01-02")]; ok {
+			if excludeHolidays {
+				continue
+			}
+			suggestion.Holiday = &holiday
+			suggestion.Explanation += fmt.Sprintf("; falls on %s", holiday.Name)
+		}
+
+		suggestions = append(suggestions, suggestion)
+	}
+
+	return suggestions
+}
+
+// Returns a one line explanation of the slot, e.g. "Tue 3pm works for all 6
+// required people" or "Wed 10am misses Dana"
+func explain(slot *scheduling.Slot, names map[string]string, required models.Set[string], numRespondents int, loc *time.Location) string {
+	when := FormatTime(slot.Start, loc)
+	if numRespondents == 0 {
+		return fmt.Sprintf("%s has no responses yet", when)
+	}
+
+	var parts []string
+	if len(required) > 0 {
+		if len(slot.MissingRequired) == 0 {
+			parts = append(parts, fmt.Sprintf("works for all %d required %s", len(required), utils.Pluralize(len(required), "person", "people")))
+		} else {
+			parts = append(parts, fmt.Sprintf("misses %s", joinNames(slot.MissingRequired, names)))
+		}
+
+		// Mention optional respondents that can't make it too
+		missingOptional := make([]string, 0)
+		for _, id := range slot.Unavailable {
+			if _, ok := required[id]; !ok {
+				missingOptional = append(missingOptional, id)
+			}
+		}
+		if len(missingOptional) > 0 && len(slot.MissingRequired) == 0 {
+			parts = append(parts, fmt.Sprintf("%s can't make it", joinNames(missingOptional, names)))
+		}
+	} else if len(slot.Unavailable) == 0 {
+		parts = append(parts, fmt.Sprintf("works for everyone (%d %s)", numRespondents, utils.Pluralize(numRespondents, "person", "people")))
+	} else {
+		numAvailable := len(slot.Available) + len(slot.IfNeeded)
+		parts = append(parts, fmt.Sprintf("works for %d of %d people", numAvailable, numRespondents))
+		parts = append(parts, fmt.Sprintf("misses %s", joinNames(slot.Unavailable, names)))
+	}
+
+	if len(slot.IfNeeded) > 0 {
+		parts = append(parts, fmt.Sprintf("%s only if needed", joinNames(slot.IfNeeded, names)))
+	}
+
+	return when + " " + strings.Join(parts, "; ")
+}
+
+// Summarizes the suggestions using the LLM backend, only sending the data
+// allowed by the given data minimization mode
+func Summarize(suggestions []Suggestion, respondents []scheduling.Respondent, required []string, mode DataMinimization, loc *time.Location) (string, error) {
+	// Determine how each respondent is referred to in the prompt
+	labels := make(map[string]string)
+	pseudonyms := make(map[string]string)
+	for i, respondent := range respondents {
+		switch mode {
+		case FirstNames:
+			labels[respondent.Id], _, _ = strings.Cut(respondent.Name, " ")
+		default:
+			labels[respondent.Id] = fmt.Sprintf("Person %d", i+1)
+			pseudonyms[labels[respondent.Id]] = respondent.Name
+		}
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "There are %d respondents", len(respondents))
+	if len(required) > 0 {
+		fmt.Fprintf(&sb, ", %d of which are required", len(required))
+		if labeled := withLabels(required, labels); mode != AggregateOnly && len(labeled) > 0 {
+			sb.WriteString(" (" + joinNames(labeled, labels) + ")")
+		}
+	}
+	sb.WriteString(".\nCandidate slots, best first:\n")
+	for i, suggestion := range suggestions {
+		fmt.Fprintf(&sb, "%d. %s: %d available, %d if needed, %d unavailable", i+1, FormatTime(suggestion.Start, loc), len(suggestion.Available), len(suggestion.IfNeeded), len(suggestion.Unavailable))
+		if mode != AggregateOnly {
+			if unavailable := withLabels(suggestion.Unavailable, labels); len(unavailable) > 0 {
+				sb.WriteString("; unavailable: " + joinNames(unavailable, labels))
+			}
+			if ifNeeded := withLabels(suggestion.IfNeeded, labels); len(ifNeeded) > 0 {
+				sb.WriteString("; if needed: " + joinNames(ifNeeded, labels))
+			}
+		} else if len(suggestion.MissingRequired) > 0 {
+			fmt.Fprintf(&sb, "; %d required unavailable", len(suggestion.MissingRequired))
+		}
+		sb.WriteString("\n")
+	}
+
+	systemPrompt := "You are a scheduling assistant. In 2-3 short sentences, recommend which slot the organizer should pick for their meeting and explain the tradeoffs. Only use the information given. Refer to people exactly as they are referred to in the input."
+	reply, err := backend.Complete(systemPrompt, sb.String())
+	if err != nil {
+		return "", err
+	}
+
+	// Map pseudonyms back to names, so that names never leave the server
+	if mode == Strict {
+		reply = pseudonymRegex.ReplaceAllStringFunc(reply, func(label string) string {
+			if name, ok := pseudonyms[label]; ok {
+				return name
+			}
+			return label
+		})
+	}
+
+	return strings.TrimSpace(reply), nil
+}
+
+var pseudonymRegex = regexp.MustCompile(`Person \d+`)
+
+// Formats the time for explanations, e.g. "Tue Mar 5 3pm" or "Tue Mar 5 3:30pm"
+func FormatTime(t time.Time, loc *time.Location) string {
+	t = t.In(loc)
+	if t.Minute() == 0 {
+		return t.Format("Mon Jan 2 3pm")
+	}
+	return t.Format("Mon Jan 2 3:04pm")
+}
+
+// Returns the ids that have a label. The others (e.g. required guests that
+// haven't responded) are left out of prompts, since a guest's id is their name
+func withLabels(ids []string, labels map[string]string) []string {
+	labeled := make([]string, 0)
+	for _, id := range ids {
+		if len(labels[id]) > 0 {
+			labeled = append(labeled, id)
+		}
+	}
+	return labeled
+}
+
+// Joins the names of the given respondents, e.g. "Dana, Eli and Fay"
+func joinNames(ids []string, names map[string]string) string {
+	list := make([]string, 0)
+	for _, id := range ids {
+		if name, ok := names[id]; ok && len(name) > 0 {
+			list = append(list, name)
+		} else {
+			list = append(list, id)
+		}
+	}
+	if len(list) <= 1 {
+		return strings.Join(list, "")
+	}
+	return strings.Join(list[:len(list)-1], ", ") + " and " + list[len(list)-1]
+}
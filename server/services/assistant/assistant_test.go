@@ -0,0 +1,108 @@
+package assistant
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/services/scheduling/schedulingtest"
+)
+
+func newEvent() *models.Event {
+	duration := float32(3)
+	event := schedulingtest.NewEvent()
+	event.Duration = &duration
+	return event
+}
+
+func respondent(id string, name string, hours ...int) scheduling.Respondent {
+	r := schedulingtest.NewRespondent(id, hours...)
+	r.Name = name
+	return r
+}
+
+func TestSuggestRequired(t *testing.T) {
+	respondents := []scheduling.Respondent{
+		respondent("a", "Ann", 9, 10, 11),
+		respondent("b", "Ben", 10, 11),
+		respondent("d", "Dana", 9),
+	}
+
+	suggestions := Suggest(newEvent(), respondents, Constraints{Required: []string{"a", "b"}}, time.UTC)
+	if len(suggestions) != 3 {
+		t.Fatalf("got %d suggestions, want 3", len(suggestions))
+	}
+	if want := "Tue May 14 10am works for all 2 required people; Dana can't make it"; suggestions[0].Explanation != want {
+		t.Errorf("got %q, want %q", suggestions[0].Explanation, want)
+	}
+	if want := "Tue May 14 9am misses Ben"; suggestions[2].Explanation != want {
+		t.Errorf("got %q, want %q", suggestions[2].Explanation, want)
+	}
+}
+
+func TestSuggestMeetingLength(t *testing.T) {
+	respondents := []scheduling.Respondent{
+		respondent("a", "Ann", 9, 10, 11),
+		respondent("b", "Ben", 10, 11),
+	}
+
+	suggestions := Suggest(newEvent(), respondents, Constraints{MeetingLength: 2 * time.Hour, Limit: 1}, time.UTC)
+	if len(suggestions) != 1 {
+		t.Fatalf("got %d suggestions, want 1", len(suggestions))
+	}
+	if want := "Tue May 14 10am works for everyone (2 people)"; suggestions[0].Explanation != want {
+		t.Errorf("got %q, want %q", suggestions[0].Explanation, want)
+	}
+}
+
+type fakeBackend struct {
+	prompt string
+	reply  string
+}
+
+func (b *fakeBackend) Complete(systemPrompt string, userPrompt string) (string, error) {
+	b.prompt = userPrompt
+	return b.reply, nil
+}
+
+func TestSummarizeStrict(t *testing.T) {
+	respondents := []scheduling.Respondent{
+		respondent("a", "Ann Lee", 9, 10),
+		respondent("b", "Ben Ray", 10),
+	}
+	suggestions := Suggest(newEvent(), respondents, Constraints{}, time.UTC)
+
+	b := &fakeBackend{reply: "Pick 10am, Person 2 can't make 9am."}
+	SetBackend(b)
+	defer SetBackend(llmBackend{})
+
+	summary, err := Summarize(suggestions, respondents, nil, Strict, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.prompt, "Ann") || strings.Contains(b.prompt, "Ben") {
+		t.Errorf("prompt contains names: %q", b.prompt)
+	}
+	if want := "Pick 10am, Ben Ray can't make 9am."; summary != want {
+		t.Errorf("got %q, want %q", summary, want)
+	}
+}
+
+func TestSummarizeLeavesOutUnknownRequired(t *testing.T) {
+	respondents := []scheduling.Respondent{respondent("a", "Ann Lee", 9)}
+	required := []string{"a", "Zed Guest"}
+	suggestions := Suggest(newEvent(), respondents, Constraints{Required: required}, time.UTC)
+
+	b := &fakeBackend{reply: "Pick 9am."}
+	SetBackend(b)
+	defer SetBackend(llmBackend{})
+
+	if _, err := Summarize(suggestions, respondents, required, Strict, time.UTC); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.prompt, "Zed") {
+		t.Errorf("prompt contains the name of a guest that hasn't responded: %q", b.prompt)
+	}
+}
@@ -0,0 +1,115 @@
+// Weights respondents by how reliably they attended the past events they
+// marked themselves available for
+package attendance
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+)
+
+// Number of past events a respondent must have been available for before
+// their availability is weighted
+const MIN_HISTORY = 3
+
+// Lowest weight a respondent's availability can have
+const MIN_WEIGHT = 0.25
+
+// How often a respondent was available for the scheduled time of past events,
+// and how many of those they cancelled their attendance of
+type Record struct {
+	Expected int `json:"expected"`
+	Missed   int `json:"missed"`
+}
+
+// Returns the respondent's record over the given events, which have been
+// scheduled and have ended before now. eventResponses are the respondent's
+// responses, matched to the events by event id
+func GetRecord(userId string, eventResponses []models.EventResponse, events []models.Event, now time.Time) Record {
+	responses := make(map[primitive.ObjectID]*models.Response)
+	for _, eventResponse := range eventResponses {
+		if eventResponse.Response != nil {
+			responses[eventResponse.EventId] = eventResponse.Response
+		}
+	}
+
+	record := Record{}
+	for _, event := range events {
+		if event.ScheduledEvent == nil || !event.ScheduledEvent.EndDate.Time().Before(now) {
+			continue
+		}
+		response, ok := responses[event.Id]
+		if !ok {
+			continue
+		}
+
+		// Only times the respondent said they were available for count, not
+		// times they were available for if needed
+		start := event.ScheduledEvent.StartDate
+		available := false
+		for _, t := range response.Availability {
+			if t == start {
+				available = true
+				break
+			}
+		}
+		if !available {
+			continue
+		}
+
+		record.Expected++
+		for _, cancellation := range event.Cancellations {
+			if cancellation.UserId == userId && cancellation.StartDate == start {
+				record.Missed++
+				break
+			}
+		}
+	}
+	return record
+}
+
+// Returns the weight of a respondent's availability given their record, the
+// share of the events they were available for that they attended
+func GetWeight(record Record) float64 {
+	if record.Expected < MIN_HISTORY {
+		return 1
+	}
+	weight := float64(record.Expected-record.Missed) / float64(record.Expected)
+	if weight < MIN_WEIGHT {
+		weight = MIN_WEIGHT
+	}
+	return weight
+}
+
+// Returns the weights of the event's signed in respondents that have missed
+// past events, mapping respondent id to weight. Guests have no history, so
+// they are never down-weighted
+func GetWeights(event *models.Event, respondents []scheduling.Respondent, now time.Time) map[string]float64 {
+	weights := make(map[string]float64)
+	for _, respondent := range respondents {
+		userId, err := primitive.ObjectIDFromHex(respondent.Id)
+		if err != nil {
+			continue
+		}
+
+		eventResponses := db.GetEventResponsesByUserId(userId)
+		eventIds := make([]primitive.ObjectID, 0)
+		for _, eventResponse := range eventResponses {
+			if eventResponse.EventId != event.Id {
+				eventIds = append(eventIds, eventResponse.EventId)
+			}
+		}
+		if len(eventIds) < MIN_HISTORY {
+			continue
+		}
+
+		record := GetRecord(respondent.Id, eventResponses, db.GetEventsByIds(eventIds), now)
+		if weight := GetWeight(record); weight < 1 {
+			weights[respondent.Id] = weight
+		}
+	}
+	return weights
+}
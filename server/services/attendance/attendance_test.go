@@ -0,0 +1,68 @@
+package attendance
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func TestGetRecord(t *testing.T) {
+	now := time.Date(2024, time.May, 14, 12, 0, 0, 0, time.UTC)
+	userId := primitive.NewObjectID().Hex()
+
+	// Returns a past event scheduled days ago, and the user's response to it
+	newEvent := func(days int, available bool, cancelled bool) (models.Event, models.EventResponse) {
+		start := primitive.NewDateTimeFromTime(now.AddDate(0, 0, -days))
+		event := models.Event{
+			Id:             primitive.NewObjectID(),
+			ScheduledEvent: &models.CalendarEvent{StartDate: start, EndDate: primitive.NewDateTimeFromTime(start.Time().Add(time.Hour))},
+		}
+		if cancelled {
+			event.Cancellations = []models.Cancellation{{UserId: userId, StartDate: start}}
+		}
+		response := &models.Response{}
+		if available {
+			response.Availability = []primitive.DateTime{start}
+		} else {
+			response.IfNeeded = []primitive.DateTime{start}
+		}
+		return event, models.EventResponse{EventId: event.Id, UserId: userId, Response: response}
+	}
+
+	events := make([]models.Event, 0)
+	eventResponses := make([]models.EventResponse, 0)
+	for _, args := range []struct{ available, cancelled bool }{{true, false}, {true, true}, {true, true}, {false, true}} {
+		event, eventResponse := newEvent(len(events)+1, args.available, args.cancelled)
+		events = append(events, event)
+		eventResponses = append(eventResponses, eventResponse)
+	}
+
+	// Events that haven't happened yet don't count
+	upcoming, upcomingResponse := newEvent(-1, true, true)
+	events = append(events, upcoming)
+	eventResponses = append(eventResponses, upcomingResponse)
+
+	record := GetRecord(userId, eventResponses, events, now)
+	if record != (Record{Expected: 3, Missed: 2}) {
+		t.Errorf("got %+v", record)
+	}
+}
+
+func TestGetWeight(t *testing.T) {
+	tests := []struct {
+		record Record
+		want   float64
+	}{
+		{Record{Expected: 2, Missed: 2}, 1},
+		{Record{Expected: 4, Missed: 0}, 1},
+		{Record{Expected: 4, Missed: 1}, 0.75},
+		{Record{Expected: 4, Missed: 4}, MIN_WEIGHT},
+	}
+	for _, test := range tests {
+		if got := GetWeight(test.record); got != test.want {
+			t.Errorf("%+v: got %v, want %v", test.record, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// Automation rules that organizers set up per event, e.g. "when there are 10
+// responses, notify Slack" or "when the deadline passes, finalize the top
+// slot". Rules are evaluated by a job and fire once, and every execution is
+// recorded in the rule's history
+package automations
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services/notifications"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/services/slack"
+	"schej.it/server/utils"
+)
+
+// Maximum number of automation rules per event
+const MAX_RULES = 20
+
+// Longest message of notify actions
+const MAX_MESSAGE_LENGTH = 1000
+
+// Finalizes the event at the given time on behalf of its owner, or asks its
+// finalization approvers to approve it, in which case finalized is false. Set
+// by main since finalizing lives in the routes
+var Finalize func(event *models.Event, start time.Time, end time.Time) (finalized bool, err error)
+
+var ErrAlreadyFinalized = errors.New("the event is already finalized")
+var ErrNoSlot = errors.New("no respondent can make any upcoming slot")
+
+// Returns an error if the rule isn't valid for the event
+func Validate(rule *models.AutomationRule, event *models.Event) error {
+	if !utils.Contains(models.AutomationTriggers, rule.Trigger) {
+		return fmt.Errorf("unknown trigger %s", rule.Trigger)
+	}
+	if !utils.Contains(models.AutomationActions, rule.Action) {
+		return fmt.Errorf("unknown action %s", rule.Action)
+	}
+	switch rule.Trigger {
+	case models.RESPONSES_REACHED_TRIGGER:
+		if rule.Threshold < 1 {
+			return fmt.Errorf("threshold must be at least 1")
+		}
+	case models.DEADLINE_PASSED_TRIGGER:
+		if GetDeadline(rule, event) == nil {
+			return fmt.Errorf("deadline is required if the event doesn't expire")
+		}
+	}
+	if len(rule.Message) > MAX_MESSAGE_LENGTH {
+		return fmt.Errorf("message must be at most %d characters", MAX_MESSAGE_LENGTH)
+	}
+	if rule.DurationMinutes < 0 {
+		return fmt.Errorf("durationMinutes can't be negative")
+	}
+	return nil
+}
+
+// Returns the deadline of a deadlinePassed rule, which defaults to when the
+// event expires, or nil if there is none
+func GetDeadline(rule *models.AutomationRule, event *models.Event) *time.Time {
+	var deadline time.Time
+	if rule.Deadline != nil {
+		deadline = rule.Deadline.Time()
+	} else if event.ExpiresAt != nil {
+		deadline = event.ExpiresAt.Time()
+	} else {
+		return nil
+	}
+	return &deadline
+}
+
+// Returns whether the rule's trigger fired for the event
+func IsTriggered(rule *models.AutomationRule, event *models.Event, now time.Time) bool {
+	switch rule.Trigger {
+	case models.RESPONSES_REACHED_TRIGGER:
+		return utils.Coalesce(event.NumResponses) >= rule.Threshold
+	case models.DEADLINE_PASSED_TRIGGER:
+		deadline := GetDeadline(rule, event)
+		return deadline != nil && !now.Before(*deadline)
+	}
+	return false
+}
+
+// Returns the upcoming slot of the given length the most respondents can
+// make, or nil if nobody can make any
+func GetTopSlot(event *models.Event, respondents []scheduling.Respondent, meetingLength time.Duration, now time.Time) *scheduling.Slot {
+	slots := scheduling.RankSlots(event, respondents, scheduling.Options{
+		MeetingLength: meetingLength,
+		Exclude:       scheduling.ExcludePast(event, now),
+	})
+	if len(slots) == 0 || slots[0].Score == 0 {
+		return nil
+	}
+	return &slots[0]
+}
+
+// Returns a description of why the rule fired, e.g. "Standup has 10 responses"
+func DescribeTrigger(rule *models.AutomationRule, event *models.Event) string {
+	switch rule.Trigger {
+	case models.RESPONSES_REACHED_TRIGGER:
+		return fmt.Sprintf("%s has %d responses", event.Name, utils.Coalesce(event.NumResponses))
+	case models.DEADLINE_PASSED_TRIGGER:
+		return fmt.Sprintf("The deadline of %s passed", event.Name)
+	}
+	return event.Name
+}
+
+// Runs the rule's action for the event, returning what it did
+func Execute(rule *models.AutomationRule, event *models.Event, now time.Time) (string, error) {
+	eventUrl := fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId())
+	message := rule.Message
+	if len(strings.TrimSpace(message)) == 0 {
+		message = DescribeTrigger(rule, event)
+	}
+
+	switch rule.Action {
+	case models.NOTIFY_SLACK_ACTION:
+		numSent := 0
+		for _, organizer := range notifications.GetOrganizers(event) {
+			for _, account := range db.GetSlackAccountsByUserId(organizer.Id) {
+				if err := slack.PostMessage(account.SlackUserId, fmt.Sprintf("%s\n<%s|%s>", message, eventUrl, event.Name), nil); err != nil {
+					return "", err
+				}
+				numSent++
+			}
+		}
+		if numSent == 0 {
+			return "", errors.New("no organizer linked their account to Slack")
+		}
+		return fmt.Sprintf("Sent %d Slack messages", numSent), nil
+
+	case models.NOTIFY_EMAIL_ACTION:
+		organizers := notifications.GetOrganizers(event)
+		for _, organizer := range organizers {
+			body := fmt.Sprintf("%s.\n\nView the event here: %s\n", message, eventUrl)
+			if err := utils.TrySendEmail(organizer.Email, event.Name, body, "text/plain"); err != nil {
+				return "", err
+			}
+		}
+		return fmt.Sprintf("Emailed %d organizers", len(organizers)), nil
+
+	case models.FINALIZE_TOP_SLOT_ACTION:
+		if event.ScheduledEvent != nil {
+			return "", ErrAlreadyFinalized
+		}
+		meetingLength := time.Duration(rule.DurationMinutes) * time.Minute
+		respondents := scheduling.GetRespondents(db.GetEventResponses(event.Id.Hex()))
+		slot := GetTopSlot(event, respondents, meetingLength, now)
+		if slot == nil {
+			return "", ErrNoSlot
+		}
+		finalized, err := Finalize(event, slot.Start, slot.End)
+		if err != nil {
+			return "", err
+		}
+		if !finalized {
+			return fmt.Sprintf("Asked the approvers to approve finalizing at %s", slot.Start.UTC().Format(time.RFC3339)), nil
+		}
+		return fmt.Sprintf("Finalized at %s (%d available)", slot.Start.UTC().Format(time.RFC3339), len(slot.Available)), nil
+	}
+
+	return "", fmt.Errorf("unknown action %s", rule.Action)
+}
+
+// Runs the actions of the armed rules whose trigger fired, and records each
+// execution. Run periodically by the jobs scheduler
+func RunDue(now time.Time) {
+	for _, rule := range db.GetArmedAutomationRules() {
+		rule := rule
+		event := db.GetEventById(rule.EventId.Hex())
+		deleted := event == nil || utils.Coalesce(event.IsDeleted)
+		if !deleted && !IsTriggered(&rule, event, now) {
+			continue
+		}
+		if !db.ClaimAutomationRule(rule.Id, now) {
+			continue
+		}
+
+		run := models.AutomationRun{
+			RuleId:  rule.Id,
+			EventId: rule.EventId,
+			Trigger: rule.Trigger,
+			Action:  rule.Action,
+			RanAt:   primitive.NewDateTimeFromTime(now),
+		}
+		if deleted {
+			run.Status = models.AUTOMATION_RUN_SKIPPED
+			run.Message = "The event was deleted"
+		} else {
+			execute(&rule, event, now, &run)
+		}
+		db.InsertAutomationRun(&run)
+	}
+}
+
+// Runs the rule's action, recording the result in the run. Panics are
+// recorded as failures, so one broken rule doesn't stop the others
+func execute(rule *models.AutomationRule, event *models.Event, now time.Time, run *models.AutomationRun) {
+	defer func() {
+		if err := recover(); err != nil {
+			logger.StdErr.Println(err)
+			run.Status = models.AUTOMATION_RUN_FAILED
+			run.Message = fmt.Sprint(err)
+		}
+	}()
+
+	message, err := Execute(rule, event, now)
+	switch {
+	case errors.Is(err, ErrAlreadyFinalized):
+		run.Status = models.AUTOMATION_RUN_SKIPPED
+		run.Message = err.Error()
+	case err != nil:
+		run.Status = models.AUTOMATION_RUN_FAILED
+		run.Message = err.Error()
+	default:
+		run.Status = models.AUTOMATION_RUN_SUCCEEDED
+		run.Message = message
+	}
+}
@@ -0,0 +1,105 @@
+package automations
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/services/scheduling/schedulingtest"
+)
+
+func newEvent() *models.Event {
+	event := schedulingtest.NewEvent()
+	event.Name = "Standup"
+	return event
+}
+
+func TestValidate(t *testing.T) {
+	event := newEvent()
+	deadline := primitive.NewDateTimeFromTime(schedulingtest.Day)
+	tests := []struct {
+		rule  models.AutomationRule
+		valid bool
+	}{
+		{models.AutomationRule{Trigger: models.RESPONSES_REACHED_TRIGGER, Action: models.NOTIFY_SLACK_ACTION, Threshold: 10}, true},
+		{models.AutomationRule{Trigger: models.RESPONSES_REACHED_TRIGGER, Action: models.NOTIFY_SLACK_ACTION}, false},
+		{models.AutomationRule{Trigger: models.DEADLINE_PASSED_TRIGGER, Action: models.FINALIZE_TOP_SLOT_ACTION, Deadline: &deadline}, true},
+		{models.AutomationRule{Trigger: models.DEADLINE_PASSED_TRIGGER, Action: models.FINALIZE_TOP_SLOT_ACTION}, false},
+		{models.AutomationRule{Trigger: "responseUpdated", Action: models.NOTIFY_EMAIL_ACTION}, false},
+		{models.AutomationRule{Trigger: models.RESPONSES_REACHED_TRIGGER, Action: "deleteEvent", Threshold: 1}, false},
+		{models.AutomationRule{Trigger: models.RESPONSES_REACHED_TRIGGER, Action: models.FINALIZE_TOP_SLOT_ACTION, Threshold: 1, DurationMinutes: -30}, false},
+	}
+	for i, test := range tests {
+		if err := Validate(&test.rule, event); (err == nil) != test.valid {
+			t.Errorf("%d: Validate = %v, expected valid = %v", i, err, test.valid)
+		}
+	}
+
+	// The deadline defaults to when the event expires
+	event.ExpiresAt = &deadline
+	if err := Validate(&models.AutomationRule{Trigger: models.DEADLINE_PASSED_TRIGGER, Action: models.NOTIFY_EMAIL_ACTION}, event); err != nil {
+		t.Errorf("Validate = %v, expected the event's expiresAt to be used", err)
+	}
+}
+
+func TestIsTriggered(t *testing.T) {
+	event := newEvent()
+	numResponses := 9
+	event.NumResponses = &numResponses
+
+	rule := models.AutomationRule{Trigger: models.RESPONSES_REACHED_TRIGGER, Threshold: 10}
+	if IsTriggered(&rule, event, schedulingtest.Day) {
+		t.Error("expected 9 responses not to reach a threshold of 10")
+	}
+	numResponses = 10
+	if !IsTriggered(&rule, event, schedulingtest.Day) {
+		t.Error("expected 10 responses to reach a threshold of 10")
+	}
+
+	expiresAt := primitive.NewDateTimeFromTime(schedulingtest.Day)
+	event.ExpiresAt = &expiresAt
+	rule = models.AutomationRule{Trigger: models.DEADLINE_PASSED_TRIGGER}
+	if IsTriggered(&rule, event, schedulingtest.Day.Add(-time.Minute)) {
+		t.Error("expected the deadline not to have passed a minute before")
+	}
+	if !IsTriggered(&rule, event, schedulingtest.Day) {
+		t.Error("expected the deadline to have passed at the deadline")
+	}
+
+	deadline := primitive.NewDateTimeFromTime(schedulingtest.Hour(10))
+	rule.Deadline = &deadline
+	if IsTriggered(&rule, event, schedulingtest.Day) {
+		t.Error("expected the rule's deadline to take precedence over expiresAt")
+	}
+}
+
+func TestGetTopSlot(t *testing.T) {
+	event := newEvent()
+	respondents := []scheduling.Respondent{
+		schedulingtest.NewRespondent("a", 9, 10, 11),
+		schedulingtest.NewRespondent("b", 10, 11),
+		schedulingtest.NewRespondent("c", 11, 12),
+	}
+
+	slot := GetTopSlot(event, respondents, time.Hour, schedulingtest.Day)
+	if slot == nil || !slot.Start.Equal(schedulingtest.Hour(11)) {
+		t.Fatalf("GetTopSlot = %+v, expected 11:00", slot)
+	}
+
+	// Slots that already started aren't considered
+	slot = GetTopSlot(event, respondents, time.Hour, schedulingtest.Hour(12))
+	if slot == nil || !slot.Start.Equal(schedulingtest.Hour(12)) {
+		t.Fatalf("GetTopSlot = %+v, expected 12:00", slot)
+	}
+
+	slot = GetTopSlot(event, respondents, 2*time.Hour, schedulingtest.Day)
+	if slot == nil || !slot.Start.Equal(schedulingtest.Hour(10)) {
+		t.Fatalf("GetTopSlot = %+v, expected 10:00 for two hours", slot)
+	}
+
+	if slot := GetTopSlot(event, nil, time.Hour, schedulingtest.Day); slot != nil {
+		t.Errorf("GetTopSlot = %+v, expected nil without respondents", slot)
+	}
+}
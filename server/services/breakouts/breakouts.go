@@ -0,0 +1,143 @@
+// Splits the respondents of an event into groups (e.g. breakout labs) that
+// each meet at a time that works for all their members
+package breakouts
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/services/sections"
+	"schej.it/server/utils"
+)
+
+// A group of respondents and the slot it meets at
+type Group struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// Ids of the members, and of the members that are only available if needed
+	Members  []string `json:"members"`
+	IfNeeded []string `json:"ifNeeded"`
+}
+
+type Result struct {
+	Groups []Group `json:"groups"`
+
+	// Ids of the respondents that couldn't be placed in a group
+	Unassigned []string `json:"unassigned"`
+}
+
+// Returns the number of groups of at most groupSize members needed for the
+// given number of respondents
+func GetNumGroups(numRespondents int, groupSize int) int {
+	if groupSize <= 0 {
+		return 0
+	}
+	return (numRespondents + groupSize - 1) / groupSize
+}
+
+// Splits the respondents with the given ids into at most numGroups groups of
+// at most groupSize members, such that every member can make their group's
+// slot and as many respondents as possible are placed. Slots are picked
+// greedily from the ranked slots, each filling as many seats as possible with
+// respondents that aren't placed yet (preferring available over "if
+// needed"), then respondents are assigned to the picked slots optimally.
+// Several groups may meet at the same time. Groups are sorted by start time
+func Split(ranked []scheduling.Slot, respondentIds []string, numGroups int, groupSize int) Result {
+	picked := make([]scheduling.Slot, 0)
+	placed := make(models.Set[string])
+	for len(picked) < numGroups {
+		best, bestGain := -1, 0
+		for i, slot := range ranked {
+			seats := pickSeats(slot, placed, groupSize)
+			if gain := len(seats) + countAvailable(seats, slot); gain > bestGain {
+				best, bestGain = i, gain
+			}
+		}
+		if best == -1 {
+			break
+		}
+
+		for _, id := range pickSeats(ranked[best], placed, groupSize) {
+			placed[id] = struct{}{}
+		}
+		picked = append(picked, ranked[best])
+	}
+	sort.SliceStable(picked, func(i, j int) bool { return picked[i].Start.Before(picked[j].Start) })
+
+	// Assign respondents to the picked slots
+	sectionsList := make([]sections.Section, 0)
+	students := make([]sections.Student, 0)
+	for i := range picked {
+		sectionsList = append(sectionsList, sections.Section{Id: strconv.Itoa(i), Capacity: groupSize})
+	}
+	for _, id := range respondentIds {
+		student := sections.Student{Id: id, Preferences: make(map[string]sections.Preference)}
+		for i, slot := range picked {
+			if utils.Contains(slot.Available, id) {
+				student.Preferences[strconv.Itoa(i)] = sections.Available
+			} else if utils.Contains(slot.IfNeeded, id) {
+				student.Preferences[strconv.Itoa(i)] = sections.IfNeeded
+			}
+		}
+		students = append(students, student)
+	}
+	assigned := sections.Assign(students, sectionsList, false)
+
+	result := Result{Groups: make([]Group, 0), Unassigned: assigned.Unassigned}
+	for _, slot := range picked {
+		result.Groups = append(result.Groups, Group{Start: slot.Start, End: slot.End, Members: make([]string, 0), IfNeeded: make([]string, 0)})
+	}
+	for _, assignment := range assigned.Assignments {
+		i, _ := strconv.Atoi(assignment.SectionId)
+		result.Groups[i].Members = append(result.Groups[i].Members, assignment.StudentId)
+		if assignment.Preference == sections.IfNeeded {
+			result.Groups[i].IfNeeded = append(result.Groups[i].IfNeeded, assignment.StudentId)
+		}
+	}
+
+	// Drop groups that ended up without members
+	groups := make([]Group, 0)
+	for _, group := range result.Groups {
+		if len(group.Members) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	result.Groups = groups
+	return result
+}
+
+// Returns up to groupSize respondents of the slot that haven't been placed,
+// available respondents first
+func pickSeats(slot scheduling.Slot, placed models.Set[string], groupSize int) []string {
+	seats := make([]string, 0, groupSize)
+	for _, ids := range [][]string{slot.Available, slot.IfNeeded} {
+		for _, id := range ids {
+			if len(seats) == groupSize {
+				return seats
+			}
+			if _, ok := placed[id]; !ok {
+				seats = append(seats, id)
+			}
+		}
+	}
+	return seats
+}
+
+// Returns how many of the respondents are available (not just if needed) for the slot
+func countAvailable(ids []string, slot scheduling.Slot) int {
+	available := make(models.Set[string])
+	for _, id := range slot.Available {
+		available[id] = struct{}{}
+	}
+	count := 0
+	for _, id := range ids {
+		if _, ok := available[id]; ok {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,47 @@
+package breakouts
+
+import (
+	"testing"
+
+	"schej.it/server/services/scheduling"
+	"schej.it/server/services/scheduling/schedulingtest"
+)
+
+func TestGetNumGroups(t *testing.T) {
+	if n := GetNumGroups(7, 3); n != 3 {
+		t.Errorf("expected 3 groups, got %d", n)
+	}
+	if n := GetNumGroups(6, 3); n != 2 {
+		t.Errorf("expected 2 groups, got %d", n)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	respondents := []scheduling.Respondent{
+		schedulingtest.NewRespondent("a", 9, 10),
+		schedulingtest.NewRespondent("b", 9, 10),
+		schedulingtest.NewRespondent("c", 10),
+		schedulingtest.NewRespondent("d", 10, 12),
+		schedulingtest.NewRespondent("e", 12),
+		schedulingtest.NewRespondentIfNeeded("f", nil, []int{12}),
+		schedulingtest.NewRespondent("g"),
+	}
+	ids := make([]string, 0)
+	for _, r := range respondents {
+		ids = append(ids, r.Id)
+	}
+	ranked := scheduling.RankSlots(schedulingtest.NewEvent(), respondents, scheduling.Options{})
+
+	result := Split(ranked, ids, 2, 3)
+	if len(result.Groups) != 2 || len(result.Unassigned) != 1 || result.Unassigned[0] != "g" {
+		t.Fatalf("got %+v", result)
+	}
+	for _, group := range result.Groups {
+		if len(group.Members) != 3 {
+			t.Errorf("expected full groups, got %+v", group)
+		}
+	}
+	if last := result.Groups[1]; !last.Start.Equal(schedulingtest.Hour(12)) || len(last.IfNeeded) != 1 || last.IfNeeded[0] != "f" {
+		t.Errorf("expected f to join the 12pm group if needed, got %+v", last)
+	}
+}
@@ -5,6 +5,8 @@ import (
 
 	"schej.it/server/models"
 	"schej.it/server/services/auth"
+	"schej.it/server/services/regions"
+	"schej.it/server/services/status"
 	"schej.it/server/utils"
 )
 
@@ -43,7 +45,12 @@ func GetCalendarEventsAsync(calendarAccountKey string, calendarProvider *Calenda
 		}
 	}()
 
+	start := time.Now()
 	calendarEvents, err := (*calendarProvider).GetCalendarEvents(calendarId, timeMin, timeMax)
+	status.Record(status.CALENDAR_SYNC, err)
+	if err == nil {
+		regions.RecordLatency(GetProviderName(*calendarProvider), time.Since(start))
+	}
 
 	c <- GetCalendarEventsData{CalendarEvents: calendarEvents, CalendarAccountKey: calendarAccountKey, Error: err}
 }
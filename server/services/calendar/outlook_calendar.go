@@ -3,6 +3,7 @@ package calendar
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -12,6 +13,9 @@ import (
 	"schej.it/server/utils"
 )
 
+// Custom time format for Outlook date-time strings
+const outlookTimeFormat = "2006-01-02T15:04:05.0000000"
+
 type OutlookCalendar struct {
 	models.OAuth2CalendarAuth
 }
@@ -49,59 +53,101 @@ func (calendar *OutlookCalendar) GetCalendarList() (map[string]models.SubCalenda
 }
 
 func (calendar *OutlookCalendar) GetCalendarEvents(calendarId string, timeMin time.Time, timeMax time.Time) ([]models.CalendarEvent, error) {
-	url := fmt.Sprintf("https://graph.microsoft.com/v1.0/me/calendars/%s/calendarview?startdatetime=%s&enddatetime=%s&$select=id,subject,start,end,showAs",
-		calendarId,
-		timeMin.Format(time.RFC3339),
-		timeMax.Format(time.RFC3339))
-	response := services.CallApi(nil, &calendar.OAuth2CalendarAuth, "GET", url, nil)
-	defer response.Body.Close()
-
-	responseBody := struct {
-		Value []struct {
-			Id      string `json:"id"`
-			Subject string `json:"subject"`
-			Start   struct {
-				DateTime string `json:"dateTime"`
-			} `json:"start"`
-			End struct {
-				DateTime string `json:"dateTime"`
-			} `json:"end"`
-			ShowAs string `json:"showAs"`
-		} `json:"value"`
-		Error bson.M `json:"error"`
-	}{}
-	err := json.NewDecoder(response.Body).Decode(&responseBody)
-	if err != nil {
-		return nil, err
-	}
-
-	if responseBody.Error != nil {
-		return nil, fmt.Errorf("error fetching Outlook events: %v", responseBody.Error)
-	}
+	// The calendar view is paged, so follow @odata.nextLink until all events are read
+	pageUrl := fmt.Sprintf("https://graph.microsoft.com/v1.0/me/calendars/%s/calendarview?startdatetime=%s&enddatetime=%s&$select=id,subject,start,end,showAs&$top=100",
+		url.PathEscape(calendarId),
+		url.QueryEscape(timeMin.UTC().Format(time.RFC3339)),
+		url.QueryEscape(timeMax.UTC().Format(time.RFC3339)))
 
 	calendarEvents := make([]models.CalendarEvent, 0)
-	for _, event := range responseBody.Value {
-		// Custom time format for Outlook date-time strings
-		const outlookTimeFormat = "2006-01-02T15:04:05.0000000"
-
-		startTime, err := time.Parse(outlookTimeFormat, event.Start.DateTime)
+	for len(pageUrl) > 0 {
+		response := services.CallApi(nil, &calendar.OAuth2CalendarAuth, "GET", pageUrl, nil)
+
+		responseBody := struct {
+			Value []struct {
+				Id      string `json:"id"`
+				Subject string `json:"subject"`
+				Start   struct {
+					DateTime string `json:"dateTime"`
+				} `json:"start"`
+				End struct {
+					DateTime string `json:"dateTime"`
+				} `json:"end"`
+				ShowAs string `json:"showAs"`
+			} `json:"value"`
+			NextLink string `json:"@odata.nextLink"`
+			Error    bson.M `json:"error"`
+		}{}
+		err := json.NewDecoder(response.Body).Decode(&responseBody)
+		response.Body.Close()
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse start time: %w", err)
+			return nil, err
 		}
-		endTime, err := time.Parse(outlookTimeFormat, event.End.DateTime)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse end time: %w", err)
+
+		if responseBody.Error != nil {
+			return nil, fmt.Errorf("error fetching Outlook events: %v", responseBody.Error)
 		}
 
-		calendarEvents = append(calendarEvents, models.CalendarEvent{
-			Id:         event.Id,
-			CalendarId: calendarId,
-			Summary:    event.Subject,
-			StartDate:  primitive.NewDateTimeFromTime(startTime),
-			EndDate:    primitive.NewDateTimeFromTime(endTime),
-			Free:       event.ShowAs == "free",
-		})
+		for _, event := range responseBody.Value {
+			startTime, err := time.Parse(outlookTimeFormat, event.Start.DateTime)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse start time: %w", err)
+			}
+			endTime, err := time.Parse(outlookTimeFormat, event.End.DateTime)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse end time: %w", err)
+			}
+
+			calendarEvents = append(calendarEvents, models.CalendarEvent{
+				Id:         event.Id,
+				CalendarId: calendarId,
+				Summary:    event.Subject,
+				StartDate:  primitive.NewDateTimeFromTime(startTime),
+				EndDate:    primitive.NewDateTimeFromTime(endTime),
+				Free:       event.ShowAs == "free",
+			})
+		}
+		pageUrl = responseBody.NextLink
 	}
 
 	return calendarEvents, nil
 }
+
+// Adds the event to the user's default Outlook calendar, inviting the
+// attendees, or updates it if eventId is set. Returns the id of the event
+func (calendar *OutlookCalendar) WriteEvent(eventId string, event WritableEvent) (string, error) {
+	attendees := make([]bson.M, 0)
+	for _, email := range event.AttendeeEmails {
+		attendees = append(attendees, bson.M{
+			"emailAddress": bson.M{"address": email},
+			"type":         "required",
+		})
+	}
+	body := bson.M{
+		"subject":   event.Summary,
+		"body":      bson.M{"contentType": "text", "content": event.Description},
+		"start":     bson.M{"dateTime": event.Start.UTC().Format(outlookTimeFormat), "timeZone": "UTC"},
+		"end":       bson.M{"dateTime": event.End.UTC().Format(outlookTimeFormat), "timeZone": "UTC"},
+		"attendees": attendees,
+	}
+
+	method, eventUrl := "POST", "https://graph.microsoft.com/v1.0/me/events"
+	if len(eventId) > 0 {
+		method, eventUrl = "PATCH", fmt.Sprintf("https://graph.microsoft.com/v1.0/me/events/%s", url.PathEscape(eventId))
+	}
+	response := services.CallApi(nil, &calendar.OAuth2CalendarAuth, method, eventUrl, &body)
+	defer response.Body.Close()
+
+	responseBody := struct {
+		Id    string `json:"id"`
+		Error bson.M `json:"error"`
+	}{}
+	if err := json.NewDecoder(response.Body).Decode(&responseBody); err != nil {
+		return "", err
+	}
+	if responseBody.Error != nil {
+		return "", fmt.Errorf("error writing Outlook event: %v", responseBody.Error)
+	}
+
+	return responseBody.Id, nil
+}
@@ -11,6 +11,21 @@ type CalendarProvider interface {
 	GetCalendarEvents(calendarId string, timeMin time.Time, timeMax time.Time) ([]models.CalendarEvent, error)
 }
 
+// Calendar providers that finalized events can be written back to
+type CalendarWriter interface {
+	// Creates the event, or updates it if eventId is set, and returns its id
+	WriteEvent(eventId string, event WritableEvent) (string, error)
+}
+
+// An event to add to the organizer's calendar
+type WritableEvent struct {
+	Summary        string
+	Description    string
+	Start          time.Time
+	End            time.Time
+	AttendeeEmails []string
+}
+
 func GetCalendarProvider(calendarAccount models.CalendarAccount) CalendarProvider {
 	switch calendarAccount.CalendarType {
 	case models.GoogleCalendarType:
@@ -28,3 +43,25 @@ func GetCalendarProvider(calendarAccount models.CalendarAccount) CalendarProvide
 	}
 	return nil
 }
+
+// Returns the name of the calendar provider, e.g. "google"
+func GetProviderName(provider CalendarProvider) string {
+	switch provider.(type) {
+	case *GoogleCalendar:
+		return string(models.GoogleCalendarType)
+	case *OutlookCalendar:
+		return string(models.OutlookCalendarType)
+	case *AppleCalendar:
+		return string(models.AppleCalendarType)
+	}
+	return "unknown"
+}
+
+// Returns the writer for the calendar account, or nil if events can't be
+// written back to its provider
+func GetCalendarWriter(calendarAccount models.CalendarAccount) CalendarWriter {
+	if writer, ok := GetCalendarProvider(calendarAccount).(CalendarWriter); ok {
+		return writer
+	}
+	return nil
+}
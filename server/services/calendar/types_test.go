@@ -0,0 +1,20 @@
+package calendar
+
+import (
+	"testing"
+
+	"schej.it/server/models"
+)
+
+func TestGetCalendarWriter(t *testing.T) {
+	outlook := models.CalendarAccount{CalendarType: models.OutlookCalendarType, OAuth2CalendarAuth: &models.OAuth2CalendarAuth{}}
+	if GetCalendarWriter(outlook) == nil {
+		t.Error("expected Outlook calendars to be writable")
+	}
+
+	google := models.CalendarAccount{CalendarType: models.GoogleCalendarType, OAuth2CalendarAuth: &models.OAuth2CalendarAuth{}}
+	apple := models.CalendarAccount{CalendarType: models.AppleCalendarType, AppleCalendarAuth: &models.AppleCalendarAuth{}}
+	if GetCalendarWriter(google) != nil || GetCalendarWriter(apple) != nil {
+		t.Error("expected only Outlook calendars to be writable")
+	}
+}
@@ -0,0 +1,169 @@
+// Imports Google Classroom rosters as the remindees of events, and keeps
+// them in sync as students join and leave the course
+package classroom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/errs"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services"
+	"schej.it/server/services/notifications"
+	"schej.it/server/utils"
+)
+
+type Course struct {
+	Id      string `json:"id"`
+	Name    string `json:"name"`
+	Section string `json:"section"`
+}
+
+type Student struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// Calls the Classroom API with the user's primary Google account, following
+// nextPageToken until all pages are read
+func callApi(user *models.User, apiUrl string, onPage func(body []byte) (string, error)) *errs.GoogleAPIError {
+	account, ok := user.CalendarAccounts[utils.GetCalendarAccountKey(user.Email, models.GoogleCalendarType)]
+	if !ok || account.OAuth2CalendarAuth == nil {
+		return &errs.GoogleAPIError{Code: http.StatusUnauthorized, Message: "no google account connected"}
+	}
+
+	pageToken := ""
+	for {
+		pageUrl := apiUrl
+		if len(pageToken) > 0 {
+			pageUrl += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		response := services.CallApi(user, account.OAuth2CalendarAuth, "GET", pageUrl, nil)
+		body, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return &errs.GoogleAPIError{Code: http.StatusBadGateway, Message: err.Error()}
+		}
+		res := struct {
+			Error *errs.GoogleAPIError `json:"error"`
+		}{}
+		if err := json.Unmarshal(body, &res); err == nil && res.Error != nil {
+			return res.Error
+		}
+
+		nextPageToken, err := onPage(body)
+		if err != nil {
+			return &errs.GoogleAPIError{Code: http.StatusBadGateway, Message: err.Error()}
+		}
+		if len(nextPageToken) == 0 {
+			return nil
+		}
+		pageToken = nextPageToken
+	}
+}
+
+// Returns the active courses the user teaches
+func GetCourses(user *models.User) ([]Course, *errs.GoogleAPIError) {
+	courses := make([]Course, 0)
+	googleErr := callApi(user, "https://classroom.googleapis.com/v1/courses?teacherId=me&courseStates=ACTIVE&pageSize=100", func(body []byte) (string, error) {
+		page := struct {
+			Courses       []Course `json:"courses"`
+			NextPageToken string   `json:"nextPageToken"`
+		}{}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return "", err
+		}
+		courses = append(courses, page.Courses...)
+		return page.NextPageToken, nil
+	})
+	return courses, googleErr
+}
+
+// Returns the course, if the user teaches it
+func GetCourse(user *models.User, courseId string) (*Course, *errs.GoogleAPIError) {
+	var course *Course
+	googleErr := callApi(user, fmt.Sprintf("https://classroom.googleapis.com/v1/courses/%s", url.PathEscape(courseId)), func(body []byte) (string, error) {
+		course = &Course{}
+		return "", json.Unmarshal(body, course)
+	})
+	return course, googleErr
+}
+
+// Returns the students of the course. Students whose email isn't visible to
+// the teacher are left out
+func GetStudents(user *models.User, courseId string) ([]Student, *errs.GoogleAPIError) {
+	students := make([]Student, 0)
+	googleErr := callApi(user, fmt.Sprintf("https://classroom.googleapis.com/v1/courses/%s/students?pageSize=100", url.PathEscape(courseId)), func(body []byte) (string, error) {
+		page := struct {
+			Students []struct {
+				Profile struct {
+					Name struct {
+						FullName string `json:"fullName"`
+					} `json:"name"`
+					EmailAddress string `json:"emailAddress"`
+				} `json:"profile"`
+			} `json:"students"`
+			NextPageToken string `json:"nextPageToken"`
+		}{}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return "", err
+		}
+		for _, student := range page.Students {
+			if len(student.Profile.EmailAddress) > 0 {
+				students = append(students, Student{
+					Name:  student.Profile.Name.FullName,
+					Email: strings.ToLower(student.Profile.EmailAddress),
+				})
+			}
+		}
+		return page.NextPageToken, nil
+	})
+	return students, googleErr
+}
+
+// Sets the event's remindees to the current students of its roster, using the
+// owner's Google account
+func SyncRoster(event *models.Event, owner *models.User, now time.Time) *errs.GoogleAPIError {
+	students, googleErr := GetStudents(owner, event.ClassroomRoster.CourseId)
+	if googleErr != nil {
+		return googleErr
+	}
+
+	notifications.UpdateRemindees(event, utils.Map(students, func(s Student) string { return s.Email }), owner.FirstName)
+	syncedAt := primitive.NewDateTimeFromTime(now)
+	event.ClassroomRoster.SyncedAt = &syncedAt
+
+	_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{"$set": bson.M{
+		"remindees":       event.Remindees,
+		"classroomRoster": event.ClassroomRoster,
+	}})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return nil
+}
+
+// Syncs the rosters of all the events that imported one and aren't scheduled
+// yet. Run periodically by the jobs scheduler
+func SyncRosters(now time.Time) {
+	for _, event := range db.GetEventsWithClassroomRoster() {
+		event := event
+		owner := db.GetUserById(event.OwnerId.Hex())
+		if owner == nil {
+			continue
+		}
+		if googleErr := SyncRoster(&event, owner, now); googleErr != nil {
+			logger.StdErr.Printf("Failed to sync classroom roster of event %s: %v\n", event.Id.Hex(), googleErr)
+		}
+	}
+}
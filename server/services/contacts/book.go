@@ -0,0 +1,45 @@
+package contacts
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"schej.it/server/models"
+)
+
+// Returns the emails trimmed, lowercased and without duplicates, or an error
+// if one of them isn't a valid email
+func NormalizeEmails(emails []string) ([]string, error) {
+	normalized := make([]string, 0)
+	seen := make(models.Set[string])
+	for _, email := range emails {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+			return nil, fmt.Errorf("invalid email %q", email)
+		}
+		if _, ok := seen[email]; !ok {
+			seen[email] = struct{}{}
+			normalized = append(normalized, email)
+		}
+	}
+	return normalized, nil
+}
+
+// Returns the emails followed by the emails of the groups that aren't already in them
+func MergeGroups(emails []string, groups []models.ContactGroup) []string {
+	merged := append(make([]string, 0), emails...)
+	seen := make(models.Set[string])
+	for _, email := range emails {
+		seen[strings.ToLower(email)] = struct{}{}
+	}
+	for _, group := range groups {
+		for _, email := range group.Emails {
+			if _, ok := seen[email]; !ok {
+				seen[email] = struct{}{}
+				merged = append(merged, email)
+			}
+		}
+	}
+	return merged
+}
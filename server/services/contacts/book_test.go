@@ -0,0 +1,31 @@
+package contacts
+
+import (
+	"reflect"
+	"testing"
+
+	"schej.it/server/models"
+)
+
+func TestNormalizeEmails(t *testing.T) {
+	emails, err := NormalizeEmails([]string{" Ada@School.edu", "ada@school.edu", "alan@school.edu"})
+	if err != nil || !reflect.DeepEqual(emails, []string{"ada@school.edu", "alan@school.edu"}) {
+		t.Errorf("got %v, %v", emails, err)
+	}
+
+	if _, err := NormalizeEmails([]string{"not an email"}); err == nil {
+		t.Errorf("invalid email accepted")
+	}
+}
+
+func TestMergeGroups(t *testing.T) {
+	groups := []models.ContactGroup{
+		{Emails: []string{"ada@school.edu", "alan@school.edu"}},
+		{Emails: []string{"grace@school.edu", "alan@school.edu"}},
+	}
+	merged := MergeGroups([]string{"Ada@school.edu"}, groups)
+	expected := []string{"Ada@school.edu", "alan@school.edu", "grace@school.edu"}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("got %v, expected %v", merged, expected)
+	}
+}
@@ -0,0 +1,55 @@
+// Delegates (e.g. executive assistants) that respond to events on behalf of
+// another user, importing the availability from that user's calendars
+package delegations
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// Maximum number of delegates a principal can have
+const MAX_DELEGATES = 10
+
+// Returns the time increments on the event's grid that don't overlap any of
+// the busy times. For days only events, days that have any busy time aren't
+// available
+func GetAvailability(event *models.Event, busy []scheduling.TimeRange) []primitive.DateTime {
+	increment := scheduling.GetTimeIncrement(event)
+	if utils.Coalesce(event.DaysOnly) {
+		increment = 24 * time.Hour
+	}
+
+	availability := make([]primitive.DateTime, 0)
+	for _, t := range scheduling.GetTimeIncrements(event) {
+		if !overlaps(busy, t, t.Add(increment)) {
+			availability = append(availability, primitive.NewDateTimeFromTime(t))
+		}
+	}
+	return availability
+}
+
+// Returns the range the event's grid spans, to fetch busy times for
+func GetRange(event *models.Event) (time.Time, time.Time, bool) {
+	increments := scheduling.GetTimeIncrements(event)
+	if len(increments) == 0 {
+		return time.Time{}, time.Time{}, false
+	}
+	increment := scheduling.GetTimeIncrement(event)
+	if utils.Coalesce(event.DaysOnly) {
+		increment = 24 * time.Hour
+	}
+	return increments[0], increments[len(increments)-1].Add(increment), true
+}
+
+func overlaps(ranges []scheduling.TimeRange, start time.Time, end time.Time) bool {
+	for _, r := range ranges {
+		if r.Start.Before(end) && r.End.After(start) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package delegations
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/services/scheduling/schedulingtest"
+)
+
+func TestGetAvailability(t *testing.T) {
+	busy := []scheduling.TimeRange{
+		{Start: schedulingtest.Hour(10), End: schedulingtest.Hour(10).Add(30 * time.Minute)},
+		{Start: schedulingtest.Hour(12), End: schedulingtest.Hour(13)},
+	}
+	availability := GetAvailability(schedulingtest.NewEvent(), busy)
+
+	expected := []time.Time{schedulingtest.Hour(9), schedulingtest.Hour(11)}
+	if len(availability) != len(expected) {
+		t.Fatalf("GetAvailability = %v, expected %v", availability, expected)
+	}
+	for i := range expected {
+		if !availability[i].Time().Equal(expected[i]) {
+			t.Errorf("availability[%d] = %v, expected %v", i, availability[i].Time(), expected[i])
+		}
+	}
+}
+
+func TestGetAvailabilityDaysOnly(t *testing.T) {
+	midnight := time.Date(2024, time.May, 14, 0, 0, 0, 0, time.UTC)
+	daysOnly := true
+	event := &models.Event{
+		DaysOnly: &daysOnly,
+		Dates: []primitive.DateTime{
+			primitive.NewDateTimeFromTime(midnight),
+			primitive.NewDateTimeFromTime(midnight.AddDate(0, 0, 1)),
+		},
+	}
+	busy := []scheduling.TimeRange{{Start: midnight.Add(15 * time.Hour), End: midnight.Add(16 * time.Hour)}}
+
+	availability := GetAvailability(event, busy)
+	if len(availability) != 1 || !availability[0].Time().Equal(midnight.AddDate(0, 0, 1)) {
+		t.Errorf("GetAvailability = %v, expected only the second schedulingtest.Day", availability)
+	}
+
+	start, end, ok := GetRange(event)
+	if !ok || !start.Equal(midnight) || !end.Equal(midnight.AddDate(0, 0, 2)) {
+		t.Errorf("GetRange = %v, %v, %v, expected both days", start, end, ok)
+	}
+}
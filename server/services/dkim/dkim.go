@@ -0,0 +1,156 @@
+// Signs outgoing emails with DKIM (RFC 6376), using rsa-sha256 and relaxed
+// canonicalization of the headers and body, so emails sent from an
+// organization's domain pass DMARC
+package dkim
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Size of the keys generated for new domains
+const KEY_BITS = 2048
+
+// Headers that are signed if the message has them
+var signedHeaders = []string{
+	"from", "to", "cc", "reply-to", "subject", "date", "message-id", "mime-version", "content-type",
+	"list-unsubscribe", "list-unsubscribe-post",
+}
+
+// Signs messages for a domain with the private key of its selector
+type Signer struct {
+	Domain   string
+	Selector string
+	Key      *rsa.PrivateKey
+}
+
+// Returns a new key for a domain, PEM encoded
+func GenerateKey() (string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, KEY_BITS)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})), nil
+}
+
+// Parses a PEM encoded key returned by GenerateKey
+func ParseKey(encoded string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil {
+		return nil, errors.New("invalid dkim key")
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+// Returns the value of the TXT record at <selector>._domainkey.<domain> that
+// publishes the public half of the key
+func GetRecordValue(key *rsa.PrivateKey) (string, error) {
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	return "v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(publicKey), nil
+}
+
+// Canonicalizes a header with the relaxed algorithm: the name is lowercased,
+// folding is removed, and runs of whitespace become a single space
+func canonicalizeHeader(header string) string {
+	name, value, _ := strings.Cut(header, ":")
+	value = strings.ReplaceAll(value, "\r\n", "")
+	value = strings.Join(strings.Fields(value), " ")
+	return strings.ToLower(strings.TrimSpace(name)) + ":" + value + "\r\n"
+}
+
+// Canonicalizes the body with the relaxed algorithm: trailing whitespace is
+// removed from lines, runs of whitespace become a single space, and trailing
+// empty lines are removed
+func canonicalizeBody(body []byte) []byte {
+	lines := strings.Split(strings.ReplaceAll(string(body), "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		lines[i] = strings.Join(strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == '\t' }), " ")
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			lines[i] = " " + lines[i]
+		}
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return []byte{}
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+// Splits a message into its headers, unfolded into one string each with
+// their CRLFs, and its body
+func splitMessage(message []byte) ([]string, []byte, error) {
+	headerEnd := bytes.Index(message, []byte("\r\n\r\n"))
+	if headerEnd == -1 {
+		return nil, nil, errors.New("message has no body")
+	}
+	headers := make([]string, 0)
+	for _, line := range strings.SplitAfter(string(message[:headerEnd+2]), "\r\n") {
+		if len(line) == 0 {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(headers) > 0 {
+			headers[len(headers)-1] += line
+		} else {
+			headers = append(headers, line)
+		}
+	}
+	return headers, message[headerEnd+4:], nil
+}
+
+// Returns the message with a DKIM-Signature header prepended
+func (s *Signer) Sign(message []byte, now time.Time) ([]byte, error) {
+	headers, body, err := splitMessage(message)
+	if err != nil {
+		return nil, err
+	}
+	bodyHash := sha256.Sum256(canonicalizeBody(body))
+
+	// Sign the last occurrence of each header, as verifiers pick headers from
+	// the bottom up
+	names := make([]string, 0)
+	var signed strings.Builder
+	for _, name := range signedHeaders {
+		for i := len(headers) - 1; i >= 0; i-- {
+			headerName, _, _ := strings.Cut(headers[i], ":")
+			if strings.EqualFold(strings.TrimSpace(headerName), name) {
+				names = append(names, name)
+				signed.WriteString(canonicalizeHeader(headers[i]))
+				break
+			}
+		}
+	}
+
+	signatureHeader := fmt.Sprintf(
+		"DKIM-Signature: v=1; a=rsa-sha256; c=relaxed/relaxed; d=%s; s=%s; t=%d; h=%s; bh=%s; b=",
+		s.Domain, s.Selector, now.Unix(), strings.Join(names, ":"), base64.StdEncoding.EncodeToString(bodyHash[:]),
+	)
+	signed.WriteString(strings.TrimSuffix(canonicalizeHeader(signatureHeader), "\r\n"))
+	hash := sha256.Sum256([]byte(signed.String()))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.Key, crypto.SHA256, hash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	var signedMessage bytes.Buffer
+	signedMessage.WriteString(signatureHeader)
+	signedMessage.WriteString(base64.StdEncoding.EncodeToString(signature))
+	signedMessage.WriteString("\r\n")
+	signedMessage.Write(message)
+	return signedMessage.Bytes(), nil
+}
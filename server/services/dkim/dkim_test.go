@@ -0,0 +1,77 @@
+package dkim
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Example from RFC 6376 section 3.4.5
+func TestCanonicalize(t *testing.T) {
+	if got := canonicalizeHeader("A: X\r\n") + canonicalizeHeader("B : Y\t\r\n\tZ  \r\n"); got != "a:X\r\nb:Y Z\r\n" {
+		t.Errorf("got headers %q", got)
+	}
+	if got := string(canonicalizeBody([]byte(" C \r\nD \t E\r\n\r\n\r\n"))); got != " C\r\nD E\r\n" {
+		t.Errorf("got body %q", got)
+	}
+	if got := canonicalizeBody([]byte("\r\n\r\n")); len(got) != 0 {
+		t.Errorf("expected an empty body, got %q", got)
+	}
+}
+
+func TestSign(t *testing.T) {
+	encoded, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParseKey(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := GetRecordValue(key)
+	if err != nil || !strings.HasPrefix(record, "v=DKIM1; k=rsa; p=") {
+		t.Fatalf("got record %q, %v", record, err)
+	}
+
+	message := "From: Acme <invites@acme.com>\r\nTo: ana@example.com\r\nSubject: Standup\r\n\twith folding\r\nX-Other: skipped\r\n\r\nHi Ana,  \r\n\r\n"
+	signer := Signer{Domain: "acme.com", Selector: "timeful", Key: key}
+	signed, err := signer.Sign([]byte(message), time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(signed), message) {
+		t.Fatal("expected the message to be unchanged after the signature")
+	}
+
+	headers, _, err := splitMessage(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signatureHeader := headers[0]
+	for _, want := range []string{"d=acme.com;", "s=timeful;", "t=1700000000;", "h=from:to:subject;"} {
+		if !strings.Contains(signatureHeader, want) {
+			t.Errorf("expected %q in %s", want, signatureHeader)
+		}
+	}
+
+	// Verify the signature the way a receiver would
+	bIndex := strings.LastIndex(signatureHeader, "b=")
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signatureHeader[bIndex+2:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified := canonicalizeHeader(headers[1]) + canonicalizeHeader(headers[2]) + canonicalizeHeader(headers[3]) +
+		strings.TrimSuffix(canonicalizeHeader(signatureHeader[:bIndex+2]), "\r\n")
+	hash := sha256.Sum256([]byte(verified))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], signature); err != nil {
+		t.Errorf("signature doesn't verify: %v", err)
+	}
+	bodyHash := sha256.Sum256([]byte("Hi Ana,\r\n"))
+	if !strings.Contains(signatureHeader, "bh="+base64.StdEncoding.EncodeToString(bodyHash[:])+";") {
+		t.Errorf("unexpected body hash in %s", signatureHeader)
+	}
+}
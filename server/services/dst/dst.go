@@ -0,0 +1,197 @@
+// Audits events that span daylight saving time transitions. The grid,
+// responses, exports and calendar sync all work with UTC instants, so they
+// agree with each other, but local wall times around a transition can be
+// skipped or happen twice, and dates generated with a fixed UTC offset end up
+// an hour off after the transition
+package dst
+
+import (
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// A change of a location's UTC offset
+type Transition struct {
+	At time.Time `json:"at"`
+
+	// Offsets from UTC in seconds before and after the transition
+	OffsetBefore int `json:"offsetBefore"`
+	OffsetAfter  int `json:"offsetAfter"`
+}
+
+// A range of local wall times on a date that is skipped (when clocks spring
+// forward) or happens twice (when clocks fall back)
+type LocalRange struct {
+	Date string `json:"date"` // e.g. "2024-03-10"
+	From string `json:"from"` // e.g. "02:00"
+	To   string `json:"to"`   // e.g. "03:00"
+}
+
+// A date of the event whose local start time differs from the first date's
+type ShiftedDate struct {
+	Date      primitive.DateTime `json:"date"`
+	LocalTime string             `json:"localTime"`
+	Expected  string             `json:"expected"`
+}
+
+// A respondent with availability that isn't on the event's grid
+type OffGridRespondent struct {
+	Id    string `json:"id"`
+	Count int    `json:"count"`
+}
+
+type Report struct {
+	Timezone    string       `json:"timezone"`
+	Transitions []Transition `json:"transitions"`
+
+	// Local wall times within the event's daily windows that don't exist or
+	// are ambiguous because of a transition
+	Nonexistent []LocalRange `json:"nonexistent"`
+	Ambiguous   []LocalRange `json:"ambiguous"`
+
+	ShiftedDates       []ShiftedDate       `json:"shiftedDates"`
+	OffGridRespondents []OffGridRespondent `json:"offGridRespondents"`
+
+	// Whether none of the above problems were found (transitions alone are fine)
+	Ok bool `json:"ok"`
+}
+
+// Returns the transitions of the location between start and end
+func FindTransitions(loc *time.Location, start time.Time, end time.Time) []Transition {
+	transitions := make([]Transition, 0)
+	_, prevOffset := start.In(loc).Zone()
+	prev := start
+	for t := start.Add(time.Hour); !t.After(end.Add(time.Hour)); t = t.Add(time.Hour) {
+		_, offset := t.In(loc).Zone()
+		if offset != prevOffset {
+			// Binary search for the exact instant of the change
+			lo, hi := prev, t
+			for hi.Sub(lo) > time.Second {
+				mid := lo.Add(hi.Sub(lo) / 2)
+				if _, midOffset := mid.In(loc).Zone(); midOffset == prevOffset {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			if !hi.Before(start) && hi.Before(end) {
+				transitions = append(transitions, Transition{At: hi.UTC(), OffsetBefore: prevOffset, OffsetAfter: offset})
+			}
+			prevOffset = offset
+		}
+		prev = t
+	}
+	return transitions
+}
+
+// Returns the local wall times skipped or repeated by the transition, in the location
+func GetLocalRange(transition Transition, loc *time.Location) LocalRange {
+	shift := time.Duration(transition.OffsetAfter-transition.OffsetBefore) * time.Second
+	if shift < 0 {
+		shift = -shift
+	}
+
+	// Wall clock reading just before the transition, in the old offset
+	before := transition.At.In(time.FixedZone("", transition.OffsetBefore))
+	localRange := LocalRange{Date: before.Format("2006-01-02")}
+	if transition.OffsetAfter > transition.OffsetBefore {
+		localRange.From = before.Format("15:04")
+		localRange.To = before.Add(shift).Format("15:04")
+	} else {
+		localRange.From = before.Add(-shift).Format("15:04")
+		localRange.To = before.Format("15:04")
+	}
+	return localRange
+}
+
+// Audits the event in the given location
+func Audit(event *models.Event, eventResponses []models.EventResponse, loc *time.Location) Report {
+	report := Report{
+		Timezone:           loc.String(),
+		Transitions:        make([]Transition, 0),
+		Nonexistent:        make([]LocalRange, 0),
+		Ambiguous:          make([]LocalRange, 0),
+		ShiftedDates:       make([]ShiftedDate, 0),
+		OffGridRespondents: make([]OffGridRespondent, 0),
+	}
+
+	increments := scheduling.GetTimeIncrements(event)
+	if len(increments) == 0 {
+		report.Ok = true
+		return report
+	}
+	increment := scheduling.GetTimeIncrement(event)
+	if utils.Coalesce(event.DaysOnly) {
+		increment = 24 * time.Hour
+	}
+	report.Transitions = FindTransitions(loc, increments[0], increments[len(increments)-1].Add(increment))
+
+	// Transitions within the daily windows of the event
+	window := time.Duration(float64(utils.Coalesce(event.Duration)) * float64(time.Hour))
+	if utils.Coalesce(event.DaysOnly) || utils.Coalesce(event.HasSpecificTimes) {
+		window = 24 * time.Hour
+	}
+	dates := make([]time.Time, 0)
+	for _, date := range event.Dates {
+		dates = append(dates, date.Time())
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	for _, transition := range report.Transitions {
+		for _, date := range dates {
+			if transition.At.Before(date) || !transition.At.Before(date.Add(window)) {
+				continue
+			}
+			if transition.OffsetAfter > transition.OffsetBefore {
+				report.Nonexistent = append(report.Nonexistent, GetLocalRange(transition, loc))
+			} else {
+				report.Ambiguous = append(report.Ambiguous, GetLocalRange(transition, loc))
+			}
+			break
+		}
+	}
+
+	// Dates should all start at the same local time. Days of the week events
+	// are stored in a placeholder week, and days only events at midnight UTC
+	if event.Type != models.DOW && !utils.Coalesce(event.DaysOnly) && len(dates) > 0 {
+		expected := dates[0].In(loc).Format("15:04")
+		for _, date := range dates[1:] {
+			if localTime := date.In(loc).Format("15:04"); localTime != expected {
+				report.ShiftedDates = append(report.ShiftedDates, ShiftedDate{
+					Date:      primitive.NewDateTimeFromTime(date),
+					LocalTime: localTime,
+					Expected:  expected,
+				})
+			}
+		}
+	}
+
+	// Availability should be on the grid, otherwise it isn't counted
+	onGrid := make(models.Set[int64])
+	for _, t := range increments {
+		onGrid[t.UnixMilli()] = struct{}{}
+	}
+	for _, eventResponse := range eventResponses {
+		if eventResponse.Response == nil {
+			continue
+		}
+		count := 0
+		for _, times := range [][]primitive.DateTime{eventResponse.Response.Availability, eventResponse.Response.IfNeeded} {
+			for _, t := range times {
+				if _, ok := onGrid[int64(t)]; !ok {
+					count++
+				}
+			}
+		}
+		if count > 0 {
+			report.OffGridRespondents = append(report.OffGridRespondents, OffGridRespondent{Id: eventResponse.UserId, Count: count})
+		}
+	}
+
+	report.Ok = len(report.Nonexistent) == 0 && len(report.Ambiguous) == 0 && len(report.ShiftedDates) == 0 && len(report.OffGridRespondents) == 0
+	return report
+}
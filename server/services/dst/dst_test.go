@@ -0,0 +1,115 @@
+package dst
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/services/emailpoll"
+	"schej.it/server/services/scheduling"
+)
+
+func newYork(t *testing.T) *time.Location {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+	return loc
+}
+
+// Event from midnight to 4am New York time, over the spring forward transition
+func newEvent(loc *time.Location) *models.Event {
+	duration := float32(4)
+	timeIncrement := 60
+	dates := make([]primitive.DateTime, 0)
+	for _, day := range []int{9, 10, 11} {
+		dates = append(dates, primitive.NewDateTimeFromTime(time.Date(2024, time.March, day, 0, 0, 0, 0, loc)))
+	}
+	return &models.Event{
+		Type:          models.SPECIFIC_DATES,
+		Dates:         dates,
+		Duration:      &duration,
+		TimeIncrement: &timeIncrement,
+	}
+}
+
+func TestFindTransitions(t *testing.T) {
+	loc := newYork(t)
+	transitions := FindTransitions(loc, time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC))
+	if len(transitions) != 2 {
+		t.Fatalf("expected 2 transitions, got %+v", transitions)
+	}
+	if !transitions[0].At.Equal(time.Date(2024, time.March, 10, 7, 0, 0, 0, time.UTC)) || transitions[0].OffsetAfter-transitions[0].OffsetBefore != 3600 {
+		t.Errorf("unexpected spring transition %+v", transitions[0])
+	}
+	if !transitions[1].At.Equal(time.Date(2024, time.November, 3, 6, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected fall transition %+v", transitions[1])
+	}
+
+	if got := GetLocalRange(transitions[0], loc); got != (LocalRange{Date: "2024-03-10", From: "02:00", To: "03:00"}) {
+		t.Errorf("expected 2am to 3am to be skipped, got %+v", got)
+	}
+	if got := GetLocalRange(transitions[1], loc); got != (LocalRange{Date: "2024-11-03", From: "01:00", To: "02:00"}) {
+		t.Errorf("expected 1am to 2am to repeat, got %+v", got)
+	}
+}
+
+func TestSlotGeneration(t *testing.T) {
+	loc := newYork(t)
+	event := newEvent(loc)
+
+	// Each day has 4 real hours, so 4 increments, even on the transition day
+	increments := scheduling.GetTimeIncrements(event)
+	if len(increments) != 12 {
+		t.Fatalf("expected 12 increments, got %d", len(increments))
+	}
+	transitionDay := increments[4:8]
+	if got := transitionDay[2].In(loc).Format("15:04"); got != "03:00" {
+		t.Errorf("expected the third hour of the transition day to be 3am, got %s", got)
+	}
+}
+
+func TestHoldInviteUsesUtc(t *testing.T) {
+	loc := newYork(t)
+	start := time.Date(2024, time.March, 10, 3, 0, 0, 0, loc)
+	slots := []models.EmailPollSlot{{Start: primitive.NewDateTimeFromTime(start), End: primitive.NewDateTimeFromTime(start.Add(time.Hour))}}
+	invite, err := emailpoll.NewHoldInvite("Sync", "Organizer", "reply@timeful.app", models.EmailPollRecipient{Email: "a@example.com", Key: "key"}, 0, slots, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(invite), "DTSTART:20240310T070000Z") {
+		t.Errorf("expected the start in UTC, got %s", invite)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	loc := newYork(t)
+	event := newEvent(loc)
+	responses := []models.EventResponse{
+		{UserId: "a", Response: &models.Response{Availability: event.Dates[:1]}},
+	}
+
+	report := Audit(event, responses, loc)
+	if len(report.Transitions) != 1 || len(report.Nonexistent) != 1 || len(report.Ambiguous) != 0 || len(report.ShiftedDates) != 0 || len(report.OffGridRespondents) != 0 || report.Ok {
+		t.Fatalf("got %+v", report)
+	}
+
+	// Dates generated with a fixed offset are an hour off after the transition,
+	// and so is availability submitted for them
+	event.Dates[2] = primitive.NewDateTimeFromTime(time.Date(2024, time.March, 11, 5, 0, 0, 0, time.UTC))
+	responses[0].Response.Availability = []primitive.DateTime{primitive.NewDateTimeFromTime(time.Date(2024, time.March, 11, 4, 30, 0, 0, time.UTC))}
+	report = Audit(event, responses, loc)
+	if len(report.ShiftedDates) != 1 || report.ShiftedDates[0].LocalTime != "01:00" || report.ShiftedDates[0].Expected != "00:00" {
+		t.Errorf("expected the last date to be shifted, got %+v", report.ShiftedDates)
+	}
+	if len(report.OffGridRespondents) != 1 || report.OffGridRespondents[0].Count != 1 {
+		t.Errorf("expected off grid availability, got %+v", report.OffGridRespondents)
+	}
+
+	// No transitions in UTC
+	if report := Audit(newEvent(time.UTC), nil, time.UTC); !report.Ok || len(report.Transitions) != 0 {
+		t.Errorf("expected no problems in UTC, got %+v", report)
+	}
+}
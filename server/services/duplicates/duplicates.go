@@ -0,0 +1,140 @@
+// Flags guests that likely responded to an event more than once, from
+// hashes of where their responses were submitted from
+package duplicates
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"os"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/services/privacy"
+)
+
+type Sensitivity string
+
+const (
+	LOW    Sensitivity = "low"    // Same IP address and device
+	MEDIUM Sensitivity = "medium" // Same device on the same network
+	HIGH   Sensitivity = "high"   // Same network
+)
+
+// Whether fingerprints are recorded at all. Set DUPLICATE_DETECTION_ENABLED
+// to false to opt out. Instances that minimize personal data never record them
+func Enabled() bool {
+	return os.Getenv("DUPLICATE_DETECTION_ENABLED") != "false" && !privacy.IsMinimized()
+}
+
+// Returns the sensitivity set with DUPLICATE_DETECTION_SENSITIVITY, medium by
+// default
+func GetSensitivity() Sensitivity {
+	switch sensitivity := Sensitivity(strings.ToLower(os.Getenv("DUPLICATE_DETECTION_SENSITIVITY"))); sensitivity {
+	case LOW, HIGH:
+		return sensitivity
+	}
+	return MEDIUM
+}
+
+// Returns the hash of value, keyed with the encryption key and the event so
+// that hashes of different events can't be compared
+func hash(eventId primitive.ObjectID, value string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("ENCRYPTION_KEY")+eventId.Hex()))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// Returns the network the IP address is in, i.e. its IPv4 /24 or IPv6 /48
+func getNetwork(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+	if ipv4 := parsed.To4(); ipv4 != nil {
+		return ipv4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// Returns the fingerprint of a submission to the event, or nil if duplicate
+// detection is turned off
+func NewFingerprint(eventId primitive.ObjectID, ip string, userAgent string, acceptLanguage string) *models.Fingerprint {
+	if !Enabled() {
+		return nil
+	}
+	return &models.Fingerprint{
+		Ip:      hash(eventId, ip),
+		Network: hash(eventId, getNetwork(ip)),
+		Device:  hash(eventId, userAgent+"\n"+acceptLanguage),
+	}
+}
+
+// Whether the two fingerprints likely belong to the same person
+func Matches(a *models.Fingerprint, b *models.Fingerprint, sensitivity Sensitivity) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	switch sensitivity {
+	case LOW:
+		return a.Ip == b.Ip && a.Device == b.Device
+	case HIGH:
+		return a.Network == b.Network
+	}
+	return a.Network == b.Network && a.Device == b.Device
+}
+
+type Respondent struct {
+	Id          string // User id, or name for guests
+	Fingerprint *models.Fingerprint
+}
+
+// Returns the groups of respondents that likely are the same person, in the
+// order they're first seen. Respondents without a fingerprint are never
+// grouped
+func FindDuplicates(respondents []Respondent, sensitivity Sensitivity) [][]string {
+	// Union find, where each respondent points to the first one it matched
+	parents := make([]int, len(respondents))
+	var find func(i int) int
+	find = func(i int) int {
+		if parents[i] != i {
+			parents[i] = find(parents[i])
+		}
+		return parents[i]
+	}
+	for i := range respondents {
+		parents[i] = i
+		for j := 0; j < i; j++ {
+			if Matches(respondents[i].Fingerprint, respondents[j].Fingerprint, sensitivity) {
+				root, other := find(j), find(i)
+				if other < root {
+					root, other = other, root
+				}
+				parents[other] = root
+			}
+		}
+	}
+
+	groupIndexes := make(map[int]int)
+	groups := make([][]string, 0)
+	for i, respondent := range respondents {
+		root := find(i)
+		index, ok := groupIndexes[root]
+		if !ok {
+			index = len(groups)
+			groupIndexes[root] = index
+			groups = append(groups, make([]string, 0))
+		}
+		groups[index] = append(groups[index], respondent.Id)
+	}
+
+	duplicates := make([][]string, 0)
+	for _, group := range groups {
+		if len(group) > 1 {
+			duplicates = append(duplicates, group)
+		}
+	}
+	return duplicates
+}
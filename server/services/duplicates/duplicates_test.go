@@ -0,0 +1,57 @@
+package duplicates
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNewFingerprint(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEY", "test-key")
+	eventId := primitive.NewObjectID()
+
+	a := NewFingerprint(eventId, "203.0.113.5", "Firefox", "en-US")
+	b := NewFingerprint(eventId, "203.0.113.77", "Firefox", "en-US")
+	if a.Ip == b.Ip || a.Network != b.Network || a.Device != b.Device {
+		t.Errorf("got %+v and %+v", a, b)
+	}
+	if a.Ip == "203.0.113.5" {
+		t.Errorf("expected the IP address to be hashed")
+	}
+	if other := NewFingerprint(primitive.NewObjectID(), "203.0.113.5", "Firefox", "en-US"); other.Ip == a.Ip {
+		t.Errorf("expected hashes to differ between events")
+	}
+
+	t.Setenv("DUPLICATE_DETECTION_ENABLED", "false")
+	if fingerprint := NewFingerprint(eventId, "203.0.113.5", "Firefox", "en-US"); fingerprint != nil {
+		t.Errorf("expected no fingerprint when turned off, got %+v", fingerprint)
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEY", "test-key")
+	eventId := primitive.NewObjectID()
+	respondents := []Respondent{
+		{"Alex", NewFingerprint(eventId, "203.0.113.5", "Firefox", "en-US")},
+		{"Sam", NewFingerprint(eventId, "198.51.100.1", "Safari", "en-US")},
+		{"alex", NewFingerprint(eventId, "203.0.113.5", "Firefox", "en-US")},
+		{"Alex2", NewFingerprint(eventId, "203.0.113.9", "Firefox", "en-US")},
+		{"Jo", NewFingerprint(eventId, "203.0.113.20", "Chrome", "de-DE")},
+		{"User", nil},
+	}
+
+	tests := []struct {
+		sensitivity Sensitivity
+		expected    [][]string
+	}{
+		{LOW, [][]string{{"Alex", "alex"}}},
+		{MEDIUM, [][]string{{"Alex", "alex", "Alex2"}}},
+		{HIGH, [][]string{{"Alex", "alex", "Alex2", "Jo"}}},
+	}
+	for _, test := range tests {
+		if got := FindDuplicates(respondents, test.sensitivity); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: got %v, expected %v", test.sensitivity, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Availability polling by email, where invitees respond to proposed slots by
+// replying to the poll email or clicking a link per slot
+package emailpoll
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+// Prefix of the plus address replies are sent to, e.g. create+poll-abc123@timeful.app
+const REPLY_ADDRESS_PREFIX = "poll-"
+
+var (
+	slotNumberRegex = regexp.MustCompile(`\b\d+\b`)
+	allRegex        = regexp.MustCompile(`\b(all|any|every)\b`)
+	noneRegex       = regexp.MustCompile(`\b(none|no|nope|neither|can't|cannot)\b`)
+	quoteStartRegex = regexp.MustCompile(`(?i)^(on .*wrote:|-+ ?original message ?-+|from: .*)$`)
+)
+
+// Returns the address replies from the recipient with the given key are sent to
+func GetReplyAddress(inboundAddress string, key string) string {
+	local, domain, found := strings.Cut(inboundAddress, "@")
+	if !found {
+		return inboundAddress
+	}
+	local, _, _ = strings.Cut(local, "+")
+	return fmt.Sprintf("%s+%s%s@%s", local, REPLY_ADDRESS_PREFIX, key, domain)
+}
+
+// Returns the recipient key in the given reply address, or "" if it isn't one
+func GetReplyKey(address string) string {
+	local, _, _ := strings.Cut(strings.ToLower(address), "@")
+	_, tag, found := strings.Cut(local, "+")
+	if !found || !strings.HasPrefix(tag, REPLY_ADDRESS_PREFIX) {
+		return ""
+	}
+	return strings.TrimPrefix(tag, REPLY_ADDRESS_PREFIX)
+}
+
+// Returns the text the respondent wrote in their reply, without the quoted
+// poll email
+func StripQuotedText(text string) string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, ">") || quoteStartRegex.MatchString(trimmed) {
+			break
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// Parses the slots picked in a reply (e.g. "1 and 3 work for me", "all", or
+// "none of these"), returning their 0-based indexes. Returns false if the
+// reply doesn't say which slots work
+func ParseReply(text string, numSlots int) ([]int, bool) {
+	text = strings.ToLower(StripQuotedText(text))
+
+	selected := make([]int, 0)
+	seen := make(models.Set[int])
+	for _, match := range slotNumberRegex.FindAllString(text, -1) {
+		number, err := strconv.Atoi(match)
+		if err != nil || number < 1 || number > numSlots {
+			continue
+		}
+		if _, ok := seen[number-1]; !ok {
+			seen[number-1] = struct{}{}
+			selected = append(selected, number-1)
+		}
+	}
+	if len(selected) > 0 {
+		sort.Ints(selected)
+		return selected, true
+	}
+
+	if allRegex.MatchString(text) {
+		for i := 0; i < numSlots; i++ {
+			selected = append(selected, i)
+		}
+		return selected, true
+	}
+	if noneRegex.MatchString(text) {
+		return selected, true
+	}
+	return nil, false
+}
+
+// Returns the availability grid times covered by the selected slots
+func GetSlotTimes(slots []models.EmailPollSlot, selected []int, increment time.Duration) []primitive.DateTime {
+	times := make([]primitive.DateTime, 0)
+	seen := make(models.Set[primitive.DateTime])
+	for _, i := range selected {
+		if i < 0 || i >= len(slots) {
+			continue
+		}
+		for t := slots[i].Start.Time(); t.Before(slots[i].End.Time()); t = t.Add(increment) {
+			dateTime := primitive.NewDateTimeFromTime(t)
+			if _, ok := seen[dateTime]; !ok {
+				seen[dateTime] = struct{}{}
+				times = append(times, dateTime)
+			}
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return times
+}
+
+// Returns the indexes of the slots fully covered by the given availability
+func GetSelectedSlots(slots []models.EmailPollSlot, availability []primitive.DateTime, increment time.Duration) []int {
+	available := make(models.Set[primitive.DateTime])
+	for _, t := range availability {
+		available[t] = struct{}{}
+	}
+	selected := make([]int, 0)
+	for i := range slots {
+		times := GetSlotTimes(slots, []int{i}, increment)
+		covered := len(times) > 0
+		for _, t := range times {
+			if _, ok := available[t]; !ok {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			selected = append(selected, i)
+		}
+	}
+	return selected
+}
+
+// Formats the slot in the given location, e.g. "Mon, Mar 2 at 3:00 PM - 4:00 PM"
+func FormatSlot(slot models.EmailPollSlot, loc *time.Location) string {
+	start := slot.Start.Time().In(loc)
+	end := slot.End.Time().In(loc)
+	if start.YearDay() == end.YearDay() && start.Year() == end.Year() {
+		return fmt.Sprintf("%s - %s", start.Format("Mon, Jan 2 at 3:04 PM"), end.Format("3:04 PM MST"))
+	}
+	return fmt.Sprintf("%s - %s", start.Format("Mon, Jan 2 at 3:04 PM"), end.Format("Mon, Jan 2 at 3:04 PM MST"))
+}
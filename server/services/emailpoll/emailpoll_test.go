@@ -0,0 +1,64 @@
+package emailpoll
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func TestReplyAddress(t *testing.T) {
+	address := GetReplyAddress("create@timeful.app", "abc123")
+	if address != "create+poll-abc123@timeful.app" {
+		t.Fatalf("unexpected reply address %q", address)
+	}
+	if key := GetReplyKey(address); key != "abc123" {
+		t.Errorf("expected key abc123, got %q", key)
+	}
+	if key := GetReplyKey("create@timeful.app"); key != "" {
+		t.Errorf("expected no key, got %q", key)
+	}
+	if key := GetReplyKey("create+other@timeful.app"); key != "" {
+		t.Errorf("expected no key, got %q", key)
+	}
+}
+
+func TestParseReply(t *testing.T) {
+	tests := []struct {
+		text     string
+		selected []int
+		ok       bool
+	}{
+		{"1 and 3 work for me", []int{0, 2}, true},
+		{"3, 1, 3", []int{0, 2}, true},
+		{"I can do 2 or 7", []int{1}, true},
+		{"Any of them!", []int{0, 1, 2}, true},
+		{"None of these work, sorry", []int{}, true},
+		{"Thanks!", nil, false},
+		{"2\n\nOn Mon, Mar 2, 2026 at 3:00 PM Timeful wrote:\n> 1. Mon\n> 3. Wed", []int{1}, true},
+	}
+	for _, test := range tests {
+		selected, ok := ParseReply(test.text, 3)
+		if ok != test.ok || !reflect.DeepEqual(selected, test.selected) {
+			t.Errorf("ParseReply(%q) = %v, %v, expected %v, %v", test.text, selected, ok, test.selected, test.ok)
+		}
+	}
+}
+
+func TestSlotTimes(t *testing.T) {
+	start := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	slots := []models.EmailPollSlot{
+		{Start: primitive.NewDateTimeFromTime(start), End: primitive.NewDateTimeFromTime(start.Add(time.Hour))},
+		{Start: primitive.NewDateTimeFromTime(start.Add(30 * time.Minute)), End: primitive.NewDateTimeFromTime(start.Add(90 * time.Minute))},
+	}
+
+	times := GetSlotTimes(slots, []int{0, 1}, 30*time.Minute)
+	if len(times) != 3 {
+		t.Fatalf("expected 3 times, got %d", len(times))
+	}
+	if selected := GetSelectedSlots(slots, times[:2], 30*time.Minute); !reflect.DeepEqual(selected, []int{0}) {
+		t.Errorf("expected only the first slot to be selected, got %v", selected)
+	}
+}
@@ -0,0 +1,123 @@
+package emailpoll
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"schej.it/server/models"
+)
+
+// Answer of a recipient to one of the tentative holds attached to their poll
+// email, sent back by their mail client as an iMIP reply
+type HoldReply struct {
+	Key       string
+	Slot      int
+	Email     string
+	Available bool
+}
+
+// Returns the UID of the hold for the slot of the recipient with the given key
+func GetHoldUid(key string, slot int) string {
+	return fmt.Sprintf("%s-%d@timeful.app", key, slot+1)
+}
+
+// Returns the recipient key and the 0-based slot of the hold with the given
+// UID, or false if it isn't the UID of a hold
+func ParseHoldUid(uid string) (string, int, bool) {
+	local, domain, found := strings.Cut(uid, "@")
+	if !found || domain != "timeful.app" {
+		return "", 0, false
+	}
+	dash := strings.LastIndex(local, "-")
+	if dash == -1 {
+		return "", 0, false
+	}
+	slot, err := strconv.Atoi(local[dash+1:])
+	if err != nil || slot < 1 {
+		return "", 0, false
+	}
+	return local[:dash], slot - 1, true
+}
+
+// Returns an iMIP request for a tentative hold on the slot, which mail clients
+// show with RSVP buttons. RSVPs are sent to the organizer, which is set to the
+// recipient's reply address
+func NewHoldInvite(eventName string, organizerName string, replyAddress string, recipient models.EmailPollRecipient, slot int, slots []models.EmailPollSlot, now time.Time) ([]byte, error) {
+	event := ical.NewEvent()
+	event.Props.SetText(ical.PropUID, GetHoldUid(recipient.Key, slot))
+	event.Props.SetDateTime(ical.PropDateTimeStamp, now.UTC())
+	event.Props.SetDateTime(ical.PropDateTimeStart, slots[slot].Start.Time().UTC())
+	event.Props.SetDateTime(ical.PropDateTimeEnd, slots[slot].End.Time().UTC())
+	event.Props.SetText(ical.PropSummary, fmt.Sprintf("Hold: %s", eventName))
+	event.Props.SetText(ical.PropDescription, fmt.Sprintf("Tentative hold for %s. Accept if this time works for you, or decline if it doesn't.", eventName))
+	event.SetStatus(ical.EventTentative)
+	event.Props.SetText(ical.PropTransparency, "TRANSPARENT")
+
+	organizer := ical.NewProp(ical.PropOrganizer)
+	organizer.Params.Set(ical.ParamCommonName, organizerName)
+	organizer.Value = "mailto:" + replyAddress
+	event.Props.Set(organizer)
+
+	attendee := ical.NewProp(ical.PropAttendee)
+	attendee.Params.Set(ical.ParamParticipationStatus, "NEEDS-ACTION")
+	attendee.Params.Set(ical.ParamRSVP, "TRUE")
+	attendee.Value = "mailto:" + recipient.Email
+	event.Props.Set(attendee)
+
+	cal := ical.NewCalendar()
+	cal.Props.SetText(ical.PropVersion, "2.0")
+	cal.Props.SetText(ical.PropProductID, "-//Timeful//Email poll//EN")
+	cal.Props.SetText(ical.PropMethod, "REQUEST")
+	cal.Children = append(cal.Children, event.Component)
+
+	var buf bytes.Buffer
+	if err := ical.NewEncoder(&buf).Encode(cal); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Parses the answers in an iMIP reply to holds
+func ParseHoldReplies(r io.Reader) ([]HoldReply, error) {
+	cal, err := ical.NewDecoder(r).Decode()
+	if err != nil {
+		return nil, err
+	}
+	if method, _ := cal.Props.Text(ical.PropMethod); !strings.EqualFold(method, "REPLY") {
+		return nil, fmt.Errorf("not a reply")
+	}
+
+	replies := make([]HoldReply, 0)
+	for _, event := range cal.Events() {
+		uid, _ := event.Props.Text(ical.PropUID)
+		key, slot, ok := ParseHoldUid(uid)
+		if !ok {
+			continue
+		}
+		attendee := event.Props.Get(ical.PropAttendee)
+		if attendee == nil {
+			continue
+		}
+
+		reply := HoldReply{
+			Key:   key,
+			Slot:  slot,
+			Email: strings.ToLower(strings.TrimPrefix(strings.ToLower(attendee.Value), "mailto:")),
+		}
+		switch strings.ToUpper(attendee.Params.Get(ical.ParamParticipationStatus)) {
+		case "ACCEPTED", "TENTATIVE":
+			reply.Available = true
+		case "DECLINED":
+			reply.Available = false
+		default:
+			continue
+		}
+		replies = append(replies, reply)
+	}
+	return replies, nil
+}
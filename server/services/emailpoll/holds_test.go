@@ -0,0 +1,52 @@
+package emailpoll
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func TestHoldUid(t *testing.T) {
+	key, slot, ok := ParseHoldUid(GetHoldUid("abc-123", 2))
+	if !ok || key != "abc-123" || slot != 2 {
+		t.Errorf("unexpected parsed uid %q, %d, %v", key, slot, ok)
+	}
+	if _, _, ok := ParseHoldUid("abc-1@example.com"); ok {
+		t.Error("expected uids of other domains to be rejected")
+	}
+}
+
+func TestHoldReplies(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	slots := []models.EmailPollSlot{{
+		Start: primitive.NewDateTimeFromTime(now.Add(24 * time.Hour)),
+		End:   primitive.NewDateTimeFromTime(now.Add(25 * time.Hour)),
+	}}
+	recipient := models.EmailPollRecipient{Email: "ann@example.com", Key: "abc123"}
+	invite, err := NewHoldInvite("Kickoff", "Bob", "create+poll-abc123@timeful.app", recipient, 0, slots, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(invite, []byte("METHOD:REQUEST")) || !bytes.Contains(invite, []byte("mailto:create+poll-abc123@timeful.app")) {
+		t.Fatalf("unexpected invite:\n%s", invite)
+	}
+
+	// Mail clients reply with the same event and the attendee's answer
+	reply := strings.Replace(string(invite), "METHOD:REQUEST", "METHOD:REPLY", 1)
+	reply = strings.Replace(reply, "PARTSTAT=NEEDS-ACTION", "PARTSTAT=ACCEPTED", 1)
+	replies, err := ParseHoldReplies(strings.NewReader(reply))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 1 || replies[0] != (HoldReply{Key: "abc123", Slot: 0, Email: "ann@example.com", Available: true}) {
+		t.Errorf("unexpected replies %+v", replies)
+	}
+
+	if _, err := ParseHoldReplies(bytes.NewReader(invite)); err == nil {
+		t.Error("expected requests to be rejected")
+	}
+}
@@ -0,0 +1,116 @@
+// Custom invite and reminder emails of organizations. Templates are limited
+// HTML, sanitized when they're saved, with {{variables}} that are escaped and
+// filled in when they're sent. Senders fall back to the default email if a
+// template can't be rendered
+package emailtemplates
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+const MAX_SUBJECT_LENGTH = 200
+const MAX_BODY_LENGTH = 20000
+
+var variableRegex = regexp.MustCompile(`\{\{\s*([A-Za-z]+)\s*\}\}`)
+var optOutRegex = regexp.MustCompile(`\{\{\s*optOutUrl\s*\}\}`)
+
+// Variables that each type of template can use, with the value used in previews
+var Variables = map[models.EmailTemplateType]map[string]string{
+	models.EMAIL_TEMPLATE_INVITE: {
+		"inviteeName":      "Ana",
+		"organizerName":    "Sam Lee",
+		"organizationName": "Acme",
+		"eventName":        "Quarterly planning",
+		"eventUrl":         "https://timeful.app/e/example",
+		"message":          "Looking forward to it!",
+		"optOutUrl":        "https://timeful.app/opt-out",
+	},
+	models.EMAIL_TEMPLATE_REMINDER: {
+		"organizerName":    "Sam Lee",
+		"organizationName": "Acme",
+		"eventName":        "Quarterly planning",
+		"eventUrl":         "https://timeful.app/e/example",
+		"optOutUrl":        "https://timeful.app/opt-out",
+	},
+}
+
+// Returns an error if the type doesn't exist or the template uses variables
+// its type doesn't have
+func Validate(template models.EmailTemplate) error {
+	variables, ok := Variables[template.Type]
+	if !ok {
+		return fmt.Errorf("invalid template type %q", template.Type)
+	}
+	if len(strings.TrimSpace(template.Subject)) == 0 || len(strings.TrimSpace(template.Body)) == 0 {
+		return errors.New("subject and body are required")
+	}
+	if len(template.Subject) > MAX_SUBJECT_LENGTH || len(template.Body) > MAX_BODY_LENGTH {
+		return errors.New("template is too long")
+	}
+	for _, text := range []string{template.Subject, template.Body} {
+		for _, match := range variableRegex.FindAllStringSubmatch(text, -1) {
+			if _, ok := variables[match[1]]; !ok {
+				return fmt.Errorf("unknown variable %q", match[1])
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the subject and HTML body of the template with the values filled
+// in. The opt out link is added to the end if the template doesn't have one
+func Render(template models.EmailTemplate, values map[string]string) (string, string, error) {
+	if err := Validate(template); err != nil {
+		return "", "", err
+	}
+	var missing error
+	fill := func(text string, escape func(string) string) string {
+		return variableRegex.ReplaceAllStringFunc(text, func(match string) string {
+			name := variableRegex.FindStringSubmatch(match)[1]
+			value, ok := values[name]
+			if !ok {
+				missing = fmt.Errorf("no value for variable %q", name)
+			}
+			return escape(value)
+		})
+	}
+
+	subject := fill(template.Subject, func(value string) string { return value })
+	subject = strings.Join(strings.Fields(subject), " ")
+	body := template.Body
+	if !optOutRegex.MatchString(body) {
+		body += `<p><a href="{{optOutUrl}}">Opt out of these emails</a></p>`
+	}
+	body = fill(body, html.EscapeString)
+	if missing != nil {
+		return "", "", missing
+	}
+	return subject, body, nil
+}
+
+// Returns a preview of the template, filled in with sample values
+func Preview(template models.EmailTemplate) (string, string, error) {
+	template.Body = Sanitize(template.Body)
+	return Render(template, Variables[template.Type])
+}
+
+// Returns the organization's template of the type, or nil if it has none or
+// isn't premium anymore
+func Get(org *models.Organization, templateType models.EmailTemplateType) *models.EmailTemplate {
+	if org == nil || !utils.Coalesce(org.IsPremium) {
+		return nil
+	}
+	for i := range org.EmailTemplates {
+		if org.EmailTemplates[i].Type == templateType {
+			return &org.EmailTemplates[i]
+		}
+	}
+	return nil
+}
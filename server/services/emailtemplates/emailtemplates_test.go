@@ -0,0 +1,100 @@
+package emailtemplates
+
+import (
+	"strings"
+	"testing"
+
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := map[string]string{
+		`<p>Hi <b>{{inviteeName}}</b></p>`:                               `<p>Hi <b>{{inviteeName}}</b></p>`,
+		`<p onclick="x()" style="color: red">Hi</p>`:                     `<p>Hi</p>`,
+		`<script>alert(1)</script><p>ok</p>`:                             `<p>ok</p>`,
+		`<a href="javascript:alert(1)">x</a>`:                            `<a>x</a>`,
+		`<a href=" {{eventUrl}} " target="_blank">Respond</a>`:           `<a href="{{eventUrl}}">Respond</a>`,
+		`<a href="https://acme.com/?a=1&b=2">Acme</a>`:                   `<a href="https://acme.com/?a=1&amp;b=2">Acme</a>`,
+		`<img src="https://tracker.example.com/p.gif"><table>Hi</table>`: `Hi`,
+		`Fish &amp; chips <3`:                                            `Fish &amp; chips &lt;3`,
+	}
+	for body, expected := range tests {
+		if sanitized := Sanitize(body); sanitized != expected {
+			t.Errorf("Sanitize(%q) = %q, expected %q", body, sanitized, expected)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := models.EmailTemplate{Type: models.EMAIL_TEMPLATE_REMINDER, Subject: "Reminder: {{eventName}}", Body: "<p>{{ organizerName }} is waiting</p>"}
+	if err := Validate(valid); err != nil {
+		t.Error(err)
+	}
+
+	invalid := []models.EmailTemplate{
+		{Type: "other", Subject: "Hi", Body: "Hi"},
+		{Type: models.EMAIL_TEMPLATE_REMINDER, Subject: "Hi {{inviteeName}}", Body: "Hi"},
+		{Type: models.EMAIL_TEMPLATE_INVITE, Subject: "", Body: "Hi"},
+		{Type: models.EMAIL_TEMPLATE_INVITE, Subject: strings.Repeat("a", MAX_SUBJECT_LENGTH+1), Body: "Hi"},
+	}
+	for _, template := range invalid {
+		if Validate(template) == nil {
+			t.Errorf("expected %+v to be invalid", template)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	template := models.EmailTemplate{
+		Type:    models.EMAIL_TEMPLATE_INVITE,
+		Subject: "{{organizerName}} invited you to {{eventName}}",
+		Body:    `<p>Hi {{inviteeName}},</p><p><a href="{{eventUrl}}">Respond</a></p>`,
+	}
+	values := map[string]string{
+		"inviteeName":   "<Ana>",
+		"organizerName": "Sam",
+		"eventName":     "Planning\r\nBcc: x@example.com",
+		"eventUrl":      "https://timeful.app/e/1?a=1&b=2",
+		"optOutUrl":     "https://timeful.app/opt-out",
+	}
+	subject, body, err := Render(template, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "Sam invited you to Planning Bcc: x@example.com" {
+		t.Errorf("expected the subject to be on one line, got %q", subject)
+	}
+	for _, want := range []string{"Hi &lt;Ana&gt;,", `href="https://timeful.app/e/1?a=1&amp;b=2"`, `href="https://timeful.app/opt-out"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in %s", want, body)
+		}
+	}
+
+	// A variable without a value makes rendering fail, so the default is sent
+	delete(values, "inviteeName")
+	if _, _, err := Render(template, values); err == nil {
+		t.Error("expected an error for a missing value")
+	}
+}
+
+func TestPreview(t *testing.T) {
+	subject, body, err := Preview(models.EmailTemplate{Type: models.EMAIL_TEMPLATE_REMINDER, Subject: "Reminder: {{eventName}}", Body: `<p>Opt out: <a href="{{optOutUrl}}">here</a></p><script>x</script>`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "Reminder: Quarterly planning" || strings.Contains(body, "script") || strings.Count(body, "opt-out") != 1 {
+		t.Errorf("got %q, %q", subject, body)
+	}
+}
+
+func TestGet(t *testing.T) {
+	org := &models.Organization{EmailTemplates: []models.EmailTemplate{{Type: models.EMAIL_TEMPLATE_INVITE}}}
+	if Get(org, models.EMAIL_TEMPLATE_INVITE) != nil {
+		t.Error("expected templates not to be used by organizations that aren't premium")
+	}
+	org.IsPremium = utils.TruePtr()
+	if Get(org, models.EMAIL_TEMPLATE_INVITE) == nil || Get(org, models.EMAIL_TEMPLATE_REMINDER) != nil || Get(nil, models.EMAIL_TEMPLATE_INVITE) != nil {
+		t.Error("expected only the organization's invite template")
+	}
+}
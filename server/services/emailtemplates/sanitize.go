@@ -0,0 +1,82 @@
+package emailtemplates
+
+import (
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Tags templates can use. Others are removed, keeping their text
+var allowedTags = map[atom.Atom]bool{
+	atom.A: true, atom.B: true, atom.Strong: true, atom.I: true, atom.Em: true, atom.U: true,
+	atom.P: true, atom.Br: true, atom.Hr: true, atom.Div: true, atom.Span: true, atom.Blockquote: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.H1: true, atom.H2: true, atom.H3: true,
+}
+
+// Tags whose content is removed along with them
+var removedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Head: true, atom.Title: true, atom.Iframe: true,
+	atom.Object: true, atom.Embed: true, atom.Svg: true, atom.Math: true, atom.Template: true,
+}
+
+var urlVariableRegex = regexp.MustCompile(`^\{\{\s*(eventUrl|optOutUrl)\s*\}\}$`)
+
+// Returns whether the link can be used in a template: web and mailto links,
+// and the url variables
+func isAllowedHref(href string) bool {
+	href = strings.TrimSpace(href)
+	lower := strings.ToLower(href)
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://") ||
+		strings.HasPrefix(lower, "mailto:") || urlVariableRegex.MatchString(href)
+}
+
+// Returns the HTML with only the allowed tags, and no attributes other than
+// the href of links
+func Sanitize(body string) string {
+	tokenizer := html.NewTokenizer(strings.NewReader(body))
+	var sanitized strings.Builder
+	removing := 0
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			if tokenizer.Err() == io.EOF {
+				break
+			}
+			return sanitized.String()
+		}
+		token := tokenizer.Token()
+
+		switch tokenType {
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			if removedTags[token.DataAtom] {
+				if tokenType == html.StartTagToken {
+					removing++
+				} else if tokenType == html.EndTagToken && removing > 0 {
+					removing--
+				}
+				continue
+			}
+			if removing > 0 || !allowedTags[token.DataAtom] {
+				continue
+			}
+			attrs := make([]html.Attribute, 0)
+			if token.DataAtom == atom.A && tokenType != html.EndTagToken {
+				for _, attr := range token.Attr {
+					if attr.Key == "href" && isAllowedHref(attr.Val) {
+						attrs = append(attrs, html.Attribute{Key: "href", Val: strings.TrimSpace(attr.Val)})
+					}
+				}
+			}
+			token.Attr = attrs
+			sanitized.WriteString(token.String())
+		case html.TextToken:
+			if removing == 0 {
+				sanitized.WriteString(token.String())
+			}
+		}
+	}
+	return sanitized.String()
+}
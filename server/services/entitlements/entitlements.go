@@ -0,0 +1,134 @@
+// What each plan is entitled to, and metering of the quotas that are enforced
+// softly: going over a quota slows the user down (API requests are rejected
+// with 429, webhook deliveries are postponed, and event descriptions are parsed
+// without the LLM) until the window resets, rather than failing anything for
+// good
+package entitlements
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+type Plan string
+
+const (
+	PLAN_FREE    Plan = "free"
+	PLAN_PREMIUM Plan = "premium"
+)
+
+// Something metered against a quota
+type Metric string
+
+const (
+	METRIC_API_REQUESTS       Metric = "apiRequests"
+	METRIC_WEBHOOK_DELIVERIES Metric = "webhookDeliveries"
+	METRIC_LLM_EVENT_PARSES   Metric = "llmEventParses"
+)
+
+// How long the window each metric is counted over lasts
+var windows = map[Metric]time.Duration{
+	METRIC_API_REQUESTS:       time.Minute,
+	METRIC_WEBHOOK_DELIVERIES: 24 * time.Hour,
+	METRIC_LLM_EVENT_PARSES:   24 * time.Hour,
+}
+
+// Quotas of each plan, per window
+var quotas = map[Plan]map[Metric]int{
+	PLAN_FREE: {
+		METRIC_API_REQUESTS:       60,
+		METRIC_WEBHOOK_DELIVERIES: 500,
+		METRIC_LLM_EVENT_PARSES:   20,
+	},
+	PLAN_PREMIUM: {
+		METRIC_API_REQUESTS:       600,
+		METRIC_WEBHOOK_DELIVERIES: 20000,
+		METRIC_LLM_EVENT_PARSES:   500,
+	},
+}
+
+// Usage of a metric in the current window
+type Status struct {
+	Limit     int       `json:"limit"`
+	Used      int       `json:"used"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"resetAt"`
+}
+
+// Returns whether the quota has been used up
+func (s Status) Exceeded() bool {
+	return s.Used > s.Limit
+}
+
+// Returns the plan of the user, which is premium if they or one of the given
+// organizations (the ones they're a member of) pay for premium
+func GetPlan(user *models.User, orgs []models.Organization) Plan {
+	if utils.Coalesce(user.IsPremium) {
+		return PLAN_PREMIUM
+	}
+	for _, org := range orgs {
+		if utils.Coalesce(org.IsPremium) {
+			return PLAN_PREMIUM
+		}
+	}
+	return PLAN_FREE
+}
+
+// Returns the plan of the user, looking up their organizations
+func GetUserPlan(user *models.User) Plan {
+	if utils.Coalesce(user.IsPremium) {
+		return PLAN_PREMIUM
+	}
+	return GetPlan(user, db.GetOrganizationsByUserId(user.Id))
+}
+
+// Returns the quota of the metric per window on the plan
+func GetQuota(plan Plan, metric Metric) int {
+	return quotas[plan][metric]
+}
+
+// Returns when the window of the metric that now falls in starts and ends
+func GetWindow(metric Metric, now time.Time) (time.Time, time.Time) {
+	length := windows[metric]
+	start := now.UTC().Truncate(length)
+	return start, start.Add(length)
+}
+
+func getCounterId(metric Metric, userId primitive.ObjectID, windowStart time.Time) string {
+	return fmt.Sprintf("%s:%s:%d", metric, userId.Hex(), windowStart.Unix())
+}
+
+func getStatus(plan Plan, metric Metric, used int, resetAt time.Time) Status {
+	limit := GetQuota(plan, metric)
+	remaining := limit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Status{Limit: limit, Used: used, Remaining: remaining, ResetAt: resetAt}
+}
+
+// Counts one use of the metric by the user and returns their usage, including
+// it. The use should be refused if the quota is exceeded
+func Consume(user *models.User, plan Plan, metric Metric, now time.Time) Status {
+	start, end := GetWindow(metric, now)
+	used := db.IncrementUsageCounter(getCounterId(metric, user.Id, start), end)
+	return getStatus(plan, metric, used, end)
+}
+
+// Returns the user's usage of the metric, without counting a use
+func GetStatus(user *models.User, plan Plan, metric Metric, now time.Time) Status {
+	start, end := GetWindow(metric, now)
+	used := db.GetUsageCount(getCounterId(metric, user.Id, start))
+	return getStatus(plan, metric, used, end)
+}
+
+// Deletes the counters of windows that have ended. Run periodically by the
+// jobs scheduler
+func DeleteExpiredCounters(now time.Time) {
+	db.DeleteExpiredUsageCounters(now)
+}
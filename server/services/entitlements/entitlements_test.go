@@ -0,0 +1,58 @@
+package entitlements
+
+import (
+	"testing"
+	"time"
+
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+func TestGetPlan(t *testing.T) {
+	free := &models.User{}
+	premium := &models.User{IsPremium: utils.TruePtr()}
+	premiumOrg := models.Organization{IsPremium: utils.TruePtr()}
+
+	if plan := GetPlan(free, nil); plan != PLAN_FREE {
+		t.Errorf("expected free, got %s", plan)
+	}
+	if plan := GetPlan(premium, nil); plan != PLAN_PREMIUM {
+		t.Errorf("expected premium, got %s", plan)
+	}
+	if plan := GetPlan(free, []models.Organization{{}, premiumOrg}); plan != PLAN_PREMIUM {
+		t.Errorf("expected members of premium organizations to be premium, got %s", plan)
+	}
+}
+
+func TestGetQuota(t *testing.T) {
+	for _, metric := range []Metric{METRIC_API_REQUESTS, METRIC_WEBHOOK_DELIVERIES, METRIC_LLM_EVENT_PARSES} {
+		if GetQuota(PLAN_FREE, metric) <= 0 || GetQuota(PLAN_PREMIUM, metric) <= GetQuota(PLAN_FREE, metric) {
+			t.Errorf("expected premium to get a larger %s quota than free", metric)
+		}
+	}
+}
+
+func TestGetWindow(t *testing.T) {
+	now := time.Date(2024, 3, 5, 14, 7, 42, 0, time.FixedZone("", -5*60*60))
+
+	start, end := GetWindow(METRIC_API_REQUESTS, now)
+	if !start.Equal(time.Date(2024, 3, 5, 19, 7, 0, 0, time.UTC)) || end.Sub(start) != time.Minute {
+		t.Errorf("got %v to %v", start, end)
+	}
+	start, end = GetWindow(METRIC_WEBHOOK_DELIVERIES, now)
+	if !start.Equal(time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("got %v to %v", start, end)
+	}
+}
+
+func TestGetStatus(t *testing.T) {
+	resetAt := time.Now()
+	limit := GetQuota(PLAN_FREE, METRIC_API_REQUESTS)
+
+	if status := getStatus(PLAN_FREE, METRIC_API_REQUESTS, limit, resetAt); status.Exceeded() || status.Remaining != 0 {
+		t.Errorf("expected the last request of the quota to be allowed, got %+v", status)
+	}
+	if status := getStatus(PLAN_FREE, METRIC_API_REQUESTS, limit+5, resetAt); !status.Exceeded() || status.Remaining != 0 {
+		t.Errorf("expected the quota to be exceeded, got %+v", status)
+	}
+}
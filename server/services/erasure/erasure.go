@@ -0,0 +1,71 @@
+// Reports of data subject erasures, signed so that they can later be shown to
+// have been issued by this instance and not altered since
+package erasure
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+)
+
+// What was done with the records of a data subject. The email is only kept as
+// a hash, so the report itself doesn't identify them
+type Report struct {
+	EmailHash   string            `json:"emailHash"`
+	RequestedBy string            `json:"requestedBy"`
+	Reference   string            `json:"reference,omitempty"`
+	ErasedAt    time.Time         `json:"erasedAt"`
+	Deleted     map[string]int    `json:"deleted"`
+	Anonymized  map[string]int    `json:"anonymized"`
+	Retained    map[string]string `json:"retained"`
+	Signature   string            `json:"signature"`
+}
+
+func getSecret() []byte {
+	return []byte(os.Getenv("ENCRYPTION_KEY"))
+}
+
+// Returns the hash the email is referred to by in reports
+func HashEmail(email string) string {
+	hash := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(hash[:])
+}
+
+func computeSignature(report Report) string {
+	report.Signature = ""
+	data, err := json.Marshal(report)
+	if err != nil {
+		return ""
+	}
+	mac := hmac.New(sha256.New, getSecret())
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Returns a signed report of the erasure of the records of the given email
+func NewReport(email string, requestedBy string, reference string, deleted map[string]int, anonymized map[string]int, retained map[string]string, now time.Time) Report {
+	report := Report{
+		EmailHash:   HashEmail(email),
+		RequestedBy: requestedBy,
+		Reference:   reference,
+		ErasedAt:    now.UTC().Truncate(time.Second),
+		Deleted:     deleted,
+		Anonymized:  anonymized,
+		Retained:    retained,
+	}
+	report.Signature = computeSignature(report)
+	return report
+}
+
+// Returns whether the report was signed by this instance and hasn't been
+// altered since
+func Verify(report Report) bool {
+	if len(report.Signature) == 0 {
+		return false
+	}
+	return hmac.Equal([]byte(report.Signature), []byte(computeSignature(report)))
+}
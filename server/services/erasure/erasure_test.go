@@ -0,0 +1,39 @@
+package erasure
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestReport(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEY", "test-key")
+	report := NewReport("Ana@Example.com ", "admin@example.com", "ticket-1", map[string]int{"users": 1}, map[string]int{"payments": 2}, map[string]string{"notificationOptOuts": "suppression list"}, time.Now())
+
+	if report.EmailHash != HashEmail("ana@example.com") {
+		t.Error("expected the email to be normalized before hashing")
+	}
+	if !Verify(report) {
+		t.Fatal("expected report to verify")
+	}
+
+	// Survives a round trip through JSON, as it would when downloaded
+	data, _ := json.Marshal(report)
+	var decoded Report
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(decoded) {
+		t.Error("expected decoded report to verify")
+	}
+
+	decoded.Deleted["users"] = 0
+	if Verify(decoded) {
+		t.Error("expected altered report not to verify")
+	}
+
+	t.Setenv("ENCRYPTION_KEY", "other-key")
+	if Verify(report) {
+		t.Error("expected report signed with another key not to verify")
+	}
+}
@@ -0,0 +1,471 @@
+// Rules-based parser that turns free text (e.g. "30 min sync next week, weekday
+// evenings") into a structured event draft
+package eventparser
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Structured event draft parsed from free text
+type Draft struct {
+	Name string `json:"name"`
+
+	// Length of the meeting in minutes
+	MeetingLength int `json:"meetingLength"`
+
+	// Candidate days, at midnight in the location of the reference time
+	Dates []time.Time `json:"dates"`
+
+	// Time range to poll on each day, in hours (e.g. 17.5 = 5:30pm)
+	StartHour float64 `json:"startHour"`
+	EndHour   float64 `json:"endHour"`
+
+	// Whether to only poll for days, not times
+	DaysOnly bool `json:"daysOnly"`
+}
+
+const (
+	defaultMeetingLength = 60
+	defaultStartHour     = 9
+	defaultEndHour       = 17
+	defaultNumDays       = 7
+)
+
+// Named parts of the day and the time ranges they correspond to
+var partsOfDay = []struct {
+	regex     *regexp.Regexp
+	startHour float64
+	endHour   float64
+
+	// Whether the matched text should be kept in the name (e.g. "Team lunch")
+	keepInName bool
+}{
+	{regexp.MustCompile(`(?i)\b(business|work|working|office) hours\b`), 9, 17, false},
+	{regexp.MustCompile(`(?i)\bmornings?\b`), 8, 12, false},
+	{regexp.MustCompile(`(?i)\b(lunchtime|midday)\b`), 11, 14, false},
+	{regexp.MustCompile(`(?i)\blunch\b`), 11, 14, true},
+	{regexp.MustCompile(`(?i)\bafternoons?\b`), 12, 17, false},
+	{regexp.MustCompile(`(?i)\bevenings?\b`), 17, 21, false},
+	{regexp.MustCompile(`(?i)\bnights?\b`), 19, 23, false},
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday, "sundays": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday, "mondays": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday, "tuesdays": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday, "wednesdays": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday, "thursdays": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday, "fridays": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday, "saturdays": time.Saturday,
+}
+
+var monthNames = map[string]time.Month{
+	"jan": time.January, "january": time.January,
+	"feb": time.February, "february": time.February,
+	"mar": time.March, "march": time.March,
+	"apr": time.April, "april": time.April,
+	"may": time.May,
+	"jun": time.June, "june": time.June,
+	"jul": time.July, "july": time.July,
+	"aug": time.August, "august": time.August,
+	"sep": time.September, "sept": time.September, "september": time.September,
+	"oct": time.October, "october": time.October,
+	"nov": time.November, "november": time.November,
+	"dec": time.December, "december": time.December,
+}
+
+var (
+	meetingLengthRegex = regexp.MustCompile(`(?i)\b(\d+(?:\.\d+)?)\s*-?\s*(minutes?|mins?|m|hours?|hrs?|h)\b(?:\s*-?\s*long)?`)
+	halfHourRegex      = regexp.MustCompile(`(?i)\b(half an hour|half hour|half-hour)\b(?:\s*-?\s*long)?`)
+	oneHourRegex       = regexp.MustCompile(`(?i)\b(an|one) hour\b(?:\s*-?\s*long)?`)
+	daysOnlyRegex      = regexp.MustCompile(`(?i)\b(all[ -]day|days only|full[ -]day)\b`)
+
+	timeRangeRegex = regexp.MustCompile(`(?i)\b(?:between |from )?(\d{1,2})(?::(\d{2}))?\s*(am|pm)?\s*(?:-|to|and|until|till)\s*(\d{1,2})(?::(\d{2}))?\s*(am|pm)\b`)
+	afterRegex     = regexp.MustCompile(`(?i)\bafter (\d{1,2})(?::(\d{2}))?\s*(am|pm)?\b`)
+	beforeRegex    = regexp.MustCompile(`(?i)\bbefore (\d{1,2}(?::\d{2})?\s*(?:am|pm)?|noon)\b`)
+
+	nextNDaysRegex   = regexp.MustCompile(`(?i)\b(?:in )?(?:the )?(?:next|coming) (\d+) days\b`)
+	weekRegex        = regexp.MustCompile(`(?i)\b(this|next|the following) week\b`)
+	weekendRegex     = regexp.MustCompile(`(?i)\b(this|next) weekend\b`)
+	monthRegex       = regexp.MustCompile(`(?i)\b(this|next) month\b`)
+	dayAfterRegex    = regexp.MustCompile(`(?i)\b(the )?day after tomorrow\b`)
+	tomorrowRegex    = regexp.MustCompile(`(?i)\btomorrow\b`)
+	todayRegex       = regexp.MustCompile(`(?i)\b(today|tonight)\b`)
+	weekdaysRegex    = regexp.MustCompile(`(?i)\bweekdays?\b`)
+	weekendsRegex    = regexp.MustCompile(`(?i)\bweekends?\b`)
+	monthDayRegex    = regexp.MustCompile(`(?i)\b(jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sept?(?:ember)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)\.? (\d{1,2})(?:st|nd|rd|th)?\b`)
+	weekdayNameRegex = regexp.MustCompile(`(?i)\b(?:(next|this|on) )?(sundays?|mondays?|tues?(?:days?)?|wed(?:nesdays?)?|thu(?:rs?)?(?:days?)?|fri(?:days?)?|sat(?:urdays?)?|sun|mon)\b`)
+
+	fillerRegex      = regexp.MustCompile(`(?i)\b(a|an|the|for|on|at|in|with|during|between|sometime|some time|or|and|to|from|let'?s|schedule|plan|please|next|this|every|each|time|times|any|over|of|within)\b`)
+	punctuationRegex = regexp.MustCompile(`[,.;:!?()\-/]+`)
+	whitespaceRegex  = regexp.MustCompile(`\s+`)
+)
+
+// Parses the given text into an event draft. Relative dates (e.g. "next week")
+// are resolved relative to now, and the draft's dates are in now's location
+func Parse(text string, now time.Time) Draft {
+	p := &parser{text: text}
+
+	draft := Draft{
+		MeetingLength: p.parseMeetingLength(),
+		DaysOnly:      p.match(daysOnlyRegex) != nil,
+	}
+	draft.StartHour, draft.EndHour = p.parseTimeRange()
+	draft.Dates = p.parseDates(now)
+	draft.Name = p.remainingName()
+
+	return draft
+}
+
+type parser struct {
+	text string
+
+	// Byte ranges of the text that have been consumed by a rule
+	consumed [][2]int
+}
+
+// Returns the submatches of the first match of the regex in the text that
+// hasn't been consumed yet, marking it as consumed
+func (p *parser) match(regex *regexp.Regexp) []string {
+	for _, loc := range regex.FindAllStringSubmatchIndex(p.text, -1) {
+		if p.isConsumed(loc[0], loc[1]) {
+			continue
+		}
+		p.consumed = append(p.consumed, [2]int{loc[0], loc[1]})
+
+		submatches := make([]string, len(loc)/2)
+		for i := range submatches {
+			if loc[2*i] >= 0 {
+				submatches[i] = p.text[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		return submatches
+	}
+	return nil
+}
+
+// Returns whether the regex matches text that hasn't been consumed yet, without consuming it
+func (p *parser) peek(regex *regexp.Regexp) bool {
+	for _, loc := range regex.FindAllStringIndex(p.text, -1) {
+		if !p.isConsumed(loc[0], loc[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Same as match, but returns all the matches
+func (p *parser) matchAll(regex *regexp.Regexp) [][]string {
+	matches := make([][]string, 0)
+	for m := p.match(regex); m != nil; m = p.match(regex) {
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+func (p *parser) isConsumed(start int, end int) bool {
+	for _, c := range p.consumed {
+		if start < c[1] && end > c[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the meeting length in minutes
+func (p *parser) parseMeetingLength() int {
+	if p.match(halfHourRegex) != nil {
+		return 30
+	}
+	if p.match(oneHourRegex) != nil {
+		return 60
+	}
+	if m := p.match(meetingLengthRegex); m != nil {
+		amount, _ := strconv.ParseFloat(m[1], 64)
+		if strings.HasPrefix(strings.ToLower(m[2]), "h") {
+			return int(amount * 60)
+		}
+		return int(amount)
+	}
+	return defaultMeetingLength
+}
+
+// Returns the time range to poll on each day
+func (p *parser) parseTimeRange() (float64, float64) {
+	if m := p.match(timeRangeRegex); m != nil {
+		endMeridiem := strings.ToLower(m[6])
+		startMeridiem := strings.ToLower(m[3])
+		end := toHour(m[4], m[5], endMeridiem)
+		start := toHour(m[1], m[2], startMeridiem)
+		if startMeridiem == "" {
+			// "2-5pm" means 2pm - 5pm, but "11-1pm" means 11am - 1pm
+			start = toHour(m[1], m[2], endMeridiem)
+			if start > end {
+				start -= 12
+			}
+		}
+		if start < end {
+			return start, end
+		}
+	}
+
+	if m := p.match(afterRegex); m != nil {
+		start := toHour(m[1], m[2], strings.ToLower(m[3]))
+		if m[3] == "" && start <= 7 {
+			// "after 3" means 3pm
+			start += 12
+		}
+		end := float64(defaultEndHour)
+		if start >= defaultEndHour-1 {
+			end = 22
+		}
+		if start < end {
+			return start, end
+		}
+	}
+
+	if m := p.match(beforeRegex); m != nil {
+		var end float64
+		if strings.ToLower(m[1]) == "noon" {
+			end = 12
+		} else {
+			hour, minute, meridiem := splitTime(m[1])
+			end = toHour(hour, minute, meridiem)
+		}
+		start := float64(defaultStartHour)
+		if end <= start {
+			start = end - 3
+		}
+		if start >= 0 && start < end {
+			return start, end
+		}
+	}
+
+	// Combine all the parts of day mentioned, e.g. "mornings or afternoons"
+	start, end := -1.0, -1.0
+	for _, part := range partsOfDay {
+		matched := false
+		if part.keepInName {
+			matched = p.peek(part.regex)
+		} else {
+			matched = len(p.matchAll(part.regex)) > 0
+		}
+		if matched {
+			if start < 0 || part.startHour < start {
+				start = part.startHour
+			}
+			if end < 0 || part.endHour > end {
+				end = part.endHour
+			}
+		}
+	}
+	if start >= 0 {
+		return start, end
+	}
+
+	return defaultStartHour, defaultEndHour
+}
+
+// Returns the candidate days
+func (p *parser) parseDates(now time.Time) []time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	// Weekdays mentioned by name either filter a range ("next week, mondays")
+	// or select the next occurrence of that day ("next friday")
+	weekdays := make(map[time.Weekday]bool)
+	onlyNext := false
+	for _, m := range p.matchAll(weekdayNameRegex) {
+		name := strings.ToLower(m[2])
+		if weekday, ok := weekdayNames[name]; ok {
+			weekdays[weekday] = true
+		}
+		if strings.ToLower(m[1]) == "next" {
+			onlyNext = true
+		}
+	}
+	var dates []time.Time
+	if m := p.match(nextNDaysRegex); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if n > 0 && n <= 60 {
+			dates = dateRange(today.AddDate(0, 0, 1), n)
+		}
+	}
+	if dates == nil {
+		if m := p.match(weekendRegex); m != nil {
+			saturday := nextWeekday(today, time.Saturday, false)
+			if strings.ToLower(m[1]) == "next" && today.Weekday() != time.Saturday && today.Weekday() != time.Sunday {
+				saturday = saturday.AddDate(0, 0, 7)
+			}
+			if today.Weekday() == time.Sunday && strings.ToLower(m[1]) == "this" {
+				dates = []time.Time{today}
+			} else {
+				dates = dateRange(saturday, 2)
+			}
+		}
+	}
+	if dates == nil {
+		if m := p.match(weekRegex); m != nil {
+			if strings.ToLower(m[1]) == "this" {
+				dates = dateRange(today, 7-(int(today.Weekday())+6)%7)
+			} else {
+				dates = dateRange(startOfWeek(today).AddDate(0, 0, 7), 7)
+			}
+		}
+	}
+	if dates == nil {
+		if m := p.match(monthRegex); m != nil {
+			firstOfNextMonth := time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, today.Location())
+			if strings.ToLower(m[1]) == "this" {
+				dates = dateRange(today, daysInMonth(today)-today.Day()+1)
+			} else {
+				dates = dateRange(firstOfNextMonth, daysInMonth(firstOfNextMonth))
+			}
+		}
+	}
+	if dates == nil && p.match(dayAfterRegex) != nil {
+		dates = []time.Time{today.AddDate(0, 0, 2)}
+	}
+	if dates == nil && p.match(tomorrowRegex) != nil {
+		dates = []time.Time{today.AddDate(0, 0, 1)}
+	}
+	if dates == nil && p.match(todayRegex) != nil {
+		dates = []time.Time{today}
+	}
+	if dates == nil {
+		for _, m := range p.matchAll(monthDayRegex) {
+			month := monthNames[strings.ToLower(m[1])]
+			day, _ := strconv.Atoi(m[2])
+			date := time.Date(today.Year(), month, day, 0, 0, 0, 0, today.Location())
+			if date.Month() != month {
+				// Invalid day, e.g. feb 30
+				continue
+			}
+			if date.Before(today) {
+				date = date.AddDate(1, 0, 0)
+			}
+			dates = append(dates, date)
+		}
+	}
+	if dates == nil && len(weekdays) > 0 {
+		// Next occurrence of each weekday mentioned
+		for weekday := range weekdays {
+			dates = append(dates, nextWeekday(today, weekday, onlyNext))
+		}
+		weekdays = nil
+	}
+	if dates == nil {
+		dates = dateRange(today.AddDate(0, 0, 1), defaultNumDays)
+	}
+
+	// Apply filters
+	weekdaysOnly := p.match(weekdaysRegex) != nil
+	weekendsOnly := !weekdaysOnly && p.match(weekendsRegex) != nil
+	filtered := make([]time.Time, 0)
+	for _, date := range dates {
+		weekday := date.Weekday()
+		isWeekend := weekday == time.Saturday || weekday == time.Sunday
+		if weekdaysOnly && isWeekend || weekendsOnly && !isWeekend {
+			continue
+		}
+		if len(weekdays) > 0 && !weekdays[date.Weekday()] {
+			continue
+		}
+		filtered = append(filtered, date)
+	}
+	if len(filtered) == 0 {
+		// Filters excluded everything, so ignore them rather than return nothing
+		filtered = dates
+	}
+
+	sort.Slice(filtered, func(i, j int) bool { return filtered[i].Before(filtered[j]) })
+	return filtered
+}
+
+// Returns the text that wasn't consumed by any rule, cleaned up to be used as
+// the event name
+func (p *parser) remainingName() string {
+	var sb strings.Builder
+	for i := 0; i < len(p.text); i++ {
+		if p.isConsumed(i, i+1) {
+			sb.WriteByte(' ')
+		} else {
+			sb.WriteByte(p.text[i])
+		}
+	}
+
+	name := punctuationRegex.ReplaceAllString(sb.String(), " ")
+	name = fillerRegex.ReplaceAllString(name, " ")
+	name = strings.TrimSpace(whitespaceRegex.ReplaceAllString(name, " "))
+	if name == "" {
+		return "New event"
+	}
+
+	// Capitalize the first letter
+	first, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(first)) + name[size:]
+}
+
+// Converts the given hour, minute, and meridiem (am/pm/"") strings to hours
+func toHour(hourString string, minuteString string, meridiem string) float64 {
+	hour, _ := strconv.Atoi(hourString)
+	minute, _ := strconv.Atoi(minuteString)
+	if meridiem == "pm" && hour < 12 {
+		hour += 12
+	} else if meridiem == "am" && hour == 12 {
+		hour = 0
+	}
+	return float64(hour) + float64(minute)/60
+}
+
+// Splits a time like "10:30am" into its hour, minute, and meridiem
+func splitTime(s string) (string, string, string) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	meridiem := ""
+	if strings.HasSuffix(s, "am") || strings.HasSuffix(s, "pm") {
+		meridiem = s[len(s)-2:]
+		s = strings.TrimSpace(s[:len(s)-2])
+	}
+	hour, minute, _ := strings.Cut(s, ":")
+	return hour, minute, meridiem
+}
+
+// Returns n consecutive days starting at start
+func dateRange(start time.Time, n int) []time.Time {
+	dates := make([]time.Time, 0)
+	for i := 0; i < n; i++ {
+		dates = append(dates, start.AddDate(0, 0, i))
+	}
+	return dates
+}
+
+// Returns the Monday of the week containing the given day
+func startOfWeek(day time.Time) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// Returns the next occurrence of the given weekday after today. If
+// skipThisWeek, "next friday" said on a Monday refers to the friday of the
+// following week
+func nextWeekday(today time.Time, weekday time.Weekday, skipThisWeek bool) time.Time {
+	offset := (int(weekday) - int(today.Weekday()) + 7) % 7
+	if offset == 0 {
+		offset = 7
+	}
+	date := today.AddDate(0, 0, offset)
+	if skipThisWeek && startOfWeek(date).Equal(startOfWeek(today)) {
+		date = date.AddDate(0, 0, 7)
+	}
+	return date
+}
+
+// Returns the number of days in the month containing the given day
+func daysInMonth(day time.Time) int {
+	return time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+}
@@ -0,0 +1,141 @@
+package eventparser
+
+import (
+	"testing"
+	"time"
+)
+
+// Wednesday
+var now = time.Date(2024, time.May, 15, 10, 30, 0, 0, time.UTC)
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2024, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func assertDates(t *testing.T, got []time.Time, want []time.Time) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d dates %v, want %d dates %v", len(got), got, len(want), want)
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("date %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseExample(t *testing.T) {
+	draft := Parse("30 min sync next week, weekday evenings", now)
+
+	if draft.Name != "Sync" {
+		t.Errorf("name: got %q, want %q", draft.Name, "Sync")
+	}
+	if draft.MeetingLength != 30 {
+		t.Errorf("meeting length: got %d, want 30", draft.MeetingLength)
+	}
+	if draft.StartHour != 17 || draft.EndHour != 21 {
+		t.Errorf("time range: got %v-%v, want 17-21", draft.StartHour, draft.EndHour)
+	}
+	assertDates(t, draft.Dates, []time.Time{day(5, 20), day(5, 21), day(5, 22), day(5, 23), day(5, 24)})
+}
+
+func TestParseDefaults(t *testing.T) {
+	draft := Parse("Team offsite planning", now)
+
+	if draft.Name != "Team offsite planning" {
+		t.Errorf("name: got %q", draft.Name)
+	}
+	if draft.MeetingLength != defaultMeetingLength {
+		t.Errorf("meeting length: got %d", draft.MeetingLength)
+	}
+	if draft.StartHour != defaultStartHour || draft.EndHour != defaultEndHour {
+		t.Errorf("time range: got %v-%v", draft.StartHour, draft.EndHour)
+	}
+	if len(draft.Dates) != defaultNumDays || !draft.Dates[0].Equal(day(5, 16)) {
+		t.Errorf("dates: got %v", draft.Dates)
+	}
+}
+
+func TestParseMultibyteName(t *testing.T) {
+	if draft := Parse("équipe sync next week", now); draft.Name != "Équipe sync" {
+		t.Errorf("name: got %q, want %q", draft.Name, "Équipe sync")
+	}
+}
+
+func TestParseMeetingLength(t *testing.T) {
+	tests := map[string]int{
+		"1 hour review":        60,
+		"1.5h workshop":        90,
+		"half an hour chat":    30,
+		"45-minute interview":  45,
+		"an hour long standup": 60,
+	}
+	for text, want := range tests {
+		if got := Parse(text, now).MeetingLength; got != want {
+			t.Errorf("%q: got %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestParseTimeRange(t *testing.T) {
+	tests := map[string][2]float64{
+		"call between 2 and 5pm":        {14, 17},
+		"call 11-1pm":                   {11, 13},
+		"call from 9:30am to 11am":      {9.5, 11},
+		"call after 6pm":                {18, 22},
+		"call before noon":              {9, 12},
+		"call mornings or afternoons":   {8, 17},
+		"call during business hours":    {9, 17},
+		"dinner tonight after 7":        {19, 22},
+		"coffee before 9am on thursday": {6, 9},
+		"team lunch on friday":          {11, 14},
+	}
+	for text, want := range tests {
+		draft := Parse(text, now)
+		if draft.StartHour != want[0] || draft.EndHour != want[1] {
+			t.Errorf("%q: got %v-%v, want %v-%v", text, draft.StartHour, draft.EndHour, want[0], want[1])
+		}
+	}
+}
+
+func TestParseDates(t *testing.T) {
+	tests := map[string][]time.Time{
+		"lunch tomorrow":                {day(5, 16)},
+		"lunch today":                   {day(5, 15)},
+		"lunch day after tomorrow":      {day(5, 17)},
+		"lunch friday":                  {day(5, 17)},
+		"lunch next friday":             {day(5, 24)},
+		"lunch this week":               {day(5, 15), day(5, 16), day(5, 17), day(5, 18), day(5, 19)},
+		"lunch this weekend":            {day(5, 18), day(5, 19)},
+		"lunch next weekend":            {day(5, 25), day(5, 26)},
+		"lunch next week mon or wed":    {day(5, 20), day(5, 22)},
+		"lunch in the next 3 days":      {day(5, 16), day(5, 17), day(5, 18)},
+		"lunch on may 20th or june 1":   {day(5, 20), day(6, 1)},
+		"lunch on jan 3":                {time.Date(2025, time.January, 3, 0, 0, 0, 0, time.UTC)},
+		"lunch the next 7 days weekend": {day(5, 18), day(5, 19)},
+	}
+	for text, want := range tests {
+		draft := Parse(text, now)
+		if draft.Name != "Lunch" {
+			t.Errorf("%q: name: got %q, want %q", text, draft.Name, "Lunch")
+		}
+		t.Run(text, func(t *testing.T) { assertDates(t, draft.Dates, want) })
+	}
+}
+
+func TestParseNextMonth(t *testing.T) {
+	draft := Parse("board meeting next month", now)
+	if len(draft.Dates) != 30 || !draft.Dates[0].Equal(day(6, 1)) {
+		t.Errorf("got %d dates starting %v", len(draft.Dates), draft.Dates[0])
+	}
+}
+
+func TestParseDaysOnly(t *testing.T) {
+	draft := Parse("all-day hackathon next week", now)
+	if !draft.DaysOnly {
+		t.Error("expected days only")
+	}
+	if draft.Name != "Hackathon" {
+		t.Errorf("name: got %q", draft.Name)
+	}
+}
@@ -0,0 +1,64 @@
+package eventparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"schej.it/server/services/llm"
+)
+
+const llmSystemPrompt = `You turn a short description of a meeting into a JSON event draft for a scheduling poll.
+Reply with a JSON object with exactly these fields:
+- "name": short event name (string)
+- "meetingLength": length of the meeting in minutes (integer, default 60)
+- "dates": candidate days to poll, as "YYYY-MM-DD" strings (default: the 7 days after today)
+- "startHour", "endHour": time range to poll each day in 24 hour time, e.g. 17.5 for 5:30pm (default 9 and 17)
+- "daysOnly": true if only days matter, not times (boolean)`
+
+// Parses the given text into an event draft using the configured LLM
+func ParseWithLLM(text string, now time.Time) (Draft, error) {
+	userPrompt := fmt.Sprintf("Today is %s.\nDescription: %s", now.Format("Monday, 2006-01-02"), text)
+	reply, err := llm.Complete(llmSystemPrompt, userPrompt, true)
+	if err != nil {
+		return Draft{}, err
+	}
+
+	var result struct {
+		Name          string   `json:"name"`
+		MeetingLength int      `json:"meetingLength"`
+		Dates         []string `json:"dates"`
+		StartHour     float64  `json:"startHour"`
+		EndHour       float64  `json:"endHour"`
+		DaysOnly      bool     `json:"daysOnly"`
+	}
+	if err := json.Unmarshal([]byte(reply), &result); err != nil {
+		return Draft{}, err
+	}
+
+	// Validate the reply, since the model can return anything
+	if result.Name == "" || result.MeetingLength <= 0 || len(result.Dates) == 0 || len(result.Dates) > 60 {
+		return Draft{}, fmt.Errorf("llm returned an invalid draft: %s", reply)
+	}
+	if result.StartHour < 0 || result.EndHour > 24 || result.StartHour >= result.EndHour {
+		return Draft{}, fmt.Errorf("llm returned an invalid time range: %s", reply)
+	}
+
+	draft := Draft{
+		Name:          result.Name,
+		MeetingLength: result.MeetingLength,
+		StartHour:     result.StartHour,
+		EndHour:       result.EndHour,
+		DaysOnly:      result.DaysOnly,
+		Dates:         make([]time.Time, 0),
+	}
+	for _, dateString := range result.Dates {
+		date, err := time.ParseInLocation("2006-01-02", dateString, now.Location())
+		if err != nil {
+			return Draft{}, fmt.Errorf("llm returned an invalid date: %s", dateString)
+		}
+		draft.Dates = append(draft.Dates, date)
+	}
+
+	return draft, nil
+}
@@ -0,0 +1,53 @@
+// Closes polls after they expire, so old share links show that the poll has
+// closed instead of a live availability grid
+package expiry
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"schej.it/server/models"
+)
+
+// Returns the number of days after an event's last date its poll closes by
+// default, set with EVENT_EXPIRY_DAYS. Returns 0 if polls don't expire by default
+func GetDefaultDays() int {
+	days, err := strconv.Atoi(os.Getenv("EVENT_EXPIRY_DAYS"))
+	if err != nil || days < 0 {
+		return 0
+	}
+	return days
+}
+
+// Returns when the event's poll closes: its expiresAt if set, otherwise
+// defaultDays after the end of its last date. Weekly events and groups have no
+// last date, so they only close if expiresAt is set. Returns nil if the poll
+// never closes
+func GetExpiry(event *models.Event, defaultDays int) *time.Time {
+	if event.ExpiresAt != nil {
+		expiresAt := event.ExpiresAt.Time()
+		return &expiresAt
+	}
+	if defaultDays <= 0 || event.Type == models.DOW || event.Type == models.GROUP || len(event.Dates) == 0 {
+		return nil
+	}
+
+	last := event.Dates[0].Time()
+	for _, date := range event.Dates[1:] {
+		if date.Time().After(last) {
+			last = date.Time()
+		}
+	}
+	if event.Duration != nil {
+		last = last.Add(time.Duration(float64(*event.Duration) * float64(time.Hour)))
+	}
+	expiresAt := last.AddDate(0, 0, defaultDays)
+	return &expiresAt
+}
+
+// Returns whether the event's poll has closed at the given time
+func IsExpired(event *models.Event, defaultDays int, now time.Time) bool {
+	expiresAt := GetExpiry(event, defaultDays)
+	return expiresAt != nil && !now.Before(*expiresAt)
+}
@@ -0,0 +1,62 @@
+package expiry
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+var day = time.Date(2024, time.May, 14, 9, 0, 0, 0, time.UTC)
+
+func newEvent(eventType models.EventType) *models.Event {
+	duration := float32(8)
+	return &models.Event{
+		Type:     eventType,
+		Duration: &duration,
+		Dates: []primitive.DateTime{
+			primitive.NewDateTimeFromTime(day.AddDate(0, 0, 2)),
+			primitive.NewDateTimeFromTime(day),
+		},
+	}
+}
+
+func TestGetExpiryDefaultsToDaysAfterLastDate(t *testing.T) {
+	expiresAt := GetExpiry(newEvent(models.SPECIFIC_DATES), 30)
+	want := day.AddDate(0, 0, 32).Add(8 * time.Hour)
+	if expiresAt == nil || !expiresAt.Equal(want) {
+		t.Fatalf("expected %v, got %v", want, expiresAt)
+	}
+}
+
+func TestGetExpiryPrefersExpiresAt(t *testing.T) {
+	event := newEvent(models.SPECIFIC_DATES)
+	expiresAt := primitive.NewDateTimeFromTime(day.AddDate(0, 0, 1))
+	event.ExpiresAt = &expiresAt
+
+	got := GetExpiry(event, 30)
+	if got == nil || !got.Equal(expiresAt.Time()) {
+		t.Fatalf("expected %v, got %v", expiresAt.Time(), got)
+	}
+}
+
+func TestGetExpiryNeverForWeeklyEventsOrNoDefault(t *testing.T) {
+	if got := GetExpiry(newEvent(models.DOW), 30); got != nil {
+		t.Fatalf("expected weekly event not to expire, got %v", got)
+	}
+	if got := GetExpiry(newEvent(models.SPECIFIC_DATES), 0); got != nil {
+		t.Fatalf("expected event not to expire without a default, got %v", got)
+	}
+}
+
+func TestIsExpired(t *testing.T) {
+	event := newEvent(models.SPECIFIC_DATES)
+	expiresAt := day.AddDate(0, 0, 9).Add(8 * time.Hour)
+	if IsExpired(event, 7, expiresAt.Add(-time.Minute)) {
+		t.Fatal("expected event not to be expired before its expiry")
+	}
+	if !IsExpired(event, 7, expiresAt) {
+		t.Fatal("expected event to be expired at its expiry")
+	}
+}
@@ -0,0 +1,129 @@
+// Injects latency and errors into the calls to mongo, Google and Stripe, so
+// retries and failure handling can be tested under controlled failure. Faults
+// only exist in the memory of the server they were set on, expire on their
+// own, and can't be set in release mode
+package faults
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"schej.it/server/utils"
+)
+
+// A dependency faults can be injected into
+type Target string
+
+const (
+	MONGO  Target = "mongo"
+	GOOGLE Target = "google"
+	STRIPE Target = "stripe"
+)
+
+// Longest a fault can be set for, so a forgotten one doesn't linger
+const MAX_DURATION = time.Hour
+
+// Longest latency that can be injected into a call
+const MAX_LATENCY = time.Minute
+
+// Returned by the calls a fault failed
+var ErrInjected = errors.New("injected fault")
+
+// Latency added to every call to the target, and the fraction of the calls
+// that fail
+type Fault struct {
+	Target    Target    `json:"target"`
+	LatencyMs int       `json:"latencyMs"`
+	ErrorRate float64   `json:"errorRate"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+var faults = make(map[Target]Fault)
+var faultsMutex sync.Mutex
+
+// Returns a number in [0, 1) that decides whether a call fails
+var random = rand.Float64
+
+// Returns whether faults can be injected, i.e. the server isn't running in
+// release mode
+func Enabled() bool {
+	return !utils.IsRelease()
+}
+
+func IsValidTarget(target Target) bool {
+	return target == MONGO || target == GOOGLE || target == STRIPE
+}
+
+// Returns an error if the fault can't be set
+func Validate(fault Fault) error {
+	if !IsValidTarget(fault.Target) {
+		return fmt.Errorf("unknown target %q, expected mongo, google or stripe", fault.Target)
+	}
+	if fault.LatencyMs < 0 || time.Duration(fault.LatencyMs)*time.Millisecond > MAX_LATENCY {
+		return fmt.Errorf("latencyMs must be between 0 and %d", MAX_LATENCY.Milliseconds())
+	}
+	if fault.ErrorRate < 0 || fault.ErrorRate > 1 {
+		return errors.New("errorRate must be between 0 and 1")
+	}
+	return nil
+}
+
+// Sets the fault of its target, replacing the previous one
+func Set(fault Fault) error {
+	if !Enabled() {
+		return errors.New("faults can't be injected in release mode")
+	}
+	if err := Validate(fault); err != nil {
+		return err
+	}
+
+	faultsMutex.Lock()
+	defer faultsMutex.Unlock()
+	faults[fault.Target] = fault
+	return nil
+}
+
+// Removes the fault of the target, returning whether there was one
+func Clear(target Target) bool {
+	faultsMutex.Lock()
+	defer faultsMutex.Unlock()
+	_, ok := faults[target]
+	delete(faults, target)
+	return ok
+}
+
+// Returns the faults that haven't expired, by target
+func List(now time.Time) []Fault {
+	faultsMutex.Lock()
+	defer faultsMutex.Unlock()
+	result := make([]Fault, 0)
+	for _, target := range []Target{MONGO, GOOGLE, STRIPE} {
+		if fault, ok := faults[target]; ok && now.Before(fault.ExpiresAt) {
+			result = append(result, fault)
+		}
+	}
+	return result
+}
+
+// Applies the fault of the target to a call: waits for its latency, then
+// returns ErrInjected if the call should fail. Returns nil right away if the
+// target has no fault
+func Inject(target Target) error {
+	faultsMutex.Lock()
+	fault, ok := faults[target]
+	faultsMutex.Unlock()
+	if !ok || !time.Now().Before(fault.ExpiresAt) {
+		return nil
+	}
+
+	if fault.LatencyMs > 0 {
+		time.Sleep(time.Duration(fault.LatencyMs) * time.Millisecond)
+	}
+	if fault.ErrorRate > 0 && random() < fault.ErrorRate {
+		return fmt.Errorf("%w into %s", ErrInjected, target)
+	}
+	return nil
+}
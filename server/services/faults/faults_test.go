@@ -0,0 +1,102 @@
+package faults
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type okTransport struct{}
+
+func (okTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK}, nil
+}
+
+func TestInject(t *testing.T) {
+	t.Setenv("GIN_MODE", "debug")
+	defer Clear(STRIPE)
+	defer func() { random = rand.Float64 }()
+
+	if err := Set(Fault{Target: STRIPE, ErrorRate: 0.5, ExpiresAt: time.Now().Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	random = func() float64 { return 0.4 }
+	if err := Inject(STRIPE); !errors.Is(err, ErrInjected) {
+		t.Errorf("got %v, want an injected error", err)
+	}
+	random = func() float64 { return 0.6 }
+	if err := Inject(STRIPE); err != nil {
+		t.Errorf("got %v, want the call to go through", err)
+	}
+	if err := Inject(GOOGLE); err != nil {
+		t.Errorf("got %v for a target without a fault", err)
+	}
+}
+
+func TestInjectExpired(t *testing.T) {
+	t.Setenv("GIN_MODE", "debug")
+	defer Clear(MONGO)
+
+	Set(Fault{Target: MONGO, ErrorRate: 1, ExpiresAt: time.Now().Add(-time.Second)})
+	if err := Inject(MONGO); err != nil {
+		t.Errorf("got %v from an expired fault", err)
+	}
+	if len(List(time.Now())) != 0 {
+		t.Error("expected expired faults to be left out")
+	}
+}
+
+func TestSet(t *testing.T) {
+	t.Setenv("GIN_MODE", "release")
+	if err := Set(Fault{Target: MONGO, ExpiresAt: time.Now().Add(time.Minute)}); err == nil {
+		t.Error("expected faults to be rejected in release mode")
+	}
+
+	t.Setenv("GIN_MODE", "debug")
+	invalid := []Fault{
+		{Target: "redis"},
+		{Target: MONGO, LatencyMs: -1},
+		{Target: MONGO, LatencyMs: int(2 * MAX_LATENCY.Milliseconds())},
+		{Target: MONGO, ErrorRate: 1.5},
+	}
+	for _, fault := range invalid {
+		if err := Set(fault); err == nil {
+			t.Errorf("expected %+v to be rejected", fault)
+		}
+	}
+}
+
+func TestGetTarget(t *testing.T) {
+	cases := map[string]Target{
+		"api.stripe.com":          STRIPE,
+		"www.googleapis.com:443":  GOOGLE,
+		"oauth2.googleapis.com":   GOOGLE,
+		"accounts.google.com":     GOOGLE,
+		"notstripe.com":           "",
+		"graph.microsoft.com":     "",
+		"googleapis.com.evil.com": "",
+	}
+	for host, want := range cases {
+		if got := GetTarget(host); got != want {
+			t.Errorf("GetTarget(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	t.Setenv("GIN_MODE", "debug")
+	defer Clear(GOOGLE)
+	Set(Fault{Target: GOOGLE, ErrorRate: 1, ExpiresAt: time.Now().Add(time.Minute)})
+
+	transport := &Transport{Base: okTransport{}}
+	req, _ := http.NewRequest("GET", "https://www.googleapis.com/calendar/v3/users/me/calendarList", nil)
+	if _, err := transport.RoundTrip(req); !errors.Is(err, ErrInjected) {
+		t.Errorf("got %v, want an injected error", err)
+	}
+	req, _ = http.NewRequest("GET", "https://graph.microsoft.com/v1.0/me/calendars", nil)
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Errorf("got %v for a host without faults", err)
+	}
+}
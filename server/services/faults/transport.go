@@ -0,0 +1,69 @@
+package faults
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Returns the target of requests to the host, or an empty target if faults
+// aren't injected into them
+func GetTarget(host string) Target {
+	host = strings.ToLower(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	switch {
+	case host == "stripe.com" || strings.HasSuffix(host, ".stripe.com"):
+		return STRIPE
+	case host == "googleapis.com" || strings.HasSuffix(host, ".googleapis.com") || host == "google.com" || strings.HasSuffix(host, ".google.com"):
+		return GOOGLE
+	}
+	return ""
+}
+
+// Injects the faults of Google and Stripe into the requests to them
+type Transport struct {
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if target := GetTarget(req.URL.Host); len(target) > 0 {
+		if err := Inject(target); err != nil {
+			return nil, err
+		}
+	}
+	return t.Base.RoundTrip(req)
+}
+
+// Injects the fault of the target into the connections it dials, e.g. to
+// mongo. Failed writes surface as network errors, which the driver retries
+// like real ones
+type Dialer struct {
+	Target Target
+	Base   *net.Dialer
+}
+
+func (d *Dialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	if err := Inject(d.Target); err != nil {
+		return nil, err
+	}
+	conn, err := d.Base.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	return &faultyConn{Conn: conn, target: d.Target}, nil
+}
+
+type faultyConn struct {
+	net.Conn
+	target Target
+}
+
+func (c *faultyConn) Write(b []byte) (int, error) {
+	if err := Inject(c.target); err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(b)
+}
@@ -0,0 +1,98 @@
+// Min cost max flow on small graphs, used to solve assignment problems
+package flow
+
+import "math"
+
+type edge struct {
+	to, capacity, flow, cost int
+}
+
+// A flow network
+type Graph struct {
+	edges     []edge
+	adjacency [][]int
+}
+
+// Returns an empty flow network with the given number of nodes
+func NewGraph(numNodes int) *Graph {
+	return &Graph{adjacency: make([][]int, numNodes)}
+}
+
+// Adds an edge and its residual edge, returning the id of the edge
+func (g *Graph) AddEdge(from int, to int, capacity int, cost int) int {
+	g.adjacency[from] = append(g.adjacency[from], len(g.edges))
+	g.edges = append(g.edges, edge{to: to, capacity: capacity, cost: cost})
+	g.adjacency[to] = append(g.adjacency[to], len(g.edges))
+	g.edges = append(g.edges, edge{to: from, capacity: 0, cost: -cost})
+	return len(g.edges) - 2
+}
+
+// Pushes as much flow as possible from source to sink, at the lowest total
+// cost. Costs must be non-negative. Uses successive shortest paths with
+// Dijkstra and potentials, augmenting one unit at a time
+func (g *Graph) MinCostMaxFlow(source int, sink int) {
+	numNodes := len(g.adjacency)
+	potential := make([]int, numNodes)
+	for {
+		dist := make([]int, numNodes)
+		prevEdge := make([]int, numNodes)
+		visited := make([]bool, numNodes)
+		for i := range dist {
+			dist[i] = math.MaxInt
+			prevEdge[i] = -1
+		}
+		dist[source] = 0
+
+		// Dense Dijkstra, the graphs are small
+		for {
+			u := -1
+			for v := 0; v < numNodes; v++ {
+				if !visited[v] && dist[v] != math.MaxInt && (u == -1 || dist[v] < dist[u]) {
+					u = v
+				}
+			}
+			if u == -1 {
+				break
+			}
+			visited[u] = true
+			for _, e := range g.adjacency[u] {
+				edge := g.edges[e]
+				if edge.capacity-edge.flow <= 0 {
+					continue
+				}
+				newDist := dist[u] + edge.cost + potential[u] - potential[edge.to]
+				if newDist < dist[edge.to] {
+					dist[edge.to] = newDist
+					prevEdge[edge.to] = e
+				}
+			}
+		}
+
+		if dist[sink] == math.MaxInt {
+			return
+		}
+		for v := 0; v < numNodes; v++ {
+			if dist[v] != math.MaxInt {
+				potential[v] += dist[v]
+			}
+		}
+
+		// Augment by one unit along the path
+		for v := sink; v != source; {
+			e := prevEdge[v]
+			g.edges[e].flow++
+			g.edges[e^1].flow--
+			v = g.edges[e^1].to
+		}
+	}
+}
+
+// Returns the flow through the edge with the given id
+func (g *Graph) Flow(edgeId int) int {
+	return g.edges[edgeId].flow
+}
+
+// Returns the node the edge with the given id points to
+func (g *Graph) To(edgeId int) int {
+	return g.edges[edgeId].to
+}
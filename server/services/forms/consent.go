@@ -0,0 +1,46 @@
+package forms
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+// Returns the consent document to save when the organizer edits it, bumping
+// the version if the title or text changed. Returns nil if the document was
+// removed
+func UpdateConsentDocument(current *models.ConsentDocument, updated *models.ConsentDocument, now time.Time) (*models.ConsentDocument, error) {
+	if updated == nil {
+		return nil, nil
+	}
+	title := strings.TrimSpace(updated.Title)
+	text := strings.TrimSpace(updated.Text)
+	if len(text) == 0 {
+		return nil, fmt.Errorf("consent document must have text")
+	}
+	if current != nil && current.Title == title && current.Text == text {
+		return current, nil
+	}
+
+	version := 1
+	if current != nil {
+		version = current.Version + 1
+	}
+	return &models.ConsentDocument{
+		Title:     title,
+		Text:      text,
+		Version:   version,
+		UpdatedAt: primitive.NewDateTimeFromTime(now),
+	}, nil
+}
+
+// Returns the SHA-256 hash of the document's title and text
+func HashConsentDocument(document *models.ConsentDocument) string {
+	hash := sha256.Sum256([]byte(document.Title + "\n\n" + document.Text))
+	return hex.EncodeToString(hash[:])
+}
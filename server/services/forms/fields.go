@@ -0,0 +1,75 @@
+package forms
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+// Maximum length of a respondent field's value
+const maxFieldValueLength = 200
+
+// Validates the respondent fields when the organizer edits them, giving new
+// fields an id. Labels have to be unique so that exports can use them as
+// column names
+func ValidateFields(fields []models.RespondentField) error {
+	labels := make(models.Set[string])
+	for i := range fields {
+		field := &fields[i]
+		if field.Id.IsZero() {
+			field.Id = primitive.NewObjectID()
+		}
+
+		field.Label = strings.TrimSpace(field.Label)
+		if len(field.Label) == 0 {
+			return fmt.Errorf("fields must have a label")
+		}
+		if _, ok := labels[strings.ToLower(field.Label)]; ok {
+			return fmt.Errorf("%q is used by more than one field", field.Label)
+		}
+		labels[strings.ToLower(field.Label)] = struct{}{}
+
+		options := make([]string, 0)
+		for _, option := range field.Options {
+			if option = strings.TrimSpace(option); len(option) > 0 && !utils.Contains(options, option) {
+				options = append(options, option)
+			}
+		}
+		field.Options = options
+	}
+	return nil
+}
+
+// Validates the respondent's values for the fields, returning the values with
+// unknown fields and empty values removed
+func ValidateFieldValues(fields []models.RespondentField, values map[string]string) (map[string]string, error) {
+	cleaned := make(map[string]string)
+	for _, field := range fields {
+		value := strings.TrimSpace(values[field.Id.Hex()])
+		if len(value) == 0 {
+			if field.Required {
+				return nil, fmt.Errorf("%q is required", field.Label)
+			}
+			continue
+		}
+		if len(value) > maxFieldValueLength || len(field.Options) > 0 && !utils.Contains(field.Options, value) {
+			return nil, fmt.Errorf("invalid value for %q", field.Label)
+		}
+		cleaned[field.Id.Hex()] = value
+	}
+	return cleaned, nil
+}
+
+// Returns the event's respondent field with the given id or label (ignoring
+// case), or nil if it has none
+func GetField(fields []models.RespondentField, idOrLabel string) *models.RespondentField {
+	for i := range fields {
+		if fields[i].Id.Hex() == idOrLabel || strings.EqualFold(fields[i].Label, strings.TrimSpace(idOrLabel)) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+// Validates and formats respondents' answers to an event's custom questions
+package forms
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+// Maximum length of the answer to a text question
+const maxTextLength = 2000
+
+// Returns whether the question is shown given the answers so far
+func IsVisible(question models.Question, answers map[string][]string) bool {
+	if question.ShowIf == nil {
+		return true
+	}
+	for _, value := range answers[question.ShowIf.QuestionId.Hex()] {
+		if utils.Contains(question.ShowIf.AnyOf, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validates the answers against the questions, returning the answers with
+// unknown questions, hidden questions, and empty values removed. Questions
+// are evaluated in order, so conditions only depend on validated answers
+func Validate(questions []models.Question, answers map[string][]string) (map[string][]string, error) {
+	cleaned := make(map[string][]string)
+	for _, question := range questions {
+		if !IsVisible(question, cleaned) {
+			continue
+		}
+
+		values := make([]string, 0)
+		for _, value := range answers[question.Id.Hex()] {
+			if value = strings.TrimSpace(value); len(value) > 0 {
+				values = append(values, value)
+			}
+		}
+
+		if len(values) == 0 {
+			if question.Required {
+				return nil, fmt.Errorf("%q is required", question.Label)
+			}
+			continue
+		}
+
+		switch question.Type {
+		case models.TEXT_QUESTION:
+			if len(values) > 1 || len(values[0]) > maxTextLength {
+				return nil, fmt.Errorf("invalid answer to %q", question.Label)
+			}
+		case models.SELECT_QUESTION, models.CHECKBOX_QUESTION:
+			if question.Type == models.SELECT_QUESTION && len(values) > 1 {
+				return nil, fmt.Errorf("only one option can be selected for %q", question.Label)
+			}
+			for _, value := range values {
+				if !utils.Contains(question.Options, value) {
+					return nil, fmt.Errorf("invalid option %q for %q", value, question.Label)
+				}
+			}
+		}
+		cleaned[question.Id.Hex()] = values
+	}
+
+	return cleaned, nil
+}
+
+// Validates the questions themselves when the organizer edits them, giving
+// new questions an id. Conditions must refer to an earlier select or checkbox
+// question and one of its options
+func ValidateQuestions(questions []models.Question) error {
+	earlier := make(map[primitive.ObjectID]models.Question)
+	for i := range questions {
+		question := &questions[i]
+		if question.Id.IsZero() {
+			question.Id = primitive.NewObjectID()
+		}
+
+		// Conditions can only depend on earlier questions, which rules out cycles
+		if question.ShowIf != nil {
+			dependency, ok := earlier[question.ShowIf.QuestionId]
+			if !ok || dependency.Type == models.TEXT_QUESTION || len(question.ShowIf.AnyOf) == 0 {
+				return fmt.Errorf("%q has an invalid condition", question.Label)
+			}
+			for _, value := range question.ShowIf.AnyOf {
+				if !utils.Contains(dependency.Options, value) {
+					return fmt.Errorf("%q has an invalid condition", question.Label)
+				}
+			}
+		}
+		earlier[question.Id] = *question
+
+		if len(strings.TrimSpace(question.Label)) == 0 {
+			return fmt.Errorf("questions must have a label")
+		}
+		switch question.Type {
+		case models.TEXT_QUESTION:
+		case models.SELECT_QUESTION, models.CHECKBOX_QUESTION:
+			if len(question.Options) == 0 {
+				return fmt.Errorf("%q must have options", question.Label)
+			}
+		default:
+			return fmt.Errorf("invalid question type %q", question.Type)
+		}
+	}
+	return nil
+}
+
+// Returns the answer to the question as a single string, e.g. for exports
+func FormatAnswer(answers map[string][]string, question models.Question) string {
+	return strings.Join(answers[question.Id.Hex()], ", ")
+}
@@ -0,0 +1,183 @@
+package forms
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func TestValidate(t *testing.T) {
+	text := models.Question{Id: primitive.NewObjectID(), Type: models.TEXT_QUESTION, Label: "Topic", Required: true}
+	choice := models.Question{Id: primitive.NewObjectID(), Type: models.SELECT_QUESTION, Label: "Level", Options: []string{"Beginner", "Advanced"}}
+	checkbox := models.Question{Id: primitive.NewObjectID(), Type: models.CHECKBOX_QUESTION, Label: "Needs", Options: []string{"Parking", "Wheelchair access"}}
+	questions := []models.Question{text, choice, checkbox}
+
+	answers, err := Validate(questions, map[string][]string{
+		text.Id.Hex():     {" Calculus "},
+		checkbox.Id.Hex(): {"Parking", "Wheelchair access"},
+		"unknown":         {"ignored"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(answers) != 2 || answers[text.Id.Hex()][0] != "Calculus" || len(answers[checkbox.Id.Hex()]) != 2 {
+		t.Errorf("got %v", answers)
+	}
+
+	invalid := []map[string][]string{
+		// Missing required answer
+		{choice.Id.Hex(): {"Beginner"}},
+		// Multiple options for a select question
+		{text.Id.Hex(): {"Calculus"}, choice.Id.Hex(): {"Beginner", "Advanced"}},
+		// Unknown option
+		{text.Id.Hex(): {"Calculus"}, checkbox.Id.Hex(): {"Snacks"}},
+	}
+	for _, answers := range invalid {
+		if _, err := Validate(questions, answers); err == nil {
+			t.Errorf("expected %v to be invalid", answers)
+		}
+	}
+}
+
+func TestValidateQuestions(t *testing.T) {
+	if err := ValidateQuestions([]models.Question{{Type: models.SELECT_QUESTION, Label: "Level"}}); err == nil {
+		t.Error("expected select question without options to be invalid")
+	}
+	if err := ValidateQuestions([]models.Question{{Type: "date", Label: "Birthday"}}); err == nil {
+		t.Error("expected unknown question type to be invalid")
+	}
+	if err := ValidateQuestions([]models.Question{{Type: models.TEXT_QUESTION, Label: "Topic"}}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateConditional(t *testing.T) {
+	attending := models.Question{Id: primitive.NewObjectID(), Type: models.SELECT_QUESTION, Label: "Attending in person?", Required: true, Options: []string{"Yes", "No"}}
+	diet := models.Question{
+		Id: primitive.NewObjectID(), Type: models.TEXT_QUESTION, Label: "Dietary restrictions", Required: true,
+		ShowIf: &models.QuestionCondition{QuestionId: attending.Id, AnyOf: []string{"Yes"}},
+	}
+	questions := []models.Question{attending, diet}
+
+	// Required branch can't be skipped when shown
+	if _, err := Validate(questions, map[string][]string{attending.Id.Hex(): {"Yes"}}); err == nil {
+		t.Error("expected missing answer to shown question to be invalid")
+	}
+
+	// Answers to hidden questions are dropped
+	answers, err := Validate(questions, map[string][]string{attending.Id.Hex(): {"No"}, diet.Id.Hex(): {"None"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := answers[diet.Id.Hex()]; ok {
+		t.Errorf("got answer to hidden question: %v", answers)
+	}
+}
+
+func TestValidateQuestionsConditional(t *testing.T) {
+	attending := models.Question{Id: primitive.NewObjectID(), Type: models.SELECT_QUESTION, Label: "Attending?", Options: []string{"Yes", "No"}}
+	topic := models.Question{Id: primitive.NewObjectID(), Type: models.TEXT_QUESTION, Label: "Topic"}
+	conditional := func(questionId primitive.ObjectID, anyOf ...string) models.Question {
+		return models.Question{Type: models.TEXT_QUESTION, Label: "Details", ShowIf: &models.QuestionCondition{QuestionId: questionId, AnyOf: anyOf}}
+	}
+
+	if err := ValidateQuestions([]models.Question{attending, conditional(attending.Id, "Yes")}); err != nil {
+		t.Error(err)
+	}
+	invalid := [][]models.Question{
+		// Depends on a later question
+		{conditional(attending.Id, "Yes"), attending},
+		// Depends on a text question
+		{topic, conditional(topic.Id, "Yes")},
+		// Unknown option
+		{attending, conditional(attending.Id, "Maybe")},
+		// No values
+		{attending, conditional(attending.Id)},
+	}
+	for _, questions := range invalid {
+		if err := ValidateQuestions(questions); err == nil {
+			t.Errorf("expected %v to be invalid", questions)
+		}
+	}
+}
+
+func TestUpdateConsentDocument(t *testing.T) {
+	now := time.Date(2024, time.May, 14, 9, 0, 0, 0, time.UTC)
+	document, err := UpdateConsentDocument(nil, &models.ConsentDocument{Title: "Waiver", Text: " I accept the risks "}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if document.Version != 1 || document.Text != "I accept the risks" {
+		t.Errorf("got %+v", document)
+	}
+
+	// Unchanged documents keep their version
+	unchanged, _ := UpdateConsentDocument(document, &models.ConsentDocument{Title: "Waiver", Text: "I accept the risks", Version: 5}, now.Add(time.Hour))
+	if unchanged.Version != 1 || !unchanged.UpdatedAt.Time().Equal(now) {
+		t.Errorf("got %+v", unchanged)
+	}
+
+	changed, _ := UpdateConsentDocument(document, &models.ConsentDocument{Title: "Waiver", Text: "I accept all the risks"}, now)
+	if changed.Version != 2 || HashConsentDocument(changed) == HashConsentDocument(document) {
+		t.Errorf("got %+v", changed)
+	}
+
+	if _, err := UpdateConsentDocument(document, &models.ConsentDocument{Title: "Waiver"}, now); err == nil {
+		t.Error("expected document without text to be invalid")
+	}
+}
+
+func TestValidateFields(t *testing.T) {
+	fields := []models.RespondentField{{Label: " Team ", Options: []string{"Design", " Design", ""}}, {Label: "Grade"}}
+	if err := ValidateFields(fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields[0].Id.IsZero() || fields[0].Label != "Team" || len(fields[0].Options) != 1 {
+		t.Errorf("got %+v", fields[0])
+	}
+
+	if err := ValidateFields([]models.RespondentField{{Label: "Team"}, {Label: "team"}}); err == nil {
+		t.Error("expected duplicate labels to be invalid")
+	}
+	if err := ValidateFields([]models.RespondentField{{Label: " "}}); err == nil {
+		t.Error("expected empty label to be invalid")
+	}
+}
+
+func TestValidateFieldValues(t *testing.T) {
+	team := models.RespondentField{Id: primitive.NewObjectID(), Label: "Team", Required: true, Options: []string{"Design", "Eng"}}
+	grade := models.RespondentField{Id: primitive.NewObjectID(), Label: "Grade"}
+	fields := []models.RespondentField{team, grade}
+
+	values, err := ValidateFieldValues(fields, map[string]string{team.Id.Hex(): " Eng ", grade.Id.Hex(): "", "unknown": "ignored"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 1 || values[team.Id.Hex()] != "Eng" {
+		t.Errorf("got %v", values)
+	}
+
+	if _, err := ValidateFieldValues(fields, map[string]string{grade.Id.Hex(): "7"}); err == nil {
+		t.Error("expected missing required field to be invalid")
+	}
+	if _, err := ValidateFieldValues(fields, map[string]string{team.Id.Hex(): "Sales"}); err == nil {
+		t.Error("expected unknown option to be invalid")
+	}
+}
+
+func TestGetField(t *testing.T) {
+	role := models.RespondentField{Id: primitive.NewObjectID(), Label: "Role"}
+	fields := []models.RespondentField{{Id: primitive.NewObjectID(), Label: "Team"}, role}
+
+	if field := GetField(fields, role.Id.Hex()); field == nil || field.Id != role.Id {
+		t.Errorf("expected to find the field by id, got %+v", field)
+	}
+	if field := GetField(fields, " role"); field == nil || field.Id != role.Id {
+		t.Errorf("expected to find the field by label, got %+v", field)
+	}
+	if field := GetField(fields, "Grade"); field != nil {
+		t.Errorf("expected no field, got %+v", field)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"strconv"
 	"time"
@@ -15,13 +16,68 @@ import (
 	"google.golang.org/api/option"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"schej.it/server/logger"
+	"schej.it/server/models"
 	"schej.it/server/services/listmonk"
+	"schej.it/server/services/status"
+	"schej.it/server/services/tasks"
 	"schej.it/server/utils"
 )
 
 var TasksClient *cloudtasks.Client
 
+// Queue the reminder emails are created in
+const tasksQueue = "projects/schej-it/locations/us-central1/queues/SendReminderEmail"
+
+// Checks that the Cloud Tasks queue is reachable. Returns nil if Cloud Tasks
+// isn't in use
+func PingTasks(ctx context.Context) error {
+	if TasksClient == nil {
+		return nil
+	}
+	_, err := TasksClient.GetQueue(ctx, &cloudtaskspb.GetQueueRequest{Name: tasksQueue})
+	return err
+}
+
+// Task queue backed by Google Cloud Tasks
+type cloudTasksQueue struct{}
+
+func (cloudTasksQueue) Create(task tasks.HttpTask) (string, error) {
+	created, err := TasksClient.CreateTask(context.Background(), &cloudtaskspb.CreateTaskRequest{
+		Parent: tasksQueue,
+		Task: &cloudtaskspb.Task{
+			ScheduleTime: timestamppb.New(task.ScheduleTime),
+			PayloadType: &cloudtaskspb.Task_HttpRequest{
+				HttpRequest: &cloudtaskspb.HttpRequest{
+					Url:        task.Url,
+					HttpMethod: cloudtaskspb.HttpMethod(cloudtaskspb.HttpMethod_value[task.Method]),
+					Headers:    task.Headers,
+					Body:       task.Body,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return created.Name, nil
+}
+
+func (cloudTasksQueue) Delete(taskId string) error {
+	return TasksClient.DeleteTask(context.Background(), &cloudtaskspb.DeleteTaskRequest{
+		Name: taskId,
+	})
+}
+
+// Initializes the task queue with the backend set by TASK_QUEUE_BACKEND. Cloud
+// Tasks needs the service account key at SERVICE_ACCOUNT_KEY_PATH, while the
+// mongo backend works without a Google Cloud project
 func InitTasks() func() {
+	if tasks.GetBackend() == tasks.MONGO {
+		logger.StdOut.Println("Using the mongo task queue")
+		tasks.Default = tasks.MongoQueue{}
+		return func() {}
+	}
+
 	ctx := context.Background()
 
 	var err error
@@ -40,6 +96,7 @@ func InitTasks() func() {
 	if err != nil {
 		logger.StdErr.Panicln(err)
 	}
+	tasks.Default = cloudTasksQueue{}
 
 	// Return function to close client
 	return func() {
@@ -47,9 +104,11 @@ func InitTasks() func() {
 	}
 }
 
-func CreateEmailTask(email string, ownerName string, eventName string, eventId string) []string {
-	if TasksClient == nil {
-		logger.StdOut.Println("Cloud Tasks client not initialized; skipping email task creation")
+// Schedules the reminder emails of the remindee, following the given cadence
+// (defaults to 1 and 3 days after the first reminder)
+func CreateEmailTask(email string, ownerName string, eventName string, eventId string, cadence *models.ReminderCadence) []string {
+	if tasks.Default == nil {
+		logger.StdOut.Println("Task queue not initialized; skipping email task creation")
 		return []string{}
 	}
 
@@ -82,10 +141,14 @@ func CreateEmailTask(email string, ownerName string, eventName string, eventId s
 	}
 
 	// Create map of emails to iterate through
-	tasksToCreate := make(map[int]*timestamppb.Timestamp)
-	tasksToCreate[initialEmailReminderId] = timestamppb.Now()
-	tasksToCreate[secondEmailReminderId] = timestamppb.New(time.Now().Add(24 * time.Hour))
-	tasksToCreate[finalEmailReminderId] = timestamppb.New(time.Now().Add(3 * 24 * time.Hour))
+	tasksToCreate := make(map[int]time.Time)
+	tasksToCreate[initialEmailReminderId] = time.Now()
+	secondReminderHours, finalReminderHours := 24, 3*24
+	if cadence != nil {
+		secondReminderHours, finalReminderHours = cadence.SecondReminderHours, cadence.FinalReminderHours
+	}
+	tasksToCreate[secondEmailReminderId] = time.Now().Add(time.Duration(secondReminderHours) * time.Hour)
+	tasksToCreate[finalEmailReminderId] = time.Now().Add(time.Duration(finalReminderHours) * time.Hour)
 
 	// Construct URLs
 	baseUrl := utils.GetBaseUrl()
@@ -112,44 +175,35 @@ func CreateEmailTask(email string, ownerName string, eventName string, eventId s
 		}
 
 		// Create task
-		task, err := TasksClient.CreateTask(context.Background(), &cloudtaskspb.CreateTaskRequest{
-			Parent: "projects/schej-it/locations/us-central1/queues/SendReminderEmail",
-			Task: &cloudtaskspb.Task{
-				ScheduleTime: scheduleTime,
-				PayloadType: &cloudtaskspb.Task_HttpRequest{
-					HttpRequest: &cloudtaskspb.HttpRequest{
-						Url:        fmt.Sprintf("%s/api/tx", listmonkUrl),
-						HttpMethod: cloudtaskspb.HttpMethod_POST,
-						Headers: map[string]string{
-							"Authorization": fmt.Sprintf("Basic %s", basicAuthString),
-							"Content-Type":  "application/json",
-						},
-						Body: body,
-					},
-				},
+		taskId, err := tasks.Default.Create(tasks.HttpTask{
+			Url:    fmt.Sprintf("%s/api/tx", listmonkUrl),
+			Method: http.MethodPost,
+			Headers: map[string]string{
+				"Authorization": fmt.Sprintf("Basic %s", basicAuthString),
+				"Content-Type":  "application/json",
 			},
+			Body:         body,
+			ScheduleTime: scheduleTime,
 		})
 
+		status.Record(status.TASK_QUEUE, err)
 		if err != nil {
 			logger.StdErr.Panicln(err)
 		}
 
-		taskIds = append(taskIds, task.Name)
+		taskIds = append(taskIds, taskId)
 	}
 
 	return taskIds
 }
 
 func DeleteEmailTask(taskId string) {
-	if TasksClient == nil {
-		logger.StdOut.Println("Cloud Tasks client not initialized; skipping email task deletion")
+	if tasks.Default == nil {
+		logger.StdOut.Println("Task queue not initialized; skipping email task deletion")
 		return
 	}
 
-	err := TasksClient.DeleteTask(context.Background(), &cloudtaskspb.DeleteTaskRequest{
-		Name: taskId,
-	})
-
+	err := tasks.Default.Delete(taskId)
 	if err != nil {
 		// logger.StdErr.Println(err)
 		return
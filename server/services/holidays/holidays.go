@@ -0,0 +1,169 @@
+// Public holidays per country and region, fetched from the Nager.Date API (or
+// any API with the same format, configured with HOLIDAYS_API_URL)
+package holidays
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+const defaultApiUrl = "https://date.nager.at/api/v3"
+
+// How long fetched holidays are cached for
+const cacheDuration = 24 * time.Hour
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// A public holiday
+type Holiday struct {
+	// Date of the holiday, in YYYY-MM-DD format
+	Date      string `json:"date"`
+	Name      string `json:"name"`
+	LocalName string `json:"localName"`
+}
+
+// Holiday as returned by the API
+type apiHoliday struct {
+	Holiday
+	Global   bool     `json:"global"`
+	Counties []string `json:"counties"`
+	Types    []string `json:"types"`
+}
+
+type cacheEntry struct {
+	holidays  []apiHoliday
+	fetchedAt time.Time
+}
+
+var cache = make(map[string]cacheEntry)
+var cacheMutex sync.Mutex
+
+// Returns the public holidays in the given year for the given country (ISO
+// 3166-1 alpha-2 code, e.g. "US"). If region is set (ISO 3166-2 code, e.g.
+// "US-CA"), regional holidays of that region are included too
+func GetHolidays(country string, region string, year int) ([]Holiday, error) {
+	country = strings.ToUpper(country)
+	region = strings.ToUpper(region)
+
+	all, err := getCountryHolidays(country, year)
+	if err != nil {
+		return nil, err
+	}
+
+	holidays := make([]Holiday, 0)
+	for _, holiday := range all {
+		if !isPublic(holiday) {
+			continue
+		}
+		if holiday.Global {
+			holidays = append(holidays, holiday.Holiday)
+			continue
+		}
+		for _, county := range holiday.Counties {
+			if len(region) > 0 && strings.EqualFold(county, region) {
+				holidays = append(holidays, holiday.Holiday)
+				break
+			}
+		}
+	}
+
+	return holidays, nil
+}
+
+// Returns the holidays that fall on the event's dates, keyed by date in
+// YYYY-MM-DD format. Dates are determined in the given location. Returns an
+// empty map if the event doesn't have a holiday country set
+func GetHolidaysForEvent(event *models.Event, loc *time.Location) map[string]Holiday {
+	result := make(map[string]Holiday)
+	settings := event.HolidaySettings
+	if settings == nil || len(settings.Country) == 0 || event.Type == models.DOW || event.Type == models.GROUP {
+		return result
+	}
+
+	dates := make(map[string]struct{})
+	years := make(map[int]struct{})
+	for _, date := range event.Dates {
+		t := date.Time().In(loc)
+		dates[t.Format("2006-01-02")] = struct{}{}
+		years[t.Year()] = struct{}{}
+	}
+
+	for year := range years {
+		holidays, err := GetHolidays(settings.Country, settings.Region, year)
+		if err != nil {
+			logger.StdErr.Println(err)
+			continue
+		}
+		for _, holiday := range holidays {
+			if _, ok := dates[holiday.Date]; ok {
+				result[holiday.Date] = holiday
+			}
+		}
+	}
+
+	return result
+}
+
+// Fetches (or returns the cached) holidays for the given country and year
+func getCountryHolidays(country string, year int) ([]apiHoliday, error) {
+	key := fmt.Sprintf("%s/%d", country, year)
+
+	cacheMutex.Lock()
+	entry, ok := cache[key]
+	cacheMutex.Unlock()
+	if ok && time.Since(entry.fetchedAt) < cacheDuration {
+		return entry.holidays, nil
+	}
+
+	apiUrl := os.Getenv("HOLIDAYS_API_URL")
+	if apiUrl == "" {
+		apiUrl = defaultApiUrl
+	}
+	resp, err := client.Get(fmt.Sprintf("%s/PublicHolidays/%d/%s", strings.TrimSuffix(apiUrl, "/"), year, country))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent, http.StatusNotFound:
+		// Unsupported country
+		return []apiHoliday{}, nil
+	default:
+		return nil, fmt.Errorf("failed to fetch holidays for %s: %s", key, resp.Status)
+	}
+
+	holidays := make([]apiHoliday, 0)
+	if err := json.NewDecoder(resp.Body).Decode(&holidays); err != nil {
+		return nil, err
+	}
+
+	cacheMutex.Lock()
+	cache[key] = cacheEntry{holidays: holidays, fetchedAt: time.Now()}
+	cacheMutex.Unlock()
+
+	return holidays, nil
+}
+
+// Returns whether the holiday is a public holiday (as opposed to e.g. an
+// observance, where businesses are open)
+func isPublic(holiday apiHoliday) bool {
+	if len(holiday.Types) == 0 {
+		return true
+	}
+	for _, t := range holiday.Types {
+		if t == "Public" {
+			return true
+		}
+	}
+	return false
+}
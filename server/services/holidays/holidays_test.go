@@ -0,0 +1,75 @@
+package holidays
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+const testResponse = `[
+	{"date":"2024-05-27","localName":"Memorial Day","name":"Memorial Day","countryCode":"US","global":true,"counties":null,"types":["Public"]},
+	{"date":"2024-03-31","localName":"Cesar Chavez Day","name":"Cesar Chavez Day","countryCode":"US","global":false,"counties":["US-CA"],"types":["Public"]},
+	{"date":"2024-02-14","localName":"Valentine's Day","name":"Valentine's Day","countryCode":"US","global":true,"counties":null,"types":["Observance"]}
+]`
+
+func setupServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/PublicHolidays/2024/US" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(testResponse))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("HOLIDAYS_API_URL", server.URL)
+	cache = make(map[string]cacheEntry)
+}
+
+func TestGetHolidaysRegion(t *testing.T) {
+	setupServer(t)
+
+	national, err := GetHolidays("us", "", 2024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(national) != 1 || national[0].Name != "Memorial Day" {
+		t.Errorf("got %v, want only Memorial Day", national)
+	}
+
+	california, err := GetHolidays("US", "us-ca", 2024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(california) != 2 {
+		t.Errorf("got %v, want Memorial Day and Cesar Chavez Day", california)
+	}
+
+	unsupported, err := GetHolidays("XX", "", 2024)
+	if err != nil || len(unsupported) != 0 {
+		t.Errorf("got %v, %v, want no holidays", unsupported, err)
+	}
+}
+
+func TestGetHolidaysForEvent(t *testing.T) {
+	setupServer(t)
+
+	loc := time.FixedZone("PDT", -7*60*60)
+	event := &models.Event{
+		Type: models.SPECIFIC_DATES,
+		Dates: []primitive.DateTime{
+			// 9am PDT on May 27 and May 28
+			primitive.NewDateTimeFromTime(time.Date(2024, time.May, 27, 16, 0, 0, 0, time.UTC)),
+			primitive.NewDateTimeFromTime(time.Date(2024, time.May, 28, 16, 0, 0, 0, time.UTC)),
+		},
+		HolidaySettings: &models.HolidaySettings{Country: "US"},
+	}
+
+	result := GetHolidaysForEvent(event, loc)
+	if len(result) != 1 || result["2024-05-27"].Name != "Memorial Day" {
+		t.Errorf("got %v, want Memorial Day on 2024-05-27", result)
+	}
+}
@@ -0,0 +1,223 @@
+// Extension points that self-hosters can plug custom integrations into
+// without forking the route code, either by registering Go functions in a
+// custom build or by configuring HTTP endpoints that are posted to
+package hooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"schej.it/server/logger"
+)
+
+type Point string
+
+const (
+	// After someone responds to an event for the first time
+	AFTER_RESPONSE_CREATED Point = "afterResponseCreated"
+
+	// Before an event is finalized. Hooks can reject the finalization by
+	// returning an error (or a non 2xx status for HTTP hooks)
+	BEFORE_FINALIZE Point = "beforeFinalize"
+
+	// After an email notification is sent
+	NOTIFICATION_DISPATCH Point = "notificationDispatch"
+)
+
+var Points = []Point{AFTER_RESPONSE_CREATED, BEFORE_FINALIZE, NOTIFICATION_DISPATCH}
+
+// How long HTTP hooks have to respond
+const TIMEOUT = 5 * time.Second
+
+// What hooks are called with
+type Context struct {
+	Point     Point                  `json:"point"`
+	CreatedAt time.Time              `json:"createdAt"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// A Go hook, registered with Register
+type Hook func(ctx Context) error
+
+// An HTTP hook from the HOOKS_CONFIG file, which the context is posted to as
+// JSON, signed with the secret like webhook deliveries
+type HttpHook struct {
+	Point  Point  `json:"point"`
+	Url    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+var mutex sync.RWMutex
+var goHooks = make(map[Point][]Hook)
+var httpHooks = make(map[Point][]HttpHook)
+
+// Registers a Go hook at the point. Meant to be called from an init function
+// of a plugin package that is imported in a custom build
+func Register(point Point, hook Hook) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	goHooks[point] = append(goHooks[point], hook)
+}
+
+// Loads the HTTP hooks from the JSON file at HOOKS_CONFIG, if set
+func Init() {
+	path := os.Getenv("HOOKS_CONFIG")
+	if path == "" {
+		return
+	}
+
+	config, err := os.ReadFile(path)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	if err := Configure(config); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+}
+
+// Replaces the HTTP hooks with the ones in the config, a JSON array of hooks
+func Configure(config []byte) error {
+	configured := make([]HttpHook, 0)
+	if err := json.Unmarshal(config, &configured); err != nil {
+		return fmt.Errorf("invalid hooks config: %w", err)
+	}
+
+	hooks := make(map[Point][]HttpHook)
+	for _, hook := range configured {
+		if !isPoint(hook.Point) {
+			return fmt.Errorf("invalid hook point: %q", hook.Point)
+		}
+		if hook.Url == "" {
+			return fmt.Errorf("missing url for %q hook", hook.Point)
+		}
+		hooks[hook.Point] = append(hooks[hook.Point], hook)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	httpHooks = hooks
+	return nil
+}
+
+// Returns whether any hook is registered at the point
+func HasHooks(point Point) bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return len(goHooks[point]) > 0 || len(httpHooks[point]) > 0
+}
+
+// Calls the hooks at the point in order, Go hooks first, and returns the
+// first error, in which case the remaining hooks aren't called
+func Run(point Point, data map[string]interface{}) error {
+	mutex.RLock()
+	registered := goHooks[point]
+	configured := httpHooks[point]
+	mutex.RUnlock()
+	if len(registered) == 0 && len(configured) == 0 {
+		return nil
+	}
+
+	ctx := Context{Point: point, CreatedAt: time.Now(), Data: data}
+	for _, hook := range registered {
+		if err := hook(ctx); err != nil {
+			return err
+		}
+	}
+	for _, hook := range configured {
+		if err := post(hook, ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Calls the hooks at the point in the background, logging errors, so slow
+// hooks don't hold up the request
+func Dispatch(point Point, data map[string]interface{}) {
+	if !HasHooks(point) {
+		return
+	}
+
+	go func() {
+		// Recover from panics
+		defer func() {
+			if err := recover(); err != nil {
+				logger.StdErr.Println(err)
+			}
+		}()
+
+		if err := Run(point, data); err != nil {
+			logger.StdErr.Printf("%s hook failed: %v\n", point, err)
+		}
+	}()
+}
+
+// Returns the signature of a call to an HTTP hook, sent in the
+// X-Timeful-Signature header. Computed the same way as webhook signatures,
+// over "<X-Timeful-Timestamp>.<body>"
+func sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Posts the context to the HTTP hook, returning an error if it doesn't
+// respond with a 2xx status. The error is the "error" field of the response
+// body if it has one
+func post(hook HttpHook, ctx Context) error {
+	body, err := json.Marshal(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, hook.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := ctx.CreatedAt.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Timeful-Hook", string(hook.Point))
+	req.Header.Set("X-Timeful-Timestamp", strconv.FormatInt(timestamp, 10))
+	if hook.Secret != "" {
+		req.Header.Set("X-Timeful-Signature", sign(hook.Secret, timestamp, body))
+	}
+
+	client := http.Client{Timeout: TIMEOUT}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	result := struct {
+		Error string `json:"error"`
+	}{}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if json.Unmarshal(respBody, &result) == nil && result.Error != "" {
+		return fmt.Errorf("%s", result.Error)
+	}
+	return fmt.Errorf("hook %s responded with status %d", hook.Url, resp.StatusCode)
+}
+
+func isPoint(point Point) bool {
+	for _, p := range Points {
+		if p == point {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package hooks
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func reset() {
+	goHooks = make(map[Point][]Hook)
+	httpHooks = make(map[Point][]HttpHook)
+}
+
+func TestConfigure(t *testing.T) {
+	defer reset()
+
+	tests := []struct {
+		config string
+		valid  bool
+	}{
+		{`[]`, true},
+		{`[{"point": "beforeFinalize", "url": "https://example.com"}]`, true},
+		{`[{"point": "beforeDelete", "url": "https://example.com"}]`, false},
+		{`[{"point": "afterResponseCreated"}]`, false},
+		{`{"point": "beforeFinalize"}`, false},
+	}
+	for _, test := range tests {
+		if err := Configure([]byte(test.config)); (err == nil) != test.valid {
+			t.Errorf("Configure(%s) = %v, expected valid = %v", test.config, err, test.valid)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	defer reset()
+
+	if err := Run(BEFORE_FINALIZE, nil); err != nil {
+		t.Errorf("Run without hooks = %v, expected nil", err)
+	}
+
+	calls := make([]string, 0)
+	Register(BEFORE_FINALIZE, func(ctx Context) error {
+		calls = append(calls, ctx.Data["name"].(string))
+		return nil
+	})
+	Register(BEFORE_FINALIZE, func(ctx Context) error {
+		return errors.New("rejected")
+	})
+	Register(BEFORE_FINALIZE, func(ctx Context) error {
+		calls = append(calls, "after rejection")
+		return nil
+	})
+
+	err := Run(BEFORE_FINALIZE, map[string]interface{}{"name": "Standup"})
+	if err == nil || err.Error() != "rejected" {
+		t.Errorf("Run = %v, expected rejected", err)
+	}
+	if len(calls) != 1 || calls[0] != "Standup" {
+		t.Errorf("calls = %v, expected [Standup]", calls)
+	}
+	if HasHooks(AFTER_RESPONSE_CREATED) {
+		t.Errorf("HasHooks(%s) = true, expected false", AFTER_RESPONSE_CREATED)
+	}
+}
+
+func TestPost(t *testing.T) {
+	defer reset()
+
+	secret := "secret"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get("X-Timeful-Timestamp"), 10, 64)
+		if r.Header.Get("X-Timeful-Signature") != sign(secret, timestamp, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/reject" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"error": "outside business hours"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	hook := HttpHook{Point: BEFORE_FINALIZE, Url: server.URL, Secret: secret}
+	ctx := Context{Point: BEFORE_FINALIZE}
+	if err := post(hook, ctx); err != nil {
+		t.Errorf("post = %v, expected nil", err)
+	}
+
+	hook.Url = server.URL + "/reject"
+	if err := post(hook, ctx); err == nil || err.Error() != "outside business hours" {
+		t.Errorf("post = %v, expected outside business hours", err)
+	}
+
+	hook.Secret = "wrong"
+	if err := post(hook, ctx); err == nil {
+		t.Errorf("post with wrong secret = nil, expected an error")
+	}
+}
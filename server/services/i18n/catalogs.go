@@ -0,0 +1,173 @@
+package i18n
+
+var en = Messages{
+	"slack.home.welcome":                "Welcome to Timeful!",
+	"slack.home.linkPrompt":             "Link your Timeful account to see your events, pending responses, and scheduled meetings right here in Slack.",
+	"slack.home.linkAccount":            "Link Timeful account",
+	"slack.home.openEvents":             ":calendar: Your open events",
+	"slack.home.noOpenEvents":           "You don't have any open events.",
+	"slack.home.more":                   "And %d more...",
+	"slack.home.responses.one":          "%d response",
+	"slack.home.responses.other":        "%d responses",
+	"slack.home.stillWaiting":           " · %d still waiting",
+	"slack.home.nudge":                  "Nudge non-responders",
+	"slack.home.nudgeConfirmTitle":      "Nudge non-responders?",
+	"slack.home.nudgeConfirmText.one":   "This will send a reminder email to %d person.",
+	"slack.home.nudgeConfirmText.other": "This will send a reminder email to %d people.",
+	"slack.home.send":                   "Send",
+	"slack.home.cancel":                 "Cancel",
+	"slack.home.pendingResponses":       ":hourglass_flowing_sand: Waiting for your response",
+	"slack.home.caughtUp":               "You're all caught up!",
+	"slack.home.upcomingMeetings":       ":white_check_mark: Upcoming meetings",
+	"slack.home.noUpcomingMeetings":     "No meetings have been scheduled yet.",
+	"slack.home.meetingDate":            "{date_short_pretty} at {time}",
+	"slack.home.signedInAs":             "Signed in as %s",
+	"slack.home.unlinkAccount":          "Unlink account",
+	"slack.open":                        "Open",
+	"slack.unknownDate":                 "Unknown date",
+	"slack.nudgeSent.one":               "Sent a reminder to %d person who hasn't responded to *%s* yet.",
+	"slack.nudgeSent.other":             "Sent a reminder to %d people who haven't responded to *%s* yet.",
+	"slack.workflow.notLinked":          "Link your Timeful account in the Timeful app's Home tab in Slack to create polls from workflows.",
+	"slack.approval.request":            "%s wants to finalize *%s* for %s. Do you approve?",
+	"slack.approval.approve":            "Approve",
+	"slack.approval.reject":             "Reject",
+	"slack.approval.approved":           "You approved finalizing *%s*. It will be finalized once enough approvers approve it.",
+	"slack.approval.rejected":           "You rejected finalizing *%s*.",
+	"slack.approval.finalized":          "You approved finalizing *%s*, and it's now finalized.",
+	"slack.approval.notPending":         "*%s* is no longer waiting for your approval.",
+	"invite.subject":                    "%s invited you to %s",
+	"invite.greeting":                   "Hi %s,",
+	"invite.greetingNoName":             "Hi,",
+	"invite.body":                       "%s would like to know when you're available for \"%s\".",
+	"invite.link":                       "Add your availability here: %s",
+	"invite.optOut":                     "Don't want these emails? Opt out: %s",
+}
+
+var es = Messages{
+	"slack.home.welcome":                "¡Te damos la bienvenida a Timeful!",
+	"slack.home.linkPrompt":             "Vincula tu cuenta de Timeful para ver tus eventos, respuestas pendientes y reuniones programadas aquí mismo en Slack.",
+	"slack.home.linkAccount":            "Vincular cuenta de Timeful",
+	"slack.home.openEvents":             ":calendar: Tus eventos abiertos",
+	"slack.home.noOpenEvents":           "No tienes eventos abiertos.",
+	"slack.home.more":                   "Y %d más...",
+	"slack.home.responses.one":          "%d respuesta",
+	"slack.home.responses.other":        "%d respuestas",
+	"slack.home.stillWaiting":           " · %d sin responder",
+	"slack.home.nudge":                  "Recordar a quienes no respondieron",
+	"slack.home.nudgeConfirmTitle":      "¿Enviar recordatorio?",
+	"slack.home.nudgeConfirmText.one":   "Se enviará un correo de recordatorio a %d persona.",
+	"slack.home.nudgeConfirmText.other": "Se enviará un correo de recordatorio a %d personas.",
+	"slack.home.send":                   "Enviar",
+	"slack.home.cancel":                 "Cancelar",
+	"slack.home.pendingResponses":       ":hourglass_flowing_sand: Esperando tu respuesta",
+	"slack.home.caughtUp":               "¡Estás al día!",
+	"slack.home.upcomingMeetings":       ":white_check_mark: Próximas reuniones",
+	"slack.home.noUpcomingMeetings":     "Todavía no hay reuniones programadas.",
+	"slack.home.meetingDate":            "{date_short_pretty} a las {time}",
+	"slack.home.signedInAs":             "Sesión iniciada como %s",
+	"slack.home.unlinkAccount":          "Desvincular cuenta",
+	"slack.open":                        "Abrir",
+	"slack.unknownDate":                 "Fecha desconocida",
+	"slack.nudgeSent.one":               "Se envió un recordatorio a %d persona que aún no ha respondido a *%s*.",
+	"slack.nudgeSent.other":             "Se envió un recordatorio a %d personas que aún no han respondido a *%s*.",
+	"slack.workflow.notLinked":          "Vincula tu cuenta de Timeful en la pestaña Inicio de la app de Timeful en Slack para crear encuestas desde flujos de trabajo.",
+	"slack.approval.request":            "%s quiere finalizar *%s* para el %s. ¿Lo apruebas?",
+	"slack.approval.approve":            "Aprobar",
+	"slack.approval.reject":             "Rechazar",
+	"slack.approval.approved":           "Aprobaste finalizar *%s*. Se finalizará cuando suficientes aprobadores lo aprueben.",
+	"slack.approval.rejected":           "Rechazaste finalizar *%s*.",
+	"slack.approval.finalized":          "Aprobaste finalizar *%s* y ya está finalizado.",
+	"slack.approval.notPending":         "*%s* ya no está esperando tu aprobación.",
+	"invite.subject":                    "%s te invitó a %s",
+	"invite.greeting":                   "Hola, %s:",
+	"invite.greetingNoName":             "Hola:",
+	"invite.body":                       "A %s le gustaría saber cuándo tienes disponibilidad para \"%s\".",
+	"invite.link":                       "Indica tu disponibilidad aquí: %s",
+	"invite.optOut":                     "¿No quieres recibir estos correos? Date de baja: %s",
+}
+
+var fr = Messages{
+	"slack.home.welcome":                "Bienvenue sur Timeful !",
+	"slack.home.linkPrompt":             "Associez votre compte Timeful pour voir vos événements, vos réponses en attente et vos réunions planifiées directement dans Slack.",
+	"slack.home.linkAccount":            "Associer le compte Timeful",
+	"slack.home.openEvents":             ":calendar: Vos événements ouverts",
+	"slack.home.noOpenEvents":           "Vous n'avez aucun événement ouvert.",
+	"slack.home.more":                   "Et %d de plus...",
+	"slack.home.responses.one":          "%d réponse",
+	"slack.home.responses.other":        "%d réponses",
+	"slack.home.stillWaiting":           " · %d en attente",
+	"slack.home.nudge":                  "Relancer les non-répondants",
+	"slack.home.nudgeConfirmTitle":      "Relancer les non-répondants ?",
+	"slack.home.nudgeConfirmText.one":   "Un e-mail de rappel sera envoyé à %d personne.",
+	"slack.home.nudgeConfirmText.other": "Un e-mail de rappel sera envoyé à %d personnes.",
+	"slack.home.send":                   "Envoyer",
+	"slack.home.cancel":                 "Annuler",
+	"slack.home.pendingResponses":       ":hourglass_flowing_sand: En attente de votre réponse",
+	"slack.home.caughtUp":               "Vous êtes à jour !",
+	"slack.home.upcomingMeetings":       ":white_check_mark: Réunions à venir",
+	"slack.home.noUpcomingMeetings":     "Aucune réunion n'a encore été planifiée.",
+	"slack.home.meetingDate":            "{date_short_pretty} à {time}",
+	"slack.home.signedInAs":             "Connecté en tant que %s",
+	"slack.home.unlinkAccount":          "Dissocier le compte",
+	"slack.open":                        "Ouvrir",
+	"slack.unknownDate":                 "Date inconnue",
+	"slack.nudgeSent.one":               "Un rappel a été envoyé à %d personne qui n'a pas encore répondu à *%s*.",
+	"slack.nudgeSent.other":             "Un rappel a été envoyé à %d personnes qui n'ont pas encore répondu à *%s*.",
+	"slack.workflow.notLinked":          "Associez votre compte Timeful dans l'onglet Accueil de l'app Timeful sur Slack pour créer des sondages depuis des workflows.",
+	"slack.approval.request":            "%s souhaite finaliser *%s* pour le %s. Approuvez-vous ?",
+	"slack.approval.approve":            "Approuver",
+	"slack.approval.reject":             "Refuser",
+	"slack.approval.approved":           "Vous avez approuvé la finalisation de *%s*. Il sera finalisé dès que suffisamment d'approbateurs l'auront approuvé.",
+	"slack.approval.rejected":           "Vous avez refusé la finalisation de *%s*.",
+	"slack.approval.finalized":          "Vous avez approuvé la finalisation de *%s*, qui est maintenant finalisé.",
+	"slack.approval.notPending":         "*%s* n'attend plus votre approbation.",
+	"invite.subject":                    "%s vous invite à %s",
+	"invite.greeting":                   "Bonjour %s,",
+	"invite.greetingNoName":             "Bonjour,",
+	"invite.body":                       "%s aimerait savoir quand vous êtes disponible pour « %s ».",
+	"invite.link":                       "Indiquez vos disponibilités ici : %s",
+	"invite.optOut":                     "Vous ne souhaitez plus recevoir ces e-mails ? Désinscrivez-vous : %s",
+}
+
+var de = Messages{
+	"slack.home.welcome":                "Willkommen bei Timeful!",
+	"slack.home.linkPrompt":             "Verknüpfe dein Timeful-Konto, um deine Events, ausstehenden Antworten und geplanten Meetings direkt in Slack zu sehen.",
+	"slack.home.linkAccount":            "Timeful-Konto verknüpfen",
+	"slack.home.openEvents":             ":calendar: Deine offenen Events",
+	"slack.home.noOpenEvents":           "Du hast keine offenen Events.",
+	"slack.home.more":                   "Und %d weitere...",
+	"slack.home.responses.one":          "%d Antwort",
+	"slack.home.responses.other":        "%d Antworten",
+	"slack.home.stillWaiting":           " · %d ausstehend",
+	"slack.home.nudge":                  "An Antwort erinnern",
+	"slack.home.nudgeConfirmTitle":      "An Antwort erinnern?",
+	"slack.home.nudgeConfirmText.one":   "Es wird eine Erinnerungs-E-Mail an %d Person gesendet.",
+	"slack.home.nudgeConfirmText.other": "Es werden Erinnerungs-E-Mails an %d Personen gesendet.",
+	"slack.home.send":                   "Senden",
+	"slack.home.cancel":                 "Abbrechen",
+	"slack.home.pendingResponses":       ":hourglass_flowing_sand: Warten auf deine Antwort",
+	"slack.home.caughtUp":               "Du bist auf dem neuesten Stand!",
+	"slack.home.upcomingMeetings":       ":white_check_mark: Anstehende Meetings",
+	"slack.home.noUpcomingMeetings":     "Es wurden noch keine Meetings geplant.",
+	"slack.home.meetingDate":            "{date_short_pretty} um {time}",
+	"slack.home.signedInAs":             "Angemeldet als %s",
+	"slack.home.unlinkAccount":          "Konto trennen",
+	"slack.open":                        "Öffnen",
+	"slack.unknownDate":                 "Unbekanntes Datum",
+	"slack.nudgeSent.one":               "Eine Erinnerung wurde an %d Person gesendet, die noch nicht auf *%s* geantwortet hat.",
+	"slack.nudgeSent.other":             "Erinnerungen wurden an %d Personen gesendet, die noch nicht auf *%s* geantwortet haben.",
+	"slack.workflow.notLinked":          "Verknüpfe dein Timeful-Konto im Tab „Home“ der Timeful-App in Slack, um Umfragen aus Workflows zu erstellen.",
+	"slack.approval.request":            "%s möchte *%s* auf %s festlegen. Stimmst du zu?",
+	"slack.approval.approve":            "Zustimmen",
+	"slack.approval.reject":             "Ablehnen",
+	"slack.approval.approved":           "Du hast dem Festlegen von *%s* zugestimmt. Es wird festgelegt, sobald genügend Genehmigende zugestimmt haben.",
+	"slack.approval.rejected":           "Du hast das Festlegen von *%s* abgelehnt.",
+	"slack.approval.finalized":          "Du hast dem Festlegen von *%s* zugestimmt, und es ist jetzt festgelegt.",
+	"slack.approval.notPending":         "*%s* wartet nicht mehr auf deine Zustimmung.",
+	"invite.subject":                    "%s hat dich zu %s eingeladen",
+	"invite.greeting":                   "Hallo %s,",
+	"invite.greetingNoName":             "Hallo,",
+	"invite.body":                       "%s möchte wissen, wann du für „%s“ Zeit hast.",
+	"invite.link":                       "Trage hier deine Verfügbarkeit ein: %s",
+	"invite.optOut":                     "Du möchtest diese E-Mails nicht mehr erhalten? Hier abmelden: %s",
+}
@@ -0,0 +1,65 @@
+// Translations of the messages Timeful sends outside of the web app (e.g. in
+// Slack), with fallbacks to English for unsupported locales and missing keys
+package i18n
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Locale used when none of the requested locales are supported
+const DEFAULT_LOCALE = "en"
+
+// Messages of a locale, keyed by message id. Plural messages have a ".one" and
+// an ".other" form
+type Messages map[string]string
+
+var catalogs = map[string]Messages{
+	"en": en,
+	"es": es,
+	"fr": fr,
+	"de": de,
+}
+
+// Returns the first of the given locales (e.g. "pt-BR" or "es_ES") that is
+// supported, matching on the language if the region isn't, or the default
+// locale if none are
+func Resolve(locales ...string) string {
+	for _, locale := range locales {
+		locale = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+		if _, ok := catalogs[locale]; ok {
+			return locale
+		}
+		language, _, _ := strings.Cut(locale, "-")
+		if _, ok := catalogs[language]; ok {
+			return language
+		}
+	}
+	return DEFAULT_LOCALE
+}
+
+// Returns the message with the given id in the locale, formatted with the
+// given args. Falls back to English, and then to the id itself
+func T(locale string, id string, args ...interface{}) string {
+	message, ok := catalogs[Resolve(locale)][id]
+	if !ok {
+		message, ok = catalogs[DEFAULT_LOCALE][id]
+	}
+	if !ok {
+		return id
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// Returns the singular or plural form of the message with the given id,
+// depending on n. n is passed to the message as the first arg
+func Plural(locale string, n int, id string, args ...interface{}) string {
+	form := ".other"
+	if n == 1 {
+		form = ".one"
+	}
+	return T(locale, id+form, append([]interface{}{n}, args...)...)
+}
@@ -0,0 +1,51 @@
+package i18n
+
+import "testing"
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		locales  []string
+		expected string
+	}{
+		{[]string{"es-ES"}, "es"},
+		{[]string{"fr_CA"}, "fr"},
+		{[]string{"pt-BR", "de-DE"}, "de"},
+		{[]string{"pt-BR"}, DEFAULT_LOCALE},
+		{[]string{""}, DEFAULT_LOCALE},
+		{nil, DEFAULT_LOCALE},
+	}
+	for _, test := range tests {
+		if locale := Resolve(test.locales...); locale != test.expected {
+			t.Errorf("Resolve(%v) = %q, expected %q", test.locales, locale, test.expected)
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	if message := T("es-MX", "slack.home.signedInAs", "ana@example.com"); message != "Sesión iniciada como ana@example.com" {
+		t.Errorf("got %q", message)
+	}
+	if message := T("ja-JP", "slack.open"); message != "Open" {
+		t.Errorf("expected fallback to English, got %q", message)
+	}
+	if message := T("en", "missing.id"); message != "missing.id" {
+		t.Errorf("expected fallback to the id, got %q", message)
+	}
+	if message := Plural("en", 1, "slack.nudgeSent", "Standup"); message != "Sent a reminder to 1 person who hasn't responded to *Standup* yet." {
+		t.Errorf("got %q", message)
+	}
+	if message := Plural("de", 3, "slack.home.responses"); message != "3 Antworten" {
+		t.Errorf("got %q", message)
+	}
+}
+
+// Every locale should translate every message, so nothing silently falls back
+func TestCatalogsComplete(t *testing.T) {
+	for locale, messages := range catalogs {
+		for id := range en {
+			if _, ok := messages[id]; !ok {
+				t.Errorf("%s is missing %s", locale, id)
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// iCalendar rendering of finalized events, for downloads and calendar
+// subscription feeds
+package ics
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+// Returns the UID of the scheduled time of the event, or of one of its sessions
+func GetUid(event *models.Event, session *models.EventSession) string {
+	if session != nil {
+		return fmt.Sprintf("%s-%s@timeful.app", event.Id.Hex(), session.Id.Hex())
+	}
+	return fmt.Sprintf("%s@timeful.app", event.Id.Hex())
+}
+
+// Returns the VEVENTs of the times the event was finalized at. If respondentId
+// isn't empty, only the sessions the respondent attends are included. Session
+// attendees are listed by name unless withAttendees is false
+func GetEvents(event *models.Event, respondentId string, withAttendees bool, now time.Time) []*ical.Event {
+	eventUrl := fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId())
+	newEvent := func(uid string, start time.Time, end time.Time) *ical.Event {
+		vevent := ical.NewEvent()
+		vevent.Props.SetText(ical.PropUID, uid)
+		vevent.Props.SetDateTime(ical.PropDateTimeStamp, now.UTC())
+		vevent.Props.SetDateTime(ical.PropDateTimeStart, start.UTC())
+		vevent.Props.SetDateTime(ical.PropDateTimeEnd, end.UTC())
+		vevent.Props.SetText(ical.PropSummary, event.Name)
+		description := eventUrl
+		if event.Description != nil && len(*event.Description) > 0 {
+			description = fmt.Sprintf("%s\n\n%s", *event.Description, eventUrl)
+		}
+		vevent.Props.SetText(ical.PropDescription, description)
+		vevent.Props.SetText(ical.PropURL, eventUrl)
+		vevent.SetStatus(ical.EventConfirmed)
+		return vevent
+	}
+
+	events := make([]*ical.Event, 0)
+	if event.ScheduledEvent != nil {
+		events = append(events, newEvent(GetUid(event, nil), event.ScheduledEvent.StartDate.Time(), event.ScheduledEvent.EndDate.Time()))
+	}
+	for i := range event.Sessions {
+		session := &event.Sessions[i]
+		attending := len(respondentId) == 0
+		for _, attendee := range session.Attendees {
+			if attendee.UserId == respondentId {
+				attending = true
+			}
+		}
+		if !attending {
+			continue
+		}
+
+		vevent := newEvent(GetUid(event, session), session.StartDate.Time(), session.EndDate.Time())
+		if withAttendees {
+			for _, attendee := range session.Attendees {
+				prop := ical.NewProp(ical.PropAttendee)
+				prop.Params.Set(ical.ParamCommonName, attendee.Name)
+				// Emails of respondents are private, so they're only listed by name
+				prop.Value = "urn:timeful:respondent"
+				if attendee.IfNeeded {
+					prop.Params.Set(ical.ParamRole, "OPT-PARTICIPANT")
+				}
+				vevent.Props.Add(prop)
+			}
+		}
+		events = append(events, vevent)
+	}
+	return events
+}
+
+// Encodes the events as an iCalendar file with the given name
+func Encode(name string, events []*ical.Event) ([]byte, error) {
+	cal := ical.NewCalendar()
+	cal.Props.SetText(ical.PropVersion, "2.0")
+	cal.Props.SetText(ical.PropProductID, "-//Timeful//Events//EN")
+	cal.Props.SetText(ical.PropMethod, "PUBLISH")
+	cal.Props.SetText("X-WR-CALNAME", name)
+	for _, event := range events {
+		cal.Children = append(cal.Children, event.Component)
+	}
+
+	var buf bytes.Buffer
+	if err := ical.NewEncoder(&buf).Encode(cal); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,53 @@
+package ics
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func TestGetEvents(t *testing.T) {
+	start := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	event := &models.Event{
+		Id:   primitive.NewObjectID(),
+		Name: "Training",
+		Sessions: []models.EventSession{
+			{Id: primitive.NewObjectID(), StartDate: primitive.NewDateTimeFromTime(start), EndDate: primitive.NewDateTimeFromTime(start.Add(time.Hour)), Attendees: []models.SessionAttendee{{UserId: "a", Name: "Ana"}}},
+			{Id: primitive.NewObjectID(), StartDate: primitive.NewDateTimeFromTime(start.Add(24 * time.Hour)), EndDate: primitive.NewDateTimeFromTime(start.Add(25 * time.Hour)), Attendees: []models.SessionAttendee{{UserId: "b", Name: "Bo"}}},
+		},
+	}
+
+	if events := GetEvents(event, "", true, time.Now()); len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	events := GetEvents(event, "b", false, time.Now())
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	if uid, _ := events[0].Props.Text(ical.PropUID); uid != GetUid(event, &event.Sessions[1]) {
+		t.Errorf("got uid %q", uid)
+	}
+	if events[0].Props.Get(ical.PropAttendee) != nil {
+		t.Error("expected attendees to be left out")
+	}
+
+	data, err := Encode("Training", events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal, err := ical.NewDecoder(bytes.NewReader(data)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := cal.Events()
+	if len(decoded) != 1 {
+		t.Fatalf("expected 1 decoded event, got %d", len(decoded))
+	}
+	if decodedStart, err := decoded[0].DateTimeStart(time.UTC); err != nil || !decodedStart.Equal(start.Add(24*time.Hour)) {
+		t.Errorf("got start %v, %v", decodedStart, err)
+	}
+}
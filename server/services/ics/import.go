@@ -0,0 +1,274 @@
+package ics
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/teambition/rrule-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services/regions"
+	"schej.it/server/utils"
+)
+
+const (
+	// How long a fetched feed is used before it's fetched again
+	REFRESH_INTERVAL = time.Hour
+
+	// Feeds that haven't been imported from for this long stop being
+	// refreshed, and are deleted
+	UNUSED_AFTER = 30 * 24 * time.Hour
+
+	// Window around the fetch time that busy times are kept for
+	HISTORY = 30 * 24 * time.Hour
+	HORIZON = 365 * 24 * time.Hour
+
+	MAX_FEED_SIZE  = 5 << 20
+	MAX_BUSY_TIMES = 10000
+)
+
+// Name the latency of fetching feeds is recorded under, alongside the
+// calendar providers
+const PROVIDER = "ics"
+
+// Returned when the feed can't be fetched
+var ErrUnreachable = errors.New("ics feed unreachable")
+
+// Returned when the feed isn't a valid iCalendar file
+var ErrInvalid = errors.New("ics feed invalid")
+
+var client = &http.Client{
+	Timeout: 15 * time.Second,
+	Transport: &http.Transport{
+		Proxy:       http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{Timeout: 10 * time.Second, Control: utils.CheckPublicAddress}).DialContext,
+	},
+}
+
+// Returns the url of the feed to fetch, turning webcal:// urls into https://
+func NormalizeUrl(rawUrl string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(rawUrl))
+	if err != nil || len(parsed.Host) == 0 {
+		return "", errors.New("invalid url")
+	}
+	switch parsed.Scheme {
+	case "webcal", "webcals":
+		parsed.Scheme = "https"
+	case "http", "https":
+	default:
+		return "", errors.New("url must be http, https or webcal")
+	}
+	parsed.Fragment = ""
+	return parsed.String(), nil
+}
+
+func fetch(feedUrl string) ([]byte, error) {
+	req, err := http.NewRequest("GET", feedUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnreachable, err)
+	}
+	req.Header.Set("Accept", "text/calendar, */*")
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnreachable, err)
+	}
+	regions.RecordLatency(PROVIDER, time.Since(start))
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d", ErrUnreachable, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MAX_FEED_SIZE+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnreachable, err)
+	}
+	if len(data) > MAX_FEED_SIZE {
+		return nil, fmt.Errorf("%w: larger than %d bytes", ErrInvalid, MAX_FEED_SIZE)
+	}
+	return data, nil
+}
+
+// Parses the start of every occurrence of the recurring event between after
+// and before
+func getOccurrences(event ical.Event, start time.Time, after time.Time, before time.Time) ([]time.Time, error) {
+	roption, err := event.Props.RecurrenceRule()
+	if err != nil {
+		return nil, err
+	}
+	roption.Dtstart = start
+	rule, err := rrule.NewRRule(*roption)
+	if err != nil {
+		return nil, err
+	}
+
+	set := rrule.Set{}
+	set.RRule(rule)
+	// EXDATE and RDATE can list several comma separated dates
+	for name, add := range map[string]func(time.Time){ical.PropExceptionDates: set.ExDate, ical.PropRecurrenceDates: set.RDate} {
+		for _, prop := range event.Props[name] {
+			for _, value := range strings.Split(prop.Value, ",") {
+				dateProp := prop
+				dateProp.Value = value
+				if t, err := dateProp.DateTime(start.Location()); err == nil {
+					add(t)
+				}
+			}
+		}
+	}
+	return set.Between(after, before, true), nil
+}
+
+// Parses the busy times in the iCalendar data that overlap the given time
+// range, expanding recurring events. Free and cancelled events are skipped,
+// along with events that can't be parsed
+func ParseBusyTimes(data []byte, timeMin time.Time, timeMax time.Time) ([]models.CalendarEvent, error) {
+	cal, err := ical.NewDecoder(bytes.NewReader(data)).Decode()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	// Floating times are in the calendar's timezone if it has one
+	loc := time.UTC
+	if prop := cal.Props.Get("X-WR-TIMEZONE"); prop != nil {
+		if calLoc, err := time.LoadLocation(prop.Value); err == nil {
+			loc = calLoc
+		}
+	}
+
+	// Occurrences of recurring events that were moved or cancelled, by UID
+	overridden := make(map[string]models.Set[int64])
+	for _, event := range cal.Events() {
+		if prop := event.Props.Get(ical.PropRecurrenceID); prop != nil {
+			if t, err := prop.DateTime(loc); err == nil {
+				uid := getText(event, ical.PropUID)
+				if _, ok := overridden[uid]; !ok {
+					overridden[uid] = make(models.Set[int64])
+				}
+				overridden[uid][t.UnixMilli()] = struct{}{}
+			}
+		}
+	}
+
+	busy := make([]models.CalendarEvent, 0)
+	for _, event := range cal.Events() {
+		if strings.EqualFold(getText(event, ical.PropTransparency), "TRANSPARENT") || strings.EqualFold(getText(event, ical.PropStatus), string(ical.EventCancelled)) {
+			continue
+		}
+		startProp := event.Props.Get(ical.PropDateTimeStart)
+		if startProp == nil {
+			continue
+		}
+		start, err := event.DateTimeStart(loc)
+		if err != nil {
+			continue
+		}
+		end, err := event.DateTimeEnd(loc)
+		if err != nil || end.Before(start) {
+			continue
+		}
+		duration := end.Sub(start)
+		allDay := startProp.ValueType() == ical.ValueDate || len(startProp.Value) == len("20060102")
+
+		uid := getText(event, ical.PropUID)
+		starts := []time.Time{start}
+		if event.Props.Get(ical.PropRecurrenceRule) != nil && event.Props.Get(ical.PropRecurrenceID) == nil {
+			if starts, err = getOccurrences(event, start, timeMin.Add(-duration), timeMax); err != nil {
+				continue
+			}
+		}
+
+		for _, occurrenceStart := range starts {
+			if event.Props.Get(ical.PropRecurrenceID) == nil {
+				if _, ok := overridden[uid][occurrenceStart.UnixMilli()]; ok {
+					continue
+				}
+			}
+			occurrenceEnd := occurrenceStart.Add(duration)
+			if !occurrenceEnd.After(timeMin) || !occurrenceStart.Before(timeMax) {
+				continue
+			}
+			busy = append(busy, models.CalendarEvent{
+				Id:        uid,
+				Summary:   getText(event, ical.PropSummary),
+				StartDate: primitive.NewDateTimeFromTime(occurrenceStart),
+				EndDate:   primitive.NewDateTimeFromTime(occurrenceEnd),
+				AllDay:    allDay,
+			})
+			if len(busy) >= MAX_BUSY_TIMES {
+				return busy, nil
+			}
+		}
+	}
+	return busy, nil
+}
+
+func getText(event ical.Event, name string) string {
+	if prop := event.Props.Get(name); prop != nil {
+		return prop.Value
+	}
+	return ""
+}
+
+// Fetches the feed and parses its busy times around the given time
+func refresh(feed *models.IcsFeed, now time.Time) error {
+	data, err := fetch(feed.Url)
+	if err != nil {
+		return err
+	}
+	busy, err := ParseBusyTimes(data, now.Add(-HISTORY), now.Add(HORIZON))
+	if err != nil {
+		return err
+	}
+	feed.BusyTimes = busy
+	feed.FetchedAt = primitive.NewDateTimeFromTime(now)
+	feed.LastError = ""
+	return nil
+}
+
+// Returns the feed with the given url, fetching it unless it was fetched
+// recently. Feeds that can't be fetched or parsed aren't cached
+func GetFeed(feedUrl string, now time.Time) (*models.IcsFeed, error) {
+	feed := db.GetIcsFeedByUrl(feedUrl)
+	if feed == nil || len(feed.LastError) > 0 || now.Sub(feed.FetchedAt.Time()) >= REFRESH_INTERVAL {
+		if feed == nil {
+			feed = &models.IcsFeed{Url: feedUrl}
+		}
+		if err := refresh(feed, now); err != nil {
+			return nil, err
+		}
+	}
+
+	feed.LastUsedAt = primitive.NewDateTimeFromTime(now)
+	db.UpsertIcsFeed(feed)
+	return feed, nil
+}
+
+// Refreshes the feeds that were imported from recently, and deletes the ones
+// that weren't. Feeds that fail to refresh keep their last busy times. Only
+// runs in the region closest to the feeds when the API runs in several
+func RefreshFeeds(now time.Time) {
+	if !regions.IsPreferred(PROVIDER, now) {
+		return
+	}
+	for _, feed := range db.GetIcsFeedsToRefresh(now.Add(-UNUSED_AFTER), now.Add(-REFRESH_INTERVAL)) {
+		if err := refresh(&feed, now); err != nil {
+			logger.StdErr.Printf("Failed to refresh ics feed %s: %v\n", feed.Id.Hex(), err)
+			feed.LastError = err.Error()
+			// Retry at the next interval instead of on every run
+			feed.FetchedAt = primitive.NewDateTimeFromTime(now)
+		}
+		db.UpsertIcsFeed(&feed)
+	}
+	db.DeleteUnusedIcsFeeds(now.Add(-UNUSED_AFTER))
+}
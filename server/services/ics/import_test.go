@@ -0,0 +1,103 @@
+package ics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const timetable = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//EN
+X-WR-TIMEZONE:Europe/Berlin
+BEGIN:VEVENT
+UID:maths@school
+DTSTAMP:20260301T000000Z
+SUMMARY:Maths
+DTSTART:20260302T080000
+DTEND:20260302T093000
+RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=6
+EXDATE:20260304T080000,20260309T080000
+END:VEVENT
+BEGIN:VEVENT
+UID:maths@school
+DTSTAMP:20260301T000000Z
+RECURRENCE-ID:20260311T080000
+SUMMARY:Maths (moved)
+DTSTART:20260311T120000
+DTEND:20260311T133000
+END:VEVENT
+BEGIN:VEVENT
+UID:trip@school
+DTSTAMP:20260301T000000Z
+SUMMARY:Trip
+DTSTART;VALUE=DATE:20260305
+DTEND;VALUE=DATE:20260306
+END:VEVENT
+BEGIN:VEVENT
+UID:free@school
+DTSTAMP:20260301T000000Z
+SUMMARY:Optional study hall
+DTSTART:20260302T140000Z
+DTEND:20260302T150000Z
+TRANSP:TRANSPARENT
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestParseBusyTimes(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	busy, err := ParseBusyTimes([]byte(strings.ReplaceAll(timetable, "\n", "\r\n")), time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]string, 0)
+	for _, b := range busy {
+		got = append(got, b.Summary+" "+b.StartDate.Time().In(berlin).Format("Jan 2 15:04"))
+	}
+	// Mar 4 and 9 are excluded, and Mar 11 was moved
+	want := []string{"Maths Mar 2 08:00", "Maths (moved) Mar 11 12:00", "Trip Mar 5 00:00"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("got busy times %v, want %v", got, want)
+	}
+	if !busy[2].AllDay {
+		t.Error("expected the trip to be all day")
+	}
+
+	if _, err := ParseBusyTimes([]byte("not a calendar"), time.Now(), time.Now()); !errors.Is(err, ErrInvalid) {
+		t.Errorf("got error %v, want ErrInvalid", err)
+	}
+}
+
+func TestNormalizeUrl(t *testing.T) {
+	if got, err := NormalizeUrl(" webcal://example.com/cal.ics#x "); err != nil || got != "https://example.com/cal.ics" {
+		t.Errorf("got %q and error %v", got, err)
+	}
+	for _, invalid := range []string{"ftp://example.com/cal.ics", "example.com/cal.ics", ""} {
+		if _, err := NormalizeUrl(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cal.ics" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(timetable))
+	}))
+	defer server.Close()
+
+	if data, err := fetch(server.URL + "/cal.ics"); err != nil || !strings.HasPrefix(string(data), "BEGIN:VCALENDAR") {
+		t.Errorf("got %q and error %v", data, err)
+	}
+	if _, err := fetch(server.URL + "/missing.ics"); !errors.Is(err, ErrUnreachable) {
+		t.Errorf("got error %v, want ErrUnreachable", err)
+	}
+}
@@ -0,0 +1,237 @@
+// Verifies the external identities users link to their account, and signs the
+// tokens used to merge two accounts when an identity already belongs to
+// another account
+package identities
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/brianvoe/sjwt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/models"
+	"schej.it/server/services/auth"
+	"schej.it/server/services/jwks"
+	"schej.it/server/services/microsoftgraph"
+	"schej.it/server/utils"
+)
+
+// How long a merge token can be used after the link that conflicted
+const MERGE_TOKEN_EXPIRY = 15 * time.Minute
+
+const (
+	appleIssuer  = "https://appleid.apple.com"
+	appleJwksUrl = "https://appleid.apple.com/auth/keys"
+
+	discordTokenUrl = "https://discord.com/api/oauth2/token"
+	discordUserUrl  = "https://discord.com/api/users/@me"
+)
+
+var (
+	ErrUnsupportedProvider   = errors.New("unsupported identity provider")
+	ErrProviderNotConfigured = errors.New("identity provider isn't configured")
+	ErrInvalidCredentials    = errors.New("invalid credentials")
+	ErrUnverifiedEmail       = errors.New("email of the identity isn't verified")
+)
+
+// What the client got back from the provider's sign in flow
+type Credentials struct {
+	// OAuth authorization code (google, microsoft, discord), or the link code
+	// shown in the Slack App Home (slack)
+	Code  string `json:"code"`
+	Scope string `json:"scope"`
+
+	// ID token (apple)
+	IdToken string `json:"idToken"`
+}
+
+// Exchanges the credentials with the provider and returns the identity they
+// belong to
+func Verify(provider models.IdentityProvider, credentials Credentials, origin string) (*models.Identity, error) {
+	identity := &models.Identity{
+		Provider: provider,
+		LinkedAt: primitive.NewDateTimeFromTime(time.Now()),
+	}
+
+	switch provider {
+	case models.GOOGLE_IDENTITY:
+		if len(credentials.Code) == 0 {
+			return nil, ErrInvalidCredentials
+		}
+		tokens := auth.GetTokensFromAuthCode(credentials.Code, credentials.Scope, origin, models.GoogleCalendarType)
+		claims := utils.ParseJWT(tokens.IdToken)
+		identity.Subject, _ = claims.GetStr("sub")
+		if verified, _ := claims.GetBool("email_verified"); verified {
+			identity.Email, _ = claims.GetStr("email")
+		}
+	case models.MICROSOFT_IDENTITY:
+		if len(credentials.Code) == 0 {
+			return nil, ErrInvalidCredentials
+		}
+		tokens := auth.GetTokensFromAuthCode(credentials.Code, credentials.Scope, origin, models.OutlookCalendarType)
+		userInfo := microsoftgraph.GetUserInfo(nil, &models.OAuth2CalendarAuth{
+			AccessToken:           tokens.AccessToken,
+			AccessTokenExpireDate: primitive.NewDateTimeFromTime(utils.GetAccessTokenExpireDate(tokens.ExpiresIn)),
+			RefreshToken:          tokens.RefreshToken,
+			Scope:                 tokens.Scope,
+		})
+		// The mail and user principal name are set by the account's tenant, so
+		// they aren't verified and can change. Only the object id identifies it
+		identity.Subject = userInfo.Id
+	case models.APPLE_IDENTITY:
+		clientId := os.Getenv("APPLE_CLIENT_ID")
+		if len(clientId) == 0 {
+			return nil, ErrProviderNotConfigured
+		}
+		claims, err := jwks.Verify(credentials.IdToken, appleJwksUrl)
+		if err != nil || claims.String("iss") != appleIssuer || !claims.HasAudience(clientId) {
+			return nil, ErrInvalidCredentials
+		}
+		identity.Subject = claims.String("sub")
+		// Apple sends the boolean claims as strings
+		if verified := claims["email_verified"]; verified == true || verified == "true" {
+			identity.Email = claims.String("email")
+		}
+	case models.SLACK_IDENTITY:
+		linkCode := db.ConsumeSlackLinkCode(credentials.Code)
+		if linkCode == nil {
+			return nil, ErrInvalidCredentials
+		}
+		identity.Subject = linkCode.SlackUserId
+		identity.TeamId = linkCode.SlackTeamId
+	case models.DISCORD_IDENTITY:
+		user, err := getDiscordUser(credentials.Code, origin)
+		if err != nil {
+			return nil, err
+		}
+		if !user.Verified {
+			return nil, ErrUnverifiedEmail
+		}
+		identity.Subject = user.Id
+		identity.Email = user.Email
+	default:
+		return nil, ErrUnsupportedProvider
+	}
+
+	if len(identity.Subject) == 0 {
+		return nil, ErrInvalidCredentials
+	}
+	return identity, nil
+}
+
+// Returns whether the identities are the same external account
+func Same(a models.Identity, b models.Identity) bool {
+	return a.Provider == b.Provider && a.Subject == b.Subject
+}
+
+type discordUser struct {
+	Id       string `json:"id"`
+	Email    string `json:"email"`
+	Verified bool   `json:"verified"`
+}
+
+// Exchanges the authorization code for an access token and returns the
+// discord user it belongs to
+func getDiscordUser(code string, origin string) (*discordUser, error) {
+	clientId, clientSecret := os.Getenv("DISCORD_CLIENT_ID"), os.Getenv("DISCORD_CLIENT_SECRET")
+	if len(clientId) == 0 || len(clientSecret) == 0 {
+		return nil, ErrProviderNotConfigured
+	}
+	if len(code) == 0 {
+		return nil, ErrInvalidCredentials
+	}
+
+	resp, err := http.PostForm(discordTokenUrl, url.Values{
+		"client_id":     {clientId},
+		"client_secret": {clientSecret},
+		"code":          {code},
+		"grant_type":    {"authorization_code"},
+		"redirect_uri":  {fmt.Sprintf("%s/auth", origin)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrInvalidCredentials
+	}
+	var tokens struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, discordUserUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	userResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer userResp.Body.Close()
+	if userResp.StatusCode != http.StatusOK {
+		return nil, ErrInvalidCredentials
+	}
+	var user discordUser
+	if err := json.NewDecoder(userResp.Body).Decode(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func getSecret() []byte {
+	return []byte(os.Getenv("ENCRYPTION_KEY"))
+}
+
+// Returns a token that lets the user merge the other account, which the
+// identity belongs to, into their own
+func NewMergeToken(userId primitive.ObjectID, otherUserId primitive.ObjectID, identity models.Identity, now time.Time) string {
+	identityJson, _ := json.Marshal(identity)
+
+	claims := sjwt.New()
+	claims.Set("userId", userId.Hex())
+	claims.Set("otherUserId", otherUserId.Hex())
+	claims.Set("identity", string(identityJson))
+	claims.SetExpiresAt(now.Add(MERGE_TOKEN_EXPIRY))
+	return claims.Generate(getSecret())
+}
+
+// Returns the other account and the identity of the merge token, or an error
+// if it isn't a valid token for the user
+func ParseMergeToken(token string, userId primitive.ObjectID, now time.Time) (primitive.ObjectID, *models.Identity, error) {
+	if !sjwt.Verify(token, getSecret()) {
+		return primitive.NilObjectID, nil, fmt.Errorf("invalid token signature")
+	}
+	claims, err := sjwt.Parse(token)
+	if err != nil {
+		return primitive.NilObjectID, nil, err
+	}
+	expiresAt, err := claims.GetExpiresAt()
+	if err != nil || now.Unix() > expiresAt {
+		return primitive.NilObjectID, nil, fmt.Errorf("token expired")
+	}
+	if tokenUserId, _ := claims.GetStr("userId"); tokenUserId != userId.Hex() {
+		return primitive.NilObjectID, nil, fmt.Errorf("token is for another user")
+	}
+
+	otherUserIdHex, _ := claims.GetStr("otherUserId")
+	otherUserId, err := primitive.ObjectIDFromHex(otherUserIdHex)
+	if err != nil {
+		return primitive.NilObjectID, nil, err
+	}
+	identityJson, _ := claims.GetStr("identity")
+	var identity models.Identity
+	if err := json.Unmarshal([]byte(identityJson), &identity); err != nil {
+		return primitive.NilObjectID, nil, err
+	}
+	return otherUserId, &identity, nil
+}
@@ -0,0 +1,53 @@
+package identities
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func TestParseMergeToken(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEY", "test-key")
+	now := time.Date(2024, time.May, 14, 12, 0, 0, 0, time.UTC)
+	userId, otherUserId := primitive.NewObjectID(), primitive.NewObjectID()
+	identity := models.Identity{
+		Provider: models.DISCORD_IDENTITY,
+		Subject:  "80351110224678912",
+		Email:    "nelly@example.com",
+		LinkedAt: primitive.NewDateTimeFromTime(now),
+	}
+
+	token := NewMergeToken(userId, otherUserId, identity, now)
+	gotUserId, gotIdentity, err := ParseMergeToken(token, userId, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("got %v", err)
+	}
+	if gotUserId != otherUserId || *gotIdentity != identity {
+		t.Errorf("got %v, %+v", gotUserId, gotIdentity)
+	}
+
+	if _, _, err := ParseMergeToken(token, otherUserId, now); err == nil {
+		t.Errorf("expected an error for another user")
+	}
+	if _, _, err := ParseMergeToken(token, userId, now.Add(MERGE_TOKEN_EXPIRY+time.Minute)); err == nil {
+		t.Errorf("expected an error for an expired token")
+	}
+	if _, _, err := ParseMergeToken(token+"x", userId, now); err == nil {
+		t.Errorf("expected an error for a tampered token")
+	}
+}
+
+func TestSame(t *testing.T) {
+	a := models.Identity{Provider: models.GOOGLE_IDENTITY, Subject: "1", Email: "a@example.com"}
+	if !Same(a, models.Identity{Provider: models.GOOGLE_IDENTITY, Subject: "1"}) {
+		t.Errorf("expected the same subject to be the same identity")
+	}
+	if Same(a, models.Identity{Provider: models.APPLE_IDENTITY, Subject: "1"}) {
+		t.Errorf("expected another provider to be another identity")
+	}
+	if Same(a, models.Identity{Provider: models.GOOGLE_IDENTITY, Subject: "2", Email: "a@example.com"}) {
+		t.Errorf("expected another subject to be another identity")
+	}
+}
@@ -0,0 +1,148 @@
+// Insights from past events: the weekly availability of a group across many
+// events (e.g. "Tuesdays 3-5pm are historically best for this group"), and an
+// organizer's scheduling patterns
+package insights
+
+import (
+	"sort"
+	"time"
+
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// Availability of the group during an hour of the week
+type Cell struct {
+	Weekday time.Weekday `json:"weekday"`
+	Hour    int          `json:"hour"`
+
+	// Average fraction of the respondents that were available
+	Score float64 `json:"score"`
+
+	// Number of events that had this hour on their grid
+	NumEvents int `json:"numEvents"`
+}
+
+// A range of hours on a weekday, e.g. Tuesdays 3-5pm
+type Window struct {
+	Weekday   time.Weekday `json:"weekday"`
+	StartHour int          `json:"startHour"`
+	EndHour   int          `json:"endHour"`
+	Score     float64      `json:"score"`
+}
+
+type cell struct {
+	sum     float64
+	samples int
+	events  models.Set[string]
+}
+
+// Aggregates availability by hour of the week in a location
+type Heatmap struct {
+	loc   *time.Location
+	cells map[int]*cell
+}
+
+func NewHeatmap(loc *time.Location) *Heatmap {
+	return &Heatmap{loc: loc, cells: make(map[int]*cell)}
+}
+
+// Adds the availability of the respondents on the event's grid. Only
+// respondents for which include returns true are counted, if include is set
+func (h *Heatmap) AddEvent(event *models.Event, respondents []scheduling.Respondent, include func(respondent scheduling.Respondent) bool) {
+	counted := make([]scheduling.Respondent, 0)
+	for _, respondent := range respondents {
+		if include == nil || include(respondent) {
+			counted = append(counted, respondent)
+		}
+	}
+	if len(counted) == 0 || utils.Coalesce(event.DaysOnly) {
+		return
+	}
+
+	for _, t := range scheduling.GetTimeIncrements(event) {
+		key := t.UnixMilli()
+		available := 0.0
+		for _, respondent := range counted {
+			if _, ok := respondent.Available[key]; ok {
+				available++
+			} else if _, ok := respondent.IfNeeded[key]; ok {
+				available += scheduling.DefaultIfNeededWeight
+			}
+		}
+
+		local := t.In(h.loc)
+		index := int(local.Weekday())*24 + local.Hour()
+		if h.cells[index] == nil {
+			h.cells[index] = &cell{events: make(models.Set[string])}
+		}
+		h.cells[index].sum += available / float64(len(counted))
+		h.cells[index].samples++
+		h.cells[index].events[event.Id.Hex()] = struct{}{}
+	}
+}
+
+// Returns the hours of the week that were on the grid of at least one event,
+// sorted by weekday and hour
+func (h *Heatmap) Cells() []Cell {
+	cells := make([]Cell, 0)
+	for index, c := range h.cells {
+		cells = append(cells, Cell{
+			Weekday:   time.Weekday(index / 24),
+			Hour:      index % 24,
+			Score:     c.sum / float64(c.samples),
+			NumEvents: len(c.events),
+		})
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].Weekday != cells[j].Weekday {
+			return cells[i].Weekday < cells[j].Weekday
+		}
+		return cells[i].Hour < cells[j].Hour
+	})
+	return cells
+}
+
+// Returns up to limit non-overlapping windows of the given number of hours
+// with the best average score, best first. Windows only include hours that
+// were on the grid of at least minEvents events
+func (h *Heatmap) BestWindows(hours int, limit int, minEvents int) []Window {
+	candidates := make([]Window, 0)
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		for start := 0; start+hours <= 24; start++ {
+			sum := 0.0
+			ok := true
+			for hour := start; hour < start+hours; hour++ {
+				c := h.cells[int(weekday)*24+hour]
+				if c == nil || len(c.events) < minEvents {
+					ok = false
+					break
+				}
+				sum += c.sum / float64(c.samples)
+			}
+			if ok {
+				candidates = append(candidates, Window{Weekday: weekday, StartHour: start, EndHour: start + hours, Score: sum / float64(hours)})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+
+	windows := make([]Window, 0)
+	for _, candidate := range candidates {
+		if len(windows) == limit {
+			break
+		}
+		overlaps := false
+		for _, window := range windows {
+			if window.Weekday == candidate.Weekday && candidate.StartHour < window.EndHour && window.StartHour < candidate.EndHour {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			windows = append(windows, candidate)
+		}
+	}
+	return windows
+}
@@ -0,0 +1,62 @@
+package insights
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+)
+
+// A Tuesday
+var day = time.Date(2024, time.May, 14, 9, 0, 0, 0, time.UTC)
+
+func newRespondent(id string, start time.Time, hours ...int) scheduling.Respondent {
+	r := scheduling.Respondent{Id: id, Available: make(models.Set[int64]), IfNeeded: make(models.Set[int64])}
+	for _, h := range hours {
+		r.Available[start.Add(time.Duration(h-9)*time.Hour).UnixMilli()] = struct{}{}
+	}
+	return r
+}
+
+func newEvent(start time.Time) *models.Event {
+	duration := float32(8)
+	timeIncrement := 60
+	return &models.Event{
+		Id:            primitive.NewObjectID(),
+		Dates:         []primitive.DateTime{primitive.NewDateTimeFromTime(start)},
+		Duration:      &duration,
+		TimeIncrement: &timeIncrement,
+	}
+}
+
+func TestHeatmap(t *testing.T) {
+	heatmap := NewHeatmap(time.UTC)
+
+	// Two Tuesdays where everyone can make 3-5pm
+	for _, start := range []time.Time{day, day.AddDate(0, 0, 7)} {
+		heatmap.AddEvent(newEvent(start), []scheduling.Respondent{
+			newRespondent("a", start, 9, 15, 16),
+			newRespondent("b", start, 10, 15, 16),
+			newRespondent("outsider", start, 9, 10),
+		}, func(r scheduling.Respondent) bool { return r.Id != "outsider" })
+	}
+
+	cells := heatmap.Cells()
+	if len(cells) != 8 || cells[0].Weekday != time.Tuesday || cells[0].Hour != 9 || cells[0].Score != 0.5 || cells[0].NumEvents != 2 {
+		t.Fatalf("got %+v", cells)
+	}
+
+	windows := heatmap.BestWindows(2, 2, 2)
+	if len(windows) != 2 || windows[0] != (Window{Weekday: time.Tuesday, StartHour: 15, EndHour: 17, Score: 1}) {
+		t.Errorf("expected Tuesdays 3-5pm to be best, got %+v", windows)
+	}
+	if windows[1].StartHour < 17 && windows[1].EndHour > 15 {
+		t.Errorf("expected windows not to overlap, got %+v", windows)
+	}
+
+	if windows := heatmap.BestWindows(2, 2, 3); len(windows) != 0 {
+		t.Errorf("expected no windows seen in 3 events, got %+v", windows)
+	}
+}
@@ -0,0 +1,152 @@
+package insights
+
+import (
+	"sort"
+	"time"
+
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+// How long after a reminder responses are attributed to it
+const reminderAttributionWindow = 24 * time.Hour
+
+// Buckets of time between an event being sent out and a reminder, in hours
+var reminderBuckets = []ReminderTiming{
+	{Label: "Within a day", MinHours: 0, MaxHours: 24},
+	{Label: "1-3 days", MinHours: 24, MaxHours: 72},
+	{Label: "3-7 days", MinHours: 72, MaxHours: 168},
+	{Label: "After a week", MinHours: 168},
+}
+
+// An organizer's event and its activity feed
+type EventHistory struct {
+	Event      *models.Event
+	Activities []models.Activity
+
+	// Number of invitees, and how many of them responded
+	NumInvitees  int
+	NumResponded int
+}
+
+// How many responses reminders sent at a given time after the event was sent
+// out brought in
+type ReminderTiming struct {
+	Label    string `json:"label"`
+	MinHours int    `json:"minHours"`
+	MaxHours int    `json:"maxHours,omitempty"` // 0 for no upper bound
+
+	NumReminded  int `json:"numReminded"`
+	NumResponses int `json:"numResponses"`
+
+	// Responses within a day of a reminder per reminded invitee
+	Rate float64 `json:"rate"`
+}
+
+// Summary of an organizer's scheduling patterns
+type OrganizerSummary struct {
+	NumEvents    int `json:"numEvents"`
+	NumFinalized int `json:"numFinalized"`
+
+	// Average time between sending out and finalizing an event, for finalized events
+	AverageHoursToFinalize *float64 `json:"averageHoursToFinalize"`
+
+	AverageResponses float64 `json:"averageResponses"`
+
+	// Fraction of invitees that responded, for events with invitees
+	ResponseRate *float64 `json:"responseRate"`
+
+	ReminderTimings []ReminderTiming `json:"reminderTimings"`
+
+	// The reminder timing with the highest rate, if any reminders were sent
+	BestReminderTiming *ReminderTiming `json:"bestReminderTiming"`
+}
+
+// Returns when the event was sent out: when it was published, or created
+func GetSentAt(event *models.Event) time.Time {
+	if event.PublishedAt != nil {
+		return event.PublishedAt.Time()
+	}
+	return event.Id.Timestamp()
+}
+
+// Summarizes the organizer's events
+func Summarize(histories []EventHistory) OrganizerSummary {
+	summary := OrganizerSummary{NumEvents: len(histories)}
+	timings := make([]ReminderTiming, len(reminderBuckets))
+	copy(timings, reminderBuckets)
+
+	totalHoursToFinalize := 0.0
+	totalResponses := 0
+	numInvitees, numResponded := 0, 0
+	for _, history := range histories {
+		sentAt := GetSentAt(history.Event)
+		activities := make([]models.Activity, len(history.Activities))
+		copy(activities, history.Activities)
+		sort.SliceStable(activities, func(i, j int) bool { return activities[i].CreatedAt < activities[j].CreatedAt })
+
+		// Time to finalize, from the first time the event was finalized
+		for _, activity := range activities {
+			if activity.Type == models.ACTIVITY_FINALIZED {
+				summary.NumFinalized++
+				totalHoursToFinalize += activity.CreatedAt.Time().Sub(sentAt).Hours()
+				break
+			}
+		}
+
+		totalResponses += utils.Coalesce(history.Event.NumResponses)
+		numInvitees += history.NumInvitees
+		numResponded += history.NumResponded
+
+		// Attribute responses to the reminders that preceded them
+		for i, activity := range activities {
+			if activity.Type != models.ACTIVITY_REMINDER_SENT || len(activity.Details) == 0 {
+				continue
+			}
+			remindedAt := activity.CreatedAt.Time()
+			responses := 0
+			for _, later := range activities[i+1:] {
+				if later.CreatedAt.Time().Sub(remindedAt) > reminderAttributionWindow {
+					break
+				}
+				if later.Type == models.ACTIVITY_RESPONDED {
+					responses++
+				}
+			}
+
+			hours := int(remindedAt.Sub(sentAt).Hours())
+			for j := range timings {
+				if hours >= timings[j].MinHours && (timings[j].MaxHours == 0 || hours < timings[j].MaxHours) {
+					timings[j].NumReminded += len(activity.Details)
+					timings[j].NumResponses += responses
+					break
+				}
+			}
+		}
+	}
+
+	if summary.NumFinalized > 0 {
+		average := totalHoursToFinalize / float64(summary.NumFinalized)
+		summary.AverageHoursToFinalize = &average
+	}
+	if summary.NumEvents > 0 {
+		summary.AverageResponses = float64(totalResponses) / float64(summary.NumEvents)
+	}
+	if numInvitees > 0 {
+		rate := float64(numResponded) / float64(numInvitees)
+		summary.ResponseRate = &rate
+	}
+	for i := range timings {
+		if timings[i].NumReminded == 0 {
+			continue
+		}
+		timings[i].Rate = float64(timings[i].NumResponses) / float64(timings[i].NumReminded)
+		if summary.BestReminderTiming == nil || timings[i].Rate > summary.BestReminderTiming.Rate {
+			best := timings[i]
+			summary.BestReminderTiming = &best
+		}
+	}
+	summary.ReminderTimings = timings
+
+	return summary
+}
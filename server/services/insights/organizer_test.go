@@ -0,0 +1,55 @@
+package insights
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func newActivity(activityType models.ActivityType, at time.Time, details ...string) models.Activity {
+	return models.Activity{Type: activityType, CreatedAt: primitive.NewDateTimeFromTime(at), Details: details}
+}
+
+func TestSummarize(t *testing.T) {
+	sentAt := time.Date(2024, time.May, 1, 9, 0, 0, 0, time.UTC)
+	numResponses := 3
+	finalized := &models.Event{Id: primitive.NewObjectIDFromTimestamp(sentAt), NumResponses: &numResponses}
+	open := &models.Event{Id: primitive.NewObjectIDFromTimestamp(sentAt)}
+
+	summary := Summarize([]EventHistory{
+		{
+			Event: finalized,
+			Activities: []models.Activity{
+				newActivity(models.ACTIVITY_FINALIZED, sentAt.Add(48*time.Hour)),
+				newActivity(models.ACTIVITY_REMINDER_SENT, sentAt.Add(2*time.Hour), "a@example.com", "b@example.com"),
+				newActivity(models.ACTIVITY_RESPONDED, sentAt.Add(3*time.Hour)),
+				newActivity(models.ACTIVITY_RESPONDED, sentAt.Add(40*time.Hour)),
+			},
+			NumInvitees:  4,
+			NumResponded: 3,
+		},
+		{
+			Event: open,
+			Activities: []models.Activity{
+				newActivity(models.ACTIVITY_REMINDER_SENT, sentAt.Add(30*time.Hour), "c@example.com"),
+				newActivity(models.ACTIVITY_RESPONDED, sentAt.Add(31*time.Hour)),
+			},
+			NumInvitees: 4,
+		},
+	})
+
+	if summary.NumEvents != 2 || summary.NumFinalized != 1 || summary.AverageHoursToFinalize == nil || *summary.AverageHoursToFinalize != 48 {
+		t.Errorf("unexpected finalization stats %+v", summary)
+	}
+	if summary.AverageResponses != 1.5 || summary.ResponseRate == nil || *summary.ResponseRate != 3.0/8 {
+		t.Errorf("unexpected response stats %+v", summary)
+	}
+	if summary.ReminderTimings[0].NumReminded != 2 || summary.ReminderTimings[0].Rate != 0.5 {
+		t.Errorf("unexpected first day reminders %+v", summary.ReminderTimings[0])
+	}
+	if summary.BestReminderTiming == nil || summary.BestReminderTiming.Label != "1-3 days" || summary.BestReminderTiming.Rate != 1 {
+		t.Errorf("expected reminders after a day to be most effective, got %+v", summary.BestReminderTiming)
+	}
+}
@@ -0,0 +1,271 @@
+// Sends personal invite emails to the invitees of a roster uploaded as a CSV,
+// in batches so large groups don't hit the mail server all at once
+package invites
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services/emailtemplates"
+	"schej.it/server/services/i18n"
+	"schej.it/server/services/notifications"
+	"schej.it/server/services/organizations"
+	"schej.it/server/utils"
+)
+
+const (
+	// Most invitees in a roster
+	MAX_INVITEES = 2000
+
+	// Invites sent per batch each time the job runs
+	BATCH_SIZE = 50
+
+	// How long a server has to send a batch of invites before another can
+	// take over
+	LOCK_DURATION = 5 * time.Minute
+)
+
+// A row of the roster that couldn't be imported
+type RowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// Returns the index of the first header among the given names, or -1
+func findColumn(header []string, names ...string) int {
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		for _, name := range names {
+			if column == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Parses the invitees of a CSV roster. The first row is a header if it has an
+// "email" column, along with optional "name" (or "first name" and "last
+// name") and "locale" (or "language") columns. Without a header, the first
+// column containing an email is the email and the next column is the name.
+// Duplicate emails are dropped, and invalid rows are returned as errors
+func ParseRoster(r io.Reader) ([]models.Invitee, []RowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.LazyQuotes = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil, errors.New("roster is empty")
+	}
+
+	emailColumn, nameColumn, firstNameColumn, lastNameColumn, localeColumn := -1, -1, -1, -1, -1
+	start := 0
+	if column := findColumn(rows[0], "email", "e-mail", "email address"); column != -1 {
+		emailColumn = column
+		nameColumn = findColumn(rows[0], "name", "full name")
+		firstNameColumn = findColumn(rows[0], "first name", "firstname", "given name")
+		lastNameColumn = findColumn(rows[0], "last name", "lastname", "surname", "family name")
+		localeColumn = findColumn(rows[0], "locale", "language", "lang")
+		start = 1
+	}
+
+	get := func(row []string, column int) string {
+		if column < 0 || column >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[column])
+	}
+
+	invitees := make([]models.Invitee, 0)
+	rowErrors := make([]RowError, 0)
+	seen := make(models.Set[string])
+	for i := start; i < len(rows); i++ {
+		row := rows[i]
+		line := i + 1
+		if len(strings.TrimSpace(strings.Join(row, ""))) == 0 {
+			continue
+		}
+
+		invitee := models.Invitee{Status: models.INVITE_PENDING}
+		if emailColumn != -1 {
+			invitee.Email = get(row, emailColumn)
+			invitee.Name = get(row, nameColumn)
+			if len(invitee.Name) == 0 {
+				invitee.Name = strings.TrimSpace(get(row, firstNameColumn) + " " + get(row, lastNameColumn))
+			}
+			invitee.Locale = get(row, localeColumn)
+		} else {
+			for j := range row {
+				if strings.Contains(row[j], "@") {
+					invitee.Email = get(row, j)
+					invitee.Name = get(row, j+1)
+					break
+				}
+			}
+		}
+
+		address, err := mail.ParseAddress(invitee.Email)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Line: line, Error: fmt.Sprintf("invalid email %q", invitee.Email)})
+			continue
+		}
+		invitee.Email = strings.ToLower(address.Address)
+		if len(invitee.Name) == 0 {
+			invitee.Name = address.Name
+		}
+		if _, ok := seen[invitee.Email]; ok {
+			continue
+		}
+		seen[invitee.Email] = struct{}{}
+
+		if len(invitees) == MAX_INVITEES {
+			return nil, nil, fmt.Errorf("roster can have at most %d invitees", MAX_INVITEES)
+		}
+		invitee.Key = NewKey()
+		invitees = append(invitees, invitee)
+	}
+
+	return invitees, rowErrors, nil
+}
+
+// Returns a new secret for an invitee's link
+func NewKey() string {
+	keyBytes := make([]byte, 12)
+	if _, err := rand.Read(keyBytes); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return hex.EncodeToString(keyBytes)
+}
+
+// Returns the personal link of the invitee, which records when they open it
+// before redirecting to the event
+func GetInviteUrl(event *models.Event, invitee *models.Invitee) string {
+	return fmt.Sprintf("%s/api/events/%s/invite/%s", utils.GetBaseUrl(), event.GetId(), invitee.Key)
+}
+
+// Returns the subject and body of the invitee's email, in their locale or
+// the batch's
+func FormatEmail(batch *models.InviteBatch, event *models.Event, owner *models.User, invitee *models.Invitee) (string, string) {
+	locale := i18n.Resolve(invitee.Locale, batch.Locale)
+	ownerName := strings.TrimSpace(owner.FirstName + " " + owner.LastName)
+
+	var body strings.Builder
+	if len(invitee.Name) > 0 {
+		body.WriteString(i18n.T(locale, "invite.greeting", invitee.Name))
+	} else {
+		body.WriteString(i18n.T(locale, "invite.greetingNoName"))
+	}
+	body.WriteString("\n\n")
+	body.WriteString(i18n.T(locale, "invite.body", ownerName, event.Name))
+	body.WriteString("\n\n")
+	if len(batch.Message) > 0 {
+		body.WriteString(batch.Message)
+		body.WriteString("\n\n")
+	}
+	body.WriteString(i18n.T(locale, "invite.link", GetInviteUrl(event, invitee)))
+	body.WriteString("\n\n")
+	body.WriteString(i18n.T(locale, "invite.optOut", notifications.GetOptOutUrl(event.Id, invitee.Email)))
+	body.WriteString("\n")
+
+	return i18n.T(locale, "invite.subject", ownerName, event.Name), body.String()
+}
+
+// Returns the subject, body, and content type of the invitee's email, from
+// the organization's template if it has one, or the default email if it
+// doesn't or the template can't be rendered
+func formatEmailWithTemplate(template *models.EmailTemplate, org *models.Organization, batch *models.InviteBatch, event *models.Event, owner *models.User, invitee *models.Invitee) (string, string, string) {
+	if template != nil {
+		subject, body, err := emailtemplates.Render(*template, map[string]string{
+			"inviteeName":      invitee.Name,
+			"organizerName":    strings.TrimSpace(owner.FirstName + " " + owner.LastName),
+			"organizationName": org.Name,
+			"eventName":        event.Name,
+			"eventUrl":         GetInviteUrl(event, invitee),
+			"message":          batch.Message,
+			"optOutUrl":        notifications.GetOptOutUrl(event.Id, invitee.Email),
+		})
+		if err == nil {
+			return subject, body, "text/html"
+		}
+		logger.StdErr.Println(err)
+	}
+	subject, body := FormatEmail(batch, event, owner, invitee)
+	return subject, body, "text/plain"
+}
+
+// Sends the next invites of the batches that are still sending. Run
+// periodically by the jobs scheduler
+func SendDue(now time.Time) {
+	for batch := db.ClaimInviteBatch(now, now.Add(LOCK_DURATION)); batch != nil; batch = db.ClaimInviteBatch(now, now.Add(LOCK_DURATION)) {
+		SendBatch(batch, time.Now())
+	}
+}
+
+// Sends up to BATCH_SIZE pending invites of the claimed batch, then releases
+// it, marking it as sent once no invites are pending
+func SendBatch(batch *models.InviteBatch, now time.Time) {
+	event := db.GetEventById(batch.EventId.Hex())
+	owner := db.GetUserById(batch.OwnerId.Hex())
+	var optedOut models.Set[string]
+	var org *models.Organization
+	if event != nil {
+		optedOut = db.GetNotificationOptOutEmails(event.Id)
+		org = notifications.GetEventOrganization(event)
+	}
+	sender := organizations.GetEmailSender(org)
+	template := emailtemplates.Get(org, models.EMAIL_TEMPLATE_INVITE)
+
+	numProcessed := 0
+	pending := false
+	for i := range batch.Invitees {
+		invitee := &batch.Invitees[i]
+		if invitee.Status != models.INVITE_PENDING {
+			continue
+		}
+		if numProcessed == BATCH_SIZE {
+			pending = true
+			break
+		}
+		numProcessed++
+
+		if event == nil || owner == nil || utils.Coalesce(event.IsDeleted) {
+			invitee.Status = models.INVITE_SKIPPED
+			invitee.Error = "event deleted"
+		} else if _, ok := optedOut[invitee.Email]; ok {
+			invitee.Status = models.INVITE_SKIPPED
+			invitee.Error = "opted out"
+		} else {
+			subject, body, contentType := formatEmailWithTemplate(template, org, batch, event, owner, invitee)
+			if err := notifications.TrySendBulkEmail(invitee.Email, subject, body, contentType, sender); err == utils.ErrEmailSuppressed {
+				invitee.Status = models.INVITE_SKIPPED
+				invitee.Error = "unsubscribed"
+			} else if err != nil {
+				logger.StdErr.Println(err)
+				invitee.Status = models.INVITE_FAILED
+				invitee.Error = err.Error()
+			} else {
+				sentAt := primitive.NewDateTimeFromTime(now)
+				invitee.Status = models.INVITE_SENT
+				invitee.SentAt = &sentAt
+			}
+		}
+		db.SetInviteStatus(batch.Id, invitee)
+	}
+
+	db.ReleaseInviteBatch(batch.Id, !pending, now)
+}
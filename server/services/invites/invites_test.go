@@ -0,0 +1,95 @@
+package invites
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+)
+
+func TestParseRosterWithHeader(t *testing.T) {
+	roster := "First Name,Last Name,Email,Language\n" +
+		"Ana,Lopez,Ana@Example.com,es-MX\n" +
+		"Ben,,not-an-email,\n" +
+		",,,\n" +
+		"Ana,Lopez,ana@example.com,\n" +
+		"Chloé,Martin,chloe@example.fr,fr\n"
+	invitees, rowErrors, err := ParseRoster(strings.NewReader(roster))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invitees) != 2 {
+		t.Fatalf("got %d invitees, want 2: %+v", len(invitees), invitees)
+	}
+	if invitees[0].Email != "ana@example.com" || invitees[0].Name != "Ana Lopez" || invitees[0].Locale != "es-MX" || invitees[0].Status != models.INVITE_PENDING {
+		t.Errorf("got invitee %+v", invitees[0])
+	}
+	if invitees[0].Key == invitees[1].Key || len(invitees[0].Key) == 0 {
+		t.Error("expected every invitee to get a different key")
+	}
+	if len(rowErrors) != 1 || rowErrors[0].Line != 3 {
+		t.Errorf("got row errors %+v, want line 3", rowErrors)
+	}
+}
+
+func TestParseRosterWithoutHeader(t *testing.T) {
+	invitees, rowErrors, err := ParseRoster(strings.NewReader("1,dee@example.com,Dee\n2,\"Eli <eli@example.com>\"\n"))
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("got errors %v %v", err, rowErrors)
+	}
+	if len(invitees) != 2 || invitees[0].Name != "Dee" || invitees[1].Email != "eli@example.com" || invitees[1].Name != "Eli" {
+		t.Errorf("got invitees %+v", invitees)
+	}
+
+	if _, _, err := ParseRoster(strings.NewReader("")); err == nil {
+		t.Error("expected an empty roster to be rejected")
+	}
+}
+
+func TestFormatEmail(t *testing.T) {
+	shortId := "abc123"
+	event := &models.Event{Id: primitive.NewObjectID(), ShortId: &shortId, Name: "Kickoff"}
+	owner := &models.User{FirstName: "Sam", LastName: "Lee"}
+	batch := &models.InviteBatch{Locale: "en", Message: "Bring snacks"}
+	invitee := &models.Invitee{Email: "ana@example.com", Name: "Ana", Locale: "es", Key: "key1"}
+
+	subject, body := FormatEmail(batch, event, owner, invitee)
+	if subject != "Sam Lee te invitó a Kickoff" {
+		t.Errorf("got subject %q", subject)
+	}
+	for _, want := range []string{"Hola, Ana:", "Bring snacks", "/api/events/abc123/invite/key1", "remind/opt-out"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body %q doesn't contain %q", body, want)
+		}
+	}
+
+	invitee.Locale = ""
+	invitee.Name = ""
+	if subject, body := FormatEmail(batch, event, owner, invitee); subject != "Sam Lee invited you to Kickoff" || !strings.HasPrefix(body, "Hi,\n") {
+		t.Errorf("got subject %q and body %q", subject, body)
+	}
+}
+
+func TestFormatEmailWithTemplate(t *testing.T) {
+	shortId := "abc123"
+	event := &models.Event{Id: primitive.NewObjectID(), ShortId: &shortId, Name: "Kickoff"}
+	owner := &models.User{FirstName: "Sam", LastName: "Lee"}
+	batch := &models.InviteBatch{Locale: "en"}
+	invitee := &models.Invitee{Email: "ana@example.com", Name: "Ana", Key: "key1"}
+	org := &models.Organization{Name: "Acme"}
+
+	template := &models.EmailTemplate{Type: models.EMAIL_TEMPLATE_INVITE, Subject: "{{organizationName}}: {{eventName}}", Body: `<p>Hi {{inviteeName}}, <a href="{{eventUrl}}">respond</a></p>`}
+	subject, body, contentType := formatEmailWithTemplate(template, org, batch, event, owner, invitee)
+	if subject != "Acme: Kickoff" || contentType != "text/html" || !strings.Contains(body, `href="http`) || !strings.Contains(body, "/invite/key1") {
+		t.Errorf("got %q, %q, %q", subject, body, contentType)
+	}
+
+	logger.Init(io.Discard)
+	template.Body = "Hi {{unknown}}"
+	if subject, _, contentType := formatEmailWithTemplate(template, org, batch, event, owner, invitee); subject != "Sam Lee invited you to Kickoff" || contentType != "text/plain" {
+		t.Errorf("expected the default email when the template can't be rendered, got %q", subject)
+	}
+}
@@ -0,0 +1,208 @@
+// Posts the scheduled time and attendees of finalized events to Linear or
+// Jira, as a comment on the issue the event is linked to
+package issuetracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// How long the tracker has to respond
+const TIMEOUT = 15 * time.Second
+
+// Client the trackers are called with. Jira urls come from organizers, so
+// internal addresses are refused and redirects aren't followed, which would
+// otherwise let them reach internal services
+var client = &http.Client{
+	Timeout: TIMEOUT,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: TIMEOUT, Control: utils.CheckPublicAddress}).DialContext,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Error returned by the tracker's API
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("issue tracker: %s (%d)", e.Message, e.Status)
+}
+
+// Sends the request, decoding the JSON response into result if it isn't nil
+func doRequest(req *http.Request, result interface{}) error {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	response, err := client.Do(req)
+	if err != nil {
+		return &Error{Status: http.StatusBadGateway, Message: err.Error()}
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return &Error{Status: http.StatusBadGateway, Message: err.Error()}
+	}
+	if response.StatusCode >= 300 {
+		return &Error{Status: response.StatusCode, Message: strings.TrimSpace(string(body))}
+	}
+	if result != nil && len(body) > 0 {
+		if err := json.Unmarshal(body, result); err != nil {
+			return &Error{Status: http.StatusBadGateway, Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+func isNotFound(err error) bool {
+	trackerErr, ok := err.(*Error)
+	return ok && trackerErr.Status == http.StatusNotFound
+}
+
+// Client of a tracker's API
+type Client interface {
+	// Returns an error if the credentials don't work
+	Verify() error
+
+	// Returns the link to the issue with the given key (e.g. ENG-123), or a
+	// 404 error if there's no such issue
+	GetIssue(issueKey string) (*models.IssueLink, error)
+
+	// Creates an issue in the project, returning the link to it
+	CreateIssue(project string, title string, description string) (*models.IssueLink, error)
+
+	// Posts the comment on the linked issue, or updates the linked comment if
+	// there is one. Returns the id of the comment
+	PostComment(link *models.IssueLink, body string) (string, error)
+}
+
+// Returns the client of the tracker, with its token decrypted
+func NewClient(tracker *models.IssueTracker) (Client, error) {
+	token, err := utils.Decrypt(tracker.Token)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(tracker, token)
+}
+
+func newClient(tracker *models.IssueTracker, token string) (Client, error) {
+	switch tracker.Type {
+	case models.LinearIssueTracker:
+		return &linearClient{apiKey: token}, nil
+	case models.JiraIssueTracker:
+		return &jiraClient{baseUrl: strings.TrimSuffix(tracker.JiraUrl, "/"), email: tracker.JiraEmail, token: token}, nil
+	}
+	return nil, fmt.Errorf("unknown issue tracker %q", tracker.Type)
+}
+
+// Returns the client of the tracker with the given plain text token, after
+// checking that the credentials work
+func Connect(tracker *models.IssueTracker, token string) (Client, error) {
+	client, err := newClient(tracker, token)
+	if err != nil {
+		return nil, err
+	}
+	return client, client.Verify()
+}
+
+// A time the event was finalized at, and who's attending
+type MeetingTime struct {
+	Start     time.Time
+	End       time.Time
+	Attendees []string
+}
+
+// Returns the times the event was finalized at. The attendees of the
+// scheduled time are the respondents that can make it, and those of sessions
+// the respondents assigned to them
+func GetMeetingTimes(event *models.Event, respondents []scheduling.Respondent) []MeetingTime {
+	times := make([]MeetingTime, 0)
+	if event.ScheduledEvent != nil {
+		meetingTime := MeetingTime{
+			Start:     event.ScheduledEvent.StartDate.Time(),
+			End:       event.ScheduledEvent.EndDate.Time(),
+			Attendees: make([]string, 0),
+		}
+		increment := scheduling.GetTimeIncrement(event)
+		for _, respondent := range respondents {
+			if respondent.IsAvailable(meetingTime.Start, meetingTime.End, increment, true) {
+				meetingTime.Attendees = append(meetingTime.Attendees, respondent.Name)
+			}
+		}
+		times = append(times, meetingTime)
+	}
+	for _, session := range event.Sessions {
+		meetingTime := MeetingTime{
+			Start:     session.StartDate.Time(),
+			End:       session.EndDate.Time(),
+			Attendees: make([]string, 0),
+		}
+		for _, attendee := range session.Attendees {
+			meetingTime.Attendees = append(meetingTime.Attendees, attendee.Name)
+		}
+		times = append(times, meetingTime)
+	}
+	return times
+}
+
+// Returns the comment posted for the finalized event, with times formatted in loc
+func FormatComment(event *models.Event, times []MeetingTime, loc *time.Location) string {
+	lines := []string{fmt.Sprintf("Meeting scheduled with Timeful: %s", event.Name)}
+	for _, meetingTime := range times {
+		start := meetingTime.Start.In(loc)
+		end := meetingTime.End.In(loc)
+		when := fmt.Sprintf("%s - %s", start.Format("Mon, Jan 2 3:04 PM"), end.Format("3:04 PM MST"))
+		if start.YearDay() != end.YearDay() || start.Year() != end.Year() {
+			when = fmt.Sprintf("%s - %s", start.Format("Mon, Jan 2 3:04 PM"), end.Format("Mon, Jan 2 3:04 PM MST"))
+		}
+		attendees := "nobody yet"
+		if len(meetingTime.Attendees) > 0 {
+			attendees = strings.Join(meetingTime.Attendees, ", ")
+		}
+		lines = append(lines, "", fmt.Sprintf("When: %s", when), fmt.Sprintf("Attendees: %s", attendees))
+	}
+	lines = append(lines, "", fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId()))
+	return strings.Join(lines, "\n")
+}
+
+// Posts the comment to the event's issue, creating an issue in the tracker's
+// project first if the event isn't linked to one of the tracker's issues.
+// Returns the updated link, or nil if there's no issue to post to
+func PostMeeting(client Client, tracker *models.IssueTracker, link *models.IssueLink, title string, comment string) (*models.IssueLink, error) {
+	if link == nil || link.Type != tracker.Type {
+		if len(tracker.Project) == 0 {
+			return nil, nil
+		}
+		created, err := client.CreateIssue(tracker.Project, title, "")
+		if err != nil {
+			return nil, err
+		}
+		link = created
+	}
+
+	updated := *link
+	commentId, err := client.PostComment(&updated, comment)
+	if err != nil && len(updated.CommentId) > 0 && isNotFound(err) {
+		// The comment was deleted, so it's posted again
+		updated.CommentId = ""
+		commentId, err = client.PostComment(&updated, comment)
+	}
+	if err != nil {
+		return nil, err
+	}
+	updated.CommentId = commentId
+	return &updated, nil
+}
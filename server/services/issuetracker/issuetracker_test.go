@@ -0,0 +1,104 @@
+package issuetracker
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/services/scheduling/schedulingtest"
+)
+
+func newEvent() *models.Event {
+	shortId := "abc123"
+	event := schedulingtest.NewEvent()
+	event.Id = primitive.NewObjectID()
+	event.ShortId = &shortId
+	event.Name = "Design review"
+	event.ScheduledEvent = &models.CalendarEvent{
+		StartDate: primitive.NewDateTimeFromTime(schedulingtest.Hour(10)),
+		EndDate:   primitive.NewDateTimeFromTime(schedulingtest.Hour(11)),
+	}
+	return event
+}
+
+func TestFormatComment(t *testing.T) {
+	event := newEvent()
+	times := GetMeetingTimes(event, []scheduling.Respondent{
+		schedulingtest.NewRespondent("Ana", 10),
+		schedulingtest.NewRespondent("Ben", 9),
+	})
+	if len(times) != 1 || len(times[0].Attendees) != 1 || times[0].Attendees[0] != "Ana" {
+		t.Fatalf("got meeting times %+v, want Ana attending", times)
+	}
+
+	comment := FormatComment(event, times, time.UTC)
+	for _, want := range []string{"Design review", "When: Tue, May 14 10:00 AM - 11:00 AM UTC", "Attendees: Ana", "/e/abc123"} {
+		if !strings.Contains(comment, want) {
+			t.Errorf("comment %q doesn't contain %q", comment, want)
+		}
+	}
+}
+
+type fakeClient struct {
+	comments map[string]string
+	created  int
+	nextId   int
+}
+
+func (f *fakeClient) Verify() error { return nil }
+
+func (f *fakeClient) GetIssue(issueKey string) (*models.IssueLink, error) {
+	return &models.IssueLink{Type: models.LinearIssueTracker, IssueKey: issueKey}, nil
+}
+
+func (f *fakeClient) CreateIssue(project string, title string, description string) (*models.IssueLink, error) {
+	f.created++
+	return &models.IssueLink{Type: models.LinearIssueTracker, IssueKey: project + "-1"}, nil
+}
+
+func (f *fakeClient) PostComment(link *models.IssueLink, body string) (string, error) {
+	if len(link.CommentId) > 0 {
+		if _, ok := f.comments[link.CommentId]; !ok {
+			return "", &Error{Status: http.StatusNotFound, Message: "comment not found"}
+		}
+		f.comments[link.CommentId] = body
+		return link.CommentId, nil
+	}
+	f.nextId++
+	commentId := string(rune('a' + f.nextId))
+	f.comments[commentId] = body
+	return commentId, nil
+}
+
+func TestPostMeeting(t *testing.T) {
+	client := &fakeClient{comments: make(map[string]string)}
+	tracker := &models.IssueTracker{Type: models.LinearIssueTracker}
+
+	// Without a linked issue or a project there's nowhere to post
+	if link, err := PostMeeting(client, tracker, nil, "Design review", "first"); link != nil || err != nil {
+		t.Errorf("got link %v and error %v, want neither", link, err)
+	}
+
+	tracker.Project = "ENG"
+	link, err := PostMeeting(client, tracker, nil, "Design review", "first")
+	if err != nil || link == nil || link.IssueKey != "ENG-1" || client.created != 1 {
+		t.Fatalf("got link %v and error %v, want a new issue", link, err)
+	}
+
+	// Finalizing again updates the comment
+	updated, err := PostMeeting(client, tracker, link, "Design review", "second")
+	if err != nil || updated.CommentId != link.CommentId || client.comments[link.CommentId] != "second" || client.created != 1 {
+		t.Errorf("got link %v and error %v, want the comment updated", updated, err)
+	}
+
+	// Deleted comments are posted again
+	delete(client.comments, link.CommentId)
+	reposted, err := PostMeeting(client, tracker, link, "Design review", "third")
+	if err != nil || reposted.CommentId == link.CommentId || client.comments[reposted.CommentId] != "third" {
+		t.Errorf("got link %v and error %v, want a new comment", reposted, err)
+	}
+}
@@ -0,0 +1,93 @@
+package issuetracker
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"schej.it/server/models"
+)
+
+// Client of Jira's REST API, authenticated with the email and API token of
+// an account
+type jiraClient struct {
+	baseUrl string
+	email   string
+	token   string
+}
+
+func (c *jiraClient) call(method string, path string, body interface{}, result interface{}) error {
+	var reqBody *bytes.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(bodyBytes)
+	} else {
+		reqBody = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequest(method, c.baseUrl+"/rest/api/2"+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.email, c.token)
+	return doRequest(req, result)
+}
+
+func (c *jiraClient) getLink(issueId string, issueKey string) *models.IssueLink {
+	return &models.IssueLink{
+		Type:     models.JiraIssueTracker,
+		IssueKey: issueKey,
+		IssueId:  issueId,
+		IssueUrl: c.baseUrl + "/browse/" + issueKey,
+	}
+}
+
+func (c *jiraClient) Verify() error {
+	return c.call("GET", "/myself", nil, nil)
+}
+
+func (c *jiraClient) GetIssue(issueKey string) (*models.IssueLink, error) {
+	issue := struct {
+		Id  string `json:"id"`
+		Key string `json:"key"`
+	}{}
+	if err := c.call("GET", "/issue/"+url.PathEscape(issueKey)+"?fields=summary", nil, &issue); err != nil {
+		return nil, err
+	}
+	return c.getLink(issue.Id, issue.Key), nil
+}
+
+func (c *jiraClient) CreateIssue(project string, title string, description string) (*models.IssueLink, error) {
+	issue := struct {
+		Id  string `json:"id"`
+		Key string `json:"key"`
+	}{}
+	err := c.call("POST", "/issue", map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":     map[string]interface{}{"key": project},
+			"summary":     title,
+			"description": description,
+			"issuetype":   map[string]interface{}{"name": "Task"},
+		},
+	}, &issue)
+	if err != nil {
+		return nil, err
+	}
+	return c.getLink(issue.Id, issue.Key), nil
+}
+
+func (c *jiraClient) PostComment(link *models.IssueLink, body string) (string, error) {
+	comment := struct {
+		Id string `json:"id"`
+	}{}
+	path := "/issue/" + url.PathEscape(link.IssueKey) + "/comment"
+	if len(link.CommentId) > 0 {
+		err := c.call("PUT", path+"/"+url.PathEscape(link.CommentId), map[string]interface{}{"body": body}, &comment)
+		return link.CommentId, err
+	}
+	err := c.call("POST", path, map[string]interface{}{"body": body}, &comment)
+	return comment.Id, err
+}
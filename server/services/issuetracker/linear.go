@@ -0,0 +1,138 @@
+package issuetracker
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"schej.it/server/models"
+)
+
+const LINEAR_API_URL = "https://api.linear.app/graphql"
+
+// Client of Linear's GraphQL API, authenticated with a personal API key
+type linearClient struct {
+	apiKey string
+}
+
+func (c *linearClient) query(query string, variables map[string]interface{}, data interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", LINEAR_API_URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", c.apiKey)
+
+	result := struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message    string `json:"message"`
+			Extensions struct {
+				Code string `json:"code"`
+			} `json:"extensions"`
+		} `json:"errors"`
+	}{}
+	if err := doRequest(req, &result); err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		status := http.StatusBadRequest
+		if strings.Contains(strings.ToLower(result.Errors[0].Message), "not found") || result.Errors[0].Extensions.Code == "ENTITY_NOT_FOUND" {
+			status = http.StatusNotFound
+		}
+		return &Error{Status: status, Message: result.Errors[0].Message}
+	}
+	return json.Unmarshal(result.Data, data)
+}
+
+type linearIssue struct {
+	Id         string `json:"id"`
+	Identifier string `json:"identifier"`
+	Url        string `json:"url"`
+}
+
+func (i linearIssue) toLink() *models.IssueLink {
+	return &models.IssueLink{
+		Type:     models.LinearIssueTracker,
+		IssueKey: i.Identifier,
+		IssueId:  i.Id,
+		IssueUrl: i.Url,
+	}
+}
+
+func (c *linearClient) Verify() error {
+	data := struct {
+		Viewer struct {
+			Id string `json:"id"`
+		} `json:"viewer"`
+	}{}
+	return c.query(`query { viewer { id } }`, nil, &data)
+}
+
+func (c *linearClient) GetIssue(issueKey string) (*models.IssueLink, error) {
+	data := struct {
+		Issue *linearIssue `json:"issue"`
+	}{}
+	if err := c.query(`query($id: String!) { issue(id: $id) { id identifier url } }`, map[string]interface{}{"id": issueKey}, &data); err != nil {
+		return nil, err
+	}
+	if data.Issue == nil {
+		return nil, &Error{Status: http.StatusNotFound, Message: "issue not found"}
+	}
+	return data.Issue.toLink(), nil
+}
+
+func (c *linearClient) CreateIssue(project string, title string, description string) (*models.IssueLink, error) {
+	data := struct {
+		IssueCreate struct {
+			Issue linearIssue `json:"issue"`
+		} `json:"issueCreate"`
+	}{}
+	err := c.query(`mutation($input: IssueCreateInput!) { issueCreate(input: $input) { issue { id identifier url } } }`, map[string]interface{}{
+		"input": map[string]interface{}{"teamId": project, "title": title, "description": description},
+	}, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data.IssueCreate.Issue.toLink(), nil
+}
+
+func (c *linearClient) PostComment(link *models.IssueLink, body string) (string, error) {
+	data := struct {
+		CommentCreate struct {
+			Comment struct {
+				Id string `json:"id"`
+			} `json:"comment"`
+		} `json:"commentCreate"`
+		CommentUpdate struct {
+			Comment struct {
+				Id string `json:"id"`
+			} `json:"comment"`
+		} `json:"commentUpdate"`
+	}{}
+
+	if len(link.CommentId) > 0 {
+		err := c.query(`mutation($id: String!, $input: CommentUpdateInput!) { commentUpdate(id: $id, input: $input) { comment { id } } }`, map[string]interface{}{
+			"id":    link.CommentId,
+			"input": map[string]interface{}{"body": body},
+		}, &data)
+		return data.CommentUpdate.Comment.Id, err
+	}
+
+	issueId := link.IssueId
+	if len(issueId) == 0 {
+		issue, err := c.GetIssue(link.IssueKey)
+		if err != nil {
+			return "", err
+		}
+		issueId = issue.IssueId
+	}
+	err := c.query(`mutation($input: CommentCreateInput!) { commentCreate(input: $input) { comment { id } } }`, map[string]interface{}{
+		"input": map[string]interface{}{"issueId": issueId, "body": body},
+	}, &data)
+	return data.CommentCreate.Comment.Id, err
+}
@@ -0,0 +1,62 @@
+// Runs background jobs on an interval, e.g. sending scheduled broadcasts.
+// Jobs should claim their work atomically in mongo, so that every server can
+// run them without doing the same work twice
+package jobs
+
+import (
+	"sync"
+	"time"
+
+	"schej.it/server/logger"
+)
+
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(now time.Time)
+}
+
+var registered []Job
+
+// Registers a job to run every interval once the jobs are started
+func Register(name string, interval time.Duration, run func(now time.Time)) {
+	registered = append(registered, Job{Name: name, Interval: interval, Run: run})
+}
+
+// Starts running the registered jobs, returning a function that stops them
+// and waits for the runs in progress to finish
+func Start() func() {
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, job := range registered {
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			ticker := time.NewTicker(job.Interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case now := <-ticker.C:
+					runJob(job, now)
+				}
+			}
+		}(job)
+	}
+
+	return func() {
+		close(stop)
+		wg.Wait()
+	}
+}
+
+// Runs the job once, recovering from panics so the job keeps running
+func runJob(job Job, now time.Time) {
+	defer func() {
+		if err := recover(); err != nil {
+			logger.StdErr.Printf("job %s: %v\n", job.Name, err)
+		}
+	}()
+	job.Run(now)
+}
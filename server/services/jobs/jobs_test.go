@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStart(t *testing.T) {
+	registered = nil
+	var runs int32
+	Register("count", 5*time.Millisecond, func(now time.Time) {
+		atomic.AddInt32(&runs, 1)
+	})
+
+	stop := Start()
+	time.Sleep(30 * time.Millisecond)
+	stop()
+
+	stoppedAt := atomic.LoadInt32(&runs)
+	if stoppedAt == 0 {
+		t.Fatal("expected job to run")
+	}
+	time.Sleep(15 * time.Millisecond)
+	if atomic.LoadInt32(&runs) != stoppedAt {
+		t.Error("expected job to stop running")
+	}
+}
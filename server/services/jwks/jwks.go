@@ -0,0 +1,218 @@
+// Verifies RS256 signed JWTs against a JSON Web Key Set (e.g. Google ID tokens)
+package jwks
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long to cache a key set before fetching it again
+const cacheDuration = time.Hour
+
+// Least time between fetches of a key set, so tokens with made up key ids
+// can't make every request fetch it
+const refetchInterval = time.Minute
+
+// Client key sets are fetched with
+var client = &http.Client{Timeout: 10 * time.Second}
+
+type key struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type keySet struct {
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+var (
+	cache       = make(map[string]*keySet)
+	lastFetches = make(map[string]time.Time)
+	cacheMu     sync.Mutex
+)
+
+// Claims contained in a verified JWT
+type Claims map[string]interface{}
+
+// Returns the string claim with the given name, or "" if it doesn't exist
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Returns whether the aud claim contains the given audience
+func (c Claims) HasAudience(audience string) bool {
+	switch aud := c["aud"].(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Parses the given JWT without verifying it, returning its claims. Used to
+// determine which key set to verify the token against
+func ParseUnverified(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed jwt")
+	}
+
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payloadBytes, &claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// Verifies the signature and expiry of the given JWT using the key set at
+// jwksUrl, and returns its claims
+func Verify(token string, jwksUrl string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed jwt")
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, err
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported jwt algorithm: %s", header.Alg)
+	}
+
+	publicKey, err := getKey(jwksUrl, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hash[:], signature); err != nil {
+		return nil, fmt.Errorf("invalid jwt signature")
+	}
+
+	claims, err := ParseUnverified(token)
+	if err != nil {
+		return nil, err
+	}
+
+	now := float64(time.Now().Unix())
+	if exp, ok := claims["exp"].(float64); !ok || exp < now {
+		return nil, fmt.Errorf("jwt expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && nbf > now {
+		return nil, fmt.Errorf("jwt not valid yet")
+	}
+
+	return claims, nil
+}
+
+// Returns the key with the given id from the key set at jwksUrl, refetching
+// the key set if it is stale or doesn't contain the key (keys get rotated). The
+// key set is fetched outside of the lock, at most once a minute once it's
+// cached, and the cached keys are used if fetching it fails
+func getKey(jwksUrl string, kid string) (*rsa.PublicKey, error) {
+	cacheMu.Lock()
+	set := cache[jwksUrl]
+	var publicKey *rsa.PublicKey
+	if set != nil {
+		publicKey = set.keys[kid]
+	}
+	needsFetch := set == nil || publicKey == nil || time.Since(set.fetchedAt) >= cacheDuration
+	refetch := needsFetch && (set == nil || time.Since(lastFetches[jwksUrl]) >= refetchInterval)
+	if refetch {
+		lastFetches[jwksUrl] = time.Now()
+	}
+	cacheMu.Unlock()
+
+	if refetch {
+		fetched, err := fetchKeySet(jwksUrl)
+		if err != nil && set == nil {
+			return nil, err
+		}
+		if err == nil {
+			cacheMu.Lock()
+			cache[jwksUrl] = fetched
+			cacheMu.Unlock()
+			publicKey = fetched.keys[kid]
+		}
+	}
+
+	if publicKey == nil {
+		return nil, fmt.Errorf("jwt signing key not found: %s", kid)
+	}
+	return publicKey, nil
+}
+
+func fetchKeySet(jwksUrl string) (*keySet, error) {
+	resp, err := client.Get(jwksUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching jwks failed with %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Keys []key `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	set := &keySet{keys: make(map[string]*rsa.PublicKey), fetchedAt: time.Now()}
+	for _, k := range body.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		nBytes, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		eBytes, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		set.keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(nBytes),
+			E: int(new(big.Int).SetBytes(eBytes).Int64()),
+		}
+	}
+
+	return set, nil
+}
@@ -0,0 +1,31 @@
+package jwks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetKeyRefetchesUnknownKeysOncePerInterval(t *testing.T) {
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Write([]byte(`{"keys": [{"kid": "known", "kty": "RSA", "n": "AQAB", "e": "AQAB"}]}`))
+	}))
+	defer server.Close()
+
+	if _, err := getKey(server.URL, "known"); err != nil {
+		t.Fatalf("got %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := getKey(server.URL, "made-up"); err == nil {
+			t.Fatal("expected an unknown key to not be found")
+		}
+	}
+	if _, err := getKey(server.URL, "known"); err != nil {
+		t.Fatalf("got %v", err)
+	}
+	if fetches != 1 {
+		t.Errorf("expected the key set to be fetched once, got %d fetches", fetches)
+	}
+}
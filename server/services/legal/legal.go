@@ -0,0 +1,75 @@
+// Tracks which versions of the terms of service and privacy policy users
+// accepted, and which ones they still have to accept
+package legal
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+// Returns the latest version of each type of legal document
+func GetLatest(documents []models.LegalDocument) []models.LegalDocument {
+	latest := make([]models.LegalDocument, 0)
+	for _, documentType := range models.LegalDocumentTypes {
+		var document *models.LegalDocument
+		for i := range documents {
+			if documents[i].Type == documentType && (document == nil || documents[i].Version > document.Version) {
+				document = &documents[i]
+			}
+		}
+		if document != nil {
+			latest = append(latest, *document)
+		}
+	}
+	return latest
+}
+
+// Returns the version the user accepted of the given type, or 0 if they
+// haven't accepted any
+func GetAcceptedVersion(acceptances []models.LegalAcceptance, documentType models.LegalDocumentType) int {
+	version := 0
+	for _, acceptance := range acceptances {
+		if acceptance.Type == documentType && acceptance.Version > version {
+			version = acceptance.Version
+		}
+	}
+	return version
+}
+
+// Returns the latest version of each type of legal document the user has to
+// accept, i.e. the ones where they haven't accepted the latest required version
+// or a later one
+func GetPending(documents []models.LegalDocument, acceptances []models.LegalAcceptance) []models.LegalDocument {
+	pending := make([]models.LegalDocument, 0)
+	for _, latest := range GetLatest(documents) {
+		required := 0
+		for _, document := range documents {
+			if document.Type == latest.Type && document.Required && document.Version > required {
+				required = document.Version
+			}
+		}
+		if required > 0 && GetAcceptedVersion(acceptances, latest.Type) < required {
+			pending = append(pending, latest)
+		}
+	}
+	return pending
+}
+
+// Returns the acceptances after the user accepted the document, replacing the
+// version they accepted before
+func Accept(acceptances []models.LegalAcceptance, document models.LegalDocument, ipAddress string, now time.Time) []models.LegalAcceptance {
+	updated := make([]models.LegalAcceptance, 0)
+	for _, acceptance := range acceptances {
+		if acceptance.Type != document.Type {
+			updated = append(updated, acceptance)
+		}
+	}
+	return append(updated, models.LegalAcceptance{
+		Type:       document.Type,
+		Version:    document.Version,
+		AcceptedAt: primitive.NewDateTimeFromTime(now),
+		IpAddress:  ipAddress,
+	})
+}
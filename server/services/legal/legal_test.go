@@ -0,0 +1,44 @@
+package legal
+
+import (
+	"testing"
+	"time"
+
+	"schej.it/server/models"
+)
+
+var documents = []models.LegalDocument{
+	{Type: models.TERMS_OF_SERVICE, Version: 1, Required: true},
+	{Type: models.TERMS_OF_SERVICE, Version: 2, Required: true},
+	{Type: models.TERMS_OF_SERVICE, Version: 3},
+	{Type: models.PRIVACY_POLICY, Version: 1},
+}
+
+func TestGetLatest(t *testing.T) {
+	latest := GetLatest(documents)
+	if len(latest) != 2 || latest[0].Version != 3 || latest[1].Type != models.PRIVACY_POLICY {
+		t.Errorf("got %v", latest)
+	}
+}
+
+func TestGetPending(t *testing.T) {
+	// Accepting an older version than the latest required one isn't enough,
+	// and the latest version is the one to accept
+	pending := GetPending(documents, []models.LegalAcceptance{{Type: models.TERMS_OF_SERVICE, Version: 1}})
+	if len(pending) != 1 || pending[0].Type != models.TERMS_OF_SERVICE || pending[0].Version != 3 {
+		t.Errorf("got %v, want terms of service version 3", pending)
+	}
+
+	// Versions that aren't required don't block users
+	if pending := GetPending(documents, []models.LegalAcceptance{{Type: models.TERMS_OF_SERVICE, Version: 2}}); len(pending) != 0 {
+		t.Errorf("got %v, want nothing pending", pending)
+	}
+}
+
+func TestAccept(t *testing.T) {
+	acceptances := []models.LegalAcceptance{{Type: models.TERMS_OF_SERVICE, Version: 1}, {Type: models.PRIVACY_POLICY, Version: 1}}
+	acceptances = Accept(acceptances, documents[2], "127.0.0.1", time.Now())
+	if len(acceptances) != 2 || GetAcceptedVersion(acceptances, models.TERMS_OF_SERVICE) != 3 || GetAcceptedVersion(acceptances, models.PRIVACY_POLICY) != 1 {
+		t.Errorf("got %v", acceptances)
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"schej.it/server/logger"
+	"schej.it/server/services/status"
 )
 
 // Adds the given user to the Listmonk contact list
@@ -139,8 +140,10 @@ func SendEmail(email string, templateId int, data bson.M) {
 
 	// Execute request
 	response, err := http.DefaultClient.Do(req)
+	status.Record(status.EMAIL, err)
 	if err != nil {
 		logger.StdErr.Println(err)
+		return
 	}
 	defer response.Body.Close()
 }
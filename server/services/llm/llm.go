@@ -0,0 +1,85 @@
+// Minimal client for an OpenAI-compatible chat completions API, used by
+// optional AI features. Works with any provider exposing the same API (e.g. a
+// self-hosted model), configured with LLM_API_URL, LLM_API_KEY and LLM_MODEL
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultApiUrl = "https://api.openai.com/v1"
+const defaultModel = "gpt-4o-mini"
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// Returns whether an LLM provider has been configured
+func IsConfigured() bool {
+	return os.Getenv("LLM_API_KEY") != "" || os.Getenv("LLM_API_URL") != ""
+}
+
+// Sends the given system and user prompts to the model, returning its reply.
+// If jsonMode is true, the model is asked to reply with a JSON object
+func Complete(systemPrompt string, userPrompt string, jsonMode bool) (string, error) {
+	apiUrl := os.Getenv("LLM_API_URL")
+	if apiUrl == "" {
+		apiUrl = defaultApiUrl
+	}
+	model := os.Getenv("LLM_MODEL")
+	if model == "" {
+		model = defaultModel
+	}
+
+	body := map[string]interface{}{
+		"model": model,
+		"messages": []map[string]string{
+			{"role": "system", "content": systemPrompt},
+			{"role": "user", "content": userPrompt},
+		},
+		"temperature": 0,
+	}
+	if jsonMode {
+		body["response_format"] = map[string]string{"type": "json_object"}
+	}
+	bodyBytes, _ := json.Marshal(body)
+
+	req, err := http.NewRequest("POST", strings.TrimSuffix(apiUrl, "/")+"/chat/completions", bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey := os.Getenv("LLM_API_KEY"); apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("llm request failed: %s", resp.Status)
+	}
+
+	var response struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", err
+	}
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("llm returned no choices")
+	}
+
+	return response.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,273 @@
+// LTI 1.3 launches, which let an LMS (e.g. Canvas or Moodle) embed Timeful
+// events with its users identified
+package lti
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+const (
+	LAUNCH_MESSAGE_TYPE = "LtiResourceLinkRequest"
+	LTI_VERSION         = "1.3.0"
+)
+
+var eventLinkRegex = regexp.MustCompile(`/e/(\w+)`)
+
+// Claims of the id token the LMS posts when launching Timeful
+type LaunchClaims struct {
+	Issuer          string   `json:"iss"`
+	Subject         string   `json:"sub"`
+	Audience        Audience `json:"aud"`
+	AuthorizedParty string   `json:"azp"`
+	ExpiresAt       int64    `json:"exp"`
+	IssuedAt        int64    `json:"iat"`
+	Nonce           string   `json:"nonce"`
+
+	// User information, depending on the LMS's privacy settings
+	Name       string `json:"name"`
+	GivenName  string `json:"given_name"`
+	FamilyName string `json:"family_name"`
+	Email      string `json:"email"`
+
+	MessageType   string            `json:"https://purl.imsglobal.org/spec/lti/claim/message_type"`
+	Version       string            `json:"https://purl.imsglobal.org/spec/lti/claim/version"`
+	DeploymentId  string            `json:"https://purl.imsglobal.org/spec/lti/claim/deployment_id"`
+	TargetLinkUri string            `json:"https://purl.imsglobal.org/spec/lti/claim/target_link_uri"`
+	Custom        map[string]string `json:"https://purl.imsglobal.org/spec/lti/claim/custom"`
+}
+
+// The "aud" claim, which is either a string or an array of strings
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var audience string
+	if err := json.Unmarshal(data, &audience); err == nil {
+		*a = Audience{audience}
+		return nil
+	}
+	var audiences []string
+	if err := json.Unmarshal(data, &audiences); err != nil {
+		return err
+	}
+	*a = audiences
+	return nil
+}
+
+// A JSON web key set, as served by the LMS's JWKS url
+type Jwks struct {
+	Keys []Jwk `json:"keys"`
+}
+
+type Jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func (jwk Jwk) PublicKey() (*rsa.PublicKey, error) {
+	if jwk.Kty != "RSA" {
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, err
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
+
+type cachedJwks struct {
+	jwks      *Jwks
+	fetchedAt time.Time
+}
+
+var jwksCache = make(map[string]cachedJwks)
+var jwksCacheMutex sync.Mutex
+
+// Returns a function that gets the LMS's public keys by id from its JWKS url.
+// Keys are cached for an hour, and refetched when the LMS rotates them
+func GetPublicKeyFunc(jwksUrl string) func(kid string) (*rsa.PublicKey, error) {
+	return func(kid string) (*rsa.PublicKey, error) {
+		jwksCacheMutex.Lock()
+		cached, ok := jwksCache[jwksUrl]
+		jwksCacheMutex.Unlock()
+
+		if !ok || time.Since(cached.fetchedAt) > time.Hour || findKey(cached.jwks, kid) == nil {
+			jwks, err := fetchJwks(jwksUrl)
+			if err != nil {
+				return nil, err
+			}
+			cached = cachedJwks{jwks: jwks, fetchedAt: time.Now()}
+			jwksCacheMutex.Lock()
+			jwksCache[jwksUrl] = cached
+			jwksCacheMutex.Unlock()
+		}
+
+		jwk := findKey(cached.jwks, kid)
+		if jwk == nil {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		return jwk.PublicKey()
+	}
+}
+
+func findKey(jwks *Jwks, kid string) *Jwk {
+	for i := range jwks.Keys {
+		if jwks.Keys[i].Kid == kid {
+			return &jwks.Keys[i]
+		}
+	}
+	return nil
+}
+
+func fetchJwks(jwksUrl string) (*Jwks, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(jwksUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching keys failed with status %d", resp.StatusCode)
+	}
+
+	var jwks Jwks
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return nil, err
+	}
+	return &jwks, nil
+}
+
+// Verifies the RS256 signature of the id token with the key returned by
+// getKey, and returns its claims
+func ParseIdToken(idToken string, getKey func(kid string) (*rsa.PublicKey, error)) (*LaunchClaims, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed id token")
+	}
+
+	headerJson, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("malformed id token header")
+	}
+	header := struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+	if err := json.Unmarshal(headerJson, &header); err != nil {
+		return nil, fmt.Errorf("malformed id token header")
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+
+	key, err := getKey(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed id token signature")
+	}
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature); err != nil {
+		return nil, fmt.Errorf("invalid id token signature")
+	}
+
+	claimsJson, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed id token claims")
+	}
+	var claims LaunchClaims
+	if err := json.Unmarshal(claimsJson, &claims); err != nil {
+		return nil, fmt.Errorf("malformed id token claims")
+	}
+	return &claims, nil
+}
+
+// Checks that the launch was meant for Timeful by the given platform, in
+// response to the login request with the given nonce
+func ValidateLaunch(claims *LaunchClaims, platform *models.LtiPlatform, nonce string, now time.Time) error {
+	if claims.Issuer != platform.Issuer {
+		return fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if !utils.Contains(claims.Audience, platform.ClientId) {
+		return fmt.Errorf("id token isn't meant for client %q", platform.ClientId)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != platform.ClientId {
+		return fmt.Errorf("unexpected authorized party %q", claims.AuthorizedParty)
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return fmt.Errorf("id token expired")
+	}
+	if len(nonce) == 0 || claims.Nonce != nonce {
+		return fmt.Errorf("nonce mismatch")
+	}
+	if len(platform.DeploymentIds) > 0 && !utils.Contains(platform.DeploymentIds, claims.DeploymentId) {
+		return fmt.Errorf("unknown deployment %q", claims.DeploymentId)
+	}
+	if claims.MessageType != LAUNCH_MESSAGE_TYPE || claims.Version != LTI_VERSION {
+		return fmt.Errorf("unsupported launch %s %s", claims.MessageType, claims.Version)
+	}
+	if len(claims.Subject) == 0 {
+		return fmt.Errorf("anonymous launches aren't supported")
+	}
+	return nil
+}
+
+// Returns the id of the launched event, set either as the "event_id" custom
+// parameter or in the target link (e.g. https://timeful.app/e/abc123)
+func GetEventId(claims *LaunchClaims) string {
+	if eventId := claims.Custom["event_id"]; len(eventId) > 0 {
+		return eventId
+	}
+	if match := eventLinkRegex.FindStringSubmatch(claims.TargetLinkUri); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// Returns the name the LMS user responds to events with
+func GetDisplayName(claims *LaunchClaims) string {
+	if name := strings.TrimSpace(claims.Name); len(name) > 0 {
+		return name
+	}
+	if name := strings.TrimSpace(claims.GivenName + " " + claims.FamilyName); len(name) > 0 {
+		return name
+	}
+	if len(claims.Email) > 0 {
+		return claims.Email
+	}
+	subject := claims.Subject
+	if len(subject) > 6 {
+		subject = subject[:6]
+	}
+	return "Student " + subject
+}
+
+// Returns name, or name followed by the smallest number that isn't taken
+func GetUniqueName(name string, isTaken func(name string) bool) string {
+	unique := name
+	for i := 2; isTaken(unique); i++ {
+		unique = fmt.Sprintf("%s (%d)", name, i)
+	}
+	return unique
+}
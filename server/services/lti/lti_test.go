@@ -0,0 +1,116 @@
+package lti
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func signIdToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hash := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestLaunch(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	getKey := func(kid string) (*rsa.PublicKey, error) {
+		if kid != "key1" {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		return &key.PublicKey, nil
+	}
+	platform := &models.LtiPlatform{Issuer: "https://canvas.instructure.com", ClientId: "10000000000001", DeploymentIds: []string{"1:abc"}}
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":   platform.Issuer,
+		"sub":   "student-1",
+		"aud":   platform.ClientId,
+		"exp":   now.Add(time.Minute).Unix(),
+		"iat":   now.Unix(),
+		"nonce": "nonce1",
+		"name":  "Ada Lovelace",
+		"https://purl.imsglobal.org/spec/lti/claim/message_type":    LAUNCH_MESSAGE_TYPE,
+		"https://purl.imsglobal.org/spec/lti/claim/version":         LTI_VERSION,
+		"https://purl.imsglobal.org/spec/lti/claim/deployment_id":   "1:abc",
+		"https://purl.imsglobal.org/spec/lti/claim/target_link_uri": "https://timeful.app/e/abc123",
+	}
+
+	launch, err := ParseIdToken(signIdToken(t, key, "key1", claims), getKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateLaunch(launch, platform, "nonce1", now); err != nil {
+		t.Errorf("valid launch rejected: %v", err)
+	}
+	if err := ValidateLaunch(launch, platform, "nonce2", now); err == nil {
+		t.Errorf("launch with a different nonce accepted")
+	}
+	if err := ValidateLaunch(launch, platform, "nonce1", now.Add(time.Hour)); err == nil {
+		t.Errorf("expired launch accepted")
+	}
+	if eventId := GetEventId(launch); eventId != "abc123" {
+		t.Errorf("got event id %q", eventId)
+	}
+	if name := GetDisplayName(launch); name != "Ada Lovelace" {
+		t.Errorf("got name %q", name)
+	}
+
+	// Tokens signed by another key are rejected
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	if _, err := ParseIdToken(signIdToken(t, otherKey, "key1", claims), getKey); err == nil {
+		t.Errorf("token signed by another key accepted")
+	}
+}
+
+func TestAudience(t *testing.T) {
+	var claims LaunchClaims
+	if err := json.Unmarshal([]byte(`{"aud":["a","b"]}`), &claims); err != nil || len(claims.Audience) != 2 {
+		t.Errorf("got %v, %v", claims.Audience, err)
+	}
+	if err := json.Unmarshal([]byte(`{"aud":"a"}`), &claims); err != nil || len(claims.Audience) != 1 {
+		t.Errorf("got %v, %v", claims.Audience, err)
+	}
+}
+
+func TestGetUniqueName(t *testing.T) {
+	taken := map[string]bool{"Ada": true, "Ada (2)": true}
+	if name := GetUniqueName("Ada", func(name string) bool { return taken[name] }); name != "Ada (3)" {
+		t.Errorf("got %q", name)
+	}
+}
+
+func TestEmbedToken(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEY", "test-key")
+	eventId := primitive.NewObjectID()
+	identity := &models.LtiIdentity{Id: primitive.NewObjectID(), Name: "Ada Lovelace", Email: "ada@example.edu"}
+
+	token := NewEmbedToken(eventId, identity)
+	embed, err := ParseEmbedToken(token)
+	if err != nil || embed.EventId != eventId || embed.Name != identity.Name {
+		t.Errorf("got %+v, %v", embed, err)
+	}
+
+	t.Setenv("ENCRYPTION_KEY", "other-key")
+	if _, err := ParseEmbedToken(token); err == nil {
+		t.Errorf("token signed with another key accepted")
+	}
+}
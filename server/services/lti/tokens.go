@@ -0,0 +1,105 @@
+package lti
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/brianvoe/sjwt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+// How long the LMS has to complete the login started by Timeful
+const STATE_EXPIRY = 10 * time.Minute
+
+// How long an embedded event can be responded to after the launch
+const EMBED_EXPIRY = 12 * time.Hour
+
+// Identity of the LMS user an embedded event was launched for
+type EmbedClaims struct {
+	EventId    primitive.ObjectID
+	IdentityId primitive.ObjectID
+	Name       string
+	Email      string
+}
+
+func getSecret() []byte {
+	return []byte(os.Getenv("ENCRYPTION_KEY"))
+}
+
+// Returns the signed state of a login request to the platform. The state is
+// posted back with the launch, so it doesn't rely on cookies, which are often
+// blocked in the LMS's iframe
+func NewState(platformId primitive.ObjectID, nonce string) string {
+	claims := sjwt.New()
+	claims.Set("platformId", platformId.Hex())
+	claims.Set("nonce", nonce)
+	claims.SetExpiresAt(time.Now().Add(STATE_EXPIRY))
+	return claims.Generate(getSecret())
+}
+
+// Returns the platform id and nonce of the login request with the given state
+func ParseState(state string) (primitive.ObjectID, string, error) {
+	claims, err := parseToken(state)
+	if err != nil {
+		return primitive.NilObjectID, "", err
+	}
+	platformId, _ := claims.GetStr("platformId")
+	platformIdObj, err := primitive.ObjectIDFromHex(platformId)
+	if err != nil {
+		return primitive.NilObjectID, "", fmt.Errorf("invalid state")
+	}
+	nonce, _ := claims.GetStr("nonce")
+	return platformIdObj, nonce, nil
+}
+
+// Returns the token that lets the LMS user respond to the embedded event as
+// their identity
+func NewEmbedToken(eventId primitive.ObjectID, identity *models.LtiIdentity) string {
+	claims := sjwt.New()
+	claims.Set("eventId", eventId.Hex())
+	claims.Set("identityId", identity.Id.Hex())
+	claims.Set("name", identity.Name)
+	claims.Set("email", identity.Email)
+	claims.SetExpiresAt(time.Now().Add(EMBED_EXPIRY))
+	return claims.Generate(getSecret())
+}
+
+func ParseEmbedToken(token string) (*EmbedClaims, error) {
+	claims, err := parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var embed EmbedClaims
+	eventId, _ := claims.GetStr("eventId")
+	identityId, _ := claims.GetStr("identityId")
+	if embed.EventId, err = primitive.ObjectIDFromHex(eventId); err != nil {
+		return nil, fmt.Errorf("invalid embed token")
+	}
+	if embed.IdentityId, err = primitive.ObjectIDFromHex(identityId); err != nil {
+		return nil, fmt.Errorf("invalid embed token")
+	}
+	embed.Name, _ = claims.GetStr("name")
+	embed.Email, _ = claims.GetStr("email")
+	if len(embed.Name) == 0 {
+		return nil, fmt.Errorf("invalid embed token")
+	}
+	return &embed, nil
+}
+
+// Verifies the signature and expiry of a token signed by Timeful
+func parseToken(token string) (sjwt.Claims, error) {
+	if !sjwt.Verify(token, getSecret()) {
+		return nil, fmt.Errorf("invalid token signature")
+	}
+	claims, err := sjwt.Parse(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := claims.Validate(); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
@@ -0,0 +1,81 @@
+// Estimates how much a meeting costs, based on the number of attendees, the
+// length of the meeting and an hourly rate
+package meetingcost
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Used when MEETING_COST_DEFAULT_HOURLY_RATE is not set
+const fallbackHourlyRate = 50
+
+// An estimated meeting cost
+type Estimate struct {
+	Attendees       int     `json:"attendees"`
+	DurationMinutes int     `json:"durationMinutes"`
+	HourlyRate      float64 `json:"hourlyRate"`
+	Currency        string  `json:"currency"`
+	PersonHours     float64 `json:"personHours"`
+	Cost            float64 `json:"cost"`
+}
+
+// Returns the hourly rate used when none is given
+func GetDefaultHourlyRate() float64 {
+	if rate, err := strconv.ParseFloat(os.Getenv("MEETING_COST_DEFAULT_HOURLY_RATE"), 64); err == nil && rate >= 0 {
+		return rate
+	}
+	return fallbackHourlyRate
+}
+
+// Returns the currency costs are estimated in
+func GetCurrency() string {
+	if currency := os.Getenv("MEETING_COST_CURRENCY"); currency != "" {
+		return currency
+	}
+	return "USD"
+}
+
+// Estimates the cost of a meeting. If hourlyRate is nil, the default hourly rate is used
+func Calculate(attendees int, duration time.Duration, hourlyRate *float64) Estimate {
+	rate := GetDefaultHourlyRate()
+	if hourlyRate != nil {
+		rate = *hourlyRate
+	}
+
+	personHours := float64(attendees) * duration.Hours()
+	return Estimate{
+		Attendees:       attendees,
+		DurationMinutes: int(duration.Minutes()),
+		HourlyRate:      rate,
+		Currency:        GetCurrency(),
+		PersonHours:     math.Round(personHours*100) / 100,
+		Cost:            math.Round(personHours*rate*100) / 100,
+	}
+}
+
+// Returns a short description of the estimate, e.g. "~300 USD (6 people x 1h at 50 USD/h)"
+func (e Estimate) String() string {
+	duration := fmt.Sprintf("%dm", e.DurationMinutes)
+	if e.DurationMinutes >= 60 {
+		duration = fmt.Sprintf("%dh", e.DurationMinutes/60)
+		if e.DurationMinutes%60 != 0 {
+			duration += fmt.Sprintf("%dm", e.DurationMinutes%60)
+		}
+	}
+	people := "people"
+	if e.Attendees == 1 {
+		people = "person"
+	}
+	return fmt.Sprintf("~%s %s (%d %s x %s at %s %s/h)", formatAmount(e.Cost), e.Currency, e.Attendees, people, duration, formatAmount(e.HourlyRate), e.Currency)
+}
+
+func formatAmount(amount float64) string {
+	if amount == math.Trunc(amount) {
+		return strconv.FormatFloat(amount, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
@@ -0,0 +1,29 @@
+package meetingcost
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalculate(t *testing.T) {
+	rate := 80.0
+	estimate := Calculate(6, 90*time.Minute, &rate)
+	if estimate.PersonHours != 9 || estimate.Cost != 720 {
+		t.Errorf("got %v person hours and cost %v, want 9 and 720", estimate.PersonHours, estimate.Cost)
+	}
+	if want := "~720 USD (6 people x 1h30m at 80 USD/h)"; estimate.String() != want {
+		t.Errorf("got %q, want %q", estimate.String(), want)
+	}
+}
+
+func TestCalculateDefaultRate(t *testing.T) {
+	t.Setenv("MEETING_COST_DEFAULT_HOURLY_RATE", "37.5")
+	t.Setenv("MEETING_COST_CURRENCY", "EUR")
+	estimate := Calculate(1, 20*time.Minute, nil)
+	if estimate.Cost != 12.5 || estimate.Currency != "EUR" {
+		t.Errorf("got %v %s, want 12.5 EUR", estimate.Cost, estimate.Currency)
+	}
+	if want := "~12.50 EUR (1 person x 20m at 37.50 EUR/h)"; estimate.String() != want {
+		t.Errorf("got %q, want %q", estimate.String(), want)
+	}
+}
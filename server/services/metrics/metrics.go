@@ -0,0 +1,187 @@
+// Collects request metrics and serves them in the Prometheus text format, so
+// the server can be scraped without pulling in the Prometheus client
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Upper bounds of the request duration buckets, in seconds
+var Buckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	Group  string
+	Method string
+	Status string
+}
+
+type histogram struct {
+	Counts []uint64 // Count of each bucket, not cumulative
+	Sum    float64
+	Count  uint64
+}
+
+type Registry struct {
+	mutex     sync.Mutex
+	requests  map[requestKey]uint64
+	durations map[requestKey]*histogram
+	startedAt time.Time
+
+	// Region every series is labeled with, if the API runs in several
+	region string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		requests:  make(map[requestKey]uint64),
+		durations: make(map[requestKey]*histogram),
+		startedAt: time.Now(),
+	}
+}
+
+// Registry the middleware records to
+var Default = NewRegistry()
+
+// Labels every series with the region, so the metrics of multiple regions can
+// be told apart once they're aggregated
+func (r *Registry) SetRegion(region string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.region = region
+}
+
+// Returns the group a route belongs to, i.e. the first segment of its path
+// after /api, e.g. "events" for /api/events/:eventId
+func GetRouteGroup(fullPath string) string {
+	if len(fullPath) == 0 {
+		return "unmatched"
+	}
+	segments := strings.Split(strings.Trim(fullPath, "/"), "/")
+	if segments[0] == "api" {
+		segments = segments[1:]
+	}
+	if len(segments) == 0 || len(segments[0]) == 0 || strings.ContainsAny(segments[0][:1], ":*") {
+		return "root"
+	}
+	return segments[0]
+}
+
+// Records a request that took the given duration
+func (r *Registry) Observe(group string, method string, status int, duration time.Duration) {
+	key := requestKey{Group: group, Method: method, Status: strconv.Itoa(status)}
+	seconds := duration.Seconds()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.requests[key]++
+
+	// Durations are only split by group and method, to keep the number of
+	// series down
+	durationKey := requestKey{Group: group, Method: method}
+	h, ok := r.durations[durationKey]
+	if !ok {
+		h = &histogram{Counts: make([]uint64, len(Buckets))}
+		r.durations[durationKey] = h
+	}
+	for i, bound := range Buckets {
+		if seconds <= bound {
+			h.Counts[i]++
+			break
+		}
+	}
+	h.Sum += seconds
+	h.Count++
+}
+
+func sortedKeys[V any](m map[requestKey]V) []requestKey {
+	keys := make([]requestKey, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Group != keys[j].Group {
+			return keys[i].Group < keys[j].Group
+		}
+		if keys[i].Method != keys[j].Method {
+			return keys[i].Method < keys[j].Method
+		}
+		return keys[i].Status < keys[j].Status
+	})
+	return keys
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// Writes the metrics in the Prometheus text exposition format
+func (r *Registry) Write(w io.Writer) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	regionLabel := ""
+	if len(r.region) > 0 {
+		regionLabel = fmt.Sprintf("region=%q,", r.region)
+	}
+
+	fmt.Fprintln(w, "# HELP http_requests_total Number of HTTP requests handled, by route group, method and status.")
+	fmt.Fprintln(w, "# TYPE http_requests_total counter")
+	for _, key := range sortedKeys(r.requests) {
+		fmt.Fprintf(w, "http_requests_total{%sgroup=%q,method=%q,status=%q} %d\n", regionLabel, key.Group, key.Method, key.Status, r.requests[key])
+	}
+
+	fmt.Fprintln(w, "# HELP http_request_duration_seconds Time taken to handle HTTP requests, by route group and method.")
+	fmt.Fprintln(w, "# TYPE http_request_duration_seconds histogram")
+	for _, key := range sortedKeys(r.durations) {
+		h := r.durations[key]
+		var cumulative uint64
+		for i, bound := range Buckets {
+			cumulative += h.Counts[i]
+			fmt.Fprintf(w, "http_request_duration_seconds_bucket{%sgroup=%q,method=%q,le=%q} %d\n", regionLabel, key.Group, key.Method, formatFloat(bound), cumulative)
+		}
+		fmt.Fprintf(w, "http_request_duration_seconds_bucket{%sgroup=%q,method=%q,le=\"+Inf\"} %d\n", regionLabel, key.Group, key.Method, h.Count)
+		fmt.Fprintf(w, "http_request_duration_seconds_sum{%sgroup=%q,method=%q} %s\n", regionLabel, key.Group, key.Method, formatFloat(h.Sum))
+		fmt.Fprintf(w, "http_request_duration_seconds_count{%sgroup=%q,method=%q} %d\n", regionLabel, key.Group, key.Method, h.Count)
+	}
+
+	fmt.Fprintln(w, "# HELP process_start_time_seconds Start time of the server since the unix epoch in seconds.")
+	fmt.Fprintln(w, "# TYPE process_start_time_seconds gauge")
+	if len(r.region) > 0 {
+		fmt.Fprintf(w, "process_start_time_seconds{region=%q} %d\n", r.region, r.startedAt.Unix())
+	} else {
+		fmt.Fprintf(w, "process_start_time_seconds %d\n", r.startedAt.Unix())
+	}
+}
+
+// Middleware that records the status and duration of every request
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		Default.Observe(GetRouteGroup(c.FullPath()), c.Request.Method, c.Writer.Status(), time.Since(start))
+	}
+}
+
+// Serves the metrics. If METRICS_TOKEN is set, scrapers have to send it as a
+// bearer token
+func Handler(c *gin.Context) {
+	if token := os.Getenv("METRICS_TOKEN"); len(token) > 0 && c.GetHeader("Authorization") != "Bearer "+token {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(http.StatusOK)
+	Default.Write(c.Writer)
+}
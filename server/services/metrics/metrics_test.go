@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetRouteGroup(t *testing.T) {
+	cases := map[string]string{
+		"/api/events/:eventId":        "events",
+		"/api/user/folders/:folderId": "user",
+		"/api/:eventId":               "root",
+		"/healthz":                    "healthz",
+		"":                            "unmatched",
+		"/api":                        "root",
+		"/swagger/*any":               "swagger",
+		"/api/auth/sign-in":           "auth",
+	}
+	for path, want := range cases {
+		if got := GetRouteGroup(path); got != want {
+			t.Errorf("GetRouteGroup(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	r := NewRegistry()
+	r.Observe("events", "GET", 200, 20*time.Millisecond)
+	r.Observe("events", "GET", 200, 3*time.Second)
+	r.Observe("events", "GET", 404, 20*time.Second)
+
+	var out strings.Builder
+	r.Write(&out)
+	for _, want := range []string{
+		`http_requests_total{group="events",method="GET",status="200"} 2`,
+		`http_requests_total{group="events",method="GET",status="404"} 1`,
+		`http_request_duration_seconds_bucket{group="events",method="GET",le="0.01"} 0`,
+		`http_request_duration_seconds_bucket{group="events",method="GET",le="0.025"} 1`,
+		`http_request_duration_seconds_bucket{group="events",method="GET",le="5"} 2`,
+		`http_request_duration_seconds_bucket{group="events",method="GET",le="10"} 2`,
+		`http_request_duration_seconds_bucket{group="events",method="GET",le="+Inf"} 3`,
+		`http_request_duration_seconds_sum{group="events",method="GET"} 23.02`,
+		`http_request_duration_seconds_count{group="events",method="GET"} 3`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("metrics don't contain %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestWriteWithRegion(t *testing.T) {
+	r := NewRegistry()
+	r.SetRegion("europe-west1")
+	r.Observe("events", "GET", 200, 20*time.Millisecond)
+
+	var out strings.Builder
+	r.Write(&out)
+	for _, want := range []string{
+		`http_requests_total{region="europe-west1",group="events",method="GET",status="200"} 1`,
+		`http_request_duration_seconds_count{region="europe-west1",group="events",method="GET"} 1`,
+		`process_start_time_seconds{region="europe-west1"}`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("metrics don't contain %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -9,6 +9,8 @@ import (
 )
 
 type UserInfo struct {
+	// Immutable object id of the account
+	Id        string `json:"id"`
 	FirstName string `json:"givenName"`
 	LastName  string `json:"surname"`
 	Email     string `json:"mail"`
@@ -19,24 +21,35 @@ func GetUserInfo(user *models.User, calendarAuth *models.OAuth2CalendarAuth) Use
 		user,
 		calendarAuth,
 		"GET",
-		"https://graph.microsoft.com/v1.0/me?$select=givenName,surname,mail",
+		"https://graph.microsoft.com/v1.0/me?$select=id,givenName,surname,mail,userPrincipalName",
 		nil,
 	)
 	defer response.Body.Close()
 
 	userResponse := struct {
+		Id        string `json:"id"`
 		GivenName string `json:"givenName"`
 		Surname   string `json:"surname"`
 		Mail      string `json:"mail"`
+
+		// Accounts without a mailbox address (e.g. some personal accounts)
+		// sign in with their user principal name instead
+		UserPrincipalName string `json:"userPrincipalName"`
 	}{}
 
 	if err := json.NewDecoder(response.Body).Decode(&userResponse); err != nil {
 		logger.StdErr.Panicln(err)
 	}
 
+	email := userResponse.Mail
+	if len(email) == 0 {
+		email = userResponse.UserPrincipalName
+	}
+
 	return UserInfo{
+		Id:        userResponse.Id,
 		FirstName: userResponse.GivenName,
 		LastName:  userResponse.Surname,
-		Email:     userResponse.Mail,
+		Email:     email,
 	}
 }
@@ -0,0 +1,136 @@
+package notifications
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services/slack"
+	"schej.it/server/utils"
+)
+
+// A respondent of an event that broadcasts are sent to
+type Recipient struct {
+	UserId string
+	Name   string
+	Email  string
+
+	// Set for respondents with a Timeful account
+	User *models.User
+}
+
+// Returns the respondents of the event, including the sign ups of sign up forms
+func GetRecipients(event *models.Event) []Recipient {
+	recipients := make([]Recipient, 0)
+	addRecipient := func(userId string, name string, email string) {
+		recipient := Recipient{UserId: userId, Name: name, Email: email}
+		if user := db.GetUserById(userId); user != nil {
+			recipient.Name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+			recipient.Email = user.Email
+			recipient.User = user
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	if utils.Coalesce(event.IsSignUpForm) {
+		for userId, response := range event.SignUpResponses {
+			addRecipient(userId, response.Name, response.Email)
+		}
+	} else {
+		for _, eventResponse := range db.GetEventResponses(event.Id.Hex()) {
+			if eventResponse.Response == nil {
+				continue
+			}
+			addRecipient(eventResponse.UserId, eventResponse.Response.Name, eventResponse.Response.Email)
+		}
+	}
+
+	return recipients
+}
+
+// Sends any broadcasts that are due. Run periodically by the jobs scheduler
+func SendDueBroadcasts(now time.Time) {
+	for broadcast := db.ClaimDueBroadcast(now); broadcast != nil; broadcast = db.ClaimDueBroadcast(now) {
+		SendBroadcast(broadcast)
+	}
+}
+
+// Sends the broadcast, which must have been claimed, to every respondent of
+// the event and saves the delivery status of each message
+func SendBroadcast(broadcast *models.Broadcast) {
+	broadcast.Deliveries = make([]models.Delivery, 0)
+	event := db.GetEventById(broadcast.EventId.Hex())
+	if event != nil {
+		optedOut := db.GetNotificationOptOutEmails(event.Id)
+		sender := GetEventSender(event)
+		eventUrl := fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId())
+		for _, recipient := range GetRecipients(event) {
+			broadcast.Deliveries = append(broadcast.Deliveries, deliverBroadcast(broadcast, event, eventUrl, recipient, optedOut, sender)...)
+		}
+	}
+
+	for _, delivery := range broadcast.Deliveries {
+		switch delivery.Status {
+		case models.DELIVERY_SENT:
+			broadcast.NumSent++
+		case models.DELIVERY_FAILED:
+			broadcast.NumFailed++
+		}
+	}
+	sentAt := primitive.NewDateTimeFromTime(time.Now())
+	broadcast.SentAt = &sentAt
+	broadcast.Status = models.BROADCAST_SENT
+	db.UpdateBroadcast(broadcast)
+}
+
+// Sends the broadcast to the recipient by email, and by Slack if they linked
+// their account
+func deliverBroadcast(broadcast *models.Broadcast, event *models.Event, eventUrl string, recipient Recipient, optedOut models.Set[string], sender *utils.EmailSender) []models.Delivery {
+	delivery := models.Delivery{UserId: recipient.UserId, Name: recipient.Name, Email: recipient.Email, Channel: models.EMAIL_CHANNEL}
+	if _, ok := optedOut[strings.ToLower(recipient.Email)]; ok {
+		delivery.Status = models.DELIVERY_SKIPPED
+		delivery.Error = "opted out"
+		return []models.Delivery{delivery}
+	}
+
+	deliveries := make([]models.Delivery, 0)
+	if len(recipient.Email) == 0 {
+		delivery.Status = models.DELIVERY_SKIPPED
+		delivery.Error = "no email"
+	} else {
+		body := fmt.Sprintf(
+			"%s\n\n%s\n\nDon't want these messages? Opt out: %s\n",
+			broadcast.Message, eventUrl, GetOptOutUrl(event.Id, recipient.Email),
+		)
+		if err := TrySendBulkEmail(recipient.Email, broadcast.Subject, body, "text/plain", sender); err == utils.ErrEmailSuppressed {
+			delivery.Status = models.DELIVERY_SKIPPED
+			delivery.Error = "unsubscribed"
+		} else if err != nil {
+			logger.StdErr.Println(err)
+			delivery.Status = models.DELIVERY_FAILED
+			delivery.Error = err.Error()
+		} else {
+			delivery.Status = models.DELIVERY_SENT
+		}
+	}
+	deliveries = append(deliveries, delivery)
+
+	if recipient.User == nil {
+		return deliveries
+	}
+	for _, account := range db.GetSlackAccountsByUserId(recipient.User.Id) {
+		delivery := models.Delivery{UserId: recipient.UserId, Name: recipient.Name, Email: recipient.Email, Channel: models.SLACK_CHANNEL, Status: models.DELIVERY_SENT}
+		text := fmt.Sprintf("*%s* (<%s|%s>)\n%s", broadcast.Subject, eventUrl, event.Name, broadcast.Message)
+		if err := slack.PostMessage(account.SlackUserId, text, nil); err != nil {
+			logger.StdErr.Println(err)
+			delivery.Status = models.DELIVERY_FAILED
+			delivery.Error = err.Error()
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries
+}
@@ -0,0 +1,170 @@
+// Sends nudges to invitees that haven't responded to an event and broadcasts
+// from the organizer to respondents, by email and Slack, and tracks the
+// invitees that opted out of them
+package notifications
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services/emailtemplates"
+	"schej.it/server/services/organizations"
+	"schej.it/server/services/slack"
+	"schej.it/server/utils"
+)
+
+// Minimum time between two nudges for the same event
+const NudgeInterval = 24 * time.Hour
+
+// Returns when the organizer can next nudge the event's invitees, which is
+// now if they never did
+func GetNextNudgeTime(event *models.Event, now time.Time) time.Time {
+	if event.LastNudgedAt == nil {
+		return now
+	}
+	next := event.LastNudgedAt.Time().Add(NudgeInterval)
+	if next.Before(now) {
+		return now
+	}
+	return next
+}
+
+// Returns the invitees that haven't responded and haven't opted out, without
+// duplicates. Emails are compared case insensitively
+func GetPendingInvitees(invitees []string, responded models.Set[string], optedOut models.Set[string]) []string {
+	pending := make([]string, 0)
+	seen := make(models.Set[string])
+	for _, email := range invitees {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if len(email) == 0 {
+			continue
+		}
+		if _, ok := seen[email]; ok {
+			continue
+		}
+		seen[email] = struct{}{}
+		_, hasResponded := responded[email]
+		_, hasOptedOut := optedOut[email]
+		if !hasResponded && !hasOptedOut {
+			pending = append(pending, email)
+		}
+	}
+	return pending
+}
+
+// Returns the token that authorizes the opt out link of the given invitee
+func GetOptOutToken(eventId primitive.ObjectID, email string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("ENCRYPTION_KEY")))
+	mac.Write([]byte(eventId.Hex() + ":" + strings.ToLower(email)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Returns whether the token authorizes the opt out link of the given invitee
+func VerifyOptOutToken(eventId primitive.ObjectID, email string, token string) bool {
+	return hmac.Equal([]byte(GetOptOutToken(eventId, email)), []byte(token))
+}
+
+// Returns the link invitees can follow to stop receiving nudges for the event
+func GetOptOutUrl(eventId primitive.ObjectID, email string) string {
+	query := url.Values{}
+	query.Set("email", email)
+	query.Set("token", GetOptOutToken(eventId, email))
+	return fmt.Sprintf("%s/api/events/%s/remind/opt-out?%s", utils.GetBaseUrl(), eventId.Hex(), query.Encode())
+}
+
+// Returns the token that authorizes unsubscribing the address from all bulk
+// emails
+func GetUnsubscribeToken(email string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("ENCRYPTION_KEY")))
+	mac.Write([]byte("unsubscribe:" + strings.ToLower(email)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Returns whether the token authorizes unsubscribing the address
+func VerifyUnsubscribeToken(email string, token string) bool {
+	return hmac.Equal([]byte(GetUnsubscribeToken(email)), []byte(token))
+}
+
+// Returns the one-click unsubscribe link of the address, which mail clients
+// post to from the List-Unsubscribe header
+func GetUnsubscribeUrl(email string) string {
+	query := url.Values{}
+	query.Set("email", email)
+	query.Set("token", GetUnsubscribeToken(email))
+	return fmt.Sprintf("%s/api/unsubscribe?%s", utils.GetBaseUrl(), query.Encode())
+}
+
+// Returns the organization the event was created in, or nil if it wasn't
+func GetEventOrganization(event *models.Event) *models.Organization {
+	if event.OrganizationId.IsZero() {
+		return nil
+	}
+	return db.GetOrganizationById(event.OrganizationId.Hex())
+}
+
+// Returns the sender of emails about the event, which is its organization's
+// sending domain if it has a verified one, or nil for the default address
+func GetEventSender(event *models.Event) *utils.EmailSender {
+	return organizations.GetEmailSender(GetEventOrganization(event))
+}
+
+// Sends a bulk email from the sender (see GetEventSender) that the address can
+// unsubscribe from in one click. Returns utils.ErrEmailSuppressed if it's on
+// the suppression list
+func TrySendBulkEmail(email string, subject string, body string, contentType string, sender *utils.EmailSender) error {
+	return utils.TrySendBulkEmail(email, subject, body, contentType, GetUnsubscribeUrl(email), sender)
+}
+
+// Nudges the invitee to respond to the event by email, and by Slack if their
+// Timeful account is linked to Slack
+func SendNudge(event *models.Event, owner *models.User, email string) {
+	eventUrl := fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId())
+	ownerName := strings.TrimSpace(owner.FirstName + " " + owner.LastName)
+
+	org := GetEventOrganization(event)
+	subject := fmt.Sprintf("Reminder: %s", event.Name)
+	body := fmt.Sprintf(
+		"Hi,\n\n%s is still waiting on your availability for %s. Add it here: %s\n\nDon't want these reminders? Opt out: %s\n",
+		ownerName, event.Name, eventUrl, GetOptOutUrl(event.Id, email),
+	)
+	contentType := "text/plain"
+	if template := emailtemplates.Get(org, models.EMAIL_TEMPLATE_REMINDER); template != nil {
+		customSubject, customBody, err := emailtemplates.Render(*template, map[string]string{
+			"organizerName":    ownerName,
+			"organizationName": org.Name,
+			"eventName":        event.Name,
+			"eventUrl":         eventUrl,
+			"optOutUrl":        GetOptOutUrl(event.Id, email),
+		})
+		if err != nil {
+			// Fall back to the default email
+			logger.StdErr.Println(err)
+		} else {
+			subject, body, contentType = customSubject, customBody, "text/html"
+		}
+	}
+	if err := TrySendBulkEmail(email, subject, body, contentType, organizations.GetEmailSender(org)); err != nil && err != utils.ErrEmailSuppressed {
+		logger.StdErr.Println(err)
+	}
+
+	user := db.GetUserByEmail(email)
+	if user == nil {
+		return
+	}
+	for _, account := range db.GetSlackAccountsByUserId(user.Id) {
+		text := fmt.Sprintf("%s is still waiting on your availability for <%s|%s>", ownerName, eventUrl, event.Name)
+		if err := slack.PostMessage(account.SlackUserId, text, nil); err != nil {
+			logger.StdErr.Println(err)
+		}
+	}
+}
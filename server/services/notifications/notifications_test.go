@@ -0,0 +1,53 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func TestGetPendingInvitees(t *testing.T) {
+	responded := models.Set[string]{"ana@example.com": {}}
+	optedOut := models.Set[string]{"bo@example.com": {}}
+	pending := GetPendingInvitees([]string{"Ana@example.com", "bo@example.com", "cy@example.com", " CY@example.com", ""}, responded, optedOut)
+	if len(pending) != 1 || pending[0] != "cy@example.com" {
+		t.Errorf("got %v, want [cy@example.com]", pending)
+	}
+}
+
+func TestGetNextNudgeTime(t *testing.T) {
+	now := time.Date(2024, time.May, 14, 9, 0, 0, 0, time.UTC)
+	event := &models.Event{}
+	if next := GetNextNudgeTime(event, now); !next.Equal(now) {
+		t.Errorf("got %v, want now", next)
+	}
+
+	lastNudgedAt := primitive.NewDateTimeFromTime(now.Add(-time.Hour))
+	event.LastNudgedAt = &lastNudgedAt
+	if next := GetNextNudgeTime(event, now); !next.Equal(now.Add(23 * time.Hour)) {
+		t.Errorf("got %v, want in 23 hours", next)
+	}
+}
+
+func TestOptOutToken(t *testing.T) {
+	eventId := primitive.NewObjectID()
+	token := GetOptOutToken(eventId, "ana@example.com")
+	if !VerifyOptOutToken(eventId, "Ana@example.com", token) {
+		t.Error("expected token to be valid")
+	}
+	if VerifyOptOutToken(eventId, "bo@example.com", token) || VerifyOptOutToken(primitive.NewObjectID(), "ana@example.com", token) {
+		t.Error("expected token to be invalid for other invitees")
+	}
+}
+
+func TestUnsubscribeToken(t *testing.T) {
+	token := GetUnsubscribeToken("ana@example.com")
+	if !VerifyUnsubscribeToken("Ana@example.com", token) {
+		t.Error("expected token to be valid")
+	}
+	if VerifyUnsubscribeToken("bo@example.com", token) {
+		t.Error("expected token to be invalid for other addresses")
+	}
+}
@@ -0,0 +1,130 @@
+package notifications
+
+import (
+	"fmt"
+	"strings"
+
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services/slack"
+	"schej.it/server/utils"
+)
+
+// Returns the organizers of the event: the owner followed by the
+// co-organizers that have a Timeful account
+func GetOrganizers(event *models.Event) []*models.User {
+	organizers := make([]*models.User, 0)
+	seen := make(models.Set[string])
+	addOrganizer := func(user *models.User) {
+		if user == nil {
+			return
+		}
+		if _, ok := seen[user.Id.Hex()]; ok {
+			return
+		}
+		seen[user.Id.Hex()] = struct{}{}
+		organizers = append(organizers, user)
+	}
+
+	addOrganizer(db.GetUserById(event.OwnerId.Hex()))
+	for _, coOrganizer := range event.CoOrganizers {
+		if !coOrganizer.UserId.IsZero() {
+			addOrganizer(db.GetUserById(coOrganizer.UserId.Hex()))
+		} else {
+			addOrganizer(db.GetUserByEmail(coOrganizer.Email))
+		}
+	}
+	return organizers
+}
+
+// Returns whether the user is the owner or a co-organizer of the event
+func IsOrganizer(event *models.Event, user *models.User) bool {
+	if event.OwnerId == user.Id {
+		return true
+	}
+	for _, coOrganizer := range event.CoOrganizers {
+		if coOrganizer.UserId == user.Id || strings.EqualFold(coOrganizer.Email, user.Email) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the emails lowercased, without blanks, duplicates and the owner's
+// email, to make co-organizers of
+func NormalizeCoOrganizerEmails(emails []string, ownerEmail string) []string {
+	normalized := make([]string, 0)
+	for _, email := range emails {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if len(email) == 0 || email == strings.ToLower(ownerEmail) || utils.Contains(normalized, email) {
+			continue
+		}
+		normalized = append(normalized, email)
+	}
+	return normalized
+}
+
+// Returns the rule of the given organizer, which defaults to every alert by email
+func GetNotificationRule(event *models.Event, user *models.User) models.NotificationRule {
+	for _, rule := range event.NotificationRules {
+		if rule.UserId == user.Id {
+			return rule
+		}
+	}
+	return models.NotificationRule{
+		UserId:   user.Id,
+		Alerts:   models.AlertTypes,
+		Channels: []models.DeliveryChannel{models.EMAIL_CHANNEL},
+	}
+}
+
+// Validates the alerts and channels of the rule, removing duplicates
+func ValidateNotificationRule(rule *models.NotificationRule) error {
+	alerts := make([]models.AlertType, 0)
+	for _, alert := range rule.Alerts {
+		if !utils.Contains(models.AlertTypes, alert) {
+			return fmt.Errorf("invalid alert %q", alert)
+		}
+		if !utils.Contains(alerts, alert) {
+			alerts = append(alerts, alert)
+		}
+	}
+	channels := make([]models.DeliveryChannel, 0)
+	for _, channel := range rule.Channels {
+		if channel != models.EMAIL_CHANNEL && channel != models.SLACK_CHANNEL {
+			return fmt.Errorf("invalid channel %q", channel)
+		}
+		if !utils.Contains(channels, channel) {
+			channels = append(channels, channel)
+		}
+	}
+	rule.Alerts = alerts
+	rule.Channels = channels
+	return nil
+}
+
+// Sends the alert to every organizer of the event that wants it, except the
+// user with the given id (e.g. the organizer that triggered it). sendEmail
+// emails the given organizer, and slackText is the Slack message
+func NotifyOrganizers(event *models.Event, alert models.AlertType, exceptUserId string, sendEmail func(organizer *models.User), slackText string) {
+	for _, organizer := range GetOrganizers(event) {
+		if organizer.Id.Hex() == exceptUserId {
+			continue
+		}
+		rule := GetNotificationRule(event, organizer)
+		if !utils.Contains(rule.Alerts, alert) {
+			continue
+		}
+		if utils.Contains(rule.Channels, models.EMAIL_CHANNEL) {
+			sendEmail(organizer)
+		}
+		if utils.Contains(rule.Channels, models.SLACK_CHANNEL) {
+			for _, account := range db.GetSlackAccountsByUserId(organizer.Id) {
+				if err := slack.PostMessage(account.SlackUserId, slackText, nil); err != nil {
+					logger.StdErr.Println(err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package notifications
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+func TestGetNotificationRule(t *testing.T) {
+	owner := &models.User{Id: primitive.NewObjectID()}
+	coOrganizer := &models.User{Id: primitive.NewObjectID(), Email: "ana@example.com"}
+	event := &models.Event{
+		OwnerId:      owner.Id,
+		CoOrganizers: []models.CoOrganizer{{Email: "ana@example.com"}},
+		NotificationRules: []models.NotificationRule{
+			{UserId: coOrganizer.Id, Alerts: []models.AlertType{models.RESCHEDULE_ALERT}, Channels: []models.DeliveryChannel{models.SLACK_CHANNEL}},
+		},
+	}
+
+	if rule := GetNotificationRule(event, owner); len(rule.Alerts) != len(models.AlertTypes) || !utils.Contains(rule.Channels, models.EMAIL_CHANNEL) {
+		t.Errorf("got owner rule %+v, want every alert by email", rule)
+	}
+	if rule := GetNotificationRule(event, coOrganizer); len(rule.Alerts) != 1 || rule.Channels[0] != models.SLACK_CHANNEL {
+		t.Errorf("got co-organizer rule %+v", rule)
+	}
+	if !IsOrganizer(event, coOrganizer) || IsOrganizer(event, &models.User{Id: primitive.NewObjectID()}) {
+		t.Error("expected only the owner and co-organizers to be organizers")
+	}
+}
+
+func TestValidateNotificationRule(t *testing.T) {
+	rule := models.NotificationRule{
+		Alerts:   []models.AlertType{models.RESPONSE_ALERT, models.RESPONSE_ALERT},
+		Channels: []models.DeliveryChannel{models.EMAIL_CHANNEL},
+	}
+	if err := ValidateNotificationRule(&rule); err != nil || len(rule.Alerts) != 1 {
+		t.Errorf("got %+v, %v", rule, err)
+	}
+	if err := ValidateNotificationRule(&models.NotificationRule{Channels: []models.DeliveryChannel{"sms"}}); err == nil {
+		t.Error("expected unknown channel to be invalid")
+	}
+}
+
+func TestNormalizeCoOrganizerEmails(t *testing.T) {
+	emails := NormalizeCoOrganizerEmails([]string{" Ana@Example.com", "", "owner@example.com", "ana@example.com", "bo@example.com"}, "Owner@example.com")
+
+	expected := []string{"ana@example.com", "bo@example.com"}
+	if len(emails) != len(expected) {
+		t.Fatalf("got %v, want %v", emails, expected)
+	}
+	for i := range expected {
+		if emails[i] != expected[i] {
+			t.Errorf("emails[%d] = %s, want %s", i, emails[i], expected[i])
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package notifications
+
+import (
+	"schej.it/server/models"
+	"schej.it/server/services/gcloud"
+	"schej.it/server/utils"
+)
+
+// Sets the event's remindees to the given emails. Reminder emails are
+// scheduled for added remindees (unless the event is a draft) and cancelled
+// for removed ones, and kept remindees keep whether they responded. Returns
+// the added emails
+func UpdateRemindees(event *models.Event, emails []string, ownerName string) []string {
+	origRemindees := utils.Coalesce(event.Remindees)
+	updatedRemindees := make([]models.Remindee, 0)
+	added, removed, kept := utils.FindAddedRemovedKept(emails, utils.Map(origRemindees, func(r models.Remindee) string { return r.Email }))
+
+	for _, keptEmail := range kept {
+		updatedRemindees = append(updatedRemindees, origRemindees[keptEmail.Index])
+	}
+
+	for _, addedEmail := range added {
+		// Schedule email tasks
+		var taskIds []string
+		if !utils.Coalesce(event.IsDraft) {
+			taskIds = gcloud.CreateEmailTask(addedEmail.Value, ownerName, event.Name, event.GetId(), event.ReminderCadence)
+		}
+		updatedRemindees = append(updatedRemindees, models.Remindee{
+			Email:     addedEmail.Value,
+			TaskIds:   taskIds,
+			Responded: utils.FalsePtr(),
+		})
+	}
+
+	for _, removedEmail := range removed {
+		// Delete email tasks
+		for _, taskId := range origRemindees[removedEmail.Index].TaskIds {
+			gcloud.DeleteEmailTask(taskId)
+		}
+	}
+
+	event.Remindees = &updatedRemindees
+	return utils.Map(added, func(a utils.ElementWithIndex[string]) string { return a.Value })
+}
@@ -0,0 +1,308 @@
+// Writes a row per event (name, status, best times, scheduled time) to a
+// Notion database the owner connected, and keeps the rows up to date as
+// people respond and the event is scheduled
+package notion
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+const API_URL = "https://api.notion.com/v1"
+const API_VERSION = "2022-06-28"
+
+// How far back events are added to a newly connected database
+const HISTORY = 30 * 24 * time.Hour
+
+// How many of the best times are listed
+const NUM_BEST_TIMES = 3
+
+// Status of an event in the database
+const (
+	STATUS_OPEN      = "Open"
+	STATUS_SCHEDULED = "Scheduled"
+	STATUS_ARCHIVED  = "Archived"
+)
+
+// Properties the database must have, by name and type
+var RequiredProperties = map[string]string{
+	"Name":       "title",
+	"Status":     "select",
+	"Responses":  "number",
+	"Best times": "rich_text",
+	"Scheduled":  "date",
+	"Link":       "url",
+}
+
+// Error returned by the Notion API
+type ApiError struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ApiError) Error() string {
+	return fmt.Sprintf("notion: %s (%d): %s", e.Code, e.Status, e.Message)
+}
+
+// Returns whether the integration can no longer access the database, in which
+// case syncing stops until the user reconnects
+func (e *ApiError) IsUnauthorized() bool {
+	return e.Status == http.StatusUnauthorized || e.Status == http.StatusForbidden
+}
+
+func callApi(token string, method string, path string, body interface{}, result interface{}) *ApiError {
+	var reqBody io.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return &ApiError{Status: http.StatusInternalServerError, Code: "invalid_body", Message: err.Error()}
+		}
+		reqBody = bytes.NewReader(bodyBytes)
+	}
+
+	req, err := http.NewRequest(method, API_URL+path, reqBody)
+	if err != nil {
+		return &ApiError{Status: http.StatusInternalServerError, Code: "invalid_request", Message: err.Error()}
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Notion-Version", API_VERSION)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	response, err := client.Do(req)
+	if err != nil {
+		return &ApiError{Status: http.StatusBadGateway, Code: "request_failed", Message: err.Error()}
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return &ApiError{Status: http.StatusBadGateway, Code: "request_failed", Message: err.Error()}
+	}
+	if response.StatusCode >= 300 {
+		apiErr := &ApiError{}
+		if err := json.Unmarshal(responseBody, apiErr); err != nil || apiErr.Status == 0 {
+			apiErr = &ApiError{Status: response.StatusCode, Code: "unknown", Message: string(responseBody)}
+		}
+		return apiErr
+	}
+	if result != nil {
+		if err := json.Unmarshal(responseBody, result); err != nil {
+			return &ApiError{Status: http.StatusBadGateway, Code: "invalid_response", Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+// Returns the title of the database, and the required properties it's missing
+// or that have the wrong type
+func GetDatabase(token string, databaseId string) (string, []string, *ApiError) {
+	database := struct {
+		Title []struct {
+			PlainText string `json:"plain_text"`
+		} `json:"title"`
+		Properties map[string]struct {
+			Type string `json:"type"`
+		} `json:"properties"`
+	}{}
+	if apiErr := callApi(token, "GET", "/databases/"+url.PathEscape(databaseId), nil, &database); apiErr != nil {
+		return "", nil, apiErr
+	}
+
+	titles := make([]string, 0)
+	for _, title := range database.Title {
+		titles = append(titles, title.PlainText)
+	}
+	missing := make([]string, 0)
+	for name, propertyType := range RequiredProperties {
+		if property, ok := database.Properties[name]; !ok || property.Type != propertyType {
+			missing = append(missing, fmt.Sprintf("%s (%s)", name, propertyType))
+		}
+	}
+	sort.Strings(missing)
+	return strings.Join(titles, ""), missing, nil
+}
+
+func getStatus(event *models.Event) string {
+	if event.ScheduledEvent != nil || len(event.Sessions) > 0 {
+		return STATUS_SCHEDULED
+	}
+	if utils.Coalesce(event.IsArchived) {
+		return STATUS_ARCHIVED
+	}
+	return STATUS_OPEN
+}
+
+func getScheduledTime(event *models.Event) (time.Time, time.Time, bool) {
+	if event.ScheduledEvent != nil {
+		return event.ScheduledEvent.StartDate.Time(), event.ScheduledEvent.EndDate.Time(), true
+	}
+	if len(event.Sessions) > 0 {
+		// The date property holds a single range, so sessions are shown as
+		// the first session
+		first := event.Sessions[0]
+		for _, session := range event.Sessions[1:] {
+			if session.StartDate < first.StartDate {
+				first = session
+			}
+		}
+		return first.StartDate.Time(), first.EndDate.Time(), true
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// Returns the best times to meet, formatted in loc, e.g.
+// "Mon, Mar 2 3:00 PM (4/5)". Times nobody can make are left out
+func GetBestTimes(event *models.Event, respondents []scheduling.Respondent, loc *time.Location) []string {
+	bestTimes := make([]string, 0)
+	if len(respondents) == 0 || event.ScheduledEvent != nil || len(event.Sessions) > 0 {
+		return bestTimes
+	}
+	for _, slot := range scheduling.RankSlots(event, respondents, scheduling.Options{}) {
+		if len(bestTimes) == NUM_BEST_TIMES || len(slot.Available) == 0 {
+			break
+		}
+		bestTimes = append(bestTimes, fmt.Sprintf("%s (%d/%d)", slot.Start.In(loc).Format("Mon, Jan 2 3:04 PM"), len(slot.Available), len(respondents)))
+	}
+	return bestTimes
+}
+
+func richText(content string) []interface{} {
+	if len(content) == 0 {
+		return []interface{}{}
+	}
+	return []interface{}{map[string]interface{}{"text": map[string]interface{}{"content": content}}}
+}
+
+// Returns the properties of the event's row
+func GetProperties(event *models.Event, numResponses int, bestTimes []string) map[string]interface{} {
+	var scheduled interface{}
+	if start, end, ok := getScheduledTime(event); ok {
+		scheduled = map[string]interface{}{
+			"start": start.UTC().Format(time.RFC3339),
+			"end":   end.UTC().Format(time.RFC3339),
+		}
+	}
+
+	return map[string]interface{}{
+		"Name":       map[string]interface{}{"title": richText(event.Name)},
+		"Status":     map[string]interface{}{"select": map[string]interface{}{"name": getStatus(event)}},
+		"Responses":  map[string]interface{}{"number": numResponses},
+		"Best times": map[string]interface{}{"rich_text": richText(strings.Join(bestTimes, "\n"))},
+		"Scheduled":  map[string]interface{}{"date": scheduled},
+		"Link":       map[string]interface{}{"url": fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId())},
+	}
+}
+
+// Returns the hash of the properties, to tell whether the row changed
+func GetHash(properties map[string]interface{}) string {
+	// Maps are marshalled with sorted keys, so equal properties hash the same
+	propertiesBytes, err := json.Marshal(properties)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	hash := sha256.Sum256(propertiesBytes)
+	return hex.EncodeToString(hash[:])
+}
+
+// Writes the event's row to the owner's database, creating it if the event
+// doesn't have one yet. Rows of deleted events are archived
+func SyncEvent(event *models.Event, owner *models.User, token string, now time.Time) *ApiError {
+	connection := owner.NotionConnection
+
+	if utils.Coalesce(event.IsDeleted) {
+		if event.NotionPage != nil {
+			apiErr := callApi(token, "PATCH", "/pages/"+url.PathEscape(event.NotionPage.PageId), map[string]interface{}{"archived": true}, nil)
+			if apiErr != nil && apiErr.Status != http.StatusNotFound {
+				return apiErr
+			}
+			db.SetNotionPage(event.Id, nil)
+		}
+		return nil
+	}
+
+	eventResponses := db.GetEventResponses(event.Id.Hex())
+	respondents := scheduling.GetRespondents(eventResponses)
+	properties := GetProperties(event, len(eventResponses), GetBestTimes(event, respondents, utils.GetEventLocation(event, owner)))
+	hash := GetHash(properties)
+	if event.NotionPage != nil && event.NotionPage.Hash == hash {
+		return nil
+	}
+
+	page := struct {
+		Id string `json:"id"`
+	}{}
+	var apiErr *ApiError
+	if event.NotionPage != nil {
+		page.Id = event.NotionPage.PageId
+		apiErr = callApi(token, "PATCH", "/pages/"+url.PathEscape(page.Id), map[string]interface{}{"properties": properties}, nil)
+	}
+	if event.NotionPage == nil || (apiErr != nil && apiErr.Status == http.StatusNotFound) {
+		// The row was deleted from the database, so it's added back
+		apiErr = callApi(token, "POST", "/pages", map[string]interface{}{
+			"parent":     map[string]interface{}{"database_id": connection.DatabaseId},
+			"properties": properties,
+		}, &page)
+	}
+	if apiErr != nil {
+		return apiErr
+	}
+
+	db.SetNotionPage(event.Id, &models.NotionPage{
+		PageId:   page.Id,
+		Hash:     hash,
+		SyncedAt: primitive.NewDateTimeFromTime(now),
+	})
+	return nil
+}
+
+// Syncs the events of every user that connected a database. Run periodically
+// by the jobs scheduler
+func SyncEvents(now time.Time) {
+	for _, user := range db.GetUsersWithNotionConnection() {
+		user := user
+		token, err := utils.Decrypt(user.NotionConnection.Token)
+		if err != nil {
+			logger.StdErr.Printf("Failed to decrypt notion token of user %s: %v\n", user.Id.Hex(), err)
+			continue
+		}
+
+		since := user.NotionConnection.ConnectedAt.Time().Add(-HISTORY)
+		syncError := ""
+		for _, event := range db.GetNotionEvents(user.Id, since) {
+			event := event
+			apiErr := SyncEvent(&event, &user, token, now)
+			if apiErr == nil {
+				continue
+			}
+			logger.StdErr.Printf("Failed to sync event %s to notion: %v\n", event.Id.Hex(), apiErr)
+			if apiErr.IsUnauthorized() || apiErr.Status == http.StatusNotFound {
+				// The integration lost access to the database (missing rows
+				// are added back, so a 404 is for the database), so the other
+				// events would fail too
+				syncError = apiErr.Message
+				break
+			}
+		}
+		if syncError != user.NotionConnection.LastError {
+			db.SetNotionSyncError(user.Id, syncError)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package notion
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/services/scheduling/schedulingtest"
+)
+
+func newEvent() *models.Event {
+	shortId := "abc123"
+	event := schedulingtest.NewEvent()
+	event.Id = primitive.NewObjectID()
+	event.ShortId = &shortId
+	event.Name = "Team sync"
+	return event
+}
+
+func TestGetBestTimes(t *testing.T) {
+	event := newEvent()
+	respondents := []scheduling.Respondent{
+		schedulingtest.NewRespondent("a", 9, 10),
+		schedulingtest.NewRespondent("b", 10),
+	}
+
+	bestTimes := GetBestTimes(event, respondents, time.UTC)
+	if len(bestTimes) != 2 {
+		t.Fatalf("got best times %v, want the 2 times someone can make", bestTimes)
+	}
+	if bestTimes[0] != "Tue, May 14 10:00 AM (2/2)" {
+		t.Errorf("got best time %q", bestTimes[0])
+	}
+
+	event.ScheduledEvent = &models.CalendarEvent{}
+	if bestTimes := GetBestTimes(event, respondents, time.UTC); len(bestTimes) != 0 {
+		t.Errorf("got best times %v for a scheduled event", bestTimes)
+	}
+}
+
+func TestGetProperties(t *testing.T) {
+	event := newEvent()
+	properties := GetProperties(event, 2, []string{"Tue, May 14 10:00 AM (2/2)"})
+	for name := range RequiredProperties {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("missing property %s", name)
+		}
+	}
+	if status := properties["Status"].(map[string]interface{})["select"].(map[string]interface{})["name"]; status != STATUS_OPEN {
+		t.Errorf("got status %v, want %v", status, STATUS_OPEN)
+	}
+	if date := properties["Scheduled"].(map[string]interface{})["date"]; date != nil {
+		t.Errorf("got scheduled date %v for an open event", date)
+	}
+
+	event.ScheduledEvent = &models.CalendarEvent{
+		StartDate: primitive.NewDateTimeFromTime(schedulingtest.Hour(10)),
+		EndDate:   primitive.NewDateTimeFromTime(schedulingtest.Hour(11)),
+	}
+	scheduled := GetProperties(event, 2, nil)
+	if status := scheduled["Status"].(map[string]interface{})["select"].(map[string]interface{})["name"]; status != STATUS_SCHEDULED {
+		t.Errorf("got status %v, want %v", status, STATUS_SCHEDULED)
+	}
+	date := scheduled["Scheduled"].(map[string]interface{})["date"].(map[string]interface{})
+	if date["start"] != "2024-05-14T10:00:00Z" || date["end"] != "2024-05-14T11:00:00Z" {
+		t.Errorf("got scheduled date %v", date)
+	}
+}
+
+func TestGetHash(t *testing.T) {
+	event := newEvent()
+	if GetHash(GetProperties(event, 1, nil)) != GetHash(GetProperties(event, 1, nil)) {
+		t.Error("got different hashes for the same properties")
+	}
+	if GetHash(GetProperties(event, 1, nil)) == GetHash(GetProperties(event, 2, nil)) {
+		t.Error("got the same hash after a new response")
+	}
+}
@@ -0,0 +1,67 @@
+package organizations
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+const maxTags = 20
+const maxTagLength = 40
+
+// Returns the tags trimmed, lowercased, and without duplicates, or an error if
+// there are too many or one is too long
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if len(tag) == 0 || utils.Contains(normalized, tag) {
+			continue
+		}
+		if len(tag) > maxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+		}
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxTags {
+		return nil, fmt.Errorf("events can have at most %d tags", maxTags)
+	}
+	return normalized, nil
+}
+
+// Filters of the organization's calendar. Unset filters match every event
+type CalendarFilter struct {
+	// Only events in the folder, by id
+	EventIds models.Set[primitive.ObjectID]
+
+	// Only events with at least one of the tags
+	Tags []string
+
+	// Only events the member owns or co-organizes
+	MemberId primitive.ObjectID
+}
+
+// Returns the events that match the filter
+func FilterCalendarEvents(events []models.Event, filter CalendarFilter) []models.Event {
+	filtered := make([]models.Event, 0)
+	for _, event := range events {
+		if filter.EventIds != nil {
+			if _, ok := filter.EventIds[event.Id]; !ok {
+				continue
+			}
+		}
+		if len(filter.Tags) > 0 && utils.Find(event.Tags, func(tag string) bool { return utils.Contains(filter.Tags, tag) }) == -1 {
+			continue
+		}
+		if !filter.MemberId.IsZero() && event.OwnerId != filter.MemberId && utils.Find(event.CoOrganizers, func(o models.CoOrganizer) bool {
+			return o.UserId == filter.MemberId
+		}) == -1 {
+			continue
+		}
+		filtered = append(filtered, event)
+	}
+	return filtered
+}
@@ -0,0 +1,56 @@
+package organizations
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := NormalizeTags([]string{" Hiring ", "hiring", "", "Offsite"})
+	if err != nil || !reflect.DeepEqual(tags, []string{"hiring", "offsite"}) {
+		t.Errorf("got %v, %v", tags, err)
+	}
+
+	if _, err := NormalizeTags([]string{strings.Repeat("a", maxTagLength+1)}); err == nil {
+		t.Errorf("expected an error for a tag that's too long")
+	}
+}
+
+func TestFilterCalendarEvents(t *testing.T) {
+	alice, bob := primitive.NewObjectID(), primitive.NewObjectID()
+	events := []models.Event{
+		{Id: primitive.NewObjectID(), OwnerId: alice, Tags: []string{"hiring"}},
+		{Id: primitive.NewObjectID(), OwnerId: alice, CoOrganizers: []models.CoOrganizer{{UserId: bob}}},
+		{Id: primitive.NewObjectID(), OwnerId: bob, Tags: []string{"offsite", "hiring"}},
+	}
+	getIds := func(events []models.Event) []primitive.ObjectID {
+		return utils.Map(events, func(e models.Event) primitive.ObjectID { return e.Id })
+	}
+
+	if got := FilterCalendarEvents(events, CalendarFilter{}); len(got) != 3 {
+		t.Errorf("got %d events without filters, want 3", len(got))
+	}
+
+	got := getIds(FilterCalendarEvents(events, CalendarFilter{Tags: []string{"hiring"}}))
+	if want := []primitive.ObjectID{events[0].Id, events[2].Id}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v filtering by tag, want %v", got, want)
+	}
+
+	got = getIds(FilterCalendarEvents(events, CalendarFilter{MemberId: bob}))
+	if want := []primitive.ObjectID{events[1].Id, events[2].Id}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v filtering by member, want %v", got, want)
+	}
+
+	got = getIds(FilterCalendarEvents(events, CalendarFilter{
+		EventIds: utils.ArrayToSet([]primitive.ObjectID{events[0].Id, events[2].Id}),
+		MemberId: bob,
+	}))
+	if want := []primitive.ObjectID{events[2].Id}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v filtering by folder and member, want %v", got, want)
+	}
+}
@@ -0,0 +1,81 @@
+package organizations
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+var domainRegex = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,}$`)
+
+// Domains of public email providers, which can't be claimed
+var publicDomains = []string{
+	"gmail.com", "googlemail.com", "outlook.com", "hotmail.com", "live.com", "yahoo.com",
+	"icloud.com", "me.com", "aol.com", "proton.me", "protonmail.com", "gmx.com", "mail.com",
+}
+
+// Returns the lowercased domain, or an error if it can't be claimed
+func NormalizeDomain(domain string) (string, error) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	domain = strings.TrimPrefix(domain, "@")
+	if !domainRegex.MatchString(domain) {
+		return "", fmt.Errorf("invalid domain %q", domain)
+	}
+	if utils.Contains(publicDomains, domain) {
+		return "", fmt.Errorf("%s is a public email domain", domain)
+	}
+	return domain, nil
+}
+
+// Returns the lowercased domain of the email
+func GetEmailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at == -1 {
+		return ""
+	}
+	return strings.ToLower(email[at+1:])
+}
+
+func NewVerificationToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Returns the name and value of the TXT record that verifies the domain
+func GetVerificationRecord(domain models.OrganizationDomain) (string, string) {
+	return "_timeful-verification." + domain.Domain, "timeful-verification=" + domain.VerificationToken
+}
+
+// Returns whether the domain has its verification record, looking up TXT
+// records with lookupTXT (net.LookupTXT)
+func IsDomainVerified(domain models.OrganizationDomain, lookupTXT func(name string) ([]string, error)) bool {
+	name, value := GetVerificationRecord(domain)
+	records, err := lookupTXT(name)
+	if err != nil {
+		return false
+	}
+	for _, record := range records {
+		if strings.TrimSpace(record) == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the claim of the organization on the domain, or nil if it has none
+func GetDomain(org *models.Organization, domain string) *models.OrganizationDomain {
+	for i := range org.Domains {
+		if org.Domains[i].Domain == domain {
+			return &org.Domains[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+// Membership checks and default event settings of organizations
+package organizations
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+var timeRegex = regexp.MustCompile(`^([01]\d|2[0-3]):[0-5]\d$`)
+var colorRegex = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+var externalIdRegex = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// Returns the membership of the given user, or nil if they aren't a member
+func GetMember(org *models.Organization, userId primitive.ObjectID) *models.OrganizationMember {
+	for i := range org.Members {
+		if org.Members[i].UserId == userId {
+			return &org.Members[i]
+		}
+	}
+	return nil
+}
+
+// Returns whether the user is an admin or an owner of the organization
+func IsAdmin(org *models.Organization, userId primitive.ObjectID) bool {
+	member := GetMember(org, userId)
+	return member != nil && (member.Role == models.ORG_ADMIN || member.Role == models.ORG_OWNER)
+}
+
+func IsOwner(org *models.Organization, userId primitive.ObjectID) bool {
+	member := GetMember(org, userId)
+	return member != nil && member.Role == models.ORG_OWNER
+}
+
+// Returns whether the user can manage the organization's subscription and
+// invoices, which admins and billing admins can
+func CanManageBilling(org *models.Organization, userId primitive.ObjectID) bool {
+	member := GetMember(org, userId)
+	return member != nil && (IsAdmin(org, userId) || member.Role == models.ORG_BILLING_ADMIN)
+}
+
+// Returns the number of admins of the organization, including owners
+func NumAdmins(org *models.Organization) int {
+	numAdmins := 0
+	for _, member := range org.Members {
+		if member.Role == models.ORG_ADMIN || member.Role == models.ORG_OWNER {
+			numAdmins++
+		}
+	}
+	return numAdmins
+}
+
+func NumOwners(org *models.Organization) int {
+	numOwners := 0
+	for _, member := range org.Members {
+		if member.Role == models.ORG_OWNER {
+			numOwners++
+		}
+	}
+	return numOwners
+}
+
+func IsValidRole(role models.OrganizationRole) bool {
+	return role == models.ORG_OWNER || role == models.ORG_ADMIN || role == models.ORG_MEMBER || role == models.ORG_BILLING_ADMIN
+}
+
+// Returned by CheckRoleChange when the user isn't allowed to make the change
+var ErrRoleChangeForbidden = errors.New("only admins can change members, and only owners can change owners")
+
+// Checks whether the actor can give the user the role, or remove them from
+// the organization if the role is empty. Admins manage members, owners also
+// manage other owners (organizations created before owners existed let
+// admins add the first one), and members can remove themselves. The last
+// owner and the last admin can't be demoted or removed
+func CheckRoleChange(org *models.Organization, actorId primitive.ObjectID, userId primitive.ObjectID, role models.OrganizationRole) error {
+	if len(role) > 0 && !IsValidRole(role) {
+		return fmt.Errorf("invalid role %q", role)
+	}
+
+	member := GetMember(org, userId)
+	leaving := len(role) == 0 && actorId == userId
+	if !leaving && !IsAdmin(org, actorId) {
+		return ErrRoleChangeForbidden
+	}
+	changesOwner := role == models.ORG_OWNER || (member != nil && member.Role == models.ORG_OWNER)
+	if changesOwner && !leaving && !IsOwner(org, actorId) && NumOwners(org) > 0 {
+		return ErrRoleChangeForbidden
+	}
+
+	if member == nil || member.Role == role {
+		return nil
+	}
+	if member.Role == models.ORG_OWNER && NumOwners(org) == 1 {
+		return errors.New("organizations need at least one owner")
+	}
+	if (member.Role == models.ORG_ADMIN || member.Role == models.ORG_OWNER) && role != models.ORG_ADMIN && role != models.ORG_OWNER && NumAdmins(org) == 1 {
+		return errors.New("organizations need at least one admin")
+	}
+	return nil
+}
+
+// Returns whether the user can edit the event: its owner can, and so can the
+// admins of the organization it was created in
+func CanEditEvent(org *models.Organization, event *models.Event, userId primitive.ObjectID) bool {
+	if event.OwnerId == userId {
+		return true
+	}
+	return org != nil && !event.OrganizationId.IsZero() && event.OrganizationId == org.Id && IsAdmin(org, userId)
+}
+
+// Returns whether the user can see the folder, or edit it if edit is set.
+// Personal folders are only accessible to their creator, while the folders of
+// an organization are visible to its members and editable by its admins
+func CanAccessFolder(org *models.Organization, folder *models.Folder, userId primitive.ObjectID, edit bool) bool {
+	if folder.OrganizationId == nil {
+		return folder.UserId == userId
+	}
+	if org == nil || org.Id != *folder.OrganizationId {
+		return false
+	}
+	if edit {
+		return IsAdmin(org, userId)
+	}
+	return GetMember(org, userId) != nil
+}
+
+func IsLocked(settings models.OrganizationSettings, setting models.OrganizationSetting) bool {
+	return utils.Contains(settings.Locked, setting)
+}
+
+// Validates an external id of the provisioning API, which are up to 128
+// letters, digits, and ".", "_", ":" or "-"
+func ValidateExternalId(externalId string) error {
+	if !externalIdRegex.MatchString(externalId) {
+		return fmt.Errorf("invalid external id %q", externalId)
+	}
+	return nil
+}
+
+// Validates the settings set by an organization admin
+func ValidateSettings(settings models.OrganizationSettings) error {
+	if settings.Timezone != nil {
+		if _, err := time.LoadLocation(*settings.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q", *settings.Timezone)
+		}
+	}
+	if settings.WorkingHours != nil {
+		if !timeRegex.MatchString(settings.WorkingHours.StartTime) || !timeRegex.MatchString(settings.WorkingHours.EndTime) || settings.WorkingHours.StartTime >= settings.WorkingHours.EndTime {
+			return fmt.Errorf("working hours must be a HH:MM range")
+		}
+	}
+	if settings.ReminderCadence != nil {
+		if settings.ReminderCadence.SecondReminderHours <= 0 || settings.ReminderCadence.FinalReminderHours <= settings.ReminderCadence.SecondReminderHours {
+			return fmt.Errorf("the final reminder must come after the second reminder")
+		}
+	}
+	if settings.Branding != nil && len(settings.Branding.PrimaryColor) > 0 && !colorRegex.MatchString(settings.Branding.PrimaryColor) {
+		return fmt.Errorf("invalid color %q", settings.Branding.PrimaryColor)
+	}
+	if settings.HourlyRate != nil && *settings.HourlyRate < 0 {
+		return fmt.Errorf("hourly rate must not be negative")
+	}
+	for _, setting := range settings.Locked {
+		if !utils.Contains(models.OrganizationSettingKeys, setting) {
+			return fmt.Errorf("unknown setting %q", setting)
+		}
+	}
+	return nil
+}
+
+// Applies the organization's settings to the event. New events get the
+// defaults for the settings they don't set, and locked settings always
+// override the event's
+func ApplySettings(event *models.Event, settings models.OrganizationSettings, isNew bool) {
+	apply := func(setting models.OrganizationSetting, isSet bool) bool {
+		return IsLocked(settings, setting) || (isNew && !isSet)
+	}
+
+	if settings.Timezone != nil && apply(models.TIMEZONE_SETTING, event.Timezone != nil) {
+		event.Timezone = settings.Timezone
+	}
+	if settings.WorkingHours != nil && apply(models.WORKING_HOURS_SETTING, event.WorkingHours != nil) {
+		event.WorkingHours = settings.WorkingHours
+	}
+	if settings.BlindAvailabilityEnabled != nil && apply(models.ANONYMITY_SETTING, event.BlindAvailabilityEnabled != nil) {
+		event.BlindAvailabilityEnabled = settings.BlindAvailabilityEnabled
+	}
+	if settings.ReminderCadence != nil && apply(models.REMINDER_CADENCE_SETTING, event.ReminderCadence != nil) {
+		event.ReminderCadence = settings.ReminderCadence
+	}
+	if settings.Branding != nil && apply(models.BRANDING_SETTING, event.Branding != nil) {
+		event.Branding = settings.Branding
+	}
+}
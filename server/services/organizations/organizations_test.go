@@ -0,0 +1,268 @@
+package organizations
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+func TestApplySettings(t *testing.T) {
+	timezone := "America/New_York"
+	settings := models.OrganizationSettings{
+		Timezone:                 &timezone,
+		BlindAvailabilityEnabled: utils.TruePtr(),
+		Branding:                 &models.Branding{PrimaryColor: "#123456"},
+		Locked:                   []models.OrganizationSetting{models.ANONYMITY_SETTING},
+	}
+
+	// New events get the defaults they don't override
+	otherTimezone := "Europe/Paris"
+	event := &models.Event{Timezone: &otherTimezone, BlindAvailabilityEnabled: utils.FalsePtr()}
+	ApplySettings(event, settings, true)
+	if *event.Timezone != otherTimezone || event.Branding == nil || !*event.BlindAvailabilityEnabled {
+		t.Errorf("got timezone %v, branding %v, anonymity %v", *event.Timezone, event.Branding, *event.BlindAvailabilityEnabled)
+	}
+
+	// Edits can only change unlocked settings
+	event = &models.Event{BlindAvailabilityEnabled: utils.FalsePtr()}
+	ApplySettings(event, settings, false)
+	if event.Timezone != nil || event.Branding != nil || !*event.BlindAvailabilityEnabled {
+		t.Errorf("got timezone %v, branding %v, anonymity %v", event.Timezone, event.Branding, *event.BlindAvailabilityEnabled)
+	}
+}
+
+func TestCanManageBilling(t *testing.T) {
+	admin, billingAdmin, member := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	org := &models.Organization{Members: []models.OrganizationMember{
+		{UserId: admin, Role: models.ORG_ADMIN},
+		{UserId: billingAdmin, Role: models.ORG_BILLING_ADMIN},
+		{UserId: member, Role: models.ORG_MEMBER},
+	}}
+
+	if !CanManageBilling(org, admin) || !CanManageBilling(org, billingAdmin) {
+		t.Errorf("admins and billing admins should manage billing")
+	}
+	if CanManageBilling(org, member) || CanManageBilling(org, primitive.NewObjectID()) {
+		t.Errorf("members and non-members shouldn't manage billing")
+	}
+	if IsAdmin(org, billingAdmin) || NumAdmins(org) != 1 {
+		t.Errorf("billing admins shouldn't be admins")
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	timezone := "Not/A_Zone"
+	hourlyRate := -10.0
+	invalid := []models.OrganizationSettings{
+		{Timezone: &timezone},
+		{WorkingHours: &models.WorkingHours{StartTime: "17:00", EndTime: "09:00"}},
+		{ReminderCadence: &models.ReminderCadence{SecondReminderHours: 48, FinalReminderHours: 24}},
+		{Branding: &models.Branding{PrimaryColor: "blue"}},
+		{HourlyRate: &hourlyRate},
+		{Locked: []models.OrganizationSetting{"name"}},
+	}
+	for _, settings := range invalid {
+		if err := ValidateSettings(settings); err == nil {
+			t.Errorf("expected %+v to be invalid", settings)
+		}
+	}
+	if err := ValidateSettings(models.OrganizationSettings{WorkingHours: &models.WorkingHours{StartTime: "09:00", EndTime: "17:30"}}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNormalizeDomain(t *testing.T) {
+	if domain, err := NormalizeDomain(" @School.EDU "); err != nil || domain != "school.edu" {
+		t.Errorf("got %q, %v", domain, err)
+	}
+	for _, domain := range []string{"gmail.com", "not a domain", "localhost"} {
+		if _, err := NormalizeDomain(domain); err == nil {
+			t.Errorf("expected %q to be invalid", domain)
+		}
+	}
+}
+
+func TestIsDomainVerified(t *testing.T) {
+	domain := models.OrganizationDomain{Domain: "school.edu", VerificationToken: "abc"}
+	lookup := func(records ...string) func(string) ([]string, error) {
+		return func(name string) ([]string, error) {
+			if name != "_timeful-verification.school.edu" {
+				t.Errorf("looked up %q", name)
+			}
+			return records, nil
+		}
+	}
+	if !IsDomainVerified(domain, lookup("v=spf1", "timeful-verification=abc")) {
+		t.Error("expected domain to be verified")
+	}
+	if IsDomainVerified(domain, lookup("timeful-verification=other")) {
+		t.Error("expected domain with another token not to be verified")
+	}
+}
+
+func TestCheckSendingDomain(t *testing.T) {
+	if _, err := NewSendingDomain("acme.com", "not an address", ""); err == nil {
+		t.Error("expected an invalid local part to be rejected")
+	}
+	sendingDomain, err := NewSendingDomain("acme.com", "Invites", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := CheckSendingDomain(*sendingDomain, func(string) ([]string, error) { return nil, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if AreRecordsVerified(records) {
+		t.Error("expected records not to be verified without DNS records")
+	}
+
+	published := map[string][]string{}
+	for _, record := range records {
+		published[record.Name] = []string{record.Value}
+	}
+	// Existing SPF records get the include added, and DKIM records are often
+	// split by DNS providers
+	published["acme.com"] = []string{"v=spf1 include:mail.example.com include:_spf.google.com -all"}
+	dkimName := "timeful._domainkey.acme.com"
+	published[dkimName] = []string{strings.Replace(published[dkimName][0], "p=", "p= ", 1)}
+	records, err = CheckSendingDomain(*sendingDomain, func(name string) ([]string, error) { return published[name], nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !AreRecordsVerified(records) {
+		t.Errorf("expected records to be verified, got %+v", records)
+	}
+
+	org := &models.Organization{Name: "Acme", SendingDomain: sendingDomain}
+	if GetEmailSender(org) != nil {
+		t.Error("expected unverified domains not to be sent from")
+	}
+	verifiedAt := primitive.NewDateTimeFromTime(time.Now())
+	sendingDomain.VerifiedAt = &verifiedAt
+	if sender := GetEmailSender(org); sender == nil || sender.Address != "invites@acme.com" || sender.Name != "Acme" || sender.Signer.Domain != "acme.com" {
+		t.Errorf("got sender %+v", sender)
+	}
+}
+
+func TestCheckRoleChange(t *testing.T) {
+	owner, admin, member := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	org := &models.Organization{Members: []models.OrganizationMember{
+		{UserId: owner, Role: models.ORG_OWNER},
+		{UserId: admin, Role: models.ORG_ADMIN},
+		{UserId: member, Role: models.ORG_MEMBER},
+	}}
+	newUser := primitive.NewObjectID()
+
+	allowed := []struct {
+		actor, user primitive.ObjectID
+		role        models.OrganizationRole
+	}{
+		{admin, newUser, models.ORG_MEMBER},
+		{admin, member, models.ORG_ADMIN},
+		{admin, member, ""},
+		{member, member, ""},
+		{owner, admin, models.ORG_OWNER},
+		{owner, newUser, models.ORG_OWNER},
+	}
+	for _, change := range allowed {
+		if err := CheckRoleChange(org, change.actor, change.user, change.role); err != nil {
+			t.Errorf("expected %v to give %v the role %q, got %v", change.actor, change.user, change.role, err)
+		}
+	}
+
+	forbidden := []struct {
+		actor, user primitive.ObjectID
+		role        models.OrganizationRole
+	}{
+		{member, newUser, models.ORG_MEMBER},
+		{member, admin, ""},
+		{admin, member, models.ORG_OWNER},
+		{admin, owner, ""},
+		{admin, owner, models.ORG_MEMBER},
+	}
+	for _, change := range forbidden {
+		if err := CheckRoleChange(org, change.actor, change.user, change.role); err != ErrRoleChangeForbidden {
+			t.Errorf("expected %v not to give %v the role %q, got %v", change.actor, change.user, change.role, err)
+		}
+	}
+
+	if err := CheckRoleChange(org, owner, owner, models.ORG_ADMIN); err == nil {
+		t.Errorf("the last owner shouldn't be demoted")
+	}
+	if err := CheckRoleChange(org, owner, owner, ""); err == nil {
+		t.Errorf("the last owner shouldn't leave")
+	}
+	if err := CheckRoleChange(org, admin, member, "superuser"); err == nil || err == ErrRoleChangeForbidden {
+		t.Errorf("expected an invalid role error, got %v", err)
+	}
+
+	// Organizations without owners let admins add the first one
+	legacy := &models.Organization{Members: []models.OrganizationMember{{UserId: admin, Role: models.ORG_ADMIN}}}
+	if err := CheckRoleChange(legacy, admin, admin, models.ORG_OWNER); err != nil {
+		t.Errorf("expected the admin to become owner, got %v", err)
+	}
+	if err := CheckRoleChange(legacy, admin, admin, models.ORG_MEMBER); err == nil {
+		t.Errorf("the last admin shouldn't be demoted")
+	}
+}
+
+func TestCanEditEvent(t *testing.T) {
+	owner, admin, member := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	org := &models.Organization{Id: primitive.NewObjectID(), Members: []models.OrganizationMember{
+		{UserId: owner, Role: models.ORG_OWNER},
+		{UserId: admin, Role: models.ORG_ADMIN},
+		{UserId: member, Role: models.ORG_MEMBER},
+	}}
+	event := &models.Event{OwnerId: member, OrganizationId: org.Id}
+
+	if !CanEditEvent(org, event, member) || !CanEditEvent(org, event, admin) || !CanEditEvent(org, event, owner) {
+		t.Errorf("the event's owner and the organization's admins should edit it")
+	}
+	if CanEditEvent(org, &models.Event{OwnerId: owner, OrganizationId: org.Id}, member) {
+		t.Errorf("members shouldn't edit other members' events")
+	}
+	if CanEditEvent(org, &models.Event{OwnerId: member}, admin) || CanEditEvent(org, &models.Event{OwnerId: member, OrganizationId: primitive.NewObjectID()}, admin) {
+		t.Errorf("admins shouldn't edit events outside the organization")
+	}
+}
+
+func TestCanAccessFolder(t *testing.T) {
+	admin, member, outsider := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	org := &models.Organization{Id: primitive.NewObjectID(), Members: []models.OrganizationMember{
+		{UserId: admin, Role: models.ORG_ADMIN},
+		{UserId: member, Role: models.ORG_MEMBER},
+	}}
+	personal := &models.Folder{UserId: member}
+	shared := &models.Folder{UserId: admin, OrganizationId: &org.Id}
+
+	if !CanAccessFolder(nil, personal, member, true) || CanAccessFolder(nil, personal, admin, false) {
+		t.Errorf("personal folders should only be accessible to their creator")
+	}
+	if !CanAccessFolder(org, shared, member, false) || CanAccessFolder(org, shared, member, true) {
+		t.Errorf("members should see but not edit the organization's folders")
+	}
+	if !CanAccessFolder(org, shared, admin, true) {
+		t.Errorf("admins should edit the organization's folders")
+	}
+	if CanAccessFolder(org, shared, outsider, false) || CanAccessFolder(nil, shared, admin, false) {
+		t.Errorf("the organization's folders shouldn't be accessible outside it")
+	}
+}
+
+func TestValidateExternalId(t *testing.T) {
+	for _, externalId := range []string{"acme", "team:eng-1", "org_42.prod"} {
+		if err := ValidateExternalId(externalId); err != nil {
+			t.Error(err)
+		}
+	}
+	for _, externalId := range []string{"", "has space", "a/b", strings.Repeat("a", 129)} {
+		if ValidateExternalId(externalId) == nil {
+			t.Errorf("expected %q to be invalid", externalId)
+		}
+	}
+}
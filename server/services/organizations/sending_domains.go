@@ -0,0 +1,133 @@
+package organizations
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"schej.it/server/models"
+	"schej.it/server/services/dkim"
+	"schej.it/server/utils"
+)
+
+// Selector of the DKIM keys generated for sending domains
+const DKIM_SELECTOR = "timeful"
+
+var localPartRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9._+-]{0,62}[a-z0-9])?$`)
+
+// A DNS record the organization has to add to send from its domain
+type DnsRecord struct {
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Verified bool   `json:"verified"`
+}
+
+// Returns the domain that sending domains' SPF records have to include, which
+// is the SMTP provider's (EMAIL_SPF_INCLUDE, Gmail's by default)
+func getSpfInclude() string {
+	if include := os.Getenv("EMAIL_SPF_INCLUDE"); len(include) > 0 {
+		return include
+	}
+	return "_spf.google.com"
+}
+
+// Returns a sending domain for the already normalized domain, with a new DKIM
+// key, or an error if the local part is invalid
+func NewSendingDomain(domain string, localPart string, fromName string) (*models.OrganizationSendingDomain, error) {
+	if err := ValidateLocalPart(localPart); err != nil {
+		return nil, err
+	}
+	key, err := dkim.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	return &models.OrganizationSendingDomain{
+		Domain:            domain,
+		LocalPart:         strings.ToLower(localPart),
+		FromName:          strings.TrimSpace(fromName),
+		VerificationToken: NewVerificationToken(),
+		DkimSelector:      DKIM_SELECTOR,
+		DkimPrivateKey:    key,
+	}, nil
+}
+
+// Returns an error if the local part can't be used in the from address
+func ValidateLocalPart(localPart string) error {
+	if !localPartRegex.MatchString(strings.ToLower(localPart)) {
+		return fmt.Errorf("invalid local part %q", localPart)
+	}
+	return nil
+}
+
+// Returns the verification, SPF, and DKIM records the domain needs, marked as
+// verified if they were found with lookupTXT (net.LookupTXT). If the domain
+// already has an SPF record, the include is added to it instead
+func CheckSendingDomain(sendingDomain models.OrganizationSendingDomain, lookupTXT func(name string) ([]string, error)) ([]DnsRecord, error) {
+	key, err := dkim.ParseKey(sendingDomain.DkimPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	dkimValue, err := dkim.GetRecordValue(key)
+	if err != nil {
+		return nil, err
+	}
+	verificationDomain := models.OrganizationDomain{Domain: sendingDomain.Domain, VerificationToken: sendingDomain.VerificationToken}
+	verificationName, verificationValue := GetVerificationRecord(verificationDomain)
+	spfInclude := "include:" + getSpfInclude()
+
+	records := []DnsRecord{
+		{Type: "TXT", Name: verificationName, Value: verificationValue, Verified: IsDomainVerified(verificationDomain, lookupTXT)},
+		{Type: "TXT", Name: sendingDomain.Domain, Value: fmt.Sprintf("v=spf1 %s ~all", spfInclude)},
+		{Type: "TXT", Name: sendingDomain.DkimSelector + "._domainkey." + sendingDomain.Domain, Value: dkimValue},
+	}
+	if values, err := lookupTXT(records[1].Name); err == nil {
+		for _, value := range values {
+			if strings.HasPrefix(value, "v=spf1") && utils.Contains(strings.Fields(value), spfInclude) {
+				records[1].Verified = true
+			}
+		}
+	}
+	if values, err := lookupTXT(records[2].Name); err == nil {
+		publicKey := dkimValue[strings.Index(dkimValue, "p="):]
+		for _, value := range values {
+			if strings.Contains(strings.Join(strings.Fields(value), ""), publicKey) {
+				records[2].Verified = true
+			}
+		}
+	}
+	return records, nil
+}
+
+// Returns whether all the records are verified
+func AreRecordsVerified(records []DnsRecord) bool {
+	for _, record := range records {
+		if !record.Verified {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the sender of emails about the organization's events, or nil if
+// they're sent from the default address because it has no verified sending
+// domain
+func GetEmailSender(org *models.Organization) *utils.EmailSender {
+	if org == nil || org.SendingDomain == nil || org.SendingDomain.VerifiedAt == nil {
+		return nil
+	}
+	key, err := dkim.ParseKey(org.SendingDomain.DkimPrivateKey)
+	if err != nil {
+		return nil
+	}
+	name := org.SendingDomain.FromName
+	if len(name) == 0 {
+		name = org.Name
+	}
+	return &utils.EmailSender{
+		Name:    name,
+		Address: org.SendingDomain.LocalPart + "@" + org.SendingDomain.Domain,
+		Signer:  &dkim.Signer{Domain: org.SendingDomain.Domain, Selector: org.SendingDomain.DkimSelector, Key: key},
+	}
+}
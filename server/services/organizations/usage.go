@@ -0,0 +1,106 @@
+package organizations
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+// Usage of an organization during a calendar month (in UTC)
+type MonthlyUsage struct {
+	Month              string `json:"month"` // e.g. "2026-03"
+	EventsCreated      int    `json:"eventsCreated"`
+	ResponsesCollected int    `json:"responsesCollected"`
+
+	// Members that used Timeful or created an event during the month
+	ActiveMembers int `json:"activeMembers"`
+
+	// Integration usage
+	SlackAccountsLinked int `json:"slackAccountsLinked"`
+	GoogleChatShares    int `json:"googleChatShares"`
+}
+
+// Everything that counts towards the usage of an organization
+type UsageRecords struct {
+	Events           []models.Event
+	Responses        []models.EventResponse
+	DailyUserLogs    []models.DailyUserLog
+	SlackAccounts    []models.SlackAccount
+	GoogleChatShares []models.GoogleChatShare
+}
+
+// Returns the start of the month numMonths-1 months before now's, so that the
+// usage report for numMonths months includes the current one
+func GetUsageStart(now time.Time, numMonths int) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month()-time.Month(numMonths-1), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Summarises the records by month, for the numMonths months starting at the
+// month of start. Only the given members count as active members
+func GetMonthlyUsage(records UsageRecords, members []models.OrganizationMember, start time.Time, numMonths int) []MonthlyUsage {
+	usage := make([]MonthlyUsage, numMonths)
+	indexes := make(map[string]int)
+	start = start.UTC()
+	for i := range usage {
+		month := time.Date(start.Year(), start.Month()+time.Month(i), 1, 0, 0, 0, 0, time.UTC).Format("2006-01")
+		usage[i].Month = month
+		indexes[month] = i
+	}
+	getUsage := func(t time.Time) *MonthlyUsage {
+		if i, ok := indexes[t.UTC().Format("2006-01")]; ok {
+			return &usage[i]
+		}
+		return nil
+	}
+
+	memberIds := make(models.Set[primitive.ObjectID])
+	for _, member := range members {
+		memberIds[member.UserId] = struct{}{}
+	}
+	activeMembers := make([]models.Set[primitive.ObjectID], numMonths)
+	for i := range activeMembers {
+		activeMembers[i] = make(models.Set[primitive.ObjectID])
+	}
+	setActive := func(t time.Time, userId primitive.ObjectID) {
+		if i, ok := indexes[t.UTC().Format("2006-01")]; ok {
+			if _, isMember := memberIds[userId]; isMember {
+				activeMembers[i][userId] = struct{}{}
+			}
+		}
+	}
+
+	for _, event := range records.Events {
+		if u := getUsage(event.Id.Timestamp()); u != nil {
+			u.EventsCreated++
+		}
+		setActive(event.Id.Timestamp(), event.OwnerId)
+	}
+	for _, response := range records.Responses {
+		if u := getUsage(response.Id.Timestamp()); u != nil {
+			u.ResponsesCollected++
+		}
+	}
+	for _, log := range records.DailyUserLogs {
+		for _, userId := range log.UserIds {
+			setActive(log.Date.Time(), userId)
+		}
+	}
+	for _, account := range records.SlackAccounts {
+		if u := getUsage(account.LinkedAt.Time()); u != nil {
+			u.SlackAccountsLinked++
+		}
+	}
+	for _, share := range records.GoogleChatShares {
+		if u := getUsage(share.SharedAt.Time()); u != nil {
+			u.GoogleChatShares++
+		}
+	}
+
+	for i := range usage {
+		usage[i].ActiveMembers = len(activeMembers[i])
+	}
+
+	return usage
+}
@@ -0,0 +1,49 @@
+package organizations
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func TestGetMonthlyUsage(t *testing.T) {
+	member, outsider := primitive.NewObjectID(), primitive.NewObjectID()
+	members := []models.OrganizationMember{{UserId: member, Role: models.ORG_MEMBER}}
+	jan := time.Date(2026, time.January, 15, 12, 0, 0, 0, time.UTC)
+	feb := time.Date(2026, time.February, 3, 12, 0, 0, 0, time.UTC)
+	dec := time.Date(2025, time.December, 31, 12, 0, 0, 0, time.UTC)
+
+	records := UsageRecords{
+		Events: []models.Event{
+			{Id: primitive.NewObjectIDFromTimestamp(jan), OwnerId: member},
+			{Id: primitive.NewObjectIDFromTimestamp(feb), OwnerId: outsider},
+			{Id: primitive.NewObjectIDFromTimestamp(dec), OwnerId: member},
+		},
+		Responses: []models.EventResponse{
+			{Id: primitive.NewObjectIDFromTimestamp(feb)},
+			{Id: primitive.NewObjectIDFromTimestamp(feb)},
+		},
+		DailyUserLogs: []models.DailyUserLog{
+			{Date: primitive.NewDateTimeFromTime(feb), UserIds: []primitive.ObjectID{member, outsider}},
+		},
+		SlackAccounts:    []models.SlackAccount{{LinkedAt: primitive.NewDateTimeFromTime(jan)}},
+		GoogleChatShares: []models.GoogleChatShare{{SharedAt: primitive.NewDateTimeFromTime(feb)}},
+	}
+
+	start := GetUsageStart(feb, 2)
+	usage := GetMonthlyUsage(records, members, start, 2)
+	expected := []MonthlyUsage{
+		{Month: "2026-01", EventsCreated: 1, ActiveMembers: 1, SlackAccountsLinked: 1},
+		{Month: "2026-02", EventsCreated: 1, ResponsesCollected: 2, ActiveMembers: 1, GoogleChatShares: 1},
+	}
+	if len(usage) != len(expected) {
+		t.Fatalf("got %d months, expected %d", len(usage), len(expected))
+	}
+	for i := range expected {
+		if usage[i] != expected[i] {
+			t.Errorf("got %+v, expected %+v", usage[i], expected[i])
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Finds slots where a panel can meet, i.e. a given number of people (or
+// resources such as rooms) from each of several pools are all free
+package panel
+
+import (
+	"sort"
+	"time"
+
+	"schej.it/server/models"
+	"schej.it/server/services/flow"
+	"schej.it/server/services/scheduling"
+)
+
+// Cost of picking a member that is only available if needed
+const ifNeededCost = 1
+
+// A pool of interchangeable members, of which Count are needed
+type Pool struct {
+	Name    string
+	Count   int
+	Members []scheduling.Respondent
+}
+
+// A slot where the panel can meet
+type Slot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// Mapping from pool name to the ids of the members picked from the pool
+	Assignments map[string][]string `json:"assignments"`
+
+	// Number of picked members that are only available if needed
+	NumIfNeeded int `json:"numIfNeeded"`
+}
+
+// Returns the slots of the given length on the event's grid where every pool
+// can be filled, with members that are available preferred over members that
+// are available if needed. A member that is part of multiple pools is only
+// ever picked once per slot. Slots are sorted by the number of "if needed"
+// members, then by start time
+func FindSlots(event *models.Event, pools []Pool, meetingLength time.Duration, exclude func(start time.Time, end time.Time) bool) []Slot {
+	increment := scheduling.GetTimeIncrement(event)
+	slots := make([]Slot, 0)
+	for _, candidate := range scheduling.GetCandidateSlots(event, meetingLength) {
+		start := candidate[0]
+		end := candidate[len(candidate)-1].Add(increment)
+		if exclude != nil && exclude(start, end) {
+			continue
+		}
+		if slot, ok := assignSlot(pools, start, end, increment); ok {
+			slots = append(slots, slot)
+		}
+	}
+
+	sort.SliceStable(slots, func(i, j int) bool {
+		return slots[i].NumIfNeeded < slots[j].NumIfNeeded
+	})
+	return slots
+}
+
+// Picks members from each pool for the given time range using a min cost
+// flow: source -> pools -> members -> sink
+func assignSlot(pools []Pool, start time.Time, end time.Time, increment time.Duration) (Slot, bool) {
+	// Assign a node to every distinct member
+	memberNodes := make(map[string]int)
+	memberIds := make([]string, 0)
+	for _, pool := range pools {
+		for _, member := range pool.Members {
+			if _, ok := memberNodes[member.Id]; !ok {
+				memberNodes[member.Id] = 2 + len(pools) + len(memberIds)
+				memberIds = append(memberIds, member.Id)
+			}
+		}
+	}
+
+	source, sink := 0, 1
+	graph := flow.NewGraph(2 + len(pools) + len(memberIds))
+	needed := 0
+	poolEdges := make([][]int, len(pools))
+	for i, pool := range pools {
+		needed += pool.Count
+		graph.AddEdge(source, 2+i, pool.Count, 0)
+		for j := range pool.Members {
+			member := &pool.Members[j]
+			if member.IsAvailable(start, end, increment, false) {
+				poolEdges[i] = append(poolEdges[i], graph.AddEdge(2+i, memberNodes[member.Id], 1, 0))
+			} else if member.IsAvailable(start, end, increment, true) {
+				poolEdges[i] = append(poolEdges[i], graph.AddEdge(2+i, memberNodes[member.Id], 1, ifNeededCost))
+			}
+		}
+	}
+	for _, node := range memberNodes {
+		graph.AddEdge(node, sink, 1, 0)
+	}
+	graph.MinCostMaxFlow(source, sink)
+
+	slot := Slot{Start: start, End: end, Assignments: make(map[string][]string)}
+	assigned := 0
+	for i, pool := range pools {
+		slot.Assignments[pool.Name] = make([]string, 0)
+		for _, edgeId := range poolEdges[i] {
+			if graph.Flow(edgeId) == 0 {
+				continue
+			}
+			memberId := memberIds[graph.To(edgeId)-2-len(pools)]
+			slot.Assignments[pool.Name] = append(slot.Assignments[pool.Name], memberId)
+			for _, member := range pool.Members {
+				if member.Id == memberId && !member.IsAvailable(start, end, increment, false) {
+					slot.NumIfNeeded++
+				}
+			}
+			assigned++
+		}
+	}
+
+	return slot, assigned == needed
+}
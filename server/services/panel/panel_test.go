@@ -0,0 +1,60 @@
+package panel
+
+import (
+	"testing"
+	"time"
+
+	"schej.it/server/services/scheduling"
+	"schej.it/server/services/scheduling/schedulingtest"
+)
+
+func TestFindSlots(t *testing.T) {
+	pools := []Pool{
+		{Name: "engineers", Count: 1, Members: []scheduling.Respondent{schedulingtest.NewRespondent("a", 9, 10), schedulingtest.NewRespondent("b", 12)}},
+		{Name: "managers", Count: 1, Members: []scheduling.Respondent{schedulingtest.NewRespondent("c", 10, 12)}},
+		{Name: "rooms", Count: 1, Members: []scheduling.Respondent{schedulingtest.NewRespondent("room", 9, 10, 11, 12)}},
+	}
+
+	slots := FindSlots(schedulingtest.NewEvent(), pools, time.Hour, nil)
+	if len(slots) != 2 {
+		t.Fatalf("got %d slots, want 2", len(slots))
+	}
+	if !slots[0].Start.Equal(schedulingtest.Hour(10)) || slots[0].Assignments["engineers"][0] != "a" {
+		t.Errorf("got first slot %v with %v", slots[0].Start, slots[0].Assignments)
+	}
+	if !slots[1].Start.Equal(schedulingtest.Hour(12)) || slots[1].Assignments["engineers"][0] != "b" {
+		t.Errorf("got second slot %v with %v", slots[1].Start, slots[1].Assignments)
+	}
+}
+
+func TestFindSlotsSharedMember(t *testing.T) {
+	// a is in both pools but can only fill one of them
+	a := schedulingtest.NewRespondent("a", 9)
+	pools := []Pool{
+		{Name: "first", Count: 1, Members: []scheduling.Respondent{a}},
+		{Name: "second", Count: 1, Members: []scheduling.Respondent{a, schedulingtest.NewRespondent("b", 10)}},
+	}
+	if slots := FindSlots(schedulingtest.NewEvent(), pools, time.Hour, nil); len(slots) != 0 {
+		t.Errorf("got %d slots, want 0", len(slots))
+	}
+}
+
+func TestFindSlotsPrefersAvailable(t *testing.T) {
+	b := schedulingtest.NewRespondent("b")
+	b.IfNeeded[schedulingtest.Hour(9).UnixMilli()] = struct{}{}
+	b.IfNeeded[schedulingtest.Hour(10).UnixMilli()] = struct{}{}
+	pools := []Pool{
+		{Name: "interviewers", Count: 1, Members: []scheduling.Respondent{b, schedulingtest.NewRespondent("a", 10)}},
+	}
+
+	slots := FindSlots(schedulingtest.NewEvent(), pools, time.Hour, nil)
+	if len(slots) != 2 {
+		t.Fatalf("got %d slots, want 2", len(slots))
+	}
+	if !slots[0].Start.Equal(schedulingtest.Hour(10)) || slots[0].Assignments["interviewers"][0] != "a" || slots[0].NumIfNeeded != 0 {
+		t.Errorf("got first slot %v with %v", slots[0].Start, slots[0].Assignments)
+	}
+	if slots[1].NumIfNeeded != 1 {
+		t.Errorf("got %d if needed, want 1", slots[1].NumIfNeeded)
+	}
+}
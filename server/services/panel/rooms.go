@@ -0,0 +1,54 @@
+package panel
+
+import (
+	"time"
+
+	"schej.it/server/models"
+	"schej.it/server/services/calendar"
+	"schej.it/server/services/scheduling"
+)
+
+// Returns the availability of the given rooms on the event's grid. Rooms are
+// calendars on the user's connected calendar accounts, and are available
+// whenever they have no busy event between timeMin and timeMax
+func GetRoomAvailability(user *models.User, event *models.Event, rooms []models.PanelRoom, timeMin time.Time, timeMax time.Time) []scheduling.Respondent {
+	increment := scheduling.GetTimeIncrement(event)
+	increments := scheduling.GetTimeIncrements(event)
+
+	accounts := make(models.Set[string])
+	members := make([]scheduling.Respondent, 0)
+	for _, room := range rooms {
+		accounts[room.CalendarAccountKey] = struct{}{}
+		member := scheduling.Respondent{
+			Id:        room.CalendarId,
+			Name:      room.Name,
+			Available: make(models.Set[int64]),
+			IfNeeded:  make(models.Set[int64]),
+		}
+		for _, t := range increments {
+			member.Available[t.UnixMilli()] = struct{}{}
+		}
+		members = append(members, member)
+	}
+	if len(rooms) == 0 {
+		return members
+	}
+
+	calendarEvents, _ := calendar.GetUsersCalendarEvents(user, accounts, timeMin, timeMax)
+	for i, room := range rooms {
+		events, ok := calendarEvents[room.CalendarAccountKey]
+		if !ok || events.Error != nil {
+			// Rooms whose calendar can't be read are never available
+			members[i].Available = make(models.Set[int64])
+			continue
+		}
+		for _, calendarEvent := range events.CalendarEvents {
+			if calendarEvent.CalendarId != room.CalendarId || calendarEvent.Free {
+				continue
+			}
+			members[i].RemoveAvailability(calendarEvent.StartDate.Time(), calendarEvent.EndDate.Time(), increment)
+		}
+	}
+
+	return members
+}
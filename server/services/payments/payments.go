@@ -0,0 +1,252 @@
+// Collects payments for sign up slots with Stripe Checkout, on behalf of the
+// organizer's Stripe Connect account
+package payments
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stripe/stripe-go/v82"
+	"github.com/stripe/stripe-go/v82/checkout/session"
+	"github.com/stripe/stripe-go/v82/paymentintent"
+	"github.com/stripe/stripe-go/v82/refund"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+// Currency used when the event doesn't specify one
+const defaultCurrency = "usd"
+
+// Returns the currency of the event's payments
+func GetCurrency(event *models.Event) string {
+	if event.PaymentCurrency != nil && len(*event.PaymentCurrency) > 0 {
+		return strings.ToLower(*event.PaymentCurrency)
+	}
+	return defaultCurrency
+}
+
+// Returns the total price of the given sign up blocks of the event
+func GetPrice(event *models.Event, signUpBlockIds []primitive.ObjectID) int64 {
+	ids := utils.ArrayToSet(signUpBlockIds)
+	var total int64
+	for _, block := range utils.Coalesce(event.SignUpBlocks) {
+		if _, ok := ids[block.Id]; ok {
+			total += utils.Coalesce(block.Price)
+		}
+	}
+	return total
+}
+
+// Returns the ids of the given sign up blocks that require payment and aren't
+// covered by one of the paid payments
+func GetUnpaidBlocks(event *models.Event, signUpBlockIds []primitive.ObjectID, paid []models.Payment) []primitive.ObjectID {
+	paidIds := make(models.Set[primitive.ObjectID])
+	for _, payment := range paid {
+		for _, id := range payment.SignUpBlockIds {
+			paidIds[id] = struct{}{}
+		}
+	}
+
+	unpaid := make([]primitive.ObjectID, 0)
+	for _, id := range signUpBlockIds {
+		if _, ok := paidIds[id]; ok {
+			continue
+		}
+		if GetPrice(event, []primitive.ObjectID{id}) > 0 {
+			unpaid = append(unpaid, id)
+		}
+	}
+	return unpaid
+}
+
+// Returns the ids of the given sign up blocks that are already signed up for
+// by as many other respondents as they have room for
+func GetFullBlocks(event *models.Event, signUpBlockIds []primitive.ObjectID, userId string) []primitive.ObjectID {
+	counts := make(map[primitive.ObjectID]int)
+	for respondentId, response := range event.SignUpResponses {
+		if respondentId == userId || response == nil {
+			continue
+		}
+		for _, id := range response.SignUpBlockIds {
+			counts[id]++
+		}
+	}
+
+	ids := utils.ArrayToSet(signUpBlockIds)
+	full := make([]primitive.ObjectID, 0)
+	for _, block := range utils.Coalesce(event.SignUpBlocks) {
+		if _, ok := ids[block.Id]; ok && block.Capacity != nil && counts[block.Id] >= *block.Capacity {
+			full = append(full, block.Id)
+		}
+	}
+	return full
+}
+
+// Returns the platform fee taken from each payment, as a percentage of the
+// amount (STRIPE_BOOKING_FEE_PERCENT) plus a fixed amount in the smallest unit
+// of the currency (STRIPE_BOOKING_FEE_FIXED). Both default to 0
+func GetPlatformFee() (float64, int64) {
+	percent, err := strconv.ParseFloat(os.Getenv("STRIPE_BOOKING_FEE_PERCENT"), 64)
+	if err != nil || percent < 0 {
+		percent = 0
+	}
+	fixed, err := strconv.ParseInt(os.Getenv("STRIPE_BOOKING_FEE_FIXED"), 10, 64)
+	if err != nil || fixed < 0 {
+		fixed = 0
+	}
+	return percent, fixed
+}
+
+// Returns the platform fee for the given amount, which is never more than the
+// amount itself
+func GetApplicationFee(amount int64) int64 {
+	percent, fixed := GetPlatformFee()
+	fee := int64(float64(amount)*percent/100) + fixed
+	if fee > amount {
+		fee = amount
+	}
+	return fee
+}
+
+// Creates a Checkout Session for the payment, transferring the funds minus
+// the platform fee to the owner's connected account
+func CreateCheckoutSession(event *models.Event, owner *models.User, payment *models.Payment, successUrl string, cancelUrl string) (*stripe.CheckoutSession, error) {
+	if owner.StripeAccountId == nil || !utils.Coalesce(owner.StripeAccountEnabled) {
+		return nil, fmt.Errorf("owner can't accept payments")
+	}
+
+	params := &stripe.CheckoutSessionParams{
+		Mode:              stripe.String(string(stripe.CheckoutSessionModePayment)),
+		ClientReferenceID: stripe.String(payment.UserId),
+		LineItems: []*stripe.CheckoutSessionLineItemParams{
+			{
+				PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+					Currency:   stripe.String(payment.Currency),
+					UnitAmount: stripe.Int64(payment.Amount),
+					ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
+						Name: stripe.String(event.Name),
+					},
+				},
+				Quantity: stripe.Int64(1),
+			},
+		},
+		PaymentIntentData: &stripe.CheckoutSessionPaymentIntentDataParams{
+			TransferData: &stripe.CheckoutSessionPaymentIntentDataTransferDataParams{
+				Destination: owner.StripeAccountId,
+			},
+		},
+		Metadata:   map[string]string{"paymentId": payment.Id.Hex()},
+		SuccessURL: stripe.String(successUrl),
+		CancelURL:  stripe.String(cancelUrl),
+	}
+	if payment.ApplicationFee > 0 {
+		params.PaymentIntentData.ApplicationFeeAmount = stripe.Int64(payment.ApplicationFee)
+	}
+	if len(payment.Email) > 0 {
+		params.CustomerEmail = stripe.String(payment.Email)
+	}
+	if event.BookingPolicy != nil && event.BookingPolicy.NoShowFee > 0 {
+		// Save the payment method so no-show fees can be charged later
+		params.CustomerCreation = stripe.String(string(stripe.CheckoutSessionCustomerCreationAlways))
+		params.PaymentIntentData.SetupFutureUsage = stripe.String(string(stripe.PaymentIntentSetupFutureUsageOffSession))
+	}
+
+	return session.New(params)
+}
+
+// Returns the start of the earliest of the payment's sign up blocks, or nil
+// if none of them has a start date
+func getFirstBlockStart(event *models.Event, payment *models.Payment) *time.Time {
+	ids := utils.ArrayToSet(payment.SignUpBlockIds)
+	var first *time.Time
+	for _, block := range utils.Coalesce(event.SignUpBlocks) {
+		if _, ok := ids[block.Id]; !ok || block.StartDate == nil {
+			continue
+		}
+		start := block.StartDate.Time()
+		if first == nil || start.Before(*first) {
+			first = &start
+		}
+	}
+	return first
+}
+
+// Returns how much of the payment to refund when the respondent's sign up is
+// cancelled at the given time, following the event's booking policy.
+// Cancellations by the organizer are always fully refunded
+func GetRefundAmount(event *models.Event, payment *models.Payment, now time.Time, byOwner bool) int64 {
+	remaining := payment.Amount - payment.RefundedAmount
+	policy := event.BookingPolicy
+	if byOwner || policy == nil {
+		return remaining
+	}
+
+	first := getFirstBlockStart(event, payment)
+	if first == nil || first.Sub(now) >= time.Duration(policy.CancellationWindowHours)*time.Hour {
+		return remaining
+	}
+	refund := payment.Amount * int64(policy.LateCancellationRefundPercent) / 100
+	if refund > remaining {
+		refund = remaining
+	}
+	return refund
+}
+
+// Returns whether the first of the payment's sign up blocks has started, i.e.
+// whether the respondent can be marked as a no-show
+func HasStarted(event *models.Event, payment *models.Payment, now time.Time) bool {
+	first := getFirstBlockStart(event, payment)
+	return first != nil && !now.Before(*first)
+}
+
+// Refunds the given amount of the payment, pulling the funds back from the
+// owner's connected account and refunding the platform fee proportionally
+func Refund(payment *models.Payment, amount int64) error {
+	if len(payment.PaymentIntentId) == 0 {
+		return fmt.Errorf("payment %s has no payment intent", payment.Id.Hex())
+	}
+	_, err := refund.New(&stripe.RefundParams{
+		PaymentIntent:        stripe.String(payment.PaymentIntentId),
+		Amount:               stripe.Int64(amount),
+		ReverseTransfer:      stripe.Bool(true),
+		RefundApplicationFee: stripe.Bool(true),
+	})
+	return err
+}
+
+// Charges the no-show fee to the payment method saved when paying for the
+// booking, transferring it to the owner's connected account
+func ChargeNoShowFee(owner *models.User, booking *models.Payment, fee *models.Payment) (*stripe.PaymentIntent, error) {
+	if len(booking.CustomerId) == 0 || len(booking.PaymentMethodId) == 0 {
+		return nil, fmt.Errorf("payment %s has no saved payment method", booking.Id.Hex())
+	}
+	if owner.StripeAccountId == nil {
+		return nil, fmt.Errorf("owner can't accept payments")
+	}
+
+	params := &stripe.PaymentIntentParams{
+		Amount:        stripe.Int64(fee.Amount),
+		Currency:      stripe.String(fee.Currency),
+		Customer:      stripe.String(booking.CustomerId),
+		PaymentMethod: stripe.String(booking.PaymentMethodId),
+		OffSession:    stripe.Bool(true),
+		Confirm:       stripe.Bool(true),
+		TransferData: &stripe.PaymentIntentTransferDataParams{
+			Destination: owner.StripeAccountId,
+		},
+		Metadata: map[string]string{"paymentId": fee.Id.Hex()},
+	}
+	if fee.ApplicationFee > 0 {
+		params.ApplicationFeeAmount = stripe.Int64(fee.ApplicationFee)
+	}
+	return paymentintent.New(params)
+}
+
+// Formats an amount in the smallest unit of the currency, e.g. "12.50 USD"
+func FormatAmount(amount int64, currency string) string {
+	return fmt.Sprintf("%d.%02d %s", amount/100, amount%100, strings.ToUpper(currency))
+}
@@ -0,0 +1,118 @@
+package payments
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func newEvent(prices ...int64) (*models.Event, []primitive.ObjectID) {
+	blocks := make([]models.SignUpBlock, 0)
+	ids := make([]primitive.ObjectID, 0)
+	for _, price := range prices {
+		price := price
+		block := models.SignUpBlock{Id: primitive.NewObjectID()}
+		if price > 0 {
+			block.Price = &price
+		}
+		blocks = append(blocks, block)
+		ids = append(ids, block.Id)
+	}
+	return &models.Event{SignUpBlocks: &blocks}, ids
+}
+
+func TestGetPrice(t *testing.T) {
+	event, ids := newEvent(2500, 0, 1000)
+	if got := GetPrice(event, ids); got != 3500 {
+		t.Errorf("got %d, want 3500", got)
+	}
+	if got := GetPrice(event, ids[1:2]); got != 0 {
+		t.Errorf("got %d, want 0", got)
+	}
+}
+
+func TestGetUnpaidBlocks(t *testing.T) {
+	event, ids := newEvent(2500, 0, 1000)
+	paid := []models.Payment{{SignUpBlockIds: ids[:1]}}
+
+	unpaid := GetUnpaidBlocks(event, ids, paid)
+	if len(unpaid) != 1 || unpaid[0] != ids[2] {
+		t.Errorf("got %v, want [%v]", unpaid, ids[2])
+	}
+}
+
+func TestGetFullBlocks(t *testing.T) {
+	event, ids := newEvent(2500, 1000, 0)
+	one, two := 1, 2
+	(*event.SignUpBlocks)[0].Capacity = &one
+	(*event.SignUpBlocks)[1].Capacity = &two
+	event.SignUpResponses = map[string]*models.SignUpResponse{
+		"Alice": {SignUpBlockIds: ids[:2]},
+		"Bob":   {SignUpBlockIds: ids[2:]},
+	}
+
+	full := GetFullBlocks(event, ids, "Carol")
+	if len(full) != 1 || full[0] != ids[0] {
+		t.Errorf("got %v, want only the first block", full)
+	}
+
+	// The respondent's own sign up doesn't take up room
+	if full := GetFullBlocks(event, ids, "Alice"); len(full) != 0 {
+		t.Errorf("got %v, want no blocks", full)
+	}
+}
+
+func TestGetApplicationFee(t *testing.T) {
+	t.Setenv("STRIPE_BOOKING_FEE_PERCENT", "2.5")
+	if got := GetApplicationFee(10000); got != 250 {
+		t.Errorf("got %d, want 250", got)
+	}
+	t.Setenv("STRIPE_BOOKING_FEE_FIXED", "30")
+	if got := GetApplicationFee(10000); got != 280 {
+		t.Errorf("got %d, want 280", got)
+	}
+	if got := GetApplicationFee(20); got != 20 {
+		t.Errorf("got %d, want 20", got)
+	}
+	t.Setenv("STRIPE_BOOKING_FEE_PERCENT", "")
+	t.Setenv("STRIPE_BOOKING_FEE_FIXED", "")
+	if got := GetApplicationFee(10000); got != 0 {
+		t.Errorf("got %d, want 0", got)
+	}
+}
+
+func TestGetRefundAmount(t *testing.T) {
+	now := time.Date(2024, time.May, 14, 9, 0, 0, 0, time.UTC)
+	event, ids := newEvent(2000)
+	start := primitive.NewDateTimeFromTime(now.Add(12 * time.Hour))
+	(*event.SignUpBlocks)[0].StartDate = &start
+	payment := &models.Payment{SignUpBlockIds: ids, Amount: 2000}
+
+	if got := GetRefundAmount(event, payment, now, false); got != 2000 {
+		t.Errorf("without a policy got %d, want 2000", got)
+	}
+
+	event.BookingPolicy = &models.BookingPolicy{CancellationWindowHours: 24, LateCancellationRefundPercent: 50}
+	if got := GetRefundAmount(event, payment, now, false); got != 1000 {
+		t.Errorf("late cancellation got %d, want 1000", got)
+	}
+	if got := GetRefundAmount(event, payment, now.Add(-12*time.Hour), false); got != 2000 {
+		t.Errorf("early cancellation got %d, want 2000", got)
+	}
+	if got := GetRefundAmount(event, payment, now, true); got != 2000 {
+		t.Errorf("cancellation by owner got %d, want 2000", got)
+	}
+
+	payment.RefundedAmount = 1500
+	if got := GetRefundAmount(event, payment, now, false); got != 500 {
+		t.Errorf("partially refunded got %d, want 500", got)
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	if got := FormatAmount(1250, "usd"); got != "12.50 USD" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,214 @@
+// Two phase polls, where a days only poll narrows down which days work and a
+// time grid poll is then created for the winning days, inviting everyone from
+// the first poll
+package phases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services/notifications"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// Maximum number of days that can be carried forward
+const MAX_DAYS = 14
+
+var ErrAlreadyAdvanced = errors.New("the next phase was already created")
+var ErrNoWinningDays = errors.New("no respondent is available on any of the days")
+
+// Returns an error if the next phase settings aren't valid for the event
+func Validate(event *models.Event, nextPhase *models.NextPhase) error {
+	if !utils.Coalesce(event.DaysOnly) || event.Type == models.GROUP {
+		return fmt.Errorf("only days only polls can have a next phase")
+	}
+	if event.NextPhase != nil && event.NextPhase.EventId != nil {
+		return ErrAlreadyAdvanced
+	}
+	if nextPhase.NumDays < 1 || nextPhase.NumDays > MAX_DAYS {
+		return fmt.Errorf("numDays must be between 1 and %d", MAX_DAYS)
+	}
+	if nextPhase.TimeIncrement < 0 || nextPhase.TimeIncrement > 60 {
+		return fmt.Errorf("timeIncrement must be at most 60 minutes")
+	}
+	start, end, err := parseHours(nextPhase.Hours)
+	if err != nil {
+		return err
+	}
+	if end <= start {
+		return fmt.Errorf("hours must end after they start")
+	}
+	return nil
+}
+
+// Returns the numDays days the most respondents can make, in chronological
+// order. Days nobody can make aren't returned
+func GetWinningDays(event *models.Event, respondents []scheduling.Respondent, numDays int) []time.Time {
+	days := make([]time.Time, 0)
+	for _, slot := range scheduling.RankSlots(event, respondents, scheduling.Options{}) {
+		if len(days) >= numDays || slot.Score == 0 {
+			break
+		}
+		days = append(days, slot.Start)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days
+}
+
+// Returns the time grid poll for the given days of the days only poll, which
+// is linked to it. Days only dates are at midnight UTC, and the hours of the
+// next phase are in loc
+func NewNextPhaseEvent(event *models.Event, days []time.Time, loc *time.Location) models.Event {
+	start, end, _ := parseHours(event.NextPhase.Hours)
+
+	dates := make([]primitive.DateTime, 0)
+	for _, day := range days {
+		day = day.UTC()
+		date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc).Add(start)
+		dates = append(dates, primitive.NewDateTimeFromTime(date))
+	}
+	duration := float32((end - start).Hours())
+
+	next := models.Event{
+		Id:                       primitive.NewObjectID(),
+		OwnerId:                  event.OwnerId,
+		OrganizationId:           event.OrganizationId,
+		Name:                     event.Name,
+		Description:              event.Description,
+		Type:                     models.SPECIFIC_DATES,
+		Dates:                    dates,
+		Duration:                 &duration,
+		Timezone:                 event.Timezone,
+		Tags:                     event.Tags,
+		InviteOnly:               event.InviteOnly,
+		AuthorizedEmails:         event.AuthorizedEmails,
+		CoOrganizers:             event.CoOrganizers,
+		NotificationsEnabled:     event.NotificationsEnabled,
+		BlindAvailabilityEnabled: event.BlindAvailabilityEnabled,
+		PrivacyLevel:             event.PrivacyLevel,
+		ReminderCadence:          event.ReminderCadence,
+		Branding:                 event.Branding,
+		RespondentFields:         event.RespondentFields,
+		PreviousPhaseEventId:     &event.Id,
+	}
+	if event.NextPhase.TimeIncrement > 0 {
+		timeIncrement := event.NextPhase.TimeIncrement
+		next.TimeIncrement = &timeIncrement
+	}
+	return next
+}
+
+// Returns the emails of the remindees and respondents of the event, who are
+// invited to the next phase
+func GetInvitees(event *models.Event, respondents []scheduling.Respondent) []string {
+	emails := make([]string, 0)
+	seen := make(models.Set[string])
+	add := func(email string) {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if _, ok := seen[email]; ok || len(email) == 0 {
+			return
+		}
+		seen[email] = struct{}{}
+		emails = append(emails, email)
+	}
+	for _, remindee := range utils.Coalesce(event.Remindees) {
+		add(remindee.Email)
+	}
+	for _, respondent := range respondents {
+		add(respondent.Email)
+	}
+	return emails
+}
+
+// Creates the time grid poll for the winning days of the event, links the two
+// polls, and invites the remindees and respondents of the event to it
+func Advance(event *models.Event, now time.Time) (*models.Event, error) {
+	respondents := scheduling.GetRespondents(db.GetEventResponses(event.Id.Hex()))
+	days := GetWinningDays(event, respondents, event.NextPhase.NumDays)
+	if len(days) == 0 {
+		return nil, ErrNoWinningDays
+	}
+	owner := db.GetUserById(event.OwnerId.Hex())
+	if owner == nil {
+		return nil, fmt.Errorf("owner of event %s not found", event.Id.Hex())
+	}
+	next := NewNextPhaseEvent(event, days, utils.GetEventLocation(event, owner))
+
+	// Claim the next phase, so that it's only created once
+	result, err := db.EventsCollection.UpdateOne(context.Background(), bson.M{
+		"_id":               event.Id,
+		"nextPhase.eventId": bson.M{"$exists": false},
+	}, bson.M{
+		"$set": bson.M{"nextPhase.eventId": next.Id},
+	})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrAlreadyAdvanced
+	}
+	event.NextPhase.EventId = &next.Id
+
+	// Remindees are added after inserting, since reminders link to the short id
+	db.InsertEvent(&next)
+	invitees := make([]string, 0)
+	for _, email := range GetInvitees(event, respondents) {
+		if email != strings.ToLower(owner.Email) {
+			invitees = append(invitees, email)
+		}
+	}
+	if len(invitees) > 0 {
+		notifications.UpdateRemindees(&next, invitees, owner.FirstName)
+		_, err = db.EventsCollection.UpdateByID(context.Background(), next.Id, bson.M{
+			"$set": bson.M{"remindees": next.Remindees},
+		})
+		if err != nil {
+			logger.StdErr.Panicln(err)
+		}
+	}
+
+	return &next, nil
+}
+
+// Creates the next phase of the days only polls whose next phase is due. Run
+// periodically by the jobs scheduler
+func AdvanceDue(now time.Time) {
+	for _, event := range db.GetEventsDueForNextPhase(now) {
+		event := event
+		if _, err := Advance(&event, now); err != nil {
+			if errors.Is(err, ErrNoWinningDays) {
+				// Leave it to the organizer to advance once someone responds
+				db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+					"$unset": bson.M{"nextPhase.advanceAt": ""},
+				})
+			}
+			if !errors.Is(err, ErrAlreadyAdvanced) {
+				logger.StdErr.Printf("Failed to advance event %s to its next phase: %v\n", event.Id.Hex(), err)
+			}
+		}
+	}
+}
+
+// Returns the start and end of the hours as offsets from midnight
+func parseHours(hours models.WorkingHours) (time.Duration, time.Duration, error) {
+	start, err := time.Parse("15:04", hours.StartTime)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid start time")
+	}
+	end, err := time.Parse("15:04", hours.EndTime)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid end time")
+	}
+	midnight := time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)
+	return start.Sub(midnight), end.Sub(midnight), nil
+}
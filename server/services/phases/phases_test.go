@@ -0,0 +1,107 @@
+package phases
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+)
+
+func newDaysOnlyEvent(days ...time.Time) *models.Event {
+	daysOnly := true
+	dates := make([]primitive.DateTime, 0)
+	for _, day := range days {
+		dates = append(dates, primitive.NewDateTimeFromTime(day))
+	}
+	return &models.Event{
+		Id:       primitive.NewObjectID(),
+		Type:     models.SPECIFIC_DATES,
+		DaysOnly: &daysOnly,
+		Dates:    dates,
+		NextPhase: &models.NextPhase{
+			NumDays:       2,
+			Hours:         models.WorkingHours{StartTime: "09:00", EndTime: "12:30"},
+			TimeIncrement: 30,
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	event := newDaysOnlyEvent(time.Date(2024, time.May, 14, 0, 0, 0, 0, time.UTC))
+	if err := Validate(event, event.NextPhase); err != nil {
+		t.Errorf("got %v", err)
+	}
+
+	invalid := []models.NextPhase{
+		{NumDays: 0, Hours: models.WorkingHours{StartTime: "09:00", EndTime: "17:00"}},
+		{NumDays: MAX_DAYS + 1, Hours: models.WorkingHours{StartTime: "09:00", EndTime: "17:00"}},
+		{NumDays: 1, Hours: models.WorkingHours{StartTime: "17:00", EndTime: "09:00"}},
+		{NumDays: 1, Hours: models.WorkingHours{StartTime: "9am", EndTime: "17:00"}},
+	}
+	for _, nextPhase := range invalid {
+		if err := Validate(event, &nextPhase); err == nil {
+			t.Errorf("%+v: expected an error", nextPhase)
+		}
+	}
+
+	timeGrid := *event
+	timeGrid.DaysOnly = nil
+	if err := Validate(&timeGrid, event.NextPhase); err == nil {
+		t.Errorf("expected an error for a time grid poll")
+	}
+}
+
+func TestGetWinningDays(t *testing.T) {
+	monday := time.Date(2024, time.May, 13, 0, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+	wednesday := monday.AddDate(0, 0, 2)
+	thursday := monday.AddDate(0, 0, 3)
+	event := newDaysOnlyEvent(monday, tuesday, wednesday, thursday)
+
+	respondents := []scheduling.Respondent{
+		{Id: "a", Available: models.Set[int64]{wednesday.UnixMilli(): {}, monday.UnixMilli(): {}}},
+		{Id: "b", Available: models.Set[int64]{wednesday.UnixMilli(): {}, monday.UnixMilli(): {}}},
+		{Id: "c", Available: models.Set[int64]{wednesday.UnixMilli(): {}}, IfNeeded: models.Set[int64]{tuesday.UnixMilli(): {}}},
+	}
+
+	// The best days, in chronological order
+	days := GetWinningDays(event, respondents, 2)
+	if len(days) != 2 || !days[0].Equal(monday) || !days[1].Equal(wednesday) {
+		t.Errorf("got %v", days)
+	}
+
+	// Days nobody can make aren't returned
+	if days := GetWinningDays(event, respondents, 4); len(days) != 3 {
+		t.Errorf("got %v", days)
+	}
+}
+
+func TestNewNextPhaseEvent(t *testing.T) {
+	day := time.Date(2024, time.May, 14, 0, 0, 0, 0, time.UTC)
+	event := newDaysOnlyEvent(day)
+	loc, _ := time.LoadLocation("America/New_York")
+
+	next := NewNextPhaseEvent(event, []time.Time{day}, loc)
+	if next.PreviousPhaseEventId == nil || *next.PreviousPhaseEventId != event.Id {
+		t.Errorf("next phase isn't linked to the event")
+	}
+	if len(next.Dates) != 1 || !next.Dates[0].Time().Equal(time.Date(2024, time.May, 14, 9, 0, 0, 0, loc)) {
+		t.Errorf("got dates %v", next.Dates)
+	}
+	if *next.Duration != 3.5 || *next.TimeIncrement != 30 || next.DaysOnly != nil {
+		t.Errorf("got duration %v, time increment %v", *next.Duration, *next.TimeIncrement)
+	}
+}
+
+func TestGetInvitees(t *testing.T) {
+	event := newDaysOnlyEvent()
+	event.Remindees = &[]models.Remindee{{Email: "a@example.com"}, {Email: "B@example.com"}}
+	respondents := []scheduling.Respondent{{Email: "b@example.com"}, {Email: "c@example.com"}, {}}
+
+	invitees := GetInvitees(event, respondents)
+	if len(invitees) != 3 || invitees[0] != "a@example.com" || invitees[1] != "b@example.com" || invitees[2] != "c@example.com" {
+		t.Errorf("got %v", invitees)
+	}
+}
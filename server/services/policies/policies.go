@@ -0,0 +1,81 @@
+// Evaluates the compliance policies of organizations on requests that create
+// or update their events, and deletes events past their retention period
+package policies
+
+import (
+	"fmt"
+	"strings"
+
+	"schej.it/server/models"
+)
+
+type Action string
+
+const (
+	CREATE_EVENT Action = "createEvent"
+	UPDATE_EVENT Action = "updateEvent"
+	RESPOND      Action = "respond"
+)
+
+// The parts of a request that policies apply to
+type Request struct {
+	Action Action
+
+	// Set when responding
+	Guest bool
+	Email string
+
+	// Emails of the remindees, attendees or co-organizers being added
+	Invitees []string
+}
+
+// A policy the request doesn't comply with
+type Violation struct {
+	Policy  string `json:"policy"`
+	Message string `json:"message"`
+}
+
+// Returns the policies the request violates. memberEmails are the emails of
+// the organization's members
+func Evaluate(policies models.OrganizationPolicies, memberEmails models.Set[string], request Request) []Violation {
+	violations := make([]Violation, 0)
+
+	if policies.RequireGuestEmail && request.Action == RESPOND && request.Guest && !strings.Contains(request.Email, "@") {
+		violations = append(violations, Violation{
+			Policy:  "requireGuestEmail",
+			Message: "guests must provide their email to respond",
+		})
+	}
+
+	if policies.DisableExternalSharing && request.Action != RESPOND {
+		for _, email := range request.Invitees {
+			if !isInternal(policies, memberEmails, email) {
+				violations = append(violations, Violation{
+					Policy:  "disableExternalSharing",
+					Message: fmt.Sprintf("%s is outside the organization", email),
+				})
+			}
+		}
+	}
+
+	return violations
+}
+
+// Returns whether the email belongs to a member or one of the allowed domains
+func isInternal(policies models.OrganizationPolicies, memberEmails models.Set[string], email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if _, ok := memberEmails[email]; ok {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at == -1 {
+		return false
+	}
+	domain := email[at+1:]
+	for _, allowed := range policies.AllowedDomains {
+		if strings.EqualFold(domain, strings.TrimPrefix(allowed, "@")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package policies
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func TestEvaluateGuestEmail(t *testing.T) {
+	policies := models.OrganizationPolicies{RequireGuestEmail: true}
+	if violations := Evaluate(policies, nil, Request{Action: RESPOND, Guest: true}); len(violations) != 1 {
+		t.Errorf("got %d violations, want 1", len(violations))
+	}
+	if violations := Evaluate(policies, nil, Request{Action: RESPOND, Guest: true, Email: "ana@example.com"}); len(violations) != 0 {
+		t.Errorf("got %v", violations)
+	}
+	if violations := Evaluate(policies, nil, Request{Action: RESPOND}); len(violations) != 0 {
+		t.Errorf("got %v for signed in respondent", violations)
+	}
+}
+
+func TestEvaluateExternalSharing(t *testing.T) {
+	policies := models.OrganizationPolicies{DisableExternalSharing: true, AllowedDomains: []string{"@school.edu"}}
+	members := models.Set[string]{"ana@gmail.com": {}}
+	request := Request{Action: CREATE_EVENT, Invitees: []string{"Ana@gmail.com", "bo@School.edu", "cy@gmail.com"}}
+
+	violations := Evaluate(policies, members, request)
+	if len(violations) != 1 || violations[0].Policy != "disableExternalSharing" {
+		t.Errorf("got %v, want a violation for cy@gmail.com", violations)
+	}
+}
+
+func TestValidateSso(t *testing.T) {
+	if err := ValidateSso(models.OrganizationPolicies{RequireSso: true}); err == nil {
+		t.Error("expected missing provider to be invalid")
+	}
+	if err := ValidateSso(models.OrganizationPolicies{RequireSso: true, SsoProvider: models.AppleCalendarType}); err == nil {
+		t.Error("expected apple to be invalid")
+	}
+	if err := ValidateSso(models.OrganizationPolicies{RequireSso: true, SsoProvider: models.OutlookCalendarType}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMeetsSso(t *testing.T) {
+	verifiedAt := primitive.NewDateTimeFromTime(time.Now())
+	member := &models.User{Id: primitive.NewObjectID(), Email: "ana@gmail.com"}
+	org := &models.Organization{
+		Members:  []models.OrganizationMember{{UserId: member.Id, Role: models.ORG_MEMBER}},
+		Domains:  []models.OrganizationDomain{{Domain: "acme.com", VerifiedAt: &verifiedAt}, {Domain: "acme.io"}},
+		Policies: models.OrganizationPolicies{RequireSso: true, SsoProvider: models.GoogleCalendarType},
+	}
+
+	if !MeetsSso(org, models.GoogleCalendarType, member) {
+		t.Error("expected member to meet the policy")
+	}
+	if MeetsSso(org, models.OutlookCalendarType, member) {
+		t.Error("expected other providers to not meet the policy")
+	}
+	if !MeetsSso(org, models.GoogleCalendarType, &models.User{Email: "bo@Acme.com"}) {
+		t.Error("expected verified domain to meet the policy")
+	}
+	if MeetsSso(org, models.GoogleCalendarType, &models.User{Email: "cy@acme.io"}) {
+		t.Error("expected unverified domain to not meet the policy")
+	}
+	if MeetsSso(org, models.GoogleCalendarType, nil) {
+		t.Error("expected signed out viewers to not meet the policy")
+	}
+
+	org.Policies.RequireSso = false
+	if !MeetsSso(org, "", nil) {
+		t.Error("expected everyone to meet a disabled policy")
+	}
+
+	hint := GetSsoHint(org, &models.Event{Id: primitive.NewObjectID()})
+	if len(hint.Domains) != 1 || hint.Domains[0] != "acme.com" {
+		t.Errorf("got %+v", hint)
+	}
+}
@@ -0,0 +1,64 @@
+package policies
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services/gcloud"
+	"schej.it/server/utils"
+)
+
+// Deletes the events of organizations with a retention policy that were
+// created more than the retention period ago. Run periodically by the jobs
+// scheduler
+func DeleteExpiredEvents(now time.Time) {
+	cursor, err := db.OrganizationsCollection.Find(context.Background(), bson.M{"policies.retentionDays": bson.M{"$gt": 0}})
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	orgs := make([]models.Organization, 0)
+	if err := cursor.All(context.Background(), &orgs); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	for _, org := range orgs {
+		// Event ids contain their creation time
+		createdBefore := primitive.NewObjectIDFromTimestamp(now.AddDate(0, 0, -org.Policies.RetentionDays))
+		filter := bson.M{
+			"organizationId": org.Id,
+			"_id":            bson.M{"$lt": createdBefore},
+			"$or": bson.A{
+				bson.M{"isDeleted": bson.M{"$exists": false}},
+				bson.M{"isDeleted": false},
+			},
+		}
+
+		cursor, err := db.EventsCollection.Find(context.Background(), filter)
+		if err != nil {
+			logger.StdErr.Panicln(err)
+		}
+		events := make([]models.Event, 0)
+		if err := cursor.All(context.Background(), &events); err != nil {
+			logger.StdErr.Panicln(err)
+		}
+
+		for _, event := range events {
+			for _, remindee := range utils.Coalesce(event.Remindees) {
+				for _, taskId := range remindee.TaskIds {
+					gcloud.DeleteEmailTask(taskId)
+				}
+			}
+			_, err := db.EventsCollection.UpdateByID(context.Background(), event.Id, bson.M{
+				"$set": bson.M{"isDeleted": true},
+			})
+			if err != nil {
+				logger.StdErr.Panicln(err)
+			}
+		}
+	}
+}
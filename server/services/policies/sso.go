@@ -0,0 +1,63 @@
+package policies
+
+import (
+	"errors"
+	"fmt"
+
+	"schej.it/server/models"
+	"schej.it/server/services/organizations"
+)
+
+// Tells the client how to sign in to meet an organization's SSO policy
+type SsoHint struct {
+	Provider models.CalendarType `json:"provider"`
+
+	// Verified domains of the organization, e.g. to pass as a login hint
+	Domains []string `json:"domains"`
+
+	// Where to send the viewer back to once they signed in
+	RedirectPath string `json:"redirectPath"`
+}
+
+// Validates the SSO policy, which needs a supported identity provider
+func ValidateSso(policies models.OrganizationPolicies) error {
+	if !policies.RequireSso {
+		return nil
+	}
+	if policies.SsoProvider != models.GoogleCalendarType && policies.SsoProvider != models.OutlookCalendarType {
+		return errors.New("ssoProvider must be google or outlook")
+	}
+	return nil
+}
+
+// Returns whether a viewer that signed in with the given identity provider
+// meets the organization's SSO policy, i.e. they're a member or their email is
+// on one of its verified domains
+func MeetsSso(org *models.Organization, provider models.CalendarType, user *models.User) bool {
+	if !org.Policies.RequireSso {
+		return true
+	}
+	if user == nil || provider != org.Policies.SsoProvider {
+		return false
+	}
+	if organizations.GetMember(org, user.Id) != nil {
+		return true
+	}
+	domain := organizations.GetDomain(org, organizations.GetEmailDomain(user.Email))
+	return domain != nil && domain.VerifiedAt != nil
+}
+
+// Returns the hint for signing in to view the organization's event
+func GetSsoHint(org *models.Organization, event *models.Event) SsoHint {
+	domains := make([]string, 0)
+	for _, domain := range org.Domains {
+		if domain.VerifiedAt != nil {
+			domains = append(domains, domain.Domain)
+		}
+	}
+	return SsoHint{
+		Provider:     org.Policies.SsoProvider,
+		Domains:      domains,
+		RedirectPath: fmt.Sprintf("/e/%s", event.GetId()),
+	}
+}
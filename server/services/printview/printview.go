@@ -0,0 +1,153 @@
+// Builds the print-optimized representation of an event's responses, so the
+// print view and the PDF export lay out the same respondent matrix
+package printview
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/utils"
+)
+
+// Whether a respondent can make a time increment
+type Status string
+
+const (
+	AVAILABLE   Status = "available"
+	IF_NEEDED   Status = "ifNeeded"
+	UNAVAILABLE Status = "unavailable"
+)
+
+// How a status is printed in the matrix
+type LegendEntry struct {
+	Status Status `json:"status"`
+	Label  string `json:"label"`
+	Symbol string `json:"symbol"`
+}
+
+var Legend = []LegendEntry{
+	{Status: AVAILABLE, Label: "Available", Symbol: "✓"},
+	{Status: IF_NEEDED, Label: "If needed", Symbol: "(✓)"},
+	{Status: UNAVAILABLE, Label: "Unavailable", Symbol: ""},
+}
+
+// A time increment of the event, with how many respondents can make it
+type Column struct {
+	Start primitive.DateTime `json:"start"`
+
+	// Label of the day, and of the time unless the event is days only
+	Day  string `json:"day"`
+	Time string `json:"time,omitempty"`
+
+	Available int `json:"available"`
+	IfNeeded  int `json:"ifNeeded"`
+
+	// Whether the increment is part of a time the event was finalized at
+	Finalized bool `json:"finalized"`
+}
+
+// A respondent, with their status for every column
+type Row struct {
+	RespondentId string   `json:"respondentId"`
+	Name         string   `json:"name"`
+	Cells        []Status `json:"cells"`
+}
+
+// A time the event was finalized at
+type Slot struct {
+	Start primitive.DateTime `json:"start"`
+	End   primitive.DateTime `json:"end"`
+	Label string             `json:"label"`
+}
+
+type PrintView struct {
+	Name     string `json:"name"`
+	Timezone string `json:"timezone"`
+
+	Columns []Column      `json:"columns"`
+	Rows    []Row         `json:"rows"`
+	Legend  []LegendEntry `json:"legend"`
+
+	// The scheduled time, or the times of the event's sessions. Empty if the
+	// event hasn't been finalized
+	Finalized []Slot `json:"finalized"`
+}
+
+// Returns the print view of the event's responses, with times in the given
+// location. Respondents are sorted by name
+func Build(event *models.Event, respondents []scheduling.Respondent, loc *time.Location) PrintView {
+	view := PrintView{
+		Name:      event.Name,
+		Timezone:  loc.String(),
+		Columns:   make([]Column, 0),
+		Rows:      make([]Row, 0),
+		Legend:    Legend,
+		Finalized: getFinalizedSlots(event, loc),
+	}
+
+	for _, t := range scheduling.GetTimeIncrements(event) {
+		column := Column{Start: primitive.NewDateTimeFromTime(t), Day: getDayLabel(event, t.In(loc))}
+		if !utils.Coalesce(event.DaysOnly) {
+			column.Time = t.In(loc).Format("3:04 PM")
+		}
+		for _, slot := range view.Finalized {
+			if !t.Before(slot.Start.Time()) && t.Before(slot.End.Time()) {
+				column.Finalized = true
+			}
+		}
+		view.Columns = append(view.Columns, column)
+	}
+
+	sorted := append([]scheduling.Respondent{}, respondents...)
+	sort.SliceStable(sorted, func(i, j int) bool { return strings.ToLower(sorted[i].Name) < strings.ToLower(sorted[j].Name) })
+	for _, respondent := range sorted {
+		row := Row{RespondentId: respondent.Id, Name: respondent.Name, Cells: make([]Status, len(view.Columns))}
+		for i := range view.Columns {
+			key := int64(view.Columns[i].Start)
+			if _, ok := respondent.Available[key]; ok {
+				row.Cells[i] = AVAILABLE
+				view.Columns[i].Available++
+			} else if _, ok := respondent.IfNeeded[key]; ok {
+				row.Cells[i] = IF_NEEDED
+				view.Columns[i].IfNeeded++
+			} else {
+				row.Cells[i] = UNAVAILABLE
+			}
+		}
+		view.Rows = append(view.Rows, row)
+	}
+
+	return view
+}
+
+func getDayLabel(event *models.Event, t time.Time) string {
+	if event.Type == models.DOW || event.Type == models.GROUP {
+		// Dates of weekly events are placeholders for the days of the week
+		return t.Format("Monday")
+	}
+	return t.Format("Mon, Jan 2")
+}
+
+func getFinalizedSlots(event *models.Event, loc *time.Location) []Slot {
+	slots := make([]Slot, 0)
+	addSlot := func(start primitive.DateTime, end primitive.DateTime) {
+		label := getDayLabel(event, start.Time().In(loc))
+		if !utils.Coalesce(event.DaysOnly) {
+			label += " " + start.Time().In(loc).Format("3:04 PM") + " – " + end.Time().In(loc).Format("3:04 PM")
+		}
+		slots = append(slots, Slot{Start: start, End: end, Label: label})
+	}
+
+	if len(event.Sessions) > 0 {
+		for _, session := range event.Sessions {
+			addSlot(session.StartDate, session.EndDate)
+		}
+	} else if event.ScheduledEvent != nil {
+		addSlot(event.ScheduledEvent.StartDate, event.ScheduledEvent.EndDate)
+	}
+	return slots
+}
@@ -0,0 +1,65 @@
+package printview
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/services/scheduling/schedulingtest"
+)
+
+func newSet(times ...time.Time) models.Set[int64] {
+	set := make(models.Set[int64])
+	for _, t := range times {
+		set[int64(primitive.NewDateTimeFromTime(t))] = struct{}{}
+	}
+	return set
+}
+
+func TestBuild(t *testing.T) {
+	duration := float32(3)
+	timeIncrement := 60
+	event := &models.Event{
+		Name:          "Planning",
+		Type:          models.SPECIFIC_DATES,
+		Dates:         []primitive.DateTime{primitive.NewDateTimeFromTime(schedulingtest.Day)},
+		Duration:      &duration,
+		TimeIncrement: &timeIncrement,
+		ScheduledEvent: &models.CalendarEvent{
+			StartDate: primitive.NewDateTimeFromTime(schedulingtest.Hour(10)),
+			EndDate:   primitive.NewDateTimeFromTime(schedulingtest.Hour(11)),
+		},
+	}
+	respondents := []scheduling.Respondent{
+		{Id: "2", Name: "bo", Available: newSet(schedulingtest.Hour(10)), IfNeeded: newSet(schedulingtest.Hour(11))},
+		{Id: "1", Name: "Ana", Available: newSet(schedulingtest.Hour(9), schedulingtest.Hour(10)), IfNeeded: newSet()},
+	}
+
+	view := Build(event, respondents, time.UTC)
+
+	if len(view.Columns) != 3 {
+		t.Fatalf("got %d columns, want 3", len(view.Columns))
+	}
+	if view.Columns[0].Day != "Tue, May 14" || view.Columns[0].Time != "9:00 AM" {
+		t.Errorf("got column labels %q %q", view.Columns[0].Day, view.Columns[0].Time)
+	}
+	if view.Columns[1].Available != 2 || view.Columns[2].IfNeeded != 1 {
+		t.Errorf("got column totals %+v", view.Columns)
+	}
+	if view.Columns[0].Finalized || !view.Columns[1].Finalized || view.Columns[2].Finalized {
+		t.Errorf("expected only the 10am column to be finalized: %+v", view.Columns)
+	}
+
+	if len(view.Rows) != 2 || view.Rows[0].Name != "Ana" || view.Rows[1].Name != "bo" {
+		t.Fatalf("expected rows sorted by name: %+v", view.Rows)
+	}
+	if cells := view.Rows[1].Cells; cells[0] != UNAVAILABLE || cells[1] != AVAILABLE || cells[2] != IF_NEEDED {
+		t.Errorf("got cells %v", cells)
+	}
+
+	if len(view.Finalized) != 1 || view.Finalized[0].Label != "Tue, May 14 10:00 AM – 11:00 AM" {
+		t.Errorf("got finalized slots %+v", view.Finalized)
+	}
+}
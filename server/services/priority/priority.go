@@ -0,0 +1,161 @@
+// Classifies requests by how urgent they are and limits how many of each class
+// run at once, so heavy exports and background polling can't starve the
+// requests of people using the site on small instances
+package priority
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"schej.it/server/errs"
+	"schej.it/server/responses"
+)
+
+type Class string
+
+const (
+	// Page loads and actions of people using the site
+	INTERACTIVE Class = "interactive"
+
+	// Polling, e.g. by calendar apps, status pages, and metrics scrapers
+	BACKGROUND Class = "background"
+
+	// Exports that read a lot of data
+	EXPORT Class = "export"
+
+	// Probes and long lived streams, which are never limited
+	EXEMPT Class = "exempt"
+)
+
+// Header clients can set to lower the priority of their requests, e.g. when
+// the web app polls in the background. It can't be used to raise the priority
+const PRIORITY_HEADER = "X-Request-Priority"
+
+var exemptPaths = map[string]bool{
+	"/healthz":                       true,
+	"/readyz":                        true,
+	"/api/events/:eventId/subscribe": true,
+}
+
+var backgroundPaths = map[string]bool{
+	"/metrics":               true,
+	"/api/status":            true,
+	"/api/config":            true,
+	"/api/user/calendar.ics": true,
+	"/api/telemetry":         true,
+}
+
+var exportPaths = map[string]bool{
+	"/api/events/:eventId/responses/export": true,
+	"/api/terms/:termId/export":             true,
+	"/api/admin/data-subjects":              true,
+}
+
+// Returns the class of the request to the route with the given full path
+func Classify(fullPath string, header http.Header) Class {
+	switch {
+	case exemptPaths[fullPath]:
+		return EXEMPT
+	case exportPaths[fullPath]:
+		return EXPORT
+	case backgroundPaths[fullPath]:
+		return BACKGROUND
+	case strings.EqualFold(header.Get(PRIORITY_HEADER), string(BACKGROUND)):
+		return BACKGROUND
+	}
+	return INTERACTIVE
+}
+
+// Limits the number of requests of a class that run at once. Requests over
+// the limit wait for a slot for up to the class's wait
+type Limiter struct {
+	slots chan struct{}
+	wait  time.Duration
+}
+
+// Returns a limiter that lets limit requests run at once, or nil if limit
+// isn't positive, i.e. the class isn't limited
+func NewLimiter(limit int, wait time.Duration) *Limiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &Limiter{slots: make(chan struct{}, limit), wait: wait}
+}
+
+// Waits for a slot, returning whether one was acquired before the wait ran out
+// or ctx was done. Acquired slots have to be released
+func (l *Limiter) Acquire(ctx context.Context) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	timer := time.NewTimer(l.wait)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (l *Limiter) Release() {
+	<-l.slots
+}
+
+// Default limit and wait of each class, overridden with
+// PRIORITY_<CLASS>_LIMIT, e.g. PRIORITY_EXPORT_LIMIT=4. Interactive requests
+// aren't limited by default
+var defaults = map[Class]struct {
+	Limit int
+	Wait  time.Duration
+}{
+	INTERACTIVE: {Limit: 0, Wait: 5 * time.Second},
+	BACKGROUND:  {Limit: 16, Wait: 2 * time.Second},
+	EXPORT:      {Limit: 2, Wait: 30 * time.Second},
+}
+
+// Returns the limiters of each class, configured from the environment
+func NewLimiters() map[Class]*Limiter {
+	limiters := make(map[Class]*Limiter)
+	for class, d := range defaults {
+		limit := d.Limit
+		if value, err := strconv.Atoi(os.Getenv(fmt.Sprintf("PRIORITY_%s_LIMIT", strings.ToUpper(string(class))))); err == nil {
+			limit = value
+		}
+		limiters[class] = NewLimiter(limit, d.Wait)
+	}
+	return limiters
+}
+
+// Limits the requests of each class to the class's limit. Requests that don't
+// get a slot in time get a 503 asking them to retry
+func Middleware() gin.HandlerFunc {
+	limiters := NewLimiters()
+	return func(c *gin.Context) {
+		limiter := limiters[Classify(c.FullPath(), c.Request.Header)]
+		if limiter == nil {
+			c.Next()
+			return
+		}
+
+		if !limiter.Acquire(c.Request.Context()) {
+			c.Header("Retry-After", "5")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, responses.Error{Error: errs.ServerBusy})
+			return
+		}
+		defer limiter.Release()
+
+		c.Next()
+	}
+}
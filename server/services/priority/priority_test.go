@@ -0,0 +1,79 @@
+package priority
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	cases := map[string]Class{
+		"/api/events/:eventId":                  INTERACTIVE,
+		"/api/events/:eventId/responses/export": EXPORT,
+		"/api/user/calendar.ics":                BACKGROUND,
+		"/api/events/:eventId/subscribe":        EXEMPT,
+		"/healthz":                              EXEMPT,
+		"":                                      INTERACTIVE,
+	}
+	for path, want := range cases {
+		if got := Classify(path, http.Header{}); got != want {
+			t.Errorf("Classify(%q) = %q, want %q", path, got, want)
+		}
+	}
+
+	// The header can lower the priority, but not raise it
+	background := http.Header{}
+	background.Set(PRIORITY_HEADER, "background")
+	if got := Classify("/api/events/:eventId", background); got != BACKGROUND {
+		t.Errorf("got %q for a background request", got)
+	}
+	interactive := http.Header{}
+	interactive.Set(PRIORITY_HEADER, "interactive")
+	if got := Classify("/api/events/:eventId/responses/export", interactive); got != EXPORT {
+		t.Errorf("got %q for an export claiming to be interactive", got)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	if NewLimiter(0, time.Second) != nil {
+		t.Errorf("expected no limiter without a limit")
+	}
+
+	short := NewLimiter(1, 10*time.Millisecond)
+	if !short.Acquire(context.Background()) {
+		t.Fatalf("expected a free slot")
+	}
+	if short.Acquire(context.Background()) {
+		t.Errorf("expected the wait to run out while the slot is taken")
+	}
+
+	limiter := NewLimiter(1, time.Second)
+	limiter.Acquire(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if limiter.Acquire(ctx) {
+		t.Errorf("expected cancelled requests to stop waiting")
+	}
+
+	// Waiting requests get the slot once it's released
+	go func() {
+		time.Sleep(time.Millisecond)
+		limiter.Release()
+	}()
+	if !limiter.Acquire(context.Background()) {
+		t.Errorf("expected the released slot")
+	}
+}
+
+func TestNewLimiters(t *testing.T) {
+	t.Setenv("PRIORITY_EXPORT_LIMIT", "4")
+	t.Setenv("PRIORITY_BACKGROUND_LIMIT", "0")
+	limiters := NewLimiters()
+	if cap(limiters[EXPORT].slots) != 4 {
+		t.Errorf("got an export limit of %d", cap(limiters[EXPORT].slots))
+	}
+	if limiters[BACKGROUND] != nil || limiters[INTERACTIVE] != nil {
+		t.Errorf("expected background and interactive requests to be unlimited")
+	}
+}
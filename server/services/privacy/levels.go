@@ -0,0 +1,104 @@
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+// How much of an event's responses a viewer sees
+type Visibility string
+
+const (
+	// Every response, with who it is from
+	VISIBLE_ALL Visibility = "all"
+
+	// Every response, without who it is from
+	VISIBLE_ANONYMOUS Visibility = "anonymous"
+
+	// Only the viewer's own response
+	VISIBLE_OWN Visibility = "own"
+)
+
+// Who is viewing an event
+type Viewer struct {
+	Organizer bool
+	Invitee   bool
+}
+
+func IsValidLevel(level models.PrivacyLevel) bool {
+	return level == models.PRIVACY_PUBLIC || level == models.PRIVACY_COUNTS || level == models.PRIVACY_INVITEES || level == models.PRIVACY_ORGANIZERS
+}
+
+// Returns the event's privacy level, which defaults to public
+func GetLevel(event *models.Event) models.PrivacyLevel {
+	if event.PrivacyLevel == nil || !IsValidLevel(*event.PrivacyLevel) {
+		return models.PRIVACY_PUBLIC
+	}
+	return *event.PrivacyLevel
+}
+
+// Returns how much of the event's responses the viewer sees
+func GetVisibility(event *models.Event, viewer Viewer) Visibility {
+	if viewer.Organizer {
+		return VISIBLE_ALL
+	}
+	switch GetLevel(event) {
+	case models.PRIVACY_COUNTS:
+		return VISIBLE_ANONYMOUS
+	case models.PRIVACY_INVITEES:
+		if viewer.Invitee {
+			return VISIBLE_ALL
+		}
+		return VISIBLE_OWN
+	case models.PRIVACY_ORGANIZERS:
+		return VISIBLE_OWN
+	}
+	return VISIBLE_ALL
+}
+
+// Returns whether anyone with the event's link sees the respondents' names,
+// e.g. in live presence
+func ShowsNames(event *models.Event) bool {
+	return GetLevel(event) == models.PRIVACY_PUBLIC && !utils.Coalesce(event.BlindAvailabilityEnabled)
+}
+
+// Returns whether anyone with the event's link sees the respondents'
+// availability, at least anonymously
+func ShowsAvailability(event *models.Event) bool {
+	level := GetLevel(event)
+	return level == models.PRIVACY_PUBLIC || level == models.PRIVACY_COUNTS
+}
+
+// Returns whether the email was invited to the event, as a remindee or an
+// authorized email
+func IsInvitee(event *models.Event, email string) bool {
+	if len(email) == 0 {
+		return false
+	}
+	for _, remindee := range utils.Coalesce(event.Remindees) {
+		if strings.EqualFold(remindee.Email, email) {
+			return true
+		}
+	}
+	for _, authorizedEmail := range event.AuthorizedEmails {
+		if strings.EqualFold(authorizedEmail, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the id an anonymous respondent of the event is keyed by, which is
+// the same across requests but doesn't reveal who they are
+func GetAnonymousId(eventId primitive.ObjectID, respondentId string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("ENCRYPTION_KEY")))
+	mac.Write([]byte(eventId.Hex() + ":" + respondentId))
+	return "anonymous-" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
@@ -0,0 +1,55 @@
+package privacy
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func TestGetVisibility(t *testing.T) {
+	organizer := Viewer{Organizer: true}
+	invitee := Viewer{Invitee: true}
+	anyone := Viewer{}
+	for level, want := range map[models.PrivacyLevel][3]Visibility{
+		"":                        {VISIBLE_ALL, VISIBLE_ALL, VISIBLE_ALL},
+		models.PRIVACY_PUBLIC:     {VISIBLE_ALL, VISIBLE_ALL, VISIBLE_ALL},
+		models.PRIVACY_COUNTS:     {VISIBLE_ALL, VISIBLE_ANONYMOUS, VISIBLE_ANONYMOUS},
+		models.PRIVACY_INVITEES:   {VISIBLE_ALL, VISIBLE_ALL, VISIBLE_OWN},
+		models.PRIVACY_ORGANIZERS: {VISIBLE_ALL, VISIBLE_OWN, VISIBLE_OWN},
+	} {
+		level := level
+		event := &models.Event{PrivacyLevel: &level}
+		for i, viewer := range []Viewer{organizer, invitee, anyone} {
+			if got := GetVisibility(event, viewer); got != want[i] {
+				t.Errorf("GetVisibility(%q, %+v) = %q, want %q", level, viewer, got, want[i])
+			}
+		}
+	}
+
+	if !ShowsNames(&models.Event{}) || !ShowsAvailability(&models.Event{}) {
+		t.Error("expected events to be public by default")
+	}
+	counts := models.PRIVACY_COUNTS
+	if ShowsNames(&models.Event{PrivacyLevel: &counts}) || !ShowsAvailability(&models.Event{PrivacyLevel: &counts}) {
+		t.Error("expected counts only events to show the availability without names")
+	}
+}
+
+func TestIsInvitee(t *testing.T) {
+	event := &models.Event{
+		Remindees:        &[]models.Remindee{{Email: "ana@example.com"}},
+		AuthorizedEmails: []string{"bo@example.com"},
+	}
+	if !IsInvitee(event, "Ana@example.com") || !IsInvitee(event, "bo@example.com") || IsInvitee(event, "cy@example.com") || IsInvitee(event, "") {
+		t.Error("expected remindees and authorized emails to be invitees")
+	}
+}
+
+func TestGetAnonymousId(t *testing.T) {
+	eventId := primitive.NewObjectID()
+	id := GetAnonymousId(eventId, "Ana")
+	if id != GetAnonymousId(eventId, "Ana") || id == GetAnonymousId(eventId, "Bo") || id == GetAnonymousId(primitive.NewObjectID(), "Ana") {
+		t.Error("expected ids to be stable per event and respondent")
+	}
+}
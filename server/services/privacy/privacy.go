@@ -0,0 +1,52 @@
+// Instance-wide minimization of the personal data kept about guests, for
+// self-hosters that are required to collect as little as possible
+package privacy
+
+import (
+	"fmt"
+	"os"
+
+	"schej.it/server/models"
+)
+
+// Whether the instance minimizes personal data, set with
+// PII_MINIMIZATION_ENABLED. When it does, guests are only stored by their
+// display name (no email or IP address), analytics don't identify users, and
+// exports leave out contact details
+func IsMinimized() bool {
+	return os.Getenv("PII_MINIMIZATION_ENABLED") == "true"
+}
+
+// Returns the email to store for a guest, which is dropped when minimized
+func GetGuestEmail(email string) string {
+	if IsMinimized() {
+		return ""
+	}
+	return email
+}
+
+// Returns the IP address to store for a guest, which is dropped when minimized
+func GetGuestIp(ip string) string {
+	if IsMinimized() {
+		return ""
+	}
+	return ip
+}
+
+// Returns how to refer to the user in analytics notifications: their name and
+// email, or only their id when minimized
+func GetAnalyticsName(user *models.User) string {
+	if IsMinimized() {
+		return fmt.Sprintf("User %s", user.Id.Hex())
+	}
+	return fmt.Sprintf("%s %s (%s)", user.FirstName, user.LastName, user.Email)
+}
+
+// Returns the location to report in analytics, which is reduced to the country
+// when minimized
+func GetAnalyticsLocation(location models.Location) models.Location {
+	if IsMinimized() {
+		return models.Location{CountryCode: location.CountryCode, CountryName: location.CountryName}
+	}
+	return location
+}
@@ -0,0 +1,36 @@
+package privacy
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func TestMinimized(t *testing.T) {
+	t.Setenv("PII_MINIMIZATION_ENABLED", "true")
+	user := &models.User{Id: primitive.NewObjectID(), FirstName: "Ana", LastName: "Lee", Email: "ana@example.com"}
+
+	if GetGuestEmail("bo@example.com") != "" || GetGuestIp("10.0.0.1") != "" {
+		t.Error("expected guest email and IP address to be dropped")
+	}
+	if name := GetAnalyticsName(user); name != "User "+user.Id.Hex() {
+		t.Errorf("got %q", name)
+	}
+	location := GetAnalyticsLocation(models.Location{CountryCode: "DE", City: "Berlin", Latitude: 52.5})
+	if location.CountryCode != "DE" || location.City != "" || location.Latitude != 0 {
+		t.Errorf("got %v", location)
+	}
+}
+
+func TestNotMinimized(t *testing.T) {
+	t.Setenv("PII_MINIMIZATION_ENABLED", "")
+	user := &models.User{FirstName: "Ana", LastName: "Lee", Email: "ana@example.com"}
+
+	if GetGuestEmail("bo@example.com") != "bo@example.com" || GetGuestIp("10.0.0.1") != "10.0.0.1" {
+		t.Error("expected guest email and IP address to be kept")
+	}
+	if name := GetAnalyticsName(user); name != "Ana Lee (ana@example.com)" {
+		t.Errorf("got %q", name)
+	}
+}
@@ -0,0 +1,50 @@
+// Flags responses that look low effort or accidental, e.g. selecting every
+// time or submitting within seconds of opening the poll
+package quality
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+)
+
+// Responses submitted sooner than this after the event page was loaded are
+// flagged
+const MIN_SUBMISSION_DURATION = 2 * time.Second
+
+// Returns the quality flags of a response with the given availability, which
+// took the respondent submissionDuration to submit (nil if unknown)
+func Check(event *models.Event, availability []primitive.DateTime, ifNeeded []primitive.DateTime, submissionDuration *time.Duration) []models.QualityFlag {
+	flags := make([]models.QualityFlag, 0)
+
+	// Availability groups are filled in from calendars, so any selection is plausible
+	if event.Type != models.GROUP {
+		selected := make(models.Set[int64])
+		for _, t := range append(append([]primitive.DateTime{}, availability...), ifNeeded...) {
+			selected[t.Time().UnixMilli()] = struct{}{}
+		}
+
+		if len(selected) == 0 {
+			flags = append(flags, models.NOTHING_SELECTED)
+		} else if increments := scheduling.GetTimeIncrements(event); len(increments) > 1 {
+			everything := true
+			for _, t := range increments {
+				if _, ok := selected[t.UnixMilli()]; !ok {
+					everything = false
+					break
+				}
+			}
+			if everything {
+				flags = append(flags, models.EVERYTHING_SELECTED)
+			}
+		}
+	}
+
+	if submissionDuration != nil && *submissionDuration < MIN_SUBMISSION_DURATION {
+		flags = append(flags, models.SUBMITTED_QUICKLY)
+	}
+
+	return flags
+}
@@ -0,0 +1,54 @@
+package quality
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func TestCheck(t *testing.T) {
+	duration := float32(1)
+	timeIncrement := 30
+	start := time.Date(2024, time.May, 14, 9, 0, 0, 0, time.UTC)
+	event := &models.Event{
+		Type:          models.SPECIFIC_DATES,
+		Dates:         []primitive.DateTime{primitive.NewDateTimeFromTime(start)},
+		Duration:      &duration,
+		TimeIncrement: &timeIncrement,
+	}
+	at := func(minutes int) primitive.DateTime {
+		return primitive.NewDateTimeFromTime(start.Add(time.Duration(minutes) * time.Minute))
+	}
+	quick := time.Second
+	slow := time.Minute
+
+	tests := []struct {
+		name         string
+		availability []primitive.DateTime
+		ifNeeded     []primitive.DateTime
+		duration     *time.Duration
+		want         []models.QualityFlag
+	}{
+		{"some selected", []primitive.DateTime{at(0)}, nil, &slow, []models.QualityFlag{}},
+		{"nothing selected", nil, nil, nil, []models.QualityFlag{models.NOTHING_SELECTED}},
+		{"everything selected", []primitive.DateTime{at(0), at(30)}, nil, nil, []models.QualityFlag{models.EVERYTHING_SELECTED}},
+		{"everything selected if needed", []primitive.DateTime{at(0)}, []primitive.DateTime{at(30)}, nil, []models.QualityFlag{models.EVERYTHING_SELECTED}},
+		{"submitted quickly", []primitive.DateTime{at(0)}, nil, &quick, []models.QualityFlag{models.SUBMITTED_QUICKLY}},
+		{"nothing selected quickly", nil, nil, &quick, []models.QualityFlag{models.NOTHING_SELECTED, models.SUBMITTED_QUICKLY}},
+	}
+	for _, test := range tests {
+		if got := Check(event, test.availability, test.ifNeeded, test.duration); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	// Availability groups aren't checked for what's selected
+	group := *event
+	group.Type = models.GROUP
+	if got := Check(&group, nil, nil, nil); len(got) != 0 {
+		t.Errorf("group: got %v", got)
+	}
+}
@@ -0,0 +1,78 @@
+// Finds slots that satisfy an event's quorum, e.g. "at least 5 people and
+// both leads"
+package quorum
+
+import (
+	"errors"
+	"time"
+
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+)
+
+// Validates the quorum, removing duplicate required respondents
+func Validate(quorum *models.Quorum) error {
+	if quorum.MinAttendees < 0 || quorum.DurationMinutes < 0 {
+		return errors.New("minAttendees and durationMinutes can't be negative")
+	}
+
+	required := make([]string, 0)
+	seen := make(models.Set[string])
+	for _, id := range quorum.Required {
+		if _, ok := seen[id]; ok || len(id) == 0 {
+			continue
+		}
+		seen[id] = struct{}{}
+		required = append(required, id)
+	}
+	quorum.Required = required
+
+	if quorum.MinAttendees == 0 && len(quorum.Required) == 0 {
+		return errors.New("quorum needs minAttendees or required respondents")
+	}
+	if quorum.MinAttendees < len(quorum.Required) {
+		quorum.MinAttendees = len(quorum.Required)
+	}
+	return nil
+}
+
+// Returns the slots on the event's grid where quorum is met, ranked from best
+// to worst
+func FindSlots(event *models.Event, respondents []scheduling.Respondent, quorum *models.Quorum, exclude func(start time.Time, end time.Time) bool) []scheduling.Slot {
+	required := make(models.Set[string])
+	for _, id := range quorum.Required {
+		required[id] = struct{}{}
+	}
+
+	slots := make([]scheduling.Slot, 0)
+	for _, slot := range scheduling.RankSlots(event, respondents, scheduling.Options{
+		MeetingLength: time.Duration(quorum.DurationMinutes) * time.Minute,
+		Required:      required,
+		Exclude:       exclude,
+	}) {
+		if IsMet(quorum, slot) {
+			slots = append(slots, slot)
+		}
+	}
+	return slots
+}
+
+// Returns whether the slot meets the quorum
+func IsMet(quorum *models.Quorum, slot scheduling.Slot) bool {
+	attendees := make(models.Set[string])
+	for _, id := range slot.Available {
+		attendees[id] = struct{}{}
+	}
+	if quorum.CountIfNeeded {
+		for _, id := range slot.IfNeeded {
+			attendees[id] = struct{}{}
+		}
+	}
+
+	for _, id := range quorum.Required {
+		if _, ok := attendees[id]; !ok {
+			return false
+		}
+	}
+	return len(attendees) >= quorum.MinAttendees
+}
@@ -0,0 +1,50 @@
+package quorum
+
+import (
+	"testing"
+
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/services/scheduling/schedulingtest"
+)
+
+func TestValidate(t *testing.T) {
+	quorum := models.Quorum{MinAttendees: 1, Required: []string{"a", "b", "a", ""}}
+	if err := Validate(&quorum); err != nil {
+		t.Fatal(err)
+	}
+	if len(quorum.Required) != 2 || quorum.MinAttendees != 2 {
+		t.Errorf("got %+v", quorum)
+	}
+
+	if err := Validate(&models.Quorum{}); err == nil {
+		t.Error("expected empty quorum to be invalid")
+	}
+	if err := Validate(&models.Quorum{MinAttendees: -1}); err == nil {
+		t.Error("expected negative minAttendees to be invalid")
+	}
+}
+
+func TestFindSlots(t *testing.T) {
+	respondents := []scheduling.Respondent{
+		schedulingtest.NewRespondent("lead1", 9, 10, 11),
+		schedulingtest.NewRespondent("lead2", 10, 11),
+		schedulingtest.NewRespondentIfNeeded("c", []int{9, 10}, []int{11}),
+		schedulingtest.NewRespondent("d", 9, 11),
+	}
+	quorum := &models.Quorum{MinAttendees: 3, Required: []string{"lead1", "lead2"}}
+
+	slots := FindSlots(schedulingtest.NewEvent(), respondents, quorum, nil)
+	if len(slots) != 2 || !slots[0].Start.Equal(schedulingtest.Hour(11)) || !slots[1].Start.Equal(schedulingtest.Hour(10)) {
+		t.Fatalf("got %+v", slots)
+	}
+
+	quorum.MinAttendees = 4
+	if slots := FindSlots(schedulingtest.NewEvent(), respondents, quorum, nil); len(slots) != 0 {
+		t.Errorf("expected no slots, got %+v", slots)
+	}
+	quorum.CountIfNeeded = true
+	if slots := FindSlots(schedulingtest.NewEvent(), respondents, quorum, nil); len(slots) != 1 || !slots[0].Start.Equal(schedulingtest.Hour(11)) {
+		t.Errorf("expected if needed respondents to count, got %+v", slots)
+	}
+}
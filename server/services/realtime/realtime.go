@@ -0,0 +1,253 @@
+// Pushes changes to an event's responses to the clients viewing it, so the
+// availability heatmap updates live, along with how many people are viewing
+// and filling out the event
+package realtime
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+)
+
+type ChangeType string
+
+const (
+	RESPONSE_ADDED   ChangeType = "responseAdded"
+	RESPONSE_UPDATED ChangeType = "responseUpdated"
+	RESPONSE_DELETED ChangeType = "responseDeleted"
+
+	// The number of people viewing or filling out the event changed
+	PRESENCE_CHANGED ChangeType = "presenceChanged"
+
+	// Someone filling out the event is selecting times, which isn't saved
+	// until they submit their response
+	SELECTION_CHANGED ChangeType = "selectionChanged"
+)
+
+// Number of changes buffered per subscriber. Changes to subscribers that fall
+// further behind are dropped, since clients refetch the responses anyway
+const bufferSize = 16
+
+// A change to one of the event's responses. Clients refetch the responses
+// when notified, so the change doesn't include the availability itself
+type Change struct {
+	Type ChangeType `json:"type"`
+
+	// Id of the respondent (user id, or name for guests). Empty for events
+	// with blind availability, so respondents stay hidden
+	UserId string `json:"userId,omitempty"`
+
+	// Set for presence changes
+	Presence *Presence `json:"presence,omitempty"`
+
+	// Set for selection changes
+	Selection *Selection `json:"selection,omitempty"`
+}
+
+// How many people are connected to an event, and how many of them are filling
+// it out. Only the names of the people that chose to share them are included
+type Presence struct {
+	Viewing int      `json:"viewing"`
+	Editing int      `json:"editing"`
+	Editors []string `json:"editors"`
+}
+
+// Times someone filling out the event is selecting, shown to the others live
+// during group fill sessions
+type Selection struct {
+	// Id of the person's connection that is shown to others, which can't be
+	// used to set their presence
+	ParticipantId string `json:"participantId"`
+
+	// Name of the person if they shared it with their presence
+	Name string `json:"name,omitempty"`
+
+	// Unix milliseconds of the time increments being selected
+	Times []int64 `json:"times"`
+
+	// What the times are being marked as: "available", "ifNeeded", or
+	// "unavailable"
+	Mode string `json:"mode"`
+}
+
+// A subscriber's connection to an event
+type connection struct {
+	id       string
+	publicId string
+
+	// Whether presence changes are sent to the subscriber
+	receivesPresence bool
+
+	editing bool
+	name    string
+}
+
+// Fans out the changes to each event's subscribers
+type Hub struct {
+	mutex       sync.Mutex
+	subscribers map[string]map[chan Change]*connection
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[string]map[chan Change]*connection)}
+}
+
+// Hub of this server. Subscribers only get changes made through this server,
+// so instances behind a load balancer need sticky sessions
+var DefaultHub = NewHub()
+
+// Returns a channel the changes to the event are sent to, and a function to
+// unsubscribe that closes it
+func (hub *Hub) Subscribe(eventId string) (<-chan Change, func()) {
+	c, _, unsubscribe := hub.subscribe(eventId, false)
+	return c, unsubscribe
+}
+
+// Like Subscribe, but presence changes are sent to the channel too, starting
+// with the presence including the new connection. Returns the id the
+// connection's presence is set with
+func (hub *Hub) Connect(eventId string) (<-chan Change, string, func()) {
+	return hub.subscribe(eventId, true)
+}
+
+func (hub *Hub) subscribe(eventId string, receivesPresence bool) (<-chan Change, string, func()) {
+	c := make(chan Change, bufferSize)
+	conn := &connection{id: newConnectionId(), publicId: newConnectionId(), receivesPresence: receivesPresence}
+
+	hub.mutex.Lock()
+	if _, ok := hub.subscribers[eventId]; !ok {
+		hub.subscribers[eventId] = make(map[chan Change]*connection)
+	}
+	hub.subscribers[eventId][c] = conn
+	hub.publishPresence(eventId)
+	hub.mutex.Unlock()
+
+	var once sync.Once
+	return c, conn.id, func() {
+		once.Do(func() {
+			hub.mutex.Lock()
+			defer hub.mutex.Unlock()
+			delete(hub.subscribers[eventId], c)
+			if len(hub.subscribers[eventId]) == 0 {
+				delete(hub.subscribers, eventId)
+			} else {
+				hub.publishPresence(eventId)
+			}
+			close(c)
+		})
+	}
+}
+
+// Sets whether the person on the connection is filling out the event, and the
+// name shown to others while they are, empty to stay anonymous. Returns false
+// if the connection isn't open
+func (hub *Hub) SetPresence(eventId string, connectionId string, editing bool, name string) bool {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	for _, conn := range hub.subscribers[eventId] {
+		if conn.id == connectionId {
+			if conn.editing != editing || conn.name != name {
+				conn.editing = editing
+				conn.name = name
+				hub.publishPresence(eventId)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Sends the selection of the person on the connection to everyone else
+// receiving presence, without blocking. Returns false if the connection isn't
+// open
+func (hub *Hub) PublishSelection(eventId string, connectionId string, selection Selection) bool {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	var sender *connection
+	for _, conn := range hub.subscribers[eventId] {
+		if conn.id == connectionId {
+			sender = conn
+			break
+		}
+	}
+	if sender == nil {
+		return false
+	}
+
+	selection.ParticipantId = sender.publicId
+	selection.Name = sender.name
+	for c, conn := range hub.subscribers[eventId] {
+		if conn == sender || !conn.receivesPresence {
+			continue
+		}
+		select {
+		case c <- Change{Type: SELECTION_CHANGED, Selection: &selection}:
+		default:
+		}
+	}
+	return true
+}
+
+// Returns who is connected to the event
+func (hub *Hub) GetPresence(eventId string) Presence {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	return hub.getPresence(eventId)
+}
+
+func (hub *Hub) getPresence(eventId string) Presence {
+	presence := Presence{Editors: make([]string, 0)}
+	for _, conn := range hub.subscribers[eventId] {
+		presence.Viewing++
+		if conn.editing {
+			presence.Editing++
+			if len(conn.name) > 0 {
+				presence.Editors = append(presence.Editors, conn.name)
+			}
+		}
+	}
+	sort.Strings(presence.Editors)
+	return presence
+}
+
+// Sends the event's presence to the subscribers that receive it, without
+// blocking. The mutex must be held
+func (hub *Hub) publishPresence(eventId string) {
+	presence := hub.getPresence(eventId)
+	for c, conn := range hub.subscribers[eventId] {
+		if !conn.receivesPresence {
+			continue
+		}
+		select {
+		case c <- Change{Type: PRESENCE_CHANGED, Presence: &presence}:
+		default:
+		}
+	}
+}
+
+func newConnectionId() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Sends the change to the event's subscribers without blocking
+func (hub *Hub) Publish(eventId string, change Change) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	for c := range hub.subscribers[eventId] {
+		select {
+		case c <- change:
+		default:
+		}
+	}
+}
+
+// Returns the number of subscribers to the event
+func (hub *Hub) NumSubscribers(eventId string) int {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	return len(hub.subscribers[eventId])
+}
@@ -0,0 +1,125 @@
+package realtime
+
+import "testing"
+
+func TestPublish(t *testing.T) {
+	hub := NewHub()
+	c, unsubscribe := hub.Subscribe("a")
+	other, unsubscribeOther := hub.Subscribe("b")
+	defer unsubscribeOther()
+
+	hub.Publish("a", Change{Type: RESPONSE_ADDED, UserId: "ana"})
+	if change := <-c; change.Type != RESPONSE_ADDED || change.UserId != "ana" {
+		t.Errorf("got %v", change)
+	}
+	select {
+	case change := <-other:
+		t.Errorf("expected subscribers of other events not to get the change, got %v", change)
+	default:
+	}
+
+	unsubscribe()
+	unsubscribe()
+	if _, ok := <-c; ok {
+		t.Error("expected the channel to be closed after unsubscribing")
+	}
+	if hub.NumSubscribers("a") != 0 {
+		t.Errorf("got %d subscribers", hub.NumSubscribers("a"))
+	}
+}
+
+func TestPublishDoesNotBlockOnSlowSubscribers(t *testing.T) {
+	hub := NewHub()
+	c, unsubscribe := hub.Subscribe("a")
+	defer unsubscribe()
+
+	for i := 0; i < bufferSize*2; i++ {
+		hub.Publish("a", Change{Type: RESPONSE_UPDATED})
+	}
+	if len(c) != bufferSize {
+		t.Errorf("expected %d buffered changes, got %d", bufferSize, len(c))
+	}
+}
+
+func TestPresence(t *testing.T) {
+	hub := NewHub()
+	plain, unsubscribePlain := hub.Subscribe("a")
+	defer unsubscribePlain()
+
+	c, connectionId, disconnect := hub.Connect("a")
+	if change := <-c; change.Type != PRESENCE_CHANGED || change.Presence.Viewing != 2 || change.Presence.Editing != 0 {
+		t.Errorf("expected the presence when connecting, got %+v", change)
+	}
+
+	other, otherId, disconnectOther := hub.Connect("a")
+	<-other
+	<-c
+
+	if !hub.SetPresence("a", connectionId, true, "") || !hub.SetPresence("a", otherId, true, "Nell") {
+		t.Fatal("expected the connections to be open")
+	}
+	<-c
+	if change := <-c; change.Presence.Editing != 2 || len(change.Presence.Editors) != 1 || change.Presence.Editors[0] != "Nell" {
+		t.Errorf("expected only shared names, got %+v", change.Presence)
+	}
+
+	// Setting the same presence again doesn't notify anyone
+	hub.SetPresence("a", otherId, true, "Nell")
+	select {
+	case change := <-c:
+		t.Errorf("got %+v", change)
+	default:
+	}
+
+	disconnectOther()
+	if change := <-c; change.Presence.Viewing != 2 || change.Presence.Editing != 1 || len(change.Presence.Editors) != 0 {
+		t.Errorf("expected the disconnected editor to be gone, got %+v", change.Presence)
+	}
+	if hub.SetPresence("a", otherId, false, "") {
+		t.Error("expected closed connections to be unknown")
+	}
+
+	// Subscribers that didn't connect for presence only get response changes
+	if len(plain) != 0 {
+		t.Errorf("got %d changes without presence", len(plain))
+	}
+	disconnect()
+	if presence := hub.GetPresence("a"); presence.Viewing != 1 || presence.Editing != 0 {
+		t.Errorf("got %+v", presence)
+	}
+}
+
+func TestPublishSelection(t *testing.T) {
+	hub := NewHub()
+	plain, unsubscribePlain := hub.Subscribe("a")
+	defer unsubscribePlain()
+	sender, senderId, disconnectSender := hub.Connect("a")
+	defer disconnectSender()
+	other, _, disconnectOther := hub.Connect("a")
+	defer disconnectOther()
+	hub.SetPresence("a", senderId, true, "Nell")
+	for len(sender) > 0 {
+		<-sender
+	}
+	for len(other) > 0 {
+		<-other
+	}
+
+	if !hub.PublishSelection("a", senderId, Selection{ParticipantId: senderId, Times: []int64{1000, 2000}, Mode: "available"}) {
+		t.Fatal("expected the connection to be open")
+	}
+	change := <-other
+	if change.Type != SELECTION_CHANGED || change.Selection.Name != "Nell" || len(change.Selection.Times) != 2 {
+		t.Errorf("got %+v", change)
+	}
+	if change.Selection.ParticipantId == senderId || len(change.Selection.ParticipantId) == 0 {
+		t.Errorf("expected the public id instead of the connection id, got %q", change.Selection.ParticipantId)
+	}
+	if len(sender) != 0 || len(plain) != 0 {
+		t.Errorf("expected only the other connections receiving presence to get the selection")
+	}
+
+	if hub.PublishSelection("a", "unknown", Selection{}) {
+		t.Error("expected unknown connections to be rejected")
+	}
+}
@@ -0,0 +1,78 @@
+// Validates where respondents are sent after submitting their availability, so
+// share links can't be used to redirect people to arbitrary sites
+package redirects
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+// Maximum length of the thank-you message shown after submitting
+const MAX_MESSAGE_LENGTH = 1000
+
+// Returns the domains respondents can be redirected to: the app's own domain,
+// the ones in REDIRECT_ALLOWED_DOMAINS, and the verified domains of the
+// organization (which can be nil)
+func GetAllowedDomains(org *models.Organization) []string {
+	domains := make([]string, 0)
+	if baseUrl, err := url.Parse(utils.GetBaseUrl()); err == nil {
+		domains = append(domains, baseUrl.Hostname())
+	}
+	for _, domain := range strings.Split(os.Getenv("REDIRECT_ALLOWED_DOMAINS"), ",") {
+		if domain = strings.TrimSpace(domain); len(domain) > 0 {
+			domains = append(domains, domain)
+		}
+	}
+	if org != nil {
+		for _, domain := range org.Domains {
+			if domain.VerifiedAt != nil {
+				domains = append(domains, domain.Domain)
+			}
+		}
+	}
+	return domains
+}
+
+// Returns whether the host is one of the allowed domains or a subdomain of one
+func isAllowedHost(host string, allowedDomains []string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, domain := range allowedDomains {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+		if len(domain) > 0 && (host == domain || strings.HasSuffix(host, "."+domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validates the settings, trimming the message and normalizing the redirect
+// URL. The URL has to be absolute, use https (or http for localhost), and be on
+// one of the allowed domains
+func Validate(postSubmission *models.PostSubmission, allowedDomains []string) error {
+	postSubmission.Message = strings.TrimSpace(postSubmission.Message)
+	if len(postSubmission.Message) > MAX_MESSAGE_LENGTH {
+		return errors.New("message is too long")
+	}
+
+	postSubmission.RedirectUrl = strings.TrimSpace(postSubmission.RedirectUrl)
+	if len(postSubmission.RedirectUrl) == 0 {
+		return nil
+	}
+	redirectUrl, err := url.Parse(postSubmission.RedirectUrl)
+	if err != nil || len(redirectUrl.Hostname()) == 0 || redirectUrl.User != nil {
+		return errors.New("redirectUrl must be an absolute url")
+	}
+	if redirectUrl.Scheme != "https" && !(redirectUrl.Scheme == "http" && redirectUrl.Hostname() == "localhost") {
+		return errors.New("redirectUrl must use https")
+	}
+	if !isAllowedHost(redirectUrl.Hostname(), allowedDomains) {
+		return errors.New("redirectUrl is not on an allowed domain")
+	}
+	postSubmission.RedirectUrl = redirectUrl.String()
+	return nil
+}
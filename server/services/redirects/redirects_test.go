@@ -0,0 +1,67 @@
+package redirects
+
+import (
+	"testing"
+
+	"schej.it/server/models"
+)
+
+func TestValidate(t *testing.T) {
+	allowed := []string{"school.edu", "timeful.app"}
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{"", true},
+		{"https://school.edu/thanks", true},
+		{" https://www.School.edu/thanks?from=poll ", true},
+		{"https://timeful.app", true},
+		{"http://localhost:8080/thanks", false},
+		{"http://school.edu/thanks", false},
+		{"https://evil.com/school.edu", false},
+		{"https://school.edu.evil.com", false},
+		{"https://evilschool.edu", false},
+		{"https://school.edu@evil.com", false},
+		{"//school.edu/thanks", false},
+		{"javascript:alert(1)", false},
+		{"/thanks", false},
+	}
+	for _, test := range tests {
+		postSubmission := models.PostSubmission{RedirectUrl: test.url}
+		if err := Validate(&postSubmission, allowed); (err == nil) != test.valid {
+			t.Errorf("Validate(%q) = %v, want valid %v", test.url, err, test.valid)
+		}
+	}
+}
+
+func TestValidateLocalhost(t *testing.T) {
+	postSubmission := models.PostSubmission{RedirectUrl: "http://localhost:8080/thanks"}
+	if err := Validate(&postSubmission, []string{"localhost"}); err != nil {
+		t.Errorf("expected localhost to be allowed over http, got %v", err)
+	}
+}
+
+func TestValidateMessage(t *testing.T) {
+	postSubmission := models.PostSubmission{Message: "  Thanks for responding!  "}
+	if err := Validate(&postSubmission, nil); err != nil || postSubmission.Message != "Thanks for responding!" {
+		t.Errorf("got %q, %v", postSubmission.Message, err)
+	}
+
+	long := make([]byte, MAX_MESSAGE_LENGTH+1)
+	for i := range long {
+		long[i] = 'a'
+	}
+	postSubmission = models.PostSubmission{Message: string(long)}
+	if err := Validate(&postSubmission, nil); err == nil {
+		t.Error("expected a message that is too long to be rejected")
+	}
+}
+
+func TestGetAllowedDomainsOnlyIncludesVerifiedDomains(t *testing.T) {
+	t.Setenv("REDIRECT_ALLOWED_DOMAINS", "partner.org, ")
+	org := &models.Organization{Domains: []models.OrganizationDomain{{Domain: "unverified.edu"}}}
+	domains := GetAllowedDomains(org)
+	if !isAllowedHost("partner.org", domains) || isAllowedHost("unverified.edu", domains) {
+		t.Errorf("got %v", domains)
+	}
+}
@@ -0,0 +1,106 @@
+// Lets the API run in multiple regions against a shared mongo cluster. Each
+// region reports a heartbeat with the latencies it measured to the calendar
+// providers, so work can be routed to the region that is closest to them
+package regions
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/models"
+)
+
+// How long a region counts as up after its last heartbeat
+const HEARTBEAT_TIMEOUT = 2 * time.Minute
+
+// How long tasks created in a region wait for it to run them before any other
+// region can
+const TAKEOVER_DELAY = 2 * time.Minute
+
+// Weight of the latest measurement in the average latency of a provider
+const latencyWeight = 0.2
+
+// Returns the region the server runs in, set with REGION, e.g. "us-east1".
+// Empty if the API is deployed in a single region
+func GetRegion() string {
+	return strings.TrimSpace(os.Getenv("REGION"))
+}
+
+var latencies = make(map[string]float64)
+var latenciesMutex sync.Mutex
+
+// Records how long a request to the calendar provider took, e.g. "google"
+func RecordLatency(provider string, duration time.Duration) {
+	ms := float64(duration) / float64(time.Millisecond)
+
+	latenciesMutex.Lock()
+	defer latenciesMutex.Unlock()
+	if average, ok := latencies[provider]; ok {
+		latencies[provider] = average + latencyWeight*(ms-average)
+	} else {
+		latencies[provider] = ms
+	}
+}
+
+// Returns the average latency of the requests to each calendar provider, in
+// milliseconds
+func GetLatencies() map[string]float64 {
+	latenciesMutex.Lock()
+	defer latenciesMutex.Unlock()
+	result := make(map[string]float64, len(latencies))
+	for provider, average := range latencies {
+		result[provider] = average
+	}
+	return result
+}
+
+// Reports the region's heartbeat and latencies. Run periodically by the jobs
+// scheduler
+func Report(now time.Time) {
+	region := GetRegion()
+	if len(region) == 0 {
+		return
+	}
+	db.UpsertRegion(&models.Region{
+		Name:              region,
+		HeartbeatAt:       primitive.NewDateTimeFromTime(now),
+		CalendarLatencies: GetLatencies(),
+	})
+}
+
+// Returns the region with the lowest latency to the calendar provider among
+// the ones that are up. Returns an empty string if no region measured it yet
+func GetPreferredRegion(regions []models.Region, provider string, now time.Time) string {
+	preferred := ""
+	lowest := 0.0
+	for _, region := range regions {
+		if now.Sub(region.HeartbeatAt.Time()) >= HEARTBEAT_TIMEOUT {
+			continue
+		}
+		latency, ok := region.CalendarLatencies[provider]
+		if !ok {
+			continue
+		}
+		if len(preferred) == 0 || latency < lowest || (latency == lowest && region.Name < preferred) {
+			preferred = region.Name
+			lowest = latency
+		}
+	}
+	return preferred
+}
+
+// Returns whether background work for the calendar provider should run in
+// this region, i.e. the API runs in a single region, this region is the
+// closest to the provider, or no region measured it yet
+func IsPreferred(provider string, now time.Time) bool {
+	region := GetRegion()
+	if len(region) == 0 {
+		return true
+	}
+	preferred := GetPreferredRegion(db.GetActiveRegions(now.Add(-HEARTBEAT_TIMEOUT)), provider, now)
+	return len(preferred) == 0 || preferred == region
+}
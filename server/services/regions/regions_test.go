@@ -0,0 +1,45 @@
+package regions
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+func TestGetPreferredRegion(t *testing.T) {
+	now := time.Date(2024, time.May, 14, 9, 0, 0, 0, time.UTC)
+	heartbeat := primitive.NewDateTimeFromTime(now.Add(-time.Minute))
+	regions := []models.Region{
+		{Name: "us-east1", HeartbeatAt: heartbeat, CalendarLatencies: map[string]float64{"google": 40, "outlook": 90}},
+		{Name: "europe-west1", HeartbeatAt: heartbeat, CalendarLatencies: map[string]float64{"google": 60, "outlook": 30}},
+		{Name: "asia-east1", HeartbeatAt: primitive.NewDateTimeFromTime(now.Add(-HEARTBEAT_TIMEOUT)), CalendarLatencies: map[string]float64{"google": 5}},
+	}
+
+	if got := GetPreferredRegion(regions, "google", now); got != "us-east1" {
+		t.Errorf("got %q for google, want us-east1 since asia-east1 is down", got)
+	}
+	if got := GetPreferredRegion(regions, "outlook", now); got != "europe-west1" {
+		t.Errorf("got %q for outlook, want europe-west1", got)
+	}
+	if got := GetPreferredRegion(regions, "apple", now); got != "" {
+		t.Errorf("got %q for a provider no region measured", got)
+	}
+}
+
+func TestRecordLatency(t *testing.T) {
+	RecordLatency("test", 100*time.Millisecond)
+	RecordLatency("test", 200*time.Millisecond)
+	if got := GetLatencies()["test"]; got != 120 {
+		t.Errorf("got an average of %v ms, want 120", got)
+	}
+}
+
+func TestIsPreferredWithoutRegion(t *testing.T) {
+	// Single region deployments run everything without touching the database
+	t.Setenv("REGION", "")
+	if !IsPreferred("google", time.Now()) {
+		t.Error("expected a single region to run everything")
+	}
+}
@@ -0,0 +1,112 @@
+// Computes when bookable resources (rooms, equipment) are busy, from their
+// bookings and linked calendars
+package resources
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/models"
+	"schej.it/server/services/calendar"
+)
+
+// A time range during which a resource is busy
+type Busy struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// Id of the event the resource is booked for, empty for events on the
+	// resource's linked calendar
+	EventId string `json:"eventId,omitempty"`
+}
+
+// Returns a mapping from resource id to the times the resource is busy
+// between timeMin and timeMax. Bookings made for ignoreEventId are skipped, so
+// an event can be moved without conflicting with itself
+func GetBusyTimes(resourcesList []models.Resource, timeMin time.Time, timeMax time.Time, ignoreEventId primitive.ObjectID) map[string][]Busy {
+	busy := make(map[string][]Busy)
+	resourceIds := make([]primitive.ObjectID, 0)
+	for _, resource := range resourcesList {
+		busy[resource.Id.Hex()] = make([]Busy, 0)
+		resourceIds = append(resourceIds, resource.Id)
+	}
+	if len(resourcesList) == 0 {
+		return busy
+	}
+
+	// Bookings made through Timeful
+	bookings := db.GetResourceBookings(resourceIds, primitive.NewDateTimeFromTime(timeMin), primitive.NewDateTimeFromTime(timeMax))
+	for _, booking := range bookings {
+		if booking.EventId == ignoreEventId {
+			continue
+		}
+		busy[booking.ResourceId.Hex()] = append(busy[booking.ResourceId.Hex()], Busy{
+			Start:   booking.StartDate.Time(),
+			End:     booking.EndDate.Time(),
+			EventId: booking.EventId.Hex(),
+		})
+	}
+
+	// Events on the linked calendars, which are read through the owners'
+	// calendar accounts
+	resourcesByOwner := make(map[primitive.ObjectID][]models.Resource)
+	for _, resource := range resourcesList {
+		if resource.Calendar != nil {
+			resourcesByOwner[resource.OwnerId] = append(resourcesByOwner[resource.OwnerId], resource)
+		}
+	}
+	for ownerId, ownedResources := range resourcesByOwner {
+		owner := db.GetUserById(ownerId.Hex())
+		if owner == nil {
+			continue
+		}
+		accounts := make(models.Set[string])
+		for _, resource := range ownedResources {
+			accounts[resource.Calendar.CalendarAccountKey] = struct{}{}
+		}
+		calendarEvents, _ := calendar.GetUsersCalendarEvents(owner, accounts, timeMin, timeMax)
+		for _, resource := range ownedResources {
+			events, ok := calendarEvents[resource.Calendar.CalendarAccountKey]
+			if !ok || events.Error != nil {
+				// Treat a resource whose calendar can't be read as busy
+				busy[resource.Id.Hex()] = append(busy[resource.Id.Hex()], Busy{Start: timeMin, End: timeMax})
+				continue
+			}
+			for _, calendarEvent := range events.CalendarEvents {
+				if calendarEvent.CalendarId != resource.Calendar.CalendarId || calendarEvent.Free {
+					continue
+				}
+				busy[resource.Id.Hex()] = append(busy[resource.Id.Hex()], Busy{
+					Start: calendarEvent.StartDate.Time(),
+					End:   calendarEvent.EndDate.Time(),
+				})
+			}
+		}
+	}
+
+	return busy
+}
+
+// Returns whether none of the busy times overlap the time range
+func IsFree(busy []Busy, start time.Time, end time.Time) bool {
+	for _, b := range busy {
+		if b.Start.Before(end) && b.End.After(start) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a function that excludes the slots during which any of the
+// resources is busy, to be used with the suggestion engine
+func ExcludeBusy(busy map[string][]Busy) func(start time.Time, end time.Time) bool {
+	return func(start time.Time, end time.Time) bool {
+		for _, resourceBusy := range busy {
+			if !IsFree(resourceBusy, start, end) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,45 @@
+package resources
+
+import (
+	"testing"
+	"time"
+)
+
+var day = time.Date(2024, time.May, 14, 0, 0, 0, 0, time.UTC)
+
+func hour(h float64) time.Time {
+	return day.Add(time.Duration(h * float64(time.Hour)))
+}
+
+func TestIsFree(t *testing.T) {
+	busy := []Busy{{Start: hour(10), End: hour(11)}, {Start: hour(14), End: hour(15.5)}}
+	tests := []struct {
+		start, end float64
+		want       bool
+	}{
+		{9, 10, true},
+		{9.5, 10.5, false},
+		{11, 14, true},
+		{15, 16, false},
+		{13, 17, false},
+		{15.5, 16, true},
+	}
+	for _, test := range tests {
+		if got := IsFree(busy, hour(test.start), hour(test.end)); got != test.want {
+			t.Errorf("IsFree(%v, %v) = %v, want %v", test.start, test.end, got, test.want)
+		}
+	}
+}
+
+func TestExcludeBusy(t *testing.T) {
+	exclude := ExcludeBusy(map[string][]Busy{
+		"room":      {{Start: hour(10), End: hour(11)}},
+		"projector": {{Start: hour(13), End: hour(14)}},
+	})
+	if !exclude(hour(10), hour(11)) || !exclude(hour(12), hour(13.5)) {
+		t.Error("expected busy slots to be excluded")
+	}
+	if exclude(hour(11), hour(13)) {
+		t.Error("expected free slot not to be excluded")
+	}
+}
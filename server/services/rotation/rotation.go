@@ -0,0 +1,172 @@
+// Rotates the time of a recurring meeting across timezones, so that the
+// inconvenient hours are shared fairly across regions over the occurrences
+package rotation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+var timeRegex = regexp.MustCompile(`^([01]\d|2[0-3]):[0-5]\d$`)
+
+// Convenient hours of regions without working hours
+var defaultWorkingHours = models.WorkingHours{StartTime: "09:00", EndTime: "17:00"}
+
+// Local hours that are inconvenient even outside working hours, and count
+// double (i.e. 22:00 to 07:00)
+const (
+	nightStart  = 22
+	nightEnd    = 7
+	nightFactor = 2
+)
+
+// Granularity of the inconvenience calculation
+const step = 15 * time.Minute
+
+// Validates the rotation, defaulting the weights of the regions
+func Validate(rotation *models.Rotation) error {
+	if len(rotation.Regions) == 0 || len(rotation.CandidateTimes) == 0 {
+		return errors.New("rotation needs regions and candidate times")
+	}
+	if rotation.DurationMinutes <= 0 || rotation.IntervalDays <= 0 {
+		return errors.New("durationMinutes and intervalDays must be positive")
+	}
+
+	names := make(models.Set[string])
+	for i := range rotation.Regions {
+		region := &rotation.Regions[i]
+		if _, ok := names[region.Name]; ok || len(region.Name) == 0 {
+			return fmt.Errorf("invalid region %q", region.Name)
+		}
+		names[region.Name] = struct{}{}
+		if _, err := time.LoadLocation(region.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q", region.Timezone)
+		}
+		if region.WorkingHours != nil && (!timeRegex.MatchString(region.WorkingHours.StartTime) || !timeRegex.MatchString(region.WorkingHours.EndTime) || region.WorkingHours.StartTime >= region.WorkingHours.EndTime) {
+			return errors.New("working hours must be a HH:MM range")
+		}
+		if region.Weight < 0 {
+			return fmt.Errorf("invalid weight for region %q", region.Name)
+		}
+		if region.Weight == 0 {
+			region.Weight = 1
+		}
+	}
+	for _, candidate := range rotation.CandidateTimes {
+		if !timeRegex.MatchString(candidate) {
+			return fmt.Errorf("invalid candidate time %q", candidate)
+		}
+	}
+	return nil
+}
+
+// Returns how inconvenient the time range is for the region: the number of
+// hours outside its working hours, with night hours counting double
+func GetInconvenience(region models.RotationRegion, start time.Time, end time.Time) float64 {
+	loc, err := time.LoadLocation(region.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	workingHours := defaultWorkingHours
+	if region.WorkingHours != nil {
+		workingHours = *region.WorkingHours
+	}
+	workStart, workEnd := toHours(workingHours.StartTime), toHours(workingHours.EndTime)
+
+	inconvenience := 0.0
+	for t := start; t.Before(end); t = t.Add(step) {
+		local := t.In(loc)
+		hour := float64(local.Hour()) + float64(local.Minute())/60
+		if hour >= workStart && hour < workEnd {
+			continue
+		}
+		cost := step.Hours()
+		if hour >= nightStart || hour < nightEnd {
+			cost *= nightFactor
+		}
+		inconvenience += cost
+	}
+	return inconvenience
+}
+
+// Returns the total inconvenience of the scheduled occurrences for each region
+func GetBurden(rotation *models.Rotation) map[string]float64 {
+	burden := make(map[string]float64)
+	for _, region := range rotation.Regions {
+		burden[region.Name] = 0
+	}
+	for _, occurrence := range rotation.History {
+		for name, inconvenience := range occurrence.Inconvenience {
+			if _, ok := burden[name]; ok {
+				burden[name] += inconvenience
+			}
+		}
+	}
+	return burden
+}
+
+// Returns the next count occurrences after the scheduled ones. Each
+// occurrence is at the candidate time that keeps the largest weighted
+// total inconvenience of any region (taking the history into account) as
+// small as possible, then the one with the least weighted inconvenience
+// overall, then the earliest candidate
+func Plan(rotation *models.Rotation, count int) []models.RotationOccurrence {
+	burden := GetBurden(rotation)
+	duration := time.Duration(rotation.DurationMinutes) * time.Minute
+
+	occurrences := make([]models.RotationOccurrence, 0)
+	for i := 0; i < count; i++ {
+		day := rotation.StartDate.Time().UTC().AddDate(0, 0, (len(rotation.History)+i)*rotation.IntervalDays)
+		day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+
+		var best *models.RotationOccurrence
+		bestMax, bestTotal := math.Inf(1), math.Inf(1)
+		for _, candidate := range rotation.CandidateTimes {
+			start := day.Add(time.Duration(toHours(candidate) * float64(time.Hour)))
+			end := start.Add(duration)
+			occurrence := models.RotationOccurrence{
+				StartDate:     primitive.NewDateTimeFromTime(start),
+				EndDate:       primitive.NewDateTimeFromTime(end),
+				Inconvenience: make(map[string]float64),
+			}
+
+			maxBurden, total := 0.0, 0.0
+			for _, region := range rotation.Regions {
+				inconvenience := GetInconvenience(region, start, end)
+				occurrence.Inconvenience[region.Name] = inconvenience
+				maxBurden = math.Max(maxBurden, getWeight(region)*(burden[region.Name]+inconvenience))
+				total += getWeight(region) * inconvenience
+			}
+			if maxBurden < bestMax || (maxBurden == bestMax && total < bestTotal) {
+				best, bestMax, bestTotal = &occurrence, maxBurden, total
+			}
+		}
+
+		for name, inconvenience := range best.Inconvenience {
+			burden[name] += inconvenience
+		}
+		occurrences = append(occurrences, *best)
+	}
+	return occurrences
+}
+
+func getWeight(region models.RotationRegion) float64 {
+	if region.Weight <= 0 {
+		return 1
+	}
+	return region.Weight
+}
+
+// Converts a "HH:MM" time to hours
+func toHours(hhmm string) float64 {
+	hours, _ := strconv.Atoi(hhmm[:2])
+	minutes, _ := strconv.Atoi(hhmm[3:])
+	return float64(hours) + float64(minutes)/60
+}
@@ -0,0 +1,91 @@
+package rotation
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+)
+
+var day = time.Date(2024, time.May, 14, 0, 0, 0, 0, time.UTC)
+
+func newRotation() *models.Rotation {
+	return &models.Rotation{
+		Regions: []models.RotationRegion{
+			{Name: "us", Timezone: "America/New_York"},
+			{Name: "asia", Timezone: "Asia/Tokyo"},
+		},
+		// 10am in New York and 11pm in Tokyo, or 11pm in New York and noon in Tokyo
+		CandidateTimes:  []string{"14:00", "03:00"},
+		DurationMinutes: 60,
+		StartDate:       primitive.NewDateTimeFromTime(day),
+		IntervalDays:    7,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	rotation := newRotation()
+	if err := Validate(rotation); err != nil {
+		t.Fatal(err)
+	}
+	if rotation.Regions[0].Weight != 1 {
+		t.Errorf("expected weight to default to 1, got %v", rotation.Regions[0].Weight)
+	}
+
+	rotation.Regions[1].Timezone = "Mars/Olympus"
+	if err := Validate(rotation); err == nil {
+		t.Error("expected invalid timezone to fail")
+	}
+	rotation = newRotation()
+	rotation.CandidateTimes = []string{"25:00"}
+	if err := Validate(rotation); err == nil {
+		t.Error("expected invalid candidate time to fail")
+	}
+}
+
+func TestGetInconvenience(t *testing.T) {
+	region := models.RotationRegion{Name: "us", Timezone: "America/New_York"}
+
+	// 9am in New York
+	if got := GetInconvenience(region, day.Add(13*time.Hour), day.Add(14*time.Hour)); got != 0 {
+		t.Errorf("expected no inconvenience, got %v", got)
+	}
+	// 6pm in New York
+	if got := GetInconvenience(region, day.Add(22*time.Hour), day.Add(23*time.Hour)); got != 1 {
+		t.Errorf("expected 1 hour of inconvenience, got %v", got)
+	}
+	// 9pm to 11pm in New York, where the last hour is at night
+	if got := GetInconvenience(region, day.Add(25*time.Hour), day.Add(27*time.Hour)); got != 3 {
+		t.Errorf("expected 3 hours of inconvenience, got %v", got)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	rotation := newRotation()
+	occurrences := Plan(rotation, 4)
+	if len(occurrences) != 4 {
+		t.Fatalf("expected 4 occurrences, got %d", len(occurrences))
+	}
+
+	// The times alternate, so each region gets the inconvenient time twice
+	for i := 1; i < len(occurrences); i++ {
+		if occurrences[i].StartDate.Time().Hour() == occurrences[i-1].StartDate.Time().Hour() {
+			t.Errorf("expected times to rotate, got %v then %v", occurrences[i-1].StartDate.Time(), occurrences[i].StartDate.Time())
+		}
+	}
+	if !occurrences[1].StartDate.Time().After(occurrences[0].StartDate.Time().Add(6 * 24 * time.Hour)) {
+		t.Errorf("expected occurrences a week apart")
+	}
+
+	// History is taken into account
+	rotation.History = occurrences[:1]
+	next := Plan(rotation, 1)
+	if next[0].StartDate.Time().Hour() == occurrences[0].StartDate.Time().Hour() {
+		t.Errorf("expected the next occurrence to rotate, got %v", next[0].StartDate.Time())
+	}
+	burden := GetBurden(rotation)
+	if burden["us"]+burden["asia"] == 0 {
+		t.Errorf("expected the first occurrence to burden a region, got %v", burden)
+	}
+}
@@ -0,0 +1,77 @@
+// Runtime config of the instance that is safe to expose to the frontend, so
+// that self-hosters don't have to rebuild the frontend for their environment.
+// Only public identifiers belong here, never secrets
+package runtimeconfig
+
+import (
+	"os"
+
+	"schej.it/server/services/duplicates"
+	"schej.it/server/services/llm"
+	"schej.it/server/services/privacy"
+)
+
+type Config struct {
+	// OAuth client ids used to build the sign in URLs
+	GoogleClientId    string `json:"googleClientId"`
+	MicrosoftClientId string `json:"microsoftClientId"`
+
+	// Stripe publishable key, set with STRIPE_PUBLISHABLE_KEY
+	StripePublishableKey string `json:"stripePublishableKey"`
+
+	// PostHog project key, set with POSTHOG_API_KEY
+	PosthogApiKey string `json:"posthogApiKey"`
+
+	Features Features `json:"features"`
+	Branding Branding `json:"branding"`
+}
+
+// Optional features, which the frontend hides when they're disabled
+type Features struct {
+	Payments            bool `json:"payments"`
+	SchedulingAssistant bool `json:"schedulingAssistant"`
+	EventParser         bool `json:"eventParser"`
+	DuplicateDetection  bool `json:"duplicateDetection"`
+	Slack               bool `json:"slack"`
+	Discord             bool `json:"discord"`
+
+	// Whether personal data is minimized, in which case guests aren't asked
+	// for their email
+	PiiMinimization bool `json:"piiMinimization"`
+}
+
+// Branding of the instance, set with INSTANCE_NAME, INSTANCE_LOGO_URL and
+// INSTANCE_PRIMARY_COLOR. Empty values use the frontend's defaults
+type Branding struct {
+	Name         string `json:"name"`
+	LogoUrl      string `json:"logoUrl"`
+	PrimaryColor string `json:"primaryColor"` // e.g. "#00994c"
+}
+
+// Returns the runtime config of the instance from the environment
+func Get() Config {
+	llmEnabled := func(flag string) bool {
+		return os.Getenv(flag) == "true" && llm.IsConfigured()
+	}
+
+	return Config{
+		GoogleClientId:       os.Getenv("CLIENT_ID"),
+		MicrosoftClientId:    os.Getenv("MICROSOFT_CLIENT_ID"),
+		StripePublishableKey: os.Getenv("STRIPE_PUBLISHABLE_KEY"),
+		PosthogApiKey:        os.Getenv("POSTHOG_API_KEY"),
+		Features: Features{
+			Payments:            os.Getenv("STRIPE_API_KEY") != "",
+			SchedulingAssistant: llmEnabled("SCHEDULING_ASSISTANT_LLM_ENABLED"),
+			EventParser:         llmEnabled("EVENT_PARSER_LLM_ENABLED"),
+			DuplicateDetection:  duplicates.Enabled(),
+			Slack:               os.Getenv("SLACK_BOT_TOKEN") != "",
+			Discord:             os.Getenv("DISCORD_BOT_TOKEN") != "",
+			PiiMinimization:     privacy.IsMinimized(),
+		},
+		Branding: Branding{
+			Name:         os.Getenv("INSTANCE_NAME"),
+			LogoUrl:      os.Getenv("INSTANCE_LOGO_URL"),
+			PrimaryColor: os.Getenv("INSTANCE_PRIMARY_COLOR"),
+		},
+	}
+}
@@ -0,0 +1,34 @@
+package runtimeconfig
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	t.Setenv("CLIENT_ID", "google-client-id")
+	t.Setenv("CLIENT_SECRET", "google-client-secret")
+	t.Setenv("STRIPE_API_KEY", "sk_test_secret")
+	t.Setenv("STRIPE_PUBLISHABLE_KEY", "pk_test_public")
+	t.Setenv("SCHEDULING_ASSISTANT_LLM_ENABLED", "true")
+	t.Setenv("LLM_API_KEY", "llm-secret")
+	t.Setenv("EVENT_PARSER_LLM_ENABLED", "")
+	t.Setenv("INSTANCE_NAME", "Acme Scheduling")
+
+	config := Get()
+	if config.GoogleClientId != "google-client-id" || config.StripePublishableKey != "pk_test_public" || config.Branding.Name != "Acme Scheduling" {
+		t.Errorf("got %+v", config)
+	}
+	if !config.Features.Payments || !config.Features.SchedulingAssistant || config.Features.EventParser {
+		t.Errorf("got features %+v", config.Features)
+	}
+
+	// Secrets are never exposed
+	body, _ := json.Marshal(config)
+	for _, secret := range []string{"google-client-secret", "sk_test_secret", "llm-secret"} {
+		if strings.Contains(string(body), secret) {
+			t.Errorf("config exposes %s", secret)
+		}
+	}
+}
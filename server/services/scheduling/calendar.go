@@ -0,0 +1,93 @@
+package scheduling
+
+import (
+	"time"
+
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services/calendar"
+	"schej.it/server/utils"
+)
+
+// Removes the time increments that overlap busy events on the respondents'
+// connected calendars from their availability, for respondents that use
+// calendar availability
+func RemoveCalendarBusyTimes(event *models.Event, eventResponses []models.EventResponse, respondents []Respondent, timeMin time.Time, timeMax time.Time) {
+	respondentsById := make(map[string]*Respondent)
+	for i := range respondents {
+		respondentsById[respondents[i].Id] = &respondents[i]
+	}
+
+	type result struct {
+		respondent *Respondent
+		events     []models.CalendarEvent
+	}
+	results := make(chan result)
+	numRequests := 0
+	for _, eventResponse := range eventResponses {
+		response := eventResponse.Response
+		respondent, ok := respondentsById[eventResponse.UserId]
+		if !ok || response == nil || !utils.Coalesce(response.UseCalendarAvailability) {
+			continue
+		}
+		user := db.GetUserById(eventResponse.UserId)
+		if user == nil {
+			continue
+		}
+
+		// Only consider the calendars enabled for this event
+		enabledAccounts := make([]string, 0)
+		enabledCalendarIds := make([]string, 0)
+		for calendarAccountKey, calendarIds := range utils.Coalesce(response.EnabledCalendars) {
+			enabledAccounts = append(enabledAccounts, calendarAccountKey)
+			enabledCalendarIds = append(enabledCalendarIds, calendarIds...)
+		}
+		calendarIdsSet := utils.ArrayToSet(enabledCalendarIds)
+
+		numRequests++
+		go func(user *models.User, respondent *Respondent) {
+			busy := make([]models.CalendarEvent, 0)
+
+			// Recover from panics, always sending a result so the caller doesn't block
+			defer func() {
+				if err := recover(); err != nil {
+					logger.StdErr.Println(err)
+				}
+				results <- result{respondent, busy}
+			}()
+
+			calendarEvents, _ := calendar.GetUsersCalendarEvents(user, utils.ArrayToSet(enabledAccounts), timeMin, timeMax)
+			for _, events := range calendarEvents {
+				for _, calendarEvent := range events.CalendarEvents {
+					if _, ok := calendarIdsSet[calendarEvent.CalendarId]; !ok || calendarEvent.Free || calendarEvent.AllDay {
+						continue
+					}
+					busy = append(busy, calendarEvent)
+				}
+			}
+		}(user, respondent)
+	}
+
+	increment := GetTimeIncrement(event)
+	for i := 0; i < numRequests; i++ {
+		r := <-results
+		for _, calendarEvent := range r.events {
+			r.respondent.RemoveAvailability(calendarEvent.StartDate.Time(), calendarEvent.EndDate.Time(), increment)
+		}
+	}
+}
+
+// Removes the time increments overlapping the given time range from the
+// respondent's availability
+func (r *Respondent) RemoveAvailability(start time.Time, end time.Time, increment time.Duration) {
+	startMillis := start.Add(-increment).UnixMilli()
+	endMillis := end.UnixMilli()
+	for _, set := range []models.Set[int64]{r.Available, r.IfNeeded} {
+		for t := range set {
+			if t > startMillis && t < endMillis {
+				delete(set, t)
+			}
+		}
+	}
+}
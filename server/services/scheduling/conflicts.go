@@ -0,0 +1,92 @@
+package scheduling
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/models"
+	"schej.it/server/services/calendar"
+	"schej.it/server/utils"
+)
+
+type ConflictType string
+
+const (
+	// Another event of the organizer scheduled at an overlapping time
+	EventConflict ConflictType = "event"
+
+	// An entry on one of the organizer's enabled calendars
+	CalendarConflict ConflictType = "calendar"
+)
+
+// Something the organizer already has planned at an overlapping time
+type Conflict struct {
+	Type      ConflictType       `json:"type"`
+	EventId   string             `json:"eventId,omitempty"`
+	Summary   string             `json:"summary"`
+	StartDate primitive.DateTime `json:"startDate"`
+	EndDate   primitive.DateTime `json:"endDate"`
+}
+
+// Returns the organizer's other scheduled events and calendar entries that
+// overlap the given time range, ignoring the given event itself
+func FindConflicts(user *models.User, event *models.Event, start time.Time, end time.Time) []Conflict {
+	conflicts := make([]Conflict, 0)
+
+	for _, other := range db.GetScheduledEventsInRange(user.Id, start, end) {
+		if other.Id == event.Id {
+			continue
+		}
+		conflicts = append(conflicts, Conflict{
+			Type:      EventConflict,
+			EventId:   other.GetId(),
+			Summary:   other.Name,
+			StartDate: other.ScheduledEvent.StartDate,
+			EndDate:   other.ScheduledEvent.EndDate,
+		})
+	}
+
+	// Only look at enabled calendar accounts and sub calendars
+	enabledAccounts := make([]string, 0)
+	enabledCalendarIds := make([]string, 0)
+	for calendarAccountKey, account := range user.CalendarAccounts {
+		if !utils.Coalesce(account.Enabled) {
+			continue
+		}
+		enabledAccounts = append(enabledAccounts, calendarAccountKey)
+		for calendarId, subCalendar := range utils.Coalesce(account.SubCalendars) {
+			if utils.Coalesce(subCalendar.Enabled) {
+				enabledCalendarIds = append(enabledCalendarIds, calendarId)
+			}
+		}
+	}
+	if len(enabledAccounts) == 0 {
+		return conflicts
+	}
+	calendarIdsSet := utils.ArrayToSet(enabledCalendarIds)
+
+	calendarEvents, _ := calendar.GetUsersCalendarEvents(user, utils.ArrayToSet(enabledAccounts), start, end)
+	for _, events := range calendarEvents {
+		for _, calendarEvent := range events.CalendarEvents {
+			if _, ok := calendarIdsSet[calendarEvent.CalendarId]; !ok || calendarEvent.Free || calendarEvent.AllDay {
+				continue
+			}
+			// Skip the calendar event created for this event
+			if len(event.CalendarEventId) > 0 && calendarEvent.Id == event.CalendarEventId {
+				continue
+			}
+			if !calendarEvent.StartDate.Time().Before(end) || !calendarEvent.EndDate.Time().After(start) {
+				continue
+			}
+			conflicts = append(conflicts, Conflict{
+				Type:      CalendarConflict,
+				Summary:   calendarEvent.Summary,
+				StartDate: calendarEvent.StartDate,
+				EndDate:   calendarEvent.EndDate,
+			})
+		}
+	}
+
+	return conflicts
+}
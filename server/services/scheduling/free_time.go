@@ -0,0 +1,169 @@
+package scheduling
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"schej.it/server/db"
+	"schej.it/server/models"
+	"schej.it/server/services/calendar"
+	"schej.it/server/utils"
+)
+
+// Working hours used when a user hasn't set their own
+var defaultWorkingHours = models.WorkingHoursOptions{Enabled: true, StartTime: 9, EndTime: 17}
+
+// A span of time, used for both busy and free times
+type TimeRange struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Returns the times the user is busy between start and end, from their
+// scheduled events and the enabled calendars of their calendar accounts. Only
+// the times are returned, so that nothing about the entries is revealed
+func GetBusyTimes(user *models.User, start time.Time, end time.Time) []TimeRange {
+	busy := make([]TimeRange, 0)
+	for _, event := range db.GetScheduledEventsInRange(user.Id, start, end) {
+		busy = append(busy, TimeRange{Start: event.ScheduledEvent.StartDate.Time(), End: event.ScheduledEvent.EndDate.Time()})
+	}
+
+	enabledAccounts := make([]string, 0)
+	enabledCalendarIds := make([]string, 0)
+	for calendarAccountKey, account := range user.CalendarAccounts {
+		if !utils.Coalesce(account.Enabled) {
+			continue
+		}
+		enabledAccounts = append(enabledAccounts, calendarAccountKey)
+		for calendarId, subCalendar := range utils.Coalesce(account.SubCalendars) {
+			if utils.Coalesce(subCalendar.Enabled) {
+				enabledCalendarIds = append(enabledCalendarIds, calendarId)
+			}
+		}
+	}
+	if len(enabledAccounts) == 0 {
+		return busy
+	}
+	calendarIdsSet := utils.ArrayToSet(enabledCalendarIds)
+
+	calendarEvents, _ := calendar.GetUsersCalendarEvents(user, utils.ArrayToSet(enabledAccounts), start, end)
+	for _, events := range calendarEvents {
+		for _, calendarEvent := range events.CalendarEvents {
+			if _, ok := calendarIdsSet[calendarEvent.CalendarId]; !ok || calendarEvent.Free || calendarEvent.AllDay {
+				continue
+			}
+			busy = append(busy, TimeRange{Start: calendarEvent.StartDate.Time(), End: calendarEvent.EndDate.Time()})
+		}
+	}
+	return busy
+}
+
+// Returns the user's working hours on each day between start and end, in
+// their timezone. Users that haven't enabled working hours get the default ones
+func GetWorkingHours(user *models.User, start time.Time, end time.Time) []TimeRange {
+	workingHours := defaultWorkingHours
+	if user.CalendarOptions != nil && user.CalendarOptions.WorkingHours.Enabled {
+		workingHours = user.CalendarOptions.WorkingHours
+	}
+	loc := utils.GetUserLocation(user)
+
+	ranges := make([]TimeRange, 0)
+	localStart := start.In(loc)
+	for day := time.Date(localStart.Year(), localStart.Month(), localStart.Day(), 0, 0, 0, 0, loc); day.Before(end); day = day.AddDate(0, 0, 1) {
+		dayStart := day.Add(time.Duration(math.Round(float64(workingHours.StartTime)*60)) * time.Minute).UTC()
+		dayEnd := day.Add(time.Duration(math.Round(float64(workingHours.EndTime)*60)) * time.Minute).UTC()
+		if !dayEnd.After(dayStart) {
+			// Working hours that span midnight
+			dayEnd = dayEnd.Add(24 * time.Hour)
+		}
+		ranges = append(ranges, TimeRange{Start: dayStart, End: dayEnd})
+	}
+	return IntersectTimes(ranges, []TimeRange{{Start: start, End: end}})
+}
+
+// Returns the times within every one of the given lists of ranges
+func IntersectTimes(lists ...[]TimeRange) []TimeRange {
+	if len(lists) == 0 {
+		return make([]TimeRange, 0)
+	}
+	result := mergeTimes(lists[0])
+	for _, list := range lists[1:] {
+		other := mergeTimes(list)
+		intersection := make([]TimeRange, 0)
+		i, j := 0, 0
+		for i < len(result) && j < len(other) {
+			start, end := result[i].Start, result[i].End
+			if other[j].Start.After(start) {
+				start = other[j].Start
+			}
+			if other[j].End.Before(end) {
+				end = other[j].End
+			}
+			if end.After(start) {
+				intersection = append(intersection, TimeRange{Start: start, End: end})
+			}
+			if result[i].End.Before(other[j].End) {
+				i++
+			} else {
+				j++
+			}
+		}
+		result = intersection
+	}
+	return result
+}
+
+// Returns the parts of the allowed times that aren't busy and are at least
+// minDuration long
+func GetFreeTimes(allowed []TimeRange, busy []TimeRange, minDuration time.Duration) []TimeRange {
+	busy = mergeTimes(busy)
+	free := make([]TimeRange, 0)
+	for _, window := range mergeTimes(allowed) {
+		start := window.Start
+		for _, b := range busy {
+			if !b.End.After(start) {
+				continue
+			}
+			if !b.Start.Before(window.End) {
+				break
+			}
+			if b.Start.After(start) {
+				free = append(free, TimeRange{Start: start, End: b.Start})
+			}
+			start = b.End
+		}
+		if window.End.After(start) {
+			free = append(free, TimeRange{Start: start, End: window.End})
+		}
+	}
+
+	long := make([]TimeRange, 0)
+	for _, window := range free {
+		if window.End.Sub(window.Start) >= minDuration {
+			long = append(long, window)
+		}
+	}
+	return long
+}
+
+// Returns the ranges sorted, with overlapping and adjacent ones combined
+func mergeTimes(ranges []TimeRange) []TimeRange {
+	sorted := append(make([]TimeRange, 0, len(ranges)), ranges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	merged := make([]TimeRange, 0)
+	for _, r := range sorted {
+		if !r.End.After(r.Start) {
+			continue
+		}
+		if len(merged) > 0 && !r.Start.After(merged[len(merged)-1].End) {
+			if r.End.After(merged[len(merged)-1].End) {
+				merged[len(merged)-1].End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
@@ -0,0 +1,56 @@
+package scheduling_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/services/scheduling/schedulingtest"
+)
+
+func timeRange(startHour int, endHour int) scheduling.TimeRange {
+	return scheduling.TimeRange{Start: schedulingtest.Hour(startHour), End: schedulingtest.Hour(endHour)}
+}
+
+func TestIntersectTimes(t *testing.T) {
+	got := scheduling.IntersectTimes(
+		[]scheduling.TimeRange{timeRange(9, 12), timeRange(14, 18)},
+		[]scheduling.TimeRange{timeRange(11, 15)},
+	)
+	want := []scheduling.TimeRange{timeRange(11, 12), timeRange(14, 15)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestGetFreeTimes(t *testing.T) {
+	allowed := []scheduling.TimeRange{timeRange(9, 17)}
+	busy := []scheduling.TimeRange{timeRange(10, 11), timeRange(10, 12), timeRange(13, 16), {Start: schedulingtest.Hour(16), End: schedulingtest.Hour(16).Add(30 * time.Minute)}}
+
+	got := scheduling.GetFreeTimes(allowed, busy, time.Hour)
+	want := []scheduling.TimeRange{timeRange(9, 10), timeRange(12, 13)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Shorter windows are kept with a shorter minimum
+	if got := scheduling.GetFreeTimes(allowed, busy, 30*time.Minute); len(got) != 3 {
+		t.Errorf("got %d free times, want 3", len(got))
+	}
+}
+
+func TestGetWorkingHours(t *testing.T) {
+	user := &models.User{CalendarOptions: &models.CalendarOptions{
+		WorkingHours: models.WorkingHoursOptions{Enabled: true, StartTime: 8.5, EndTime: 12},
+	}}
+	got := scheduling.GetWorkingHours(user, schedulingtest.Hour(9), schedulingtest.Hour(9).Add(24*time.Hour))
+	want := []scheduling.TimeRange{
+		{Start: schedulingtest.Hour(9), End: schedulingtest.Hour(12)},
+		{Start: schedulingtest.Hour(8).Add(24*time.Hour + 30*time.Minute), End: schedulingtest.Hour(9).Add(24 * time.Hour)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,368 @@
+// Computes and ranks candidate meeting slots from an event's responses
+package scheduling
+
+import (
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+// Default time increment of the availability grid, in minutes
+const defaultTimeIncrement = 15
+
+// Weight of an "if needed" response relative to an available response
+const DefaultIfNeededWeight = 0.5
+
+// A respondent's availability for an event
+type Respondent struct {
+	// User id for signed in users, name for guests (i.e. the key of the responses map)
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"-"`
+
+	// Sets of the unix milliseconds of the time increments the respondent is available for
+	Available models.Set[int64] `json:"-"`
+	IfNeeded  models.Set[int64] `json:"-"`
+
+	// Values of the event's respondent fields, mapping field id to the value
+	Fields map[string]string `json:"-"`
+}
+
+// Respondents that have the same value for a respondent field
+type RespondentGroup struct {
+	Value       string
+	Respondents []Respondent
+}
+
+// A candidate slot for the meeting, and who can make it
+type Slot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// Ids of the respondents that are available, available if needed, or unavailable
+	Available   []string `json:"available"`
+	IfNeeded    []string `json:"ifNeeded"`
+	Unavailable []string `json:"unavailable"`
+
+	// Ids of the required respondents that are unavailable
+	MissingRequired []string `json:"missingRequired"`
+
+	Score float64 `json:"score"`
+}
+
+// Options used to rank slots
+type Options struct {
+	// Length of the meeting. Defaults to the time increment of the event
+	MeetingLength time.Duration
+
+	// Ids of the respondents that must be able to make it
+	Required models.Set[string]
+
+	// Weight of each respondent, defaults to 1
+	Weights map[string]float64
+
+	// Weight of an "if needed" response relative to an available response
+	IfNeededWeight *float64
+
+	// Slots for which Exclude returns true are not considered
+	Exclude func(start time.Time, end time.Time) bool
+}
+
+// Returns the respondents of the given event responses, populating the names
+// of signed in users
+func GetRespondents(eventResponses []models.EventResponse) []Respondent {
+	userIds := make([]string, 0)
+	for _, eventResponse := range eventResponses {
+		userIds = append(userIds, eventResponse.UserId)
+	}
+	users := db.GetUsersByIds(userIds)
+
+	respondents := make([]Respondent, 0)
+	for _, eventResponse := range eventResponses {
+		response := eventResponse.Response
+		if response == nil {
+			continue
+		}
+
+		respondent := Respondent{
+			Id:        eventResponse.UserId,
+			Name:      response.Name,
+			Email:     response.Email,
+			Available: toSet(response.Availability),
+			IfNeeded:  toSet(response.IfNeeded),
+			Fields:    response.Fields,
+		}
+		if user, ok := users[eventResponse.UserId]; ok {
+			respondent.Name = user.FirstName
+			if len(user.LastName) > 0 {
+				respondent.Name += " " + user.LastName
+			}
+			respondent.Email = user.Email
+		} else if len(response.Name) == 0 {
+			// User was deleted
+			continue
+		}
+
+		respondents = append(respondents, respondent)
+	}
+
+	return respondents
+}
+
+// Returns the time increment of the event's availability grid
+func GetTimeIncrement(event *models.Event) time.Duration {
+	increment := utils.Coalesce(event.TimeIncrement)
+	if increment <= 0 {
+		increment = defaultTimeIncrement
+	}
+	return time.Duration(increment) * time.Minute
+}
+
+// Returns an Exclude function that excludes the slots starting before now
+func ExcludePast(event *models.Event, now time.Time) func(start time.Time, end time.Time) bool {
+	return func(start time.Time, end time.Time) bool {
+		// Days of the week events don't have real dates
+		return event.Type != models.DOW && start.Before(now)
+	}
+}
+
+// Returns the start times of all the time increments on the event's grid, sorted
+func GetTimeIncrements(event *models.Event) []time.Time {
+	times := make([]time.Time, 0)
+	if utils.Coalesce(event.DaysOnly) {
+		for _, date := range event.Dates {
+			times = append(times, date.Time())
+		}
+	} else if utils.Coalesce(event.HasSpecificTimes) {
+		for _, t := range event.Times {
+			times = append(times, t.Time())
+		}
+	} else {
+		increment := GetTimeIncrement(event)
+		duration := time.Duration(float64(utils.Coalesce(event.Duration)) * float64(time.Hour))
+		for _, date := range event.Dates {
+			start := date.Time()
+			for t := start; t.Before(start.Add(duration)); t = t.Add(increment) {
+				times = append(times, t)
+			}
+		}
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	return times
+}
+
+// Returns all the slots of the given length that fit on the event's grid,
+// along with the time increments each slot spans
+func GetCandidateSlots(event *models.Event, meetingLength time.Duration) [][]time.Time {
+	increments := GetTimeIncrements(event)
+	if utils.Coalesce(event.DaysOnly) {
+		// Each day is a slot
+		slots := make([][]time.Time, 0)
+		for _, t := range increments {
+			slots = append(slots, []time.Time{t})
+		}
+		return slots
+	}
+
+	increment := GetTimeIncrement(event)
+	if meetingLength < increment {
+		meetingLength = increment
+	}
+	numIncrements := int((meetingLength + increment - 1) / increment)
+
+	onGrid := make(models.Set[int64])
+	for _, t := range increments {
+		onGrid[t.UnixMilli()] = struct{}{}
+	}
+
+	slots := make([][]time.Time, 0)
+	for _, start := range increments {
+		slot := make([]time.Time, 0, numIncrements)
+		for i := 0; i < numIncrements; i++ {
+			t := start.Add(time.Duration(i) * increment)
+			if _, ok := onGrid[t.UnixMilli()]; !ok {
+				break
+			}
+			slot = append(slot, t)
+		}
+		if len(slot) == numIncrements {
+			slots = append(slots, slot)
+		}
+	}
+	return slots
+}
+
+// Returns the candidate slots of the event ranked from best to worst. Slots
+// that work for all required respondents come first, then slots are ordered
+// by the weighted number of respondents that can make it
+func RankSlots(event *models.Event, respondents []Respondent, options Options) []Slot {
+	increment := GetTimeIncrement(event)
+	meetingLength := options.MeetingLength
+	if meetingLength <= 0 {
+		meetingLength = increment
+	}
+	ifNeededWeight := DefaultIfNeededWeight
+	if options.IfNeededWeight != nil {
+		ifNeededWeight = *options.IfNeededWeight
+	}
+
+	slots := make([]Slot, 0)
+	for _, increments := range GetCandidateSlots(event, meetingLength) {
+		start := increments[0]
+		end := increments[len(increments)-1].Add(increment)
+		if utils.Coalesce(event.DaysOnly) {
+			end = start.Add(24 * time.Hour)
+		}
+		if options.Exclude != nil && options.Exclude(start, end) {
+			continue
+		}
+
+		slot := Slot{
+			Start:           start,
+			End:             end,
+			Available:       make([]string, 0),
+			IfNeeded:        make([]string, 0),
+			Unavailable:     make([]string, 0),
+			MissingRequired: make([]string, 0),
+		}
+		for _, respondent := range respondents {
+			weight := 1.0
+			if w, ok := options.Weights[respondent.Id]; ok {
+				weight = w
+			}
+
+			switch respondent.availabilityFor(increments) {
+			case available:
+				slot.Available = append(slot.Available, respondent.Id)
+				slot.Score += weight
+			case ifNeeded:
+				slot.IfNeeded = append(slot.IfNeeded, respondent.Id)
+				slot.Score += weight * ifNeededWeight
+			default:
+				slot.Unavailable = append(slot.Unavailable, respondent.Id)
+				if _, ok := options.Required[respondent.Id]; ok {
+					slot.MissingRequired = append(slot.MissingRequired, respondent.Id)
+				}
+			}
+		}
+
+		slots = append(slots, slot)
+	}
+
+	sort.SliceStable(slots, func(i, j int) bool {
+		if len(slots[i].MissingRequired) != len(slots[j].MissingRequired) {
+			return len(slots[i].MissingRequired) < len(slots[j].MissingRequired)
+		}
+		if slots[i].Score != slots[j].Score {
+			return slots[i].Score > slots[j].Score
+		}
+		return slots[i].Start.Before(slots[j].Start)
+	})
+
+	return slots
+}
+
+// How many respondents are available at a time increment of the grid
+type HeatmapCell struct {
+	Start     time.Time `json:"start"`
+	Available int       `json:"available"`
+	IfNeeded  int       `json:"ifNeeded"`
+}
+
+// Groups the respondents by their value for the respondent field, sorted by
+// value. Respondents without a value are grouped last, with an empty value
+func GroupRespondents(respondents []Respondent, fieldId string) []RespondentGroup {
+	groups := make([]RespondentGroup, 0)
+	indexes := make(map[string]int)
+	for _, respondent := range respondents {
+		value := respondent.Fields[fieldId]
+		index, ok := indexes[value]
+		if !ok {
+			index = len(groups)
+			indexes[value] = index
+			groups = append(groups, RespondentGroup{Value: value, Respondents: make([]Respondent, 0)})
+		}
+		groups[index].Respondents = append(groups[index].Respondents, respondent)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if (groups[i].Value == "") != (groups[j].Value == "") {
+			return groups[j].Value == ""
+		}
+		return groups[i].Value < groups[j].Value
+	})
+	return groups
+}
+
+// Returns the number of available and available if needed respondents for
+// every time increment of the event's grid, without revealing who they are
+func GetHeatmap(event *models.Event, respondents []Respondent) []HeatmapCell {
+	cells := make([]HeatmapCell, 0)
+	for _, t := range GetTimeIncrements(event) {
+		cell := HeatmapCell{Start: t}
+		for i := range respondents {
+			switch respondents[i].availabilityFor([]time.Time{t}) {
+			case available:
+				cell.Available++
+			case ifNeeded:
+				cell.IfNeeded++
+			}
+		}
+		cells = append(cells, cell)
+	}
+	return cells
+}
+
+type availability int
+
+const (
+	unavailable availability = iota
+	ifNeeded
+	available
+)
+
+// Returns whether the respondent is available for all the given time increments
+func (r *Respondent) availabilityFor(increments []time.Time) availability {
+	result := available
+	for _, t := range increments {
+		key := t.UnixMilli()
+		if _, ok := r.Available[key]; ok {
+			continue
+		}
+		if _, ok := r.IfNeeded[key]; ok {
+			result = ifNeeded
+			continue
+		}
+		return unavailable
+	}
+	return result
+}
+
+// Returns whether the respondent is available (or available if needed) for
+// the whole time range, given the event's time increment
+func (r *Respondent) IsAvailable(start time.Time, end time.Time, increment time.Duration, includeIfNeeded bool) bool {
+	for t := start; t.Before(end); t = t.Add(increment) {
+		key := t.UnixMilli()
+		if _, ok := r.Available[key]; ok {
+			continue
+		}
+		if _, ok := r.IfNeeded[key]; ok && includeIfNeeded {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+func toSet(times []primitive.DateTime) models.Set[int64] {
+	set := make(models.Set[int64])
+	for _, t := range times {
+		set[int64(t)] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,79 @@
+package scheduling_test
+
+import (
+	"testing"
+	"time"
+
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/services/scheduling/schedulingtest"
+)
+
+func TestRankSlots(t *testing.T) {
+	respondents := []scheduling.Respondent{
+		schedulingtest.NewRespondent("a", 9, 10, 11),
+		schedulingtest.NewRespondent("b", 10, 11, 12),
+		schedulingtest.NewRespondent("c", 9),
+	}
+
+	slots := scheduling.RankSlots(schedulingtest.NewEvent(), respondents, scheduling.Options{
+		MeetingLength: 2 * time.Hour,
+		Required:      models.Set[string]{"c": struct{}{}},
+	})
+	if len(slots) != 3 {
+		t.Fatalf("got %d slots, want 3", len(slots))
+	}
+	// Every slot misses c, so the slot that works for a and b comes first
+	if !slots[0].Start.Equal(schedulingtest.Hour(10)) || len(slots[0].Available) != 2 {
+		t.Errorf("got best slot %v with %v available", slots[0].Start, slots[0].Available)
+	}
+	if !slots[0].End.Equal(schedulingtest.Hour(12)) {
+		t.Errorf("got end %v, want %v", slots[0].End, schedulingtest.Hour(12))
+	}
+}
+
+func TestRemoveAvailability(t *testing.T) {
+	r := schedulingtest.NewRespondent("a", 9, 10, 11, 12)
+	r.RemoveAvailability(schedulingtest.Hour(10).Add(30*time.Minute), schedulingtest.Hour(11).Add(15*time.Minute), time.Hour)
+
+	for h, want := range map[int]bool{9: true, 10: false, 11: false, 12: true} {
+		if got := r.IsAvailable(schedulingtest.Hour(h), schedulingtest.Hour(h+1), time.Hour, false); got != want {
+			t.Errorf("%d: got %v, want %v", h, got, want)
+		}
+	}
+}
+
+func TestGetHeatmap(t *testing.T) {
+	c := schedulingtest.NewRespondent("c", 9)
+	c.IfNeeded[schedulingtest.Hour(10).UnixMilli()] = struct{}{}
+	respondents := []scheduling.Respondent{schedulingtest.NewRespondent("a", 9, 10), schedulingtest.NewRespondent("b", 10, 12), c}
+
+	cells := scheduling.GetHeatmap(schedulingtest.NewEvent(), respondents)
+	if len(cells) != 4 {
+		t.Fatalf("got %d cells, want 4", len(cells))
+	}
+	want := [][2]int{{2, 0}, {2, 1}, {0, 0}, {1, 0}}
+	for i, cell := range cells {
+		if !cell.Start.Equal(schedulingtest.Hour(9 + i)) {
+			t.Errorf("%d: got start %v", i, cell.Start)
+		}
+		if cell.Available != want[i][0] || cell.IfNeeded != want[i][1] {
+			t.Errorf("%d: got %d available, %d if needed, want %v", i, cell.Available, cell.IfNeeded, want[i])
+		}
+	}
+}
+
+func TestGroupRespondents(t *testing.T) {
+	respondents := []scheduling.Respondent{schedulingtest.NewRespondent("a"), schedulingtest.NewRespondent("b"), schedulingtest.NewRespondent("c"), schedulingtest.NewRespondent("d")}
+	respondents[0].Fields = map[string]string{"team": "Eng"}
+	respondents[1].Fields = map[string]string{"team": "Design"}
+	respondents[3].Fields = map[string]string{"team": "Eng"}
+
+	groups := scheduling.GroupRespondents(respondents, "team")
+	if len(groups) != 3 || groups[0].Value != "Design" || groups[1].Value != "Eng" || groups[2].Value != "" {
+		t.Fatalf("got %+v", groups)
+	}
+	if len(groups[1].Respondents) != 2 || groups[2].Respondents[0].Id != "c" {
+		t.Errorf("got %+v", groups)
+	}
+}
@@ -0,0 +1,49 @@
+// Builds the events and respondents that the tests of the scheduling services
+// share, all on the same day
+package schedulingtest
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+)
+
+// 9am on a Tuesday (UTC), when events start
+var Day = time.Date(2024, time.May, 14, 9, 0, 0, 0, time.UTC)
+
+// Returns the given hour of Day, e.g. Hour(13) for 1pm
+func Hour(h int) time.Time {
+	return Day.Add(time.Duration(h-9) * time.Hour)
+}
+
+// Returns an event on Day from 9am to 1pm, with hour long time slots
+func NewEvent() *models.Event {
+	duration := float32(4)
+	timeIncrement := 60
+	return &models.Event{
+		Dates:         []primitive.DateTime{primitive.NewDateTimeFromTime(Day)},
+		Duration:      &duration,
+		TimeIncrement: &timeIncrement,
+	}
+}
+
+// Returns a respondent named after their id that's available at the given
+// hours of Day
+func NewRespondent(id string, hours ...int) scheduling.Respondent {
+	return NewRespondentIfNeeded(id, hours, nil)
+}
+
+// Returns a respondent named after their id that's available at some hours of
+// Day, and available if needed at others
+func NewRespondentIfNeeded(id string, available []int, ifNeeded []int) scheduling.Respondent {
+	r := scheduling.Respondent{Id: id, Name: id, Available: make(models.Set[int64]), IfNeeded: make(models.Set[int64])}
+	for _, h := range available {
+		r.Available[Hour(h).UnixMilli()] = struct{}{}
+	}
+	for _, h := range ifNeeded {
+		r.IfNeeded[Hour(h).UnixMilli()] = struct{}{}
+	}
+	return r
+}
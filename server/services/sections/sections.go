@@ -0,0 +1,144 @@
+// Assigns students to class sections based on their weekly availability,
+// maximizing how many students get a section that works for them while
+// respecting section capacities
+package sections
+
+import (
+	"sort"
+	"time"
+
+	"schej.it/server/models"
+	"schej.it/server/services/flow"
+)
+
+// How well a section works for a student
+type Preference int
+
+const (
+	Unavailable Preference = iota
+	IfNeeded
+	Available
+)
+
+// Costs used by the solver. Unavailable sections are only used if conflicts
+// are allowed, and the small balancing cost spreads students evenly across
+// sections that work equally well
+const (
+	availableCost   = 0
+	ifNeededCost    = 1000
+	unavailableCost = 100000
+	balancingCost   = 1
+)
+
+type Section struct {
+	Id string
+
+	// Maximum number of students, 0 for unlimited
+	Capacity int
+}
+
+type Student struct {
+	Id string
+
+	// Preference for each section, keyed by section id. Missing sections are unavailable
+	Preferences map[string]Preference
+}
+
+type Assignment struct {
+	StudentId  string     `json:"studentId"`
+	SectionId  string     `json:"sectionId"`
+	Preference Preference `json:"preference"`
+}
+
+type Result struct {
+	Assignments []Assignment `json:"assignments"`
+
+	// Ids of the students that couldn't be assigned a section
+	Unassigned []string `json:"unassigned"`
+}
+
+// Assigns each student to at most one section, such that as many students as
+// possible are assigned, preferring available over "if needed" sections. If
+// allowConflicts is true, students may be assigned to sections they are
+// unavailable for when no other section has room
+func Assign(students []Student, sections []Section, allowConflicts bool) Result {
+	// Build a flow network: source -> students -> sections -> sink, then find
+	// the min cost max flow
+	numStudents := len(students)
+	source := 0
+	studentNode := func(i int) int { return 1 + i }
+	sectionNode := func(j int) int { return 1 + numStudents + j }
+	sink := 1 + numStudents + len(sections)
+	g := flow.NewGraph(sink + 1)
+
+	for i := range students {
+		g.AddEdge(source, studentNode(i), 1, 0)
+	}
+	studentEdges := make([][]int, numStudents)
+	for i, student := range students {
+		for j, section := range sections {
+			cost := unavailableCost
+			switch student.Preferences[section.Id] {
+			case Available:
+				cost = availableCost
+			case IfNeeded:
+				cost = ifNeededCost
+			default:
+				if !allowConflicts {
+					continue
+				}
+			}
+			studentEdges[i] = append(studentEdges[i], g.AddEdge(studentNode(i), sectionNode(j), 1, cost))
+		}
+	}
+	for j, section := range sections {
+		capacity := section.Capacity
+		if capacity <= 0 || capacity > numStudents {
+			capacity = numStudents
+		}
+		// One unit edge per seat, each more expensive than the last
+		for k := 0; k < capacity; k++ {
+			g.AddEdge(sectionNode(j), sink, 1, k*balancingCost)
+		}
+	}
+
+	g.MinCostMaxFlow(source, sink)
+
+	result := Result{Assignments: make([]Assignment, 0), Unassigned: make([]string, 0)}
+	for i, student := range students {
+		assigned := false
+		for _, e := range studentEdges[i] {
+			if g.Flow(e) == 1 {
+				section := sections[g.To(e)-sectionNode(0)]
+				result.Assignments = append(result.Assignments, Assignment{
+					StudentId:  student.Id,
+					SectionId:  section.Id,
+					Preference: student.Preferences[section.Id],
+				})
+				assigned = true
+				break
+			}
+		}
+		if !assigned {
+			result.Unassigned = append(result.Unassigned, student.Id)
+		}
+	}
+	sort.SliceStable(result.Assignments, func(i, j int) bool {
+		return result.Assignments[i].SectionId < result.Assignments[j].SectionId
+	})
+
+	return result
+}
+
+// Sunday of the placeholder week that days of the week events store their
+// dates and availability in
+var dowSunday = [3]int{2018, 6, 17}
+
+// Returns the start and end of the weekly slot in the placeholder week used
+// by days of the week events, in the given location
+func GetDowTimes(slot models.WeeklySlot, loc *time.Location) (time.Time, time.Time) {
+	day := time.Date(dowSunday[0], time.Month(dowSunday[1]), dowSunday[2]+slot.DayOfWeek, 0, 0, 0, 0, loc)
+	start := day.Add(time.Duration(float64(slot.StartTime) * float64(time.Hour)))
+	end := day.Add(time.Duration(float64(slot.EndTime) * float64(time.Hour)))
+	return start, end
+}
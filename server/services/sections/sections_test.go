@@ -0,0 +1,67 @@
+package sections
+
+import "testing"
+
+func assignmentsBySection(result Result) map[string][]string {
+	bySection := make(map[string][]string)
+	for _, assignment := range result.Assignments {
+		bySection[assignment.SectionId] = append(bySection[assignment.SectionId], assignment.StudentId)
+	}
+	return bySection
+}
+
+func TestAssignRespectsCapacity(t *testing.T) {
+	students := []Student{
+		{Id: "a", Preferences: map[string]Preference{"mon": Available, "wed": Available}},
+		{Id: "b", Preferences: map[string]Preference{"mon": Available}},
+		{Id: "c", Preferences: map[string]Preference{"mon": Available, "wed": IfNeeded}},
+	}
+	sections := []Section{{Id: "mon", Capacity: 2}, {Id: "wed", Capacity: 2}}
+
+	result := Assign(students, sections, false)
+	if len(result.Unassigned) != 0 {
+		t.Fatalf("got unassigned %v", result.Unassigned)
+	}
+	bySection := assignmentsBySection(result)
+	if len(bySection["mon"]) != 2 || len(bySection["wed"]) != 1 {
+		t.Fatalf("got %v", bySection)
+	}
+	// a should move to wed, since it's available for both and c is only
+	// available if needed on wed
+	if bySection["wed"][0] != "a" {
+		t.Errorf("got %v in wed, want a", bySection["wed"])
+	}
+}
+
+func TestAssignUnassigned(t *testing.T) {
+	students := []Student{
+		{Id: "a", Preferences: map[string]Preference{"mon": Available}},
+		{Id: "b", Preferences: map[string]Preference{"mon": Available}},
+		{Id: "c", Preferences: map[string]Preference{}},
+	}
+	sections := []Section{{Id: "mon", Capacity: 1}, {Id: "wed"}}
+
+	result := Assign(students, sections, false)
+	if len(result.Assignments) != 1 || len(result.Unassigned) != 2 {
+		t.Errorf("got %v", result)
+	}
+
+	// With conflicts allowed, everyone gets a section
+	result = Assign(students, sections, true)
+	if len(result.Unassigned) != 0 {
+		t.Errorf("got unassigned %v", result.Unassigned)
+	}
+}
+
+func TestAssignBalances(t *testing.T) {
+	students := make([]Student, 0)
+	for _, id := range []string{"a", "b", "c", "d"} {
+		students = append(students, Student{Id: id, Preferences: map[string]Preference{"mon": Available, "wed": Available}})
+	}
+	sections := []Section{{Id: "mon"}, {Id: "wed"}}
+
+	bySection := assignmentsBySection(Assign(students, sections, false))
+	if len(bySection["mon"]) != 2 || len(bySection["wed"]) != 2 {
+		t.Errorf("got %v, want 2 students in each section", bySection)
+	}
+}
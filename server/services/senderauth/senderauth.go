@@ -0,0 +1,100 @@
+// Checks that inbound emails come from the address in their From header, using
+// the SPF and DKIM results of the email provider that received them. The From
+// header alone can be set to anything, so it isn't trusted to act for a user
+package senderauth
+
+import (
+	"encoding/json"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+// Returns the domain of the address, in lower case
+func getDomain(address string) string {
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(address[at+1:]))
+}
+
+// Returns whether a result for the authenticated domain vouches for the From
+// domain, i.e. they're the same domain or the From domain is a subdomain of it,
+// like DMARC's relaxed alignment
+func isAligned(fromDomain string, authenticatedDomain string) bool {
+	authenticatedDomain = strings.ToLower(strings.TrimSuffix(authenticatedDomain, "."))
+	if len(fromDomain) == 0 || len(authenticatedDomain) == 0 {
+		return false
+	}
+	return fromDomain == authenticatedDomain || strings.HasSuffix(fromDomain, "."+authenticatedDomain)
+}
+
+// Matches the results in SendGrid's dkim field, e.g. "{@example.com : pass}"
+var sendgridDkimRegex = regexp.MustCompile(`@([^\s:,{}]+)\s*:\s*(\w+)`)
+
+// Returns whether the email SendGrid received is from the sender, given the
+// SPF, dkim and envelope fields of the inbound parse webhook. Either a DKIM
+// signature of the sender's domain passed, or SPF passed for an envelope
+// sender of the sender's domain
+func CheckSendgrid(from *mail.Address, spf string, dkim string, envelope string) bool {
+	fromDomain := getDomain(from.Address)
+
+	for _, match := range sendgridDkimRegex.FindAllStringSubmatch(dkim, -1) {
+		if strings.EqualFold(match[2], "pass") && isAligned(fromDomain, match[1]) {
+			return true
+		}
+	}
+
+	parsedEnvelope := struct {
+		From string `json:"from"`
+	}{}
+	if err := json.Unmarshal([]byte(envelope), &parsedEnvelope); err != nil {
+		return false
+	}
+	return strings.EqualFold(strings.TrimSpace(spf), "pass") && isAligned(fromDomain, getDomain(parsedEnvelope.From))
+}
+
+// Verdicts of the receipt of an email SES received
+type SesReceipt struct {
+	SpfVerdict   SesVerdict `json:"spfVerdict"`
+	DkimVerdict  SesVerdict `json:"dkimVerdict"`
+	DmarcVerdict SesVerdict `json:"dmarcVerdict"`
+}
+
+type SesVerdict struct {
+	Status string `json:"status"`
+}
+
+// Returns whether the email SES received is from the sender, given the
+// receipt, the envelope sender and the raw email. Either DMARC passed, a DKIM
+// signature of the sender's domain passed, or SPF passed for an envelope sender
+// of the sender's domain
+func CheckSes(from *mail.Address, receipt SesReceipt, source string, rawContent string) bool {
+	fromDomain := getDomain(from.Address)
+
+	if receipt.DmarcVerdict.Status == "PASS" {
+		return true
+	}
+	if receipt.SpfVerdict.Status == "PASS" && isAligned(fromDomain, getDomain(source)) {
+		return true
+	}
+	if receipt.DkimVerdict.Status != "PASS" {
+		return false
+	}
+
+	// SES doesn't say which domain signed the email, so look at the signatures
+	message, err := mail.ReadMessage(strings.NewReader(rawContent))
+	if err != nil {
+		return false
+	}
+	for _, signature := range message.Header["Dkim-Signature"] {
+		for _, tag := range strings.Split(signature, ";") {
+			name, value, found := strings.Cut(strings.TrimSpace(tag), "=")
+			if found && strings.TrimSpace(name) == "d" && isAligned(fromDomain, strings.TrimSpace(value)) {
+				return true
+			}
+		}
+	}
+	return false
+}
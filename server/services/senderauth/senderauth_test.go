@@ -0,0 +1,56 @@
+package senderauth
+
+import (
+	"net/mail"
+	"testing"
+)
+
+func TestCheckSendgrid(t *testing.T) {
+	from := &mail.Address{Address: "alice@mail.example.com"}
+	tests := []struct {
+		spf      string
+		dkim     string
+		envelope string
+		expected bool
+	}{
+		{"none", "{@example.com : pass}", "", true},
+		{"none", "{@attacker.com : pass, @example.com : fail}", "", false},
+		{"pass", "none", `{"from":"bounces@mail.example.com"}`, true},
+		{"pass", "none", `{"from":"bounces@attacker.com"}`, false},
+		{"fail", "none", `{"from":"alice@mail.example.com"}`, false},
+		{"pass", "{@notexample.com : pass}", "", false},
+	}
+	for _, test := range tests {
+		if got := CheckSendgrid(from, test.spf, test.dkim, test.envelope); got != test.expected {
+			t.Errorf("CheckSendgrid(%q, %q, %q) = %v, expected %v", test.spf, test.dkim, test.envelope, got, test.expected)
+		}
+	}
+}
+
+func TestCheckSes(t *testing.T) {
+	from := &mail.Address{Address: "alice@example.com"}
+	pass := SesVerdict{Status: "PASS"}
+	fail := SesVerdict{Status: "FAIL"}
+	raw := "DKIM-Signature: v=1; a=rsa-sha256; d=example.com; s=s1; b=abc\r\nFrom: alice@example.com\r\n\r\nHi\r\n"
+	attackerRaw := "DKIM-Signature: v=1; a=rsa-sha256; d=attacker.com; s=s1; b=abc\r\nFrom: alice@example.com\r\n\r\nHi\r\n"
+
+	tests := []struct {
+		name     string
+		receipt  SesReceipt
+		source   string
+		raw      string
+		expected bool
+	}{
+		{"dmarc", SesReceipt{DmarcVerdict: pass, SpfVerdict: fail, DkimVerdict: fail}, "", "", true},
+		{"aligned spf", SesReceipt{SpfVerdict: pass, DkimVerdict: fail}, "bounces@example.com", "", true},
+		{"unaligned spf", SesReceipt{SpfVerdict: pass, DkimVerdict: fail}, "bounces@attacker.com", "", false},
+		{"aligned dkim", SesReceipt{SpfVerdict: fail, DkimVerdict: pass}, "", raw, true},
+		{"unaligned dkim", SesReceipt{SpfVerdict: fail, DkimVerdict: pass}, "", attackerRaw, false},
+		{"failed dkim", SesReceipt{SpfVerdict: fail, DkimVerdict: fail}, "", raw, false},
+	}
+	for _, test := range tests {
+		if got := CheckSes(from, test.receipt, test.source, test.raw); got != test.expected {
+			t.Errorf("%s: got %v, expected %v", test.name, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Finalizes an event at several times (sessions), picking the times that
+// let the most respondents attend one of them and assigning each respondent
+// to a single session
+package sessions
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/services/sections"
+	"schej.it/server/utils"
+)
+
+// Picks up to count non-overlapping slots from the ranked slots, greedily
+// choosing the slot that lets the most respondents that can't make any of the
+// already picked slots attend, with available respondents counting twice as
+// much as "if needed" ones. Ties go to the higher ranked slot. The picked
+// slots are sorted by start time
+func PickSlots(ranked []scheduling.Slot, count int) []scheduling.Slot {
+	picked := make([]scheduling.Slot, 0)
+	covered := make(models.Set[string])
+	for len(picked) < count {
+		best, bestGain := -1, -1
+		for i, slot := range ranked {
+			if overlapsAny(slot, picked) {
+				continue
+			}
+			gain := 0
+			for _, id := range slot.Available {
+				if _, ok := covered[id]; !ok {
+					gain += 2
+				}
+			}
+			for _, id := range slot.IfNeeded {
+				if _, ok := covered[id]; !ok {
+					gain++
+				}
+			}
+			if gain > bestGain {
+				best, bestGain = i, gain
+			}
+		}
+		if best == -1 {
+			break
+		}
+
+		picked = append(picked, ranked[best])
+		for _, id := range ranked[best].Available {
+			covered[id] = struct{}{}
+		}
+		for _, id := range ranked[best].IfNeeded {
+			covered[id] = struct{}{}
+		}
+	}
+
+	sort.SliceStable(picked, func(i, j int) bool { return picked[i].Start.Before(picked[j].Start) })
+	return picked
+}
+
+func overlapsAny(slot scheduling.Slot, slots []scheduling.Slot) bool {
+	for _, other := range slots {
+		if slot.Start.Before(other.End) && other.Start.Before(slot.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// Assigns each respondent to at most one of the sessions, such that as many
+// respondents as possible attend a session that works for them, preferring
+// available over "if needed" and spreading respondents evenly. Sessions hold
+// at most capacity respondents, 0 for unlimited. Replaces the attendees of
+// the sessions, and returns the ids of the respondents that couldn't be assigned
+func Assign(event *models.Event, respondents []scheduling.Respondent, sessions []models.EventSession, capacity int) []string {
+	increment := scheduling.GetTimeIncrement(event)
+	if utils.Coalesce(event.DaysOnly) {
+		increment = 24 * time.Hour
+	}
+
+	sectionsList := make([]sections.Section, 0)
+	for _, session := range sessions {
+		sectionsList = append(sectionsList, sections.Section{Id: session.Id.Hex(), Capacity: capacity})
+	}
+	students := make([]sections.Student, 0)
+	respondentsById := make(map[string]scheduling.Respondent)
+	for _, respondent := range respondents {
+		respondentsById[respondent.Id] = respondent
+		student := sections.Student{Id: respondent.Id, Preferences: make(map[string]sections.Preference)}
+		for _, session := range sessions {
+			start, end := session.StartDate.Time(), session.EndDate.Time()
+			if respondent.IsAvailable(start, end, increment, false) {
+				student.Preferences[session.Id.Hex()] = sections.Available
+			} else if respondent.IsAvailable(start, end, increment, true) {
+				student.Preferences[session.Id.Hex()] = sections.IfNeeded
+			}
+		}
+		students = append(students, student)
+	}
+
+	result := sections.Assign(students, sectionsList, false)
+
+	indices := make(map[string]int)
+	for i := range sessions {
+		sessions[i].Attendees = make([]models.SessionAttendee, 0)
+		indices[sessions[i].Id.Hex()] = i
+	}
+	for _, assignment := range result.Assignments {
+		respondent := respondentsById[assignment.StudentId]
+		i := indices[assignment.SectionId]
+		sessions[i].Attendees = append(sessions[i].Attendees, models.SessionAttendee{
+			UserId:   respondent.Id,
+			Name:     respondent.Name,
+			Email:    respondent.Email,
+			IfNeeded: assignment.Preference == sections.IfNeeded,
+		})
+	}
+	return result.Unassigned
+}
+
+// Returns the token that authorizes the respondent's confirmation link for the session
+func GetConfirmationToken(eventId primitive.ObjectID, sessionId primitive.ObjectID, userId string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("ENCRYPTION_KEY")))
+	mac.Write([]byte(eventId.Hex() + ":" + sessionId.Hex() + ":" + userId))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Returns whether the token authorizes the respondent's confirmation link for the session
+func VerifyConfirmationToken(eventId primitive.ObjectID, sessionId primitive.ObjectID, userId string, token string) bool {
+	return hmac.Equal([]byte(GetConfirmationToken(eventId, sessionId, userId)), []byte(token))
+}
+
+// Returns the link the respondent follows to confirm they will attend the session
+func GetConfirmationUrl(eventId primitive.ObjectID, sessionId primitive.ObjectID, userId string) string {
+	query := url.Values{}
+	query.Set("userId", userId)
+	query.Set("token", GetConfirmationToken(eventId, sessionId, userId))
+	return fmt.Sprintf("%s/api/events/%s/sessions/%s/confirm?%s", utils.GetBaseUrl(), eventId.Hex(), sessionId.Hex(), query.Encode())
+}
@@ -0,0 +1,79 @@
+package sessions
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/models"
+	"schej.it/server/services/scheduling"
+	"schej.it/server/services/scheduling/schedulingtest"
+)
+
+func newSession(h int) models.EventSession {
+	return models.EventSession{
+		Id:        primitive.NewObjectID(),
+		StartDate: primitive.NewDateTimeFromTime(schedulingtest.Hour(h)),
+		EndDate:   primitive.NewDateTimeFromTime(schedulingtest.Hour(h + 1)),
+	}
+}
+
+func TestPickSlots(t *testing.T) {
+	respondents := []scheduling.Respondent{
+		schedulingtest.NewRespondent("a", 9, 10),
+		schedulingtest.NewRespondent("b", 9, 10),
+		schedulingtest.NewRespondent("c", 10),
+		schedulingtest.NewRespondent("d", 12),
+		schedulingtest.NewRespondent("e", 12),
+	}
+	ranked := scheduling.RankSlots(schedulingtest.NewEvent(), respondents, scheduling.Options{})
+
+	// The best slot alone is 10am, but 12pm covers the respondents it misses
+	picked := PickSlots(ranked, 2)
+	if len(picked) != 2 || !picked[0].Start.Equal(schedulingtest.Hour(10)) || !picked[1].Start.Equal(schedulingtest.Hour(12)) {
+		t.Errorf("got %+v", picked)
+	}
+}
+
+func TestAssign(t *testing.T) {
+	respondents := []scheduling.Respondent{
+		schedulingtest.NewRespondent("a", 9, 10),
+		schedulingtest.NewRespondent("b", 9, 10),
+		schedulingtest.NewRespondent("c", 10),
+		schedulingtest.NewRespondentIfNeeded("d", nil, []int{9}),
+		schedulingtest.NewRespondent("e", 11),
+	}
+	sessionsList := []models.EventSession{newSession(9), newSession(10)}
+
+	unassigned := Assign(schedulingtest.NewEvent(), respondents, sessionsList, 0)
+	if len(unassigned) != 1 || unassigned[0] != "e" {
+		t.Errorf("expected e to be unassigned, got %v", unassigned)
+	}
+	if len(sessionsList[0].Attendees) != 2 || len(sessionsList[1].Attendees) != 2 {
+		t.Fatalf("expected respondents to be spread evenly, got %+v", sessionsList)
+	}
+	for _, attendee := range sessionsList[0].Attendees {
+		if attendee.UserId == "c" {
+			t.Error("c was assigned to a session they can't make")
+		}
+		if attendee.UserId == "d" && !attendee.IfNeeded {
+			t.Error("expected d to be marked if needed")
+		}
+	}
+
+	// With a capacity of 1, only one respondent per session
+	unassigned = Assign(schedulingtest.NewEvent(), respondents, sessionsList, 1)
+	if len(unassigned) != 3 || len(sessionsList[0].Attendees) != 1 || len(sessionsList[1].Attendees) != 1 {
+		t.Errorf("got %v, %+v", unassigned, sessionsList)
+	}
+}
+
+func TestConfirmationToken(t *testing.T) {
+	eventId, sessionId := primitive.NewObjectID(), primitive.NewObjectID()
+	token := GetConfirmationToken(eventId, sessionId, "a")
+	if !VerifyConfirmationToken(eventId, sessionId, "a", token) {
+		t.Error("expected token to verify")
+	}
+	if VerifyConfirmationToken(eventId, primitive.NewObjectID(), "a", token) || VerifyConfirmationToken(eventId, sessionId, "b", token) {
+		t.Error("expected token to only verify for its session and respondent")
+	}
+}
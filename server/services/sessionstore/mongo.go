@@ -0,0 +1,41 @@
+package sessionstore
+
+import (
+	"time"
+
+	"schej.it/server/db"
+	"schej.it/server/models"
+)
+
+// Repository that stores sessions in mongo. Expired sessions are deleted by
+// the DeleteExpired job
+type MongoRepository struct{}
+
+func (MongoRepository) Get(sessionId string) (*models.UserSession, error) {
+	return db.GetUserSession(sessionId), nil
+}
+
+func (MongoRepository) Save(session *models.UserSession) error {
+	db.UpsertUserSession(session)
+	return nil
+}
+
+func (MongoRepository) Delete(session *models.UserSession) error {
+	db.DeleteUserSession(session.Id)
+	return nil
+}
+
+func (MongoRepository) GetByUserId(userId string) ([]models.UserSession, error) {
+	return db.GetUserSessionsByUserId(userId, time.Now()), nil
+}
+
+// Deletes expired sessions from mongo, if that's where sessions are stored.
+// Run periodically by the jobs scheduler
+func DeleteExpired(now time.Time) {
+	if Default == nil {
+		return
+	}
+	if _, ok := Default.Repository.(MongoRepository); ok {
+		db.DeleteExpiredUserSessions(now)
+	}
+}
@@ -0,0 +1,315 @@
+package sessionstore
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"schej.it/server/models"
+)
+
+// How many idle connections the redis client keeps open
+const REDIS_POOL_SIZE = 10
+
+// How long a redis command has to complete
+const REDIS_TIMEOUT = 5 * time.Second
+
+// Error reply of a redis command, which leaves the connection usable
+type RedisError string
+
+func (e RedisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// Minimal redis client speaking RESP, enough to store sessions
+type RedisClient struct {
+	address  string
+	useTls   bool
+	username string
+	password string
+	database int
+
+	mutex sync.Mutex
+	idle  []*redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Returns a client for the redis at the url, e.g.
+// redis://:password@localhost:6379/0, or rediss:// for TLS
+func NewRedisClient(redisUrl string) (*RedisClient, error) {
+	parsed, err := url.Parse(redisUrl)
+	if err != nil || (parsed.Scheme != "redis" && parsed.Scheme != "rediss") || len(parsed.Hostname()) == 0 {
+		return nil, fmt.Errorf("REDIS_URL must be a redis:// or rediss:// url")
+	}
+
+	client := &RedisClient{
+		address: parsed.Host,
+		useTls:  parsed.Scheme == "rediss",
+	}
+	if len(parsed.Port()) == 0 {
+		client.address = net.JoinHostPort(parsed.Hostname(), "6379")
+	}
+	if parsed.User != nil {
+		client.username = parsed.User.Username()
+		client.password, _ = parsed.User.Password()
+	}
+	if database := strings.TrimPrefix(parsed.Path, "/"); len(database) > 0 {
+		if client.database, err = strconv.Atoi(database); err != nil {
+			return nil, fmt.Errorf("invalid redis database %q", database)
+		}
+	}
+
+	// Fail fast if redis can't be reached
+	if _, err := client.Do("PING"); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func (c *RedisClient) dial() (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: REDIS_TIMEOUT}
+	var conn net.Conn
+	var err error
+	if c.useTls {
+		host, _, _ := net.SplitHostPort(c.address)
+		conn, err = tls.DialWithDialer(dialer, "tcp", c.address, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", c.address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rc := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	if len(c.password) > 0 {
+		args := []string{"AUTH", c.password}
+		if len(c.username) > 0 {
+			args = []string{"AUTH", c.username, c.password}
+		}
+		if _, err := rc.do(args...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if c.database != 0 {
+		if _, err := rc.do("SELECT", strconv.Itoa(c.database)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
+// Runs the command, returning its reply: a string, an int64, nil, or a slice
+// of replies
+func (c *RedisClient) Do(args ...string) (interface{}, error) {
+	c.mutex.Lock()
+	var rc *redisConn
+	if len(c.idle) > 0 {
+		rc = c.idle[len(c.idle)-1]
+		c.idle = c.idle[:len(c.idle)-1]
+	}
+	c.mutex.Unlock()
+
+	if rc == nil {
+		var err error
+		if rc, err = c.dial(); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := rc.do(args...)
+	var redisErr RedisError
+	if err != nil && !errors.As(err, &redisErr) {
+		// The connection is in an unknown state
+		rc.conn.Close()
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	if len(c.idle) < REDIS_POOL_SIZE {
+		c.idle = append(c.idle, rc)
+		rc = nil
+	}
+	c.mutex.Unlock()
+	if rc != nil {
+		rc.conn.Close()
+	}
+	return reply, err
+}
+
+// Closes the idle connections
+func (c *RedisClient) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, rc := range c.idle {
+		rc.conn.Close()
+	}
+	c.idle = nil
+}
+
+func (rc *redisConn) do(args ...string) (interface{}, error) {
+	rc.conn.SetDeadline(time.Now().Add(REDIS_TIMEOUT))
+	if _, err := rc.conn.Write(EncodeRedisCommand(args...)); err != nil {
+		return nil, err
+	}
+	return ReadRedisReply(rc.reader)
+}
+
+// Encodes the command as a RESP array of bulk strings
+func EncodeRedisCommand(args ...string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return []byte(b.String())
+}
+
+// Reads a RESP reply. Error replies are returned as a RedisError
+func ReadRedisReply(reader *bufio.Reader) (interface{}, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if len(line) == 0 {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, RedisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil || length < 0 {
+			return nil, err
+		}
+		data := make([]byte, length+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:length]), nil
+	case '*':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil || length < 0 {
+			return nil, err
+		}
+		replies := make([]interface{}, length)
+		for i := range replies {
+			if replies[i], err = ReadRedisReply(reader); err != nil {
+				return nil, err
+			}
+		}
+		return replies, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
+
+// Repository that stores sessions in redis, which expires them. Each user's
+// session ids are kept in a set to list them
+type RedisRepository struct {
+	Client *RedisClient
+}
+
+func getSessionKey(sessionId string) string {
+	return "session:" + sessionId
+}
+
+func getUserSessionsKey(userId string) string {
+	return "user-sessions:" + userId
+}
+
+func (r RedisRepository) Get(sessionId string) (*models.UserSession, error) {
+	reply, err := r.Client.Do("GET", getSessionKey(sessionId))
+	if err != nil || reply == nil {
+		return nil, err
+	}
+	data, ok := reply.(string)
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected reply to GET")
+	}
+	var session models.UserSession
+	if err := bson.Unmarshal([]byte(data), &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r RedisRepository) Save(session *models.UserSession) error {
+	ttl := time.Until(session.ExpiresAt.Time())
+	if ttl <= 0 {
+		return r.Delete(session)
+	}
+	data, err := bson.Marshal(session)
+	if err != nil {
+		return err
+	}
+	if _, err := r.Client.Do("SET", getSessionKey(session.Id), string(data), "PX", strconv.FormatInt(ttl.Milliseconds(), 10)); err != nil {
+		return err
+	}
+	if len(session.UserId) == 0 {
+		return nil
+	}
+	if _, err := r.Client.Do("SADD", getUserSessionsKey(session.UserId), session.Id); err != nil {
+		return err
+	}
+	_, err = r.Client.Do("PEXPIRE", getUserSessionsKey(session.UserId), strconv.FormatInt(MAX_AGE.Milliseconds(), 10))
+	return err
+}
+
+func (r RedisRepository) Delete(session *models.UserSession) error {
+	if _, err := r.Client.Do("DEL", getSessionKey(session.Id)); err != nil {
+		return err
+	}
+	if len(session.UserId) == 0 {
+		return nil
+	}
+	_, err := r.Client.Do("SREM", getUserSessionsKey(session.UserId), session.Id)
+	return err
+}
+
+func (r RedisRepository) GetByUserId(userId string) ([]models.UserSession, error) {
+	reply, err := r.Client.Do("SMEMBERS", getUserSessionsKey(userId))
+	if err != nil {
+		return nil, err
+	}
+	sessionIds, _ := reply.([]interface{})
+
+	userSessions := make([]models.UserSession, 0)
+	for _, sessionId := range sessionIds {
+		sessionId, _ := sessionId.(string)
+		session, err := r.Get(sessionId)
+		if err != nil {
+			return nil, err
+		}
+		if session == nil || session.UserId != userId {
+			// Expired, or signed out and back in as someone else
+			if _, err := r.Client.Do("SREM", getUserSessionsKey(userId), sessionId); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		userSessions = append(userSessions, *session)
+	}
+	sort.Slice(userSessions, func(i, j int) bool { return userSessions[i].LastSeenAt > userSessions[j].LastSeenAt })
+	return userSessions, nil
+}
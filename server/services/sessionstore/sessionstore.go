@@ -0,0 +1,267 @@
+// Server side session store, keeping sessions in mongo or redis so they work
+// across instances and can be listed and revoked. The session cookie only
+// holds the session id, signed with SESSION_SECRET
+package sessionstore
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gorilla/securecookie"
+	gsessions "github.com/gorilla/sessions"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/utils"
+)
+
+type Backend string
+
+const (
+	MONGO Backend = "mongo"
+	REDIS Backend = "redis"
+)
+
+// How long sessions last, unless the cookie options set a max age
+const MAX_AGE = 30 * 24 * time.Hour
+
+// How often the last seen time of a session is updated
+const TOUCH_INTERVAL = 10 * time.Minute
+
+// Minimum length of SESSION_SECRET
+const MIN_SECRET_LENGTH = 32
+
+// Where sessions are stored
+type Repository interface {
+	// Returns the session with the given id, or nil if it doesn't exist
+	Get(sessionId string) (*models.UserSession, error)
+	Save(session *models.UserSession) error
+	Delete(session *models.UserSession) error
+
+	// Returns the unexpired sessions of the user
+	GetByUserId(userId string) ([]models.UserSession, error)
+}
+
+// Store used by the sessions middleware
+var Default *Store
+
+// Returns the configured backend: SESSION_STORE if it's set, otherwise mongo
+func GetBackend() Backend {
+	if backend := Backend(os.Getenv("SESSION_STORE")); len(backend) > 0 {
+		return backend
+	}
+	return MONGO
+}
+
+// Sets up the default store with the configured backend, exiting if
+// SESSION_SECRET isn't set. Returns a function to close the backend
+func Init() func() {
+	secret := os.Getenv("SESSION_SECRET")
+	if len(secret) < MIN_SECRET_LENGTH {
+		logger.StdErr.Fatalf("SESSION_SECRET must be set to at least %d characters\n", MIN_SECRET_LENGTH)
+	}
+
+	switch backend := GetBackend(); backend {
+	case MONGO:
+		logger.StdOut.Println("Storing sessions in mongo")
+		Default = NewStore(MongoRepository{}, []byte(secret))
+		return func() {}
+	case REDIS:
+		client, err := NewRedisClient(os.Getenv("REDIS_URL"))
+		if err != nil {
+			logger.StdErr.Fatalln(err)
+		}
+		logger.StdOut.Println("Storing sessions in redis")
+		Default = NewStore(RedisRepository{Client: client}, []byte(secret))
+		return client.Close
+	default:
+		logger.StdErr.Fatalf("Unknown SESSION_STORE %q\n", backend)
+		return nil
+	}
+}
+
+// Session store backed by a repository, implementing the store interface of
+// the sessions middleware
+type Store struct {
+	Repository Repository
+	codecs     []securecookie.Codec
+	options    *gsessions.Options
+}
+
+func NewStore(repository Repository, secret []byte) *Store {
+	return &Store{
+		Repository: repository,
+		codecs:     securecookie.CodecsFromPairs(secret),
+		options: &gsessions.Options{
+			Path:     "/",
+			MaxAge:   int(MAX_AGE.Seconds()),
+			HttpOnly: true,
+		},
+	}
+}
+
+func (s *Store) Options(options sessions.Options) {
+	s.options = options.ToGorillaOptions()
+}
+
+func (s *Store) Get(r *http.Request, name string) (*gsessions.Session, error) {
+	return gsessions.GetRegistry(r).Get(s, name)
+}
+
+// Returns the session of the id in the request's cookie, or a new session if
+// there's no cookie or the session was revoked or expired
+func (s *Store) New(r *http.Request, name string) (*gsessions.Session, error) {
+	session := gsessions.NewSession(s, name)
+	options := *s.options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	var sessionId string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &sessionId, s.codecs...); err != nil {
+		// E.g. cookies signed with another secret
+		return session, nil
+	}
+
+	stored, err := s.Repository.Get(sessionId)
+	if err != nil {
+		return session, err
+	}
+	now := time.Now()
+	if stored == nil || !stored.ExpiresAt.Time().After(now) {
+		return session, nil
+	}
+	if err := (securecookie.GobEncoder{}).Deserialize(stored.Data, &session.Values); err != nil {
+		return session, nil
+	}
+	session.ID = sessionId
+	session.IsNew = false
+
+	if now.Sub(stored.LastSeenAt.Time()) > TOUCH_INTERVAL {
+		stored.LastSeenAt = primitive.NewDateTimeFromTime(now)
+		if err := s.Repository.Save(stored); err != nil {
+			logger.StdErr.Println(err)
+		}
+	}
+	return session, nil
+}
+
+// Stores the session and sets the cookie with its id. Sessions with a
+// negative max age are deleted
+func (s *Store) Save(r *http.Request, w http.ResponseWriter, session *gsessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if len(session.ID) > 0 {
+			stored, err := s.Repository.Get(session.ID)
+			if err != nil {
+				return err
+			}
+			if stored != nil {
+				if err := s.Repository.Delete(stored); err != nil {
+					return err
+				}
+			}
+		}
+		http.SetCookie(w, gsessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	now := time.Now()
+	stored := &models.UserSession{
+		Id:        session.ID,
+		CreatedAt: primitive.NewDateTimeFromTime(now),
+	}
+	if len(session.ID) == 0 {
+		session.ID = NewSessionId()
+		stored.Id = session.ID
+	} else if existing, err := s.Repository.Get(session.ID); err != nil {
+		return err
+	} else if existing != nil {
+		stored.CreatedAt = existing.CreatedAt
+	}
+
+	data, err := (securecookie.GobEncoder{}).Serialize(session.Values)
+	if err != nil {
+		return err
+	}
+	maxAge := MAX_AGE
+	if session.Options.MaxAge > 0 {
+		maxAge = time.Duration(session.Options.MaxAge) * time.Second
+	}
+	stored.UserId = getUserId(session)
+	stored.Data = data
+	stored.UserAgent = r.UserAgent()
+	stored.Ip = utils.GetClientIp(r)
+	stored.LastSeenAt = primitive.NewDateTimeFromTime(now)
+	stored.ExpiresAt = primitive.NewDateTimeFromTime(now.Add(maxAge))
+	if err := s.Repository.Save(stored); err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, gsessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// Returns the unexpired sessions of the user, marking the one with the given
+// id as the current one
+func (s *Store) GetUserSessions(userId string, currentSessionId string) ([]models.UserSession, error) {
+	userSessions, err := s.Repository.GetByUserId(userId)
+	if err != nil {
+		return nil, err
+	}
+	for i := range userSessions {
+		userSessions[i].Current = userSessions[i].Id == currentSessionId
+	}
+	return userSessions, nil
+}
+
+// Revokes the user's session with the given id, returning false if the user
+// has no such session
+func (s *Store) Revoke(userId string, sessionId string) (bool, error) {
+	stored, err := s.Repository.Get(sessionId)
+	if err != nil || stored == nil || stored.UserId != userId {
+		return false, err
+	}
+	return true, s.Repository.Delete(stored)
+}
+
+// Revokes all of the user's sessions, except the one with the given id
+func (s *Store) RevokeAll(userId string, exceptSessionId string) error {
+	userSessions, err := s.Repository.GetByUserId(userId)
+	if err != nil {
+		return err
+	}
+	for i := range userSessions {
+		if userSessions[i].Id == exceptSessionId {
+			continue
+		}
+		if err := s.Repository.Delete(&userSessions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns a new random session id
+func NewSessionId() string {
+	idBytes := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return hex.EncodeToString(idBytes)
+}
+
+func getUserId(session *gsessions.Session) string {
+	userId, _ := session.Values["userId"].(string)
+	return userId
+}
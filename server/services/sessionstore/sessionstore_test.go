@@ -0,0 +1,203 @@
+package sessionstore
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	gsessions "github.com/gorilla/sessions"
+	"schej.it/server/models"
+)
+
+type memoryRepository map[string]models.UserSession
+
+func (m memoryRepository) Get(sessionId string) (*models.UserSession, error) {
+	session, ok := m[sessionId]
+	if !ok {
+		return nil, nil
+	}
+	return &session, nil
+}
+
+func (m memoryRepository) Save(session *models.UserSession) error {
+	m[session.Id] = *session
+	return nil
+}
+
+func (m memoryRepository) Delete(session *models.UserSession) error {
+	delete(m, session.Id)
+	return nil
+}
+
+func (m memoryRepository) GetByUserId(userId string) ([]models.UserSession, error) {
+	sessions := make([]models.UserSession, 0)
+	for _, session := range m {
+		if session.UserId == userId {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+// Signs in on a new store request, returning the session cookie
+func signIn(t *testing.T, store *Store, userId string) *http.Cookie {
+	r := httptest.NewRequest("POST", "/api/auth/sign-in", nil)
+	w := httptest.NewRecorder()
+	session, err := store.New(r, "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Values["userId"] = userId
+	if err := store.Save(r, w, session); err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies, want 1", len(cookies))
+	}
+	return cookies[0]
+}
+
+func getSession(t *testing.T, store *Store, cookie *http.Cookie) *gsessions.Session {
+	r := httptest.NewRequest("GET", "/api/user/profile", nil)
+	r.AddCookie(cookie)
+	session, err := store.New(r, "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return session
+}
+
+func TestStore(t *testing.T) {
+	repository := make(memoryRepository)
+	store := NewStore(repository, secret)
+	cookie := signIn(t, store, "user1")
+
+	if strings.Contains(cookie.Value, "user1") || !cookie.HttpOnly {
+		t.Errorf("got cookie %+v, want an http only cookie with the signed session id", cookie)
+	}
+	session := getSession(t, store, cookie)
+	if session.IsNew || session.Values["userId"] != "user1" {
+		t.Fatalf("got session %+v, want the signed in session", session)
+	}
+	if repository[session.ID].UserId != "user1" {
+		t.Errorf("got stored user id %q", repository[session.ID].UserId)
+	}
+
+	// Cookies signed with another secret are ignored
+	otherStore := NewStore(repository, []byte("fedcba9876543210fedcba9876543210"))
+	if session := getSession(t, otherStore, cookie); !session.IsNew {
+		t.Error("got the session with a cookie signed with another secret")
+	}
+
+	// Signing out deletes the stored session
+	r := httptest.NewRequest("POST", "/api/auth/sign-out", nil)
+	session.Options.MaxAge = -1
+	if err := store.Save(r, httptest.NewRecorder(), session); err != nil {
+		t.Fatal(err)
+	}
+	if len(repository) != 0 {
+		t.Errorf("got %d stored sessions after signing out, want 0", len(repository))
+	}
+	if session := getSession(t, store, cookie); !session.IsNew {
+		t.Error("got the session after signing out")
+	}
+}
+
+func TestStoreRecordsClientIp(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8")
+	tests := []struct {
+		remoteAddr   string
+		forwardedFor string
+		ip           string
+	}{
+		{"10.0.0.2:1234", "203.0.113.7", "203.0.113.7"},
+		{"10.0.0.2:1234", "1.2.3.4, 203.0.113.7, 10.0.0.3", "203.0.113.7"},
+		{"198.51.100.9:1234", "1.2.3.4", "198.51.100.9"},
+	}
+	for _, test := range tests {
+		repository := make(memoryRepository)
+		store := NewStore(repository, secret)
+		r := httptest.NewRequest("POST", "/api/auth/sign-in", nil)
+		r.RemoteAddr = test.remoteAddr
+		r.Header.Set("X-Forwarded-For", test.forwardedFor)
+		session, err := store.New(r, "session")
+		if err != nil {
+			t.Fatal(err)
+		}
+		session.Values["userId"] = "user1"
+		if err := store.Save(r, httptest.NewRecorder(), session); err != nil {
+			t.Fatal(err)
+		}
+		if ip := repository[session.ID].Ip; ip != test.ip {
+			t.Errorf("from %s forwarded for %q, got ip %q, want %q", test.remoteAddr, test.forwardedFor, ip, test.ip)
+		}
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	repository := make(memoryRepository)
+	store := NewStore(repository, secret)
+	laptop := signIn(t, store, "user1")
+	phone := signIn(t, store, "user1")
+	signIn(t, store, "user2")
+
+	current := getSession(t, store, laptop).ID
+	userSessions, err := store.GetUserSessions("user1", current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(userSessions) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(userSessions))
+	}
+	for _, session := range userSessions {
+		if session.Current != (session.Id == current) {
+			t.Errorf("got current %v for session %s", session.Current, session.Id)
+		}
+	}
+
+	phoneId := getSession(t, store, phone).ID
+	if revoked, _ := store.Revoke("user2", phoneId); revoked {
+		t.Error("revoked another user's session")
+	}
+	if revoked, _ := store.Revoke("user1", phoneId); !revoked {
+		t.Error("didn't revoke the session")
+	}
+	if session := getSession(t, store, phone); !session.IsNew {
+		t.Error("got the revoked session")
+	}
+
+	signIn(t, store, "user1")
+	if err := store.RevokeAll("user1", current); err != nil {
+		t.Fatal(err)
+	}
+	if userSessions, _ := store.GetUserSessions("user1", current); len(userSessions) != 1 || userSessions[0].Id != current {
+		t.Errorf("got sessions %v, want only the current one", userSessions)
+	}
+	if userSessions, _ := store.GetUserSessions("user2", ""); len(userSessions) != 1 {
+		t.Errorf("got %d sessions of the other user, want 1", len(userSessions))
+	}
+}
+
+func TestRedisProtocol(t *testing.T) {
+	if command := string(EncodeRedisCommand("SET", "key", "a b")); command != "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$3\r\na b\r\n" {
+		t.Errorf("got command %q", command)
+	}
+
+	reader := bufio.NewReader(strings.NewReader("+OK\r\n:2\r\n$-1\r\n$5\r\nab\r\nc\r\n*2\r\n$1\r\na\r\n:1\r\n-ERR wrong type\r\n"))
+	want := []interface{}{"OK", int64(2), nil, "ab\r\nc", []interface{}{"a", int64(1)}}
+	for _, w := range want {
+		reply, err := ReadRedisReply(reader)
+		if err != nil || !reflect.DeepEqual(reply, w) {
+			t.Errorf("got reply %#v (%v), want %#v", reply, err, w)
+		}
+	}
+	if _, err := ReadRedisReply(reader); err != RedisError("ERR wrong type") {
+		t.Errorf("got error %v, want the error reply", err)
+	}
+}
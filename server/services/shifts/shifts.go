@@ -0,0 +1,198 @@
+// Assigns volunteers to shifts based on their availability, filling as many
+// spots as possible while spreading shifts evenly across volunteers
+package shifts
+
+import (
+	"sort"
+	"time"
+
+	"schej.it/server/models"
+	"schej.it/server/services/flow"
+)
+
+// Costs used by the solver. The balancing cost makes each additional shift
+// given to the same volunteer slightly more expensive
+const (
+	availableCost = 0
+	ifNeededCost  = 1000
+	balancingCost = 1
+)
+
+type Shift struct {
+	Id    string
+	Start time.Time
+	End   time.Time
+
+	// Number of people needed
+	Needed int
+}
+
+type Volunteer struct {
+	Id string
+
+	// Ids of the shifts the volunteer is available, or available if needed, for
+	Available models.Set[string]
+	IfNeeded  models.Set[string]
+}
+
+type Options struct {
+	// Maximum number of shifts per volunteer, 0 for unlimited
+	MaxShiftsPerVolunteer int
+
+	// Assignments that were made manually and must be kept, mapping shift id
+	// to volunteer ids
+	Locked map[string][]string
+}
+
+type Result struct {
+	// Mapping from shift id to the ids of the volunteers assigned to it,
+	// including locked assignments
+	Assignments map[string][]string `json:"assignments"`
+
+	// Mapping from shift id to the number of spots that couldn't be filled
+	Unfilled map[string]int `json:"unfilled"`
+}
+
+// Assigns volunteers to shifts. A volunteer is never assigned to two shifts
+// that overlap (or that are part of the same run of overlapping shifts)
+func Assign(shiftsList []Shift, volunteers []Volunteer, options Options) Result {
+	clusters := getOverlapClusters(shiftsList)
+
+	result := Result{Assignments: make(map[string][]string), Unfilled: make(map[string]int)}
+	lockedShifts := make(map[string]models.Set[string]) // volunteer id -> shift ids
+	for _, shift := range shiftsList {
+		result.Assignments[shift.Id] = make([]string, 0)
+		for _, volunteerId := range options.Locked[shift.Id] {
+			result.Assignments[shift.Id] = append(result.Assignments[shift.Id], volunteerId)
+			if lockedShifts[volunteerId] == nil {
+				lockedShifts[volunteerId] = make(models.Set[string])
+			}
+			lockedShifts[volunteerId][shift.Id] = struct{}{}
+		}
+	}
+
+	// Build a flow network: source -> volunteers -> (volunteer, cluster) ->
+	// shifts -> sink, then find the min cost max flow
+	numNodes := 2
+	newNode := func() int {
+		numNodes++
+		return numNodes - 1
+	}
+	source, sink := 0, 1
+	shiftNodes := make([]int, len(shiftsList))
+	for i := range shiftsList {
+		shiftNodes[i] = newNode()
+	}
+
+	type edgeInfo struct {
+		edgeId      int
+		volunteerId string
+		shiftIndex  int
+	}
+	type pendingEdge struct {
+		from, to, capacity, cost int
+		info                     *edgeInfo
+	}
+	pending := make([]pendingEdge, 0)
+	assignmentEdges := make([]*edgeInfo, 0)
+
+	for _, volunteer := range volunteers {
+		capacity := options.MaxShiftsPerVolunteer
+		if capacity <= 0 {
+			capacity = len(shiftsList)
+		}
+		capacity -= len(lockedShifts[volunteer.Id])
+		if capacity <= 0 {
+			continue
+		}
+
+		volunteerNode := newNode()
+		for k := 0; k < capacity; k++ {
+			pending = append(pending, pendingEdge{source, volunteerNode, 1, (len(lockedShifts[volunteer.Id]) + k) * balancingCost, nil})
+		}
+
+		for _, cluster := range clusters {
+			// Skip clusters the volunteer is already locked into
+			locked := false
+			for _, i := range cluster {
+				if _, ok := lockedShifts[volunteer.Id][shiftsList[i].Id]; ok {
+					locked = true
+				}
+			}
+			if locked {
+				continue
+			}
+
+			clusterNode := -1
+			for _, i := range cluster {
+				cost := availableCost
+				if _, ok := volunteer.Available[shiftsList[i].Id]; !ok {
+					if _, ok := volunteer.IfNeeded[shiftsList[i].Id]; !ok {
+						continue
+					}
+					cost = ifNeededCost
+				}
+				if clusterNode == -1 {
+					clusterNode = newNode()
+					pending = append(pending, pendingEdge{volunteerNode, clusterNode, 1, 0, nil})
+				}
+				info := &edgeInfo{volunteerId: volunteer.Id, shiftIndex: i}
+				assignmentEdges = append(assignmentEdges, info)
+				pending = append(pending, pendingEdge{clusterNode, shiftNodes[i], 1, cost, info})
+			}
+		}
+	}
+	for i, shift := range shiftsList {
+		remaining := shift.Needed - len(options.Locked[shift.Id])
+		if remaining > 0 {
+			pending = append(pending, pendingEdge{shiftNodes[i], sink, remaining, 0, nil})
+		}
+	}
+
+	g := flow.NewGraph(numNodes)
+	for _, e := range pending {
+		edgeId := g.AddEdge(e.from, e.to, e.capacity, e.cost)
+		if e.info != nil {
+			e.info.edgeId = edgeId
+		}
+	}
+	g.MinCostMaxFlow(source, sink)
+
+	for _, info := range assignmentEdges {
+		if g.Flow(info.edgeId) == 1 {
+			shiftId := shiftsList[info.shiftIndex].Id
+			result.Assignments[shiftId] = append(result.Assignments[shiftId], info.volunteerId)
+		}
+	}
+	for _, shift := range shiftsList {
+		if missing := shift.Needed - len(result.Assignments[shift.Id]); missing > 0 {
+			result.Unfilled[shift.Id] = missing
+		}
+	}
+
+	return result
+}
+
+// Groups the shifts into runs of overlapping shifts, returning the indices of
+// the shifts in each run
+func getOverlapClusters(shiftsList []Shift) [][]int {
+	indices := make([]int, len(shiftsList))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.Slice(indices, func(a, b int) bool { return shiftsList[indices[a]].Start.Before(shiftsList[indices[b]].Start) })
+
+	clusters := make([][]int, 0)
+	var clusterEnd time.Time
+	for _, i := range indices {
+		if len(clusters) > 0 && shiftsList[i].Start.Before(clusterEnd) {
+			clusters[len(clusters)-1] = append(clusters[len(clusters)-1], i)
+		} else {
+			clusters = append(clusters, []int{i})
+		}
+		if shiftsList[i].End.After(clusterEnd) {
+			clusterEnd = shiftsList[i].End
+		}
+	}
+	return clusters
+}
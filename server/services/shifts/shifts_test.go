@@ -0,0 +1,79 @@
+package shifts
+
+import (
+	"testing"
+	"time"
+
+	"schej.it/server/models"
+)
+
+var day = time.Date(2024, time.May, 18, 0, 0, 0, 0, time.UTC)
+
+func shift(id string, startHour int, endHour int, needed int) Shift {
+	return Shift{Id: id, Start: day.Add(time.Duration(startHour) * time.Hour), End: day.Add(time.Duration(endHour) * time.Hour), Needed: needed}
+}
+
+func volunteer(id string, shiftIds ...string) Volunteer {
+	return Volunteer{Id: id, Available: models.Set[string]{}, IfNeeded: models.Set[string]{}}.with(shiftIds...)
+}
+
+func (v Volunteer) with(shiftIds ...string) Volunteer {
+	for _, id := range shiftIds {
+		v.Available[id] = struct{}{}
+	}
+	return v
+}
+
+func TestAssignFillsShifts(t *testing.T) {
+	shiftsList := []Shift{shift("morning", 8, 12, 2), shift("afternoon", 12, 16, 1)}
+	volunteers := []Volunteer{
+		volunteer("a", "morning", "afternoon"),
+		volunteer("b", "morning"),
+		volunteer("c", "morning", "afternoon"),
+	}
+
+	result := Assign(shiftsList, volunteers, Options{MaxShiftsPerVolunteer: 1})
+	if len(result.Unfilled) != 0 {
+		t.Fatalf("got unfilled %v", result.Unfilled)
+	}
+	if len(result.Assignments["morning"]) != 2 || len(result.Assignments["afternoon"]) != 1 {
+		t.Errorf("got %v", result.Assignments)
+	}
+}
+
+func TestAssignOverlapping(t *testing.T) {
+	shiftsList := []Shift{shift("setup", 8, 10, 1), shift("greeter", 9, 11, 1)}
+	volunteers := []Volunteer{volunteer("a", "setup", "greeter")}
+
+	result := Assign(shiftsList, volunteers, Options{})
+	if len(result.Assignments["setup"])+len(result.Assignments["greeter"]) != 1 || len(result.Unfilled) != 1 {
+		t.Errorf("got %v, unfilled %v", result.Assignments, result.Unfilled)
+	}
+}
+
+func TestAssignLocked(t *testing.T) {
+	shiftsList := []Shift{shift("morning", 8, 12, 1), shift("afternoon", 12, 16, 1)}
+	volunteers := []Volunteer{volunteer("a", "morning", "afternoon"), volunteer("b", "afternoon")}
+
+	// b is manually put on the morning shift, so a has to cover the afternoon
+	result := Assign(shiftsList, volunteers, Options{MaxShiftsPerVolunteer: 1, Locked: map[string][]string{"morning": {"b"}}})
+	if len(result.Unfilled) != 0 || result.Assignments["morning"][0] != "b" || result.Assignments["afternoon"][0] != "a" {
+		t.Errorf("got %v, unfilled %v", result.Assignments, result.Unfilled)
+	}
+}
+
+func TestAssignBalances(t *testing.T) {
+	shiftsList := []Shift{shift("1", 8, 9, 1), shift("2", 9, 10, 1), shift("3", 10, 11, 1), shift("4", 11, 12, 1)}
+	volunteers := []Volunteer{volunteer("a", "1", "2", "3", "4"), volunteer("b", "1", "2", "3", "4")}
+
+	result := Assign(shiftsList, volunteers, Options{})
+	counts := make(map[string]int)
+	for _, ids := range result.Assignments {
+		for _, id := range ids {
+			counts[id]++
+		}
+	}
+	if counts["a"] != 2 || counts["b"] != 2 {
+		t.Errorf("got %v, want 2 shifts each", counts)
+	}
+}
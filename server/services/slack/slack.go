@@ -0,0 +1,185 @@
+// Wrapper around the Slack Web API, used by the Slack app (as opposed to the
+// incoming webhooks used for internal notifications)
+package slack
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const apiUrl = "https://slack.com/api/"
+
+// Common response fields returned by every Slack Web API method
+type apiResponse struct {
+	Ok    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+// Calls the given Slack Web API method with the given body, decoding the
+// response into result (if not nil)
+func callApi(method string, body interface{}, result interface{}) error {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", apiUrl+method, bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	return doRequest(method, req, result)
+}
+
+// Calls the given read-only Slack Web API method, which only accept their
+// arguments as query parameters
+func getApi(method string, params url.Values, result interface{}) error {
+	req, err := http.NewRequest("GET", apiUrl+method+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	return doRequest(method, req, result)
+}
+
+func doRequest(method string, req *http.Request, result interface{}) error {
+	token := os.Getenv("SLACK_BOT_TOKEN")
+	if token == "" {
+		return fmt.Errorf("SLACK_BOT_TOKEN not set")
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return err
+	}
+
+	var status apiResponse
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return err
+	}
+	if !status.Ok {
+		return fmt.Errorf("slack %s failed: %s", method, status.Error)
+	}
+
+	if result != nil {
+		return json.Unmarshal(raw, result)
+	}
+	return nil
+}
+
+// Returns the locale the given slack user set for Slack, e.g. "en-US"
+func GetUserLocale(slackUserId string) (string, error) {
+	var result struct {
+		User struct {
+			Locale string `json:"locale"`
+		} `json:"user"`
+	}
+	if err := getApi("users.info", url.Values{"user": {slackUserId}, "include_locale": {"true"}}, &result); err != nil {
+		return "", err
+	}
+	return result.User.Locale, nil
+}
+
+// Returns the name the given slack user goes by in their workspace
+func GetUserName(slackUserId string) (string, error) {
+	var result struct {
+		User struct {
+			Name    string `json:"name"`
+			Profile struct {
+				RealName string `json:"real_name"`
+			} `json:"profile"`
+		} `json:"user"`
+	}
+	if err := getApi("users.info", url.Values{"user": {slackUserId}}, &result); err != nil {
+		return "", err
+	}
+	if len(result.User.Profile.RealName) > 0 {
+		return result.User.Profile.RealName, nil
+	}
+	return result.User.Name, nil
+}
+
+// Publishes the given view as the App Home of the given slack user
+func PublishHomeView(slackUserId string, view bson.M) error {
+	return callApi("views.publish", bson.M{
+		"user_id": slackUserId,
+		"view":    view,
+	}, nil)
+}
+
+// Posts a message to the given channel (or user id, for a DM)
+func PostMessage(channel string, text string, blocks []bson.M) error {
+	body := bson.M{
+		"channel": channel,
+		"text":    text,
+	}
+	if len(blocks) > 0 {
+		body["blocks"] = blocks
+	}
+	return callApi("chat.postMessage", body, nil)
+}
+
+// Posts an ephemeral message visible only to the given user in the given channel
+func PostEphemeral(channel string, slackUserId string, text string) error {
+	return callApi("chat.postEphemeral", bson.M{
+		"channel": channel,
+		"user":    slackUserId,
+		"text":    text,
+	}, nil)
+}
+
+// Verifies that a request came from Slack using the signing secret.
+// See https://api.slack.com/authentication/verifying-requests-from-slack
+func VerifySignature(signingSecret string, timestamp string, signature string, body []byte) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	// Reject requests older than 5 minutes to prevent replay attacks
+	if time.Since(time.Unix(ts, 0)).Abs() > 5*time.Minute {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// Completes an execution of one of the app's workflow steps, passing the given
+// outputs to the next steps of the workflow
+func CompleteFunction(functionExecutionId string, outputs bson.M) error {
+	return callApi("functions.completeSuccess", bson.M{
+		"function_execution_id": functionExecutionId,
+		"outputs":               outputs,
+	}, nil)
+}
+
+// Fails an execution of one of the app's workflow steps, showing the given
+// message in the workflow's activity
+func FailFunction(functionExecutionId string, message string) error {
+	return callApi("functions.completeError", bson.M{
+		"function_execution_id": functionExecutionId,
+		"error":                 message,
+	}, nil)
+}
@@ -0,0 +1,135 @@
+// Writes minimal Excel workbooks (.xlsx), with a sheet per table of strings
+// and numbers
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const CONTENT_TYPE = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// A sheet of the workbook. Cells are strings, ints, or float64s, and the
+// first row is frozen as the header
+type Sheet struct {
+	Name string
+	Rows [][]interface{}
+}
+
+// Excel limits sheet names to 31 characters, without some punctuation
+func sheetName(name string, index int) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return ' '
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if len([]rune(name)) > 31 {
+		name = string([]rune(name)[:31])
+	}
+	if len(name) == 0 {
+		name = fmt.Sprintf("Sheet%d", index+1)
+	}
+	return name
+}
+
+// Returns the letters of the column with the given index, e.g. "AB" for 27
+func ColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+func escape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func writeSheet(w io.Writer, sheet Sheet) {
+	fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+"\n")
+	fmt.Fprint(w, `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(sheet.Rows) > 1 {
+		fmt.Fprint(w, `<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	}
+	fmt.Fprint(w, `<sheetData>`)
+	for i, row := range sheet.Rows {
+		fmt.Fprintf(w, `<row r="%d">`, i+1)
+		for j, value := range row {
+			ref := fmt.Sprintf("%s%d", ColumnName(j), i+1)
+			switch v := value.(type) {
+			case int:
+				fmt.Fprintf(w, `<c r="%s"><v>%d</v></c>`, ref, v)
+			case float64:
+				fmt.Fprintf(w, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'g', -1, 64))
+			case string:
+				if len(v) > 0 {
+					fmt.Fprintf(w, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, escape(v))
+				}
+			default:
+				if value != nil {
+					fmt.Fprintf(w, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, escape(fmt.Sprint(v)))
+				}
+			}
+		}
+		fmt.Fprint(w, `</row>`)
+	}
+	fmt.Fprint(w, `</sheetData></worksheet>`)
+}
+
+// Writes the sheets as an xlsx workbook
+func WriteXlsx(w io.Writer, sheets []Sheet) error {
+	z := zip.NewWriter(w)
+	add := func(name string, write func(w io.Writer)) error {
+		f, err := z.Create(name)
+		if err != nil {
+			return err
+		}
+		write(f)
+		return nil
+	}
+
+	var overrides, workbookSheets, relationships strings.Builder
+	for i := range sheets {
+		fmt.Fprintf(&overrides, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+		fmt.Fprintf(&workbookSheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(sheetName(sheets[i].Name, i)), i+1, i+1)
+		fmt.Fprintf(&relationships, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` + overrides.String() + `</Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` + workbookSheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + relationships.String() + `</Relationships>`},
+	}
+	for _, file := range files {
+		content := file.content
+		if err := add(file.name, func(w io.Writer) { io.WriteString(w, content) }); err != nil {
+			return err
+		}
+	}
+	for i, sheet := range sheets {
+		sheet := sheet
+		if err := add(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), func(w io.Writer) { writeSheet(w, sheet) }); err != nil {
+			return err
+		}
+	}
+
+	return z.Close()
+}
@@ -0,0 +1,69 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestColumnName(t *testing.T) {
+	for index, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := ColumnName(index); got != want {
+			t.Errorf("ColumnName(%d) = %q, want %q", index, got, want)
+		}
+	}
+}
+
+func TestWriteXlsx(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteXlsx(&buf, []Sheet{
+		{Name: "Responses", Rows: [][]interface{}{{"Name", "Score"}, {"Ana & <Ben>", 3}, {"Chloé", 0.5}}},
+		{Name: "Best times: [top]", Rows: [][]interface{}{{"Time"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, f := range reader.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+
+		// Every part must be well-formed xml
+		decoder := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			if _, err := decoder.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s isn't valid xml: %v", f.Name, err)
+			}
+		}
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing %s", name)
+		}
+	}
+	if !strings.Contains(files["xl/workbook.xml"], `name="Best times   top"`) {
+		t.Errorf("expected the sheet name to be sanitized: %s", files["xl/workbook.xml"])
+	}
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{`<c r="A2" t="inlineStr"><is><t xml:space="preserve">Ana &amp; &lt;Ben&gt;</t></is></c>`, `<c r="B2"><v>3</v></c>`, `<c r="B3"><v>0.5</v></c>`} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet doesn't contain %s", want)
+		}
+	}
+}
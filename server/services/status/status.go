@@ -0,0 +1,120 @@
+// Tracks the health of the components the server depends on, from the
+// results of recent calls to them, for the public status page
+package status
+
+import (
+	"sync"
+	"time"
+)
+
+type Component string
+
+const (
+	DATABASE      Component = "database"
+	CALENDAR_SYNC Component = "calendarSync"
+	EMAIL         Component = "email"
+	WEBHOOKS      Component = "webhooks"
+	TASK_QUEUE    Component = "taskQueue"
+)
+
+// Components shown on the status page, in order
+var Components = []Component{DATABASE, CALENDAR_SYNC, EMAIL, WEBHOOKS, TASK_QUEUE}
+
+type Health string
+
+const (
+	OPERATIONAL Health = "operational"
+	DEGRADED    Health = "degraded"
+	OUTAGE      Health = "outage"
+)
+
+// How far back results count towards a component's health
+const Window = 15 * time.Minute
+
+// Most results kept per component
+const maxResults = 200
+
+// Fraction of failed calls at which a component is degraded
+const degradedFailureRate = 0.2
+
+type result struct {
+	At time.Time
+	Ok bool
+}
+
+type ComponentStatus struct {
+	Component Component `json:"component"`
+	Health    Health    `json:"health"`
+
+	// When the component was last called successfully, or last failed
+	LastSuccessAt *time.Time `json:"lastSuccessAt,omitempty"`
+	LastFailureAt *time.Time `json:"lastFailureAt,omitempty"`
+}
+
+var mutex sync.Mutex
+var results = make(map[Component][]result)
+
+// Records the result of a call to the component, which failed if err is set
+func Record(component Component, err error) {
+	RecordAt(component, err, time.Now())
+}
+
+func RecordAt(component Component, err error, at time.Time) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	componentResults := append(results[component], result{At: at, Ok: err == nil})
+	if len(componentResults) > maxResults {
+		componentResults = componentResults[len(componentResults)-maxResults:]
+	}
+	results[component] = componentResults
+}
+
+// Returns the health of every component at the given time. Components that
+// weren't called recently are assumed to be operational
+func GetStatuses(now time.Time) []ComponentStatus {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	statuses := make([]ComponentStatus, 0)
+	for _, component := range Components {
+		statuses = append(statuses, getStatus(component, results[component], now))
+	}
+	return statuses
+}
+
+func getStatus(component Component, componentResults []result, now time.Time) ComponentStatus {
+	status := ComponentStatus{Component: component, Health: OPERATIONAL}
+
+	numRecent, numFailed := 0, 0
+	for i := range componentResults {
+		r := componentResults[i]
+		if r.Ok {
+			status.LastSuccessAt = &r.At
+		} else {
+			status.LastFailureAt = &r.At
+		}
+		if now.Sub(r.At) <= Window {
+			numRecent++
+			if !r.Ok {
+				numFailed++
+			}
+		}
+	}
+
+	if numFailed > 0 && numFailed == numRecent {
+		status.Health = OUTAGE
+	} else if numRecent > 0 && float64(numFailed)/float64(numRecent) >= degradedFailureRate {
+		status.Health = DEGRADED
+	}
+	return status
+}
+
+// Returns the worse of the two healths
+func Worst(a Health, b Health) Health {
+	rank := map[Health]int{OPERATIONAL: 0, DEGRADED: 1, OUTAGE: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,48 @@
+package status
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGetStatuses(t *testing.T) {
+	results = make(map[Component][]result)
+	now := time.Date(2024, time.May, 14, 12, 0, 0, 0, time.UTC)
+	failure := errors.New("timeout")
+
+	// Old failures don't count
+	RecordAt(DATABASE, failure, now.Add(-time.Hour))
+	RecordAt(DATABASE, nil, now.Add(-time.Minute))
+	// A few failures degrade the component
+	RecordAt(EMAIL, nil, now.Add(-3*time.Minute))
+	RecordAt(EMAIL, failure, now.Add(-2*time.Minute))
+	// Only failures mean it's down
+	RecordAt(TASK_QUEUE, failure, now.Add(-time.Minute))
+
+	want := map[Component]Health{
+		DATABASE:      OPERATIONAL,
+		CALENDAR_SYNC: OPERATIONAL,
+		EMAIL:         DEGRADED,
+		WEBHOOKS:      OPERATIONAL,
+		TASK_QUEUE:    OUTAGE,
+	}
+	statuses := GetStatuses(now)
+	if len(statuses) != len(Components) {
+		t.Fatalf("got %d statuses, want %d", len(statuses), len(Components))
+	}
+	for _, s := range statuses {
+		if s.Health != want[s.Component] {
+			t.Errorf("got %s for %s, want %s", s.Health, s.Component, want[s.Component])
+		}
+	}
+	if statuses[0].LastFailureAt == nil || !statuses[0].LastFailureAt.Equal(now.Add(-time.Hour)) {
+		t.Errorf("got last database failure %v", statuses[0].LastFailureAt)
+	}
+}
+
+func TestWorst(t *testing.T) {
+	if Worst(DEGRADED, OPERATIONAL) != DEGRADED || Worst(DEGRADED, OUTAGE) != OUTAGE {
+		t.Errorf("wrong ordering of healths")
+	}
+}
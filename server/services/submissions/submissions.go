@@ -0,0 +1,110 @@
+// Single use tokens that guests submit their responses with, so that replayed
+// or scripted duplicate submissions are rejected, and the tokens that prove
+// which guest response is whose
+package submissions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/brianvoe/sjwt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+)
+
+// How long a token can be used after it's issued with the event page
+const TOKEN_EXPIRY = 24 * time.Hour
+
+func getSecret() []byte {
+	return []byte(os.Getenv("ENCRYPTION_KEY"))
+}
+
+// Returns a token for one submission to the event
+func NewToken(eventId primitive.ObjectID, now time.Time) string {
+	nonceBytes := make([]byte, 16)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	claims := sjwt.New()
+	claims.Set("eventId", eventId.Hex())
+	claims.Set("nonce", hex.EncodeToString(nonceBytes))
+	claims.SetIssuedAt(now)
+	claims.Set("issuedAtMs", now.UnixMilli())
+	claims.SetExpiresAt(now.Add(TOKEN_EXPIRY))
+	return claims.Generate(getSecret())
+}
+
+// Returns the nonce of the token, or an error if it isn't a valid token for
+// the event. Whether the nonce was already used is up to the caller
+func ParseToken(token string, eventId primitive.ObjectID, now time.Time) (string, error) {
+	if !sjwt.Verify(token, getSecret()) {
+		return "", fmt.Errorf("invalid token signature")
+	}
+	claims, err := sjwt.Parse(token)
+	if err != nil {
+		return "", err
+	}
+	expiresAt, err := claims.GetExpiresAt()
+	if err != nil || now.Unix() > expiresAt {
+		return "", fmt.Errorf("token expired")
+	}
+	if tokenEventId, _ := claims.GetStr("eventId"); tokenEventId != eventId.Hex() {
+		return "", fmt.Errorf("token is for another event")
+	}
+	nonce, _ := claims.GetStr("nonce")
+	if len(nonce) == 0 {
+		return "", fmt.Errorf("invalid token")
+	}
+	return nonce, nil
+}
+
+// Returns when the token was issued, i.e. when the respondent loaded the event,
+// or an error if it isn't a valid token for the event
+func GetIssuedAt(token string, eventId primitive.ObjectID, now time.Time) (time.Time, error) {
+	if _, err := ParseToken(token, eventId, now); err != nil {
+		return time.Time{}, err
+	}
+	claims, err := sjwt.Parse(token)
+	if err != nil {
+		return time.Time{}, err
+	}
+	issuedAtMs, err := claims.GetInt("issuedAtMs")
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(int64(issuedAtMs)), nil
+}
+
+// Returns a token proving that the guest created their response to the event,
+// which they need to cancel their attendance or paid sign up later on
+func NewResponseToken(eventId primitive.ObjectID, guestName string) string {
+	claims := sjwt.New()
+	claims.Set("eventId", eventId.Hex())
+	claims.Set("guestName", guestName)
+	return claims.Generate(getSecret())
+}
+
+// Returns whether the token was issued for the guest's response to the event
+func CheckResponseToken(token string, eventId primitive.ObjectID, guestName string) bool {
+	if len(guestName) == 0 || !sjwt.Verify(token, getSecret()) {
+		return false
+	}
+	claims, err := sjwt.Parse(token)
+	if err != nil {
+		return false
+	}
+	tokenEventId, _ := claims.GetStr("eventId")
+	tokenGuestName, _ := claims.GetStr("guestName")
+	return tokenEventId == eventId.Hex() && tokenGuestName == guestName
+}
+
+// Deletes the used nonces whose tokens have expired, since those tokens are
+// rejected anyway. Run periodically by the jobs scheduler
+func DeleteExpiredNonces(now time.Time) {
+	db.DeleteSubmissionNoncesUsedBefore(now.Add(-TOKEN_EXPIRY))
+}
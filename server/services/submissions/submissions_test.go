@@ -0,0 +1,73 @@
+package submissions
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseToken(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEY", "test-key")
+	now := time.Date(2024, time.May, 14, 12, 0, 0, 0, time.UTC)
+	eventId := primitive.NewObjectID()
+
+	token := NewToken(eventId, now)
+	nonce, err := ParseToken(token, eventId, now.Add(time.Hour))
+	if err != nil || len(nonce) == 0 {
+		t.Fatalf("got %q, %v", nonce, err)
+	}
+
+	// Every token gets its own nonce
+	if other, _ := ParseToken(NewToken(eventId, now), eventId, now); other == nonce {
+		t.Errorf("got the same nonce twice")
+	}
+
+	if _, err := ParseToken(token, primitive.NewObjectID(), now); err == nil {
+		t.Errorf("expected an error for another event")
+	}
+	if _, err := ParseToken(token, eventId, now.Add(TOKEN_EXPIRY+time.Minute)); err == nil {
+		t.Errorf("expected an error for an expired token")
+	}
+	if _, err := ParseToken(token+"x", eventId, now); err == nil {
+		t.Errorf("expected an error for a tampered token")
+	}
+}
+
+func TestGetIssuedAt(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEY", "test-key")
+	now := time.Date(2024, time.May, 14, 12, 0, 0, 500*int(time.Millisecond), time.UTC)
+	eventId := primitive.NewObjectID()
+
+	token := NewToken(eventId, now)
+	if issuedAt, err := GetIssuedAt(token, eventId, now.Add(time.Second)); err != nil || !issuedAt.Equal(now) {
+		t.Errorf("got %v, %v", issuedAt, err)
+	}
+	if _, err := GetIssuedAt(token, primitive.NewObjectID(), now); err == nil {
+		t.Errorf("expected an error for another event")
+	}
+}
+
+func TestCheckResponseToken(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEY", "test-key")
+	eventId := primitive.NewObjectID()
+
+	token := NewResponseToken(eventId, "Alice")
+	if !CheckResponseToken(token, eventId, "Alice") {
+		t.Errorf("expected the token to be valid for the guest")
+	}
+	if CheckResponseToken(token, eventId, "Bob") {
+		t.Errorf("expected the token to be invalid for another guest")
+	}
+	if CheckResponseToken(token, primitive.NewObjectID(), "Alice") {
+		t.Errorf("expected the token to be invalid for another event")
+	}
+	if CheckResponseToken(token+"x", eventId, "Alice") {
+		t.Errorf("expected a tampered token to be invalid")
+	}
+
+	// Submission tokens don't prove which response is whose
+	if CheckResponseToken(NewToken(eventId, time.Now()), eventId, "") {
+		t.Errorf("expected a submission token to be invalid")
+	}
+}
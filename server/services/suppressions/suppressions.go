@@ -0,0 +1,111 @@
+// The email suppression list, which the email sender checks before sending,
+// and the parsing of the bounce and complaint events that email providers
+// post, so addresses that bounce or mark emails as spam are suppressed
+// automatically
+package suppressions
+
+import (
+	"encoding/json"
+	"strings"
+
+	"schej.it/server/db"
+	"schej.it/server/models"
+)
+
+// An address to add to the suppression list
+type Suppression struct {
+	Email  string
+	Reason models.SuppressionReason
+}
+
+// Returns whether emails to the address shouldn't be sent, see
+// utils.IsEmailSuppressed
+func IsSuppressed(email string, bulk bool) bool {
+	suppression := db.GetEmailSuppression(email)
+	return suppression != nil && (bulk || suppression.Reason != models.SUPPRESSION_UNSUBSCRIBED)
+}
+
+// Adds the addresses to the suppression list
+func Apply(suppressions []Suppression) {
+	for _, suppression := range suppressions {
+		db.SuppressEmail(suppression.Email, suppression.Reason)
+	}
+}
+
+// Returns the addresses to suppress from a SendGrid event webhook body, which
+// is a JSON array of events. Blocked emails aren't suppressed since blocks are
+// usually temporary
+func ParseSendgridEvents(body []byte) ([]Suppression, error) {
+	var events []struct {
+		Email string `json:"email"`
+		Event string `json:"event"`
+		Type  string `json:"type"`
+	}
+	if err := json.Unmarshal(body, &events); err != nil {
+		return nil, err
+	}
+
+	suppressions := make([]Suppression, 0)
+	for _, event := range events {
+		var reason models.SuppressionReason
+		switch event.Event {
+		case "bounce":
+			if event.Type == "blocked" {
+				continue
+			}
+			reason = models.SUPPRESSION_BOUNCED
+		case "spamreport":
+			reason = models.SUPPRESSION_COMPLAINED
+		case "unsubscribe", "group_unsubscribe":
+			reason = models.SUPPRESSION_UNSUBSCRIBED
+		default:
+			continue
+		}
+		if email := strings.ToLower(strings.TrimSpace(event.Email)); len(email) > 0 {
+			suppressions = append(suppressions, Suppression{Email: email, Reason: reason})
+		}
+	}
+	return suppressions, nil
+}
+
+// Returns the addresses to suppress from the message of an SES bounce or
+// complaint notification. Only permanent bounces are suppressed
+func ParseSesNotification(message []byte) ([]Suppression, error) {
+	var notification struct {
+		NotificationType string `json:"notificationType"`
+		Bounce           struct {
+			BounceType        string `json:"bounceType"`
+			BouncedRecipients []struct {
+				EmailAddress string `json:"emailAddress"`
+			} `json:"bouncedRecipients"`
+		} `json:"bounce"`
+		Complaint struct {
+			ComplainedRecipients []struct {
+				EmailAddress string `json:"emailAddress"`
+			} `json:"complainedRecipients"`
+		} `json:"complaint"`
+	}
+	if err := json.Unmarshal(message, &notification); err != nil {
+		return nil, err
+	}
+
+	suppressions := make([]Suppression, 0)
+	add := func(email string, reason models.SuppressionReason) {
+		if email = strings.ToLower(strings.TrimSpace(email)); len(email) > 0 {
+			suppressions = append(suppressions, Suppression{Email: email, Reason: reason})
+		}
+	}
+	switch notification.NotificationType {
+	case "Bounce":
+		if notification.Bounce.BounceType == "Permanent" {
+			for _, recipient := range notification.Bounce.BouncedRecipients {
+				add(recipient.EmailAddress, models.SUPPRESSION_BOUNCED)
+			}
+		}
+	case "Complaint":
+		for _, recipient := range notification.Complaint.ComplainedRecipients {
+			add(recipient.EmailAddress, models.SUPPRESSION_COMPLAINED)
+		}
+	}
+	return suppressions, nil
+}
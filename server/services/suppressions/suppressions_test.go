@@ -0,0 +1,67 @@
+package suppressions
+
+import (
+	"reflect"
+	"testing"
+
+	"schej.it/server/models"
+)
+
+func TestParseSendgridEvents(t *testing.T) {
+	body := []byte(`[
+		{"email": "Ana@Example.com", "event": "bounce", "type": "bounce"},
+		{"email": "bo@example.com", "event": "bounce", "type": "blocked"},
+		{"email": "cy@example.com", "event": "spamreport"},
+		{"email": "di@example.com", "event": "unsubscribe"},
+		{"email": "ed@example.com", "event": "delivered"}
+	]`)
+	suppressions, err := ParseSendgridEvents(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Suppression{
+		{Email: "ana@example.com", Reason: models.SUPPRESSION_BOUNCED},
+		{Email: "cy@example.com", Reason: models.SUPPRESSION_COMPLAINED},
+		{Email: "di@example.com", Reason: models.SUPPRESSION_UNSUBSCRIBED},
+	}
+	if !reflect.DeepEqual(suppressions, expected) {
+		t.Errorf("got %+v, expected %+v", suppressions, expected)
+	}
+
+	if _, err := ParseSendgridEvents([]byte(`{"not": "an array"}`)); err == nil {
+		t.Error("expected an error for a body that isn't an array")
+	}
+}
+
+func TestParseSesNotification(t *testing.T) {
+	tests := []struct {
+		message  string
+		expected []Suppression
+	}{
+		{
+			`{"notificationType": "Bounce", "bounce": {"bounceType": "Permanent", "bouncedRecipients": [{"emailAddress": "Ana@example.com"}]}}`,
+			[]Suppression{{Email: "ana@example.com", Reason: models.SUPPRESSION_BOUNCED}},
+		},
+		{
+			`{"notificationType": "Bounce", "bounce": {"bounceType": "Transient", "bouncedRecipients": [{"emailAddress": "bo@example.com"}]}}`,
+			[]Suppression{},
+		},
+		{
+			`{"notificationType": "Complaint", "complaint": {"complainedRecipients": [{"emailAddress": "cy@example.com"}]}}`,
+			[]Suppression{{Email: "cy@example.com", Reason: models.SUPPRESSION_COMPLAINED}},
+		},
+		{
+			`{"notificationType": "Delivery"}`,
+			[]Suppression{},
+		},
+	}
+	for _, test := range tests {
+		suppressions, err := ParseSesNotification([]byte(test.message))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(suppressions, test.expected) {
+			t.Errorf("%s: got %+v, expected %+v", test.message, suppressions, test.expected)
+		}
+	}
+}
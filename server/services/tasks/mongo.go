@@ -0,0 +1,113 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/models"
+	"schej.it/server/services/regions"
+)
+
+// How many times a task is run before it's marked as failed
+const MAX_ATTEMPTS = 5
+
+// How long a task has to finish before another server can run it again
+const RUN_LEASE = time.Minute
+
+// How long finished tasks are kept around for debugging
+const FINISHED_RETENTION = 7 * 24 * time.Hour
+
+// Queue that stores tasks in mongo, run by the RunDue job
+type MongoQueue struct{}
+
+func (MongoQueue) Create(task HttpTask) (string, error) {
+	scheduledTask := models.ScheduledTask{
+		Url:          task.Url,
+		Method:       task.Method,
+		Headers:      task.Headers,
+		Body:         task.Body,
+		ScheduleTime: primitive.NewDateTimeFromTime(task.ScheduleTime),
+		Status:       models.SCHEDULED_TASK_PENDING,
+		Region:       regions.GetRegion(),
+		CreatedAt:    primitive.NewDateTimeFromTime(time.Now()),
+	}
+	db.InsertScheduledTask(&scheduledTask)
+	return scheduledTask.Id.Hex(), nil
+}
+
+func (MongoQueue) Delete(taskId string) error {
+	objectId, err := primitive.ObjectIDFromHex(taskId)
+	if err != nil {
+		// e.g. the id of a Cloud Tasks task from before switching backends
+		return fmt.Errorf("invalid task id %s", taskId)
+	}
+	if !db.DeletePendingScheduledTask(objectId) {
+		return fmt.Errorf("task %s not found", taskId)
+	}
+	return nil
+}
+
+// Returns how long to wait before retrying a task that failed the given
+// number of times: 1m, 2m, 4m, ...
+func GetBackoff(attempts int) time.Duration {
+	return time.Minute << (attempts - 1)
+}
+
+// Makes the task's request, returning an error if it didn't succeed
+func run(task *models.ScheduledTask) error {
+	ctx, cancel := context.WithTimeout(context.Background(), RUN_LEASE/2)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, task.Method, task.Url, bytes.NewReader(task.Body))
+	if err != nil {
+		return err
+	}
+	for key, value := range task.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("request failed with %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Runs the tasks that are due, retrying failed ones with backoff. Tasks created
+// in other regions are left to them until they're overdue. Run periodically by
+// the jobs scheduler
+func RunDue(now time.Time) {
+	region := regions.GetRegion()
+	for task := db.ClaimDueScheduledTask(now, RUN_LEASE, region, regions.TAKEOVER_DELAY); task != nil; task = db.ClaimDueScheduledTask(now, RUN_LEASE, region, regions.TAKEOVER_DELAY) {
+		err := run(task)
+		task.Attempts++
+		finishedAt := primitive.NewDateTimeFromTime(now)
+		if err == nil {
+			task.Status = models.SCHEDULED_TASK_DONE
+			task.FinishedAt = &finishedAt
+		} else {
+			task.LastError = err.Error()
+			if task.Attempts >= MAX_ATTEMPTS {
+				task.Status = models.SCHEDULED_TASK_FAILED
+				task.FinishedAt = &finishedAt
+			} else {
+				task.ScheduleTime = primitive.NewDateTimeFromTime(now.Add(GetBackoff(task.Attempts)))
+			}
+		}
+		db.UpdateScheduledTask(task)
+	}
+
+	db.DeleteFinishedScheduledTasks(now.Add(-FINISHED_RETENTION))
+}
@@ -0,0 +1,51 @@
+// Queue of HTTP requests to make at a later time, e.g. reminder emails sent
+// through listmonk. Backed by Google Cloud Tasks, or by mongo for self-hosted
+// instances without a Google Cloud project, selected with TASK_QUEUE_BACKEND
+package tasks
+
+import (
+	"os"
+	"time"
+)
+
+type Backend string
+
+const (
+	CLOUD_TASKS Backend = "cloudtasks"
+	MONGO       Backend = "mongo"
+)
+
+// An HTTP request to make at ScheduleTime
+type HttpTask struct {
+	Url          string
+	Method       string
+	Headers      map[string]string
+	Body         []byte
+	ScheduleTime time.Time
+}
+
+type Queue interface {
+	// Schedules the task, returning its id
+	Create(task HttpTask) (string, error)
+
+	// Cancels the task if it hasn't run yet
+	Delete(taskId string) error
+}
+
+// Queue used to schedule tasks, set once the backend is initialized
+var Default Queue
+
+// Returns the backend set by TASK_QUEUE_BACKEND. Defaults to Cloud Tasks if a
+// service account key is configured, and to mongo otherwise
+func GetBackend() Backend {
+	switch Backend(os.Getenv("TASK_QUEUE_BACKEND")) {
+	case CLOUD_TASKS:
+		return CLOUD_TASKS
+	case MONGO:
+		return MONGO
+	}
+	if os.Getenv("SERVICE_ACCOUNT_KEY_PATH") != "" {
+		return CLOUD_TASKS
+	}
+	return MONGO
+}
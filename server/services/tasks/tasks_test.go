@@ -0,0 +1,43 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetBackend(t *testing.T) {
+	tests := []struct {
+		backend  string
+		keyPath  string
+		expected Backend
+	}{
+		{"", "", MONGO},
+		{"", "/secrets/key.json", CLOUD_TASKS},
+		{"mongo", "/secrets/key.json", MONGO},
+		{"cloudtasks", "", CLOUD_TASKS},
+		{"unknown", "", MONGO},
+	}
+	for _, test := range tests {
+		t.Setenv("TASK_QUEUE_BACKEND", test.backend)
+		t.Setenv("SERVICE_ACCOUNT_KEY_PATH", test.keyPath)
+		if backend := GetBackend(); backend != test.expected {
+			t.Errorf("GetBackend() with %q, %q = %q, expected %q", test.backend, test.keyPath, backend, test.expected)
+		}
+	}
+}
+
+func TestGetBackoff(t *testing.T) {
+	if backoff := GetBackoff(1); backoff != time.Minute {
+		t.Errorf("got %v", backoff)
+	}
+	if backoff := GetBackoff(MAX_ATTEMPTS - 1); backoff != 8*time.Minute {
+		t.Errorf("got %v", backoff)
+	}
+}
+
+func TestMongoQueueDeleteInvalidId(t *testing.T) {
+	// Ids of Cloud Tasks tasks are rejected without touching the database
+	if err := (MongoQueue{}).Delete("projects/schej-it/locations/us-central1/queues/SendReminderEmail/tasks/1"); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,149 @@
+// Opt-in anonymous usage reports from self-hosted instances, so maintainers
+// learn which features matter. Nothing is sent unless TELEMETRY_ENABLED=true.
+// Reports only contain the version, platform, enabled features, and counts
+// rounded down to a power of ten
+package telemetry
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"time"
+
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services/runtimeconfig"
+)
+
+// Version of the server, set at build time with
+// -ldflags "-X schej.it/server/services/telemetry.Version=<version>"
+var Version = "dev"
+
+// Where reports are sent unless TELEMETRY_URL is set
+const DEFAULT_URL = "https://api.timeful.app/api/telemetry"
+
+// Returns whether the instance opted in to sending reports
+func Enabled() bool {
+	return os.Getenv("TELEMETRY_ENABLED") == "true"
+}
+
+// Returns whether the instance collects the reports other instances send,
+// i.e. it's the maintainers' instance
+func CollectorEnabled() bool {
+	return os.Getenv("TELEMETRY_COLLECTOR_ENABLED") == "true"
+}
+
+// Returns a new random instance id
+func NewInstanceId() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Returns the id the instance's reports are sent with, which is generated
+// randomly and stored the first time it's needed so it isn't derived from any
+// secret
+func GetInstanceId() string {
+	return db.GetOrCreateTelemetryInstanceId(NewInstanceId())
+}
+
+// Rounds the count down to a power of ten, e.g. "100+" for 420
+func Bucket(n int64) string {
+	if n <= 0 {
+		return "0"
+	}
+	bucket := int64(1)
+	for bucket*10 <= n {
+		bucket *= 10
+	}
+	return strconv.FormatInt(bucket, 10) + "+"
+}
+
+// Returns the report of the instance
+func NewReport(instanceId string, counts db.TelemetryCounts, features runtimeconfig.Features) models.TelemetryReport {
+	featureMap := make(map[string]bool)
+	featureJson, _ := json.Marshal(features)
+	json.Unmarshal(featureJson, &featureMap)
+
+	return models.TelemetryReport{
+		InstanceId:       instanceId,
+		Version:          Version,
+		GoVersion:        runtime.Version(),
+		Os:               runtime.GOOS,
+		Arch:             runtime.GOARCH,
+		Users:            Bucket(counts.Users),
+		Events:           Bucket(counts.Events),
+		EventsLast30Days: Bucket(counts.EventsLast30Days),
+		Responses:        Bucket(counts.Responses),
+		Features:         featureMap,
+	}
+}
+
+// Sends the instance's report if it opted in. Run daily by the jobs scheduler
+func Send(now time.Time) {
+	if !Enabled() {
+		return
+	}
+
+	report := NewReport(GetInstanceId(), db.GetTelemetryCounts(now), runtimeconfig.Get().Features)
+	body, err := json.Marshal(report)
+	if err != nil {
+		logger.StdErr.Panicln(err)
+	}
+
+	url := os.Getenv("TELEMETRY_URL")
+	if len(url) == 0 {
+		url = DEFAULT_URL
+	}
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		logger.StdErr.Println("telemetry:", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+var (
+	instanceIdRegex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+	bucketRegex     = regexp.MustCompile(`^(0|10*\+)$`)
+)
+
+// Most features a report can have, so reports can't be used to store junk
+const maxFeatures = 32
+
+// Returns an error if the report isn't one an instance could have sent
+func Validate(report *models.TelemetryReport) error {
+	if !instanceIdRegex.MatchString(report.InstanceId) {
+		return fmt.Errorf("invalid instance id")
+	}
+	for _, value := range []string{report.Version, report.GoVersion, report.Os, report.Arch} {
+		if len(value) > 64 {
+			return fmt.Errorf("invalid version or platform")
+		}
+	}
+	for _, bucket := range []string{report.Users, report.Events, report.EventsLast30Days, report.Responses} {
+		if !bucketRegex.MatchString(bucket) {
+			return fmt.Errorf("invalid count %q", bucket)
+		}
+	}
+	if len(report.Features) > maxFeatures {
+		return fmt.Errorf("too many features")
+	}
+	for feature := range report.Features {
+		if len(feature) > 64 {
+			return fmt.Errorf("invalid feature")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package telemetry
+
+import (
+	"testing"
+
+	"schej.it/server/db"
+	"schej.it/server/services/runtimeconfig"
+)
+
+func TestBucket(t *testing.T) {
+	cases := map[int64]string{
+		0:      "0",
+		-1:     "0",
+		1:      "1+",
+		9:      "1+",
+		10:     "10+",
+		420:    "100+",
+		999999: "100000+",
+	}
+	for n, want := range cases {
+		if got := Bucket(n); got != want {
+			t.Errorf("Bucket(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestNewInstanceId(t *testing.T) {
+	id := NewInstanceId()
+	if !instanceIdRegex.MatchString(id) {
+		t.Errorf("expected a valid instance id, got %q", id)
+	}
+	if NewInstanceId() == id {
+		t.Errorf("expected instances to get different ids")
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	t.Setenv("TELEMETRY_ENABLED", "")
+	if Enabled() {
+		t.Errorf("expected telemetry to be off by default")
+	}
+	t.Setenv("TELEMETRY_ENABLED", "true")
+	if !Enabled() {
+		t.Errorf("expected telemetry to be on once opted in")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	report := NewReport(NewInstanceId(), db.TelemetryCounts{Users: 42, Events: 1500, EventsLast30Days: 0, Responses: 7}, runtimeconfig.Features{Slack: true})
+	if err := Validate(&report); err != nil {
+		t.Fatalf("got %v", err)
+	}
+	if report.Users != "10+" || report.Events != "1000+" || !report.Features["slack"] || report.Features["payments"] {
+		t.Errorf("got %+v", report)
+	}
+
+	exact := report
+	exact.Users = "42"
+	if Validate(&exact) == nil {
+		t.Errorf("expected exact counts to be rejected")
+	}
+	unknown := report
+	unknown.InstanceId = "instance"
+	if Validate(&unknown) == nil {
+		t.Errorf("expected invalid instance ids to be rejected")
+	}
+}
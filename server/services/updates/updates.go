@@ -0,0 +1,161 @@
+// Checks whether a newer release of the server is out, so self-hosters know
+// to upgrade. Nothing is checked unless UPDATE_CHECK_ENABLED=true
+package updates
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"schej.it/server/logger"
+	"schej.it/server/services/telemetry"
+)
+
+// Where the latest release is fetched from unless UPDATE_CHECK_URL is set, in
+// the format of GitHub's latest release API
+const DEFAULT_URL = "https://api.github.com/repos/schej-it/timeful.app/releases/latest"
+
+// How often the latest release is checked
+const CHECK_INTERVAL = 6 * time.Hour
+
+// Whether a newer release is available, as of the last check
+type Status struct {
+	CheckEnabled    bool       `json:"checkEnabled"`
+	CurrentVersion  string     `json:"currentVersion"`
+	LatestVersion   string     `json:"latestVersion,omitempty"`
+	ReleaseUrl      string     `json:"releaseUrl,omitempty"`
+	UpdateAvailable bool       `json:"updateAvailable"`
+	CheckedAt       *time.Time `json:"checkedAt,omitempty"`
+}
+
+var (
+	lastStatus *Status
+	mutex      sync.Mutex
+)
+
+// Returns whether the instance opted in to checking for updates
+func Enabled() bool {
+	return os.Getenv("UPDATE_CHECK_ENABLED") == "true"
+}
+
+// Returns the status of the last check, checking now if it's out of date
+func GetStatus(now time.Time) Status {
+	if !Enabled() {
+		return Status{CurrentVersion: telemetry.Version}
+	}
+
+	mutex.Lock()
+	status := lastStatus
+	mutex.Unlock()
+	if status == nil || now.Sub(*status.CheckedAt) > CHECK_INTERVAL {
+		Check(now)
+		mutex.Lock()
+		status = lastStatus
+		mutex.Unlock()
+	}
+	if status == nil {
+		return Status{CheckEnabled: true, CurrentVersion: telemetry.Version}
+	}
+	return *status
+}
+
+// Fetches the latest release and logs if it's newer than the running version.
+// Run by the jobs scheduler
+func Check(now time.Time) {
+	if !Enabled() {
+		return
+	}
+
+	url := os.Getenv("UPDATE_CHECK_URL")
+	if len(url) == 0 {
+		url = DEFAULT_URL
+	}
+	status, err := fetchStatus(url, telemetry.Version, now)
+	if err != nil {
+		logger.StdErr.Println("update check:", err)
+		return
+	}
+
+	mutex.Lock()
+	lastStatus = status
+	mutex.Unlock()
+
+	if status.UpdateAvailable {
+		logger.StdOut.Printf("A newer version of Timeful is available: %s (running %s). See %s\n", status.LatestVersion, status.CurrentVersion, status.ReleaseUrl)
+	}
+}
+
+func fetchStatus(url string, currentVersion string, now time.Time) (*Status, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("release endpoint responded with %d", resp.StatusCode)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+		HtmlUrl string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, err
+	}
+
+	return &Status{
+		CheckEnabled:    true,
+		CurrentVersion:  currentVersion,
+		LatestVersion:   release.TagName,
+		ReleaseUrl:      release.HtmlUrl,
+		UpdateAvailable: IsNewer(release.TagName, currentVersion),
+		CheckedAt:       &now,
+	}, nil
+}
+
+// Returns whether the latest version is newer than the current one. Versions
+// are compared as major.minor.patch, ignoring a leading "v" and anything
+// after the patch number (e.g. "-3-gabc123" from git describe). Returns false
+// if either isn't a version, e.g. for development builds
+func IsNewer(latest string, current string) bool {
+	l, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+func parseVersion(version string) ([3]int, bool) {
+	var parsed [3]int
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+"); i != -1 {
+		version = version[:i]
+	}
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return parsed, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return parsed, false
+		}
+		parsed[i] = n
+	}
+	return parsed, true
+}
@@ -0,0 +1,63 @@
+package updates
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIsNewer(t *testing.T) {
+	cases := []struct {
+		latest  string
+		current string
+		want    bool
+	}{
+		{"v1.4.0", "v1.3.9", true},
+		{"v1.10.0", "v1.9.0", true},
+		{"v2.0.0", "1.9.9", true},
+		{"v1.3.9", "v1.4.0", false},
+		{"v1.4.0", "v1.4.0", false},
+		{"v1.4.1", "v1.4.0-3-gabc123", true},
+		{"v1.4.0", "v1.4.0-3-gabc123", false},
+		{"v1.4.0", "dev", false},
+		{"v1.4.0", "abc123", false},
+		{"latest", "v1.4.0", false},
+	}
+	for _, c := range cases {
+		if got := IsNewer(c.latest, c.current); got != c.want {
+			t.Errorf("IsNewer(%q, %q) = %v, want %v", c.latest, c.current, got, c.want)
+		}
+	}
+}
+
+func TestFetchStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag_name": "v1.5.0", "html_url": "https://example.com/releases/v1.5.0"}`))
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, time.May, 14, 12, 0, 0, 0, time.UTC)
+	status, err := fetchStatus(server.URL, "v1.4.2", now)
+	if err != nil {
+		t.Fatalf("got %v", err)
+	}
+	if !status.UpdateAvailable || status.LatestVersion != "v1.5.0" || status.ReleaseUrl != "https://example.com/releases/v1.5.0" || !status.CheckedAt.Equal(now) {
+		t.Errorf("got %+v", status)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer failing.Close()
+	if _, err := fetchStatus(failing.URL, "v1.4.2", now); err == nil {
+		t.Errorf("expected an error when the endpoint fails")
+	}
+}
+
+func TestGetStatusDisabled(t *testing.T) {
+	t.Setenv("UPDATE_CHECK_ENABLED", "")
+	if status := GetStatus(time.Now()); status.CheckEnabled || status.UpdateAvailable {
+		t.Errorf("expected no check by default, got %+v", status)
+	}
+}
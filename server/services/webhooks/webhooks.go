@@ -0,0 +1,203 @@
+// Outgoing webhooks, which post events (e.g. a new response) to URLs that
+// owners registered. Deliveries are queued in mongo and sent by a job, so a
+// slow or failing target never holds up the request that triggered them
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/models"
+	"schej.it/server/services/entitlements"
+	"schej.it/server/utils"
+)
+
+// How many times a delivery is attempted before it's marked as failed
+const MAX_ATTEMPTS = 8
+
+// How long the target has to respond
+const TIMEOUT = 10 * time.Second
+
+// Client deliveries are posted with. The urls come from owners, so internal
+// addresses are refused and redirects aren't followed, which would otherwise
+// let them reach internal services
+var client = &http.Client{
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: TIMEOUT, Control: utils.CheckPublicAddress}).DialContext,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Body posted to webhooks
+type Payload struct {
+	Id        string                  `json:"id"`
+	Type      models.WebhookEventType `json:"type"`
+	CreatedAt time.Time               `json:"createdAt"`
+	Data      interface{}             `json:"data"`
+}
+
+// Returns a new secret to sign deliveries with
+func NewSecret() string {
+	secretBytes := make([]byte, 24)
+	if _, err := rand.Read(secretBytes); err != nil {
+		logger.StdErr.Panicln(err)
+	}
+	return "whsec_" + hex.EncodeToString(secretBytes)
+}
+
+// Returns the signature of a delivery, sent in the X-Timeful-Signature header.
+// Targets recompute it over "<X-Timeful-Timestamp>.<body>" to verify the
+// delivery came from Timeful
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Returns how long to wait before the next attempt, after the given number of
+// failed attempts: 30s, 1m, 2m, ... capped at 6 hours
+func GetBackoff(attempts int) time.Duration {
+	backoff := 30 * time.Second
+	for i := 1; i < attempts && backoff < 6*time.Hour; i++ {
+		backoff *= 2
+	}
+	if backoff > 6*time.Hour {
+		backoff = 6 * time.Hour
+	}
+	return backoff
+}
+
+// Queues a delivery of the event to every webhook subscribed to it
+func Enqueue(event *models.Event, eventType models.WebhookEventType, data interface{}) {
+	now := time.Now()
+	for _, webhook := range db.GetSubscribedWebhooks(event, eventType) {
+		delivery := models.WebhookDelivery{
+			Id:            primitive.NewObjectID(),
+			WebhookId:     webhook.Id,
+			Type:          eventType,
+			Status:        models.WEBHOOK_DELIVERY_PENDING,
+			NextAttemptAt: primitive.NewDateTimeFromTime(now),
+			CreatedAt:     primitive.NewDateTimeFromTime(now),
+		}
+		body, err := json.Marshal(Payload{Id: delivery.Id.Hex(), Type: eventType, CreatedAt: now.UTC(), Data: data})
+		if err != nil {
+			logger.StdErr.Println(err)
+			continue
+		}
+		delivery.Payload = string(body)
+		db.InsertWebhookDelivery(&delivery)
+	}
+}
+
+// Queues the delivery to be sent again with the same payload, e.g. once the
+// owner fixed their target. The original delivery is kept as it was
+func Replay(delivery *models.WebhookDelivery, now time.Time) *models.WebhookDelivery {
+	replay := models.WebhookDelivery{
+		Id:            primitive.NewObjectID(),
+		WebhookId:     delivery.WebhookId,
+		Type:          delivery.Type,
+		Payload:       delivery.Payload,
+		Status:        models.WEBHOOK_DELIVERY_PENDING,
+		NextAttemptAt: primitive.NewDateTimeFromTime(now),
+		ReplayOf:      &delivery.Id,
+		CreatedAt:     primitive.NewDateTimeFromTime(now),
+	}
+	db.InsertWebhookDelivery(&replay)
+	return &replay
+}
+
+// Posts the body to the url, returning the status code of the response
+func post(url string, secret string, deliveryId string, body []byte, now time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), TIMEOUT)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Timeful-Webhooks/1.0")
+	req.Header.Set("X-Timeful-Delivery", deliveryId)
+	req.Header.Set("X-Timeful-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Timeful-Signature", Sign(secret, timestamp, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("target responded with %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Attempts the delivery once, scheduling a retry if it fails. Deliveries over
+// the owner's daily quota are postponed until it resets
+func Deliver(delivery *models.WebhookDelivery, now time.Time) {
+	webhook := db.GetWebhookById(delivery.WebhookId.Hex())
+	if webhook == nil || !webhook.Enabled {
+		delivery.Status = models.WEBHOOK_DELIVERY_FAILED
+		delivery.LastError = "webhook was deleted or disabled"
+		db.UpdateWebhookDelivery(delivery)
+		return
+	}
+	if owner := db.GetUserById(webhook.OwnerId.Hex()); owner != nil {
+		quota := entitlements.Consume(owner, entitlements.GetUserPlan(owner), entitlements.METRIC_WEBHOOK_DELIVERIES, now)
+		if quota.Exceeded() {
+			// Postponed without counting an attempt, so it's delivered once the
+			// quota resets
+			delivery.NextAttemptAt = primitive.NewDateTimeFromTime(quota.ResetAt)
+			delivery.LastError = "daily delivery quota of the plan exceeded"
+			db.UpdateWebhookDelivery(delivery)
+			return
+		}
+	}
+
+	start := time.Now()
+	statusCode, err := post(webhook.Url, webhook.Secret, delivery.Id.Hex(), []byte(delivery.Payload), now)
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastLatencyMs = time.Since(start).Milliseconds()
+	if err == nil {
+		deliveredAt := primitive.NewDateTimeFromTime(now)
+		delivery.Status = models.WEBHOOK_DELIVERY_DELIVERED
+		delivery.DeliveredAt = &deliveredAt
+		delivery.LastError = ""
+	} else {
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= MAX_ATTEMPTS {
+			delivery.Status = models.WEBHOOK_DELIVERY_FAILED
+		} else {
+			delivery.NextAttemptAt = primitive.NewDateTimeFromTime(now.Add(GetBackoff(delivery.Attempts)))
+		}
+	}
+	db.UpdateWebhookDelivery(delivery)
+}
+
+// Attempts the deliveries that are due. Run periodically by the jobs scheduler
+func DeliverDue(now time.Time) {
+	for delivery := db.ClaimDueWebhookDelivery(now, 2*TIMEOUT); delivery != nil; delivery = db.ClaimDueWebhookDelivery(now, 2*TIMEOUT) {
+		Deliver(delivery, now)
+	}
+}
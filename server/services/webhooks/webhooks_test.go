@@ -0,0 +1,69 @@
+package webhooks
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGetBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{MAX_ATTEMPTS, 64 * time.Minute},
+		{20, 6 * time.Hour},
+	}
+	for _, test := range tests {
+		if backoff := GetBackoff(test.attempts); backoff != test.expected {
+			t.Errorf("GetBackoff(%d) = %v, expected %v", test.attempts, backoff, test.expected)
+		}
+	}
+}
+
+func TestPost(t *testing.T) {
+	secret := NewSecret()
+	now := time.Now()
+	body := []byte(`{"type":"response.created"}`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get("X-Timeful-Timestamp"), 10, 64)
+		if r.Header.Get("X-Timeful-Signature") != Sign(secret, timestamp, received) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	if statusCode, err := post(server.URL, secret, "1", body, now); err != nil || statusCode != http.StatusNoContent {
+		t.Errorf("got %d, %v", statusCode, err)
+	}
+	if statusCode, err := post(server.URL, "other", "1", body, now); err == nil || statusCode != http.StatusUnauthorized {
+		t.Errorf("expected signature with another secret to be rejected, got %d, %v", statusCode, err)
+	}
+}
+
+func TestPostDoesNotFollowRedirects(t *testing.T) {
+	followed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			followed = true
+			return
+		}
+		http.Redirect(w, r, "/internal", http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	statusCode, err := post(server.URL, NewSecret(), "1", []byte("{}"), time.Now())
+	if err == nil || statusCode != http.StatusTemporaryRedirect || followed {
+		t.Errorf("expected the redirect to fail the delivery, got %d, %v", statusCode, err)
+	}
+}
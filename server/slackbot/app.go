@@ -0,0 +1,269 @@
+package slackbot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/brianvoe/sjwt"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/middleware"
+	"schej.it/server/services/i18n"
+	"schej.it/server/services/slack"
+	"schej.it/server/utils"
+)
+
+// Middleware that rejects requests that weren't signed by Slack
+func verifySlackRequest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		signingSecret := os.Getenv("SLACK_SIGNING_SECRET")
+		if signingSecret == "" {
+			logger.StdErr.Println("SLACK_SIGNING_SECRET not set")
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+
+		if !slack.VerifySignature(signingSecret, c.GetHeader("X-Slack-Request-Timestamp"), c.GetHeader("X-Slack-Signature"), body) {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		// Restore body so that it can be read by the handler
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+
+		c.Next()
+	}
+}
+
+// @Summary Receives events from the Slack Events API
+// @Tags slackbot
+// @Accept json
+// @Produce json
+// @Success 200
+// @Router /slackbot/events [post]
+func handleEvent(c *gin.Context) {
+	payload := struct {
+		Type      string `json:"type" binding:"required"`
+		Challenge string `json:"challenge"`
+		TeamId    string `json:"team_id"`
+		Event     struct {
+			Type string `json:"type"`
+			User string `json:"user"`
+			Tab  string `json:"tab"`
+
+			// Set for function_executed events, i.e. when a workflow runs one
+			// of the app's steps
+			FunctionExecutionId string `json:"function_execution_id"`
+			Function            struct {
+				CallbackId string `json:"callback_id"`
+			} `json:"function"`
+			Inputs map[string]interface{} `json:"inputs"`
+		} `json:"event"`
+	}{}
+	if err := c.BindJSON(&payload); err != nil {
+		return
+	}
+
+	switch payload.Type {
+	case "url_verification":
+		c.JSON(http.StatusOK, gin.H{"challenge": payload.Challenge})
+		return
+	case "event_callback":
+		switch payload.Event.Type {
+		case "app_home_opened":
+			if payload.Event.Tab == "home" {
+				go PublishHome(payload.Event.User, payload.TeamId)
+			}
+		case "function_executed":
+			go executeWorkflowStep(payload.Event.FunctionExecutionId, payload.Event.Function.CallbackId, payload.Event.Inputs)
+		}
+	}
+
+	// Slack expects a response within 3 seconds, so always acknowledge immediately
+	c.Status(http.StatusOK)
+}
+
+// @Summary Receives interactions (e.g. button clicks) from Slack
+// @Tags slackbot
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Success 200
+// @Router /slackbot/interactions [post]
+func handleInteraction(c *gin.Context) {
+	var interaction struct {
+		Type string `json:"type"`
+		User struct {
+			Id     string `json:"id"`
+			TeamId string `json:"team_id"`
+		} `json:"user"`
+		Actions []struct {
+			ActionId string `json:"action_id"`
+			Value    string `json:"value"`
+		} `json:"actions"`
+	}
+	if err := json.Unmarshal([]byte(c.PostForm("payload")), &interaction); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	if interaction.Type == "block_actions" {
+		for _, action := range interaction.Actions {
+			go handleBlockAction(interaction.User.Id, interaction.User.TeamId, action.ActionId, action.Value)
+		}
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Performs the given block action on behalf of the given slack user
+func handleBlockAction(slackUserId string, slackTeamId string, actionId string, value string) {
+	defer func() {
+		if err := recover(); err != nil {
+			logger.StdErr.Println(err)
+		}
+	}()
+
+	switch actionId {
+	case nudgeNonRespondersActionId:
+		principal := middleware.SlackPrincipal(slackUserId)
+		if principal == nil {
+			return
+		}
+
+		// Only the owner of the event can nudge non-responders
+		event := db.GetEventById(value)
+		if event == nil || event.OwnerId != principal.UserId {
+			return
+		}
+		owner := db.GetUserById(principal.UserId.Hex())
+		if owner == nil {
+			return
+		}
+
+		numSent := nudgeNonResponders(event, owner)
+		if err := slack.PostMessage(slackUserId, i18n.Plural(getLocale(slackUserId), numSent, "slack.nudgeSent", event.Name), nil); err != nil {
+			logger.StdErr.Println(err)
+		}
+	case approveFinalizationActionId, rejectFinalizationActionId:
+		reviewFinalization(slackUserId, value, actionId == approveFinalizationActionId)
+		return
+	case unlinkAccountActionId:
+		db.UnlinkSlackAccount(slackUserId)
+	default:
+		// Link buttons and open event buttons just open a url, nothing to do
+		return
+	}
+
+	PublishHome(slackUserId, slackTeamId)
+}
+
+// How long the confirmation page can be submitted after it's shown
+const linkConsentExpiry = 15 * time.Minute
+
+// Returns a token that the confirmation page posts back, so that only a page
+// shown to the user can link their account, and not a form on another site
+func newLinkConsentToken(userId primitive.ObjectID, code string, now time.Time) string {
+	claims := sjwt.New()
+	claims.Set("userId", userId.Hex())
+	claims.Set("code", code)
+	claims.SetExpiresAt(now.Add(linkConsentExpiry))
+	return claims.Generate([]byte(os.Getenv("ENCRYPTION_KEY")))
+}
+
+func verifyLinkConsentToken(token string, userId primitive.ObjectID, code string, now time.Time) bool {
+	if !sjwt.Verify(token, []byte(os.Getenv("ENCRYPTION_KEY"))) {
+		return false
+	}
+	claims, err := sjwt.Parse(token)
+	if err != nil {
+		return false
+	}
+	expiresAt, err := claims.GetExpiresAt()
+	if err != nil || now.Unix() > expiresAt {
+		return false
+	}
+	tokenUserId, _ := claims.GetStr("userId")
+	tokenCode, _ := claims.GetStr("code")
+	return tokenUserId == userId.Hex() && tokenCode == code
+}
+
+// @Summary Shows a page confirming the link to the slack user that generated the link code
+// @Description Opened from the App Home. Doesn't link by itself, since anyone can send the signed in user a link with their own code, but asks to confirm with a POST
+// @Tags slackbot
+// @Produce html
+// @Param code query string true "One-time link code shown in the Slack App Home"
+// @Success 200
+// @Router /slackbot/link [get]
+func getLinkAccount(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+
+	code := c.Query("code")
+	linkCode := db.GetSlackLinkCode(code)
+	if linkCode == nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("%s/home?slackLink=invalid", utils.GetBaseUrl()))
+		return
+	}
+	slackName, err := slack.GetUserName(linkCode.SlackUserId)
+	if err != nil {
+		logger.StdErr.Println(err)
+		slackName = linkCode.SlackUserId
+	}
+
+	values := url.Values{}
+	values.Set("code", code)
+	page := fmt.Sprintf(
+		`<!DOCTYPE html><html><head><meta charset="utf-8"><meta name="viewport" content="width=device-width"><title>Link Slack account</title></head>`+
+			`<body style="font-family: sans-serif; max-width: 32rem; margin: 4rem auto; padding: 0 1rem">`+
+			`<p>Let the Slack user %s see the events of your Timeful account %s and nudge their respondents from Slack?</p>`+
+			`<p>Only continue if you opened this link from your own Slack App Home.</p>`+
+			`<form method="post" action="?%s"><input type="hidden" name="consent" value="%s"><button type="submit">Link account</button></form></body></html>`,
+		html.EscapeString(slackName), html.EscapeString(user.Email), html.EscapeString(values.Encode()),
+		html.EscapeString(newLinkConsentToken(user.Id, code, time.Now())),
+	)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+}
+
+// @Summary Links the signed in user's Timeful account to the slack user that generated the link code
+// @Description Posted by the confirmation page
+// @Tags slackbot
+// @Accept x-www-form-urlencoded
+// @Param code query string true "One-time link code shown in the Slack App Home"
+// @Param consent formData string true "Token from the confirmation page"
+// @Success 302
+// @Router /slackbot/link [post]
+func linkAccount(c *gin.Context) {
+	user := utils.GetAuthUser(c)
+
+	code := c.Query("code")
+	if !verifyLinkConsentToken(c.PostForm("consent"), user.Id, code, time.Now()) {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	linkCode := db.ConsumeSlackLinkCode(code)
+	if linkCode == nil {
+		c.Redirect(http.StatusFound, fmt.Sprintf("%s/home?slackLink=invalid", utils.GetBaseUrl()))
+		return
+	}
+
+	db.LinkSlackAccount(linkCode.SlackUserId, linkCode.SlackTeamId, user.Id)
+
+	// Refresh the App Home so that the user sees their events right away
+	go PublishHome(linkCode.SlackUserId, linkCode.SlackTeamId)
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("%s/home?slackLink=success", utils.GetBaseUrl()))
+}
@@ -0,0 +1,90 @@
+package slackbot
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/middleware"
+	"schej.it/server/models"
+	"schej.it/server/services/i18n"
+	"schej.it/server/services/slack"
+)
+
+// Action ids of the buttons in finalization approval requests
+const (
+	approveFinalizationActionId = "approve_finalization"
+	rejectFinalizationActionId  = "reject_finalization"
+)
+
+// Approves or rejects the event's pending finalization on behalf of the
+// approver. Returns whether the event was finalized, and false for ok if the
+// finalization is no longer waiting for the approver. Set in main.go, since
+// finalizing events lives in the routes package
+var ReviewFinalization func(event *models.Event, approver *models.User, approve bool) (finalized bool, ok bool)
+
+// Asks the approver to approve or reject the event's pending finalization in
+// each of their linked Slack accounts. Returns whether they have any
+func SendFinalizationApprovalRequest(approverId primitive.ObjectID, requester *models.User, event *models.Event) bool {
+	accounts := db.GetSlackAccountsByUserId(approverId)
+	for _, account := range accounts {
+		locale := getLocale(account.SlackUserId)
+		when := formatSlackDate(event.PendingFinalization.StartDate.Time().Unix(), i18n.T(locale, "slack.home.meetingDate"), locale)
+		text := i18n.T(locale, "slack.approval.request", requester.FirstName, event.Name, when)
+		blocks := []bson.M{
+			textBlock(text),
+			{
+				"type": "actions",
+				"elements": bson.A{
+					bson.M{
+						"type":      "button",
+						"action_id": approveFinalizationActionId,
+						"style":     "primary",
+						"text":      plainText(i18n.T(locale, "slack.approval.approve")),
+						"value":     event.Id.Hex(),
+					},
+					bson.M{
+						"type":      "button",
+						"action_id": rejectFinalizationActionId,
+						"style":     "danger",
+						"text":      plainText(i18n.T(locale, "slack.approval.reject")),
+						"value":     event.Id.Hex(),
+					},
+					openEventButton(event, locale),
+				},
+			},
+		}
+		if err := slack.PostMessage(account.SlackUserId, text, blocks); err != nil {
+			logger.StdErr.Println(err)
+		}
+	}
+	return len(accounts) > 0
+}
+
+// Approves or rejects the pending finalization of the event with the given id
+// on behalf of the slack user, and tells them the outcome
+func reviewFinalization(slackUserId string, eventId string, approve bool) {
+	principal := middleware.SlackPrincipal(slackUserId)
+	if principal == nil {
+		return
+	}
+	approver := db.GetUserById(principal.UserId.Hex())
+	event := db.GetEventById(eventId)
+	if approver == nil || event == nil {
+		return
+	}
+
+	finalized, ok := ReviewFinalization(event, approver, approve)
+	key := "slack.approval.notPending"
+	switch {
+	case ok && finalized:
+		key = "slack.approval.finalized"
+	case ok && approve:
+		key = "slack.approval.approved"
+	case ok:
+		key = "slack.approval.rejected"
+	}
+	if err := slack.PostMessage(slackUserId, i18n.T(getLocale(slackUserId), key, event.Name), nil); err != nil {
+		logger.StdErr.Println(err)
+	}
+}
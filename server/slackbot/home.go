@@ -0,0 +1,243 @@
+package slackbot
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/middleware"
+	"schej.it/server/models"
+	"schej.it/server/services/i18n"
+	"schej.it/server/services/listmonk"
+	"schej.it/server/services/slack"
+	"schej.it/server/utils"
+)
+
+// Maximum number of events to show in each section of the App Home
+const homeSectionLimit = 10
+
+// Action ids of the interactive elements in the App Home
+const (
+	openEventActionId          = "open_event"
+	nudgeNonRespondersActionId = "nudge_non_responders"
+	linkAccountActionId        = "link_account"
+	unlinkAccountActionId      = "unlink_account"
+)
+
+// Returns the locale to show the given slack user messages in, i.e. the locale
+// they set for Slack, falling back to the workspace's (SLACK_WORKSPACE_LOCALE)
+func getLocale(slackUserId string) string {
+	locale, err := slack.GetUserLocale(slackUserId)
+	if err != nil {
+		logger.StdErr.Println(err)
+	}
+	return i18n.Resolve(locale, os.Getenv("SLACK_WORKSPACE_LOCALE"))
+}
+
+// Publishes the App Home for the given slack user
+func PublishHome(slackUserId string, slackTeamId string) {
+	if err := slack.PublishHomeView(slackUserId, buildHomeView(slackUserId, slackTeamId)); err != nil {
+		logger.StdErr.Println(err)
+	}
+}
+
+// Builds the App Home view for the given slack user. If the slack user hasn't
+// linked their Timeful account, shows a button to link it instead
+func buildHomeView(slackUserId string, slackTeamId string) bson.M {
+	blocks := make([]bson.M, 0)
+	locale := getLocale(slackUserId)
+
+	principal := middleware.SlackPrincipal(slackUserId)
+	var user *models.User
+	if principal != nil {
+		user = db.GetUserById(principal.UserId.Hex())
+	}
+
+	if user == nil {
+		code := db.CreateSlackLinkCode(slackUserId, slackTeamId)
+		blocks = append(blocks,
+			headerBlock(i18n.T(locale, "slack.home.welcome")),
+			textBlock(i18n.T(locale, "slack.home.linkPrompt")),
+			bson.M{
+				"type": "actions",
+				"elements": bson.A{
+					bson.M{
+						"type":      "button",
+						"action_id": linkAccountActionId,
+						"style":     "primary",
+						"text":      plainText(i18n.T(locale, "slack.home.linkAccount")),
+						"url":       fmt.Sprintf("%s/api/slackbot/link?code=%s", utils.GetBaseUrl(), code),
+					},
+				},
+			},
+		)
+		return bson.M{"type": "home", "blocks": blocks}
+	}
+
+	// Open events the user owns
+	openEvents := make([]models.Event, 0)
+	for _, event := range db.GetActiveEventsOwnedByUser(user.Id) {
+		if event.ScheduledEvent == nil {
+			openEvents = append(openEvents, event)
+		}
+	}
+	blocks = append(blocks, headerBlock(i18n.T(locale, "slack.home.openEvents")))
+	if len(openEvents) == 0 {
+		blocks = append(blocks, contextBlock(i18n.T(locale, "slack.home.noOpenEvents")))
+	}
+	for i, event := range openEvents {
+		if i >= homeSectionLimit {
+			blocks = append(blocks, contextBlock(i18n.T(locale, "slack.home.more", len(openEvents)-homeSectionLimit)))
+			break
+		}
+
+		numResponses := utils.Coalesce(event.NumResponses)
+		text := fmt.Sprintf("*%s*\n%s", event.Name, i18n.Plural(locale, numResponses, "slack.home.responses"))
+		numNonResponders := len(getNonResponders(&event))
+		if numNonResponders > 0 {
+			text += i18n.T(locale, "slack.home.stillWaiting", numNonResponders)
+		}
+		blocks = append(blocks, textBlock(text))
+
+		elements := bson.A{openEventButton(&event, locale)}
+		if numNonResponders > 0 {
+			elements = append(elements, bson.M{
+				"type":      "button",
+				"action_id": nudgeNonRespondersActionId,
+				"text":      plainText(i18n.T(locale, "slack.home.nudge")),
+				"value":     event.Id.Hex(),
+				"confirm": bson.M{
+					"title":   plainText(i18n.T(locale, "slack.home.nudgeConfirmTitle")),
+					"text":    plainText(i18n.Plural(locale, numNonResponders, "slack.home.nudgeConfirmText")),
+					"confirm": plainText(i18n.T(locale, "slack.home.send")),
+					"deny":    plainText(i18n.T(locale, "slack.home.cancel")),
+				},
+			})
+		}
+		blocks = append(blocks, bson.M{"type": "actions", "elements": elements})
+	}
+
+	// Events the user has been asked to respond to
+	pendingEvents := db.GetEventsPendingResponse(user.Email)
+	blocks = append(blocks, bson.M{"type": "divider"}, headerBlock(i18n.T(locale, "slack.home.pendingResponses")))
+	if len(pendingEvents) == 0 {
+		blocks = append(blocks, contextBlock(i18n.T(locale, "slack.home.caughtUp")))
+	}
+	for i, event := range pendingEvents {
+		if i >= homeSectionLimit {
+			blocks = append(blocks, contextBlock(i18n.T(locale, "slack.home.more", len(pendingEvents)-homeSectionLimit)))
+			break
+		}
+		blocks = append(blocks, sectionWithButton(fmt.Sprintf("*%s*", event.Name), openEventButton(&event, locale)))
+	}
+
+	// Finalized meetings
+	scheduledEvents := db.GetUpcomingScheduledEvents(user.Id)
+	blocks = append(blocks, bson.M{"type": "divider"}, headerBlock(i18n.T(locale, "slack.home.upcomingMeetings")))
+	if len(scheduledEvents) == 0 {
+		blocks = append(blocks, contextBlock(i18n.T(locale, "slack.home.noUpcomingMeetings")))
+	}
+	for i, event := range scheduledEvents {
+		if i >= homeSectionLimit {
+			blocks = append(blocks, contextBlock(i18n.T(locale, "slack.home.more", len(scheduledEvents)-homeSectionLimit)))
+			break
+		}
+		text := fmt.Sprintf("*%s*\n%s", event.Name, formatSlackDate(event.ScheduledEvent.StartDate.Time().Unix(), i18n.T(locale, "slack.home.meetingDate"), locale))
+		blocks = append(blocks, sectionWithButton(text, openEventButton(&event, locale)))
+	}
+
+	blocks = append(blocks,
+		bson.M{"type": "divider"},
+		bson.M{
+			"type": "context",
+			"elements": bson.A{
+				bson.M{"type": "mrkdwn", "text": i18n.T(locale, "slack.home.signedInAs", user.Email)},
+			},
+		},
+		bson.M{
+			"type": "actions",
+			"elements": bson.A{
+				bson.M{
+					"type":      "button",
+					"action_id": unlinkAccountActionId,
+					"text":      plainText(i18n.T(locale, "slack.home.unlinkAccount")),
+				},
+			},
+		},
+	)
+
+	return bson.M{"type": "home", "blocks": blocks}
+}
+
+// Returns the emails of the remindees that haven't responded to the event yet
+func getNonResponders(event *models.Event) []string {
+	emails := make([]string, 0)
+	for _, remindee := range utils.Coalesce(event.Remindees) {
+		if !utils.Coalesce(remindee.Responded) {
+			emails = append(emails, remindee.Email)
+		}
+	}
+	return emails
+}
+
+// Sends a reminder email to everyone who hasn't responded to the event yet.
+// Returns the number of reminders sent
+func nudgeNonResponders(event *models.Event, owner *models.User) int {
+	templateId, err := strconv.Atoi(os.Getenv("LISTMONK_SECOND_EMAIL_REMINDER_ID"))
+	if err != nil {
+		logger.StdErr.Println(err)
+		return 0
+	}
+
+	baseUrl := utils.GetBaseUrl()
+	nonResponders := getNonResponders(event)
+	for _, email := range nonResponders {
+		listmonk.SendEmailAddSubscriberIfNotExist(email, templateId, bson.M{
+			"ownerName":   owner.FirstName,
+			"eventName":   event.Name,
+			"eventUrl":    fmt.Sprintf("%s/e/%s", baseUrl, event.GetId()),
+			"finishedUrl": fmt.Sprintf("%s/e/%s/responded?email=%s", baseUrl, event.GetId(), email),
+		}, false)
+	}
+
+	return len(nonResponders)
+}
+
+func openEventButton(event *models.Event, locale string) bson.M {
+	return bson.M{
+		"type":      "button",
+		"action_id": openEventActionId,
+		"text":      plainText(i18n.T(locale, "slack.open")),
+		"url":       fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId()),
+		"value":     event.Id.Hex(),
+	}
+}
+
+func headerBlock(text string) bson.M {
+	return bson.M{"type": "header", "text": bson.M{"type": "plain_text", "text": text, "emoji": true}}
+}
+
+func textBlock(text string) bson.M {
+	return bson.M{"type": "section", "text": bson.M{"type": "mrkdwn", "text": text}}
+}
+
+func contextBlock(text string) bson.M {
+	return bson.M{"type": "context", "elements": bson.A{bson.M{"type": "mrkdwn", "text": text}}}
+}
+
+func sectionWithButton(text string, button bson.M) bson.M {
+	return bson.M{"type": "section", "text": bson.M{"type": "mrkdwn", "text": text}, "accessory": button}
+}
+
+func plainText(text string) bson.M {
+	return bson.M{"type": "plain_text", "text": text, "emoji": true}
+}
+
+// Formats a unix timestamp so that slack displays it in the viewer's timezone.
+// See https://api.slack.com/reference/surfaces/formatting#date-formatting
+func formatSlackDate(unix int64, format string, locale string) string {
+	return fmt.Sprintf("<!date^%d^%s|%s>", unix, format, i18n.T(locale, "slack.unknownDate"))
+}
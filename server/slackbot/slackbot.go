@@ -8,6 +8,7 @@ import (
 	"regexp"
 
 	"github.com/gin-gonic/gin"
+	"schej.it/server/middleware"
 	"schej.it/server/slackbot/commands"
 	"schej.it/server/utils"
 )
@@ -54,6 +55,10 @@ func InitSlackbot(router *gin.RouterGroup) {
 	slackbotRouter := router.Group("/slackbot")
 
 	slackbotRouter.POST("", execCommand)
+	slackbotRouter.POST("/events", verifySlackRequest(), handleEvent)
+	slackbotRouter.POST("/interactions", verifySlackRequest(), handleInteraction)
+	slackbotRouter.GET("/link", middleware.AuthRequired(), getLinkAccount)
+	slackbotRouter.POST("/link", middleware.AuthRequired(), linkAccount)
 }
 
 // @Summary Gets the number of signed up users
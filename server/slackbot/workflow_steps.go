@@ -0,0 +1,115 @@
+package slackbot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"schej.it/server/db"
+	"schej.it/server/logger"
+	"schej.it/server/middleware"
+	"schej.it/server/models"
+	"schej.it/server/services/i18n"
+	"schej.it/server/services/organizations"
+	"schej.it/server/services/slack"
+	"schej.it/server/utils"
+)
+
+// Callback id of the "Create scheduling poll" workflow step, which has to match
+// the custom step defined in the Slack app's manifest
+const createPollStepCallbackId = "create_scheduling_poll"
+
+// Runs the given execution of one of the app's workflow steps, completing it
+// with its outputs or failing it with an error message
+func executeWorkflowStep(functionExecutionId string, callbackId string, inputs map[string]interface{}) {
+	defer func() {
+		if err := recover(); err != nil {
+			logger.StdErr.Println(err)
+		}
+	}()
+
+	var outputs bson.M
+	var err error
+	switch callbackId {
+	case createPollStepCallbackId:
+		outputs, err = createPollStep(inputs)
+	default:
+		err = fmt.Errorf("unknown step %q", callbackId)
+	}
+
+	if err != nil {
+		err = slack.FailFunction(functionExecutionId, err.Error())
+	} else {
+		err = slack.CompleteFunction(functionExecutionId, outputs)
+	}
+	if err != nil {
+		logger.StdErr.Println(err)
+	}
+}
+
+// Creates an event owned by the Timeful account linked to the "user_id" input,
+// named by the "name" input and spanning the next 7 days. Outputs the event's
+// id and url
+func createPollStep(inputs map[string]interface{}) (bson.M, error) {
+	name := strings.TrimSpace(getStringInput(inputs, "name"))
+	slackUserId := getStringInput(inputs, "user_id")
+	if len(name) == 0 || len(slackUserId) == 0 {
+		return nil, errors.New("name and user_id are required")
+	}
+
+	principal := middleware.SlackPrincipal(slackUserId)
+	var owner *models.User
+	if principal != nil {
+		owner = db.GetUserById(principal.UserId.Hex())
+	}
+	if owner == nil {
+		return nil, errors.New(i18n.T(getLocale(slackUserId), "slack.workflow.notLinked"))
+	}
+
+	// Use the timezone input if it's set, and the owner's otherwise
+	loc := utils.GetUserLocation(owner)
+	timezone := getStringInput(inputs, "timezone")
+	if len(timezone) > 0 {
+		tzLoc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q", timezone)
+		}
+		loc = tzLoc
+	}
+	dates, duration := utils.GetDefaultEventDates(loc)
+
+	event := models.Event{
+		OwnerId:  owner.Id,
+		Name:     name,
+		Duration: &duration,
+		Dates:    dates,
+		Type:     models.SPECIFIC_DATES,
+	}
+	if description := strings.TrimSpace(getStringInput(inputs, "description")); len(description) > 0 {
+		event.Description = &description
+	}
+	if len(timezone) > 0 {
+		event.Timezone = &timezone
+	}
+
+	// Apply the defaults and locked settings of the owner's organization
+	if orgs := db.GetOrganizationsByUserId(owner.Id); len(orgs) > 0 {
+		event.OrganizationId = orgs[0].Id
+		organizations.ApplySettings(&event, orgs[0].Settings, true)
+	}
+
+	db.InsertEvent(&event)
+
+	return bson.M{
+		"event_id":  event.Id.Hex(),
+		"event_url": fmt.Sprintf("%s/e/%s", utils.GetBaseUrl(), event.GetId()),
+	}, nil
+}
+
+// Returns the given string input of a workflow step, or "" if it's not set
+func getStringInput(inputs map[string]interface{}, key string) string {
+	value, _ := inputs[key].(string)
+	return value
+}
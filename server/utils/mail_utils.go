@@ -3,17 +3,103 @@ package utils
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"gopkg.in/gomail.v2"
 	"schej.it/server/logger"
+	"schej.it/server/services/dkim"
+	"schej.it/server/services/hooks"
+	"schej.it/server/services/status"
 )
 
 // Send email to the given email
 func SendEmail(toEmail string, subject string, body string, contentType string) {
+	if err := TrySendEmail(toEmail, subject, body, contentType); err != nil {
+		logger.StdErr.Println(err)
+	}
+}
+
+// Send email to the given email, returning the error if it couldn't be sent
+func TrySendEmail(toEmail string, subject string, body string, contentType string) error {
+	return TrySendEmailWithReplyTo(toEmail, "", subject, body, contentType)
+}
+
+// File attached to an email
+type EmailAttachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Returned when an email isn't sent because its address is suppressed
+var ErrEmailSuppressed = errors.New("email address is suppressed")
+
+// Returns whether emails to the address shouldn't be sent: bulk emails if it
+// unsubscribed, and every email if it bounced or complained. Set to the
+// suppression list's check on startup, since utils can't import db
+var IsEmailSuppressed = func(email string, bulk bool) bool { return false }
+
+// Send email to the given email, with replies going to replyTo instead of the
+// sender if it's set
+func TrySendEmailWithReplyTo(toEmail string, replyTo string, subject string, body string, contentType string, attachments ...EmailAttachment) error {
+	headers := make(map[string]string)
+	if replyTo != "" {
+		headers["Reply-To"] = replyTo
+	}
+	return sendEmail(toEmail, subject, body, contentType, headers, false, attachments, nil)
+}
+
+// Sends emails from another address than the default one, e.g. on an
+// organization's own domain, signed with the domain's DKIM key
+type EmailSender struct {
+	// Name the emails are from
+	Name string
+
+	Address string
+	Signer  *dkim.Signer
+}
+
+// Send a bulk email (e.g. a reminder or a broadcast) to the given email, with
+// RFC 8058 one-click List-Unsubscribe headers so mail clients can show an
+// unsubscribe button that posts to unsubscribeUrl. It's sent from the sender
+// if it isn't nil
+func TrySendBulkEmail(toEmail string, subject string, body string, contentType string, unsubscribeUrl string, sender *EmailSender) error {
+	headers := map[string]string{
+		"List-Unsubscribe":      fmt.Sprintf("<%s>", unsubscribeUrl),
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+	return sendEmail(toEmail, subject, body, contentType, headers, true, nil, sender)
+}
+
+// Writes the message signed by the signer
+type signedMessage struct {
+	message *gomail.Message
+	signer  *dkim.Signer
+}
+
+func (m signedMessage) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	if _, err := m.message.WriteTo(&buf); err != nil {
+		return 0, err
+	}
+	signed, err := m.signer.Sign(buf.Bytes(), time.Now())
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(signed)
+	return int64(n), err
+}
+
+func sendEmail(toEmail string, subject string, body string, contentType string, headers map[string]string, bulk bool, attachments []EmailAttachment, sender *EmailSender) error {
+	if IsEmailSuppressed(toEmail, bulk) {
+		return ErrEmailSuppressed
+	}
 	if contentType == "" {
 		contentType = "text/plain"
 	}
@@ -22,17 +108,56 @@ func SendEmail(toEmail string, subject string, body string, contentType string)
 	fromEmail := os.Getenv("SCHEJ_EMAIL_ADDRESS")
 
 	m := gomail.NewMessage()
-	m.SetHeader("From", fromEmail)
+	if sender != nil {
+		m.SetAddressHeader("From", sender.Address, sender.Name)
+	} else {
+		m.SetHeader("From", fromEmail)
+	}
 	m.SetHeader("To", toEmail)
+	for name, value := range headers {
+		m.SetHeader(name, value)
+	}
 	m.SetHeader("Subject", subject)
 	m.SetBody(contentType, body)
+	for _, attachment := range attachments {
+		data := attachment.Data
+		m.Attach(attachment.Filename,
+			gomail.SetHeader(map[string][]string{"Content-Type": {attachment.ContentType}}),
+			gomail.SetCopyFunc(func(w io.Writer) error {
+				_, err := w.Write(data)
+				return err
+			}),
+		)
+	}
 
 	d := gomail.NewDialer("smtp.gmail.com", 587, fromEmail, appPassword)
 
 	// Send the email to Bob, Cora and Dan.
-	if err := d.DialAndSend(m); err != nil {
-		logger.StdErr.Println(err)
+	var err error
+	if sender != nil && sender.Signer != nil {
+		err = sendSigned(d, sender, toEmail, signedMessage{m, sender.Signer})
+	} else {
+		err = d.DialAndSend(m)
+	}
+	status.Record(status.EMAIL, err)
+	if err == nil {
+		hooks.Dispatch(hooks.NOTIFICATION_DISPATCH, map[string]interface{}{
+			"channel": "email",
+			"to":      toEmail,
+			"subject": subject,
+			"bulk":    bulk,
+		})
+	}
+	return err
+}
+
+func sendSigned(d *gomail.Dialer, sender *EmailSender, toEmail string, message signedMessage) error {
+	s, err := d.Dial()
+	if err != nil {
+		return err
 	}
+	defer s.Close()
+	return s.Send(sender.Address, []string{toEmail}, message)
 }
 
 func AddUserToMailchimp(email string, firstName string, lastName string) {
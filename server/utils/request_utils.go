@@ -1,8 +1,13 @@
 package utils
 
 import (
+	"fmt"
+	"net"
+	"net/http"
 	"net/url"
+	"os"
 	"strings"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 	"schej.it/server/logger"
@@ -21,3 +26,109 @@ func ParseArrayQueryParam(s string) []string {
 func GetOrigin(c *gin.Context) string {
 	return c.Request.Header.Get("Origin")
 }
+
+// Origins of the frontends allowed to call the api, which are also the only
+// places payments can return to
+var AllowedOrigins = []string{
+	"http://localhost:8080",
+
+	// EasyPanel (teste)
+	"https://timeful-timeful-app.4kaj9t.easypanel.host",
+
+	// Seu domínio real
+	"https://timeful.viaaha.com.br",
+
+	// Domínios oficiais do projeto
+	"https://www.schej.it",
+	"https://schej.it",
+	"https://www.timeful.app",
+	"https://timeful.app",
+}
+
+// Returns whether the url is on one of the allowed origins
+func IsAllowedOrigin(rawUrl string) bool {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil || parsed.User != nil {
+		return false
+	}
+	return Contains(AllowedOrigins, fmt.Sprintf("%s://%s", parsed.Scheme, parsed.Host))
+}
+
+// Returns the addresses or cidrs of the proxies in front of the server whose
+// X-Forwarded-For is trusted for the client ip, set with TRUSTED_PROXIES.
+// Defaults to loopback, for nginx on the same host
+func GetTrustedProxies() []string {
+	trustedProxies := make([]string, 0)
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); len(proxy) > 0 {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+	if len(trustedProxies) == 0 {
+		return []string{"127.0.0.1", "::1"}
+	}
+	return trustedProxies
+}
+
+// Returns whether the ip is one of the trusted proxies, given as addresses or cidrs
+func isTrustedProxy(ip string, trustedProxies []string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, proxy := range trustedProxies {
+		if _, cidr, err := net.ParseCIDR(proxy); err == nil {
+			if cidr.Contains(parsed) {
+				return true
+			}
+		} else if proxyIp := net.ParseIP(proxy); proxyIp != nil && proxyIp.Equal(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the ip the request came from, for handlers that don't have a gin
+// context. Like gin's ClientIP, X-Forwarded-For is only used for requests from
+// trusted proxies, and the client is its rightmost ip that isn't a trusted proxy
+func GetClientIp(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	trustedProxies := GetTrustedProxies()
+	if !isTrustedProxy(ip, trustedProxies) {
+		return ip
+	}
+	forwardedFor := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwardedFor) - 1; i >= 0; i-- {
+		forwardedIp := strings.TrimSpace(forwardedFor[i])
+		if len(forwardedIp) == 0 {
+			break
+		}
+		ip = forwardedIp
+		if !isTrustedProxy(ip, trustedProxies) {
+			break
+		}
+	}
+	return ip
+}
+
+// Refuses to connect to internal addresses in release. Set as the Control of
+// the dialer of clients that request urls given by users, e.g. ics feeds and
+// webhooks, so they can't be used to reach internal services
+func CheckPublicAddress(network string, address string, _ syscall.RawConn) error {
+	if !IsRelease() {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+		return fmt.Errorf("address %s isn't public", host)
+	}
+	return nil
+}
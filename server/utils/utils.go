@@ -62,6 +62,23 @@ func GetAuthUser(c *gin.Context) *models.User {
 	return user
 }
 
+// Returns the principal resolved by the auth middleware, or nil if the request
+// is anonymous
+func GetPrincipal(c *gin.Context) *models.Principal {
+	principal, _ := c.Get("principal")
+	p, _ := principal.(*models.Principal)
+	return p
+}
+
+// Returns the id of the user the request is made on behalf of, and whether
+// there is one
+func GetUserId(c *gin.Context) (string, bool) {
+	if principal := GetPrincipal(c); principal != nil {
+		return principal.UserId.Hex(), true
+	}
+	return "", false
+}
+
 // Gets the access token expire date from an "expiresIn" int representing the number of seconds
 // after which the access token will expire
 func GetAccessTokenExpireDate(expiresIn int) time.Time {
@@ -137,6 +154,14 @@ func Coalesce[T any](args ...*T) T {
 	return val
 }
 
+// Returns singular if n is 1, plural otherwise
+func Pluralize(n int, singular string, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
+
 // Return a pointer to true
 func TruePtr() *bool {
 	b := true
@@ -218,3 +243,32 @@ func ConvertEventToOldFormat(event *models.Event, eventResponses []models.EventR
 	}
 	event.ResponsesMap = responsesMap
 }
+
+// Returns the location corresponding to the user's timezone offset
+func GetUserLocation(user *models.User) *time.Location {
+	// Timezone offset is in minutes and positive for timezones behind UTC (same as JS getTimezoneOffset())
+	return time.FixedZone("UserOffset", -user.TimezoneOffset*60)
+}
+
+// Returns the location of the event's timezone, or of the owner's timezone
+// offset if the event doesn't have one
+func GetEventLocation(event *models.Event, owner *models.User) *time.Location {
+	if event.Timezone != nil {
+		if loc, err := time.LoadLocation(*event.Timezone); err == nil {
+			return loc
+		}
+	}
+	return GetUserLocation(owner)
+}
+
+// Returns the default dates and duration of an event created without a date
+// picker: 9am - 5pm for the next 7 days in the given location
+func GetDefaultEventDates(loc *time.Location) ([]primitive.DateTime, float32) {
+	now := time.Now().In(loc)
+	dates := make([]primitive.DateTime, 0)
+	for i := 1; i <= 7; i++ {
+		day := time.Date(now.Year(), now.Month(), now.Day()+i, 9, 0, 0, 0, loc)
+		dates = append(dates, primitive.NewDateTimeFromTime(day))
+	}
+	return dates, 8
+}